@@ -0,0 +1,197 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// MachineImagesClient is the client API for MachineImages service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MachineImagesClient interface {
+	// ListEntries streams all versions of the computed machine images, one message per version.
+	ListEntries(ctx context.Context, in *ListEntriesRequest, opts ...grpc.CallOption) (MachineImages_ListEntriesClient, error)
+	// Watch streams the current versions as ADDED events and afterwards an event for every change of the computed
+	// machine images.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (MachineImages_WatchClient, error)
+}
+
+type machineImagesClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMachineImagesClient(cc grpc.ClientConnInterface) MachineImagesClient {
+	return &machineImagesClient{cc}
+}
+
+func (c *machineImagesClient) ListEntries(ctx context.Context, in *ListEntriesRequest, opts ...grpc.CallOption) (MachineImages_ListEntriesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MachineImages_ServiceDesc.Streams[0], "/machineimages.landscaper.gardener.cloud.v1alpha1.MachineImages/ListEntries", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &machineImagesListEntriesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MachineImages_ListEntriesClient interface {
+	Recv() (*Entry, error)
+	grpc.ClientStream
+}
+
+type machineImagesListEntriesClient struct {
+	grpc.ClientStream
+}
+
+func (x *machineImagesListEntriesClient) Recv() (*Entry, error) {
+	m := new(Entry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *machineImagesClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (MachineImages_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MachineImages_ServiceDesc.Streams[1], "/machineimages.landscaper.gardener.cloud.v1alpha1.MachineImages/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &machineImagesWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MachineImages_WatchClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type machineImagesWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *machineImagesWatchClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// MachineImagesServer is the server API for MachineImages service.
+// All implementations must embed UnimplementedMachineImagesServer
+// for forward compatibility
+type MachineImagesServer interface {
+	// ListEntries streams all versions of the computed machine images, one message per version.
+	ListEntries(*ListEntriesRequest, MachineImages_ListEntriesServer) error
+	// Watch streams the current versions as ADDED events and afterwards an event for every change of the computed
+	// machine images.
+	Watch(*WatchRequest, MachineImages_WatchServer) error
+	mustEmbedUnimplementedMachineImagesServer()
+}
+
+// UnimplementedMachineImagesServer must be embedded to have forward compatible implementations.
+type UnimplementedMachineImagesServer struct {
+}
+
+func (UnimplementedMachineImagesServer) ListEntries(*ListEntriesRequest, MachineImages_ListEntriesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ListEntries not implemented")
+}
+func (UnimplementedMachineImagesServer) Watch(*WatchRequest, MachineImages_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedMachineImagesServer) mustEmbedUnimplementedMachineImagesServer() {}
+
+// UnsafeMachineImagesServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MachineImagesServer will
+// result in compilation errors.
+type UnsafeMachineImagesServer interface {
+	mustEmbedUnimplementedMachineImagesServer()
+}
+
+func RegisterMachineImagesServer(s grpc.ServiceRegistrar, srv MachineImagesServer) {
+	s.RegisterService(&MachineImages_ServiceDesc, srv)
+}
+
+func _MachineImages_ListEntries_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListEntriesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MachineImagesServer).ListEntries(m, &machineImagesListEntriesServer{stream})
+}
+
+type MachineImages_ListEntriesServer interface {
+	Send(*Entry) error
+	grpc.ServerStream
+}
+
+type machineImagesListEntriesServer struct {
+	grpc.ServerStream
+}
+
+func (x *machineImagesListEntriesServer) Send(m *Entry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MachineImages_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MachineImagesServer).Watch(m, &machineImagesWatchServer{stream})
+}
+
+type MachineImages_WatchServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type machineImagesWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *machineImagesWatchServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// MachineImages_ServiceDesc is the grpc.ServiceDesc for MachineImages service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MachineImages_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "machineimages.landscaper.gardener.cloud.v1alpha1.MachineImages",
+	HandlerType: (*MachineImagesServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListEntries",
+			Handler:       _MachineImages_ListEntries_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _MachineImages_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "machineimages/v1alpha1/machineimages.proto",
+}
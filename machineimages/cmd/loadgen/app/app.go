@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/loadgen"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	targetLibrary = "library"
+	targetServer  = "server"
+)
+
+type options struct {
+	generator loadgen.GeneratorOptions
+	run       loadgen.RunOptions
+	target    string
+	verbose   bool
+	output    string
+}
+
+// NewLoadgenCommand returns a command which measures computations over synthetic catalogs.
+func NewLoadgenCommand(ctx context.Context) *cobra.Command {
+	o := &options{}
+
+	cmd := &cobra.Command{
+		Use:   "loadgen",
+		Short: "Measures the throughput and latency of computations over synthetic catalogs",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			log, err := logger.NewCliLogger()
+			if err != nil {
+				return fmt.Errorf("unable to setup logger: %w", err)
+			}
+			logger.SetLogger(log)
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.validate(); err != nil {
+				return err
+			}
+			return o.execute(ctx)
+		},
+	}
+
+	logger.InitFlags(cmd.PersistentFlags())
+	o.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *options) addFlags(fs *pflag.FlagSet) {
+	fs.IntVar(&o.generator.Images, "images", 10, "The number of machine images of the catalog")
+	fs.IntVar(&o.generator.VersionsPerImage, "versions", 20, "The number of versions of every machine image")
+	fs.IntVar(&o.generator.Regions, "regions", 5, "The number of provider regions of every version")
+	fs.Float64Var(&o.generator.Churn, "churn", 0.05, "The fraction of versions which is replaced by newer versions in every iteration")
+	fs.Int64Var(&o.generator.Seed, "seed", 1, "The seed of the generated catalogs")
+	fs.IntVar(&o.run.Iterations, "iterations", 100, "The number of computations")
+	fs.IntVar(&o.run.Concurrency, "concurrency", 1, "The number of concurrent computations")
+	fs.StringVar(&o.target, "target", targetLibrary, "What computes the machine images: library or server")
+	fs.BoolVar(&o.verbose, "verbose-computations", false, "Log the computations instead of discarding their logs")
+	fs.StringVarP(&o.output, "output", "o", "table", "The output format: table or json")
+}
+
+func (o *options) validate() error {
+	if o.target != targetLibrary && o.target != targetServer {
+		return errors.New("the target must be library or server. ")
+	}
+	if o.output != "table" && o.output != "json" {
+		return errors.New("the output format must be table or json. ")
+	}
+	return nil
+}
+
+func (o *options) execute(ctx context.Context) error {
+	generator, err := loadgen.NewGenerator(o.generator)
+	if err != nil {
+		return err
+	}
+
+	log := logr.Discard()
+	if o.verbose {
+		log = logger.Log
+	}
+
+	var target loadgen.Target = &loadgen.LibraryTarget{Log: log}
+	if o.target == targetServer {
+		serverTarget, err := loadgen.NewServerTarget(log)
+		if err != nil {
+			return err
+		}
+		defer serverTarget.Close()
+		target = serverTarget
+	}
+
+	logger.Log.Info("Running load test", "target", o.target, "images", o.generator.Images,
+		"versions", o.generator.VersionsPerImage, "iterations", o.run.Iterations, "concurrency", o.run.Concurrency)
+	result, err := loadgen.Run(ctx, generator, target, o.run)
+	if err != nil {
+		return err
+	}
+
+	if o.output == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	return result.WriteTable(os.Stdout)
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build js && wasm
+// +build js,wasm
+
+// Command machineimages-wasm exposes the computation of machine images to javascript, so that landscape configuration
+// UIs can validate and preview the result in the browser with the same code as the landscaper deploy item.
+//
+// It registers the global functions
+//
+//	machineImagesCompute(imports) returning {exports} or {error}
+//	machineImagesExplain(imports, image, version) returning {explanation} or {error}
+//
+// where imports is the yaml or json content of an imports file and the results are json strings.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"syscall/js"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+func main() {
+	js.Global().Set("machineImagesCompute", js.FuncOf(compute))
+	js.Global().Set("machineImagesExplain", js.FuncOf(explain))
+
+	// keep the functions available until the page is closed
+	select {}
+}
+
+func compute(this js.Value, args []js.Value) interface{} {
+	if len(args) != 1 {
+		return errorResult(errors.New("expected the imports as argument"))
+	}
+
+	imports, err := parseImports(args[0])
+	if err != nil {
+		return errorResult(err)
+	}
+
+	exports, err := mi.ComputeExports(context.Background(), logr.Discard(), imports)
+	if err != nil {
+		return errorResult(err)
+	}
+	return result("exports", exports)
+}
+
+func explain(this js.Value, args []js.Value) interface{} {
+	if len(args) != 3 {
+		return errorResult(errors.New("expected the imports, the image and the version as arguments"))
+	}
+
+	imports, err := parseImports(args[0])
+	if err != nil {
+		return errorResult(err)
+	}
+
+	explanation, err := mi.ExplainVersion(context.Background(), logr.Discard(), imports, args[1].String(), args[2].String())
+	if err != nil {
+		return errorResult(err)
+	}
+	return result("explanation", explanation)
+}
+
+func parseImports(value js.Value) (*mi.Imports, error) {
+	if value.Type() != js.TypeString {
+		return nil, errors.New("the imports must be a string")
+	}
+
+	imports := &mi.Imports{}
+	if err := yaml.Unmarshal([]byte(value.String()), imports); err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+func result(key string, value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return errorResult(err)
+	}
+	return map[string]interface{}{key: string(data)}
+}
+
+func errorResult(err error) interface{} {
+	return map[string]interface{}{"error": err.Error()}
+}
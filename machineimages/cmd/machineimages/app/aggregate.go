@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+type aggregateOptions struct {
+	// Landscapes are the landscapes in the format "name=imports-path".
+	Landscapes []string
+	// Output is the output format, either "table" or "yaml".
+	Output string
+	pluginOptions
+}
+
+// NewAggregateCommand creates the command which shows the versions of several landscapes side by side.
+func NewAggregateCommand(ctx context.Context) *cobra.Command {
+	options := &aggregateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "aggregate",
+		Short: "Shows which machine image versions are live in which landscapes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.pluginOptions.complete(cmd); err != nil {
+				return mi.ClassifyError(err, mi.ErrorClassValidation)
+			}
+			if len(options.Landscapes) == 0 {
+				return mi.ClassifyError(errors.New("at least one landscape must be provided. "), mi.ErrorClassValidation)
+			}
+			if options.Output != "table" && options.Output != "yaml" {
+				return mi.ClassifyError(fmt.Errorf("unsupported output format %s", options.Output), mi.ErrorClassValidation)
+			}
+
+			return options.run(ctx)
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *aggregateOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringArrayVarP(&o.Landscapes, "landscape", "l", nil, "A landscape in the format name=imports-path, can be repeated")
+	fs.StringVarP(&o.Output, "output", "o", "table", "The output format, either table or yaml")
+}
+
+func (o *aggregateOptions) run(ctx context.Context) error {
+	landscapes := []mi.LandscapeImports{}
+	for _, landscape := range o.Landscapes {
+		parts := strings.SplitN(landscape, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return fmt.Errorf("invalid landscape %q, expected name=imports-path", landscape)
+		}
+
+		imports, err := o.readResolvedImports(ctx, parts[1])
+		if err != nil {
+			return err
+		}
+		landscapes = append(landscapes, mi.LandscapeImports{Name: parts[0], Imports: imports})
+	}
+
+	matrix, err := mi.AggregateLandscapes(ctx, logger.Log, landscapes)
+	if err != nil {
+		return err
+	}
+
+	if o.Output == "table" {
+		return matrix.WriteTable(os.Stdout)
+	}
+
+	out, err := yaml.Marshal(matrix)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
@@ -10,37 +10,78 @@ import (
 	"os"
 
 	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func NewComputeMachineImagesCommand(ctx context.Context) *cobra.Command {
 	options := newOptions()
+	transportOptions := &mi.TransportOptions{}
 
 	cmd := &cobra.Command{
 		Use:   "compute-machine-images",
 		Short: "Computes machine images",
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateErrorFormat(cmd); err != nil {
+				return mi.ClassifyError(err, mi.ErrorClassValidation)
+			}
+			if err := mi.ValidateFeatureGates(os.Getenv(mi.EnvVarFeatureGates)); err != nil {
+				return mi.ClassifyError(fmt.Errorf("invalid %s: %w", mi.EnvVarFeatureGates, err), mi.ErrorClassValidation)
+			}
+
 			log, err := logger.NewCliLogger()
 			if err != nil {
-				fmt.Println("unable to setup logger")
-				fmt.Println(err.Error())
-				os.Exit(1)
+				return fmt.Errorf("unable to setup logger: %w", err)
 			}
 			logger.SetLogger(log)
+
+			if err := mi.SetDefaultTransport(transportOptions); err != nil {
+				return mi.ClassifyError(fmt.Errorf("unable to setup network clients: %w", err), mi.ErrorClassFetch)
+			}
+			return nil
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := options.complete(); err != nil {
-				fmt.Print(err)
-				return err
+				return mi.ClassifyError(err, mi.ErrorClassValidation)
 			}
 
 			return options.run(ctx)
 		},
 	}
 
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return mi.ClassifyError(err, mi.ErrorClassValidation)
+	})
+
 	logger.InitFlags(cmd.PersistentFlags())
+	cmd.PersistentFlags().String(errorFormatFlag, ErrorFormatText, "The format of errors, either text or json. Failures exit with 2 for validation, 3 for fetch, 4 for policy, 5 for apply and 1 for other errors")
+	addTransportFlags(cmd.PersistentFlags(), transportOptions)
+	cmd.PersistentFlags().StringSliceVar(&options.AllowedPluginCommands, allowedPluginCommandsFlag, nil, "The executables which the plugins of the imports may run, e.g. /usr/local/bin/tagger, plugins with other executables are rejected")
 	options.addFlags(cmd.Flags())
 
+	cmd.AddCommand(NewBrowseCommand())
+	cmd.AddCommand(NewServeCommand(ctx))
+	cmd.AddCommand(NewWhatIfCommand(ctx))
+	cmd.AddCommand(NewAggregateCommand(ctx))
+	cmd.AddCommand(NewVerifyCommand())
+	cmd.AddCommand(NewConvertLegacyCommand())
+	cmd.AddCommand(NewRotateKeysCommand(ctx))
+	cmd.AddCommand(NewPromoteCandidateCommand(ctx))
+	cmd.AddCommand(NewHistoryCommand(ctx))
+	cmd.AddCommand(NewExtendExpirationCommand(ctx))
+	cmd.AddCommand(NewCapabilitiesCommand())
+	cmd.AddCommand(NewDiffCloudProfileCommand(ctx))
+	cmd.AddCommand(NewAuditCommand(ctx))
+
 	return cmd
 }
+
+func addTransportFlags(fs *pflag.FlagSet, options *mi.TransportOptions) {
+	fs.StringVar(&options.ProxyURL, "proxy", "", "The proxy of all outbound requests, defaults to the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables")
+	fs.StringSliceVar(&options.NoProxy, "no-proxy", nil, "Hosts, domains, ips or cidrs which are reached without the proxy")
+	fs.StringVar(&options.CABundleFile, "ca-bundle", "", "The path to pem encoded certificates which are trusted in addition to the system certificates")
+	fs.StringVar((*string)(&options.IPFamily), "ip-family", "", "Restricts outbound connections to ipv4 or ipv6, by default both are used")
+	fs.StringSliceVar(&options.DNSServers, "dns-server", nil, "DNS servers which resolve the hosts of outbound requests instead of the servers of the node, e.g. 10.0.0.10 or [fd00::10]:53")
+}
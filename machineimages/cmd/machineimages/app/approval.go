@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+// approvalOptions configure the approval gate of the commands which apply machine images.
+type approvalOptions struct {
+	// ApprovalThreshold is the impact level from which changes of the machine images need an approval before they are
+	// applied. The change is computed against the machine images which were applied last.
+	ApprovalThreshold string
+	// ApprovalRemovedVersions, ApprovalAffectedShoots and ApprovalProviders are the limits of the impact score from
+	// which changes need an approval, see mi.ApprovalThreshold. Zero is no limit.
+	ApprovalRemovedVersions int
+	ApprovalAffectedShoots  int
+	ApprovalProviders       int
+	// ApprovalShootsPath is the path to a yaml list of the machine image versions used by shoots, whose affected shoots
+	// are counted in the impact score.
+	ApprovalShootsPath string
+	// ApprovalStore references the store in which the applied machine images are recorded and, without approval
+	// webhook, changes are acknowledged. It is either a directory or a configmap:// or crd:// reference.
+	ApprovalStore string
+	// ApprovalWebhookURL is the url of an endpoint which approves changes.
+	ApprovalWebhookURL string
+	// ApprovalWebhookTokenPath is the path to the bearer token of the approval webhook.
+	ApprovalWebhookTokenPath string
+}
+
+func (o *approvalOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.ApprovalThreshold, "approval-threshold", "", "The impact from which changes of the machine images need an approval before they are applied, one of addition, change or removal")
+	fs.IntVar(&o.ApprovalRemovedVersions, "approval-removed-versions", 0, "The number of removed versions from which changes need an approval")
+	fs.IntVar(&o.ApprovalAffectedShoots, "approval-affected-shoots", 0, "The number of affected shoots from which changes need an approval")
+	fs.IntVar(&o.ApprovalProviders, "approval-providers", 0, "The number of touched providers from which changes need an approval")
+	fs.StringVar(&o.ApprovalShootsPath, "approval-shoots", "", "The path to a yaml list of shoot, image and version of the shoots, whose affected shoots are counted")
+	fs.StringVar(&o.ApprovalStore, "approval-store", "", "The directory or the configmap://<namespace>/<name> or crd://<namespace>/<name> reference of the store which records the applied machine images and in which changes are acknowledged")
+	fs.StringVar(&o.ApprovalWebhookURL, "approval-webhook", "", "The url of an endpoint which approves changes")
+	fs.StringVar(&o.ApprovalWebhookTokenPath, "approval-webhook-token-file", "", "The path to the bearer token of the approval webhook")
+}
+
+// validate checks the approval flags. Without approval store, the applied machine images are recorded in the state
+// store, if the command has one.
+func (o *approvalOptions) validate(hasStateStore bool) error {
+	if o.gatesApproval() {
+		if err := o.approvalThreshold().Validate(); err != nil {
+			return fmt.Errorf("invalid approval threshold: %w. ", err)
+		}
+		if len(o.ApprovalWebhookURL) == 0 && len(o.ApprovalStore) == 0 {
+			return errors.New("an approval store or an approval webhook must be provided together with the approval threshold. ")
+		}
+		if len(o.ApprovalStore) == 0 && !hasStateStore {
+			return errors.New("an approval store must be provided together with the approval webhook, to record the applied machine images. ")
+		}
+	} else if len(o.ApprovalStore) > 0 || len(o.ApprovalWebhookURL) > 0 || len(o.ApprovalShootsPath) > 0 {
+		return errors.New("an approval threshold must be provided together with the approval store, webhook or shoots. ")
+	}
+
+	if len(o.ApprovalWebhookTokenPath) > 0 && len(o.ApprovalWebhookURL) == 0 {
+		return errors.New("an approval webhook must be provided together with the approval webhook token. ")
+	}
+	return nil
+}
+
+// gatesApproval returns whether changes are checked by an approval gate.
+func (o *approvalOptions) gatesApproval() bool {
+	return len(o.ApprovalThreshold) > 0 || o.ApprovalRemovedVersions != 0 || o.ApprovalAffectedShoots != 0 || o.ApprovalProviders != 0
+}
+
+func (o *approvalOptions) approvalThreshold() *mi.ApprovalThreshold {
+	return &mi.ApprovalThreshold{
+		Impact:          mi.ImpactLevel(o.ApprovalThreshold),
+		RemovedVersions: o.ApprovalRemovedVersions,
+		AffectedShoots:  o.ApprovalAffectedShoots,
+		Providers:       o.ApprovalProviders,
+	}
+}
+
+// approval returns the approval gate of the flags. The applied machine images are recorded in the approval store, or
+// in the given store without approval store.
+func (o *approvalOptions) approval(applied state.Store) (*state.Approval, error) {
+	shoots, err := o.shoots()
+	if err != nil {
+		return nil, err
+	}
+	approval := &state.Approval{Threshold: o.approvalThreshold(), Store: applied, Shoots: shoots}
+
+	if len(o.ApprovalStore) > 0 {
+		store, err := state.NewStore(o.ApprovalStore)
+		if err != nil {
+			return nil, err
+		}
+		approval.Store = store
+		approval.Gate = state.NewAcknowledgementGate(store)
+	}
+	if len(o.ApprovalWebhookURL) > 0 {
+		webhook := &mi.WebhookApprovalGate{URL: o.ApprovalWebhookURL}
+		if len(o.ApprovalWebhookTokenPath) > 0 {
+			token, err := ioutil.ReadFile(o.ApprovalWebhookTokenPath)
+			if err != nil {
+				return nil, err
+			}
+			webhook.Token = strings.TrimSpace(string(token))
+		}
+		approval.Gate = webhook
+	}
+	return approval, approval.Validate()
+}
+
+// shoots returns the shoots of the shoots path, which are counted in the impact scores.
+func (o *approvalOptions) shoots() ([]mi.ShootMachineImage, error) {
+	shoots := []mi.ShootMachineImage{}
+	if len(o.ApprovalShootsPath) == 0 {
+		return shoots, nil
+	}
+	data, err := ioutil.ReadFile(o.ApprovalShootsPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &shoots); err != nil {
+		return nil, fmt.Errorf("unable to parse shoots %s: %w", o.ApprovalShootsPath, err)
+	}
+	return shoots, nil
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/attestation"
+)
+
+// writeAttestation writes a signed provenance attestation which binds the imports file to the written exports file.
+func (o *options) writeAttestation(ctx context.Context, started time.Time) error {
+	signer, err := attestation.NewSigner(ctx, o.AttestationKeyPath)
+	if err != nil {
+		return err
+	}
+
+	imports, err := ioutil.ReadFile(o.ImportsPath)
+	if err != nil {
+		return err
+	}
+	exports, err := ioutil.ReadFile(o.ExportsPath)
+	if err != nil {
+		return err
+	}
+
+	finished := time.Now()
+	statement, err := attestation.NewStatement(
+		[]attestation.Artifact{{Name: filepath.Base(o.ImportsPath), Content: imports}},
+		[]attestation.Artifact{{Name: filepath.Base(o.ExportsPath), Content: exports}},
+		&attestation.StatementOptions{StartedOn: &started, FinishedOn: &finished},
+	)
+	if err != nil {
+		return err
+	}
+
+	envelope, err := attestation.Sign(ctx, statement, signer)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Info("Writing attestation", "attestation-path", o.AttestationPath, "keyid", signer.KeyID())
+	return ioutil.WriteFile(o.AttestationPath, data, os.ModePerm)
+}
@@ -0,0 +1,137 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/cloudprofile"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+type auditOptions struct {
+	// ImportsPath is the path to the imports file.
+	ImportsPath string
+	// Kubeconfig is the path to the kubeconfig of the garden cluster, or a list of paths like $KUBECONFIG. Defaults to
+	// $KUBECONFIG and then to the cluster in which the process runs.
+	Kubeconfig string
+	// CloudProfile is the name of the live cloud profile.
+	CloudProfile string
+	// Output is the output format, either "yaml" or "json".
+	Output string
+	pluginOptions
+}
+
+// NewAuditCommand creates the command which compares a live cloud profile to the machine images computed from the
+// sources without changing anything. It fails with a policy error if the audit reports any finding.
+func NewAuditCommand(ctx context.Context) *cobra.Command {
+	options := &auditOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Reports unmanaged, missing, drifted and expired machine image versions of a live cloud profile without changing anything",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.pluginOptions.complete(cmd); err != nil {
+				return mi.ClassifyError(err, mi.ErrorClassValidation)
+			}
+			if len(options.ImportsPath) == 0 {
+				options.ImportsPath = os.Getenv(EnvVarImportsPath)
+			}
+			if len(options.Kubeconfig) == 0 {
+				options.Kubeconfig = os.Getenv("KUBECONFIG")
+			}
+			if len(options.ImportsPath) == 0 {
+				return mi.ClassifyError(errors.New("an imports path must be provided. "), mi.ErrorClassValidation)
+			}
+			if len(options.CloudProfile) == 0 {
+				return mi.ClassifyError(errors.New("a cloud profile must be provided. "), mi.ErrorClassValidation)
+			}
+			if options.Output != "yaml" && options.Output != "json" {
+				return mi.ClassifyError(fmt.Errorf("unsupported output format %s", options.Output), mi.ErrorClassValidation)
+			}
+
+			return options.run(ctx)
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *auditOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.ImportsPath, "imports-path", "i", "", "The path to the imports file")
+	fs.StringVar(&o.Kubeconfig, "kubeconfig", "", "The path to the kubeconfig of the garden cluster, defaults to $KUBECONFIG and then to the cluster in which the process runs")
+	fs.StringVar(&o.CloudProfile, "cloud-profile", "", "The name of the live cloud profile")
+	fs.StringVarP(&o.Output, "output", "o", "yaml", "The output format, either yaml or json")
+}
+
+func (o *auditOptions) run(ctx context.Context) error {
+	imports, err := o.readResolvedImports(ctx, o.ImportsPath)
+	if err != nil {
+		return err
+	}
+
+	var config *state.KubernetesConfig
+	if len(o.Kubeconfig) > 0 {
+		config, err = state.KubeconfigConfig(o.Kubeconfig)
+	} else {
+		config, err = state.InClusterConfig()
+	}
+	if err != nil {
+		return mi.ClassifyError(err, mi.ErrorClassValidation)
+	}
+
+	logger.Log.Info("Reading cloud profile", "cloud-profile", o.CloudProfile)
+	data, err := config.GetCloudProfile(ctx, o.CloudProfile)
+	if err != nil {
+		return mi.ClassifyError(fmt.Errorf("unable to read cloud profile %s: %w", o.CloudProfile, err), mi.ErrorClassFetch)
+	}
+	live, err := cloudprofile.DecodeCloudProfile(data)
+	if err != nil {
+		return mi.ClassifyError(fmt.Errorf("unable to parse cloud profile %s: %w", o.CloudProfile, err), mi.ErrorClassFetch)
+	}
+
+	computed, err := mi.ComputeMachineImagesFromImports(ctx, logger.Log, imports)
+	if err != nil {
+		return err
+	}
+
+	report, err := cloudprofile.AuditCloudProfileWithOptions(computed, live, nil, time.Now(), imports.Diff)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	if o.Output == "json" {
+		out, err = json.MarshalIndent(report, "", "  ")
+		out = append(out, '\n')
+	} else {
+		out, err = yaml.Marshal(report)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stdout.Write(out); err != nil {
+		return err
+	}
+
+	if !report.Clean() {
+		return mi.ClassifyError(fmt.Errorf("the cloud profile %s has unmanaged, missing, drifted or expired versions", o.CloudProfile),
+			mi.ErrorClassPolicy)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/browser"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+type browseOptions struct {
+	// CatalogDir is the root directory of the catalog.
+	CatalogDir string
+	// Include are the glob patterns of the catalog files.
+	Include []string
+	// Exclude are the glob patterns of files which are not part of the catalog.
+	Exclude []string
+	// Strictness decides whether malformed catalog entries fail the command or are skipped.
+	Strictness string
+}
+
+// NewBrowseCommand creates the command to explore a catalog interactively.
+func NewBrowseCommand() *cobra.Command {
+	options := &browseOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "browse",
+		Short: "Explores a machine image catalog interactively",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.CatalogDir) == 0 {
+				return mi.ClassifyError(errors.New("a catalog directory must be provided. "), mi.ErrorClassValidation)
+			}
+
+			catalog, err := mi.LoadCatalog(options.CatalogDir, &mi.CatalogLoadOptions{
+				Include:    options.Include,
+				Exclude:    options.Exclude,
+				Strictness: mi.CatalogStrictness(options.Strictness),
+			})
+			if err != nil {
+				return err
+			}
+			for _, skip := range catalog.Skipped {
+				fmt.Fprintf(os.Stderr, "skipped invalid catalog entry %s\n", skip)
+			}
+
+			return browser.Run(catalog)
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *browseOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.CatalogDir, "catalog-dir", "c", "", "The root directory of the catalog")
+	fs.StringSliceVar(&o.Include, "include", nil, "Glob patterns of the catalog files")
+	fs.StringSliceVar(&o.Exclude, "exclude", nil, "Glob patterns of files which are not part of the catalog")
+	fs.StringVar(&o.Strictness, "catalog-strictness", string(mi.CatalogStrict), "Whether malformed catalog entries fail the command (strict) or are skipped (lenient)")
+}
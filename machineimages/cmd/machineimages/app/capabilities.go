@@ -0,0 +1,67 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+type capabilitiesOptions struct {
+	// Output is the output format, either "yaml" or "json".
+	Output string
+}
+
+// NewCapabilitiesCommand creates the command which prints the capabilities of this version.
+func NewCapabilitiesCommand() *cobra.Command {
+	options := &capabilitiesOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "capabilities",
+		Short: "Prints the supported catalog formats, filter kinds, providers and feature gates of this version",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.Output != "yaml" && options.Output != "json" {
+				return mi.ClassifyError(fmt.Errorf("unsupported output format %s", options.Output), mi.ErrorClassValidation)
+			}
+
+			return options.run()
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *capabilitiesOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.Output, "output", "o", "yaml", "The output format, either yaml or json")
+}
+
+func (o *capabilitiesOptions) run() error {
+	capabilities := mi.GetCapabilities()
+
+	var (
+		out []byte
+		err error
+	)
+	if o.Output == "json" {
+		out, err = json.MarshalIndent(capabilities, "", "  ")
+		out = append(out, '\n')
+	} else {
+		out, err = yaml.Marshal(capabilities)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
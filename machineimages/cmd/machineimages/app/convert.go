@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+type convertLegacyOptions struct {
+	// LandscapePath is the path to the acre.yaml of the garden-setup landscape.
+	LandscapePath string
+	// OutputDir is the directory to which the catalog files are written.
+	OutputDir string
+}
+
+// NewConvertLegacyCommand creates the command which converts the machine images of a garden-setup landscape into
+// catalog files.
+func NewConvertLegacyCommand() *cobra.Command {
+	options := &convertLegacyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "convert-legacy",
+		Short: "Converts the machine images of a garden-setup acre.yaml into catalog files",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.LandscapePath) == 0 {
+				return mi.ClassifyError(errors.New("a landscape path must be provided. "), mi.ErrorClassValidation)
+			}
+			if len(options.OutputDir) == 0 {
+				return mi.ClassifyError(errors.New("an output directory must be provided. "), mi.ErrorClassValidation)
+			}
+
+			return options.run()
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *convertLegacyOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.LandscapePath, "landscape", "l", "", "The path to the acre.yaml of the garden-setup landscape")
+	fs.StringVarP(&o.OutputDir, "output-dir", "o", "", "The directory to which the catalog files are written")
+}
+
+func (o *convertLegacyOptions) run() error {
+	data, err := ioutil.ReadFile(o.LandscapePath)
+	if err != nil {
+		return err
+	}
+
+	conversion, err := mi.ConvertLegacyLandscape(data)
+	if err != nil {
+		return err
+	}
+	for _, warning := range conversion.Warnings {
+		logger.Log.Info("Warning: " + warning)
+	}
+
+	if err := os.MkdirAll(o.OutputDir, 0755); err != nil {
+		return err
+	}
+
+	if err := o.writeCatalogFile("machine-images.yaml", conversion.Catalog); err != nil {
+		return err
+	}
+	for _, providerType := range conversion.ProviderTypes() {
+		if err := o.writeCatalogFile("machine-images-"+providerType+".yaml", conversion.ProviderCatalogs[providerType]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *convertLegacyOptions) writeCatalogFile(name string, file *mi.CatalogFile) error {
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(o.OutputDir, name)
+	logger.Log.Info("Writing catalog file", "path", path)
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/cloudprofile"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+type diffCloudProfileOptions struct {
+	// ImportsPath is the path to the imports file.
+	ImportsPath string
+	// CloudProfilePath is the path to the yaml file with the existing cloud profile.
+	CloudProfilePath string
+	// Output is the output format, either "yaml" or "json".
+	Output string
+	pluginOptions
+}
+
+// NewDiffCloudProfileCommand creates the command which compares the computed machine images to an existing cloud
+// profile.
+func NewDiffCloudProfileCommand(ctx context.Context) *cobra.Command {
+	options := &diffCloudProfileOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "diff-cloud-profile",
+		Short: "Shows how the computed machine images would change the machine images of an existing cloud profile",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.pluginOptions.complete(cmd); err != nil {
+				return mi.ClassifyError(err, mi.ErrorClassValidation)
+			}
+			if len(options.ImportsPath) == 0 {
+				options.ImportsPath = os.Getenv(EnvVarImportsPath)
+			}
+			if len(options.ImportsPath) == 0 {
+				return mi.ClassifyError(errors.New("an imports path must be provided. "), mi.ErrorClassValidation)
+			}
+			if len(options.CloudProfilePath) == 0 {
+				return mi.ClassifyError(errors.New("a cloud profile path must be provided. "), mi.ErrorClassValidation)
+			}
+			if options.Output != "yaml" && options.Output != "json" {
+				return mi.ClassifyError(fmt.Errorf("unsupported output format %s", options.Output), mi.ErrorClassValidation)
+			}
+
+			return options.run(ctx)
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *diffCloudProfileOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.ImportsPath, "imports-path", "i", "", "The path to the imports file")
+	fs.StringVar(&o.CloudProfilePath, "cloud-profile", "", "The path to the yaml file with the existing cloud profile")
+	fs.StringVarP(&o.Output, "output", "o", "yaml", "The output format, either yaml or json")
+}
+
+func (o *diffCloudProfileOptions) run(ctx context.Context) error {
+	imports, err := o.readResolvedImports(ctx, o.ImportsPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(o.CloudProfilePath)
+	if err != nil {
+		return err
+	}
+	profile, err := cloudprofile.DecodeCloudProfile(data)
+	if err != nil {
+		return fmt.Errorf("unable to parse cloud profile %s: %w", o.CloudProfilePath, err)
+	}
+
+	computed, err := mi.ComputeMachineImagesFromImports(ctx, logger.Log, imports)
+	if err != nil {
+		return err
+	}
+
+	diff, err := cloudprofile.ComputeMachineImagesDiffWithOptions(computed, &profile.Spec, nil, imports.Diff)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	if o.Output == "json" {
+		out, err = json.MarshalIndent(diff, "", "  ")
+		out = append(out, '\n')
+	} else {
+		out, err = yaml.Marshal(diff)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
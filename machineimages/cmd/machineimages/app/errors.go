@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// Exit codes of the commands per class of failure, see mi.ErrorClass. They are stable, so that pipelines can branch on
+// them.
+const (
+	// ExitCodeInternal is the exit code of unclassified failures.
+	ExitCodeInternal   = 1
+	ExitCodeValidation = 2
+	ExitCodeFetch      = 3
+	ExitCodePolicy     = 4
+	ExitCodeApply      = 5
+)
+
+// Formats of the errors of the commands.
+const (
+	ErrorFormatText = "text"
+	ErrorFormatJSON = "json"
+)
+
+const errorFormatFlag = "error-format"
+
+// errorClassInternal is the class of unclassified failures in the json error format.
+const errorClassInternal = "internal"
+
+var exitCodes = map[mi.ErrorClass]int{
+	mi.ErrorClassValidation: ExitCodeValidation,
+	mi.ErrorClassFetch:      ExitCodeFetch,
+	mi.ErrorClassPolicy:     ExitCodePolicy,
+	mi.ErrorClassApply:      ExitCodeApply,
+}
+
+// errorOutput is an error in the json error format.
+type errorOutput struct {
+	Class    string `json:"class"`
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error"`
+	// Problems are the problems of invalid imports.
+	Problems []string `json:"problems,omitempty"`
+	// ApprovalID is the id of the change which waits for an approval.
+	ApprovalID string `json:"approvalID,omitempty"`
+}
+
+// ExitCode returns the exit code of the class of the error.
+func ExitCode(err error) int {
+	if code, ok := exitCodes[mi.ClassOf(err)]; ok {
+		return code
+	}
+	return ExitCodeInternal
+}
+
+// Execute executes the command with the arguments and returns the exit code. Failures are written in the error
+// format of the arguments: text errors to stdout, json errors to stderr. The error format is read from the arguments
+// instead of the parsed flags, so that also the errors of flags which cannot be parsed are written in json.
+func Execute(cmd *cobra.Command, args []string, stdout, stderr io.Writer) int {
+	format := errorFormat(args)
+	if format == ErrorFormatJSON {
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+	}
+	cmd.SetArgs(args)
+	cmd.SetErr(stderr)
+	if err := cmd.Execute(); err != nil {
+		if format == ErrorFormatJSON {
+			return HandleError(format, stderr, err)
+		}
+		return HandleError(format, stdout, err)
+	}
+	return 0
+}
+
+// HandleError writes the error of an executed command in the error format and returns the exit code.
+func HandleError(format string, out io.Writer, err error) int {
+	code := ExitCode(err)
+	if format != ErrorFormatJSON {
+		fmt.Fprint(out, err)
+		return code
+	}
+
+	output := &errorOutput{Class: string(mi.ClassOf(err)), ExitCode: code, Error: err.Error()}
+	if len(output.Class) == 0 {
+		output.Class = errorClassInternal
+	}
+	var validationErr *mi.ValidationError
+	if errors.As(err, &validationErr) {
+		output.Problems = validationErr.Problems
+	}
+	var pendingErr *mi.ApprovalPendingError
+	if errors.As(err, &pendingErr) {
+		output.ApprovalID = pendingErr.Request.ID
+	}
+	data, marshalErr := json.Marshal(output)
+	if marshalErr != nil {
+		fmt.Fprint(out, err)
+		return code
+	}
+	fmt.Fprintln(out, string(data))
+	return code
+}
+
+// errorFormat returns the last error format of the arguments, or the text format.
+func errorFormat(args []string) string {
+	format := ErrorFormatText
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return format
+		case strings.HasPrefix(arg, "--"+errorFormatFlag+"="):
+			format = strings.TrimPrefix(arg, "--"+errorFormatFlag+"=")
+		case arg == "--"+errorFormatFlag && i+1 < len(args):
+			format = args[i+1]
+		}
+	}
+	return format
+}
+
+// validateErrorFormat returns an error if the error format of the command is unknown.
+func validateErrorFormat(cmd *cobra.Command) error {
+	format, _ := cmd.Root().PersistentFlags().GetString(errorFormatFlag)
+	switch format {
+	case ErrorFormatText, ErrorFormatJSON:
+		return nil
+	}
+	return fmt.Errorf("unsupported error format %s", format)
+}
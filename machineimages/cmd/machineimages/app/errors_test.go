@@ -0,0 +1,154 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("errors", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "errors")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "imports.yaml"), []byte(`
+machineImages:
+- name: ubuntu
+  versions:
+  - version: 22.4.0
+machineImagesProvider:
+- name: ubuntu
+  versions:
+  - version: 22.4.0
+    image: a
+`), 0600)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(dir, "exports"), 0700)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	execute := func(args ...string) (int, string, string) {
+		for i, arg := range args {
+			args[i] = os.Expand(arg, func(name string) string { return dir })
+		}
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := Execute(NewComputeMachineImagesCommand(context.Background()), args, stdout, stderr)
+		return code, stdout.String(), stderr.String()
+	}
+	parse := func(stderr string) *errorOutput {
+		output := &errorOutput{}
+		Expect(json.Unmarshal([]byte(stderr), output)).To(Succeed())
+		return output
+	}
+
+	for _, entry := range []struct {
+		name  string
+		args  []string
+		class string
+		code  int
+		error string
+	}{
+		{"an unknown flag before the error format", []string{"--unknown", "--error-format=json"},
+			"validation", ExitCodeValidation, "unknown flag: --unknown"},
+		{"an invalid flag value after the error format", []string{"--error-format", "json", "--history-revisions=many"},
+			"validation", ExitCodeValidation, `invalid argument "many" for "--history-revisions" flag: strconv.ParseInt: parsing "many": invalid syntax`},
+		{"missing options", []string{"--error-format=json"},
+			"validation", ExitCodeValidation, "an imports path must be provided. "},
+		{"an unreadable ca bundle", []string{"--error-format=json", "--ca-bundle", "$dir/missing.pem"},
+			"fetch", ExitCodeFetch, "unable to setup network clients: open $dir/missing.pem: no such file or directory"},
+		{"a pending approval", []string{"--error-format=json", "-i", "$dir/imports.yaml", "-e", "$dir/exports.yaml",
+			"--approval-threshold", "addition", "--approval-store", "$dir/approvals"},
+			"policy", ExitCodePolicy, ""},
+		{"unwritable exports", []string{"--error-format=json", "-i", "$dir/imports.yaml", "-e", "$dir/exports"},
+			"apply", ExitCodeApply, "open $dir/exports: is a directory"},
+	} {
+		entry := entry
+		It("should write json errors of "+entry.name+" to stderr", func() {
+			code, stdout, stderr := execute(entry.args...)
+			Expect(code).To(Equal(entry.code))
+			Expect(stdout).To(BeEmpty())
+			output := parse(stderr)
+			Expect(output.Class).To(Equal(entry.class))
+			Expect(output.ExitCode).To(Equal(entry.code))
+			if len(entry.error) > 0 {
+				Expect(output.Error).To(Equal(os.Expand(entry.error, func(string) string { return dir })))
+			}
+			if entry.class == "policy" {
+				Expect(output.ApprovalID).NotTo(BeEmpty())
+			}
+		})
+	}
+
+	It("should list the problems of invalid imports", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "imports.yaml"), []byte("includeFilters: [unknown]\n"), 0600)).To(Succeed())
+		code, _, stderr := execute("--error-format=json", "-i", "$dir/imports.yaml", "-e", "$dir/exports.yaml")
+		Expect(code).To(Equal(ExitCodeValidation))
+		Expect(parse(stderr).Problems).To(Equal([]string{`includeFilters: unknown filter "unknown"`}))
+	})
+
+	It("should reject plugins whose commands are not allowed", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "imports.yaml"), []byte(`
+plugins:
+- name: tagger
+  command: [/bin/true]
+`), 0600)).To(Succeed())
+		code, _, stderr := execute("--error-format=json", "-i", "$dir/imports.yaml", "-e", "$dir/exports.yaml",
+			"--allowed-plugin-commands", "/usr/local/bin/tagger")
+		Expect(code).To(Equal(ExitCodePolicy))
+		Expect(parse(stderr).Error).To(Equal("the command /bin/true of plugin tagger is not allowed, see --allowed-plugin-commands"))
+	})
+
+	It("should reject plugins of the environment", func() {
+		Expect(os.Setenv("MACHINEIMAGES_PLUGINS", `[{name: tagger, command: [/bin/true]}]`)).To(Succeed())
+		defer os.Unsetenv("MACHINEIMAGES_PLUGINS")
+		code, _, stderr := execute("--error-format=json", "-i", "$dir/imports.yaml", "-e", "$dir/exports.yaml",
+			"--allowed-plugin-commands", "/bin/true")
+		Expect(code).To(Equal(ExitCodeValidation))
+		Expect(parse(stderr).Error).To(Equal("the imports field plugins cannot be set from environment variable MACHINEIMAGES_PLUGINS, it must be set in the imports file"))
+		_, err := os.Stat(filepath.Join(dir, "exports.yaml"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("should not share the allowed plugin commands between commands", func() {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "imports.yaml"), []byte(`
+plugins:
+- name: tagger
+  command: [/bin/true]
+`), 0600)).To(Succeed())
+		cmd := NewComputeMachineImagesCommand(context.Background())
+		Expect(cmd.PersistentFlags().Set(allowedPluginCommandsFlag, "/bin/true")).To(Succeed())
+
+		code, _, stderr := execute("--error-format=json", "-i", "$dir/imports.yaml", "-e", "$dir/exports.yaml")
+		Expect(code).To(Equal(ExitCodePolicy))
+		Expect(parse(stderr).Error).To(ContainSubstring("is not allowed"))
+	})
+
+	It("should write text errors to stdout", func() {
+		code, stdout, stderr := execute("--unknown")
+		Expect(code).To(Equal(ExitCodeValidation))
+		Expect(stdout).To(Equal("unknown flag: --unknown"))
+		Expect(stderr).To(HavePrefix("Error: unknown flag: --unknown"))
+	})
+
+	It("should classify unclassified errors as internal", func() {
+		out := &bytes.Buffer{}
+		Expect(HandleError(ErrorFormatJSON, out, errors.New("boom"))).To(Equal(ExitCodeInternal))
+		Expect(out.String()).To(Equal(`{"class":"internal","exitCode":1,"error":"boom"}` + "\n"))
+	})
+})
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+type extendExpirationOptions struct {
+	// CatalogDirs are the root directories of the catalogs.
+	CatalogDirs []string
+	// Images restricts the extension to these images.
+	Images []string
+	// Constraint selects the versions which are extended.
+	Constraint string
+	// Until is the new expiration date, either a timestamp or a date.
+	Until string
+	// Reason and Actor are recorded in the audit entry.
+	Reason string
+	Actor  string
+	// AuditLogPath is the path to the file to which the audit entry is appended.
+	AuditLogPath string
+	// DryRun prints the audit entry without writing the catalogs and the audit log.
+	DryRun bool
+}
+
+// NewExtendExpirationCommand creates the command which extends the expiration dates of versions in catalogs.
+func NewExtendExpirationCommand(ctx context.Context) *cobra.Command {
+	options := &extendExpirationOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "extend-expiration",
+		Short: "Extends the expiration dates of the selected versions of catalogs",
+		Long: "Extends the expiration dates of the versions of catalogs which match the images and the constraint and " +
+			"expire before the new date, e.g. to postpone forced upgrades during a change freeze. The extended " +
+			"versions are printed and appended as audit entry to the audit log.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.CatalogDirs) == 0 {
+				return mi.ClassifyError(errors.New("a catalog directory must be provided. "), mi.ErrorClassValidation)
+			}
+			if len(options.Until) == 0 {
+				return mi.ClassifyError(errors.New("an expiration date must be provided. "), mi.ErrorClassValidation)
+			}
+			if len(options.Reason) == 0 {
+				return mi.ClassifyError(errors.New("a reason must be provided. "), mi.ErrorClassValidation)
+			}
+			if len(options.AuditLogPath) == 0 && !options.DryRun {
+				return mi.ClassifyError(errors.New("an audit log must be provided. "), mi.ErrorClassValidation)
+			}
+
+			return options.run(ctx)
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *extendExpirationOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringSliceVarP(&o.CatalogDirs, "catalog-dir", "c", nil, "The root directories of the catalogs")
+	fs.StringSliceVar(&o.Images, "image", nil, "Only extend versions of these images, defaults to all images")
+	fs.StringVar(&o.Constraint, "constraint", "", "Only extend versions which satisfy the version constraint, e.g. <934.8")
+	fs.StringVar(&o.Until, "until", "", "The new expiration date, e.g. 2024-04-30 or 2024-04-30T23:59:59Z")
+	fs.StringVar(&o.Reason, "reason", "", "The reason of the extension, which is recorded in the audit entry")
+	fs.StringVar(&o.Actor, "actor", os.Getenv("USER"), "The actor who extends the versions, which is recorded in the audit entry")
+	fs.StringVar(&o.AuditLogPath, "audit-log", "", "The path to the file to which the audit entry is appended as json line")
+	fs.BoolVar(&o.DryRun, "dry-run", false, "Print the versions which would be extended without writing the catalogs and the audit log")
+}
+
+func (o *extendExpirationOptions) run(ctx context.Context) error {
+	until, err := parseTime(o.Until)
+	if err != nil {
+		return err
+	}
+
+	audit, err := mi.ExtendExpirations(mi.NewContext(ctx, logger.Log, nil), o.CatalogDirs, &mi.ExpirationExtension{
+		Images:         o.Images,
+		Constraint:     o.Constraint,
+		ExpirationDate: until,
+		Reason:         o.Reason,
+		Actor:          o.Actor,
+		DryRun:         o.DryRun,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !o.DryRun {
+		logger.Log.Info("Writing audit entry", "audit-log", o.AuditLogPath, "versions", len(audit.Versions))
+		if err := mi.AppendExpirationAudit(o.AuditLogPath, audit); err != nil {
+			return err
+		}
+	}
+
+	out, err := yaml.Marshal(audit)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+type historyOptions struct {
+	// StateStore references the store of the revision history.
+	StateStore string
+	// StateEncryptionKeyPath is the path to the key which encrypts the documents of the state store.
+	StateEncryptionKeyPath string
+	// StateDecryptionKeyPaths are the paths to further keys which decrypt documents of the state store.
+	StateDecryptionKeyPaths []string
+	// At is the time at which the catalog is printed, either a timestamp or a date.
+	At string
+	// Image and Version select the version whose timeline is printed.
+	Image   string
+	Version string
+	// Output is the output format, either "yaml" or "json".
+	Output string
+}
+
+// revisionSummary is a revision without its machine images.
+type revisionSummary struct {
+	Number   int       `json:"number"`
+	Time     time.Time `json:"time"`
+	Images   int       `json:"images"`
+	Versions int       `json:"versions"`
+}
+
+// NewHistoryCommand creates the command which queries the revision history of a state store.
+func NewHistoryCommand(ctx context.Context) *cobra.Command {
+	options := &historyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Queries the revision history of the catalog in a state store",
+		Long: "Queries the revision history which computations with --history record in a state store. Without " +
+			"query, the revisions are listed. With --at, the catalog at the time is printed, and with --image and " +
+			"--version the periods in which the version was part of the catalog.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.StateStore) == 0 {
+				return mi.ClassifyError(errors.New("a state store must be provided. "), mi.ErrorClassValidation)
+			}
+			if (len(options.Image) > 0) != (len(options.Version) > 0) {
+				return mi.ClassifyError(errors.New("an image must be provided together with a version. "), mi.ErrorClassValidation)
+			}
+			if len(options.At) > 0 && len(options.Image) > 0 {
+				return mi.ClassifyError(errors.New("only one of at and version must be provided. "), mi.ErrorClassValidation)
+			}
+			if options.Output != "yaml" && options.Output != "json" {
+				return mi.ClassifyError(fmt.Errorf("unsupported output format %s", options.Output), mi.ErrorClassValidation)
+			}
+
+			return options.run(ctx)
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *historyOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.StateStore, "state-store", "", "The directory or the configmap://<namespace>/<name> or crd://<namespace>/<name> reference of the state store of the history")
+	fs.StringVar(&o.StateEncryptionKeyPath, "state-encryption-key", "", "The path to the base64 encoded AES-256 key which encrypts the state store")
+	fs.StringSliceVar(&o.StateDecryptionKeyPaths, "state-decryption-key", nil, "The paths to further keys which decrypt the state store")
+	fs.StringVar(&o.At, "at", "", "Print the catalog at the time, e.g. 2024-03-01 or 2024-03-01T12:00:00Z")
+	fs.StringVar(&o.Image, "image", "", "The image of the version whose timeline is printed")
+	fs.StringVar(&o.Version, "version", "", "Print when the version of the image was added to and removed from the catalog")
+	fs.StringVarP(&o.Output, "output", "o", "yaml", "The output format, either yaml or json")
+}
+
+func (o *historyOptions) run(ctx context.Context) error {
+	store, err := newStateStore(o.StateStore, o.StateEncryptionKeyPath, o.StateDecryptionKeyPaths)
+	if err != nil {
+		return err
+	}
+	history, err := state.LoadHistory(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	var result interface{}
+	switch {
+	case len(o.At) > 0:
+		at, err := parseTime(o.At)
+		if err != nil {
+			return err
+		}
+		revision := history.At(at)
+		if revision == nil {
+			return fmt.Errorf("the history has no revision at %s", o.At)
+		}
+		result = revision
+	case len(o.Image) > 0:
+		result = history.Timeline(o.Image, o.Version)
+	default:
+		summaries := make([]revisionSummary, 0, len(history.Revisions))
+		for _, revision := range history.Revisions {
+			summary := revisionSummary{Number: revision.Number, Time: revision.Time, Images: len(revision.MachineImages)}
+			for _, image := range revision.MachineImages {
+				summary.Versions += len(image.Versions)
+			}
+			summaries = append(summaries, summary)
+		}
+		result = summaries
+	}
+
+	var out []byte
+	if o.Output == "json" {
+		out, err = json.MarshalIndent(result, "", "  ")
+		out = append(out, '\n')
+	} else {
+		out, err = yaml.Marshal(result)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// parseTime parses a timestamp or a date, which is the end of the day, so that --at 2024-03-01 includes the
+// revisions of the day.
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t.Add(24*time.Hour - time.Nanosecond), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected a timestamp like 2006-01-02T15:04:05Z or a date", value)
+}
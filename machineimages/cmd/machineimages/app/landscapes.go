@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// validateLandscapes validates the options of the landscapes mode. The outputs and the state of a single landscape
+// are not supported together with the landscapes.
+func (o *options) validateLandscapes() error {
+	if len(o.ImportsPath) > 0 || len(o.ExportsPath) > 0 {
+		return errors.New("the imports and exports path must not be provided together with the landscapes. ")
+	}
+	if len(o.SoakStatePath) > 0 || len(o.StateStore) > 0 || o.Channels || o.History || o.gatesApproval() {
+		return errors.New("the soak state, state store, channels, history and approval must not be provided together with the landscapes. ")
+	}
+	if len(o.CycloneDXPath) > 0 || len(o.TerraformVariablesPath) > 0 || len(o.CAPIImageLookupPath) > 0 ||
+		len(o.ImageVectorPath) > 0 || len(o.PartitionsDir) > 0 || len(o.PrewarmPath) > 0 || len(o.AttestationPath) > 0 || o.ScopeToSeedRegions {
+		return errors.New("the outputs of a single landscape must not be provided together with the landscapes. ")
+	}
+	if len(o.LandscapeImportsFile) == 0 {
+		return errors.New("a landscape imports file must be provided. ")
+	}
+	if o.ValidateOnly && len(o.LandscapeExportsFile) > 0 {
+		return errors.New("the landscape exports file must not be provided together with validate only. ")
+	}
+	return nil
+}
+
+// runLandscapes validates or computes all landscape directories of the glob concurrently and writes the report of all
+// landscapes to stdout. It fails with the class of the first failed landscape if any landscape failed. The imports are
+// overridden with the environment variables and flags like the imports of a single landscape, and outbound requests
+// identify with the landscape of the flags.
+func (o *options) runLandscapes(ctx context.Context) error {
+	ctx = mi.WithClientIdentity(ctx, mi.ClientIdentity{Landscape: o.Landscape})
+	matches, err := filepath.Glob(o.Landscapes)
+	if err != nil {
+		return mi.ClassifyError(fmt.Errorf("invalid landscapes glob: %w", err), mi.ErrorClassValidation)
+	}
+	sources := []mi.LandscapeSource{}
+	for _, match := range matches {
+		if info, err := os.Stat(match); err != nil || !info.IsDir() {
+			continue
+		}
+		dir := match
+		sources = append(sources, mi.LandscapeSource{
+			Name: dir,
+			Load: func(ctx context.Context) (*mi.Imports, error) {
+				imports, err := o.readImports(ctx, filepath.Join(dir, o.LandscapeImportsFile))
+				if err != nil || len(imports.Focus) == 0 || len(o.LandscapeExportsFile) == 0 {
+					return imports, err
+				}
+				// the focused images are merged into the exports of the previous run
+				imports.FocusBaseline, err = readExports(filepath.Join(dir, o.LandscapeExportsFile))
+				return imports, mi.ClassifyError(err, mi.ErrorClassFetch)
+			},
+		})
+	}
+	if len(sources) == 0 {
+		return mi.ClassifyError(fmt.Errorf("no landscape directories match %s", o.Landscapes), mi.ErrorClassValidation)
+	}
+
+	logger.Log.Info("Processing landscapes", "landscapes", len(sources), "parallelism", o.LandscapesParallelism)
+	landscapesOptions := &mi.LandscapesOptions{
+		Parallelism:       o.LandscapesParallelism,
+		ValidateOnly:      o.ValidateOnly,
+		SelectionResolver: newSelectionResolver(),
+		SecretResolver:    newSecretResolver(),
+	}
+	if len(o.LandscapeExportsFile) > 0 {
+		landscapesOptions.Previous = func(ctx context.Context, name string) (*mi.Exports, error) {
+			return readExports(filepath.Join(name, o.LandscapeExportsFile))
+		}
+		landscapesOptions.Computed = func(ctx context.Context, name string, exports *mi.Exports) error {
+			data, err := yaml.Marshal(exports)
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(name, o.LandscapeExportsFile)
+			logger.Log.Info("Writing exports", "exports-path", path)
+			return ioutil.WriteFile(path, data, os.ModePerm)
+		}
+	}
+	report := mi.RunLandscapes(ctx, logger.Log, sources, landscapesOptions)
+
+	out, err := yaml.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stdout.Write(out); err != nil {
+		return err
+	}
+
+	for _, result := range report.Landscapes {
+		if len(result.Error) == 0 {
+			continue
+		}
+		err := fmt.Errorf("%d of %d landscapes failed", report.Failed, len(report.Landscapes))
+		if len(result.ErrorClass) > 0 {
+			return mi.ClassifyError(err, result.ErrorClass)
+		}
+		return err
+	}
+	return nil
+}
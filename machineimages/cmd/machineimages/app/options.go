@@ -7,15 +7,22 @@ package app
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
 
 	"sigs.k8s.io/yaml"
 
+	"github.com/gardener/landscaper-utils/machineimages/pkg/cloudprofile"
 	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/engine"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
 
 	"github.com/spf13/pflag"
 )
@@ -30,6 +37,83 @@ type options struct {
 	ImportsPath string
 	// ExportsPath is the path to the exports file.
 	ExportsPath string
+	// SoakStatePath is the path to the file in which the first seen times of the computed versions are tracked.
+	SoakStatePath string
+	// StateStore references the store of the soak state, either a directory or a configmap:// or crd:// reference.
+	StateStore string
+	// StateEncryptionKeyPath is the path to the base64 encoded key which encrypts the documents of the state store.
+	StateEncryptionKeyPath string
+	// StateDecryptionKeyPaths are the paths to further keys which decrypt documents of the state store during a key
+	// rotation.
+	StateDecryptionKeyPaths []string
+	// Channels maintains a current and a candidate catalog in the state store. The computed machine images become the
+	// candidate, the current catalog only changes with the promote-candidate command.
+	Channels bool
+	// History records the computed machine images as revisions in the revision history of the state store.
+	History bool
+	// HistoryRevisions is the number of revisions which the history keeps. Defaults to mi.DefaultHistoryRevisions.
+	HistoryRevisions int
+	// Landscape is the name of the landscape under which the versions are tracked.
+	Landscape string
+	// CycloneDXPath is the path to which a CycloneDX bom of the computed machine images is written.
+	CycloneDXPath string
+	// TerraformVariablesPath is the path to which the image ids of the computed machine images are written as
+	// Terraform variables. Files ending with .json are written as terraform.tfvars.json, all others as hcl.
+	TerraformVariablesPath string
+	// CAPIImageLookupPath is the path to which the image references of the computed machine images are written in the
+	// formats of the Cluster API providers.
+	CAPIImageLookupPath string
+	// ImageVectorPath is the path to which the OCI artifacts of the computed machine images are written in the image
+	// vector format of gardener.
+	ImageVectorPath string
+	// PartitionsDir is the directory to which the computed machine images are written per provider, with only the
+	// fields the provider understands.
+	PartitionsDir string
+	// PartitionProviders are the providers of the partitions. Defaults to all known providers.
+	PartitionProviders []string
+	// PrewarmPath is the path to which the artifacts of the computed machine images are written per seed region, for
+	// the pre-pull tooling of registry and image caches.
+	PrewarmPath string
+	// PrewarmRegions are the seed regions of the pre-warm manifest. Defaults to the regions of the machine images.
+	PrewarmRegions []string
+	// CloudProfilePath is the path to which a cloud profile with the computed machine images is written.
+	CloudProfilePath string
+	// CloudProfileInputsPath is the path to the yaml inputs of the cloud profile, see cloudprofile.Inputs, without
+	// machine images.
+	CloudProfileInputsPath string
+	// CloudProfileName is the name of the cloud profile. Defaults to the type of the cloud profile inputs.
+	CloudProfileName string
+	// CloudProfileAPIVersion overrides the target api version of the cloud profile inputs.
+	CloudProfileAPIVersion string
+	// CloudProfileCapabilities describes the architectures of the cloud profile with machine capabilities.
+	CloudProfileCapabilities bool
+	// ScopeToSeedRegions scopes the machine images to the regions of the seeds of the garden cluster in which the
+	// process runs, in addition to the regions of the region scope of the imports.
+	ScopeToSeedRegions bool
+	// SeedProviderType restricts the seeds of ScopeToSeedRegions to a provider type.
+	SeedProviderType string
+	// AttestationPath is the path to which a signed provenance attestation of the computation is written.
+	AttestationPath string
+	// AttestationKeyPath references the key which signs the attestation. It is either the path to a pem encoded private
+	// key or a vault://, awskms:// or gcpkms:// key reference.
+	AttestationKeyPath string
+	approvalOptions
+	pluginOptions
+	// Landscapes is a glob of landscape configuration directories, e.g. "landscapes/*". The landscapes are validated or
+	// computed concurrently instead of the imports path, and a report of all landscapes is written to stdout.
+	Landscapes string
+	// LandscapeImportsFile is the name of the imports file in the landscape directories.
+	LandscapeImportsFile string
+	// LandscapeExportsFile is the name of the file in the landscape directories to which the exports are written. If
+	// empty, the exports are not written.
+	LandscapeExportsFile string
+	// LandscapesParallelism is the number of landscapes which are processed concurrently.
+	LandscapesParallelism int
+	// ValidateOnly only validates the imports of the landscapes.
+	ValidateOnly bool
+
+	// importsBinding overrides fields of the imports with environment variables and flags.
+	importsBinding *mi.ImportsBinding
 }
 
 func newOptions() *options {
@@ -39,10 +123,46 @@ func newOptions() *options {
 func (o *options) addFlags(fs *pflag.FlagSet) {
 	fs.StringVarP(&o.ImportsPath, "imports-path", "i", "", "The path to the imports file")
 	fs.StringVarP(&o.ExportsPath, "exports-path", "e", "", "The path to the exports file")
+	fs.StringVar(&o.SoakStatePath, "soak-state", "", "The path to the file which tracks since when versions are live")
+	fs.StringVar(&o.StateStore, "state-store", "", "The directory or the configmap://<namespace>/<name> or crd://<namespace>/<name> reference of the store which tracks since when versions are live")
+	fs.StringVar(&o.StateEncryptionKeyPath, "state-encryption-key", "", "The path to the base64 encoded AES-256 key which encrypts the state store")
+	fs.StringSliceVar(&o.StateDecryptionKeyPaths, "state-decryption-key", nil, "The paths to further keys which decrypt the state store, e.g. the old key during a key rotation")
+	fs.BoolVar(&o.Channels, "channels", false, "Export the current catalog of the state store and the computed machine images as candidate, which becomes current with promote-candidate")
+	fs.BoolVar(&o.History, "history", false, "Record the computed machine images in the revision history of the state store, which is queried with the history command")
+	fs.IntVar(&o.HistoryRevisions, "history-revisions", mi.DefaultHistoryRevisions, "The number of revisions which the revision history keeps")
+	fs.StringVar(&o.Landscape, "landscape", "", "The name of the landscape under which versions are tracked in the soak state")
+	fs.StringVar(&o.CycloneDXPath, "cyclonedx-path", "", "The path to which a CycloneDX bom of the machine images is written")
+	fs.StringVar(&o.TerraformVariablesPath, "tfvars-path", "", "The path to which the image ids of the machine images are written as Terraform variables, in json if the path ends with .json")
+	fs.StringVar(&o.CAPIImageLookupPath, "capi-path", "", "The path to which the image references of the machine images are written in the formats of the Cluster API providers")
+	fs.StringVar(&o.ImageVectorPath, "image-vector-path", "", "The path to which the OCI artifacts of the machine images and their aliases are written in the image vector format of gardener")
+	fs.StringVar(&o.PartitionsDir, "partitions-dir", "", "The directory to which the machine images are written as <provider>.yaml exports per provider, with only the fields the provider understands")
+	fs.StringSliceVar(&o.PartitionProviders, "partition-providers", nil, "The providers of the partitions, defaults to all known providers")
+	fs.StringVar(&o.PrewarmPath, "prewarm-path", "", "The path to which the artifacts of the machine images are written per seed region, to pre-warm registry and image caches")
+	fs.StringSliceVar(&o.PrewarmRegions, "prewarm-regions", nil, "The seed regions of the pre-warm manifest, defaults to the regions of the machine images")
+	fs.StringVar(&o.CloudProfilePath, "cloud-profile-path", "", "The path to which a cloud profile with the machine images is written")
+	fs.StringVar(&o.CloudProfileInputsPath, "cloud-profile-inputs", "", "The path to the yaml inputs of the cloud profile, e.g. the type, regions and machine types, without machine images")
+	fs.StringVar(&o.CloudProfileName, "cloud-profile-name", "", "The name of the cloud profile, defaults to the type of the cloud profile inputs")
+	fs.StringVar(&o.CloudProfileAPIVersion, "cloud-profile-api-version", "", fmt.Sprintf("The api version of the cloud profile, one of %s, defaults to the target api version of the cloud profile inputs", strings.Join(cloudprofile.SupportedAPIVersions(), ", ")))
+	fs.BoolVar(&o.CloudProfileCapabilities, "cloud-profile-capabilities", false, "Describe the architectures of the machine images and machine types of the cloud profile with machine capabilities")
+	fs.BoolVar(&o.ScopeToSeedRegions, "scope-to-seed-regions", false, "Scope the machine images to the regions of the seeds of the garden cluster in which the process runs")
+	fs.StringVar(&o.SeedProviderType, "seed-provider-type", "", "Only scope the machine images to the regions of the seeds of the provider type")
+	fs.StringVar(&o.AttestationPath, "attestation-path", "", "The path to which a signed in-toto attestation of the computation is written")
+	fs.StringVar(&o.AttestationKeyPath, "attestation-key", "", "The path to the pem encoded private key or the vault://, awskms:// or gcpkms:// reference of the key which signs the attestation")
+	o.approvalOptions.addFlags(fs)
+	fs.StringVar(&o.Landscapes, "landscapes", "", "A glob of landscape directories, e.g. landscapes/*, which are validated or computed concurrently instead of the imports path")
+	fs.StringVar(&o.LandscapeImportsFile, "landscape-imports-file", "imports.yaml", "The name of the imports file in the landscape directories")
+	fs.StringVar(&o.LandscapeExportsFile, "landscape-exports-file", "", "The name of the file in the landscape directories to which the exports are written, by default they are not written")
+	fs.IntVar(&o.LandscapesParallelism, "landscapes-parallelism", mi.DefaultLandscapesParallelism, "The number of landscapes which are processed concurrently")
+	fs.BoolVar(&o.ValidateOnly, "validate-only", false, "Only validate the imports of the landscapes")
+	o.importsBinding = mi.NewImportsBinding(fs)
 }
 
 // complete parses all options and flags and initializes the basic functions
 func (o *options) complete() error {
+	if len(o.Landscapes) > 0 {
+		return o.validateLandscapes()
+	}
+
 	if len(o.ImportsPath) == 0 {
 		o.ImportsPath = os.Getenv(EnvVarImportsPath)
 	}
@@ -63,64 +183,581 @@ func (o *options) validate() error {
 		return errors.New("an exports path must be provided. ")
 	}
 
+	if len(o.SoakStatePath) > 0 && len(o.StateStore) > 0 {
+		return errors.New("only one of soak state and state store must be provided. ")
+	}
+
+	if (len(o.StateEncryptionKeyPath) > 0 || len(o.StateDecryptionKeyPaths) > 0) && len(o.StateStore) == 0 {
+		return errors.New("a state store must be provided together with the state encryption keys. ")
+	}
+
+	if len(o.StateDecryptionKeyPaths) > 0 && len(o.StateEncryptionKeyPath) == 0 {
+		return errors.New("a state encryption key must be provided together with the state decryption keys. ")
+	}
+
+	if o.Channels && len(o.StateStore) == 0 {
+		return errors.New("a state store must be provided together with the channels. ")
+	}
+
+	if o.History && len(o.StateStore) == 0 {
+		return errors.New("a state store must be provided together with the history. ")
+	}
+
+	if o.tracksSoak() && len(o.Landscape) == 0 {
+		return errors.New("a landscape must be provided together with the soak state. ")
+	}
+
+	if len(o.SeedProviderType) > 0 && !o.ScopeToSeedRegions {
+		return errors.New("the seed provider type must only be provided together with the scope to seed regions. ")
+	}
+
+	if (len(o.CloudProfilePath) > 0) != (len(o.CloudProfileInputsPath) > 0) {
+		return errors.New("the cloud profile path and the cloud profile inputs must be provided together. ")
+	}
+
+	if len(o.CloudProfilePath) == 0 && (len(o.CloudProfileName) > 0 || len(o.CloudProfileAPIVersion) > 0 || o.CloudProfileCapabilities) {
+		return errors.New("the cloud profile name, api version and capabilities must only be provided together with the cloud profile path. ")
+	}
+
+	if len(o.AttestationPath) > 0 && len(o.AttestationKeyPath) == 0 {
+		return errors.New("an attestation key must be provided together with the attestation path. ")
+	}
+
+	if err := o.approvalOptions.validate(len(o.StateStore) > 0); err != nil {
+		return err
+	}
+	if o.gatesApproval() && o.Channels {
+		return errors.New("the approval threshold must not be provided together with the channels, which are approved with promote-candidate. ")
+	}
+
+	if o.ValidateOnly || len(o.LandscapeExportsFile) > 0 {
+		return errors.New("the validate only and landscape exports file flags must only be provided together with the landscapes. ")
+	}
+
 	return nil
 }
 
+// tracksSoak returns whether the soak state is tracked. With channels or history, the state store only tracks the soak
+// state if a landscape is provided.
+func (o *options) tracksSoak() bool {
+	return len(o.SoakStatePath) > 0 || len(o.StateStore) > 0 && (!o.Channels && !o.History || len(o.Landscape) > 0)
+}
+
 func (o *options) run(ctx context.Context) error {
-	imports, err := o.readImports()
+	if len(o.Landscapes) > 0 {
+		return o.runLandscapes(ctx)
+	}
+
+	started := time.Now()
+	ctx = mi.WithClientIdentity(ctx, mi.ClientIdentity{Landscape: o.Landscape})
+
+	var (
+		imports *mi.Imports
+		report  *mi.Report
+		applied []mi.MachineImage
+	)
+	source := engine.SourceFunc(func(ctx context.Context) (*mi.Imports, error) {
+		var err error
+		if imports, err = o.readImports(ctx, o.ImportsPath); err != nil {
+			return nil, err
+		}
+		if len(imports.Focus) > 0 {
+			if imports.FocusBaseline, err = o.focusBaseline(ctx); err != nil {
+				return nil, mi.ClassifyError(err, mi.ErrorClassFetch)
+			}
+		}
+		if imports.Notifications != nil && imports.Reporter == nil {
+			report = mi.NewReport()
+			imports.Reporter = report
+			// the applied machine images are read before they are replaced by the computed ones
+			if applied, err = o.appliedMachineImages(ctx); err != nil {
+				return nil, mi.ClassifyError(err, mi.ErrorClassFetch)
+			}
+		}
+		return imports, nil
+	})
+
+	builder := engine.NewBuilder().
+		WithLogger(logger.Log).
+		WithSource(source).
+		WithSelectionResolver(newSelectionResolver()).
+		WithSecretResolver(newSecretResolver())
+	if err := o.configureEngine(ctx, builder, started); err != nil {
+		return err
+	}
+	e, err := builder.Build()
 	if err != nil {
 		return err
 	}
 
-	result, err := mi.ComputeMachineImages(
-		context.Background(),
-		logger.Log,
-		imports.MachineImages,
-		imports.MachineImagesLs,
-		imports.MachineImagesProvider,
-		imports.MachineImagesProviderLs,
-		imports.DisableMachineImages,
-		imports.IncludeFilters,
-		imports.ExcludeFilters,
-	)
+	result, err := e.Run(ctx)
+	if report != nil && (err == nil || mi.ClassOf(err) != mi.ErrorClassValidation) {
+		var computed []mi.MachineImage
+		if result != nil {
+			computed = result.MachineImages
+		}
+		o.notifyMaintainers(ctx, imports, report, applied, computed, err)
+	}
+	return err
+}
+
+// configureEngine adds the approval, the result stages, the emitters and the appliers of the options to the engine.
+func (o *options) configureEngine(ctx context.Context, builder *engine.Builder, started time.Time) error {
+	appliedStore, err := o.appliedStore()
+	if err != nil {
+		return mi.ClassifyError(err, mi.ErrorClassFetch)
+	}
+	if o.gatesApproval() {
+		approval, err := o.approval(appliedStore)
+		if err != nil {
+			return mi.ClassifyError(err, mi.ErrorClassFetch)
+		}
+		builder.WithApproval(approval)
+	}
+
+	var stateStore state.Store
+	if len(o.StateStore) > 0 {
+		if stateStore, err = newStateStore(o.StateStore, o.StateEncryptionKeyPath, o.StateDecryptionKeyPaths); err != nil {
+			return mi.ClassifyError(err, mi.ErrorClassFetch)
+		}
+	}
+	builder.WithResultStage(o.keepCosmeticExports)
+	// the history records the computed machine images, also if the channels export the current catalog
+	if o.History {
+		builder.WithResultStage(engine.HistoryApplier(stateStore, o.HistoryRevisions).Apply)
+	}
+	if o.Channels {
+		builder.WithResultStage(func(ctx context.Context, result *engine.Result) error {
+			exports, err := o.updateChannels(ctx, stateStore, result.Imports, result.Exports)
+			if err != nil {
+				return err
+			}
+			result.Exports = exports
+			result.MachineImages = exports.ResultMachineImages
+			return nil
+		})
+	}
+
+	if len(o.CycloneDXPath) > 0 {
+		builder.WithEmitter(engine.CycloneDXFile(o.CycloneDXPath, nil))
+	}
+	if len(o.TerraformVariablesPath) > 0 {
+		builder.WithEmitter(engine.TerraformVariablesFile(o.TerraformVariablesPath, nil))
+	}
+	if len(o.CAPIImageLookupPath) > 0 {
+		builder.WithEmitter(engine.CAPIImageLookupFile(o.CAPIImageLookupPath))
+	}
+	if len(o.ImageVectorPath) > 0 {
+		builder.WithEmitter(engine.ImageVectorFile(o.ImageVectorPath))
+	}
+	if len(o.PartitionsDir) > 0 {
+		builder.WithEmitter(engine.EmitterFunc(o.writePartitions))
+	}
+	if len(o.PrewarmPath) > 0 {
+		builder.WithEmitter(engine.PrewarmManifestFile(o.PrewarmPath, &mi.PrewarmOptions{Regions: o.PrewarmRegions}))
+	}
+	if len(o.CloudProfilePath) > 0 {
+		inputs, err := o.cloudProfileInputs()
+		if err != nil {
+			return mi.ClassifyError(err, mi.ErrorClassValidation)
+		}
+		name := o.CloudProfileName
+		if len(name) == 0 {
+			name = inputs.Type
+		}
+		builder.WithEmitter(engine.CloudProfileFile(o.CloudProfilePath, name, inputs))
+	}
+	builder.WithEmitter(engine.ExportsFile(o.ExportsPath))
+
+	if o.tracksSoak() {
+		if stateStore != nil {
+			builder.WithApplier(engine.SoakApplier(stateStore, o.Landscape))
+		} else {
+			builder.WithApplier(engine.ApplierFunc(o.trackSoak))
+		}
+	}
+	// with approval, the engine records the applied machine images
+	if !o.gatesApproval() && appliedStore != nil {
+		builder.WithApplier(engine.ApplierFunc(func(ctx context.Context, result *engine.Result) error {
+			return state.SaveApplied(ctx, appliedStore, result.MachineImages)
+		}))
+	}
+	if len(o.AttestationPath) > 0 {
+		builder.WithApplier(engine.ApplierFunc(func(ctx context.Context, _ *engine.Result) error {
+			return o.writeAttestation(ctx, started)
+		}))
+	}
+	return nil
+}
+
+// writePartitions writes the machine images of the result per provider to the partitions directory.
+func (o *options) writePartitions(ctx context.Context, result *engine.Result) error {
+	disabled := result.Imports.DisabledProviders()
+	if len(disabled) > 0 {
+		logger.Log.Info("Skipping partitions of disabled providers", "providers", disabled)
+	}
+	partitions, err := mi.PartitionByProvider(result.MachineImages, &mi.PartitionOptions{Providers: o.PartitionProviders, Disabled: disabled})
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(o.PartitionsDir, 0700); err != nil {
+		return err
+	}
+	for provider, partition := range partitions {
+		data, err := yaml.Marshal(&mi.Exports{ResultMachineImages: partition})
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(o.PartitionsDir, provider+".yaml")
+		logger.Log.Info("Writing provider partition", "path", path)
+		if err := ioutil.WriteFile(path, data, os.ModePerm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cloudProfileInputs reads the cloud profile inputs and applies the api version and capabilities flags.
+func (o *options) cloudProfileInputs() (*cloudprofile.Inputs, error) {
+	data, err := ioutil.ReadFile(o.CloudProfileInputsPath)
+	if err != nil {
+		return nil, err
+	}
+	inputs := &cloudprofile.Inputs{}
+	if err := yaml.UnmarshalStrict(data, inputs); err != nil {
+		return nil, fmt.Errorf("unable to parse cloud profile inputs %s: %w", o.CloudProfileInputsPath, err)
+	}
+	if len(inputs.MachineImages) > 0 {
+		return nil, fmt.Errorf("the cloud profile inputs %s must not contain machine images, they are computed", o.CloudProfileInputsPath)
+	}
+	if len(o.CloudProfileAPIVersion) > 0 {
+		inputs.TargetAPIVersion = o.CloudProfileAPIVersion
+	}
+	if _, err := cloudprofile.ConvertCloudProfile(&cloudprofile.CloudProfile{}, inputs.TargetAPIVersion); err != nil {
+		return nil, err
+	}
+	inputs.Capabilities = inputs.Capabilities || o.CloudProfileCapabilities
+	return inputs, nil
+}
+
+// trackSoak records the versions of the machine images of the result which the landscape did not see before in the
+// soak state file.
+func (o *options) trackSoak(ctx context.Context, result *engine.Result) error {
+	tracker, err := mi.LoadSoakTracker(o.SoakStatePath)
 	if err != nil {
 		return err
 	}
+	if !tracker.Observe(o.Landscape, result.MachineImages, time.Now()) {
+		return nil
+	}
 
-	err = o.writeExports(&mi.Exports{ResultMachineImages: result})
+	logger.Log.Info("Writing soak state", "soak-state", o.SoakStatePath)
+	return tracker.Save(o.SoakStatePath)
+}
+
+// keepCosmeticExports keeps the machine images of the previous exports if the computed machine images only differ
+// cosmetically from them, see mi.KeepCosmeticExports.
+func (o *options) keepCosmeticExports(ctx context.Context, result *engine.Result) error {
+	if result.Imports.Diff == nil {
+		return nil
+	}
+	previous, err := readExports(o.ExportsPath)
+	if err != nil {
+		return err
+	}
+	exports, err := mi.KeepCosmeticExports(result.Imports, previous, result.Exports)
+	if err != nil || exports == result.Exports {
+		return err
+	}
+	logger.Log.Info("Keeping the previous machine images, the computed machine images only differ cosmetically", "exports-path", o.ExportsPath)
+	result.Exports = exports
+	result.MachineImages, err = resultMachineImages(exports)
 	return err
 }
 
-func (o *options) readImports() (*mi.Imports, error) {
-	logger.Log.Info("Reading imports", "imports-path", o.ImportsPath)
+// focusBaseline returns the machine images into which the focused images are merged: the candidate channel with
+// channels, otherwise the applied machine images of the approval or the state store or the previous exports. It returns
+// nil if none of them exists, e.g. before the first run.
+func (o *options) focusBaseline(ctx context.Context) (*mi.Exports, error) {
+	if o.Channels {
+		store, err := newStateStore(o.StateStore, o.StateEncryptionKeyPath, o.StateDecryptionKeyPaths)
+		if err != nil {
+			return nil, err
+		}
+		channels, err := state.LoadChannels(ctx, store)
+		if err != nil || channels.Candidate == nil {
+			return nil, err
+		}
+		return &mi.Exports{ResultMachineImages: channels.Candidate}, nil
+	}
 
-	data, err := ioutil.ReadFile(o.ImportsPath)
+	store, err := o.appliedStore()
 	if err != nil {
 		return nil, err
 	}
+	if store != nil {
+		baseline, err := state.LoadFocusBaseline(ctx, store)
+		if err != nil || baseline != nil {
+			return baseline, err
+		}
+	}
+	return readExports(o.ExportsPath)
+}
+
+// appliedStore returns the store which records the applied machine images, the approval store or the state store, or
+// nil without both.
+func (o *options) appliedStore() (state.Store, error) {
+	if len(o.ApprovalStore) > 0 {
+		return state.NewStore(o.ApprovalStore)
+	}
+	if len(o.StateStore) > 0 {
+		return newStateStore(o.StateStore, o.StateEncryptionKeyPath, o.StateDecryptionKeyPaths)
+	}
+	return nil, nil
+}
 
-	imports := &mi.Imports{}
-	if err := yaml.Unmarshal(data, imports); err != nil {
+// appliedMachineImages returns the machine images which were applied last according to the approval or the state
+// store. Without both, the machine images of the previous exports are returned, a missing exports file is treated as
+// empty.
+func (o *options) appliedMachineImages(ctx context.Context) ([]mi.MachineImage, error) {
+	store, err := o.appliedStore()
+	if err != nil {
 		return nil, err
 	}
+	if store != nil {
+		applied, _, err := state.LoadApplied(ctx, store)
+		return applied, err
+	}
 
-	return imports, nil
+	previous, err := readExports(o.ExportsPath)
+	if err != nil || previous == nil {
+		return []mi.MachineImage{}, err
+	}
+	return resultMachineImages(previous)
 }
 
-func (o *options) writeExports(exports *mi.Exports) error {
-	logger.Log.Info("Writing imports", "exports-path", o.ExportsPath)
+// readExports reads the previous exports of the path, or returns nil if the file does not exist.
+func readExports(path string) (*mi.Exports, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	previous := &mi.Exports{}
+	if err := yaml.Unmarshal(data, previous); err != nil {
+		return nil, fmt.Errorf("unable to parse previous exports %s: %w", path, err)
+	}
+	return previous, nil
+}
 
-	b, err := yaml.Marshal(exports)
+// notifyMaintainers sends the changes from the previously applied machine images with their impact on the approval
+// shoots, the findings and the error of the computation to the maintainers of the imports. Cosmetic differences of the
+// diff options of the imports are no changes. With an approval or a state store, the notifications which the
+// maintainers received last are not sent again. Failures to notify are logged, they do not fail the computation.
+func (o *options) notifyMaintainers(ctx context.Context, imports *mi.Imports, report *mi.Report, applied, computed []mi.MachineImage, computeErr error) {
+	shoots, err := o.shoots()
 	if err != nil {
-		return err
+		logger.Log.Error(err, "Unable to read the shoots for the notifications")
+		return
+	}
+	if computeErr == nil {
+		applied, err = imports.Diff.NormalizeMachineImages(applied)
+		if err == nil {
+			computed, err = imports.Diff.NormalizeMachineImages(computed)
+		}
+		if err != nil {
+			logger.Log.Error(err, "Unable to compute the changes for the notifications")
+			return
+		}
 	}
 
-	parentPath := filepath.Dir(o.ExportsPath)
-	if _, err := os.Stat(parentPath); os.IsNotExist(err) {
-		if err := os.MkdirAll(parentPath, 0700); err != nil {
-			return err
+	store, err := o.appliedStore()
+	if err != nil {
+		logger.Log.Error(err, "Unable to open the store of the sent notifications")
+		return
+	}
+	var sent *mi.SentNotifications
+	if store != nil {
+		if sent, err = state.LoadSentNotifications(ctx, store); err != nil {
+			logger.Log.Error(err, "Unable to read the sent notifications")
+			return
+		}
+	}
+
+	ctx = mi.NewContext(ctx, logger.Log, nil)
+	if err := mi.NotifyMaintainers(ctx, imports.Notifications, o.Landscape, applied, computed, shoots, report.Entries(), computeErr, sent); err != nil {
+		logger.Log.Error(err, "Unable to notify the maintainers")
+	}
+	if sent != nil {
+		if err := state.SaveSentNotifications(ctx, store, sent); err != nil {
+			logger.Log.Error(err, "Unable to write the sent notifications")
+		}
+	}
+}
+
+// updateChannels sets the computed machine images as candidate of the channels in the state store and returns the
+// exports of the channels. The version aliases are resolved for the current channel.
+func (o *options) updateChannels(ctx context.Context, store state.Store, imports *mi.Imports, exports *mi.Exports) (*mi.Exports, error) {
+	if exports.ResultMachineImagesConfigMap != nil {
+		return nil, errors.New("the channels cannot be exported in a config map")
+	}
+
+	channels, err := state.LoadChannels(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	changed, err := channels.Update(exports.ResultMachineImages)
+	if err != nil {
+		return nil, err
+	}
+	if changed {
+		logger.Log.Info("Writing candidate channel", "state-store", o.StateStore)
+		if err := state.SaveChannels(ctx, store, channels); err != nil {
+			return nil, err
+		}
+	}
+	result := channels.Exports()
+	result.ResultWarnings = exports.ResultWarnings
+	if result.ResultVersionAliases, err = imports.ResolveVersionAliases(result.ResultMachineImages); err != nil {
+		return nil, mi.ClassifyError(err, mi.ErrorClassPolicy)
+	}
+	return result, nil
+}
+
+// resultMachineImages returns the computed machine images of the exports, also if they are exported in a config map.
+func resultMachineImages(exports *mi.Exports) ([]mi.MachineImage, error) {
+	if exports.ResultMachineImagesConfigMap != nil {
+		return mi.MachineImagesFromConfigMap(exports.ResultMachineImagesConfigMap, exports.ResultMachineImagesRef)
+	}
+	return exports.ResultMachineImages, nil
+}
+
+// readImports reads the imports file, overrides its fields with the environment variables and flags and rejects the
+// plugins of the final imports whose commands are not allowed. The selections and secrets of the imports are not
+// resolved and the imports are not validated, the engine or mi.RunLandscapes does that.
+func (o *options) readImports(ctx context.Context, importsPath string) (*mi.Imports, error) {
+	imports, err := loadImports(importsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.importsBinding != nil {
+		if err := o.importsBinding.Apply(imports, os.LookupEnv); err != nil {
+			return nil, mi.ClassifyError(err, mi.ErrorClassValidation)
+		}
+	}
+
+	if o.ScopeToSeedRegions {
+		if err := o.scopeToSeedRegions(ctx, imports); err != nil {
+			return nil, mi.ClassifyError(err, mi.ErrorClassFetch)
 		}
 	}
+	if err := o.checkPluginCommands(imports.Plugins); err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+// scopeToSeedRegions adds the regions of the seeds of the cluster in which the process runs to the region scope of the
+// imports.
+func (o *options) scopeToSeedRegions(ctx context.Context, imports *mi.Imports) error {
+	config, err := state.InClusterConfig()
+	if err != nil {
+		return err
+	}
+	regions, err := config.SeedRegions(ctx, o.SeedProviderType)
+	if err != nil {
+		return err
+	}
+	if len(regions) == 0 {
+		return fmt.Errorf("no seeds found to scope the machine images to, provider type %q", o.SeedProviderType)
+	}
+
+	logger.Log.Info("Scoping machine images to seed regions", "regions", regions)
+	if imports.RegionScope == nil {
+		imports.RegionScope = &mi.RegionScope{}
+	}
+	active := map[string]bool{}
+	for _, region := range append(imports.RegionScope.Regions, regions...) {
+		active[region] = true
+	}
+	imports.RegionScope.Regions = make([]string, 0, len(active))
+	for region := range active {
+		imports.RegionScope.Regions = append(imports.RegionScope.Regions, region)
+	}
+	sort.Strings(imports.RegionScope.Regions)
+	return nil
+}
+
+// newSecretResolver returns a resolver which reads secrets from the cluster in which the process runs.
+func newSecretResolver() *mi.SecretResolver {
+	return &mi.SecretResolver{
+		GetSecretKey: func(ctx context.Context, namespace, name, key string) ([]byte, error) {
+			config, err := state.InClusterConfig()
+			if err != nil {
+				return nil, err
+			}
+			return config.GetSecretKey(ctx, namespace, name, key)
+		},
+	}
+}
+
+// newSelectionResolver returns a resolver which reads selections from the cluster in which the process runs.
+func newSelectionResolver() *mi.SelectionResolver {
+	return &mi.SelectionResolver{
+		GetConfigMapKey: func(ctx context.Context, namespace, name, key string) ([]byte, error) {
+			config, err := state.InClusterConfig()
+			if err != nil {
+				return nil, err
+			}
+			return config.GetConfigMapKey(ctx, namespace, name, key)
+		},
+		GetDataObject: func(ctx context.Context, namespace, name string) ([]byte, error) {
+			config, err := state.InClusterConfig()
+			if err != nil {
+				return nil, err
+			}
+			return config.GetDataObject(ctx, namespace, name)
+		},
+	}
+}
+
+// loadImports reads and parses the imports file.
+func loadImports(importsPath string) (*mi.Imports, error) {
+	logger.Log.Info("Reading imports", "imports-path", importsPath)
+
+	data, err := ioutil.ReadFile(importsPath)
+	if err != nil {
+		return nil, mi.ClassifyError(err, mi.ErrorClassValidation)
+	}
+	return decodeImports(data)
+}
 
-	return ioutil.WriteFile(o.ExportsPath, b, os.ModePerm)
+// decodeImports parses the imports.
+func decodeImports(data []byte) (*mi.Imports, error) {
+	imports, err := mi.LoadImports(data, nil)
+	if err != nil {
+		return nil, mi.ClassifyError(err, mi.ErrorClassValidation)
+	}
+	return imports, nil
+}
+
+// resolveImports resolves the selections of the imports, validates them and resolves their secrets, like the engine.
+func resolveImports(ctx context.Context, imports *mi.Imports) (*mi.Imports, error) {
+	if err := imports.ResolveSelection(ctx, newSelectionResolver()); err != nil {
+		return nil, mi.ClassifyError(err, mi.ErrorClassFetch)
+	}
+
+	if err := mi.ValidateImports(imports); err != nil {
+		return nil, err
+	}
+
+	if err := imports.ResolveSecrets(ctx, newSecretResolver()); err != nil {
+		return nil, mi.ClassifyError(err, mi.ErrorClassFetch)
+	}
+	return imports, nil
 }
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+const allowedPluginCommandsFlag = "allowed-plugin-commands"
+
+// pluginOptions restrict the plugins of the imports which the commands compute.
+type pluginOptions struct {
+	// AllowedPluginCommands are the executables which the plugins of the imports may run. Plugins with other
+	// executables are rejected, also when there are no allowed plugin commands.
+	AllowedPluginCommands []string
+}
+
+// complete reads the allowed plugin commands from the persistent flag of the root command, which subcommands inherit.
+func (o *pluginOptions) complete(cmd *cobra.Command) error {
+	if cmd.Flags().Lookup(allowedPluginCommandsFlag) == nil {
+		return nil
+	}
+	commands, err := cmd.Flags().GetStringSlice(allowedPluginCommandsFlag)
+	if err != nil {
+		return err
+	}
+	o.AllowedPluginCommands = commands
+	return nil
+}
+
+// checkPluginCommands returns an error if the executable of a plugin is not one of the allowed plugin commands.
+// Imports may not execute arbitrary commands on the host of the computation. It must be called with the final
+// imports, after all overrides.
+func (o *pluginOptions) checkPluginCommands(plugins []*mi.Plugin) error {
+	for _, plugin := range plugins {
+		if plugin == nil || len(plugin.Command) == 0 {
+			continue
+		}
+		allowed := false
+		for _, command := range o.AllowedPluginCommands {
+			allowed = allowed || command == plugin.Command[0]
+		}
+		if !allowed {
+			return mi.ClassifyError(fmt.Errorf("the command %s of plugin %s is not allowed, see --%s",
+				plugin.Command[0], plugin.Name, allowedPluginCommandsFlag), mi.ErrorClassPolicy)
+		}
+	}
+	return nil
+}
+
+// readResolvedImports reads the imports file, rejects its plugins whose commands are not allowed and resolves the
+// imports, see resolveImports.
+func (o *pluginOptions) readResolvedImports(ctx context.Context, importsPath string) (*mi.Imports, error) {
+	imports, err := loadImports(importsPath)
+	if err != nil {
+		return nil, err
+	}
+	return o.resolveImports(ctx, imports)
+}
+
+// parseResolvedImports parses the imports, rejects their plugins whose commands are not allowed and resolves the
+// imports, see resolveImports.
+func (o *pluginOptions) parseResolvedImports(ctx context.Context, data []byte) (*mi.Imports, error) {
+	imports, err := decodeImports(data)
+	if err != nil {
+		return nil, err
+	}
+	return o.resolveImports(ctx, imports)
+}
+
+func (o *pluginOptions) resolveImports(ctx context.Context, imports *mi.Imports) (*mi.Imports, error) {
+	if err := o.checkPluginCommands(imports.Plugins); err != nil {
+		return nil, err
+	}
+	return resolveImports(ctx, imports)
+}
@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+type promoteCandidateOptions struct {
+	// StateStore references the store of the channels.
+	StateStore string
+	// StateEncryptionKeyPath is the path to the key which encrypts the documents of the state store.
+	StateEncryptionKeyPath string
+	// StateDecryptionKeyPaths are the paths to further keys which decrypt documents of the state store.
+	StateDecryptionKeyPaths []string
+}
+
+// NewPromoteCandidateCommand creates the command which promotes the candidate channel of a state store to the current
+// channel.
+func NewPromoteCandidateCommand(ctx context.Context) *cobra.Command {
+	options := &promoteCandidateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "promote-candidate",
+		Short: "Promotes the candidate catalog of a state store to the current catalog",
+		Long: "Promotes the candidate catalog of a state store to the current catalog. Computations with --channels " +
+			"export the current catalog as machine images and the computed machine images as candidate, so that the " +
+			"candidate can be validated, e.g. with a cloud profile in a staging project, before shoots are switched over.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.StateStore) == 0 {
+				return mi.ClassifyError(errors.New("a state store must be provided. "), mi.ErrorClassValidation)
+			}
+
+			return options.run(ctx)
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *promoteCandidateOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.StateStore, "state-store", "", "The directory or the configmap://<namespace>/<name> or crd://<namespace>/<name> reference of the state store of the channels")
+	fs.StringVar(&o.StateEncryptionKeyPath, "state-encryption-key", "", "The path to the base64 encoded AES-256 key which encrypts the state store")
+	fs.StringSliceVar(&o.StateDecryptionKeyPaths, "state-decryption-key", nil, "The paths to further keys which decrypt the state store")
+}
+
+func (o *promoteCandidateOptions) run(ctx context.Context) error {
+	store, err := newStateStore(o.StateStore, o.StateEncryptionKeyPath, o.StateDecryptionKeyPaths)
+	if err != nil {
+		return err
+	}
+	channels, err := state.LoadChannels(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	promoted, err := channels.Promote(time.Now())
+	if err != nil {
+		return err
+	}
+	if !promoted {
+		logger.Log.Info("Current channel is up to date", "state-store", o.StateStore)
+		return nil
+	}
+
+	logger.Log.Info("Promoting candidate channel", "state-store", o.StateStore)
+	return state.SaveChannels(ctx, store, channels)
+}
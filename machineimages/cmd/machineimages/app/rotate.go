@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+type rotateKeysOptions struct {
+	// StateStore references the store whose documents are re-encrypted.
+	StateStore string
+	// NewKeyPath is the path to the key with which the documents are encrypted afterwards.
+	NewKeyPath string
+	// OldKeyPaths are the paths to the keys with which documents are currently encrypted.
+	OldKeyPaths []string
+}
+
+// NewRotateKeysCommand creates the command which re-encrypts the documents of a state store with a new key.
+func NewRotateKeysCommand(ctx context.Context) *cobra.Command {
+	options := &rotateKeysOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "rotate-keys",
+		Short: "Re-encrypts the documents of a state store with a new key",
+		Long: "Re-encrypts the documents of a state store with a new key. To rotate without downtime, first run all " +
+			"computations with the new key as --state-encryption-key and the old key as --state-decryption-key, then " +
+			"rotate the keys, and finally remove the old key. Unencrypted documents are encrypted as well, which migrates " +
+			"an existing state store before --state-encryption-key is set, as encrypted state stores reject them.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.StateStore) == 0 {
+				return mi.ClassifyError(errors.New("a state store must be provided. "), mi.ErrorClassValidation)
+			}
+			if len(options.NewKeyPath) == 0 {
+				return mi.ClassifyError(errors.New("a new key must be provided. "), mi.ErrorClassValidation)
+			}
+
+			return options.run(ctx)
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *rotateKeysOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.StateStore, "state-store", "", "The directory or the configmap://<namespace>/<name> or crd://<namespace>/<name> reference of the state store")
+	fs.StringVar(&o.NewKeyPath, "new-key", "", "The path to the base64 encoded AES-256 key with which the documents are encrypted")
+	fs.StringSliceVar(&o.OldKeyPaths, "old-key", nil, "The paths to the keys with which the documents are currently encrypted")
+}
+
+func (o *rotateKeysOptions) run(ctx context.Context) error {
+	store, err := state.NewStore(o.StateStore)
+	if err != nil {
+		return err
+	}
+
+	newKey, err := state.LoadEncryptionKey(o.NewKeyPath)
+	if err != nil {
+		return err
+	}
+	oldKeys, err := loadEncryptionKeys(o.OldKeyPaths)
+	if err != nil {
+		return err
+	}
+
+	rotated, err := state.RotateKeys(ctx, store, newKey, oldKeys...)
+	logger.Log.Info("Re-encrypted state", "state-store", o.StateStore, "keys", rotated)
+	return err
+}
+
+// newStateStore returns the referenced state store, which encrypts its documents if an encryption key is given.
+func newStateStore(ref, encryptionKeyPath string, decryptionKeyPaths []string) (state.Store, error) {
+	store, err := state.NewStore(ref)
+	if err != nil || len(encryptionKeyPath) == 0 {
+		return store, err
+	}
+
+	encryptionKey, err := state.LoadEncryptionKey(encryptionKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	decryptionKeys, err := loadEncryptionKeys(decryptionKeyPaths)
+	if err != nil {
+		return nil, err
+	}
+	return state.NewEncryptedStore(store, encryptionKey, decryptionKeys...)
+}
+
+func loadEncryptionKeys(paths []string) ([][]byte, error) {
+	keys := [][]byte{}
+	for _, path := range paths {
+		key, err := state.LoadEncryptionKey(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
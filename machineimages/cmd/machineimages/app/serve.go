@@ -0,0 +1,340 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/server"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+const defaultServeAddress = ":8080"
+
+type serveOptions struct {
+	// ImportsPath is the path to the imports file which backs the server.
+	ImportsPath string
+	// Address is the address the server listens on.
+	Address string
+	// GRPCAddress is the address the gRPC service listens on. The gRPC service is disabled if empty.
+	GRPCAddress string
+	// IPFamily restricts the server to an ip family.
+	IPFamily mi.IPFamily
+
+	// TokenFile is the path to a csv file with static tokens in the format "token,user,group1,group2,...".
+	TokenFile string
+	// TokenReviewURL is the url of a kube-apiserver which is used to review bearer tokens.
+	TokenReviewURL string
+	// TokenReviewTokenFile is the path to the token used to create token reviews.
+	TokenReviewTokenFile string
+	// TokenReviewCAFile is the path to the ca bundle of the kube-apiserver.
+	TokenReviewCAFile string
+	// OIDCIssuerURL is the url of an OpenID Connect issuer whose id tokens are accepted.
+	OIDCIssuerURL string
+	// OIDCClientID is the client id which must be contained in the audience of the id tokens.
+	OIDCClientID string
+	// OIDCUsernameClaim is the claim with the user name.
+	OIDCUsernameClaim string
+	// OIDCGroupsClaim is the claim with the groups of the user.
+	OIDCGroupsClaim string
+	// AuthzConfig is the path to a yaml file with the role bindings of the users.
+	AuthzConfig string
+
+	// SLOWindow is the rolling window over which the success of computations is tracked.
+	SLOWindow time.Duration
+	// SLOObjective is the ratio of computations which must succeed within the window.
+	SLOObjective float64
+
+	// AttestationPath is the path to an attestation which must attest the imports file. The imports are refused if
+	// the attestation does not satisfy the policy.
+	AttestationPath string
+	// AttestationPolicy configures the attestations which are accepted.
+	AttestationPolicy verificationPolicyOptions
+
+	// approvalOptions gate the changes which the endpoints serve, the apply endpoint records them as applied.
+	approvalOptions
+	pluginOptions
+}
+
+// NewServeCommand creates the command which serves the computation via http.
+func NewServeCommand(ctx context.Context) *cobra.Command {
+	options := &serveOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serves the compute, explain and diff endpoints for the imports and optionally the gRPC service",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.pluginOptions.complete(cmd); err != nil {
+				return mi.ClassifyError(err, mi.ErrorClassValidation)
+			}
+			if len(options.ImportsPath) == 0 {
+				options.ImportsPath = os.Getenv(EnvVarImportsPath)
+			}
+			if len(options.ImportsPath) == 0 {
+				return mi.ClassifyError(errors.New("an imports path must be provided. "), mi.ErrorClassValidation)
+			}
+			if err := options.approvalOptions.validate(false); err != nil {
+				return mi.ClassifyError(err, mi.ErrorClassValidation)
+			}
+
+			loader, err := options.importsLoader(ctx)
+			if err != nil {
+				return err
+			}
+
+			serveCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			serverOptions, err := options.serverOptions()
+			if err != nil {
+				return err
+			}
+			serverOptions.SLO = &server.SLOOptions{Window: options.SLOWindow, Objective: options.SLOObjective}
+			if err := serverOptions.SLO.Validate(); err != nil {
+				return mi.ClassifyError(err, mi.ErrorClassValidation)
+			}
+			serverOptions.IPFamily = options.IPFamily
+			if options.gatesApproval() {
+				if serverOptions.Approval, err = options.approval(nil); err != nil {
+					return mi.ClassifyError(err, mi.ErrorClassValidation)
+				}
+			}
+
+			s := server.New(logger.Log, loader, serverOptions)
+			if len(options.GRPCAddress) == 0 {
+				return s.ListenAndServe(serveCtx, options.Address)
+			}
+			return serveBoth(serveCtx, func(ctx context.Context) error {
+				return s.ListenAndServe(ctx, options.Address)
+			}, func(ctx context.Context) error {
+				return s.ListenAndServeGRPC(ctx, options.GRPCAddress)
+			})
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *serveOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.ImportsPath, "imports-path", "i", "", "The path to the imports file")
+	fs.StringVar(&o.Address, "address", defaultServeAddress, "The address the server listens on, e.g. :8080 for all addresses or [::1]:8080")
+	fs.StringVar(&o.GRPCAddress, "grpc-address", "", "The address the gRPC service listens on, e.g. :8081. The gRPC service is disabled by default")
+	fs.StringVar((*string)(&o.IPFamily), "bind-ip-family", "", "Restricts the server to ipv4 or ipv6, by default addresses without host are served dual-stack")
+	fs.StringVar(&o.TokenFile, "token-file", "", "The path to a csv file with static tokens in the format token,user,groups...")
+	fs.StringVar(&o.TokenReviewURL, "tokenreview-url", "", "The url of a kube-apiserver which reviews bearer tokens")
+	fs.StringVar(&o.TokenReviewTokenFile, "tokenreview-token-file", "", "The path to the token used to create token reviews")
+	fs.StringVar(&o.TokenReviewCAFile, "tokenreview-ca-file", "", "The path to the ca bundle of the kube-apiserver")
+	fs.StringVar(&o.OIDCIssuerURL, "oidc-issuer-url", "", "The url of an OpenID Connect issuer whose id tokens are accepted")
+	fs.StringVar(&o.OIDCClientID, "oidc-client-id", "", "The client id which must be contained in the audience of id tokens")
+	fs.StringVar(&o.OIDCUsernameClaim, "oidc-username-claim", "sub", "The id token claim with the user name")
+	fs.StringVar(&o.OIDCGroupsClaim, "oidc-groups-claim", "groups", "The id token claim with the groups of the user")
+	fs.StringVar(&o.AuthzConfig, "authz-config", "", "The path to a yaml file with the role bindings of the users")
+	fs.DurationVar(&o.SLOWindow, "slo-window", server.DefaultSLOWindow, "The rolling window over which the success of computations is tracked")
+	fs.Float64Var(&o.SLOObjective, "slo-objective", server.DefaultSLOObjective, "The ratio of computations which must succeed within the slo window")
+	fs.StringVar(&o.AttestationPath, "verify-attestation", "", "The path to an attestation which must attest the imports file, e.g. of a signed catalog")
+	o.AttestationPolicy.addFlags(fs, "verify-")
+	o.approvalOptions.addFlags(fs)
+}
+
+// serveBoth runs both servers until the context is cancelled or one of them fails, which stops the other one.
+func serveBoth(ctx context.Context, serveHTTP, serveGRPC func(ctx context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, 2)
+	for _, serve := range []func(ctx context.Context) error{serveHTTP, serveGRPC} {
+		serve := serve
+		go func() {
+			err := serve(ctx)
+			cancel()
+			errs <- err
+		}()
+	}
+
+	var result error
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// importsLoader returns the loader of the imports. If an attestation is configured, the loader refuses imports which
+// are not attested.
+func (o *serveOptions) importsLoader(ctx context.Context) (server.ImportsLoader, error) {
+	if len(o.AttestationPath) == 0 {
+		if len(o.AttestationPolicy.KeyPaths) > 0 {
+			return nil, mi.ClassifyError(errors.New("an attestation must be provided together with the trusted keys. "),
+				mi.ErrorClassValidation)
+		}
+		return func() (*mi.Imports, error) {
+			return o.readResolvedImports(ctx, o.ImportsPath)
+		}, nil
+	}
+
+	if len(o.AttestationPolicy.KeyPaths) == 0 {
+		return nil, mi.ClassifyError(errors.New("at least one trusted key must be provided together with the attestation. "),
+			mi.ErrorClassValidation)
+	}
+	policy, err := o.AttestationPolicy.policy()
+	if err != nil {
+		return nil, err
+	}
+	return func() (*mi.Imports, error) {
+		logger.Log.Info("Reading imports", "imports-path", o.ImportsPath)
+		data, err := ioutil.ReadFile(o.ImportsPath)
+		if err != nil {
+			return nil, mi.ClassifyError(err, mi.ErrorClassValidation)
+		}
+		if err := verifyAttestation(o.AttestationPath, data, policy); err != nil {
+			return nil, err
+		}
+		return o.parseResolvedImports(ctx, data)
+	}, nil
+}
+
+// serverOptions creates the authenticators and the authorizer configured by the flags. Authentication is disabled if
+// no authenticator is configured.
+func (o *serveOptions) serverOptions() (*server.Options, error) {
+	authenticators := server.UnionAuthenticator{}
+
+	if len(o.TokenFile) > 0 {
+		authenticator, err := readTokenFile(o.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+
+	if len(o.TokenReviewURL) > 0 {
+		authenticator, err := o.tokenReviewAuthenticator()
+		if err != nil {
+			return nil, err
+		}
+		authenticators = append(authenticators, authenticator)
+	}
+
+	if len(o.OIDCIssuerURL) > 0 {
+		if len(o.OIDCClientID) == 0 {
+			return nil, errors.New("an oidc client id must be provided together with the oidc issuer url")
+		}
+		authenticators = append(authenticators, &server.OIDCAuthenticator{
+			IssuerURL:     o.OIDCIssuerURL,
+			ClientID:      o.OIDCClientID,
+			UsernameClaim: o.OIDCUsernameClaim,
+			GroupsClaim:   o.OIDCGroupsClaim,
+		})
+	}
+
+	if len(authenticators) == 0 {
+		if len(o.AuthzConfig) > 0 {
+			return nil, errors.New("an authorization config requires at least one authenticator")
+		}
+		return &server.Options{}, nil
+	}
+
+	if len(o.AuthzConfig) == 0 {
+		return nil, errors.New("an authorization config must be provided if authentication is enabled")
+	}
+
+	data, err := ioutil.ReadFile(o.AuthzConfig)
+	if err != nil {
+		return nil, err
+	}
+	authorizer := &server.RBACAuthorizer{}
+	if err := yaml.UnmarshalStrict(data, authorizer); err != nil {
+		return nil, fmt.Errorf("unable to parse authorization config %s: %w", o.AuthzConfig, err)
+	}
+	if err := authorizer.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid authorization config %s: %w", o.AuthzConfig, err)
+	}
+
+	return &server.Options{
+		Authenticator: authenticators,
+		Authorizer:    authorizer,
+	}, nil
+}
+
+func (o *serveOptions) tokenReviewAuthenticator() (*server.TokenReviewAuthenticator, error) {
+	if len(o.TokenReviewTokenFile) == 0 {
+		return nil, errors.New("a token review token file must be provided together with the token review url")
+	}
+	token, err := ioutil.ReadFile(o.TokenReviewTokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticator := &server.TokenReviewAuthenticator{
+		Host:  o.TokenReviewURL,
+		Token: strings.TrimSpace(string(token)),
+	}
+
+	if len(o.TokenReviewCAFile) > 0 {
+		ca, err := ioutil.ReadFile(o.TokenReviewCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in ca file %s", o.TokenReviewCAFile)
+		}
+		authenticator.Client = &http.Client{
+			Transport: mi.NewClientTransport(&http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}),
+		}
+	}
+
+	return authenticator, nil
+}
+
+func readTokenFile(path string) (*server.StaticTokenAuthenticator, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse token file %s: %w", path, err)
+	}
+
+	authenticator := &server.StaticTokenAuthenticator{Tokens: map[string]server.User{}}
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d of token file %s must contain a token and a user", i+1, path)
+		}
+		if len(record[0]) == 0 {
+			return nil, fmt.Errorf("line %d of token file %s has an empty token", i+1, path)
+		}
+		if _, ok := authenticator.Tokens[record[0]]; ok {
+			return nil, fmt.Errorf("line %d of token file %s repeats the token of a previous line", i+1, path)
+		}
+		authenticator.Tokens[record[0]] = server.User{Name: record[1], Groups: record[2:]}
+	}
+	return authenticator, nil
+}
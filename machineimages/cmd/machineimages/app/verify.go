@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/attestation"
+)
+
+type verifyOptions struct {
+	// AttestationPath is the path to the attestation.
+	AttestationPath string
+	// ArtifactPath is the path to the exports or catalog which must be attested.
+	ArtifactPath string
+
+	policyOptions verificationPolicyOptions
+}
+
+// verificationPolicyOptions configure the attestations which are accepted.
+type verificationPolicyOptions struct {
+	// KeyPaths are the paths to the pem encoded public keys which are trusted.
+	KeyPaths []string
+	// RequiredKeyIDs are the ids of the keys which all must have signed.
+	RequiredKeyIDs []string
+	// MaxAge is the maximum age of the attestation.
+	MaxAge time.Duration
+	// ApprovedMaterials are the sha256 digests of the approved inputs.
+	ApprovedMaterials []string
+}
+
+// NewVerifyCommand creates the command which refuses artifacts without a valid attestation.
+func NewVerifyCommand() *cobra.Command {
+	options := &verifyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verifies that an exports file is attested by trusted keys and fails otherwise",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.AttestationPath) == 0 || len(options.ArtifactPath) == 0 {
+				return mi.ClassifyError(errors.New("an attestation and an artifact must be provided. "), mi.ErrorClassValidation)
+			}
+			if len(options.policyOptions.KeyPaths) == 0 {
+				return mi.ClassifyError(errors.New("at least one trusted key must be provided. "), mi.ErrorClassValidation)
+			}
+
+			return options.run()
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *verifyOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.AttestationPath, "attestation", "", "The path to the attestation")
+	fs.StringVar(&o.ArtifactPath, "artifact", "", "The path to the exports file which must be attested")
+	o.policyOptions.addFlags(fs, "")
+}
+
+// addFlags adds the flags of the policy. The prefix distinguishes them from other flags of the command.
+func (o *verificationPolicyOptions) addFlags(fs *pflag.FlagSet, prefix string) {
+	fs.StringSliceVar(&o.KeyPaths, prefix+"key", nil, "The paths to pem encoded public keys which are trusted")
+	fs.StringSliceVar(&o.RequiredKeyIDs, prefix+"required-key-id", nil, "The ids of trusted keys which all must have signed")
+	fs.DurationVar(&o.MaxAge, prefix+"max-age", 0, "The maximum age of the attestation, e.g. 24h")
+	fs.StringSliceVar(&o.ApprovedMaterials, prefix+"approved-material", nil, "The sha256 digests of approved inputs")
+}
+
+// policy reads the trusted keys and returns the verification policy.
+func (o *verificationPolicyOptions) policy() (*attestation.VerificationPolicy, error) {
+	policy := &attestation.VerificationPolicy{
+		RequiredKeyIDs:    o.RequiredKeyIDs,
+		MaxAge:            o.MaxAge,
+		ApprovedMaterials: o.ApprovedMaterials,
+	}
+	for _, path := range o.KeyPaths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		verifier, err := attestation.NewPublicKeyVerifier(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read key %s: %w", path, err)
+		}
+		policy.Verifiers = append(policy.Verifiers, verifier)
+	}
+	return policy, nil
+}
+
+func (o *verifyOptions) run() error {
+	policy, err := o.policyOptions.policy()
+	if err != nil {
+		return err
+	}
+
+	artifact, err := ioutil.ReadFile(o.ArtifactPath)
+	if err != nil {
+		return err
+	}
+	if err := verifyAttestation(o.AttestationPath, artifact, policy); err != nil {
+		return err
+	}
+
+	logger.Log.Info("Attestation verified", "artifact", o.ArtifactPath)
+	return nil
+}
+
+// verifyAttestation verifies that the attestation at the path attests the artifact according to the policy.
+func verifyAttestation(attestationPath string, artifact []byte, policy *attestation.VerificationPolicy) error {
+	data, err := ioutil.ReadFile(attestationPath)
+	if err != nil {
+		return mi.ClassifyError(err, mi.ErrorClassPolicy)
+	}
+	envelope, err := attestation.ParseEnvelope(data)
+	if err != nil {
+		return mi.ClassifyError(fmt.Errorf("unable to parse attestation %s: %w", attestationPath, err), mi.ErrorClassPolicy)
+	}
+	if _, err := attestation.Verify(envelope, artifact, policy); err != nil {
+		return mi.ClassifyError(err, mi.ErrorClassPolicy)
+	}
+	return nil
+}
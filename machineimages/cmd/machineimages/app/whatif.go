@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package app
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/logger"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+type whatIfOptions struct {
+	// ImportsPath is the path to the imports file with the current configuration.
+	ImportsPath string
+	// ShootsPath is the path to a yaml file with the machine image versions used by shoots.
+	ShootsPath string
+	// DisableMachineImages are the images which are disabled additionally.
+	DisableMachineImages []string
+	// MinVersions are the minimum versions per image which are applied additionally.
+	MinVersions map[string]string
+	// IncludeFilters replace the include filters of the imports.
+	IncludeFilters []string
+	// ExcludeFilters are the exclude filters which are applied additionally.
+	ExcludeFilters []string
+	pluginOptions
+}
+
+// NewWhatIfCommand creates the command which simulates changes of the configuration.
+func NewWhatIfCommand(ctx context.Context) *cobra.Command {
+	options := &whatIfOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "what-if",
+		Short: "Shows how hypothetical changes of the imports would change the machine images and which shoots are affected",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.pluginOptions.complete(cmd); err != nil {
+				return mi.ClassifyError(err, mi.ErrorClassValidation)
+			}
+			if len(options.ImportsPath) == 0 {
+				options.ImportsPath = os.Getenv(EnvVarImportsPath)
+			}
+			if len(options.ImportsPath) == 0 {
+				return mi.ClassifyError(errors.New("an imports path must be provided. "), mi.ErrorClassValidation)
+			}
+
+			return options.run(ctx)
+		},
+	}
+
+	options.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *whatIfOptions) addFlags(fs *pflag.FlagSet) {
+	fs.StringVarP(&o.ImportsPath, "imports-path", "i", "", "The path to the imports file")
+	fs.StringVar(&o.ShootsPath, "shoots", "", "The path to a yaml list of shoot, image and version of the shoots")
+	fs.StringSliceVar(&o.DisableMachineImages, "disable", nil, "Machine images which are disabled additionally, as image patterns with an optional version, e.g. suse-* or gardenlinux:934.7")
+	fs.StringToStringVar(&o.MinVersions, "min-version", nil, "Minimum versions per image, e.g. ubuntu=20.4.0")
+	fs.StringSliceVar(&o.IncludeFilters, "include-filter", nil, "Include filters which replace the include filters of the imports")
+	fs.StringSliceVar(&o.ExcludeFilters, "exclude-filter", nil, "Exclude filters which are applied additionally")
+}
+
+func (o *whatIfOptions) run(ctx context.Context) error {
+	imports, err := o.readResolvedImports(ctx, o.ImportsPath)
+	if err != nil {
+		return err
+	}
+
+	shoots := []mi.ShootMachineImage{}
+	if len(o.ShootsPath) > 0 {
+		data, err := ioutil.ReadFile(o.ShootsPath)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(data, &shoots); err != nil {
+			return err
+		}
+	}
+
+	changes := &mi.SimulationChanges{
+		DisableMachineImages: o.DisableMachineImages,
+		MinVersions:          o.MinVersions,
+		IncludeFilters:       toFilterKinds(o.IncludeFilters),
+		ExcludeFilters:       toFilterKinds(o.ExcludeFilters),
+	}
+
+	result, err := mi.Simulate(ctx, logger.Log, imports, changes, shoots)
+	if err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(result)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+func toFilterKinds(filters []string) []mi.OsImagesFilterKind {
+	result := []mi.OsImagesFilterKind{}
+	for _, f := range filters {
+		result = append(result, mi.OsImagesFilterKind(f))
+	}
+	return result
+}
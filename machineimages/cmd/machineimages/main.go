@@ -6,7 +6,6 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"os"
 
 	"github.com/gardener/landscaper-utils/machineimages/cmd/machineimages/app"
@@ -18,8 +17,5 @@ func main() {
 
 	cmd := app.NewComputeMachineImagesCommand(ctx)
 
-	if err := cmd.Execute(); err != nil {
-		fmt.Print(err)
-		os.Exit(1)
-	}
+	os.Exit(app.Execute(cmd, os.Args[1:], os.Stdout, os.Stderr))
 }
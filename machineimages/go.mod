@@ -3,6 +3,7 @@ module github.com/gardener/landscaper-utils/machineimages
 go 1.16
 
 require (
+	github.com/charmbracelet/bubbletea v0.14.1
 	github.com/go-logr/logr v0.4.0
 	github.com/go-logr/zapr v0.4.0
 	github.com/onsi/ginkgo v1.16.4
@@ -10,5 +11,10 @@ require (
 	github.com/spf13/cobra v1.2.0
 	github.com/spf13/pflag v1.0.5
 	go.uber.org/zap v1.17.0
+	google.golang.org/grpc v1.38.0
+	google.golang.org/protobuf v1.26.0
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
+	k8s.io/apimachinery v0.21.2
+	k8s.io/client-go v0.21.2
 	sigs.k8s.io/yaml v1.3.0
 )
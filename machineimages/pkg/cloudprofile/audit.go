@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudprofile
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// AuditReport lists how the machine images of a live cloud profile deviate from the machine images which are computed
+// from the sources.
+type AuditReport struct {
+	// CloudProfile is the name of the audited cloud profile.
+	CloudProfile string `json:"cloudProfile"`
+	// Unmanaged are the versions of the cloud profile which the sources do not compute, e.g. versions which were added
+	// manually, in the order of the cloud profile.
+	Unmanaged []mi.VersionRef `json:"unmanaged"`
+	// Missing are the computed versions which the cloud profile does not contain.
+	Missing []mi.VersionRef `json:"missing"`
+	// Drift are the versions whose core fields differ from the computed versions.
+	Drift []VersionChange `json:"drift"`
+	// ProviderMappingDrift are the versions whose machine images in the provider config differ from the computed ones.
+	ProviderMappingDrift []ProviderMappingChange `json:"providerMappingDrift"`
+	// ExpiredPresent are the versions of the cloud profile whose expiration date has passed.
+	ExpiredPresent []ExpiredVersion `json:"expiredPresent"`
+}
+
+// ExpiredVersion is a version with its expiration date.
+type ExpiredVersion struct {
+	mi.VersionRef  `json:",inline"`
+	ExpirationDate time.Time `json:"expirationDate"`
+}
+
+// Clean returns whether the cloud profile has exactly the computed machine images and no expired versions.
+func (r *AuditReport) Clean() bool {
+	return len(r.Unmanaged) == 0 && len(r.Missing) == 0 && len(r.Drift) == 0 && len(r.ProviderMappingDrift) == 0 &&
+		len(r.ExpiredPresent) == 0
+}
+
+// AuditCloudProfile compares the computed machine images to the machine images of a live cloud profile like
+// ComputeMachineImagesDiff and additionally reports the versions of the cloud profile which are expired at now. Nothing
+// is changed. The provider fields are optional.
+func AuditCloudProfile(computed []mi.MachineImage, live *CloudProfile, providerFields *mi.ProviderFields, now time.Time) (*AuditReport, error) {
+	return AuditCloudProfileWithOptions(computed, live, providerFields, now, nil)
+}
+
+// AuditCloudProfileWithOptions is AuditCloudProfile which does not report cosmetic differences of the diff options as
+// drift, see ComputeMachineImagesDiffWithOptions. The diff options may be nil.
+func AuditCloudProfileWithOptions(computed []mi.MachineImage, live *CloudProfile, providerFields *mi.ProviderFields, now time.Time,
+	diffOptions *mi.DiffOptions) (*AuditReport, error) {
+	if live == nil {
+		return nil, errors.New("a live cloud profile must be provided")
+	}
+
+	diff, err := ComputeMachineImagesDiffWithOptions(computed, &live.Spec, providerFields, diffOptions)
+	if err != nil {
+		return nil, err
+	}
+	report := &AuditReport{
+		CloudProfile:         live.Metadata.Name,
+		Unmanaged:            diff.Removed,
+		Missing:              diff.Added,
+		Drift:                diff.Changed,
+		ProviderMappingDrift: diff.ProviderMappings,
+		ExpiredPresent:       []ExpiredVersion{},
+	}
+
+	for _, image := range live.Spec.MachineImages {
+		for _, version := range image.Versions {
+			expiration, err := version.ExpirationDate()
+			if err != nil {
+				return nil, fmt.Errorf("machine image %s: %w", image.Name, err)
+			}
+			if expiration != nil && expiration.Before(now) {
+				v, _ := version["version"].(string)
+				report.ExpiredPresent = append(report.ExpiredPresent, ExpiredVersion{
+					VersionRef:     mi.VersionRef{Image: image.Name, Version: v},
+					ExpirationDate: *expiration,
+				})
+			}
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudprofile
+
+import (
+	"time"
+
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const liveCloudProfile = `
+apiVersion: core.gardener.cloud/v1beta1
+kind: CloudProfile
+metadata:
+  name: aws
+spec:
+  type: aws
+  machineImages:
+  - name: gardenlinux
+    versions:
+    - version: 318.8.0
+      classification: supported
+    - version: 318.7.0
+      classification: supported
+      expirationDate: "2021-06-01T00:00:00Z"
+    - version: 318.5.0
+      classification: deprecated
+`
+
+var _ = Describe("cloud profile audit", func() {
+
+	var live *CloudProfile
+
+	BeforeEach(func() {
+		live = &CloudProfile{}
+		Expect(yaml.Unmarshal([]byte(liveCloudProfile), live)).To(Succeed())
+	})
+
+	It("should report unmanaged, missing, drifted and expired versions", func() {
+		computed := []mi.MachineImage{{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+			{"version": "318.9.0", "classification": "preview"},
+			{"version": "318.8.0", "classification": "deprecated"},
+			{"version": "318.7.0", "classification": "supported", "expirationDate": "2021-06-01T00:00:00Z"},
+		}}}
+
+		report, err := AuditCloudProfile(computed, live, nil, time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Clean()).To(BeFalse())
+		Expect(report.CloudProfile).To(Equal("aws"))
+		Expect(report.Unmanaged).To(Equal([]mi.VersionRef{{Image: mi.OsNameGardenLinux, Version: "318.5.0"}}))
+		Expect(report.Missing).To(Equal([]mi.VersionRef{{Image: mi.OsNameGardenLinux, Version: "318.9.0"}}))
+		Expect(report.Drift).To(Equal([]VersionChange{{
+			VersionRef: mi.VersionRef{Image: mi.OsNameGardenLinux, Version: "318.8.0"},
+			Fields:     []FieldChange{{Field: "classification", Old: "supported", New: "deprecated"}},
+		}}))
+		Expect(report.ExpiredPresent).To(Equal([]ExpiredVersion{{
+			VersionRef:     mi.VersionRef{Image: mi.OsNameGardenLinux, Version: "318.7.0"},
+			ExpirationDate: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		}}))
+	})
+
+	It("should be clean for the machine images of the cloud profile", func() {
+		report, err := AuditCloudProfile(live.Spec.MachineImages, live, nil, time.Date(2021, 5, 1, 0, 0, 0, 0, time.UTC))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Clean()).To(BeTrue())
+	})
+})
@@ -0,0 +1,218 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudprofile
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/kubernetesversions"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machinetypes"
+)
+
+// Inputs are the parts of a cloud profile, usually the results of mi.ComputeMachineImages,
+// machinetypes.ComputeMachineTypes and kubernetesversions.ComputeKubernetesVersions.
+type Inputs struct {
+	// Type is the provider type, e.g. aws.
+	Type string `json:"type" yaml:"type"`
+	// MachineImages are the machine images with the provider specific fields of their versions, e.g. the regions.
+	MachineImages      []mi.MachineImage                      `json:"machineImages" yaml:"machineImages"`
+	MachineTypes       []machinetypes.MachineType             `json:"machineTypes" yaml:"machineTypes"`
+	KubernetesVersions []kubernetesversions.KubernetesVersion `json:"kubernetesVersions" yaml:"kubernetesVersions"`
+	Regions            []Region                               `json:"regions" yaml:"regions"`
+	VolumeTypes        []VolumeType                           `json:"volumeTypes,omitempty" yaml:"volumeTypes,omitempty"`
+	CABundle           *string                                `json:"caBundle,omitempty" yaml:"caBundle,omitempty"`
+	// ProviderConfig is the provider config without machine images. Its apiVersion defaults to the one of the
+	// provider extension of the type, e.g. "aws.provider.extensions.gardener.cloud/v1alpha1".
+	ProviderConfig map[string]interface{} `json:"providerConfig,omitempty" yaml:"providerConfig,omitempty"`
+	// ProviderFields overrides the provider specific fields of the versions of the type, see
+	// mi.DefaultProviderFields.
+	ProviderFields *mi.ProviderFields `json:"providerFields,omitempty" yaml:"providerFields,omitempty"`
+	// TargetAPIVersion is the api version of the cloud profiles of BuildConvertedCloudProfile, see
+	// SupportedAPIVersions. Defaults to APIVersion.
+	TargetAPIVersion string `json:"targetAPIVersion,omitempty" yaml:"targetAPIVersion,omitempty"`
+	// Capabilities describes the architectures of the machine images and machine types with machine capabilities,
+	// see UseCapabilities.
+	Capabilities bool `json:"capabilities,omitempty" yaml:"capabilities,omitempty"`
+	// DefaultVersions selects the versions which shoots without machine image version default to, see
+	// mi.ApplyDefaultVersions. The versions of the machine images are used as they are if nil.
+	DefaultVersions *mi.DefaultVersionOptions `json:"defaultVersions,omitempty" yaml:"defaultVersions,omitempty"`
+}
+
+// BuildCloudProfileSpec assembles the spec of a cloud profile. The versions of the machine images of the spec only
+// contain the fields which all provider extensions understand, the provider specific fields are moved to the machine
+// images of the provider config.
+func BuildCloudProfileSpec(inputs *Inputs) (*CloudProfileSpec, error) {
+	if err := validateInputs(inputs); err != nil {
+		return nil, err
+	}
+
+	images := inputs.MachineImages
+	if inputs.DefaultVersions != nil {
+		var err error
+		if images, err = mi.ApplyDefaultVersions(images, inputs.DefaultVersions); err != nil {
+			return nil, err
+		}
+	}
+
+	machineImages, providerImages, err := splitMachineImages(inputs.Type, images, inputs.ProviderFields)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &CloudProfileSpec{
+		CABundle:       inputs.CABundle,
+		Kubernetes:     KubernetesSettings{Versions: inputs.KubernetesVersions},
+		MachineImages:  machineImages,
+		MachineTypes:   inputs.MachineTypes,
+		ProviderConfig: buildProviderConfig(inputs.Type, inputs.ProviderConfig, providerImages),
+		Regions:        inputs.Regions,
+		Type:           inputs.Type,
+		VolumeTypes:    inputs.VolumeTypes,
+	}
+	if inputs.Capabilities {
+		if err := UseCapabilities(spec); err != nil {
+			return nil, err
+		}
+	}
+	return spec, nil
+}
+
+// BuildCloudProfile assembles a cloud profile with the spec of BuildCloudProfileSpec.
+func BuildCloudProfile(name string, inputs *Inputs) (*CloudProfile, error) {
+	spec, err := BuildCloudProfileSpec(inputs)
+	if err != nil {
+		return nil, err
+	}
+	return &CloudProfile{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Metadata:   ObjectMeta{Name: name},
+		Spec:       *spec,
+	}, nil
+}
+
+// splitMachineImages splits the machine images into the machine images of the spec, with the core fields, and the
+// machine images of the provider config, with the provider specific fields of the type. Images without provider
+// specific fields are not part of the provider config.
+func splitMachineImages(providerType string, images []mi.MachineImage, providerFields *mi.ProviderFields) ([]mi.MachineImage, []interface{}, error) {
+	options := &mi.PartitionOptions{Providers: []string{providerType}}
+	if providerFields != nil {
+		options.Fields = map[string]mi.ProviderFields{providerType: *providerFields}
+	}
+	partitions, err := mi.PartitionByProvider(images, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	machineImages := make([]mi.MachineImage, 0, len(images))
+	providerImages := []interface{}{}
+	for _, image := range partitions[providerType] {
+		core := mi.MachineImage{Name: image.Name, Versions: make([]mi.MachineImageVersion, 0, len(image.Versions))}
+		provider := mi.MachineImage{Name: image.Name}
+		for _, version := range image.Versions {
+			coreVersion, providerVersion := splitVersion(version)
+			core.Versions = append(core.Versions, coreVersion)
+			if len(providerVersion) > 1 {
+				provider.Versions = append(provider.Versions, providerVersion)
+			}
+		}
+		machineImages = append(machineImages, core)
+		if len(provider.Versions) > 0 {
+			providerImages = append(providerImages, provider)
+		}
+	}
+	return machineImages, providerImages, nil
+}
+
+// splitVersion splits a version into its core fields and its version with the provider specific fields.
+func splitVersion(version mi.MachineImageVersion) (mi.MachineImageVersion, mi.MachineImageVersion) {
+	core := mi.MachineImageVersion{}
+	provider := mi.MachineImageVersion{"version": version["version"]}
+	for field, value := range version {
+		if contains(mi.CoreVersionFields, field) {
+			core[field] = value
+		} else {
+			provider[field] = value
+		}
+	}
+	return core, provider
+}
+
+func buildProviderConfig(providerType string, config map[string]interface{}, machineImages []interface{}) map[string]interface{} {
+	if config == nil && len(machineImages) == 0 {
+		return nil
+	}
+
+	result := map[string]interface{}{}
+	for key, value := range config {
+		result[key] = value
+	}
+	if _, ok := result["apiVersion"]; !ok {
+		result["apiVersion"] = providerType + ".provider.extensions.gardener.cloud/v1alpha1"
+	}
+	if _, ok := result["kind"]; !ok {
+		result["kind"] = ProviderConfigKind
+	}
+	if len(machineImages) > 0 {
+		result["machineImages"] = machineImages
+	}
+	return result
+}
+
+func validateInputs(inputs *Inputs) error {
+	if inputs == nil {
+		return errors.New("inputs must be provided")
+	}
+	if len(inputs.Type) == 0 {
+		return errors.New("type must be provided")
+	}
+	if len(inputs.KubernetesVersions) == 0 {
+		return errors.New("at least one kubernetes version must be provided")
+	}
+	if len(inputs.MachineImages) == 0 {
+		return errors.New("at least one machine image must be provided")
+	}
+	if len(inputs.MachineTypes) == 0 {
+		return errors.New("at least one machine type must be provided")
+	}
+	if len(inputs.Regions) == 0 {
+		return errors.New("at least one region must be provided")
+	}
+	if _, ok := cloudProfileConversions[inputs.TargetAPIVersion]; !ok && len(inputs.TargetAPIVersion) > 0 {
+		return fmt.Errorf("unsupported target api version %q, expected one of %s", inputs.TargetAPIVersion,
+			strings.Join(SupportedAPIVersions(), ", "))
+	}
+	if _, ok := inputs.ProviderConfig["machineImages"]; ok {
+		return errors.New("the provider config must not contain machine images, they are taken from the machine images")
+	}
+
+	seen := map[string]bool{}
+	for _, region := range inputs.Regions {
+		if seen[region.Name] {
+			return fmt.Errorf("duplicate region %s", region.Name)
+		}
+		seen[region.Name] = true
+	}
+	for _, image := range inputs.MachineImages {
+		for _, version := range image.Versions {
+			if _, ok := version["version"].(string); !ok {
+				return fmt.Errorf("version without version of image %s", image.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
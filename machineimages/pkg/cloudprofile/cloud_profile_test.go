@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudprofile
+
+import (
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/kubernetesversions"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machinetypes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cloud profile", func() {
+
+	var inputs *Inputs
+
+	BeforeEach(func() {
+		inputs = &Inputs{
+			Type: "aws",
+			MachineImages: []mi.MachineImage{{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+				{
+					"version":        "318.8.0",
+					"classification": "supported",
+					"cri":            []interface{}{map[string]interface{}{"name": "containerd"}},
+					"regions":        []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1", "owner": "sap"}},
+					"image":          "gcp-only",
+				},
+				{"version": "318.9.0", "classification": "preview"},
+			}}},
+			MachineTypes:       []machinetypes.MachineType{{Name: "m5.large", CPU: "2", Memory: "8Gi"}},
+			KubernetesVersions: []kubernetesversions.KubernetesVersion{{Version: "1.28.0"}},
+			Regions:            []Region{{Name: "eu-west-1", Zones: []AvailabilityZone{{Name: "eu-west-1a"}}}},
+		}
+	})
+
+	It("should split the machine images into the spec and the provider config", func() {
+		spec, err := BuildCloudProfileSpec(inputs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(spec.MachineImages).To(Equal([]mi.MachineImage{{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+			{"version": "318.8.0", "classification": "supported", "cri": []interface{}{map[string]interface{}{"name": "containerd"}}},
+			{"version": "318.9.0", "classification": "preview"},
+		}}}))
+		Expect(spec.ProviderConfig).To(Equal(map[string]interface{}{
+			"apiVersion": "aws.provider.extensions.gardener.cloud/v1alpha1",
+			"kind":       "CloudProfileConfig",
+			"machineImages": []interface{}{mi.MachineImage{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+				{"version": "318.8.0", "regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}},
+			}}},
+		}))
+		Expect(spec.Type).To(Equal("aws"))
+		Expect(spec.Kubernetes.Versions).To(Equal(inputs.KubernetesVersions))
+	})
+
+	It("should default shoots to the selected default versions", func() {
+		inputs.DefaultVersions = &mi.DefaultVersionOptions{Strategy: mi.DefaultVersionStrategyPinned,
+			Pinned: map[string]string{mi.OsNameGardenLinux: "318.8.0"}}
+		inputs.MachineImages[0].Versions = append(inputs.MachineImages[0].Versions,
+			mi.MachineImageVersion{"version": "318.10.0", "classification": "supported"})
+
+		spec, err := BuildCloudProfileSpec(inputs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(spec.MachineImages[0].Versions).To(Equal([]mi.MachineImageVersion{
+			{"version": "318.8.0", "classification": "supported", "cri": []interface{}{map[string]interface{}{"name": "containerd"}}},
+			{"version": "318.9.0", "classification": "preview"},
+			{"version": "318.10.0", "classification": "preview"},
+		}))
+		Expect(inputs.MachineImages[0].Versions[2]).To(HaveKeyWithValue("classification", "supported"))
+
+		inputs.DefaultVersions.Pinned[mi.OsNameGardenLinux] = "318.9.0"
+		_, err = BuildCloudProfileSpec(inputs)
+		Expect(err).To(MatchError(ContainSubstring("default version 318.9.0 of machine image gardenlinux")))
+	})
+
+	It("should render a complete cloud profile", func() {
+		inputs.ProviderConfig = map[string]interface{}{"apiVersion": "aws.provider.extensions.gardener.cloud/v1alpha1", "kind": "CloudProfileConfig"}
+		inputs.ProviderFields = &mi.ProviderFields{}
+		cloudProfile, err := BuildCloudProfile("aws", inputs)
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := yaml.Marshal(cloudProfile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`apiVersion: core.gardener.cloud/v1beta1
+kind: CloudProfile
+metadata:
+  name: aws
+spec:
+  kubernetes:
+    versions:
+    - version: 1.28.0
+  machineImages:
+  - name: gardenlinux
+    versions:
+    - classification: supported
+      cri:
+      - name: containerd
+      version: 318.8.0
+    - classification: preview
+      version: 318.9.0
+  machineTypes:
+  - cpu: "2"
+    memory: 8Gi
+    name: m5.large
+  providerConfig:
+    apiVersion: aws.provider.extensions.gardener.cloud/v1alpha1
+    kind: CloudProfileConfig
+  regions:
+  - name: eu-west-1
+    zones:
+    - name: eu-west-1a
+  type: aws
+`))
+	})
+
+	It("should reject incomplete inputs", func() {
+		inputs.Regions = nil
+		_, err := BuildCloudProfileSpec(inputs)
+		Expect(err).To(MatchError("at least one region must be provided"))
+
+		inputs.Regions = []Region{{Name: "eu-west-1"}, {Name: "eu-west-1"}}
+		_, err = BuildCloudProfileSpec(inputs)
+		Expect(err).To(MatchError("duplicate region eu-west-1"))
+
+		inputs.Regions = []Region{{Name: "eu-west-1"}}
+		inputs.ProviderConfig = map[string]interface{}{"machineImages": []interface{}{}}
+		_, err = BuildCloudProfileSpec(inputs)
+		Expect(err).To(MatchError(ContainSubstring("must not contain machine images")))
+	})
+
+	It("should fail for providers with unknown fields", func() {
+		inputs.Type = "metal"
+		_, err := BuildCloudProfileSpec(inputs)
+		Expect(err).To(MatchError("unknown fields of provider metal"))
+	})
+})
@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudprofile
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// CapabilityArchitecture is the machine capability of the architecture.
+const CapabilityArchitecture = "architecture"
+
+// cloudProfileConversion converts an unstructured core.gardener.cloud/v1beta1 cloud profile in place.
+type cloudProfileConversion func(profile map[string]interface{}) error
+
+// cloudProfileConversions contains the conversions to all supported target api versions. Gardener only serves
+// core.gardener.cloud/v1beta1 cloud profiles so far, the conversions to its next api versions are added here, so that
+// the emitters of cloud profiles do not change.
+var cloudProfileConversions = map[string]cloudProfileConversion{
+	APIVersion: func(map[string]interface{}) error { return nil },
+}
+
+// SupportedAPIVersions returns the target api versions of ConvertCloudProfile.
+func SupportedAPIVersions() []string {
+	versions := []string{}
+	for version := range cloudProfileConversions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// ConvertCloudProfile returns the cloud profile as unstructured object of the target api version, so that emitters do
+// not depend on the api version. An empty target is APIVersion.
+func ConvertCloudProfile(profile *CloudProfile, targetAPIVersion string) (map[string]interface{}, error) {
+	if len(targetAPIVersion) == 0 {
+		targetAPIVersion = APIVersion
+	}
+	conversion, ok := cloudProfileConversions[targetAPIVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported target api version %q, expected one of %s", targetAPIVersion,
+			strings.Join(SupportedAPIVersions(), ", "))
+	}
+
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return nil, err
+	}
+	object := map[string]interface{}{}
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil, err
+	}
+	if err := conversion(object); err != nil {
+		return nil, fmt.Errorf("unable to convert cloud profile to %s: %w", targetAPIVersion, err)
+	}
+	object["apiVersion"] = targetAPIVersion
+	return object, nil
+}
+
+// BuildConvertedCloudProfile assembles a cloud profile like BuildCloudProfile and converts it to the target api version
+// of the inputs.
+func BuildConvertedCloudProfile(name string, inputs *Inputs) (map[string]interface{}, error) {
+	profile, err := BuildCloudProfile(name, inputs)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertCloudProfile(profile, inputs.TargetAPIVersion)
+}
+
+// DecodeCloudProfile decodes a yaml or json cloud profile of one of the supported api versions. The architectures of
+// cloud profiles with capabilities are set from the capabilities, see UseCapabilities, so that the diff and the audit
+// compare them like the architectures of cloud profiles without capabilities.
+func DecodeCloudProfile(data []byte) (*CloudProfile, error) {
+	profile := &CloudProfile{}
+	if err := yaml.Unmarshal(data, profile); err != nil {
+		return nil, err
+	}
+	if profile.APIVersion != APIVersion {
+		return nil, fmt.Errorf("unsupported api version %q, expected %s", profile.APIVersion, APIVersion)
+	}
+	if err := useArchitectures(&profile.Spec); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// UseCapabilities describes the architectures of the versions and machine types of the spec with gardener's machine
+// capabilities: the spec lists the values of the architecture capability, the versions have a capability flavor per
+// architecture and the machine types have the architecture capability. Versions without architectures and machine
+// types without architecture have the default architecture. The machine images of the provider config are not
+// changed.
+func UseCapabilities(spec *CloudProfileSpec) error {
+	architectures := map[string]bool{}
+	for _, image := range spec.MachineImages {
+		for _, version := range image.Versions {
+			values, err := stringList(version["architectures"])
+			if err != nil {
+				return fmt.Errorf("architectures of machine image %s version %v: %w", image.Name, version["version"], err)
+			}
+			if len(values) == 0 {
+				values = []string{mi.DefaultArchitecture}
+			}
+			flavors := []interface{}{}
+			for _, architecture := range values {
+				architectures[architecture] = true
+				flavors = append(flavors, map[string]interface{}{"capabilities": map[string]interface{}{
+					CapabilityArchitecture: []interface{}{architecture},
+				}})
+			}
+			delete(version, "architectures")
+			version["capabilityFlavors"] = flavors
+		}
+	}
+
+	for i := range spec.MachineTypes {
+		machineType := &spec.MachineTypes[i]
+		architecture := mi.DefaultArchitecture
+		if machineType.Architecture != nil && len(*machineType.Architecture) > 0 {
+			architecture = *machineType.Architecture
+		}
+		architectures[architecture] = true
+		machineType.Architecture = nil
+		machineType.Capabilities = map[string][]string{CapabilityArchitecture: {architecture}}
+	}
+
+	spec.MachineCapabilities = []CapabilityDefinition{{Name: CapabilityArchitecture, Values: sortedSet(architectures)}}
+	return nil
+}
+
+// useArchitectures reverts UseCapabilities: the versions get the architectures of their capability flavors and the
+// machine types the architecture of their capabilities. Flavors without architecture have the first value of the
+// architecture capability, which gardener treats as default.
+func useArchitectures(spec *CloudProfileSpec) error {
+	defaultArchitecture := mi.DefaultArchitecture
+	for _, capability := range spec.MachineCapabilities {
+		if capability.Name == CapabilityArchitecture && len(capability.Values) > 0 {
+			defaultArchitecture = capability.Values[0]
+		}
+	}
+
+	for _, image := range spec.MachineImages {
+		for _, version := range image.Versions {
+			value, ok := version["capabilityFlavors"]
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			flavors := []MachineImageFlavor{}
+			if err := json.Unmarshal(data, &flavors); err != nil {
+				return fmt.Errorf("capability flavors of machine image %s version %v: %w", image.Name, version["version"], err)
+			}
+
+			seen := map[string]bool{}
+			architectures := []interface{}{}
+			for _, flavor := range flavors {
+				values := flavor.Capabilities[CapabilityArchitecture]
+				if len(values) == 0 {
+					values = []string{defaultArchitecture}
+				}
+				for _, architecture := range values {
+					if !seen[architecture] {
+						seen[architecture] = true
+						architectures = append(architectures, architecture)
+					}
+				}
+			}
+			delete(version, "capabilityFlavors")
+			if _, ok := version["architectures"]; !ok && len(architectures) > 0 {
+				version["architectures"] = architectures
+			}
+		}
+	}
+
+	for i := range spec.MachineTypes {
+		machineType := &spec.MachineTypes[i]
+		if values := machineType.Capabilities[CapabilityArchitecture]; machineType.Architecture == nil && len(values) > 0 {
+			architecture := values[0]
+			machineType.Architecture = &architecture
+		}
+		machineType.Capabilities = nil
+	}
+	spec.MachineCapabilities = nil
+	return nil
+}
+
+func stringList(value interface{}) ([]string, error) {
+	switch list := value.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return list, nil
+	case []interface{}:
+		result := []string{}
+		for _, item := range list {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("must be a list of strings")
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("must be a list of strings")
+	}
+}
+
+func sortedSet(set map[string]bool) []string {
+	result := []string{}
+	for value := range set {
+		result = append(result, value)
+	}
+	sort.Strings(result)
+	return result
+}
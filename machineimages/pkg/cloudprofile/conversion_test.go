@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudprofile
+
+import (
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/kubernetesversions"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machinetypes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cloud profile conversion", func() {
+
+	amd64, arm64 := "amd64", "arm64"
+	var inputs *Inputs
+
+	BeforeEach(func() {
+		inputs = &Inputs{
+			Type: "gcp",
+			MachineImages: []mi.MachineImage{{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+				{"version": "934.7.0", "architectures": []interface{}{"amd64", "arm64"}},
+				{"version": "934.6.0"},
+			}}},
+			MachineTypes: []machinetypes.MachineType{
+				{Name: "n2-standard-2", CPU: "2", Memory: "8Gi"},
+				{Name: "t2a-standard-2", CPU: "2", Memory: "8Gi", Architecture: &arm64},
+			},
+			KubernetesVersions: []kubernetesversions.KubernetesVersion{{Version: "1.28.0"}},
+			Regions:            []Region{{Name: "europe-west1"}},
+		}
+	})
+
+	It("should keep v1beta1 cloud profiles", func() {
+		profile, err := BuildCloudProfile("gcp", inputs)
+		Expect(err).NotTo(HaveOccurred())
+		expected, err := yaml.Marshal(profile)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, target := range []string{"", APIVersion} {
+			inputs.TargetAPIVersion = target
+			converted, err := BuildConvertedCloudProfile("gcp", inputs)
+			Expect(err).NotTo(HaveOccurred())
+			data, err := yaml.Marshal(converted)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).To(Equal(string(expected)))
+		}
+	})
+
+	It("should describe the architectures with capabilities", func() {
+		inputs.Capabilities = true
+		converted, err := BuildConvertedCloudProfile("gcp", inputs)
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := yaml.Marshal(converted)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`apiVersion: core.gardener.cloud/v1beta1
+kind: CloudProfile
+metadata:
+  name: gcp
+spec:
+  kubernetes:
+    versions:
+    - version: 1.28.0
+  machineCapabilities:
+  - name: architecture
+    values:
+    - amd64
+    - arm64
+  machineImages:
+  - name: gardenlinux
+    versions:
+    - capabilityFlavors:
+      - capabilities:
+          architecture:
+          - amd64
+      - capabilities:
+          architecture:
+          - arm64
+      version: 934.7.0
+    - capabilityFlavors:
+      - capabilities:
+          architecture:
+          - amd64
+      version: 934.6.0
+  machineTypes:
+  - capabilities:
+      architecture:
+      - amd64
+    cpu: "2"
+    memory: 8Gi
+    name: n2-standard-2
+  - capabilities:
+      architecture:
+      - arm64
+    cpu: "2"
+    memory: 8Gi
+    name: t2a-standard-2
+  regions:
+  - name: europe-west1
+  type: gcp
+`))
+	})
+
+	It("should decode the architectures of cloud profiles with capabilities", func() {
+		inputs.Capabilities = true
+		converted, err := BuildConvertedCloudProfile("gcp", inputs)
+		Expect(err).NotTo(HaveOccurred())
+		data, err := yaml.Marshal(converted)
+		Expect(err).NotTo(HaveOccurred())
+
+		decoded, err := DecodeCloudProfile(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(decoded.Spec.MachineCapabilities).To(BeNil())
+		Expect(decoded.Spec.MachineImages[0].Versions).To(Equal([]mi.MachineImageVersion{
+			{"version": "934.7.0", "architectures": []interface{}{"amd64", "arm64"}},
+			{"version": "934.6.0", "architectures": []interface{}{"amd64"}},
+		}))
+		Expect(decoded.Spec.MachineTypes[0].Architecture).To(Equal(&amd64))
+		Expect(decoded.Spec.MachineTypes[0].Capabilities).To(BeNil())
+		Expect(decoded.Spec.MachineTypes[1].Architecture).To(Equal(&arm64))
+	})
+
+	It("should reject cloud profiles of unsupported api versions", func() {
+		_, err := DecodeCloudProfile([]byte("apiVersion: core.gardener.cloud/v2\nkind: CloudProfile\n"))
+		Expect(err).To(MatchError(`unsupported api version "core.gardener.cloud/v2", expected core.gardener.cloud/v1beta1`))
+	})
+
+	It("should reject unsupported target api versions", func() {
+		inputs.TargetAPIVersion = "core.gardener.cloud/v2"
+		_, err := BuildConvertedCloudProfile("gcp", inputs)
+		Expect(err).To(MatchError(`unsupported target api version "core.gardener.cloud/v2", expected one of core.gardener.cloud/v1beta1`))
+
+		_, err = ConvertCloudProfile(&CloudProfile{}, "core.gardener.cloud/v2")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject invalid architectures", func() {
+		spec := &CloudProfileSpec{MachineImages: []mi.MachineImage{{Name: mi.OsNameGardenLinux,
+			Versions: []mi.MachineImageVersion{{"version": "934.7.0", "architectures": "amd64"}}}}}
+		Expect(UseCapabilities(spec)).To(MatchError("architectures of machine image gardenlinux version 934.7.0: must be a list of strings"))
+	})
+})
@@ -0,0 +1,208 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudprofile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// MachineImagesDiff lists how the machine images of a cloud profile change if they are replaced by computed machine
+// images.
+type MachineImagesDiff struct {
+	// Added are the versions which are only computed, in the order of the computed machine images.
+	Added []mi.VersionRef `json:"added"`
+	// Removed are the versions which are only in the cloud profile, in the order of the cloud profile.
+	Removed []mi.VersionRef `json:"removed"`
+	// Changed are the versions whose core fields, i.e. the fields of the spec, differ.
+	Changed []VersionChange `json:"changed"`
+	// ProviderMappings are the versions whose machine images in the provider config differ, e.g. changed AMIs. Added and
+	// removed versions are not listed.
+	ProviderMappings []ProviderMappingChange `json:"providerMappings"`
+}
+
+// VersionChange lists the differing fields of a version.
+type VersionChange struct {
+	mi.VersionRef `json:",inline"`
+	Fields        []FieldChange `json:"fields"`
+}
+
+// FieldChange is a field of a version with its old and new value. A missing value is nil.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// ProviderMappingChange is a version with its old and new entry in the machine images of the provider config. A
+// missing entry is nil.
+type ProviderMappingChange struct {
+	mi.VersionRef `json:",inline"`
+	Old           mi.MachineImageVersion `json:"old,omitempty"`
+	New           mi.MachineImageVersion `json:"new,omitempty"`
+}
+
+// Empty returns whether the cloud profile already has the computed machine images.
+func (d *MachineImagesDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 && len(d.ProviderMappings) == 0
+}
+
+// ComputeMachineImagesDiff compares the computed machine images, with the provider specific fields of their
+// versions, to the machine images of an existing cloud profile without changing anything. The computed machine images
+// are split like in BuildCloudProfileSpec for the type of the cloud profile, the provider fields are optional and
+// default to mi.DefaultProviderFields. Versions of the cloud profile without architectures are compared as amd64
+// versions, as gardener defaults them.
+func ComputeMachineImagesDiff(computed []mi.MachineImage, existing *CloudProfileSpec, providerFields *mi.ProviderFields) (*MachineImagesDiff, error) {
+	return ComputeMachineImagesDiffWithOptions(computed, existing, providerFields, nil)
+}
+
+// ComputeMachineImagesDiffWithOptions is ComputeMachineImagesDiff which ignores the cosmetic differences of the diff
+// options in the versions and in the machine images of the provider config. The diff options may be nil.
+func ComputeMachineImagesDiffWithOptions(computed []mi.MachineImage, existing *CloudProfileSpec, providerFields *mi.ProviderFields,
+	diffOptions *mi.DiffOptions) (*MachineImagesDiff, error) {
+	if existing == nil {
+		return nil, errors.New("an existing cloud profile must be provided")
+	}
+	if len(existing.Type) == 0 {
+		return nil, errors.New("the type of the existing cloud profile must be provided")
+	}
+
+	coreImages, providerConfigImages, err := splitMachineImages(existing.Type, computed, providerFields)
+	if err != nil {
+		return nil, err
+	}
+	newImages := []mi.MachineImage{}
+	if err := normalize(&newImages, coreImages); err != nil {
+		return nil, err
+	}
+	newProviderImages := []mi.MachineImage{}
+	if err := normalize(&newProviderImages, providerConfigImages); err != nil {
+		return nil, err
+	}
+
+	oldImages := []mi.MachineImage{}
+	if err := normalize(&oldImages, existing.MachineImages); err != nil {
+		return nil, err
+	}
+	oldProviderImages := []mi.MachineImage{}
+	if config, ok := existing.ProviderConfig["machineImages"]; ok {
+		if err := normalize(&oldProviderImages, config); err != nil {
+			return nil, fmt.Errorf("invalid machine images of the provider config: %w", err)
+		}
+	}
+	for _, images := range [][]mi.MachineImage{oldImages, newImages} {
+		defaultArchitectures(images)
+	}
+	for _, images := range []*[]mi.MachineImage{&oldImages, &newImages, &oldProviderImages, &newProviderImages} {
+		if *images, err = diffOptions.NormalizeMachineImages(*images); err != nil {
+			return nil, err
+		}
+	}
+
+	diff := &MachineImagesDiff{
+		Added:            []mi.VersionRef{},
+		Removed:          []mi.VersionRef{},
+		Changed:          []VersionChange{},
+		ProviderMappings: []ProviderMappingChange{},
+	}
+
+	versionDiff := mi.DiffMachineImages(oldImages, newImages)
+	diff.Added = versionDiff.Added
+	diff.Removed = versionDiff.Removed
+	oldVersions := indexVersions(oldImages)
+	newVersions := indexVersions(newImages)
+	for _, ref := range versionDiff.Changed {
+		diff.Changed = append(diff.Changed, VersionChange{
+			VersionRef: ref,
+			Fields:     diffFields(oldVersions[ref], newVersions[ref]),
+		})
+	}
+
+	oldMappings := indexVersions(oldProviderImages)
+	newMappings := indexVersions(newProviderImages)
+	for _, ref := range versionRefs(newImages) {
+		if _, ok := oldVersions[ref]; !ok {
+			continue
+		}
+		if !reflect.DeepEqual(oldMappings[ref], newMappings[ref]) {
+			diff.ProviderMappings = append(diff.ProviderMappings, ProviderMappingChange{
+				VersionRef: ref,
+				Old:        oldMappings[ref],
+				New:        newMappings[ref],
+			})
+		}
+	}
+	return diff, nil
+}
+
+// normalize converts the value into the result with a json round trip, so that values of cloud profiles read from
+// files and computed values compare equal.
+func normalize(result interface{}, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+// defaultArchitectures sets the architectures of versions without architectures to amd64.
+func defaultArchitectures(images []mi.MachineImage) {
+	for _, image := range images {
+		for _, version := range image.Versions {
+			if _, ok := version["architectures"]; !ok {
+				version["architectures"] = []interface{}{"amd64"}
+			}
+		}
+	}
+}
+
+// diffFields returns the differing fields of the versions in lexical order.
+func diffFields(oldVersion, newVersion mi.MachineImageVersion) []FieldChange {
+	fields := make([]string, 0, len(oldVersion)+len(newVersion))
+	for field := range oldVersion {
+		fields = append(fields, field)
+	}
+	for field := range newVersion {
+		if _, ok := oldVersion[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []FieldChange{}
+	for _, field := range fields {
+		if !reflect.DeepEqual(oldVersion[field], newVersion[field]) {
+			changes = append(changes, FieldChange{Field: field, Old: oldVersion[field], New: newVersion[field]})
+		}
+	}
+	return changes
+}
+
+func indexVersions(images []mi.MachineImage) map[mi.VersionRef]mi.MachineImageVersion {
+	index := map[mi.VersionRef]mi.MachineImageVersion{}
+	for _, image := range images {
+		for _, version := range image.Versions {
+			v, _ := version["version"].(string)
+			index[mi.VersionRef{Image: image.Name, Version: v}] = version
+		}
+	}
+	return index
+}
+
+func versionRefs(images []mi.MachineImage) []mi.VersionRef {
+	refs := []mi.VersionRef{}
+	for _, image := range images {
+		for _, version := range image.Versions {
+			v, _ := version["version"].(string)
+			refs = append(refs, mi.VersionRef{Image: image.Name, Version: v})
+		}
+	}
+	return refs
+}
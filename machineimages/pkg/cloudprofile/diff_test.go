@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudprofile
+
+import (
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const existingCloudProfile = `
+apiVersion: core.gardener.cloud/v1beta1
+kind: CloudProfile
+metadata:
+  name: aws
+spec:
+  type: aws
+  machineImages:
+  - name: gardenlinux
+    versions:
+    - version: 318.8.0
+      classification: supported
+    - version: 318.7.0
+      classification: supported
+    - version: 318.6.0
+      classification: deprecated
+  providerConfig:
+    apiVersion: aws.provider.extensions.gardener.cloud/v1alpha1
+    kind: CloudProfileConfig
+    machineImages:
+    - name: gardenlinux
+      versions:
+      - version: 318.8.0
+        regions:
+        - name: eu-west-1
+          ami: ami-1
+      - version: 318.7.0
+        regions:
+        - name: eu-west-1
+          ami: ami-2
+`
+
+var _ = Describe("cloud profile diff", func() {
+
+	var existing *CloudProfile
+
+	BeforeEach(func() {
+		existing = &CloudProfile{}
+		Expect(yaml.Unmarshal([]byte(existingCloudProfile), existing)).To(Succeed())
+	})
+
+	It("should not report differences for the machine images of the cloud profile", func() {
+		computed := []mi.MachineImage{{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+			{"version": "318.8.0", "classification": "supported", "architectures": []interface{}{"amd64"},
+				"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}},
+			{"version": "318.7.0", "classification": "supported",
+				"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-2"}}},
+			{"version": "318.6.0", "classification": "deprecated"},
+		}}}
+
+		diff, err := ComputeMachineImagesDiff(computed, &existing.Spec, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.Empty()).To(BeTrue())
+	})
+
+	It("should report added, removed and changed versions and changed provider mappings", func() {
+		computed := []mi.MachineImage{{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+			{"version": "318.9.0", "classification": "preview",
+				"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-3"}}},
+			{"version": "318.8.0", "classification": "supported",
+				"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-4"}}},
+			{"version": "318.7.0", "classification": "deprecated",
+				"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-2"}}},
+		}}}
+
+		diff, err := ComputeMachineImagesDiff(computed, &existing.Spec, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.Added).To(Equal([]mi.VersionRef{{Image: mi.OsNameGardenLinux, Version: "318.9.0"}}))
+		Expect(diff.Removed).To(Equal([]mi.VersionRef{{Image: mi.OsNameGardenLinux, Version: "318.6.0"}}))
+		Expect(diff.Changed).To(Equal([]VersionChange{{
+			VersionRef: mi.VersionRef{Image: mi.OsNameGardenLinux, Version: "318.7.0"},
+			Fields:     []FieldChange{{Field: "classification", Old: "supported", New: "deprecated"}},
+		}}))
+		Expect(diff.ProviderMappings).To(Equal([]ProviderMappingChange{{
+			VersionRef: mi.VersionRef{Image: mi.OsNameGardenLinux, Version: "318.8.0"},
+			Old:        mi.MachineImageVersion{"version": "318.8.0", "regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}},
+			New:        mi.MachineImageVersion{"version": "318.8.0", "regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-4"}}},
+		}}))
+	})
+
+	It("should report removed provider mappings of retained versions", func() {
+		computed := []mi.MachineImage{{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+			{"version": "318.8.0", "classification": "supported",
+				"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}},
+			{"version": "318.7.0", "classification": "supported"},
+			{"version": "318.6.0", "classification": "deprecated"},
+		}}}
+
+		diff, err := ComputeMachineImagesDiff(computed, &existing.Spec, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.Changed).To(BeEmpty())
+		Expect(diff.ProviderMappings).To(HaveLen(1))
+		Expect(diff.ProviderMappings[0].Version).To(Equal("318.7.0"))
+		Expect(diff.ProviderMappings[0].New).To(BeNil())
+	})
+
+	It("should ignore the cosmetic differences of the diff options", func() {
+		mapping := existing.Spec.ProviderConfig["machineImages"].([]interface{})[0].(map[string]interface{})["versions"].([]interface{})[0].(map[string]interface{})
+		mapping["regions"] = append(mapping["regions"].([]interface{}), map[string]interface{}{"name": "eu-central-1", "ami": "ami-5"})
+		computed := []mi.MachineImage{{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+			{"version": "318.8.0", "classification": "supported", "regions": []interface{}{
+				map[string]interface{}{"name": "eu-central-1", "ami": "ami-5"}, map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}},
+			{"version": "318.7.0", "classification": "supported",
+				"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-2"}}},
+			{"version": "318.6.0", "classification": "deprecated"},
+		}}}
+
+		diff, err := ComputeMachineImagesDiff(computed, &existing.Spec, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.ProviderMappings).To(HaveLen(1))
+
+		diff, err = ComputeMachineImagesDiffWithOptions(computed, &existing.Spec, nil, &mi.DiffOptions{IgnoreOrder: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.Empty()).To(BeTrue())
+
+		_, err = ComputeMachineImagesDiffWithOptions(computed, &existing.Spec, nil, &mi.DiffOptions{IgnoreFields: []string{"version"}})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not modify the computed machine images", func() {
+		computed := []mi.MachineImage{{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+			{"version": "318.8.0", "classification": "supported"},
+		}}}
+
+		_, err := ComputeMachineImagesDiff(computed, &existing.Spec, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(computed[0].Versions[0]).To(Equal(mi.MachineImageVersion{"version": "318.8.0", "classification": "supported"}))
+	})
+
+	It("should fail without type of the existing cloud profile", func() {
+		_, err := ComputeMachineImagesDiff(nil, &CloudProfileSpec{}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
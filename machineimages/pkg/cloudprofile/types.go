@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cloudprofile
+
+import (
+	"github.com/gardener/landscaper-utils/machineimages/pkg/kubernetesversions"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machinetypes"
+)
+
+const (
+	// APIVersion is the api version of gardener cloud profiles.
+	APIVersion = "core.gardener.cloud/v1beta1"
+	// Kind is the kind of gardener cloud profiles.
+	Kind = "CloudProfile"
+	// ProviderConfigKind is the kind of the provider configs of all provider extensions.
+	ProviderConfigKind = "CloudProfileConfig"
+)
+
+// CloudProfile is a gardener core.gardener.cloud/v1beta1 CloudProfile.
+type CloudProfile struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	Metadata   ObjectMeta       `json:"metadata"`
+	Spec       CloudProfileSpec `json:"spec"`
+}
+
+// ObjectMeta is the metadata of a cloud profile.
+type ObjectMeta struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// CloudProfileSpec is the spec of a gardener cloud profile.
+type CloudProfileSpec struct {
+	CABundle   *string            `json:"caBundle,omitempty"`
+	Kubernetes KubernetesSettings `json:"kubernetes"`
+	// MachineCapabilities are the capabilities of the machines and their values, if the cloud profile describes the
+	// architectures with capabilities, see UseCapabilities.
+	MachineCapabilities []CapabilityDefinition     `json:"machineCapabilities,omitempty"`
+	MachineImages       []mi.MachineImage          `json:"machineImages"`
+	MachineTypes        []machinetypes.MachineType `json:"machineTypes"`
+	ProviderConfig      map[string]interface{}     `json:"providerConfig,omitempty"`
+	Regions             []Region                   `json:"regions"`
+	Type                string                     `json:"type"`
+	VolumeTypes         []VolumeType               `json:"volumeTypes,omitempty"`
+}
+
+// CapabilityDefinition is a capability of the machines of a cloud profile, e.g. the architecture, with its values.
+type CapabilityDefinition struct {
+	Name   string   `json:"name"`
+	Values []string `json:"values"`
+}
+
+// Capabilities are the values of the capabilities of a machine type or a flavor of a machine image version by name.
+type Capabilities map[string][]string
+
+// MachineImageFlavor is a combination of capabilities which a machine image version supports. The versions of cloud
+// profiles with capabilities list their flavors in the capabilityFlavors field.
+type MachineImageFlavor struct {
+	Capabilities Capabilities `json:"capabilities"`
+}
+
+// KubernetesSettings are the kubernetes versions of a cloud profile.
+type KubernetesSettings struct {
+	Versions []kubernetesversions.KubernetesVersion `json:"versions"`
+}
+
+// Region is a region of a cloud profile.
+type Region struct {
+	Name   string             `json:"name"`
+	Zones  []AvailabilityZone `json:"zones,omitempty"`
+	Labels map[string]string  `json:"labels,omitempty"`
+}
+
+// AvailabilityZone is a zone of a region.
+type AvailabilityZone struct {
+	Name                    string   `json:"name"`
+	UnavailableMachineTypes []string `json:"unavailableMachineTypes,omitempty"`
+	UnavailableVolumeTypes  []string `json:"unavailableVolumeTypes,omitempty"`
+}
+
+// VolumeType is a volume type of a cloud profile.
+type VolumeType struct {
+	Name    string `json:"name"`
+	Class   string `json:"class"`
+	MinSize string `json:"minSize,omitempty"`
+	// Usable is whether shoots may use the volume type. Gardener defaults it to true.
+	Usable *bool `json:"usable,omitempty"`
+}
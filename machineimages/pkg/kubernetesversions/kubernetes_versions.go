@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetesversions
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// expirationDateFormat is the format of computed expiration dates, like in Gardener cloud profiles.
+const expirationDateFormat = "2006-01-02T15:04:05Z"
+
+// ComputeKubernetesVersionsOptions contains optional settings for the computation of kubernetes versions.
+type ComputeKubernetesVersionsOptions struct {
+	// MaintenanceWindows are the lifecycles of the minor versions, from which classification and expiration date
+	// of their versions are computed.
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty" yaml:"maintenanceWindows,omitempty"`
+	// ReferenceTime is the time at which the classifications are computed. Defaults to the time of the computation.
+	ReferenceTime *time.Time `json:"referenceTime,omitempty" yaml:"referenceTime,omitempty"`
+}
+
+// ComputeKubernetesVersions computes the kubernetes versions of a cloud profile like
+// machineimages.ComputeMachineImages computes its machine images. The landscape versions override the default
+// versions with the same version field by field. Versions without classification or expiration date get the ones of
+// the maintenance window of their minor, explicit values are kept. The result is sorted from the highest to the
+// lowest version. The options may be nil.
+func ComputeKubernetesVersions(
+	ctx context.Context,
+	log logr.Logger,
+	defaultVersions []KubernetesVersion,
+	landscapeVersions []KubernetesVersion,
+	options *ComputeKubernetesVersionsOptions,
+) (
+	[]KubernetesVersion,
+	error,
+) {
+	log.Info("Computing kubernetes versions")
+
+	if options == nil {
+		options = &ComputeKubernetesVersionsOptions{}
+	}
+	now := time.Now()
+	if options.ReferenceTime != nil {
+		now = *options.ReferenceTime
+	}
+
+	windows, err := maintenanceWindows(options.MaintenanceWindows)
+	if err != nil {
+		return nil, err
+	}
+
+	result := mergeKubernetesVersions(defaultVersions, landscapeVersions)
+	parsed := make(map[string]*mi.Version, len(result))
+	for i, version := range result {
+		v, err := mi.ParseVersion(version.Version)
+		if err != nil {
+			return nil, err
+		}
+		parsed[version.Version] = v
+
+		minor, _ := parseMinor(version.Version)
+		window, ok := windows[minor]
+		if !ok {
+			continue
+		}
+		if version.Classification == nil {
+			classification := window.Classification(now)
+			result[i].Classification = &classification
+		}
+		if version.ExpirationDate == nil && window.Expiration != nil {
+			expirationDate := window.Expiration.UTC().Format(expirationDateFormat)
+			result[i].ExpirationDate = &expirationDate
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return parsed[result[i].Version].Compare(parsed[result[j].Version]) > 0
+	})
+	return result, nil
+}
+
+// ComputeKubernetesVersionsFromImports computes the kubernetes versions from the lists and options of the imports.
+func ComputeKubernetesVersionsFromImports(ctx context.Context, log logr.Logger, imports *Imports) ([]KubernetesVersion, error) {
+	return ComputeKubernetesVersions(
+		ctx,
+		log,
+		imports.KubernetesVersionsDefault,
+		imports.KubernetesVersions,
+		&imports.ComputeKubernetesVersionsOptions,
+	)
+}
+
+// mergeKubernetesVersions merges the lists in order. Later versions override the fields of earlier versions with the
+// same version, which keep their position.
+func mergeKubernetesVersions(lists ...[]KubernetesVersion) []KubernetesVersion {
+	result := []KubernetesVersion{}
+	index := map[string]int{}
+	for _, list := range lists {
+		for _, version := range list {
+			i, ok := index[version.Version]
+			if !ok {
+				index[version.Version] = len(result)
+				result = append(result, version)
+				continue
+			}
+			if version.Classification != nil {
+				result[i].Classification = version.Classification
+			}
+			if version.ExpirationDate != nil {
+				result[i].ExpirationDate = version.ExpirationDate
+			}
+		}
+	}
+	return result
+}
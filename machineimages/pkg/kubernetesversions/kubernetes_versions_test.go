@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetesversions
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("kubernetes versions", func() {
+
+	str := func(value string) *string { return &value }
+	date := func(value string) *time.Time {
+		t, err := time.Parse("2006-01-02", value)
+		Expect(err).NotTo(HaveOccurred())
+		return &t
+	}
+
+	defaults := []KubernetesVersion{
+		{Version: "1.26.9"},
+		{Version: "1.27.3"},
+		{Version: "1.28.0"},
+		{Version: "1.27.10"},
+	}
+
+	It("should merge the lists and sort them from the highest version", func() {
+		result, err := ComputeKubernetesVersions(context.Background(), logr.Discard(), defaults, []KubernetesVersion{
+			{Version: "1.27.3", Classification: str("deprecated")},
+			{Version: "1.29.0", Classification: str("preview")},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]KubernetesVersion{
+			{Version: "1.29.0", Classification: str("preview")},
+			{Version: "1.28.0"},
+			{Version: "1.27.10"},
+			{Version: "1.27.3", Classification: str("deprecated")},
+			{Version: "1.26.9"},
+		}))
+	})
+
+	It("should compute classification and expiration from the maintenance windows", func() {
+		result, err := ComputeKubernetesVersions(context.Background(), logr.Discard(), defaults, []KubernetesVersion{
+			{Version: "1.27.3", ExpirationDate: str("2023-12-01T00:00:00Z")},
+		}, &ComputeKubernetesVersionsOptions{
+			ReferenceTime: date("2024-01-15"),
+			MaintenanceWindows: []MaintenanceWindow{
+				{Minor: "1.28", Supported: date("2024-02-01")},
+				{Minor: "1.27", Supported: date("2023-06-01"), Deprecated: date("2024-01-01"), Expiration: date("2024-04-30")},
+				{Minor: "v1.26", Deprecated: date("2023-09-01")},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]KubernetesVersion{
+			{Version: "1.28.0", Classification: str("preview")},
+			{Version: "1.27.10", Classification: str("deprecated"), ExpirationDate: str("2024-04-30T00:00:00Z")},
+			{Version: "1.27.3", Classification: str("deprecated"), ExpirationDate: str("2023-12-01T00:00:00Z")},
+			{Version: "1.26.9", Classification: str("deprecated")},
+		}))
+	})
+
+	It("should fail on invalid versions and windows", func() {
+		_, err := ComputeKubernetesVersions(context.Background(), logr.Discard(), []KubernetesVersion{{Version: "latest"}}, nil, nil)
+		Expect(err).To(MatchError(ContainSubstring(`invalid version "latest"`)))
+
+		_, err = ComputeKubernetesVersions(context.Background(), logr.Discard(), defaults, nil, &ComputeKubernetesVersionsOptions{
+			MaintenanceWindows: []MaintenanceWindow{{Minor: "1.27"}, {Minor: "1.27.0"}},
+		})
+		Expect(err).To(MatchError("duplicate maintenance window 1.27.0"))
+	})
+
+	It("should compute the kubernetes versions of the imports", func() {
+		result, err := ComputeKubernetesVersionsFromImports(context.Background(), logr.Discard(), &Imports{
+			KubernetesVersions:        []KubernetesVersion{{Version: "1.28.0", Classification: str("supported")}},
+			KubernetesVersionsDefault: []KubernetesVersion{{Version: "1.27.3"}, {Version: "1.28.0"}},
+			ComputeKubernetesVersionsOptions: ComputeKubernetesVersionsOptions{
+				MaintenanceWindows: []MaintenanceWindow{{Minor: "1.27", Deprecated: date("2020-01-01")}},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]KubernetesVersion{
+			{Version: "1.28.0", Classification: str("supported")},
+			{Version: "1.27.3", Classification: str("deprecated")},
+		}))
+	})
+})
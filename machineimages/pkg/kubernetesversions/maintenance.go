@@ -0,0 +1,76 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetesversions
+
+import (
+	"fmt"
+	"time"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// MaintenanceWindow is the lifecycle of a kubernetes minor version. The versions of the minor are preview before
+// Supported, supported until Deprecated and deprecated afterwards.
+type MaintenanceWindow struct {
+	// Minor is the minor version, e.g. "1.27".
+	Minor string `json:"minor" yaml:"minor"`
+	// Supported is the start of the support. If nil, the versions are supported from the start.
+	Supported *time.Time `json:"supported,omitempty" yaml:"supported,omitempty"`
+	// Deprecated is the start of the deprecation. If nil, the versions are never deprecated.
+	Deprecated *time.Time `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	// Expiration is the expiration date of the versions of the minor.
+	Expiration *time.Time `json:"expiration,omitempty" yaml:"expiration,omitempty"`
+}
+
+// Validate returns an error if the minor is invalid or the dates are out of order.
+func (w MaintenanceWindow) Validate() error {
+	if _, err := parseMinor(w.Minor); err != nil {
+		return err
+	}
+	if w.Supported != nil && w.Deprecated != nil && w.Deprecated.Before(*w.Supported) {
+		return fmt.Errorf("maintenance window %s: deprecated must not be before supported", w.Minor)
+	}
+	if w.Deprecated != nil && w.Expiration != nil && w.Expiration.Before(*w.Deprecated) {
+		return fmt.Errorf("maintenance window %s: expiration must not be before deprecated", w.Minor)
+	}
+	return nil
+}
+
+// Classification returns the classification of the versions of the minor at the reference time.
+func (w MaintenanceWindow) Classification(now time.Time) string {
+	switch {
+	case w.Supported != nil && now.Before(*w.Supported):
+		return mi.ClassificationPreview
+	case w.Deprecated != nil && !now.Before(*w.Deprecated):
+		return mi.ClassificationDeprecated
+	default:
+		return mi.ClassificationSupported
+	}
+}
+
+// parseMinor returns the normalized minor of a version or minor version, e.g. "1.27" of "v1.27" or "1.27.3".
+func parseMinor(version string) (string, error) {
+	v, err := mi.ParseVersion(version)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor), nil
+}
+
+// maintenanceWindows indexes the windows by minor.
+func maintenanceWindows(windows []MaintenanceWindow) (map[string]MaintenanceWindow, error) {
+	result := map[string]MaintenanceWindow{}
+	for _, window := range windows {
+		if err := window.Validate(); err != nil {
+			return nil, err
+		}
+		minor, _ := parseMinor(window.Minor)
+		if _, ok := result[minor]; ok {
+			return nil, fmt.Errorf("duplicate maintenance window %s", window.Minor)
+		}
+		result[minor] = window
+	}
+	return result, nil
+}
@@ -0,0 +1,36 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetesversions
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("maintenance window", func() {
+
+	supported := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	deprecated := supported.AddDate(0, 6, 0)
+	expiration := deprecated.AddDate(0, 3, 0)
+	window := MaintenanceWindow{Minor: "1.28", Supported: &supported, Deprecated: &deprecated, Expiration: &expiration}
+
+	It("should classify the versions at the reference time", func() {
+		Expect(window.Classification(supported.Add(-time.Second))).To(Equal("preview"))
+		Expect(window.Classification(supported)).To(Equal("supported"))
+		Expect(window.Classification(deprecated)).To(Equal("deprecated"))
+		Expect(MaintenanceWindow{Minor: "1.28"}.Classification(deprecated)).To(Equal("supported"))
+	})
+
+	It("should validate the minor and the order of the dates", func() {
+		Expect(window.Validate()).To(Succeed())
+		Expect(MaintenanceWindow{Minor: "one"}.Validate()).To(HaveOccurred())
+		Expect(MaintenanceWindow{Minor: "1.28", Supported: &deprecated, Deprecated: &supported}.Validate()).
+			To(MatchError("maintenance window 1.28: deprecated must not be before supported"))
+		Expect(MaintenanceWindow{Minor: "1.28", Deprecated: &expiration, Expiration: &deprecated}.Validate()).
+			To(MatchError("maintenance window 1.28: expiration must not be before deprecated"))
+	})
+})
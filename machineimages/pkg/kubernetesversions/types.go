@@ -0,0 +1,26 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kubernetesversions
+
+// KubernetesVersion is a kubernetes version of a cloud profile.
+type KubernetesVersion struct {
+	Version        string  `json:"version"`
+	Classification *string `json:"classification,omitempty"`
+	// ExpirationDate is a timestamp like "2022-01-15T23:59:59Z".
+	ExpirationDate *string `json:"expirationDate,omitempty"`
+}
+
+// Imports are the inputs of the computation of kubernetes versions.
+type Imports struct {
+	KubernetesVersions        []KubernetesVersion `json:"kubernetesVersions" yaml:"kubernetesVersions"`
+	KubernetesVersionsDefault []KubernetesVersion `json:"kubernetesVersionsDefault" yaml:"kubernetesVersionsDefault"`
+
+	ComputeKubernetesVersionsOptions `json:",inline" yaml:",inline"`
+}
+
+// Exports are the result of the computation of kubernetes versions.
+type Exports struct {
+	ResultKubernetesVersions []KubernetesVersion `json:"resultKubernetesVersions" yaml:"resultKubernetesVersions"`
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build go1.21
+// +build go1.21
+
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// NewSlogLogger returns a logr.Logger which writes to the given slog handler, so that consumers which have
+// standardized on log/slog can pass their handler to all functions of this repository.
+// As with zapr, the verbosity level n of V(n) is mapped to the slog level slog.LevelInfo-n and names are written
+// as "logger" attribute.
+func NewSlogLogger(handler slog.Handler) logr.Logger {
+	return &slogLogger{handler: handler}
+}
+
+// SetSlogHandler sets the global logger to a logger which writes to the given slog handler.
+func SetSlogHandler(handler slog.Handler) {
+	SetLogger(NewSlogLogger(handler))
+}
+
+type slogLogger struct {
+	handler slog.Handler
+	name    string
+	level   int
+}
+
+var _ logr.Logger = &slogLogger{}
+
+func (l *slogLogger) Enabled() bool {
+	return l.handler.Enabled(context.Background(), l.slogLevel())
+}
+
+func (l *slogLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log(l.slogLevel(), msg, keysAndValues)
+}
+
+func (l *slogLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelError, msg, append([]interface{}{"error", err}, keysAndValues...))
+}
+
+func (l *slogLogger) V(level int) logr.Logger {
+	c := *l
+	c.level += level
+	return &c
+}
+
+func (l *slogLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	c := *l
+	c.handler = l.handler.WithAttrs(toAttrs(keysAndValues))
+	return &c
+}
+
+func (l *slogLogger) WithName(name string) logr.Logger {
+	c := *l
+	if len(c.name) > 0 {
+		c.name = c.name + "." + name
+	} else {
+		c.name = name
+	}
+	return &c
+}
+
+func (l *slogLogger) slogLevel() slog.Level {
+	return slog.LevelInfo - slog.Level(l.level)
+}
+
+func (l *slogLogger) log(level slog.Level, msg string, keysAndValues []interface{}) {
+	ctx := context.Background()
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+
+	record := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	if len(l.name) > 0 {
+		record.AddAttrs(slog.String("logger", l.name))
+	}
+	record.AddAttrs(toAttrs(keysAndValues)...)
+	if err := l.handler.Handle(ctx, record); err != nil {
+		// logr has no way to return the error, so it is written to stderr like the internal errors of zap
+		fmt.Fprintf(os.Stderr, "%v slog handler error: %v\n", time.Now().UTC(), err)
+	}
+}
+
+// toAttrs converts logr key value pairs to slog attributes. A key without value gets the value "(MISSING)".
+func toAttrs(keysAndValues []interface{}) []slog.Attr {
+	attrs := make([]slog.Attr, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key, ok := keysAndValues[i].(string)
+		if !ok {
+			key = "!BADKEY"
+		}
+
+		var value interface{} = "(MISSING)"
+		if i+1 < len(keysAndValues) {
+			value = keysAndValues[i+1]
+		}
+
+		attrs = append(attrs, slog.Any(key, value))
+	}
+	return attrs
+}
@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-logr/logr"
+)
+
+// LandscapeImports are the imports of one landscape.
+type LandscapeImports struct {
+	// Name of the landscape, e.g. "dev", "canary" or "live".
+	Name    string   `json:"name"`
+	Imports *Imports `json:"imports"`
+}
+
+// LandscapeMatrixRow states in which landscapes a version is live.
+type LandscapeMatrixRow struct {
+	VersionRef `json:",inline"`
+	// Live contains for each landscape of the matrix whether the version is part of its result.
+	Live []bool `json:"live"`
+}
+
+// LandscapeMatrix shows which machine image versions are live in which landscapes.
+type LandscapeMatrix struct {
+	// Landscapes are the names of the landscapes in the order in which they were aggregated.
+	Landscapes []string `json:"landscapes"`
+	// Rows contains one row per version. Images are ordered by their first occurrence, versions of an image from the
+	// highest to the lowest.
+	Rows []LandscapeMatrixRow `json:"rows"`
+}
+
+// AggregateLandscapes computes the machine images of all landscapes and returns the matrix of their versions.
+func AggregateLandscapes(ctx context.Context, log logr.Logger, landscapes []LandscapeImports) (*LandscapeMatrix, error) {
+	matrix := &LandscapeMatrix{
+		Landscapes: []string{},
+		Rows:       []LandscapeMatrixRow{},
+	}
+
+	results := make([][]MachineImage, len(landscapes))
+	for i, landscape := range landscapes {
+		log.Info("Computing machine images of landscape", "landscape", landscape.Name)
+		result, err := ComputeMachineImagesFromImports(ctx, log.WithValues("landscape", landscape.Name), landscape.Imports)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compute machine images of landscape %s: %w", landscape.Name, err)
+		}
+		matrix.Landscapes = append(matrix.Landscapes, landscape.Name)
+		results[i] = result
+	}
+
+	imageOrder := []string{}
+	versions := map[string][]string{}
+	live := map[VersionRef][]bool{}
+
+	for i, result := range results {
+		for _, ref := range versionRefs(result) {
+			if _, ok := versions[ref.Image]; !ok {
+				imageOrder = append(imageOrder, ref.Image)
+			}
+			if _, ok := live[ref]; !ok {
+				versions[ref.Image] = append(versions[ref.Image], ref.Version)
+				live[ref] = make([]bool, len(landscapes))
+			}
+			live[ref][i] = true
+		}
+	}
+
+	for _, image := range imageOrder {
+		imageVersions := versions[image]
+		sort.SliceStable(imageVersions, func(i, j int) bool {
+			return compareVersions(imageVersions[i], imageVersions[j]) > 0
+		})
+		for _, version := range imageVersions {
+			ref := VersionRef{Image: image, Version: version}
+			matrix.Rows = append(matrix.Rows, LandscapeMatrixRow{VersionRef: ref, Live: live[ref]})
+		}
+	}
+
+	return matrix, nil
+}
+
+// LiveIn returns the names of the landscapes in which the version is live.
+func (m *LandscapeMatrix) LiveIn(image, version string) []string {
+	result := []string{}
+	for _, row := range m.Rows {
+		if row.Image != image || row.Version != version {
+			continue
+		}
+		for i, live := range row.Live {
+			if live {
+				result = append(result, m.Landscapes[i])
+			}
+		}
+	}
+	return result
+}
+
+// WriteTable writes the matrix as a table with one column per landscape, in which live versions are marked with "x".
+func (m *LandscapeMatrix) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	header := append([]string{"IMAGE", "VERSION"}, m.Landscapes...)
+	if _, err := fmt.Fprintln(tw, strings.Join(header, "\t")); err != nil {
+		return err
+	}
+
+	for _, row := range m.Rows {
+		columns := []string{row.Image, row.Version}
+		for _, live := range row.Live {
+			if live {
+				columns = append(columns, "x")
+			} else {
+				columns = append(columns, "-")
+			}
+		}
+		if _, err := fmt.Fprintln(tw, strings.Join(columns, "\t")); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
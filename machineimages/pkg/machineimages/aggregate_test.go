@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("aggregate", func() {
+
+	newImports := func(versions ...string) *Imports {
+		lss := MachineImage{Name: OsNameGardenLinux}
+		provider := MachineImage{Name: OsNameGardenLinux}
+		for _, v := range versions {
+			lss.Versions = append(lss.Versions, MachineImageVersion{"version": v})
+			provider.Versions = append(provider.Versions, MachineImageVersion{"version": v, "image": "gl-" + v})
+		}
+		return &Imports{
+			MachineImages:         []MachineImage{lss},
+			MachineImagesProvider: []MachineImage{provider},
+		}
+	}
+
+	landscapes := func() []LandscapeImports {
+		return []LandscapeImports{
+			{Name: "dev", Imports: newImports("318.9.0", "934.0.0")},
+			{Name: "canary", Imports: newImports("318.9.0", "934.0.0")},
+			{Name: "live", Imports: newImports("318.9.0")},
+		}
+	}
+
+	It("should show in which landscapes a version is live", func() {
+		matrix, err := AggregateLandscapes(context.Background(), logr.Discard(), landscapes())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matrix.Landscapes).To(Equal([]string{"dev", "canary", "live"}))
+		Expect(matrix.Rows).To(Equal([]LandscapeMatrixRow{
+			{VersionRef: VersionRef{Image: OsNameGardenLinux, Version: "934.0.0"}, Live: []bool{true, true, false}},
+			{VersionRef: VersionRef{Image: OsNameGardenLinux, Version: "318.9.0"}, Live: []bool{true, true, true}},
+		}))
+		Expect(matrix.LiveIn(OsNameGardenLinux, "934.0.0")).To(Equal([]string{"dev", "canary"}))
+		Expect(matrix.LiveIn(OsNameGardenLinux, "1.0.0")).To(BeEmpty())
+	})
+
+	It("should write the matrix as table", func() {
+		matrix, err := AggregateLandscapes(context.Background(), logr.Discard(), landscapes())
+		Expect(err).NotTo(HaveOccurred())
+
+		buf := &bytes.Buffer{}
+		Expect(matrix.WriteTable(buf)).To(Succeed())
+		Expect(buf.String()).To(Equal(
+			"IMAGE        VERSION  dev  canary  live\n" +
+				"gardenlinux  934.0.0  x    x       -\n" +
+				"gardenlinux  318.9.0  x    x       x\n"))
+	})
+
+	It("should name the landscape which fails", func() {
+		l := landscapes()
+		l[1].Imports.IncludeFilters = []OsImagesFilterKind{OsImagesFilterKindAll}
+		l[1].Imports.ExcludeFilters = []OsImagesFilterKind{OsImagesFilterKindAll}
+		_, err := AggregateLandscapes(context.Background(), logr.Discard(), l)
+		Expect(err).To(MatchError(ContainSubstring("canary")))
+	})
+})
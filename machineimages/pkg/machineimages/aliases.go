@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/constraint"
+)
+
+// VersionAlias is an alias, e.g. latest or lts, which is resolved to a concrete version of every image. The version is
+// selected like a default version, see SelectDefaultVersion.
+type VersionAlias struct {
+	// Name is the alias, e.g. latest.
+	Name string `json:"name" yaml:"name"`
+	// Strategy selects the version among the versions satisfying the constraint. Defaults to
+	// DefaultVersionStrategyHighestSupported.
+	Strategy DefaultVersionStrategy `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	// Constraint restricts the versions of the alias, e.g. "^934" for the versions of an lts line, see the
+	// constraint package. Versions which are no semver never satisfy a constraint.
+	Constraint string `json:"constraint,omitempty" yaml:"constraint,omitempty"`
+	// Pinned maps image names to the version of the alias. Only used by DefaultVersionStrategyPinned.
+	Pinned map[string]string `json:"pinned,omitempty" yaml:"pinned,omitempty"`
+}
+
+// VersionAliases maps image names to aliases to the versions they are resolved to.
+type VersionAliases map[string]map[string]string
+
+// Validate checks that the alias has a name, a known strategy and a valid constraint.
+func (a *VersionAlias) Validate() error {
+	if len(a.Name) == 0 {
+		return errors.New("name must be set")
+	}
+	switch a.Strategy {
+	case "", DefaultVersionStrategyHighestSupported, DefaultVersionStrategyHighestNonExpired, DefaultVersionStrategyPinned:
+	default:
+		return fmt.Errorf("default version strategy does not exist %s", a.Strategy)
+	}
+	if len(a.Constraint) > 0 {
+		if _, err := constraint.Parse(a.Constraint); err != nil {
+			return fmt.Errorf("invalid constraint: %w", err)
+		}
+	}
+	return nil
+}
+
+// ResolveVersionAliases resolves the aliases for all images. Images without a version which qualifies for an alias
+// have no entry for it, also pinned images, whereas missing pinned versions are an error. Aliases must not be versions
+// of the images, so that consumers which key on versions can tell them apart.
+func ResolveVersionAliases(images []MachineImage, aliases []VersionAlias, now time.Time) (VersionAliases, error) {
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+
+	result := VersionAliases{}
+	for _, image := range images {
+		for _, alias := range aliases {
+			if alias.Strategy == DefaultVersionStrategyPinned {
+				if _, ok := alias.Pinned[image.Name]; !ok {
+					continue
+				}
+			}
+			for _, v := range image.Versions {
+				if versionOrEmpty(v) == alias.Name {
+					return nil, fmt.Errorf("alias %s of machine image %s is also a version", alias.Name, image.Name)
+				}
+			}
+
+			candidates := image
+			if len(alias.Constraint) > 0 {
+				candidates = MachineImage{Name: image.Name}
+				for _, v := range image.Versions {
+					if matched, err := constraint.Evaluate(alias.Constraint, versionOrEmpty(v)); err == nil && matched {
+						candidates.Versions = append(candidates.Versions, v)
+					}
+				}
+			}
+
+			version, ok, err := SelectDefaultVersion(candidates, &DefaultVersionOptions{
+				Strategy: alias.Strategy,
+				Pinned:   alias.Pinned,
+				Now:      now,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("unable to resolve alias %s: %w", alias.Name, err)
+			}
+			if !ok {
+				continue
+			}
+			if _, ok := result[image.Name]; !ok {
+				result[image.Name] = map[string]string{}
+			}
+			result[image.Name][alias.Name] = version
+		}
+	}
+	return result, nil
+}
+
+// aliasesOf returns the aliases of the image which are resolved to the version, in lexical order.
+func (a VersionAliases) aliasesOf(image, version string) []string {
+	aliases := []string{}
+	for _, alias := range sortedKeys(a[image]) {
+		if a[image][alias] == version {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("version aliases", func() {
+
+	now := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	images := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "1000.0.0", "classification": ClassificationPreview, "image": "gl-1000-0-0"},
+			{"version": "934.8.0", "classification": ClassificationSupported, "image": "gl-934-8-0"},
+			{"version": "576.12.0", "classification": ClassificationSupported, "image": "gl-576-12-0",
+				"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-576"}}},
+		}},
+		{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+			{"version": "22.4.0", "classification": ClassificationPreview},
+		}},
+	}
+	aliases := []VersionAlias{
+		{Name: "latest"},
+		{Name: "lts", Constraint: "^576"},
+		{Name: "edge", Strategy: DefaultVersionStrategyHighestNonExpired},
+	}
+
+	It("should resolve the aliases to the versions of the images", func() {
+		resolved, err := ResolveVersionAliases(images, aliases, now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved).To(Equal(VersionAliases{
+			OsNameGardenLinux: {"latest": "934.8.0", "lts": "576.12.0", "edge": "1000.0.0"},
+			OsNameUbuntu:      {"edge": "22.4.0"},
+		}))
+
+		_, err = ResolveVersionAliases(images, []VersionAlias{{Name: "934.8.0"}}, now)
+		Expect(err).To(MatchError("alias 934.8.0 of machine image gardenlinux is also a version"))
+		_, err = ResolveVersionAliases(images, []VersionAlias{{Name: "pinned", Strategy: DefaultVersionStrategyPinned,
+			Pinned: map[string]string{OsNameUbuntu: "20.4.0"}}}, now)
+		Expect(err).To(MatchError(ContainSubstring("unable to resolve alias pinned")))
+	})
+
+	It("should add alias entries to the terraform variables and the cluster api image lookup", func() {
+		resolved := VersionAliases{OsNameGardenLinux: {"latest": "934.8.0", "lts": "576.12.0"}}
+
+		ids := NewTerraformImageIDs(images, &TerraformOptions{Aliases: resolved})
+		Expect(ids[OsNameGardenLinux]["latest"]).To(Equal(map[string]string{TerraformGlobalRegion: "gl-934-8-0"}))
+		Expect(ids[OsNameGardenLinux]["lts"]).To(Equal(ids[OsNameGardenLinux]["576.12.0"]))
+
+		lookup := NewCAPIImageLookup(images)
+		lookup.AddAliases(resolved)
+		Expect(lookup.AWS).To(Equal([]CAPAMachineImage{
+			{Name: OsNameGardenLinux, Version: "576.12.0", Region: "eu-west-1", AMI: CAPAAMIReference{ID: "ami-576"}},
+			{Name: OsNameGardenLinux, Version: "lts", Region: "eu-west-1", AMI: CAPAAMIReference{ID: "ami-576"}},
+		}))
+	})
+
+	It("should export the resolved aliases and reject invalid aliases", func() {
+		imports := &Imports{
+			MachineImages:         images[:1],
+			MachineImagesProvider: images[:1],
+			ComputeMachineImagesOptions: ComputeMachineImagesOptions{
+				VersionAliases:          aliases[:2],
+				ExpirationReferenceTime: &now,
+			},
+		}
+		exports, err := ComputeExports(context.Background(), logr.Discard(), imports)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exports.ResultVersionAliases).To(Equal(VersionAliases{
+			OsNameGardenLinux: {"latest": "934.8.0", "lts": "576.12.0"},
+		}))
+
+		imports.VersionAliases = []VersionAlias{{Name: "latest"}, {Name: "latest"}, {}, {Name: "lts", Constraint: ">>576"}}
+		err = ValidateImports(imports)
+		Expect(err).To(MatchError(ContainSubstring("versionAliases[1]: duplicate alias latest")))
+		Expect(err).To(MatchError(ContainSubstring("versionAliases[2]: name must be set")))
+		Expect(err).To(MatchError(ContainSubstring("versionAliases[3]: invalid constraint")))
+	})
+})
@@ -0,0 +1,231 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ImpactLevel classifies a change of the machine images by its most severe kind of difference.
+type ImpactLevel string
+
+const (
+	// ImpactNone is a change without differences.
+	ImpactNone = ImpactLevel("none")
+	// ImpactAddition is a change which only adds versions.
+	ImpactAddition = ImpactLevel("addition")
+	// ImpactChange is a change which modifies fields of versions, e.g. their classification, but removes none.
+	ImpactChange = ImpactLevel("change")
+	// ImpactRemoval is a change which removes versions.
+	ImpactRemoval = ImpactLevel("removal")
+)
+
+var impactRanks = map[ImpactLevel]int{ImpactNone: 0, ImpactAddition: 1, ImpactChange: 2, ImpactRemoval: 3}
+
+// Validate returns an error if the level is unknown.
+func (l ImpactLevel) Validate() error {
+	if _, ok := impactRanks[l]; !ok {
+		return fmt.Errorf("unknown impact level %q, expected none, addition, change or removal", l)
+	}
+	return nil
+}
+
+// Exceeds returns whether the level is at least the threshold.
+func (l ImpactLevel) Exceeds(threshold ImpactLevel) bool {
+	return impactRanks[l] >= impactRanks[threshold]
+}
+
+// ImpactOf returns the impact level of the diff.
+func ImpactOf(diff *MachineImagesDiff) ImpactLevel {
+	switch {
+	case len(diff.Removed) > 0:
+		return ImpactRemoval
+	case len(diff.Changed) > 0:
+		return ImpactChange
+	case len(diff.Added) > 0:
+		return ImpactAddition
+	default:
+		return ImpactNone
+	}
+}
+
+// ApprovalRequest asks for the approval to apply computed machine images instead of the applied ones.
+type ApprovalRequest struct {
+	// ID identifies the change. It only depends on the landscape, the applied and the computed machine images, so that
+	// repeated computations of the same change request the same approval.
+	ID        string             `json:"id"`
+	Landscape string             `json:"landscape,omitempty"`
+	Impact    ImpactLevel        `json:"impact"`
+	Score     *ImpactScore       `json:"score"`
+	Diff      *MachineImagesDiff `json:"diff"`
+}
+
+// NewApprovalRequest returns the request to replace the applied with the computed machine images. The shoots are
+// optional and only counted in the score.
+func NewApprovalRequest(landscape string, applied, computed []MachineImage, shoots []ShootMachineImage) (*ApprovalRequest, error) {
+	data, err := json.Marshal(struct {
+		Landscape string         `json:"landscape"`
+		Applied   []MachineImage `json:"applied"`
+		Computed  []MachineImage `json:"computed"`
+	}{landscape, applied, computed})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+
+	diff := DiffMachineImages(applied, computed)
+	return &ApprovalRequest{
+		ID:        hex.EncodeToString(sum[:8]),
+		Landscape: landscape,
+		Impact:    ImpactOf(diff),
+		Score:     ScoreImpact(diff, applied, computed, shoots),
+		Diff:      diff,
+	}, nil
+}
+
+// ApprovalThreshold determines which changes need an approval. A change needs an approval if it reaches any of the
+// limits which are set.
+type ApprovalThreshold struct {
+	// Impact is the lowest impact level which needs an approval, e.g. ImpactRemoval.
+	Impact ImpactLevel `json:"impact,omitempty"`
+	// RemovedVersions is the number of removed versions from which an approval is needed. Zero is no limit.
+	RemovedVersions int `json:"removedVersions,omitempty"`
+	// AffectedShoots is the number of affected shoots from which an approval is needed. Zero is no limit.
+	AffectedShoots int `json:"affectedShoots,omitempty"`
+	// Providers is the number of touched providers from which an approval is needed. Zero is no limit.
+	Providers int `json:"providers,omitempty"`
+}
+
+// Validate returns an error if the impact level is unknown, a limit is negative or no limit is set.
+func (t *ApprovalThreshold) Validate() error {
+	if t == nil || len(t.Impact) == 0 && t.RemovedVersions == 0 && t.AffectedShoots == 0 && t.Providers == 0 {
+		return errors.New("an approval threshold must set an impact level or a limit")
+	}
+	if len(t.Impact) > 0 {
+		if err := t.Impact.Validate(); err != nil {
+			return err
+		}
+	}
+	if t.RemovedVersions < 0 || t.AffectedShoots < 0 || t.Providers < 0 {
+		return errors.New("the limits of an approval threshold must not be negative")
+	}
+	return nil
+}
+
+// reachedBy returns the limits which the request reaches.
+func (t *ApprovalThreshold) reachedBy(request *ApprovalRequest) []string {
+	reached := []string{}
+	if len(t.Impact) > 0 && request.Impact != ImpactNone && request.Impact.Exceeds(t.Impact) {
+		reached = append(reached, "impact "+string(request.Impact))
+	}
+	if score := request.Score; score != nil {
+		if t.RemovedVersions > 0 && score.RemovedVersions >= t.RemovedVersions {
+			reached = append(reached, fmt.Sprintf("%d removed versions", score.RemovedVersions))
+		}
+		if t.AffectedShoots > 0 && score.AffectedShoots >= t.AffectedShoots {
+			reached = append(reached, fmt.Sprintf("%d affected shoots", score.AffectedShoots))
+		}
+		if t.Providers > 0 && len(score.Providers) >= t.Providers {
+			reached = append(reached, fmt.Sprintf("%d touched providers", len(score.Providers)))
+		}
+	}
+	return reached
+}
+
+// ApprovalGate decides whether a change may be applied, e.g. by consulting a change management process.
+type ApprovalGate interface {
+	// CheckApproval returns a reason if the change is not approved yet and an empty string otherwise.
+	CheckApproval(ctx context.Context, request *ApprovalRequest) (string, error)
+}
+
+// ApprovalConsumer is implemented by gates whose approvals must be consumed once the change is applied, so that they
+// cannot approve the same change again, e.g. after a rollback.
+type ApprovalConsumer interface {
+	// ConsumeApproval removes the approval of the request. Consuming a missing approval is no error.
+	ConsumeApproval(ctx context.Context, request *ApprovalRequest) error
+}
+
+// ConsumeApproval consumes the approval of the request if the gate is an ApprovalConsumer.
+func ConsumeApproval(ctx context.Context, gate ApprovalGate, request *ApprovalRequest) error {
+	consumer, ok := gate.(ApprovalConsumer)
+	if !ok {
+		return nil
+	}
+	if err := consumer.ConsumeApproval(ctx, request); err != nil {
+		return fmt.Errorf("unable to consume approval of change %s: %w", request.ID, err)
+	}
+	return nil
+}
+
+// ApprovalPendingError is returned by CheckApproval if the gate has not approved the change yet.
+type ApprovalPendingError struct {
+	Request *ApprovalRequest
+	Reason  string
+}
+
+func (e *ApprovalPendingError) Error() string {
+	return fmt.Sprintf("change %s with impact %s is not approved: %s", e.Request.ID, e.Request.Impact, e.Reason)
+}
+
+// CheckApproval consults the gate if the request reaches the threshold. Changes below the threshold are applied
+// without approval. It returns an *ApprovalPendingError if the gate does not approve the change.
+func CheckApproval(ctx context.Context, gate ApprovalGate, request *ApprovalRequest, threshold *ApprovalThreshold) error {
+	if err := threshold.Validate(); err != nil {
+		return err
+	}
+	reached := threshold.reachedBy(request)
+	if len(reached) == 0 {
+		return nil
+	}
+
+	LoggerFromContext(ctx).Info("Requesting approval", "id", request.ID, "reached", strings.Join(reached, ", "))
+	reason, err := gate.CheckApproval(ctx, request)
+	if err != nil {
+		return fmt.Errorf("unable to check approval of change %s: %w", request.ID, err)
+	}
+	if len(reason) > 0 {
+		return &ApprovalPendingError{Request: request, Reason: reason}
+	}
+	return nil
+}
+
+// WebhookApprovalGate posts the approval request as json to an http endpoint, which answers with an ApprovalResponse.
+// The endpoint is called on every check, so it must answer repeated requests with the same ID consistently.
+type WebhookApprovalGate struct {
+	URL string
+	// Token is sent as bearer token, if set.
+	Token string
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+}
+
+// ApprovalResponse is the answer of the endpoint of a WebhookApprovalGate.
+type ApprovalResponse struct {
+	Approved bool `json:"approved"`
+	// Reason explains why a change is not approved, e.g. a link to the pending change request.
+	Reason string `json:"reason,omitempty"`
+}
+
+// CheckApproval asks the endpoint for the approval.
+func (g *WebhookApprovalGate) CheckApproval(ctx context.Context, request *ApprovalRequest) (string, error) {
+	response := &ApprovalResponse{}
+	if err := postAuthorizedJSON(ctx, g.Client, "request approval", g.URL, g.Token, request, response); err != nil {
+		return "", err
+	}
+	if response.Approved {
+		return "", nil
+	}
+	if len(response.Reason) == 0 {
+		return "rejected by " + g.URL, nil
+	}
+	return response.Reason, nil
+}
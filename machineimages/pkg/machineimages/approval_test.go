@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type approvalGateFunc func(ctx context.Context, request *ApprovalRequest) (string, error)
+
+func (f approvalGateFunc) CheckApproval(ctx context.Context, request *ApprovalRequest) (string, error) {
+	return f(ctx, request)
+}
+
+var _ = Describe("approval", func() {
+
+	var applied []MachineImage
+
+	BeforeEach(func() {
+		applied = []MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0"}, {"version": "934.8.0"}}},
+		}
+	})
+
+	It("should classify the impact of changes", func() {
+		for _, computed := range []struct {
+			images []MachineImage
+			impact ImpactLevel
+		}{
+			{applied, ImpactNone},
+			{[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0"}, {"version": "934.8.0"}, {"version": "934.9.0"}}}}, ImpactAddition},
+			{[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0", "classification": "deprecated"}, {"version": "934.8.0"}}}}, ImpactChange},
+			{[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.8.0"}, {"version": "934.9.0"}}}}, ImpactRemoval},
+		} {
+			request, err := NewApprovalRequest("dev", applied, computed.images, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(request.Impact).To(Equal(computed.impact))
+		}
+	})
+
+	It("should identify requests by the change", func() {
+		first, err := NewApprovalRequest("dev", applied, []MachineImage{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := NewApprovalRequest("dev", applied, []MachineImage{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		other, err := NewApprovalRequest("prod", applied, []MachineImage{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(first.ID).To(HaveLen(16))
+		Expect(second.ID).To(Equal(first.ID))
+		Expect(other.ID).NotTo(Equal(first.ID))
+	})
+
+	It("should only consult the gate above the threshold", func() {
+		calls := 0
+		gate := approvalGateFunc(func(_ context.Context, _ *ApprovalRequest) (string, error) {
+			calls++
+			return "change request CHG-1 is open", nil
+		})
+		request, err := NewApprovalRequest("dev", applied, []MachineImage{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		removal := &ApprovalThreshold{Impact: ImpactRemoval}
+		Expect(CheckApproval(context.Background(), gate, &ApprovalRequest{Impact: ImpactChange}, removal)).To(Succeed())
+		Expect(CheckApproval(context.Background(), gate, &ApprovalRequest{Impact: ImpactNone}, &ApprovalThreshold{Impact: ImpactAddition})).To(Succeed())
+		Expect(calls).To(Equal(0))
+
+		err = CheckApproval(context.Background(), gate, request, removal)
+		Expect(err).To(Equal(&ApprovalPendingError{Request: request, Reason: "change request CHG-1 is open"}))
+		Expect(err).To(MatchError("change " + request.ID + " with impact removal is not approved: change request CHG-1 is open"))
+		Expect(calls).To(Equal(1))
+
+		Expect(CheckApproval(context.Background(), gate, request, &ApprovalThreshold{Impact: "any"})).To(MatchError(ContainSubstring(`unknown impact level "any"`)))
+		Expect(CheckApproval(context.Background(), gate, request, &ApprovalThreshold{})).To(MatchError("an approval threshold must set an impact level or a limit"))
+		Expect(CheckApproval(context.Background(), gate, request, &ApprovalThreshold{Providers: -1})).To(MatchError("the limits of an approval threshold must not be negative"))
+	})
+
+	It("should consult the gate if the score reaches a limit", func() {
+		calls := 0
+		gate := approvalGateFunc(func(_ context.Context, _ *ApprovalRequest) (string, error) {
+			calls++
+			return "", nil
+		})
+		request := &ApprovalRequest{Impact: ImpactRemoval, Score: &ImpactScore{RemovedVersions: 2, AffectedShoots: 5, Providers: []string{"aws"}}}
+
+		for _, threshold := range []*ApprovalThreshold{{RemovedVersions: 3}, {AffectedShoots: 6}, {Providers: 2}} {
+			Expect(CheckApproval(context.Background(), gate, request, threshold)).To(Succeed())
+		}
+		Expect(calls).To(Equal(0))
+		for _, threshold := range []*ApprovalThreshold{{RemovedVersions: 2}, {AffectedShoots: 5}, {Providers: 1}, {Impact: ImpactChange, Providers: 3}} {
+			Expect(CheckApproval(context.Background(), gate, request, threshold)).To(Succeed())
+		}
+		Expect(calls).To(Equal(4))
+	})
+
+	It("should fail if the gate fails", func() {
+		gate := approvalGateFunc(func(_ context.Context, _ *ApprovalRequest) (string, error) {
+			return "", errors.New("unavailable")
+		})
+		err := CheckApproval(context.Background(), gate, &ApprovalRequest{ID: "1", Impact: ImpactRemoval}, &ApprovalThreshold{Impact: ImpactRemoval})
+		Expect(err).To(MatchError("unable to check approval of change 1: unavailable"))
+	})
+
+	It("should ask a webhook for the approval", func() {
+		approved := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer secret"))
+			request := &ApprovalRequest{}
+			Expect(json.NewDecoder(r.Body).Decode(request)).To(Succeed())
+			Expect(request.Diff.Removed).To(HaveLen(2))
+			Expect(json.NewEncoder(w).Encode(&ApprovalResponse{Approved: approved})).To(Succeed())
+		}))
+		defer server.Close()
+
+		gate := &WebhookApprovalGate{URL: server.URL, Token: "secret"}
+		request, err := NewApprovalRequest("dev", applied, []MachineImage{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(gate.CheckApproval(context.Background(), request)).To(Equal("rejected by " + server.URL))
+		approved = true
+		Expect(gate.CheckApproval(context.Background(), request)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+const (
+	ArchitectureAMD64 = "amd64"
+	ArchitectureARM64 = "arm64"
+
+	// DefaultArchitecture is the architecture of provider configs and regions without architecture, like in Gardener.
+	DefaultArchitecture = ArchitectureAMD64
+)
+
+// getArchitectures returns the architectures list of the version, or nil for architecture-agnostic versions.
+func (v MachineImageVersion) getArchitectures() []string {
+	switch value := v["architectures"].(type) {
+	case []string:
+		return value
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, entry := range value {
+			if architecture, ok := entry.(string); ok {
+				result = append(result, architecture)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// getArchitecture returns the architecture field of a provider config or one of its regions.
+func getArchitecture(config map[string]interface{}) string {
+	if architecture, ok := config["architecture"].(string); ok && len(architecture) > 0 {
+		return architecture
+	}
+	return DefaultArchitecture
+}
+
+// getArchitectureVersionConfigs returns the provider configs of a version which supports the given architectures and
+// the architectures without provider config. The configs are looked up per architecture, like for architecture-agnostic
+// versions the configs of exact versions take precedence over version constraints and the configs of the landscape over
+// the default configs. The regions of the matching configs with regions are merged into one config which only contains
+// regions of the architectures, its other fields are taken from the first of these configs. Matching configs without
+// regions are the image of their architecture and are returned one per architecture, so that the images of the other
+// architectures are kept. The architectures field of each config lists the supported architectures in the order of the
+// given architectures.
+func getArchitectureVersionConfigs(
+	imageName, versionNumber string,
+	architectures []string,
+	providerLandscapeOsImages, providerOsImages []MachineImage,
+) ([]MachineImageVersion, []string) {
+	landscapeConfigs, landscapeRangeConfigs := getVersionConfigs(imageName, versionNumber, providerLandscapeOsImages)
+	providerConfigs, providerRangeConfigs := getVersionConfigs(imageName, versionNumber, providerOsImages)
+	// exact versions take precedence over version constraints in both lists
+	if len(landscapeConfigs) == 0 && len(providerConfigs) == 0 {
+		landscapeConfigs, providerConfigs = landscapeRangeConfigs, providerRangeConfigs
+	}
+	// the configs of the landscape take precedence for the architectures they support
+	landscapeArchitectures := map[string]bool{}
+	for _, config := range landscapeConfigs {
+		for _, architecture := range configArchitectures(config) {
+			landscapeArchitectures[architecture] = true
+		}
+	}
+
+	var merged MachineImageVersion
+	var regions []interface{}
+	mergedArchitectures := map[string]bool{}
+	plainConfigs := []MachineImageVersion{}
+	plainArchitectures := map[string]bool{}
+	add := func(config MachineImageVersion, fromLandscape bool) {
+		applies := func(architecture string) bool {
+			return contains(architectures, architecture) && landscapeArchitectures[architecture] == fromLandscape
+		}
+		configRegions, ok := config["regions"].([]interface{})
+		if !ok {
+			architecture := getArchitecture(config)
+			if !applies(architecture) || plainArchitectures[architecture] {
+				return
+			}
+			plainArchitectures[architecture] = true
+			plain := MachineImageVersion{}
+			for key, value := range config {
+				plain[key] = value
+			}
+			plain.SetArchitectures(architecture)
+			plainConfigs = append(plainConfigs, plain)
+			return
+		}
+
+		matched := false
+		for _, entry := range configRegions {
+			region, ok := entry.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if architecture := getArchitecture(region); applies(architecture) {
+				mergedArchitectures[architecture] = true
+				regions = append(regions, region)
+				matched = true
+			}
+		}
+		if matched && merged == nil {
+			merged = MachineImageVersion{}
+			for key, value := range config {
+				merged[key] = value
+			}
+		}
+	}
+	for _, config := range landscapeConfigs {
+		add(config, true)
+	}
+	for _, config := range providerConfigs {
+		add(config, false)
+	}
+
+	result := []MachineImageVersion{}
+	if merged != nil {
+		merged["regions"] = regions
+		supportedArchitectures := []string{}
+		for _, architecture := range architectures {
+			if mergedArchitectures[architecture] {
+				supportedArchitectures = append(supportedArchitectures, architecture)
+			}
+		}
+		if len(supportedArchitectures) > 1 {
+			// the architecture of the first config does not apply to the merged config
+			delete(merged, "architecture")
+		}
+		merged.SetArchitectures(supportedArchitectures...)
+		result = append(result, merged)
+	}
+	result = append(result, plainConfigs...)
+
+	missing := []string{}
+	for _, architecture := range architectures {
+		if !mergedArchitectures[architecture] && !plainArchitectures[architecture] {
+			missing = append(missing, architecture)
+		}
+	}
+	return result, missing
+}
+
+// configArchitectures returns the architectures of a provider config, which are the architectures of its regions if it
+// has regions.
+func configArchitectures(config MachineImageVersion) []string {
+	configRegions, ok := config["regions"].([]interface{})
+	if !ok {
+		return []string{getArchitecture(config)}
+	}
+	result := []string{}
+	for _, entry := range configRegions {
+		if region, ok := entry.(map[string]interface{}); ok {
+			result = append(result, getArchitecture(region))
+		}
+	}
+	return result
+}
+
+// getVersionConfigs returns all configs of the exact version in the images and all configs whose version constraint
+// the version satisfies.
+func getVersionConfigs(imageName, versionNumber string, images []MachineImage) ([]MachineImageVersion, []MachineImageVersion) {
+	result := []MachineImageVersion{}
+	rangeConfigs := []MachineImageVersion{}
+	for _, image := range images {
+		if image.Name != imageName {
+			continue
+		}
+		for _, version := range image.Versions {
+			if version.getVersion() != nil && *version.getVersion() == versionNumber {
+				result = append(result, version)
+			} else if matchesVersionConstraint(version, versionNumber) {
+				rangeConfigs = append(rangeConfigs, withoutVersionConstraint(version))
+			}
+		}
+	}
+	return result, rangeConfigs
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("architectures", func() {
+
+	parse := func(data string) []MachineImage {
+		images := []MachineImage{}
+		Expect(yaml.Unmarshal([]byte(data), &images)).To(Succeed())
+		return images
+	}
+
+	compute := func(images, providerImages string) []MachineImage {
+		result, err := ComputeMachineImages(context.Background(), logr.Discard(), parse(images), nil,
+			parse(providerImages), nil, nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	It("should merge the regions of all architectures", func() {
+		result := compute(`
+- name: gardenlinux
+  versions:
+  - version: 934.1.0
+    architectures: [amd64, arm64]
+`, `
+- name: gardenlinux
+  versions:
+  - version: 934.1.0
+    regions:
+    - {name: eu-west-1, ami: ami-a}
+    - {name: eu-west-1, ami: ami-b, architecture: arm64}
+    - {name: eu-west-1, ami: ami-c, architecture: ppc64le}
+`)
+		Expect(result).To(Equal(parse(`
+- name: gardenlinux
+  versions:
+  - version: 934.1.0
+    architectures: [amd64, arm64]
+    regions:
+    - {name: eu-west-1, ami: ami-a}
+    - {name: eu-west-1, ami: ami-b, architecture: arm64}
+`)))
+	})
+
+	It("should match provider configs per architecture", func() {
+		result := compute(`
+- name: gardenlinux
+  versions:
+  - version: 934.1.0
+    architectures: [amd64, arm64]
+  - version: 318.9.0
+    architectures: [arm64]
+  - version: 184.0.0
+`, `
+- name: gardenlinux
+  versions:
+  - {version: 934.1.0, image: gl-arm, architecture: arm64}
+  - {version: 934.1.0, image: gl-amd}
+  - {version: 318.9.0, image: gl-318-amd}
+  - {version: 184.0.0, image: gl-184}
+`)
+		Expect(result).To(Equal(parse(`
+- name: gardenlinux
+  versions:
+  - version: 934.1.0
+    image: gl-arm
+    architecture: arm64
+    architectures: [arm64]
+  - version: 934.1.0
+    image: gl-amd
+    architectures: [amd64]
+  - version: 184.0.0
+    image: gl-184
+`)))
+	})
+
+	It("should keep the architectures of a partially configured version and report the missing ones", func() {
+		imports := &Imports{
+			MachineImages: parse(`
+- name: gardenlinux
+  versions:
+  - version: 934.1.0
+    architectures: [amd64, arm64]
+  - version: 318.9.0
+    architectures: [amd64, arm64]
+`),
+			MachineImagesProviderLs: parse(`
+- name: gardenlinux
+  versions:
+  - {version: 934.1.0, image: gl-landscape-amd}
+`),
+			MachineImagesProvider: parse(`
+- name: gardenlinux
+  versions:
+  - {version: 934.1.0, image: gl-amd}
+  - {version: 934.1.0, image: gl-arm, architecture: arm64}
+  - version: 318.9.0
+    regions:
+    - {name: eu-west-1, ami: ami-a}
+`),
+		}
+		result, warnings, err := ComputeMachineImagesWithWarnings(context.Background(), logr.Discard(), imports)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(parse(`
+- name: gardenlinux
+  versions:
+  - version: 934.1.0
+    image: gl-landscape-amd
+    architectures: [amd64]
+  - version: 934.1.0
+    image: gl-arm
+    architecture: arm64
+    architectures: [arm64]
+  - version: 318.9.0
+    architectures: [amd64]
+    regions:
+    - {name: eu-west-1, ami: ami-a}
+`)))
+		Expect(warnings).To(Equal([]ReportEntry{{Image: OsNameGardenLinux, Version: "318.9.0", Reason: ReasonNoProviderConfig,
+			Message: "no provider config found for the architectures arm64"}}))
+	})
+
+	It("should keep the architecture of a single architecture config", func() {
+		result := compute(`
+- name: ubuntu
+  versions:
+  - version: 22.4.0
+    architectures: [arm64]
+`, `
+- name: ubuntu
+  versions:
+  - {version: 22.4.0, image: ubuntu-amd}
+  - {version: 22.4.0, image: ubuntu-arm, architecture: arm64}
+`)
+		Expect(result).To(Equal(parse(`
+- name: ubuntu
+  versions:
+  - version: 22.4.0
+    image: ubuntu-arm
+    architecture: arm64
+    architectures: [arm64]
+`)))
+	})
+})
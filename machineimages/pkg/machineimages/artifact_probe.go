@@ -0,0 +1,472 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultArtifactReferenceField is the field of versions which references their OCI artifact.
+	DefaultArtifactReferenceField = "ociReference"
+	// DefaultArtifactProbeTimeout is the default timeout of probing an artifact.
+	DefaultArtifactProbeTimeout = 10 * time.Second
+)
+
+// manifestMediaTypes are accepted when probing manifests, so that registries do not convert or reject them.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+}
+
+// ArtifactProbe checks that the OCI artifacts of versions exist in their registry before the versions are advertised,
+// e.g. for Garden Linux, which is distributed as OCI artifacts. The manifests are probed with the OCI distribution
+// api. Versions whose artifact does not exist are dropped or fail the computation.
+type ArtifactProbe struct {
+	// Repositories maps image names to the repository of their artifacts, e.g. "ghcr.io/gardenlinux/gardenlinux". The
+	// artifact of a version is tagged with the version.
+	Repositories map[string]string `json:"repositories,omitempty" yaml:"repositories,omitempty"`
+	// ReferenceField is the field of versions with the full reference of their artifact, e.g.
+	// "ghcr.io/gardenlinux/gardenlinux@sha256:...". It takes precedence over Repositories, so that versions of all
+	// images can be pinned to a digest. Defaults to DefaultArtifactReferenceField.
+	ReferenceField string `json:"referenceField,omitempty" yaml:"referenceField,omitempty"`
+	// Action determines whether versions with missing artifacts are dropped or cause an error. Defaults to
+	// PolicyActionDrop.
+	Action PolicyAction `json:"action,omitempty" yaml:"action,omitempty"`
+	// InferArchitectures reads the architectures of the artifacts from their image index or config. Versions without
+	// architectures get the inferred architectures. Declared architectures which the artifact does not support would
+	// only surface as boot failures of nodes, so they are removed from the version or, with the error action, fail the
+	// computation.
+	InferArchitectures bool `json:"inferArchitectures,omitempty" yaml:"inferArchitectures,omitempty"`
+	// Token is sent as bearer token to the registries of TokenRegistries. Without token, and for all other registries,
+	// anonymous pull tokens are requested from registries which require them.
+	Token *SecretValue `json:"token,omitempty" yaml:"token,omitempty"`
+	// TokenRegistries are the registry hosts, e.g. "ghcr.io", which the token is sent to. It is required with a token,
+	// so that the token of one registry is never sent to the registry of another artifact.
+	TokenRegistries []string `json:"tokenRegistries,omitempty" yaml:"tokenRegistries,omitempty"`
+	// TimeoutSeconds limits probing an artifact. Defaults to DefaultArtifactProbeTimeout.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty"`
+	// PlainHTTP probes the registries via http instead of https, e.g. local registries.
+	PlainHTTP bool `json:"plainHTTP,omitempty" yaml:"plainHTTP,omitempty"`
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client `json:"-" yaml:"-"`
+
+	// results caches the probes of artifacts referenced by digest, which cannot change.
+	results      map[string]artifactProbeResult
+	resultsMutex sync.Mutex
+}
+
+type artifactProbeResult struct {
+	exists bool
+	// architectures is nil if the architectures were not inspected.
+	architectures []string
+}
+
+// ArtifactReference is a parsed reference of an OCI artifact.
+type ArtifactReference struct {
+	Registry   string
+	Repository string
+	// Reference is the tag or digest.
+	Reference string
+}
+
+// String returns the reference in the format "<registry>/<repository>:<tag>" or "<registry>/<repository>@<digest>".
+func (r ArtifactReference) String() string {
+	if strings.Contains(r.Reference, ":") {
+		return r.Registry + "/" + r.Repository + "@" + r.Reference
+	}
+	return r.Registry + "/" + r.Repository + ":" + r.Reference
+}
+
+// ParseArtifactReference parses a reference with registry and tag or digest, e.g. "ghcr.io/gardenlinux/gardenlinux:934.1".
+func ParseArtifactReference(reference string) (ArtifactReference, error) {
+	parts := strings.SplitN(reference, "/", 2)
+	if len(parts) != 2 || !(strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return ArtifactReference{}, fmt.Errorf("artifact reference %q has no registry", reference)
+	}
+	result := ArtifactReference{Registry: parts[0], Repository: parts[1]}
+
+	if i := strings.Index(result.Repository, "@"); i >= 0 {
+		result.Repository, result.Reference = result.Repository[:i], result.Repository[i+1:]
+	} else if i := strings.LastIndex(result.Repository, ":"); i >= 0 {
+		result.Repository, result.Reference = result.Repository[:i], result.Repository[i+1:]
+	}
+	if len(result.Repository) == 0 || len(result.Reference) == 0 {
+		return ArtifactReference{}, fmt.Errorf("artifact reference %q has no repository and tag or digest", reference)
+	}
+	return result, nil
+}
+
+// artifactReference returns the reference of the artifact of a version or false if the version is not probed.
+func (p *ArtifactProbe) artifactReference(image string, v MachineImageVersion) (string, bool) {
+	field := p.ReferenceField
+	if len(field) == 0 {
+		field = DefaultArtifactReferenceField
+	}
+	if reference, ok := v[field].(string); ok && len(reference) > 0 {
+		return reference, true
+	}
+	if repository, ok := p.Repositories[image]; ok {
+		return repository + ":" + versionOrEmpty(v), true
+	}
+	return "", false
+}
+
+// Exists returns whether the manifest of the artifact exists in its registry.
+func (p *ArtifactProbe) Exists(ctx context.Context, reference ArtifactReference) (bool, error) {
+	exists, _, err := p.inspect(ctx, reference, false)
+	return exists, err
+}
+
+// Architectures returns the architectures of the artifact, which are the platforms of an image index or the
+// architecture of the config of an image manifest. It returns nil if the artifact does not exist.
+func (p *ArtifactProbe) Architectures(ctx context.Context, reference ArtifactReference) ([]string, error) {
+	exists, architectures, err := p.inspect(ctx, reference, true)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return architectures, nil
+}
+
+// inspect returns whether the artifact exists and, if requested, its architectures. The results of artifacts referenced
+// by digest are cached.
+func (p *ArtifactProbe) inspect(ctx context.Context, reference ArtifactReference, architectures bool) (bool, []string, error) {
+	digest := strings.Contains(reference.Reference, ":")
+	if digest {
+		p.resultsMutex.Lock()
+		result, ok := p.results[reference.String()]
+		p.resultsMutex.Unlock()
+		if ok && (!architectures || !result.exists || result.architectures != nil) {
+			return result.exists, result.architectures, nil
+		}
+	}
+
+	exists, result, err := p.probe(ctx, reference, architectures)
+	if err != nil {
+		return false, nil, err
+	}
+	if digest {
+		p.resultsMutex.Lock()
+		if p.results == nil {
+			p.results = map[string]artifactProbeResult{}
+		}
+		p.results[reference.String()] = artifactProbeResult{exists: exists, architectures: result}
+		p.resultsMutex.Unlock()
+	}
+	return exists, result, nil
+}
+
+// probe requests the manifest of the artifact and, if requested, its architectures from the registry.
+func (p *ArtifactProbe) probe(ctx context.Context, reference ArtifactReference, architectures bool) (bool, []string, error) {
+	timeout := DefaultArtifactProbeTimeout
+	if p.TimeoutSeconds > 0 {
+		timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	scheme := "https"
+	if p.PlainHTTP {
+		scheme = "http"
+	}
+	client := p.Client
+	if client == nil {
+		client = NewHTTPClient(nil)
+	}
+	token := ""
+	if contains(p.TokenRegistries, reference.Registry) {
+		var err error
+		if token, err = p.Token.Secret(); err != nil {
+			return false, nil, fmt.Errorf("invalid token of artifact probe: %w", err)
+		}
+	}
+	session := &registrySession{
+		client:    client,
+		baseURL:   fmt.Sprintf("%s://%s/v2/%s", scheme, reference.Registry, reference.Repository),
+		token:     token,
+		anonymous: len(token) == 0,
+	}
+
+	method := http.MethodHead
+	if architectures {
+		method = http.MethodGet
+	}
+	resp, err := session.request(ctx, method, "/manifests/"+reference.Reference, strings.Join(manifestMediaTypes, ", "))
+	if err != nil {
+		return false, nil, fmt.Errorf("unable to probe artifact %s: %w", reference, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, nil, nil
+	default:
+		return false, nil, fmt.Errorf("unable to probe artifact %s: unexpected status %d", reference, resp.StatusCode)
+	}
+	if !architectures {
+		return true, nil, nil
+	}
+
+	manifest := &struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Manifests []struct {
+			Platform *struct {
+				Architecture string `json:"architecture"`
+			} `json:"platform"`
+		} `json:"manifests"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(manifest); err != nil {
+		return false, nil, fmt.Errorf("invalid manifest of artifact %s: %w", reference, err)
+	}
+
+	// an empty list marks the architectures as inspected
+	result := []string{}
+	if len(manifest.Manifests) > 0 {
+		for _, m := range manifest.Manifests {
+			// attestations of image indexes have the platform unknown/unknown
+			if m.Platform != nil && len(m.Platform.Architecture) > 0 && m.Platform.Architecture != "unknown" &&
+				!contains(result, m.Platform.Architecture) {
+				result = append(result, m.Platform.Architecture)
+			}
+		}
+	} else if len(manifest.Config.Digest) > 0 {
+		configResp, err := session.request(ctx, http.MethodGet, "/blobs/"+manifest.Config.Digest, "")
+		if err != nil {
+			return false, nil, fmt.Errorf("unable to get config of artifact %s: %w", reference, err)
+		}
+		defer configResp.Body.Close()
+		if configResp.StatusCode != http.StatusOK {
+			return false, nil, fmt.Errorf("unable to get config of artifact %s: unexpected status %d", reference, configResp.StatusCode)
+		}
+		config := &struct {
+			Architecture string `json:"architecture"`
+		}{}
+		if err := json.NewDecoder(configResp.Body).Decode(config); err != nil {
+			return false, nil, fmt.Errorf("invalid config of artifact %s: %w", reference, err)
+		}
+		if len(config.Architecture) > 0 {
+			result = append(result, config.Architecture)
+		}
+	}
+	sort.Strings(result)
+	return true, result, nil
+}
+
+// registrySession sends the requests of a probe to a repository and keeps the token between them.
+type registrySession struct {
+	client  *http.Client
+	baseURL string
+	token   string
+	// anonymous allows to request anonymous pull tokens
+	anonymous bool
+}
+
+// request sends a request to the path of the repository. The caller must close the body of the response.
+func (s *registrySession) request(ctx context.Context, method, path, accept string) (*http.Response, error) {
+	requestURL := s.baseURL + path
+	if err := CheckNetworkAccess(ctx, "probe artifact", requestURL); err != nil {
+		return nil, err
+	}
+	if err := InjectFault(ctx, FaultPointFetch, requestURL); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, method, requestURL, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && s.anonymous && len(s.token) == 0 {
+		resp.Body.Close()
+		// registries like ghcr.io require anonymous pull tokens for public repositories
+		s.token, err = anonymousToken(ctx, s.client, resp.Header.Get("WWW-Authenticate"))
+		if err != nil {
+			return nil, err
+		}
+		return s.do(ctx, method, requestURL, accept)
+	}
+	return resp, nil
+}
+
+func (s *registrySession) do(ctx context.Context, method, requestURL, accept string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", accept)
+	}
+	if len(s.token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+	return s.client.Do(req)
+}
+
+// anonymousToken requests a token from the realm of a bearer challenge, e.g.
+// `Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:gardenlinux/gardenlinux:pull"`.
+func anonymousToken(ctx context.Context, client *http.Client, challenge string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return "", fmt.Errorf("registry requires unsupported authentication %q", challenge)
+	}
+	params := map[string]string{}
+	for _, param := range strings.Split(challenge[len("bearer "):], ",") {
+		parts := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(parts) == 2 {
+			params[strings.ToLower(parts[0])] = strings.Trim(parts[1], `"`)
+		}
+	}
+	realm, err := url.Parse(params["realm"])
+	if err != nil || len(params["realm"]) == 0 {
+		return "", fmt.Errorf("invalid realm of bearer challenge %q", challenge)
+	}
+	query := realm.Query()
+	for _, name := range []string{"service", "scope"} {
+		if len(params[name]) > 0 {
+			query.Set(name, params[name])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	body := &struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := fetchJSON(ctx, client, "request registry token", realm.String(), body); err != nil {
+		return "", err
+	}
+	if len(body.Token) > 0 {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
+
+// applyArtifactProbe drops or rejects all versions of the images whose OCI artifact does not exist. With inferred
+// architectures, the architectures of the versions are modified in place, so it must only be applied to the computed
+// result.
+func applyArtifactProbe(ctx context.Context, images []MachineImage, probe *ArtifactProbe) ([]MachineImage, error) {
+	if probe == nil {
+		return images, nil
+	}
+	action := probe.Action
+	if action == "" {
+		action = PolicyActionDrop
+	}
+	if action != PolicyActionDrop && action != PolicyActionError {
+		return nil, fmt.Errorf("policy action does not exist %s", action)
+	}
+
+	_, reporter := FromContext(ctx)
+	result := make([]MachineImage, 0, len(images))
+	missing, mismatches := []string{}, []string{}
+	for _, image := range images {
+		versions := make([]MachineImageVersion, 0, len(image.Versions))
+		for _, v := range image.Versions {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			reference, ok := probe.artifactReference(image.Name, v)
+			if !ok {
+				versions = append(versions, v)
+				continue
+			}
+			parsed, err := ParseArtifactReference(reference)
+			if err != nil {
+				return nil, fmt.Errorf("invalid artifact of %s version %s: %w", image.Name, versionOrEmpty(v), err)
+			}
+			exists, inferred, err := probe.inspect(ctx, parsed, probe.InferArchitectures)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				mismatch := reconcileArchitectures(v, inferred)
+				if len(mismatch) == 0 {
+					versions = append(versions, v)
+					continue
+				}
+				message := fmt.Sprintf("artifact %s does not support the architectures %s", reference, strings.Join(mismatch, ", "))
+				mismatches = append(mismatches, fmt.Sprintf("%s:%s (%s)", image.Name, versionOrEmpty(v), message))
+				if action == PolicyActionError {
+					continue
+				}
+				supported := []string{}
+				for _, architecture := range v.getArchitectures() {
+					if !contains(mismatch, architecture) {
+						supported = append(supported, architecture)
+					}
+				}
+				if len(supported) > 0 {
+					v.SetArchitectures(supported...)
+					versions = append(versions, v)
+				} else {
+					message += ", dropping the version"
+				}
+				reporter.Report(ReportEntry{
+					Image:   image.Name,
+					Version: versionOrEmpty(v),
+					Reason:  ReasonArchitectureMismatch,
+					Message: message,
+				})
+				continue
+			}
+
+			missing = append(missing, fmt.Sprintf("%s:%s (%s)", image.Name, versionOrEmpty(v), reference))
+			if action == PolicyActionDrop {
+				reporter.Report(ReportEntry{
+					Image:   image.Name,
+					Version: versionOrEmpty(v),
+					Reason:  ReasonArtifactNotFound,
+					Message: "artifact " + reference + " does not exist",
+				})
+			}
+		}
+		if len(versions) > 0 {
+			result = append(result, MachineImage{Name: image.Name, Versions: versions})
+		}
+	}
+
+	if action == PolicyActionError && len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("artifacts of machine image versions do not exist: %s", strings.Join(missing, ", "))
+	}
+	if action == PolicyActionError && len(mismatches) > 0 {
+		sort.Strings(mismatches)
+		return nil, fmt.Errorf("architectures of machine image versions do not match their artifacts: %s", strings.Join(mismatches, ", "))
+	}
+	return result, nil
+}
+
+// reconcileArchitectures sets the inferred architectures of a version without architectures and returns the declared
+// architectures of the version which are not inferred. Nothing is reconciled if no architectures are inferred, e.g.
+// for artifacts without platform.
+func reconcileArchitectures(v MachineImageVersion, inferred []string) []string {
+	if len(inferred) == 0 {
+		return nil
+	}
+	declared := v.getArchitectures()
+	if len(declared) == 0 {
+		v.SetArchitectures(inferred...)
+		return nil
+	}
+
+	mismatch := []string{}
+	for _, architecture := range declared {
+		if !contains(inferred, architecture) {
+			mismatch = append(mismatch, architecture)
+		}
+	}
+	return mismatch
+}
@@ -0,0 +1,242 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("artifact probe", func() {
+
+	var (
+		registry *httptest.Server
+		host     string
+		ctx      context.Context
+		reporter *Report
+
+		requestsMutex  sync.Mutex
+		authorizations []string
+		manifests      map[string]int
+	)
+
+	BeforeEach(func() {
+		authorizations, manifests = nil, map[string]int{}
+		registry = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestsMutex.Lock()
+			authorizations = append(authorizations, r.Header.Get("Authorization"))
+			if strings.Contains(r.URL.Path, "/manifests/") && len(r.Header.Get("Authorization")) > 0 {
+				manifests[r.URL.Path]++
+			}
+			requestsMutex.Unlock()
+			if r.Header.Get("Authorization") == "Bearer secret" {
+				_, _ = w.Write([]byte(`{"mediaType": "application/vnd.oci.image.manifest.v1+json"}`))
+				return
+			}
+			if r.URL.Path == "/token" {
+				if r.URL.Query().Get("scope") != "repository:gardenlinux/gardenlinux:pull" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				_, _ = w.Write([]byte(`{"token": "anonymous"}`))
+				return
+			}
+			if r.Header.Get("Authorization") != "Bearer anonymous" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="http://`+r.Host+`/token",service="registry",scope="repository:gardenlinux/gardenlinux:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Path == "/v2/gardenlinux/gardenlinux/blobs/sha256:config" {
+				_, _ = w.Write([]byte(`{"architecture": "amd64", "os": "linux"}`))
+				return
+			}
+			if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.manifest.v1+json") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			switch r.URL.Path {
+			case "/v2/gardenlinux/gardenlinux/manifests/934.1.0":
+				_, _ = w.Write([]byte(`{"mediaType": "application/vnd.oci.image.index.v1+json", "manifests": [
+					{"platform": {"architecture": "arm64", "os": "linux"}},
+					{"platform": {"architecture": "amd64", "os": "linux"}},
+					{"platform": {"architecture": "unknown", "os": "unknown"}}]}`))
+			case "/v2/gardenlinux/gardenlinux/manifests/sha256:abc":
+				_, _ = w.Write([]byte(`{"mediaType": "application/vnd.oci.image.manifest.v1+json", "config": {"digest": "sha256:config"}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		host = strings.TrimPrefix(registry.URL, "http://")
+		reporter = NewReport()
+		ctx = NewContext(context.Background(), logr.Discard(), reporter)
+	})
+
+	AfterEach(func() {
+		registry.Close()
+	})
+
+	images := func() []MachineImage {
+		return []MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.1.0"},
+				{"version": "934.0.0"},
+				{"version": "318.9.0", "ociReference": host + "/gardenlinux/gardenlinux@sha256:abc"},
+			}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}}},
+		}
+	}
+
+	It("should drop versions whose artifact does not exist", func() {
+		probe := &ArtifactProbe{Repositories: map[string]string{OsNameGardenLinux: host + "/gardenlinux/gardenlinux"}, PlainHTTP: true}
+		result, err := applyArtifactProbe(ctx, images(), probe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.1.0"},
+				{"version": "318.9.0", "ociReference": host + "/gardenlinux/gardenlinux@sha256:abc"},
+			}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}}},
+		}))
+		Expect(reporter.Entries()).To(ConsistOf(ReportEntry{
+			Image:   OsNameGardenLinux,
+			Version: "934.0.0",
+			Reason:  ReasonArtifactNotFound,
+			Message: "artifact " + host + "/gardenlinux/gardenlinux:934.0.0 does not exist",
+		}))
+	})
+
+	It("should fail on missing artifacts with the error action", func() {
+		probe := &ArtifactProbe{Repositories: map[string]string{OsNameGardenLinux: host + "/gardenlinux/gardenlinux"}, PlainHTTP: true, Action: PolicyActionError}
+		_, err := applyArtifactProbe(ctx, images(), probe)
+		Expect(err).To(MatchError(ContainSubstring("gardenlinux:934.0.0")))
+	})
+
+	It("should respect the network policy guard", func() {
+		probe := &ArtifactProbe{Repositories: map[string]string{OsNameGardenLinux: host + "/gardenlinux/gardenlinux"}, PlainHTTP: true}
+		_, err := applyArtifactProbe(WithNetworkPolicyGuard(ctx), images(), probe)
+		Expect(err).To(HaveOccurred())
+		Expect(IsNetworkAccessDenied(err)).To(BeTrue())
+	})
+
+	It("should infer and reconcile architectures", func() {
+		probe := &ArtifactProbe{Repositories: map[string]string{OsNameGardenLinux: host + "/gardenlinux/gardenlinux"}, PlainHTTP: true, InferArchitectures: true}
+		input := []MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.1.0"},
+				{"version": "318.9.0", "ociReference": host + "/gardenlinux/gardenlinux@sha256:abc", "architectures": []interface{}{"amd64", "arm64"}},
+			}},
+		}
+		result, err := applyArtifactProbe(ctx, input, probe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.1.0", "architectures": []interface{}{"amd64", "arm64"}},
+				{"version": "318.9.0", "ociReference": host + "/gardenlinux/gardenlinux@sha256:abc", "architectures": []interface{}{"amd64"}},
+			}},
+		}))
+		Expect(reporter.Entries()).To(ConsistOf(ReportEntry{
+			Image:   OsNameGardenLinux,
+			Version: "318.9.0",
+			Reason:  ReasonArchitectureMismatch,
+			Message: "artifact " + host + "/gardenlinux/gardenlinux@sha256:abc does not support the architectures arm64",
+		}))
+
+		reporter = NewReport()
+		ctx = NewContext(context.Background(), logr.Discard(), reporter)
+		input[0].Versions[1]["architectures"] = []interface{}{"arm64"}
+		result, err = applyArtifactProbe(ctx, input, probe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.1.0", "architectures": []interface{}{"amd64", "arm64"}},
+			}},
+		}))
+		Expect(reporter.Entries()).To(ConsistOf(ReportEntry{
+			Image:   OsNameGardenLinux,
+			Version: "318.9.0",
+			Reason:  ReasonArchitectureMismatch,
+			Message: "artifact " + host + "/gardenlinux/gardenlinux@sha256:abc does not support the architectures arm64, dropping the version",
+		}))
+
+		probe.Action = PolicyActionError
+		_, err = applyArtifactProbe(ctx, input, probe)
+		Expect(err).To(MatchError(ContainSubstring("do not match their artifacts")))
+	})
+
+	It("should only send the token to the token registries", func() {
+		probe := &ArtifactProbe{Repositories: map[string]string{OsNameGardenLinux: host + "/gardenlinux/gardenlinux"}, PlainHTTP: true,
+			Token: &SecretValue{Value: "secret"}, TokenRegistries: []string{"ghcr.io"}}
+		exists, err := probe.Exists(ctx, ArtifactReference{Registry: host, Repository: "gardenlinux/gardenlinux", Reference: "934.1.0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(BeTrue())
+		Expect(authorizations).NotTo(ContainElement("Bearer secret"))
+
+		probe.TokenRegistries = []string{host}
+		exists, err = probe.Exists(ctx, ArtifactReference{Registry: host, Repository: "gardenlinux/gardenlinux", Reference: "934.0.0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exists).To(BeTrue())
+		Expect(authorizations[len(authorizations)-1]).To(Equal("Bearer secret"))
+
+		err = ValidateImports(&Imports{ComputeMachineImagesOptions: ComputeMachineImagesOptions{ArtifactProbe: &ArtifactProbe{Token: &SecretValue{Value: "secret"}}}})
+		Expect(err).To(MatchError(ContainSubstring("artifactProbe: token requires tokenRegistries")))
+	})
+
+	It("should probe artifacts referenced by digest once", func() {
+		probe := &ArtifactProbe{PlainHTTP: true, InferArchitectures: true}
+		reference := host + "/gardenlinux/gardenlinux@sha256:abc"
+		input := []MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "318.9.0", "ociReference": reference},
+				{"version": "318.9.1", "ociReference": reference},
+			}},
+		}
+		_, err := applyArtifactProbe(ctx, input, probe)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = applyArtifactProbe(ctx, input, probe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(manifests).To(Equal(map[string]int{"/v2/gardenlinux/gardenlinux/manifests/sha256:abc": 1}))
+		Expect(input[0].Versions[1]["architectures"]).To(Equal([]interface{}{"amd64"}))
+	})
+
+	It("should time out probing an artifact", func() {
+		blocked := make(chan struct{})
+		slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-blocked:
+			}
+		}))
+		defer slow.Close()
+		defer close(blocked)
+
+		probe := &ArtifactProbe{PlainHTTP: true, TimeoutSeconds: 1}
+		start := time.Now()
+		_, err := probe.Exists(ctx, ArtifactReference{Registry: strings.TrimPrefix(slow.URL, "http://"), Repository: "gl", Reference: "1.0.0"})
+		Expect(err).To(MatchError(ContainSubstring("context deadline exceeded")))
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	})
+
+	It("should parse artifact references", func() {
+		Expect(ParseArtifactReference("ghcr.io/gardenlinux/gardenlinux:934.1")).To(Equal(ArtifactReference{
+			Registry: "ghcr.io", Repository: "gardenlinux/gardenlinux", Reference: "934.1"}))
+		Expect(ParseArtifactReference("localhost:5000/gl@sha256:abc")).To(Equal(ArtifactReference{
+			Registry: "localhost:5000", Repository: "gl", Reference: "sha256:abc"}))
+		Expect(ArtifactReference{Registry: "localhost:5000", Repository: "gl", Reference: "sha256:abc"}.String()).To(Equal("localhost:5000/gl@sha256:abc"))
+
+		for _, reference := range []string{"gardenlinux/gardenlinux:934.1", "ghcr.io/gardenlinux", "ghcr.io/:934.1"} {
+			_, err := ParseArtifactReference(reference)
+			Expect(err).To(HaveOccurred(), reference)
+		}
+	})
+})
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// PayloadType is the payload type of envelopes with in-toto statements.
+const PayloadType = "application/vnd.in-toto+json"
+
+// Envelope is a DSSE envelope which carries a signed statement.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a signature of an envelope.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Sign encodes the statement and signs it with all signers.
+func Sign(ctx context.Context, statement *Statement, signers ...Signer) (*Envelope, error) {
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("at least one signer must be provided")
+	}
+
+	payload, err := json.Marshal(statement)
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []Signature{},
+	}
+
+	message := pae(PayloadType, payload)
+	for _, signer := range signers {
+		sig, err := signer.Sign(ctx, message)
+		if err != nil {
+			return nil, fmt.Errorf("unable to sign with key %s: %w", signer.KeyID(), err)
+		}
+		envelope.Signatures = append(envelope.Signatures, Signature{
+			KeyID: signer.KeyID(),
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		})
+	}
+
+	return envelope, nil
+}
+
+// ParseEnvelope parses a json encoded envelope like Sign returns it.
+func ParseEnvelope(data []byte) (*Envelope, error) {
+	envelope := &Envelope{}
+	if err := json.Unmarshal(data, envelope); err != nil {
+		return nil, err
+	}
+	return envelope, nil
+}
+
+// pae returns the pre-authentication encoding of DSSE, which is the message that is actually signed.
+func pae(payloadType string, payload []byte) []byte {
+	return []byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("dsse", func() {
+
+	It("should sign the pre-authentication encoding of the statement", func() {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		signer, err := NewLocalSigner(encodePKCS8(private))
+		Expect(err).NotTo(HaveOccurred())
+
+		statement, err := NewStatement(nil, []Artifact{{Name: "exports.yaml", Content: []byte("exports")}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		envelope, err := Sign(context.Background(), statement, signer)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envelope.PayloadType).To(Equal(PayloadType))
+		Expect(envelope.Signatures).To(HaveLen(1))
+		Expect(envelope.Signatures[0].KeyID).To(Equal(signer.KeyID()))
+
+		payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+		Expect(err).NotTo(HaveOccurred())
+		decoded := &Statement{}
+		Expect(json.Unmarshal(payload, decoded)).To(Succeed())
+		Expect(decoded).To(Equal(statement))
+
+		sig, err := base64.StdEncoding.DecodeString(envelope.Signatures[0].Sig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ed25519.Verify(public, pae(PayloadType, payload), sig)).To(BeTrue())
+	})
+
+	It("should encode according to the DSSE specification", func() {
+		Expect(string(pae("http://example.com/HelloWorld", []byte("hello world")))).To(
+			Equal("DSSEv1 29 http://example.com/HelloWorld 11 hello world"))
+	})
+
+	It("should require a signer", func() {
+		statement, err := NewStatement(nil, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = Sign(context.Background(), statement)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// newRequest creates a request with a json body. The network policy guard of the context is checked first.
+func newRequest(ctx context.Context, method, url string, headers map[string]string, body interface{}) (*http.Request, []byte, error) {
+	if err := mi.CheckNetworkAccess(ctx, "sign attestation", url); err != nil {
+		return nil, nil, err
+	}
+	if err := mi.InjectFault(ctx, mi.FaultPointFetch, url); err != nil {
+		return nil, nil, err
+	}
+
+	var data []byte
+	var reader io.Reader
+	if body != nil {
+		var err error
+		data, err = json.Marshal(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return req, data, nil
+}
+
+// do sends the request and decodes the json response into result.
+func do(client *http.Client, req *http.Request, result interface{}) error {
+	if client == nil {
+		client = mi.NewHTTPClient(nil)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %d of %s: %s", resp.StatusCode, req.URL.Host, bytes.TrimSpace(message))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// doJSON sends a request with a json body and decodes the json response into result.
+func doJSON(ctx context.Context, client *http.Client, method, url string, headers map[string]string, body, result interface{}) error {
+	req, _, err := newRequest(ctx, method, url, headers, body)
+	if err != nil {
+		return err
+	}
+	return do(client, req, result)
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Signer signs messages with a key.
+type Signer interface {
+	// KeyID identifies the key, so that verifiers can select the public key.
+	KeyID() string
+	// Sign returns the signature of the message. Implementations hash the message as required by their algorithm.
+	Sign(ctx context.Context, message []byte) ([]byte, error)
+}
+
+// LocalSigner signs with a private key which is available in memory.
+type LocalSigner struct {
+	key   crypto.Signer
+	keyID string
+}
+
+// NewLocalSigner parses a pem encoded ecdsa, rsa or ed25519 private key. ECDSA and RSA keys sign the sha256 digest
+// of messages.
+func NewLocalSigner(pemData []byte) (*LocalSigner, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no pem encoded private key found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	keyID, err := KeyIDOf(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalSigner{key: signer, keyID: keyID}, nil
+}
+
+// KeyID returns the id of the public key of the signer.
+func (s *LocalSigner) KeyID() string {
+	return s.keyID
+}
+
+// Sign signs the message.
+func (s *LocalSigner) Sign(_ context.Context, message []byte) ([]byte, error) {
+	switch s.key.(type) {
+	case ed25519.PrivateKey:
+		return s.key.Sign(rand.Reader, message, crypto.Hash(0))
+	case *ecdsa.PrivateKey, *rsa.PrivateKey:
+		digest := sha256.Sum256(message)
+		return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", s.key)
+	}
+}
+
+// KeyIDOf returns the hex encoded sha256 digest of the PKIX encoding of the public key.
+func KeyIDOf(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
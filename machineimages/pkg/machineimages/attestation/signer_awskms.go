@@ -0,0 +1,124 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// AWSKMSSignerConfig configures a signer which uses an asymmetric key of AWS KMS.
+type AWSKMSSignerConfig struct {
+	// KeyID is the id, arn or alias of the key.
+	KeyID string
+	// Region of the key.
+	Region string
+	// Credentials authenticate the requests.
+	Credentials AWSCredentials
+	// Endpoint overrides the KMS endpoint of the region. Optional.
+	Endpoint string
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+}
+
+// AWSKMSSigner signs with an asymmetric ECC_NIST_P256 or RSA key of AWS KMS.
+type AWSKMSSigner struct {
+	config    AWSKMSSignerConfig
+	algorithm string
+	publicKey crypto.PublicKey
+	keyID     string
+}
+
+// NewAWSKMSSigner reads the public key of the KMS key, so that the key id matches the id of its verifier.
+func NewAWSKMSSigner(ctx context.Context, config AWSKMSSignerConfig) (*AWSKMSSigner, error) {
+	s := &AWSKMSSigner{config: config}
+
+	response := struct {
+		PublicKey string `json:"PublicKey"`
+	}{}
+	if err := s.call(ctx, "GetPublicKey", map[string]string{"KeyId": config.KeyID}, &response); err != nil {
+		return nil, fmt.Errorf("unable to read public key of kms key %s: %w", config.KeyID, err)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(response.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key of kms key %s: %w", config.KeyID, err)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key of kms key %s: %w", config.KeyID, err)
+	}
+
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey:
+		s.algorithm = "ECDSA_SHA_256"
+	case *rsa.PublicKey:
+		s.algorithm = "RSASSA_PKCS1_V1_5_SHA_256"
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T of kms key %s", publicKey, config.KeyID)
+	}
+
+	s.publicKey = publicKey
+	s.keyID, err = KeyIDOf(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// KeyID returns the id of the public key of the KMS key.
+func (s *AWSKMSSigner) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the public key of the KMS key.
+func (s *AWSKMSSigner) PublicKey() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign signs the sha256 digest of the message with the KMS key.
+func (s *AWSKMSSigner) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+
+	response := struct {
+		Signature string `json:"Signature"`
+	}{}
+	err := s.call(ctx, "Sign", map[string]string{
+		"KeyId":            s.config.KeyID,
+		"Message":          base64.StdEncoding.EncodeToString(digest[:]),
+		"MessageType":      "DIGEST",
+		"SigningAlgorithm": s.algorithm,
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(response.Signature)
+}
+
+func (s *AWSKMSSigner) call(ctx context.Context, operation string, body, result interface{}) error {
+	endpoint := s.config.Endpoint
+	if len(endpoint) == 0 {
+		endpoint = "https://kms." + s.config.Region + ".amazonaws.com/"
+	}
+
+	req, data, err := newRequest(ctx, http.MethodPost, endpoint, map[string]string{
+		"Content-Type": "application/x-amz-json-1.1",
+		"X-Amz-Target": "TrentService." + operation,
+	}, body)
+	if err != nil {
+		return err
+	}
+	signV4(req, data, s.config.Credentials, s.config.Region, "kms", time.Now())
+
+	return do(s.config.Client, req, result)
+}
@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultGCPKMSEndpoint is the endpoint of the Google Cloud KMS api.
+	DefaultGCPKMSEndpoint = "https://cloudkms.googleapis.com"
+
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GCPKMSSignerConfig configures a signer which uses an asymmetric key version of Google Cloud KMS.
+type GCPKMSSignerConfig struct {
+	// Name is the resource name of the key version,
+	// e.g. projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>/cryptoKeyVersions/<version>.
+	Name string
+	// TokenSource returns the oauth2 access token of the requests.
+	// Defaults to the token of the default service account from the metadata server.
+	TokenSource func(ctx context.Context) (string, error)
+	// Endpoint overrides DefaultGCPKMSEndpoint. Optional.
+	Endpoint string
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+}
+
+// GCPKMSSigner signs with an asymmetric EC_SIGN_*_SHA256 or RSA_SIGN_PKCS1_*_SHA256 key version of Google Cloud KMS.
+type GCPKMSSigner struct {
+	config    GCPKMSSignerConfig
+	publicKey crypto.PublicKey
+	keyID     string
+}
+
+// NewGCPKMSSigner reads the public key of the key version, so that the key id matches the id of its verifier.
+func NewGCPKMSSigner(ctx context.Context, config GCPKMSSignerConfig) (*GCPKMSSigner, error) {
+	if len(config.Endpoint) == 0 {
+		config.Endpoint = DefaultGCPKMSEndpoint
+	}
+	if config.TokenSource == nil {
+		config.TokenSource = metadataTokenSource(config.Client)
+	}
+	s := &GCPKMSSigner{config: config}
+
+	response := struct {
+		Pem string `json:"pem"`
+	}{}
+	if err := s.call(ctx, http.MethodGet, "/publicKey", nil, &response); err != nil {
+		return nil, fmt.Errorf("unable to read public key of kms key %s: %w", config.Name, err)
+	}
+
+	block, _ := pem.Decode([]byte(response.Pem))
+	if block == nil {
+		return nil, fmt.Errorf("invalid public key of kms key %s", config.Name)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key of kms key %s: %w", config.Name, err)
+	}
+
+	s.publicKey = publicKey
+	s.keyID, err = KeyIDOf(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// KeyID returns the id of the public key of the key version.
+func (s *GCPKMSSigner) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the public key of the key version.
+func (s *GCPKMSSigner) PublicKey() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign signs the sha256 digest of the message with the key version.
+func (s *GCPKMSSigner) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	request := map[string]interface{}{
+		"digest": map[string]string{
+			"sha256": base64.StdEncoding.EncodeToString(digest[:]),
+		},
+	}
+
+	response := struct {
+		Signature string `json:"signature"`
+	}{}
+	if err := s.call(ctx, http.MethodPost, ":asymmetricSign", request, &response); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(response.Signature)
+}
+
+func (s *GCPKMSSigner) call(ctx context.Context, method, operation string, body, result interface{}) error {
+	token, err := s.config.TokenSource(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get access token: %w", err)
+	}
+
+	url := strings.TrimSuffix(s.config.Endpoint, "/") + "/v1/" + s.config.Name + operation
+	return doJSON(ctx, s.config.Client, method, url, map[string]string{"Authorization": "Bearer " + token}, body, result)
+}
+
+// metadataTokenSource returns the access token of the default service account from the gce metadata server.
+func metadataTokenSource(client *http.Client) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		response := struct {
+			AccessToken string `json:"access_token"`
+		}{}
+		err := doJSON(ctx, client, http.MethodGet, gcpMetadataTokenURL, map[string]string{"Metadata-Flavor": "Google"}, nil, &response)
+		return response.AccessToken, err
+	}
+}
@@ -0,0 +1,257 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func encodePublicKey(key *ecdsa.PrivateKey) []byte {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func signDigest(key *ecdsa.PrivateKey, digest []byte) string {
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest)
+	Expect(err).NotTo(HaveOccurred())
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// expectVerifiable checks that the signer signs messages which the verifier of the public key accepts.
+func expectVerifiable(signer Signer, key *ecdsa.PrivateKey) {
+	verifier, err := NewPublicKeyVerifier(encodePublicKey(key))
+	Expect(err).NotTo(HaveOccurred())
+	Expect(signer.KeyID()).To(Equal(verifier.KeyID()))
+
+	message := []byte("message")
+	sig, err := signer.Sign(context.Background(), message)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(verifier.Verify(message, sig)).To(Succeed())
+}
+
+var _ = Describe("kms signers", func() {
+
+	var key *ecdsa.PrivateKey
+
+	BeforeEach(func() {
+		var err error
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("vault", func() {
+
+		newServer := func() *httptest.Server {
+			return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("X-Vault-Token")).To(Equal("token"))
+
+				switch r.URL.Path {
+				case "/v1/signing/keys/attestation":
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{
+						"data": map[string]interface{}{
+							"type":           "ecdsa-p256",
+							"latest_version": 2,
+							"keys": map[string]interface{}{
+								"2": map[string]string{"public_key": string(encodePublicKey(key))},
+							},
+						},
+					})
+				case "/v1/signing/sign/attestation/sha2-256":
+					request := struct {
+						Input string `json:"input"`
+					}{}
+					Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+					input, err := base64.StdEncoding.DecodeString(request.Input)
+					Expect(err).NotTo(HaveOccurred())
+					digest := sha256.Sum256(input)
+					_ = json.NewEncoder(w).Encode(map[string]interface{}{
+						"data": map[string]string{"signature": "vault:v2:" + signDigest(key, digest[:])},
+					})
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+		}
+
+		It("should sign with a transit key", func() {
+			server := newServer()
+			defer server.Close()
+
+			signer, err := NewVaultSigner(context.Background(), VaultSignerConfig{
+				Address: server.URL,
+				Token:   "token",
+				Mount:   "signing",
+				Key:     "attestation",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			expectVerifiable(signer, key)
+		})
+
+		It("should be created from a vault reference", func() {
+			server := newServer()
+			defer server.Close()
+
+			os.Setenv("VAULT_ADDR", server.URL)
+			os.Setenv("VAULT_TOKEN", "token")
+			defer os.Unsetenv("VAULT_ADDR")
+			defer os.Unsetenv("VAULT_TOKEN")
+
+			signer, err := NewSigner(context.Background(), "vault://signing/attestation")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signer).To(BeAssignableToTypeOf(&VaultSigner{}))
+			expectVerifiable(signer, key)
+		})
+
+		It("should fail if the key does not exist", func() {
+			server := newServer()
+			defer server.Close()
+
+			_, err := NewVaultSigner(context.Background(), VaultSignerConfig{Address: server.URL, Token: "token", Key: "other"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("404"))
+		})
+	})
+
+	Context("aws kms", func() {
+
+		It("should sign with an asymmetric key", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("Authorization")).To(HavePrefix("AWS4-HMAC-SHA256 Credential=access/"))
+				Expect(r.Header.Get("Authorization")).To(ContainSubstring("/eu-west-1/kms/aws4_request"))
+
+				request := map[string]string{}
+				Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+				Expect(request["KeyId"]).To(Equal("alias/attestation"))
+
+				switch r.Header.Get("X-Amz-Target") {
+				case "TrentService.GetPublicKey":
+					der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+					Expect(err).NotTo(HaveOccurred())
+					_ = json.NewEncoder(w).Encode(map[string]string{"PublicKey": base64.StdEncoding.EncodeToString(der)})
+				case "TrentService.Sign":
+					Expect(request["MessageType"]).To(Equal("DIGEST"))
+					Expect(request["SigningAlgorithm"]).To(Equal("ECDSA_SHA_256"))
+					digest, err := base64.StdEncoding.DecodeString(request["Message"])
+					Expect(err).NotTo(HaveOccurred())
+					_ = json.NewEncoder(w).Encode(map[string]string{"Signature": signDigest(key, digest)})
+				default:
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			}))
+			defer server.Close()
+
+			signer, err := NewAWSKMSSigner(context.Background(), AWSKMSSignerConfig{
+				KeyID:       "alias/attestation",
+				Region:      "eu-west-1",
+				Credentials: AWSCredentials{AccessKeyID: "access", SecretAccessKey: "secret"},
+				Endpoint:    server.URL,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			expectVerifiable(signer, key)
+		})
+
+		It("should compute the signature version 4 of the aws example request", func() {
+			req, err := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+			Expect(err).NotTo(HaveOccurred())
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+			signV4(req, nil, AWSCredentials{
+				AccessKeyID:     "AKIDEXAMPLE",
+				SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			}, "us-east-1", "iam", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+			Expect(req.Header.Get("Authorization")).To(Equal("AWS4-HMAC-SHA256 " +
+				"Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+				"SignedHeaders=content-type;host;x-amz-date, " +
+				"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"))
+		})
+	})
+
+	Context("gcp kms", func() {
+
+		name := "projects/p/locations/europe-west1/keyRings/r/cryptoKeys/attestation/cryptoKeyVersions/1"
+
+		It("should sign with an asymmetric key version", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer token"))
+
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/v1/"+name+"/publicKey":
+					_ = json.NewEncoder(w).Encode(map[string]string{"pem": string(encodePublicKey(key))})
+				case r.Method == http.MethodPost && r.URL.Path == "/v1/"+name+":asymmetricSign":
+					request := struct {
+						Digest struct {
+							SHA256 string `json:"sha256"`
+						} `json:"digest"`
+					}{}
+					Expect(json.NewDecoder(r.Body).Decode(&request)).To(Succeed())
+					digest, err := base64.StdEncoding.DecodeString(request.Digest.SHA256)
+					Expect(err).NotTo(HaveOccurred())
+					_ = json.NewEncoder(w).Encode(map[string]string{"signature": signDigest(key, digest)})
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			signer, err := NewGCPKMSSigner(context.Background(), GCPKMSSignerConfig{
+				Name:        name,
+				TokenSource: func(context.Context) (string, error) { return "token", nil },
+				Endpoint:    server.URL,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			expectVerifiable(signer, key)
+		})
+
+		It("should reject references which are no key versions", func() {
+			_, err := NewSigner(context.Background(), "gcpkms://projects/p/locations/l/keyRings/r/cryptoKeys/k")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("key version"))
+		})
+	})
+
+	It("should not access the network if the network policy guard is enabled", func() {
+		ctx := mi.WithNetworkPolicyGuard(context.Background())
+		_, err := NewVaultSigner(ctx, VaultSignerConfig{Address: "https://vault.example.com", Key: "attestation"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("sign attestation"))
+	})
+
+	It("should create local signers from file references", func() {
+		dir, err := ioutil.TempDir("", "signer")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		file := filepath.Join(dir, "key.pem")
+		Expect(ioutil.WriteFile(file, encodePKCS8(key), 0600)).To(Succeed())
+
+		for _, ref := range []string{file, SchemeFile + file} {
+			signer, err := NewSigner(context.Background(), ref)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signer).To(BeAssignableToTypeOf(&LocalSigner{}))
+			expectVerifiable(signer, key)
+		}
+	})
+})
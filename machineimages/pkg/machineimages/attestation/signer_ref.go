@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Signer references which select a backend by their scheme.
+const (
+	SchemeFile   = "file://"
+	SchemeVault  = "vault://"
+	SchemeAWSKMS = "awskms://"
+	SchemeGCPKMS = "gcpkms://"
+)
+
+// NewSigner returns the signer of a key reference. The reference is one of
+//   - a path to a pem encoded private key, optionally prefixed with file://
+//   - vault://[<mount>/]<key> for a key of the vault transit secrets engine. The address, token and namespace are read
+//     from VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE.
+//   - awskms://<key id, arn or alias> for an AWS KMS key. The region and credentials are read from AWS_REGION,
+//     AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+//   - gcpkms://projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>/cryptoKeyVersions/<version> for
+//     a Google Cloud KMS key version. The access token is read from GOOGLE_OAUTH_ACCESS_TOKEN or the metadata server.
+func NewSigner(ctx context.Context, ref string) (Signer, error) {
+	switch {
+	case strings.HasPrefix(ref, SchemeVault):
+		key := strings.TrimPrefix(ref, SchemeVault)
+		config := VaultSignerConfig{
+			Address:   os.Getenv("VAULT_ADDR"),
+			Token:     os.Getenv("VAULT_TOKEN"),
+			Namespace: os.Getenv("VAULT_NAMESPACE"),
+			Key:       key,
+		}
+		if i := strings.LastIndex(key, "/"); i >= 0 {
+			config.Mount, config.Key = key[:i], key[i+1:]
+		}
+		if len(config.Key) == 0 || len(config.Address) == 0 {
+			return nil, fmt.Errorf("vault signer %q requires a key and VAULT_ADDR", ref)
+		}
+		return NewVaultSigner(ctx, config)
+
+	case strings.HasPrefix(ref, SchemeAWSKMS):
+		config := AWSKMSSignerConfig{
+			KeyID:  strings.TrimPrefix(ref, SchemeAWSKMS),
+			Region: os.Getenv("AWS_REGION"),
+			Credentials: AWSCredentials{
+				AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+				SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			},
+		}
+		if len(config.KeyID) == 0 || len(config.Region) == 0 {
+			return nil, fmt.Errorf("aws kms signer %q requires a key id and AWS_REGION", ref)
+		}
+		return NewAWSKMSSigner(ctx, config)
+
+	case strings.HasPrefix(ref, SchemeGCPKMS):
+		config := GCPKMSSignerConfig{
+			Name: strings.TrimPrefix(ref, SchemeGCPKMS),
+		}
+		if !strings.Contains(config.Name, "/cryptoKeyVersions/") {
+			return nil, fmt.Errorf("gcp kms signer %q requires the resource name of a key version", ref)
+		}
+		if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); len(token) > 0 {
+			config.TokenSource = func(context.Context) (string, error) { return token, nil }
+		}
+		return NewGCPKMSSigner(ctx, config)
+	}
+
+	data, err := ioutil.ReadFile(strings.TrimPrefix(ref, SchemeFile))
+	if err != nil {
+		return nil, err
+	}
+	return NewLocalSigner(data)
+}
@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// encodePKCS8 returns the pem encoding of a private key.
+func encodePKCS8(key interface{}) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+var _ = Describe("signer", func() {
+
+	message := []byte("message")
+	digest := sha256.Sum256(message)
+
+	It("should sign with ecdsa keys", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+
+		signer, err := NewLocalSigner(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+		Expect(err).NotTo(HaveOccurred())
+		sig, err := signer.Sign(context.Background(), message)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ecdsa.VerifyASN1(&key.PublicKey, digest[:], sig)).To(BeTrue())
+
+		keyID, err := KeyIDOf(&key.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signer.KeyID()).To(Equal(keyID))
+	})
+
+	It("should sign with rsa keys", func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+
+		signer, err := NewLocalSigner(encodePKCS8(key))
+		Expect(err).NotTo(HaveOccurred())
+		sig, err := signer.Sign(context.Background(), message)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig)).To(Succeed())
+	})
+
+	It("should sign with ed25519 keys", func() {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+
+		signer, err := NewLocalSigner(encodePKCS8(private))
+		Expect(err).NotTo(HaveOccurred())
+		sig, err := signer.Sign(context.Background(), message)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ed25519.Verify(public, message, sig)).To(BeTrue())
+	})
+
+	It("should reject invalid keys", func() {
+		_, err := NewLocalSigner([]byte("no key"))
+		Expect(err).To(HaveOccurred())
+	})
+})
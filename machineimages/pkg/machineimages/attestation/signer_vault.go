@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// VaultSignerConfig configures a signer which uses a key of the transit secrets engine of HashiCorp Vault.
+type VaultSignerConfig struct {
+	// Address of the vault server, e.g. https://vault.example.com:8200.
+	Address string
+	// Token authenticates the requests.
+	Token string
+	// Namespace is the vault enterprise namespace. Optional.
+	Namespace string
+	// Mount is the path of the transit secrets engine. Defaults to "transit".
+	Mount string
+	// Key is the name of the transit key.
+	Key string
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+}
+
+// VaultSigner signs with a key of the vault transit secrets engine.
+type VaultSigner struct {
+	config    VaultSignerConfig
+	rsa       bool
+	publicKey crypto.PublicKey
+	keyID     string
+}
+
+// NewVaultSigner reads the latest public key of the transit key, so that the key id matches the id of its verifier.
+func NewVaultSigner(ctx context.Context, config VaultSignerConfig) (*VaultSigner, error) {
+	if len(config.Mount) == 0 {
+		config.Mount = "transit"
+	}
+
+	key := struct {
+		Data struct {
+			Type          string `json:"type"`
+			LatestVersion int    `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}{}
+	if err := doJSON(ctx, config.Client, http.MethodGet, config.url("keys"), config.headers(), nil, &key); err != nil {
+		return nil, fmt.Errorf("unable to read vault transit key %s: %w", config.Key, err)
+	}
+
+	version, ok := key.Data.Keys[strconv.Itoa(key.Data.LatestVersion)]
+	if !ok {
+		return nil, fmt.Errorf("vault transit key %s has no public key", config.Key)
+	}
+
+	var publicKey crypto.PublicKey
+	if key.Data.Type == "ed25519" {
+		raw, err := base64.StdEncoding.DecodeString(version.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key of vault transit key %s: %w", config.Key, err)
+		}
+		publicKey = ed25519.PublicKey(raw)
+	} else {
+		block, _ := pem.Decode([]byte(version.PublicKey))
+		if block == nil {
+			return nil, fmt.Errorf("invalid public key of vault transit key %s", config.Key)
+		}
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key of vault transit key %s: %w", config.Key, err)
+		}
+		publicKey = parsed
+	}
+
+	keyID, err := KeyIDOf(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	_, isRSA := publicKey.(*rsa.PublicKey)
+	return &VaultSigner{config: config, rsa: isRSA, publicKey: publicKey, keyID: keyID}, nil
+}
+
+// KeyID returns the id of the public key of the transit key.
+func (s *VaultSigner) KeyID() string {
+	return s.keyID
+}
+
+// PublicKey returns the public key of the transit key.
+func (s *VaultSigner) PublicKey() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign signs the message with the transit key. Signatures have the same format as signatures of a LocalSigner.
+func (s *VaultSigner) Sign(ctx context.Context, message []byte) ([]byte, error) {
+	request := map[string]interface{}{
+		"input":                base64.StdEncoding.EncodeToString(message),
+		"marshaling_algorithm": "asn1",
+	}
+	if s.rsa {
+		request["signature_algorithm"] = "pkcs1v15"
+	}
+
+	response := struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}{}
+	if err := doJSON(ctx, s.config.Client, http.MethodPost, s.config.url("sign")+"/sha2-256", s.config.headers(), request, &response); err != nil {
+		return nil, err
+	}
+
+	// signatures have the format vault:v<version>:<base64 signature>
+	parts := strings.SplitN(response.Data.Signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, fmt.Errorf("unexpected vault signature format")
+	}
+	return base64.StdEncoding.DecodeString(parts[2])
+}
+
+func (c *VaultSignerConfig) url(operation string) string {
+	return strings.TrimSuffix(c.Address, "/") + "/v1/" + strings.Trim(c.Mount, "/") + "/" + operation + "/" + c.Key
+}
+
+func (c *VaultSignerConfig) headers() map[string]string {
+	headers := map[string]string{"X-Vault-Token": c.Token}
+	if len(c.Namespace) > 0 {
+		headers["X-Vault-Namespace"] = c.Namespace
+	}
+	return headers
+}
@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// StatementType is the type of in-toto statements.
+	StatementType = "https://in-toto.io/Statement/v0.1"
+	// PredicateTypeSLSAProvenance is the predicate type of SLSA provenance.
+	PredicateTypeSLSAProvenance = "https://slsa.dev/provenance/v0.2"
+	// BuildType identifies the compute step of this tool in provenance predicates.
+	BuildType = "https://github.com/gardener/landscaper-utils/machineimages/compute@v1"
+	// DefaultBuilderID identifies this tool as builder if no builder id is configured.
+	DefaultBuilderID = "https://github.com/gardener/landscaper-utils/machineimages"
+)
+
+// DigestSet maps digest algorithms to hex encoded digests.
+type DigestSet map[string]string
+
+// Subject is an artifact produced by the attested step.
+type Subject struct {
+	Name   string    `json:"name"`
+	Digest DigestSet `json:"digest"`
+}
+
+// Material is an artifact consumed by the attested step.
+type Material struct {
+	URI    string    `json:"uri"`
+	Digest DigestSet `json:"digest"`
+}
+
+// Statement is an in-toto statement with a SLSA provenance predicate.
+type Statement struct {
+	Type          string     `json:"_type"`
+	PredicateType string     `json:"predicateType"`
+	Subject       []Subject  `json:"subject"`
+	Predicate     Provenance `json:"predicate"`
+}
+
+// Provenance is a SLSA provenance predicate.
+type Provenance struct {
+	Builder    Builder    `json:"builder"`
+	BuildType  string     `json:"buildType"`
+	Invocation Invocation `json:"invocation"`
+	Metadata   Metadata   `json:"metadata"`
+	Materials  []Material `json:"materials"`
+}
+
+// Builder identifies the entity which executed the step.
+type Builder struct {
+	ID string `json:"id"`
+}
+
+// Invocation describes how the step was invoked.
+type Invocation struct {
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// Metadata contains the time of the step.
+type Metadata struct {
+	BuildStartedOn  *time.Time `json:"buildStartedOn,omitempty"`
+	BuildFinishedOn *time.Time `json:"buildFinishedOn,omitempty"`
+}
+
+// Artifact is the content of an input or output of the compute step.
+type Artifact struct {
+	// Name is the uri of a material or the name of a subject.
+	Name    string
+	Content []byte
+}
+
+// StatementOptions configures a statement.
+type StatementOptions struct {
+	// BuilderID identifies the builder. Defaults to DefaultBuilderID.
+	BuilderID string
+	// Parameters are recorded in the invocation, e.g. the flags of the compute step.
+	Parameters map[string]string
+	// StartedOn is the time the step was started.
+	StartedOn *time.Time
+	// FinishedOn is the time the step was finished.
+	FinishedOn *time.Time
+}
+
+// NewStatement returns a provenance statement which binds the digests of the inputs to the digests of the outputs.
+// Subjects and materials are sorted by name, so that the statement does not depend on the order of the artifacts.
+func NewStatement(inputs, outputs []Artifact, options *StatementOptions) (*Statement, error) {
+	if options == nil {
+		options = &StatementOptions{}
+	}
+
+	builderID := options.BuilderID
+	if len(builderID) == 0 {
+		builderID = DefaultBuilderID
+	}
+
+	statement := &Statement{
+		Type:          StatementType,
+		PredicateType: PredicateTypeSLSAProvenance,
+		Subject:       []Subject{},
+		Predicate: Provenance{
+			Builder:    Builder{ID: builderID},
+			BuildType:  BuildType,
+			Invocation: Invocation{Parameters: options.Parameters},
+			Metadata: Metadata{
+				BuildStartedOn:  utc(options.StartedOn),
+				BuildFinishedOn: utc(options.FinishedOn),
+			},
+			Materials: []Material{},
+		},
+	}
+
+	outputs, err := sortedArtifacts(outputs, "subject")
+	if err != nil {
+		return nil, err
+	}
+	inputs, err = sortedArtifacts(inputs, "material")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, output := range outputs {
+		statement.Subject = append(statement.Subject, Subject{Name: output.Name, Digest: Digest(output.Content)})
+	}
+	for _, input := range inputs {
+		statement.Predicate.Materials = append(statement.Predicate.Materials,
+			Material{URI: input.Name, Digest: Digest(input.Content)})
+	}
+
+	return statement, nil
+}
+
+// sortedArtifacts returns a copy of the artifacts sorted by name. Artifacts without a name or with the same name are
+// rejected, as a verifier could not tell which digest belongs to the artifact.
+func sortedArtifacts(artifacts []Artifact, kind string) ([]Artifact, error) {
+	sorted := make([]Artifact, len(artifacts))
+	copy(sorted, artifacts)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	for i, artifact := range sorted {
+		if len(artifact.Name) == 0 {
+			return nil, fmt.Errorf("%s without a name", kind)
+		}
+		if i > 0 && sorted[i-1].Name == artifact.Name {
+			return nil, fmt.Errorf("duplicate %s %s", kind, artifact.Name)
+		}
+	}
+	return sorted, nil
+}
+
+// Digest returns the sha256 digest set of the content.
+func Digest(content []byte) DigestSet {
+	sum := sha256.Sum256(content)
+	return DigestSet{"sha256": hex.EncodeToString(sum[:])}
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC().Truncate(time.Second)
+	return &u
+}
@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("statement", func() {
+
+	It("should bind the input digests to the output digests", func() {
+		started := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+		statement, err := NewStatement(
+			[]Artifact{{Name: "imports.yaml", Content: []byte("imports")}},
+			[]Artifact{{Name: "exports.yaml", Content: []byte("exports")}},
+			&StatementOptions{StartedOn: &started, Parameters: map[string]string{"landscape": "dev"}},
+		)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(statement.Type).To(Equal(StatementType))
+		Expect(statement.PredicateType).To(Equal(PredicateTypeSLSAProvenance))
+		Expect(statement.Subject).To(Equal([]Subject{{
+			Name:   "exports.yaml",
+			Digest: Digest([]byte("exports")),
+		}}))
+		Expect(statement.Predicate.Materials).To(Equal([]Material{{URI: "imports.yaml", Digest: Digest([]byte("imports"))}}))
+		Expect(statement.Predicate.Builder.ID).To(Equal(DefaultBuilderID))
+		Expect(*statement.Predicate.Metadata.BuildStartedOn).To(Equal(started))
+		Expect(statement.Predicate.Metadata.BuildFinishedOn).To(BeNil())
+	})
+
+	It("should not depend on the order of the artifacts", func() {
+		artifacts := []Artifact{{Name: "b.yaml", Content: []byte("b")}, {Name: "a.yaml", Content: []byte("a")}}
+		reversed := []Artifact{artifacts[1], artifacts[0]}
+
+		statement, err := NewStatement(artifacts, artifacts, nil)
+		Expect(err).NotTo(HaveOccurred())
+		other, err := NewStatement(reversed, reversed, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other).To(Equal(statement))
+		Expect(statement.Subject[0].Name).To(Equal("a.yaml"))
+		Expect(statement.Predicate.Materials[0].URI).To(Equal("a.yaml"))
+		// the input order is kept
+		Expect(artifacts[0].Name).To(Equal("b.yaml"))
+	})
+
+	It("should reject artifacts with the same name", func() {
+		_, err := NewStatement(nil, []Artifact{{Name: "exports.yaml"}, {Name: "exports.yaml", Content: []byte("x")}}, nil)
+		Expect(err).To(MatchError("duplicate subject exports.yaml"))
+
+		_, err = NewStatement([]Artifact{{Name: "imports.yaml"}, {Name: "imports.yaml"}}, nil, nil)
+		Expect(err).To(MatchError("duplicate material imports.yaml"))
+	})
+
+	It("should reject artifacts without a name", func() {
+		_, err := NewStatement([]Artifact{{Content: []byte("imports")}}, nil, nil)
+		Expect(err).To(MatchError("material without a name"))
+	})
+
+	It("should compute sha256 digests", func() {
+		Expect(Digest([]byte("abc"))).To(Equal(DigestSet{
+			"sha256": "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		}))
+	})
+})
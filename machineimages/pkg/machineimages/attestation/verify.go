@@ -0,0 +1,235 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Verifier verifies signatures of one key.
+type Verifier interface {
+	// KeyID identifies the key like the KeyID of the corresponding Signer.
+	KeyID() string
+	// Verify returns an error if the signature of the message is invalid.
+	Verify(message, signature []byte) error
+}
+
+// PublicKeyVerifier verifies signatures with a public key.
+type PublicKeyVerifier struct {
+	key   crypto.PublicKey
+	keyID string
+}
+
+// NewPublicKeyVerifier parses a pem encoded PKIX ecdsa, rsa or ed25519 public key.
+func NewPublicKeyVerifier(pemData []byte) (*PublicKeyVerifier, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no pem encoded public key found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse public key: %w", err)
+	}
+
+	keyID, err := KeyIDOf(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicKeyVerifier{key: key, keyID: keyID}, nil
+}
+
+// KeyID returns the id of the public key.
+func (v *PublicKeyVerifier) KeyID() string {
+	return v.keyID
+}
+
+// Verify verifies the signature of the message like LocalSigner creates it.
+func (v *PublicKeyVerifier) Verify(message, signature []byte) error {
+	digest := sha256.Sum256(message)
+
+	switch key := v.key.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, message, signature) {
+			return errors.New("invalid signature")
+		}
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, digest[:], signature) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid signature")
+		}
+	default:
+		return fmt.Errorf("unsupported public key type %T", v.key)
+	}
+	return nil
+}
+
+// MaxClockSkew is how far the finish time of an attestation may be in the future, to allow for clocks of the builder
+// and the verifier which are not exactly in sync.
+const MaxClockSkew = 5 * time.Minute
+
+// VerificationPolicy states which attestations are accepted.
+type VerificationPolicy struct {
+	// Verifiers are the trusted keys. At least one signature of a trusted key must be valid.
+	Verifiers []Verifier
+	// RequiredKeyIDs are the ids of trusted keys which all must have signed the attestation.
+	RequiredKeyIDs []string
+	// BuilderID is the required builder. Optional.
+	BuilderID string
+	// MaxAge is the maximum time since the attested step finished. Optional. Attestations which finished more than
+	// MaxClockSkew in the future are always rejected.
+	MaxAge time.Duration
+	// ApprovedMaterials are the sha256 digests of approved inputs. If not empty, all materials must be approved.
+	ApprovedMaterials []string
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// VerificationError is returned if an attestation does not satisfy the verification policy.
+type VerificationError struct {
+	Reason string
+}
+
+func (e *VerificationError) Error() string {
+	return "attestation verification failed: " + e.Reason
+}
+
+// IsVerificationError returns whether the error or one of the errors it wraps is a VerificationError.
+func IsVerificationError(err error) bool {
+	var verificationErr *VerificationError
+	return errors.As(err, &verificationErr)
+}
+
+func verificationErrorf(format string, args ...interface{}) error {
+	return &VerificationError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Verify checks that the envelope is signed according to the policy and attests the artifact. It returns the verified
+// statement.
+func Verify(envelope *Envelope, artifact []byte, policy *VerificationPolicy) (*Statement, error) {
+	if policy == nil || len(policy.Verifiers) == 0 {
+		return nil, errors.New("at least one trusted key must be configured")
+	}
+
+	if envelope.PayloadType != PayloadType {
+		return nil, verificationErrorf("unexpected payload type %q", envelope.PayloadType)
+	}
+	payload, err := base64.StdEncoding.DecodeString(envelope.Payload)
+	if err != nil {
+		return nil, verificationErrorf("invalid payload encoding")
+	}
+
+	if err := verifySignatures(envelope, pae(envelope.PayloadType, payload), policy); err != nil {
+		return nil, err
+	}
+
+	statement := &Statement{}
+	if err := json.Unmarshal(payload, statement); err != nil {
+		return nil, verificationErrorf("invalid statement: %s", err)
+	}
+	if statement.Type != StatementType || statement.PredicateType != PredicateTypeSLSAProvenance {
+		return nil, verificationErrorf("unexpected statement type %s with predicate %s", statement.Type, statement.PredicateType)
+	}
+	if len(policy.BuilderID) > 0 && statement.Predicate.Builder.ID != policy.BuilderID {
+		return nil, verificationErrorf("unexpected builder %s", statement.Predicate.Builder.ID)
+	}
+
+	digest := Digest(artifact)["sha256"]
+	if !hasSubject(statement, digest) {
+		return nil, verificationErrorf("artifact with digest sha256:%s is not attested", digest)
+	}
+
+	now := time.Now
+	if policy.Now != nil {
+		now = policy.Now
+	}
+	finished := statement.Predicate.Metadata.BuildFinishedOn
+	if finished == nil && policy.MaxAge > 0 {
+		return nil, verificationErrorf("attestation has no finish time")
+	}
+	if finished != nil {
+		age := now().Sub(*finished)
+		if age < -MaxClockSkew {
+			return nil, verificationErrorf("attestation finished %s in the future, at most %s are allowed", (-age).Round(time.Second), MaxClockSkew)
+		}
+		if policy.MaxAge > 0 && age > policy.MaxAge {
+			return nil, verificationErrorf("attestation is %s old, at most %s are allowed", age.Round(time.Second), policy.MaxAge)
+		}
+	}
+
+	if len(policy.ApprovedMaterials) > 0 {
+		for _, material := range statement.Predicate.Materials {
+			if !containsString(policy.ApprovedMaterials, material.Digest["sha256"]) {
+				return nil, verificationErrorf("material %s with digest sha256:%s is not approved", material.URI, material.Digest["sha256"])
+			}
+		}
+	}
+
+	return statement, nil
+}
+
+func verifySignatures(envelope *Envelope, message []byte, policy *VerificationPolicy) error {
+	verified := []string{}
+	for _, signature := range envelope.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(signature.Sig)
+		if err != nil {
+			continue
+		}
+		for _, verifier := range policy.Verifiers {
+			if verifier.KeyID() == signature.KeyID && verifier.Verify(message, sig) == nil {
+				verified = append(verified, signature.KeyID)
+			}
+		}
+	}
+
+	if len(verified) == 0 {
+		return verificationErrorf("no valid signature of a trusted key")
+	}
+
+	missing := []string{}
+	for _, keyID := range policy.RequiredKeyIDs {
+		if !containsString(verified, keyID) {
+			missing = append(missing, keyID)
+		}
+	}
+	if len(missing) > 0 {
+		return verificationErrorf("no valid signature of the required keys %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func hasSubject(statement *Statement, digest string) bool {
+	for _, subject := range statement.Subject {
+		if subject.Digest["sha256"] == digest {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package attestation
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("verify", func() {
+
+	var (
+		signer, otherSigner     Signer
+		verifier, otherVerifier Verifier
+		finished                time.Time
+	)
+
+	newKeyPair := func() (Signer, Verifier) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		s, err := NewLocalSigner(encodePKCS8(key))
+		Expect(err).NotTo(HaveOccurred())
+
+		der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		v, err := NewPublicKeyVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		Expect(err).NotTo(HaveOccurred())
+		return s, v
+	}
+
+	BeforeEach(func() {
+		signer, verifier = newKeyPair()
+		otherSigner, otherVerifier = newKeyPair()
+		finished = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	})
+
+	envelope := func(signers ...Signer) *Envelope {
+		statement, err := NewStatement(
+			[]Artifact{{Name: "imports.yaml", Content: []byte("imports")}},
+			[]Artifact{{Name: "exports.yaml", Content: []byte("exports")}},
+			&StatementOptions{FinishedOn: &finished},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		e, err := Sign(context.Background(), statement, signers...)
+		Expect(err).NotTo(HaveOccurred())
+		return e
+	}
+
+	It("should accept attestations which satisfy the policy", func() {
+		statement, err := Verify(envelope(signer, otherSigner), []byte("exports"), &VerificationPolicy{
+			Verifiers:         []Verifier{verifier, otherVerifier},
+			RequiredKeyIDs:    []string{signer.KeyID(), otherSigner.KeyID()},
+			BuilderID:         DefaultBuilderID,
+			MaxAge:            time.Hour,
+			ApprovedMaterials: []string{Digest([]byte("imports"))["sha256"]},
+			Now:               func() time.Time { return finished.Add(time.Minute) },
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statement.Subject[0].Name).To(Equal("exports.yaml"))
+	})
+
+	It("should reject attestations of untrusted or missing keys", func() {
+		_, err := Verify(envelope(otherSigner), []byte("exports"), &VerificationPolicy{Verifiers: []Verifier{verifier}})
+		Expect(IsVerificationError(err)).To(BeTrue())
+
+		_, err = Verify(envelope(signer), []byte("exports"), &VerificationPolicy{
+			Verifiers:      []Verifier{verifier, otherVerifier},
+			RequiredKeyIDs: []string{otherSigner.KeyID()},
+		})
+		Expect(IsVerificationError(err)).To(BeTrue())
+	})
+
+	It("should reject tampered payloads", func() {
+		e := envelope(signer)
+		e.Payload = e.Payload[:len(e.Payload)-4] + "AAAA"
+		_, err := Verify(e, []byte("exports"), &VerificationPolicy{Verifiers: []Verifier{verifier}})
+		Expect(IsVerificationError(err)).To(BeTrue())
+	})
+
+	It("should reject other artifacts", func() {
+		_, err := Verify(envelope(signer), []byte("other"), &VerificationPolicy{Verifiers: []Verifier{verifier}})
+		Expect(err).To(MatchError(ContainSubstring("is not attested")))
+	})
+
+	It("should reject old and future attestations and unapproved materials", func() {
+		_, err := Verify(envelope(signer), []byte("exports"), &VerificationPolicy{
+			Verifiers: []Verifier{verifier},
+			MaxAge:    time.Hour,
+			Now:       func() time.Time { return finished.Add(2 * time.Hour) },
+		})
+		Expect(err).To(MatchError(ContainSubstring("old")))
+
+		_, err = Verify(envelope(signer), []byte("exports"), &VerificationPolicy{
+			Verifiers: []Verifier{verifier},
+			MaxAge:    time.Hour,
+			Now:       func() time.Time { return finished.Add(-MaxClockSkew + time.Second) },
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = Verify(envelope(signer), []byte("exports"), &VerificationPolicy{
+			Verifiers: []Verifier{verifier},
+			MaxAge:    time.Hour,
+			Now:       func() time.Time { return finished.Add(-24 * time.Hour) },
+		})
+		Expect(err).To(MatchError(ContainSubstring("attestation finished 24h0m0s in the future, at most 5m0s are allowed")))
+
+		_, err = Verify(envelope(signer), []byte("exports"), &VerificationPolicy{
+			Verifiers:         []Verifier{verifier},
+			ApprovedMaterials: []string{Digest([]byte("other"))["sha256"]},
+		})
+		Expect(err).To(MatchError(ContainSubstring("is not approved")))
+	})
+
+	It("should verify ed25519 signatures", func() {
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		s, err := NewLocalSigner(encodePKCS8(private))
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalPKIXPublicKey(public)
+		Expect(err).NotTo(HaveOccurred())
+		v, err := NewPublicKeyVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(v.KeyID()).To(Equal(s.KeyID()))
+
+		_, err = Verify(envelope(s), []byte("exports"), &VerificationPolicy{Verifiers: []Verifier{v}})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
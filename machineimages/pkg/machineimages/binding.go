@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/pflag"
+	"sigs.k8s.io/yaml"
+)
+
+// EnvVarImportsPrefix is the prefix of the environment variables which set fields of the imports.
+const EnvVarImportsPrefix = "MACHINEIMAGES_"
+
+// ImportsBinding binds the fields of the imports to environment variables and flags. A field with the json name
+// minVersionsAction is bound to the environment variable MACHINEIMAGES_MIN_VERSIONS_ACTION and the flag
+// --min-versions-action. Values are yaml, so that lists, maps and nested objects can be set. Lists of strings may also
+// be given comma separated. The fields of unboundFields are not bound.
+type ImportsBinding struct {
+	fields []boundField
+	flags  *pflag.FlagSet
+	values map[string]*string
+}
+
+type boundField struct {
+	name   string
+	index  []int
+	flag   string
+	envVar string
+	// unbound fields have no flag, and their environment variable is rejected.
+	unbound bool
+}
+
+// unboundFields are the json names of the imports fields which cannot be set by environment variables and flags,
+// because they run code, fetch from or send to remote endpoints, possibly with credentials, or decide which changes
+// need an approval. They must be set in the imports file, which can be reviewed and attested.
+var unboundFields = map[string]bool{
+	"plugins":          true,
+	"remoteOsImages":   true,
+	"selectionFrom":    true,
+	"incidentsWebhook": true,
+	"notifications":    true,
+	"artifactProbe":    true,
+	"diff":             true,
+}
+
+// NewImportsBinding registers a flag for every field of the imports in the flag set.
+func NewImportsBinding(fs *pflag.FlagSet) *ImportsBinding {
+	b := &ImportsBinding{
+		fields: bindFields(reflect.TypeOf(Imports{}), nil),
+		flags:  fs,
+		values: map[string]*string{},
+	}
+	for _, field := range b.fields {
+		if field.unbound {
+			continue
+		}
+		b.values[field.flag] = fs.String(field.flag, "", fmt.Sprintf("Sets the imports field %s, also settable with %s", field.flag, field.envVar))
+	}
+	return b
+}
+
+// bindFields returns the bound fields of a struct type with their flag and environment variable names. Inlined
+// structs contribute their fields, fields without json name are skipped.
+func bindFields(t reflect.Type, index []int) []boundField {
+	fields := []boundField{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				fields = append(fields, bindFields(field.Type, fieldIndex)...)
+			}
+			continue
+		}
+
+		words := splitCamelCase(name)
+		fields = append(fields, boundField{
+			name:    name,
+			index:   fieldIndex,
+			flag:    strings.ToLower(strings.Join(words, "-")),
+			envVar:  EnvVarImportsPrefix + strings.ToUpper(strings.Join(words, "_")),
+			unbound: unboundFields[name],
+		})
+	}
+	return fields
+}
+
+func splitCamelCase(name string) []string {
+	words := []string{}
+	start := 0
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			words = append(words, name[start:i])
+			start = i
+		}
+	}
+	return append(words, name[start:])
+}
+
+// Apply sets the fields of the imports, which were read from the imports file, from the environment variables and then
+// from the changed flags, so that flags take precedence over environment variables and both over the imports file.
+// lookupEnv is usually os.LookupEnv. Environment variables of unbound fields are rejected.
+func (b *ImportsBinding) Apply(imports *Imports, lookupEnv func(string) (string, bool)) error {
+	target := reflect.ValueOf(imports).Elem()
+	for _, field := range b.fields {
+		if field.unbound {
+			if _, ok := lookupEnv(field.envVar); ok {
+				return fmt.Errorf("the imports field %s cannot be set from environment variable %s, it must be set in the imports file",
+					field.name, field.envVar)
+			}
+			continue
+		}
+		if value, ok := lookupEnv(field.envVar); ok {
+			if err := setField(target.FieldByIndex(field.index), value); err != nil {
+				return fmt.Errorf("unable to set imports from environment variable %s: %w", field.envVar, err)
+			}
+		}
+		if b.flags.Changed(field.flag) {
+			if err := setField(target.FieldByIndex(field.index), *b.values[field.flag]); err != nil {
+				return fmt.Errorf("unable to set imports from flag --%s: %w", field.flag, err)
+			}
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, value string) error {
+	if field.Kind() == reflect.String {
+		field.SetString(value)
+		return nil
+	}
+
+	trimmed := strings.TrimSpace(value)
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(trimmed, "[") {
+		items := reflect.MakeSlice(field.Type(), 0, 0)
+		for _, item := range strings.Split(trimmed, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				items = reflect.Append(items, reflect.ValueOf(item).Convert(field.Type().Elem()))
+			}
+		}
+		field.Set(items)
+		return nil
+	}
+
+	parsed := reflect.New(field.Type())
+	if err := yaml.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+		return err
+	}
+	field.Set(parsed.Elem())
+	return nil
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/pflag"
+)
+
+var _ = Describe("binding", func() {
+
+	var (
+		fs      *pflag.FlagSet
+		binding *ImportsBinding
+		env     map[string]string
+	)
+
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	BeforeEach(func() {
+		fs = pflag.NewFlagSet("test", pflag.ContinueOnError)
+		binding = NewImportsBinding(fs)
+		env = map[string]string{}
+	})
+
+	It("should register flags for inlined options", func() {
+		Expect(fs.Lookup("disable-machine-images")).NotTo(BeNil())
+		Expect(fs.Lookup("min-versions-action")).NotTo(BeNil())
+		Expect(fs.Lookup("network-policy-guard")).NotTo(BeNil())
+		Expect(fs.Lookup("incidents")).To(BeNil())
+	})
+
+	It("should let flags take precedence over environment variables over the imports file", func() {
+		imports := &Imports{
+			DisableMachineImages:        []string{"ubuntu"},
+			ComputeMachineImagesOptions: ComputeMachineImagesOptions{RequiredImages: []string{"gardenlinux"}},
+		}
+		env["MACHINEIMAGES_DISABLE_MACHINE_IMAGES"] = "suse-chost, flatcar"
+		env["MACHINEIMAGES_MIN_VERSIONS_ACTION"] = "drop"
+		Expect(fs.Parse([]string{"--min-versions-action=error", "--network-policy-guard=true"})).To(Succeed())
+
+		Expect(binding.Apply(imports, lookupEnv)).To(Succeed())
+		Expect(imports.DisableMachineImages).To(Equal([]string{"suse-chost", "flatcar"}))
+		Expect(imports.RequiredImages).To(Equal([]string{"gardenlinux"}))
+		Expect(imports.MinVersionsAction).To(Equal(PolicyAction("error")))
+		Expect(imports.NetworkPolicyGuard).To(BeTrue())
+	})
+
+	It("should parse yaml values", func() {
+		imports := &Imports{}
+		env["MACHINEIMAGES_MIN_VERSIONS"] = "{gardenlinux: 318.8.0}"
+		Expect(fs.Parse([]string{"--include-filters=[patch-latest]"})).To(Succeed())
+
+		Expect(binding.Apply(imports, lookupEnv)).To(Succeed())
+		Expect(imports.MinVersions).To(Equal(map[string]string{"gardenlinux": "318.8.0"}))
+		Expect(imports.IncludeFilters).To(Equal([]OsImagesFilterKind{"patch-latest"}))
+	})
+
+	It("should not bind fields which run code or change trust", func() {
+		for _, name := range []string{"plugins", "remote-os-images", "selection-from", "incidents-webhook", "notifications",
+			"artifact-probe", "diff"} {
+			Expect(fs.Lookup(name)).To(BeNil(), name)
+		}
+	})
+
+	It("should reject environment variables of unbound fields", func() {
+		for _, envVar := range []string{"MACHINEIMAGES_PLUGINS", "MACHINEIMAGES_REMOTE_OS_IMAGES", "MACHINEIMAGES_SELECTION_FROM",
+			"MACHINEIMAGES_INCIDENTS_WEBHOOK", "MACHINEIMAGES_NOTIFICATIONS", "MACHINEIMAGES_ARTIFACT_PROBE", "MACHINEIMAGES_DIFF"} {
+			env = map[string]string{envVar: "{}"}
+			imports := &Imports{}
+			err := binding.Apply(imports, lookupEnv)
+			Expect(err).To(MatchError(ContainSubstring("cannot be set from environment variable "+envVar)), envVar)
+			Expect(imports).To(Equal(&Imports{}))
+		}
+	})
+
+	It("should name the source of invalid values", func() {
+		env["MACHINEIMAGES_NETWORK_POLICY_GUARD"] = "maybe"
+		err := binding.Apply(&Imports{}, lookupEnv)
+		Expect(err).To(MatchError(ContainSubstring("MACHINEIMAGES_NETWORK_POLICY_GUARD")))
+	})
+})
@@ -0,0 +1,358 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js && !wasip1
+// +build !js,!wasip1
+
+// Package browser implements a terminal user interface to explore machine image catalogs.
+package browser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+const (
+	helpList    = "up/down move • enter open • esc back • o os filter • c classification filter • x clear filters • q quit"
+	helpVersion = "up/down scroll • esc back • q quit"
+)
+
+type page int
+
+const (
+	pageImages page = iota
+	pageVersions
+	pageVersion
+)
+
+// Model is the bubbletea model of the browser. It lists the images of the catalog, the versions of an image and the
+// provenance trail and the configuration of a version.
+type Model struct {
+	catalog        *mi.Catalog
+	osFilter       string
+	classification string
+
+	page    page
+	image   string
+	version string
+	// cursor is the selected line of the lists and the first visible line of a version.
+	cursor int
+	// imageCursor is the cursor of the image list, which is restored when leaving the versions.
+	imageCursor int
+	// height is the height of the terminal, all lines are rendered if zero.
+	height int
+}
+
+// New returns a browser for the given catalog.
+func New(catalog *mi.Catalog) *Model {
+	return &Model{catalog: catalog}
+}
+
+// Run runs the browser in the terminal until the user quits.
+func Run(catalog *mi.Catalog) error {
+	return tea.NewProgram(New(catalog), tea.WithAltScreen()).Start()
+}
+
+// Init implements tea.Model.
+func (m *Model) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model.
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tea.KeyMsg:
+		return m, m.handleKey(msg.String())
+	}
+	return m, nil
+}
+
+// View implements tea.Model.
+func (m *Model) View() string {
+	lines := m.lines()
+	help := helpList
+	if m.page == pageVersion {
+		help = helpVersion
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintln(b, m.title())
+	fmt.Fprintln(b)
+
+	first, last := m.visible(len(lines))
+	for i := first; i < last; i++ {
+		switch {
+		case m.page == pageVersion:
+			fmt.Fprintln(b, lines[i])
+		case i == m.cursor:
+			fmt.Fprintln(b, "> "+lines[i])
+		default:
+			fmt.Fprintln(b, "  "+lines[i])
+		}
+	}
+	if len(lines) == 0 {
+		fmt.Fprintln(b, "  no versions match the current filter")
+	}
+
+	fmt.Fprintln(b)
+	fmt.Fprint(b, help)
+	return b.String()
+}
+
+func (m *Model) handleKey(key string) tea.Cmd {
+	switch key {
+	case "ctrl+c", "q":
+		return tea.Quit
+	case "up", "k":
+		if m.cursor > 0 {
+			m.cursor--
+		}
+	case "down", "j":
+		if m.cursor < len(m.lines())-1 {
+			m.cursor++
+		}
+	case "enter", "right", "l":
+		m.open()
+	case "esc", "backspace", "left", "h":
+		m.back()
+	case "o":
+		m.setFilters(next(m.osNames(), m.osFilter), m.classification)
+	case "c":
+		m.setFilters(m.osFilter, next(m.classifications(), m.classification))
+	case "x":
+		m.setFilters("", "")
+	}
+	return nil
+}
+
+func (m *Model) open() {
+	switch m.page {
+	case pageImages:
+		images := m.images()
+		if len(images) == 0 {
+			return
+		}
+		m.image = images[m.cursor]
+		m.imageCursor = m.cursor
+		m.page = pageVersions
+		m.cursor = 0
+	case pageVersions:
+		versions := m.versions()
+		if len(versions) == 0 {
+			return
+		}
+		m.version = versions[m.cursor]
+		m.page = pageVersion
+		m.cursor = 0
+	}
+}
+
+func (m *Model) back() {
+	switch m.page {
+	case pageVersions:
+		m.page = pageImages
+		m.cursor = m.imageCursor
+	case pageVersion:
+		m.page = pageVersions
+		m.cursor = indexOf(m.versions(), m.version)
+	}
+}
+
+// setFilters changes the filters and returns to the image list, as the selected image or version may not match
+// anymore.
+func (m *Model) setFilters(osFilter, classification string) {
+	if m.page == pageVersion {
+		return
+	}
+	m.osFilter = osFilter
+	m.classification = classification
+	m.page = pageImages
+	m.cursor = 0
+}
+
+func (m *Model) title() string {
+	title := fmt.Sprintf("%d versions from %d files", len(m.catalog.Entries), len(m.catalog.Files))
+	switch m.page {
+	case pageVersions:
+		title = m.image
+	case pageVersion:
+		title = m.image + " " + m.version
+	}
+
+	filters := []string{}
+	if len(m.osFilter) > 0 {
+		filters = append(filters, "os="+m.osFilter)
+	}
+	if len(m.classification) > 0 {
+		filters = append(filters, "classification="+m.classification)
+	}
+	if len(filters) > 0 && m.page != pageVersion {
+		title += " [" + strings.Join(filters, " ") + "]"
+	}
+	return title
+}
+
+// lines returns the lines of the current page.
+func (m *Model) lines() []string {
+	lines := []string{}
+	switch m.page {
+	case pageImages:
+		counts := map[string]int{}
+		for _, entry := range m.matchingEntries() {
+			counts[entry.Name]++
+		}
+		for _, name := range m.images() {
+			lines = append(lines, fmt.Sprintf("%-20s %d versions", name, counts[name]))
+		}
+	case pageVersions:
+		for _, version := range m.versions() {
+			entry := m.entriesOf(m.image, version)[0]
+			lines = append(lines, fmt.Sprintf("%-24s %-12s %s", version,
+				stringValue(entry.Version, "classification"), stringValue(entry.Version, "expirationDate")))
+		}
+	case pageVersion:
+		entries := m.entriesOf(m.image, m.version)
+		lines = append(lines, "provenance:")
+		for _, entry := range entries {
+			lines = append(lines, "  "+entry.Source.String())
+		}
+		for _, entry := range entries {
+			data, err := yaml.Marshal(entry.Version)
+			if err != nil {
+				data = []byte(err.Error())
+			}
+			lines = append(lines, "", "# "+entry.Source.String())
+			lines = append(lines, strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")...)
+		}
+	}
+	return lines
+}
+
+// visible returns the range of the lines which fit into the terminal. Lists keep the cursor visible, versions start
+// at the cursor.
+func (m *Model) visible(count int) (int, int) {
+	// the title, the help and the blank lines around the body
+	height := m.height - 4
+	if m.height == 0 || count <= height {
+		return 0, count
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	first := m.cursor
+	if m.page != pageVersion {
+		first = m.cursor - height + 1
+		if first < 0 {
+			first = 0
+		}
+	}
+	last := first + height
+	if last > count {
+		last = count
+	}
+	return first, last
+}
+
+// images returns the sorted names of the images which have versions matching the filters.
+func (m *Model) images() []string {
+	names := []string{}
+	for _, entry := range m.matchingEntries() {
+		if indexOf(names, entry.Name) < 0 {
+			names = append(names, entry.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// versions returns the versions of the selected image which match the filters, in the order of the catalog.
+func (m *Model) versions() []string {
+	versions := []string{}
+	for _, entry := range m.matchingEntries() {
+		version := stringValue(entry.Version, "version")
+		if entry.Name == m.image && indexOf(versions, version) < 0 {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+func (m *Model) osNames() []string {
+	names := []string{}
+	for _, entry := range m.catalog.Entries {
+		if indexOf(names, entry.Name) < 0 {
+			names = append(names, entry.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m *Model) classifications() []string {
+	classifications := []string{}
+	for _, entry := range m.catalog.Entries {
+		classification := stringValue(entry.Version, "classification")
+		if len(classification) > 0 && indexOf(classifications, classification) < 0 {
+			classifications = append(classifications, classification)
+		}
+	}
+	sort.Strings(classifications)
+	return classifications
+}
+
+func (m *Model) matchingEntries() []mi.CatalogEntry {
+	result := []mi.CatalogEntry{}
+	for _, entry := range m.catalog.Entries {
+		if len(m.osFilter) > 0 && entry.Name != m.osFilter {
+			continue
+		}
+		if len(m.classification) > 0 && stringValue(entry.Version, "classification") != m.classification {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func (m *Model) entriesOf(image, version string) []mi.CatalogEntry {
+	result := []mi.CatalogEntry{}
+	for _, entry := range m.catalog.Entries {
+		if entry.Name == image && stringValue(entry.Version, "version") == version {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// next returns the value after current, or no value after the last one.
+func next(values []string, current string) string {
+	i := indexOf(values, current)
+	if i+1 < len(values) {
+		return values[i+1]
+	}
+	return ""
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func stringValue(v mi.MachineImageVersion, key string) string {
+	value, _ := v[key].(string)
+	return value
+}
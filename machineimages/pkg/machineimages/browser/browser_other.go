@@ -0,0 +1,20 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build js || wasip1
+// +build js wasip1
+
+package browser
+
+import (
+	"fmt"
+	"runtime"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// Run fails, as there is no terminal on this platform.
+func Run(_ *mi.Catalog) error {
+	return fmt.Errorf("the browser requires a terminal, which is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js && !wasip1
+// +build !js,!wasip1
+
+package browser
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+var _ = Describe("browser", func() {
+
+	keys := map[string]tea.KeyMsg{
+		"up":    {Type: tea.KeyUp},
+		"down":  {Type: tea.KeyDown},
+		"enter": {Type: tea.KeyEnter},
+		"esc":   {Type: tea.KeyEsc},
+	}
+
+	// press sends the keys to a new browser and returns the final view and command.
+	press := func(names ...string) (string, tea.Cmd) {
+		catalog, err := mi.LoadCatalog("../resources/catalog", nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		var model tea.Model = New(catalog)
+		var cmd tea.Cmd
+		for _, name := range names {
+			key, ok := keys[name]
+			if !ok {
+				key = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(name)}
+			}
+			model, cmd = model.Update(key)
+		}
+		return model.View(), cmd
+	}
+
+	It("should list the images", func() {
+		view, _ := press()
+		Expect(view).To(ContainSubstring("> flatcar              1 versions\n"))
+		Expect(view).To(ContainSubstring("  gardenlinux          3 versions\n"))
+		Expect(view).To(ContainSubstring("  ubuntu               1 versions\n"))
+	})
+
+	It("should apply filters", func() {
+		view, _ := press("c")
+		Expect(view).To(ContainSubstring("[classification=deprecated]"))
+
+		view, _ = press("c", "c", "enter")
+		Expect(view).To(HavePrefix("gardenlinux [classification=preview]"))
+		Expect(view).To(ContainSubstring("> 318.9.0"))
+		Expect(view).NotTo(ContainSubstring("184.0.0"))
+
+		view, _ = press("o", "o", "o")
+		Expect(view).To(ContainSubstring("[os=ubuntu]"))
+		Expect(view).NotTo(ContainSubstring("gardenlinux"))
+
+		view, _ = press("o", "c", "x")
+		Expect(view).NotTo(ContainSubstring("["))
+	})
+
+	It("should show the provenance and configuration of a version", func() {
+		view, _ := press("down", "enter", "down", "down", "enter")
+		Expect(view).To(HavePrefix("gardenlinux 184.0.0\n"))
+		Expect(view).To(ContainSubstring("provenance:\n  gcp/gardenlinux.yml:[0].versions[0]\n"))
+		Expect(view).To(ContainSubstring("# gcp/gardenlinux.yml:[0].versions[0]\nclassification: deprecated"))
+	})
+
+	It("should navigate back to the selected version and image", func() {
+		view, _ := press("down", "down", "enter", "esc")
+		Expect(view).To(ContainSubstring("> ubuntu"))
+
+		view, _ = press("down", "enter", "down", "enter", "esc")
+		Expect(view).To(MatchRegexp(`\n  318.8.0 .*\n> 318.9.0 `))
+	})
+
+	It("should only render the lines which fit into the terminal", func() {
+		catalog, err := mi.LoadCatalog("../resources/catalog", nil)
+		Expect(err).NotTo(HaveOccurred())
+		model := New(catalog)
+		model.Update(tea.WindowSizeMsg{Height: 5})
+		model.Update(keys["down"])
+
+		Expect(model.View()).NotTo(ContainSubstring("flatcar"))
+		Expect(model.View()).To(ContainSubstring("> gardenlinux"))
+	})
+
+	It("should quit", func() {
+		_, cmd := press("q")
+		Expect(cmd).NotTo(BeNil())
+		Expect(cmd()).To(Equal(tea.Quit()))
+	})
+})
@@ -0,0 +1,197 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TrimStrategy determines which versions are removed first if a VersionBudget is exceeded.
+type TrimStrategy string
+
+const (
+	// TrimStrategyNewest keeps the newest versions.
+	TrimStrategyNewest = TrimStrategy("newest")
+	// TrimStrategyNewestPerMinor keeps the newest version of every minor line before any other version.
+	TrimStrategyNewestPerMinor = TrimStrategy("newestPerMinor")
+	// TrimStrategyKeepSupported never removes supported versions and keeps the newest of the other versions.
+	TrimStrategyKeepSupported = TrimStrategy("keepSupported")
+)
+
+// VersionBudget limits the number of versions in the result.
+type VersionBudget struct {
+	// MaxVersions is the maximum number of versions of all images together. Zero means no limit.
+	MaxVersions int `json:"maxVersions,omitempty" yaml:"maxVersions,omitempty"`
+	// MaxVersionsPerImage is the maximum number of versions per image. Zero means no limit.
+	MaxVersionsPerImage int `json:"maxVersionsPerImage,omitempty" yaml:"maxVersionsPerImage,omitempty"`
+	// Strategy determines which versions are removed. Defaults to TrimStrategyNewest.
+	Strategy TrimStrategy `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+}
+
+// trimCandidates contains the indices of the versions of one image ordered by importance, most important first. The
+// first protected candidates must not be removed.
+type trimCandidates struct {
+	indices   []int
+	protected int
+}
+
+// applyVersionBudget removes versions until the budget is met. Versions keep their order within an image.
+func applyVersionBudget(ctx context.Context, images []MachineImage, budget *VersionBudget) ([]MachineImage, error) {
+	if budget == nil || (budget.MaxVersions <= 0 && budget.MaxVersionsPerImage <= 0) {
+		return images, nil
+	}
+
+	candidates := make([]trimCandidates, len(images))
+	for i, image := range images {
+		c, err := newTrimCandidates(image.Versions, budget.Strategy)
+		if err != nil {
+			return nil, err
+		}
+		candidates[i] = c
+	}
+
+	if budget.MaxVersionsPerImage > 0 {
+		for i := range candidates {
+			if err := candidates[i].trimTo(budget.MaxVersionsPerImage, images[i].Name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if budget.MaxVersions > 0 {
+		if err := trimTotal(candidates, budget.MaxVersions); err != nil {
+			return nil, err
+		}
+	}
+
+	_, reporter := FromContext(ctx)
+
+	result := []MachineImage{}
+	for i, image := range images {
+		keep := map[int]bool{}
+		for _, index := range candidates[i].indices {
+			keep[index] = true
+		}
+
+		kept := []MachineImageVersion{}
+		for index, v := range image.Versions {
+			if keep[index] {
+				kept = append(kept, v)
+			} else {
+				reporter.Report(ReportEntry{
+					Image:   image.Name,
+					Version: versionOrEmpty(v),
+					Reason:  ReasonBudgetExceeded,
+					Message: "version was removed to meet the version budget",
+				})
+			}
+		}
+		if len(kept) > 0 {
+			result = append(result, MachineImage{Name: image.Name, Versions: kept})
+		}
+	}
+
+	return result, nil
+}
+
+// trimTotal removes the least important version of the image with the most removable versions until the total number
+// of versions is within the limit.
+func trimTotal(candidates []trimCandidates, maxVersions int) error {
+	total := 0
+	for _, c := range candidates {
+		total += len(c.indices)
+	}
+
+	for total > maxVersions {
+		next := -1
+		for i, c := range candidates {
+			if len(c.indices) > c.protected && (next < 0 || len(c.indices) > len(candidates[next].indices)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			return fmt.Errorf("version budget of %d versions cannot be met without removing protected versions", maxVersions)
+		}
+
+		candidates[next].indices = candidates[next].indices[:len(candidates[next].indices)-1]
+		total--
+	}
+
+	return nil
+}
+
+func newTrimCandidates(versions []MachineImageVersion, strategy TrimStrategy) (trimCandidates, error) {
+	sorted := make([]int, len(versions))
+	for i := range sorted {
+		sorted[i] = i
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareVersions(versionOrEmpty(versions[sorted[i]]), versionOrEmpty(versions[sorted[j]])) > 0
+	})
+
+	switch strategy {
+	case "", TrimStrategyNewest:
+		return trimCandidates{indices: sorted}, nil
+	case TrimStrategyNewestPerMinor:
+		newest := []int{}
+		others := []int{}
+		seen := map[string]bool{}
+		for _, i := range sorted {
+			line := minorLine(versionOrEmpty(versions[i]))
+			if seen[line] {
+				others = append(others, i)
+			} else {
+				seen[line] = true
+				newest = append(newest, i)
+			}
+		}
+		return trimCandidates{indices: append(newest, others...)}, nil
+	case TrimStrategyKeepSupported:
+		supported := []int{}
+		others := []int{}
+		for _, i := range sorted {
+			if versions[i].hasClassification(ClassificationSupported) {
+				supported = append(supported, i)
+			} else {
+				others = append(others, i)
+			}
+		}
+		return trimCandidates{indices: append(supported, others...), protected: len(supported)}, nil
+	default:
+		return trimCandidates{}, fmt.Errorf("trim strategy does not exist %s", strategy)
+	}
+}
+
+func (c *trimCandidates) trimTo(maxVersions int, imageName string) error {
+	if len(c.indices) <= maxVersions {
+		return nil
+	}
+	if c.protected > maxVersions {
+		return fmt.Errorf("version budget of %d versions per image cannot be met without removing protected versions of %s",
+			maxVersions, imageName)
+	}
+	c.indices = c.indices[:maxVersions]
+	return nil
+}
+
+func versionOrEmpty(v MachineImageVersion) string {
+	if version := v.getVersion(); version != nil {
+		return *version
+	}
+	return ""
+}
+
+// minorLine returns the major and minor part of a version, e.g. "318.9" for "318.9.0". Versions with less than two
+// dot separated parts form their own line.
+func minorLine(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
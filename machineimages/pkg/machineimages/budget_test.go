@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("budget", func() {
+
+	Context("applyVersionBudget", func() {
+
+		newImages := func() []MachineImage {
+			return []MachineImage{
+				{
+					Name: OsNameGardenLinux,
+					Versions: []MachineImageVersion{
+						{"version": "318.9.1", "classification": ClassificationPreview},
+						{"version": "318.9.0", "classification": ClassificationSupported},
+						{"version": "184.1.0", "classification": ClassificationDeprecated},
+						{"version": "184.0.0", "classification": ClassificationSupported},
+					},
+				},
+				{
+					Name: OsNameUbuntu,
+					Versions: []MachineImageVersion{
+						{"version": "18.4.20210415"},
+					},
+				},
+			}
+		}
+
+		versionsOf := func(image MachineImage) []string {
+			result := []string{}
+			for _, v := range image.Versions {
+				result = append(result, *v.getVersion())
+			}
+			return result
+		}
+
+		It("should not change images without budget", func() {
+			result, err := applyVersionBudget(context.Background(), newImages(), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(newImages()))
+		})
+
+		It("should keep the newest versions per image", func() {
+			result, err := applyVersionBudget(context.Background(), newImages(), &VersionBudget{MaxVersionsPerImage: 2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(result[0])).To(Equal([]string{"318.9.1", "318.9.0"}))
+			Expect(versionsOf(result[1])).To(Equal([]string{"18.4.20210415"}))
+		})
+
+		It("should keep the newest version per minor line", func() {
+			result, err := applyVersionBudget(context.Background(), newImages(), &VersionBudget{MaxVersionsPerImage: 2, Strategy: TrimStrategyNewestPerMinor})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(result[0])).To(Equal([]string{"318.9.1", "184.1.0"}))
+		})
+
+		It("should keep supported versions", func() {
+			result, err := applyVersionBudget(context.Background(), newImages(), &VersionBudget{MaxVersionsPerImage: 2, Strategy: TrimStrategyKeepSupported})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(result[0])).To(Equal([]string{"318.9.0", "184.0.0"}))
+
+			_, err = applyVersionBudget(context.Background(), newImages(), &VersionBudget{MaxVersionsPerImage: 1, Strategy: TrimStrategyKeepSupported})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should trim the image with most versions first to meet the total budget", func() {
+			result, err := applyVersionBudget(context.Background(), newImages(), &VersionBudget{MaxVersions: 3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(versionsOf(result[0])).To(Equal([]string{"318.9.1", "318.9.0"}))
+			Expect(versionsOf(result[1])).To(Equal([]string{"18.4.20210415"}))
+		})
+
+		It("should reject unknown strategies", func() {
+			_, err := applyVersionBudget(context.Background(), newImages(), &VersionBudget{MaxVersions: 3, Strategy: TrimStrategy("oldest")})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
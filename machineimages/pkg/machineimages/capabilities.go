@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"os"
+	"sort"
+)
+
+// CapabilitiesAPIVersion is the version of the Capabilities format. Fields are only added within a version.
+const CapabilitiesAPIVersion = "machineimages.landscaper.gardener.cloud/v1alpha1"
+
+// Capabilities describe what this version of the tooling supports, so that orchestrating tools can adapt to the
+// deployed version.
+type Capabilities struct {
+	APIVersion string `json:"apiVersion"`
+	// Version is the version of the binary.
+	Version string `json:"version"`
+	// CatalogAPIVersions are the formats of catalog files which can be loaded. Files without apiVersion are always
+	// supported in the legacy format.
+	CatalogAPIVersions []string `json:"catalogAPIVersions"`
+	// FilterKinds are the kinds of include and exclude filters.
+	FilterKinds []OsImagesFilterKind `json:"filterKinds"`
+	// Providers are the provider types whose provider configs are understood, either for partitions or as typed image
+	// mappings.
+	Providers []ProviderCapabilities `json:"providers"`
+	// FeatureGates are the feature gates and whether they are enabled in this process.
+	FeatureGates []FeatureGateCapability `json:"featureGates"`
+}
+
+// ProviderCapabilities describe the support of a provider type.
+type ProviderCapabilities struct {
+	Name string `json:"name"`
+	// Partition is whether the machine images can be partitioned for the provider, see PartitionByProvider.
+	Partition bool `json:"partition"`
+	// Mapping is whether the provider configs are validated as typed image mappings, see DecodeProviderMapping.
+	Mapping bool `json:"mapping"`
+}
+
+// FeatureGateCapability is a feature gate and its state.
+type FeatureGateCapability struct {
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+}
+
+// GetCapabilities returns the capabilities of this version of the tooling.
+func GetCapabilities() *Capabilities {
+	capabilities := &Capabilities{
+		APIVersion:         CapabilitiesAPIVersion,
+		Version:            buildVersion(),
+		CatalogAPIVersions: []string{},
+		FilterKinds:        RegisteredOsImagesFilterKinds(),
+		Providers:          []ProviderCapabilities{},
+		FeatureGates:       []FeatureGateCapability{},
+	}
+
+	for apiVersion := range catalogConversions {
+		if len(apiVersion) > 0 {
+			capabilities.CatalogAPIVersions = append(capabilities.CatalogAPIVersions, apiVersion)
+		}
+	}
+	sort.Strings(capabilities.CatalogAPIVersions)
+
+	providers := map[string]*ProviderCapabilities{}
+	for provider := range DefaultProviderFields {
+		providers[provider] = &ProviderCapabilities{Name: provider, Partition: true}
+	}
+	for _, provider := range MappingProviders() {
+		if providers[provider] == nil {
+			providers[provider] = &ProviderCapabilities{Name: provider}
+		}
+		providers[provider].Mapping = true
+	}
+	for _, provider := range providers {
+		capabilities.Providers = append(capabilities.Providers, *provider)
+	}
+	sort.Slice(capabilities.Providers, func(i, j int) bool {
+		return capabilities.Providers[i].Name < capabilities.Providers[j].Name
+	})
+
+	gates := os.Getenv(EnvVarFeatureGates)
+	for _, gate := range FeatureGates {
+		enabled := featureGateEnabled(gates, gate)
+		if gate == FaultInjectionFeatureGate {
+			enabled = FaultInjectionEnabled()
+		}
+		capabilities.FeatureGates = append(capabilities.FeatureGates, FeatureGateCapability{Name: gate, Enabled: enabled})
+	}
+	return capabilities
+}
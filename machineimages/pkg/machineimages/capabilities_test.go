@@ -0,0 +1,40 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("capabilities", func() {
+
+	It("should describe the supported formats, filters and providers", func() {
+		capabilities := GetCapabilities()
+		Expect(capabilities.APIVersion).To(Equal(CapabilitiesAPIVersion))
+		Expect(capabilities.Version).NotTo(BeEmpty())
+		Expect(capabilities.CatalogAPIVersions).To(Equal([]string{CatalogAPIVersionV1Alpha1}))
+		Expect(capabilities.FilterKinds).To(ContainElements(OsImagesFilterKindAll, OsImagesFilterKindOutdated, OsImagesFilterKindMemoryoneChost))
+		Expect(capabilities.Providers).To(ContainElements(
+			ProviderCapabilities{Name: ProviderAWS, Partition: true, Mapping: true},
+			ProviderCapabilities{Name: "vsphere", Partition: true},
+		))
+	})
+
+	It("should list a filter kind for every filter", func() {
+		for _, kind := range GetCapabilities().FilterKinds {
+			_, err := createFilter(kind)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	})
+
+	It("should report the state of the feature gates", func() {
+		defer os.Unsetenv(EnvVarFeatureGates)
+		Expect(os.Setenv(EnvVarFeatureGates, FaultInjectionFeatureGate+"=true")).To(Succeed())
+		Expect(GetCapabilities().FeatureGates).To(Equal([]FeatureGateCapability{{Name: FaultInjectionFeatureGate, Enabled: true}}))
+	})
+})
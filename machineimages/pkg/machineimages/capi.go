@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"strings"
+)
+
+// CAPIImageLookup contains the image references of the machine images in the formats of the Cluster API providers.
+type CAPIImageLookup struct {
+	// AWS are the images in the format of the ami field of AWSMachine specs of the Cluster API provider AWS (CAPA).
+	AWS []CAPAMachineImage `json:"aws,omitempty"`
+	// Azure are the images in the format of the image field of AzureMachine specs of the Cluster API provider Azure
+	// (CAPZ).
+	Azure []CAPZMachineImage `json:"azure,omitempty"`
+}
+
+// CAPAMachineImage is the ami of a version in a region.
+type CAPAMachineImage struct {
+	Name    string           `json:"name"`
+	Version string           `json:"version"`
+	Region  string           `json:"region"`
+	AMI     CAPAAMIReference `json:"ami"`
+}
+
+// CAPAAMIReference references an ami by its id.
+type CAPAAMIReference struct {
+	ID string `json:"id"`
+}
+
+// CAPZMachineImage is the image of a version.
+type CAPZMachineImage struct {
+	Name    string    `json:"name"`
+	Version string    `json:"version"`
+	Image   CAPZImage `json:"image"`
+}
+
+// CAPZImage references an image by its resource id, in the marketplace or in a compute gallery.
+type CAPZImage struct {
+	ID             *string                  `json:"id,omitempty"`
+	Marketplace    *CAPZMarketplaceImage    `json:"marketplace,omitempty"`
+	SharedGallery  *CAPZSharedGalleryImage  `json:"sharedGallery,omitempty"`
+	ComputeGallery *CAPZComputeGalleryImage `json:"computeGallery,omitempty"`
+}
+
+// CAPZMarketplaceImage references a marketplace image.
+type CAPZMarketplaceImage struct {
+	Publisher string `json:"publisher"`
+	Offer     string `json:"offer"`
+	SKU       string `json:"sku"`
+	Version   string `json:"version"`
+}
+
+// CAPZSharedGalleryImage references an image of a gallery in a subscription.
+type CAPZSharedGalleryImage struct {
+	SubscriptionID string `json:"subscriptionID"`
+	ResourceGroup  string `json:"resourceGroup"`
+	Gallery        string `json:"gallery"`
+	Name           string `json:"name"`
+	Version        string `json:"version"`
+}
+
+// CAPZComputeGalleryImage references an image of a community gallery or of a gallery shared with the subscription.
+type CAPZComputeGalleryImage struct {
+	Gallery string `json:"gallery"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// NewCAPIImageLookup returns the image references of all versions for the providers they are configured for. Versions
+// with regions which have an ami are AWS images. Versions with a urn, a gallery image id or a resource id are Azure
+// images. All other versions have no Cluster API equivalent and are omitted.
+func NewCAPIImageLookup(images []MachineImage) *CAPIImageLookup {
+	lookup := &CAPIImageLookup{}
+	for _, image := range images {
+		for _, version := range image.Versions {
+			regions, _ := version["regions"].([]interface{})
+			for _, entry := range regions {
+				region, ok := entry.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := region["name"].(string)
+				ami, _ := region["ami"].(string)
+				if len(name) > 0 && len(ami) > 0 {
+					lookup.AWS = append(lookup.AWS, CAPAMachineImage{
+						Name:    image.Name,
+						Version: versionOrEmpty(version),
+						Region:  name,
+						AMI:     CAPAAMIReference{ID: ami},
+					})
+				}
+			}
+
+			if azureImage, ok := newCAPZImage(version); ok {
+				lookup.Azure = append(lookup.Azure, CAPZMachineImage{
+					Name:    image.Name,
+					Version: versionOrEmpty(version),
+					Image:   *azureImage,
+				})
+			}
+		}
+	}
+	return lookup
+}
+
+// AddAliases adds a copy of the images of every version which an alias is resolved to, with the alias as version.
+func (l *CAPIImageLookup) AddAliases(aliases VersionAliases) {
+	for _, image := range l.AWS {
+		for _, alias := range aliases.aliasesOf(image.Name, image.Version) {
+			aliasImage := image
+			aliasImage.Version = alias
+			l.AWS = append(l.AWS, aliasImage)
+		}
+	}
+	for _, image := range l.Azure {
+		for _, alias := range aliases.aliasesOf(image.Name, image.Version) {
+			aliasImage := image
+			aliasImage.Version = alias
+			l.Azure = append(l.Azure, aliasImage)
+		}
+	}
+}
+
+func newCAPZImage(version MachineImageVersion) (*CAPZImage, bool) {
+	if urn, ok := version["urn"].(string); ok {
+		parts := strings.Split(urn, ":")
+		if len(parts) == 4 {
+			return &CAPZImage{Marketplace: &CAPZMarketplaceImage{
+				Publisher: parts[0],
+				Offer:     parts[1],
+				SKU:       parts[2],
+				Version:   parts[3],
+			}}, true
+		}
+	}
+
+	for _, field := range []string{"communityGalleryImageID", "sharedGalleryImageID"} {
+		if id, ok := version[field].(string); ok {
+			// /CommunityGalleries/<gallery>/Images/<name>/Versions/<version>
+			parts := strings.Split(strings.TrimPrefix(id, "/"), "/")
+			if len(parts) == 6 {
+				return &CAPZImage{ComputeGallery: &CAPZComputeGalleryImage{
+					Gallery: parts[1],
+					Name:    parts[3],
+					Version: parts[5],
+				}}, true
+			}
+		}
+	}
+
+	if id, ok := version["id"].(string); ok && strings.HasPrefix(strings.ToLower(id), "/subscriptions/") {
+		// /subscriptions/<id>/resourceGroups/<group>/providers/Microsoft.Compute/galleries/<gallery>/images/<name>/versions/<version>
+		parts := strings.Split(strings.TrimPrefix(id, "/"), "/")
+		if len(parts) == 12 && strings.EqualFold(parts[6], "galleries") {
+			return &CAPZImage{SharedGallery: &CAPZSharedGalleryImage{
+				SubscriptionID: parts[1],
+				ResourceGroup:  parts[3],
+				Gallery:        parts[7],
+				Name:           parts[9],
+				Version:        parts[11],
+			}}, true
+		}
+		return &CAPZImage{ID: &id}, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("capi", func() {
+
+	It("should convert the amis of all regions", func() {
+		lookup := NewCAPIImageLookup([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "318.9.0", "regions": []interface{}{
+					map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"},
+					map[string]interface{}{"name": "us-east-1", "ami": "ami-2"},
+				}},
+			}},
+		})
+		Expect(lookup.Azure).To(BeEmpty())
+		Expect(lookup.AWS).To(Equal([]CAPAMachineImage{
+			{Name: OsNameGardenLinux, Version: "318.9.0", Region: "eu-west-1", AMI: CAPAAMIReference{ID: "ami-1"}},
+			{Name: OsNameGardenLinux, Version: "318.9.0", Region: "us-east-1", AMI: CAPAAMIReference{ID: "ami-2"}},
+		}))
+	})
+
+	It("should convert urns, gallery image ids and resource ids", func() {
+		resourceID := "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/images/ubuntu"
+		lookup := NewCAPIImageLookup([]MachineImage{
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "18.4.0", "urn": "Canonical:UbuntuServer:18.04-LTS:18.04.202106040"},
+				{"version": "18.4.1", "communityGalleryImageID": "/CommunityGalleries/gallery-1/Images/ubuntu/Versions/18.4.1"},
+				{"version": "18.4.2", "id": "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/galleries/gallery/images/ubuntu/versions/18.4.2"},
+				{"version": "18.4.3", "id": resourceID},
+				{"version": "18.4.4", "image": "projects/ubuntu/images/18-4-4"},
+			}},
+		})
+		Expect(lookup.AWS).To(BeEmpty())
+		Expect(lookup.Azure).To(Equal([]CAPZMachineImage{
+			{Name: OsNameUbuntu, Version: "18.4.0", Image: CAPZImage{Marketplace: &CAPZMarketplaceImage{
+				Publisher: "Canonical", Offer: "UbuntuServer", SKU: "18.04-LTS", Version: "18.04.202106040",
+			}}},
+			{Name: OsNameUbuntu, Version: "18.4.1", Image: CAPZImage{ComputeGallery: &CAPZComputeGalleryImage{
+				Gallery: "gallery-1", Name: "ubuntu", Version: "18.4.1",
+			}}},
+			{Name: OsNameUbuntu, Version: "18.4.2", Image: CAPZImage{SharedGallery: &CAPZSharedGalleryImage{
+				SubscriptionID: "sub", ResourceGroup: "rg", Gallery: "gallery", Name: "ubuntu", Version: "18.4.2",
+			}}},
+			{Name: OsNameUbuntu, Version: "18.4.3", Image: CAPZImage{ID: &resourceID}},
+		}))
+	})
+})
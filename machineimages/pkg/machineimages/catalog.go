@@ -0,0 +1,256 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// defaultCatalogIncludes are the include patterns used if no include patterns are configured.
+var defaultCatalogIncludes = []string{"**/*.yaml", "**/*.yml", "**/*.json"}
+
+// CatalogLoadOptions configures how a catalog is assembled from a directory tree.
+type CatalogLoadOptions struct {
+	// Include contains glob patterns of the files which belong to the catalog. The patterns are matched against the
+	// slash separated file path relative to the root directory and support "**" to match any number of directories.
+	// If empty, all yaml and json files are included.
+	Include []string `json:"include,omitempty"`
+	// Exclude contains glob patterns of files which are skipped even if they match an include pattern.
+	Exclude []string `json:"exclude,omitempty"`
+	// Strictness decides whether a malformed file or entry fails the load. Defaults to CatalogStrict.
+	Strictness CatalogStrictness `json:"strictness,omitempty"`
+	// FieldMapping translates the versions of catalogs with a foreign schema into the internal model.
+	FieldMapping *FieldMapping `json:"fieldMapping,omitempty"`
+}
+
+// CatalogSource describes where a catalog entry was loaded from.
+type CatalogSource struct {
+	// File is the slash separated path of the file relative to the root directory of the catalog.
+	File string `json:"file"`
+	// Path is the position of the version inside the file, e.g. "[0].versions[1]".
+	Path string `json:"path"`
+}
+
+func (s CatalogSource) String() string {
+	return s.File + ":" + s.Path
+}
+
+// CatalogEntry is a single machine image version of a catalog together with its provenance.
+type CatalogEntry struct {
+	OsImage
+	Source CatalogSource `json:"source"`
+}
+
+// Catalog is a list of machine image versions assembled from several files.
+type Catalog struct {
+	// Files are the loaded files in the order in which they were read.
+	Files []string `json:"files"`
+	// Entries are the versions of all files in the order in which they were read.
+	Entries []CatalogEntry `json:"entries"`
+	// Skipped are the malformed files and entries which were skipped by a lenient load.
+	Skipped []CatalogSkip `json:"skipped,omitempty"`
+}
+
+// LoadCatalog assembles a catalog from all files below the root directory which match the include patterns and none
+// of the exclude patterns. Files are read in lexical order of their relative path, so that the result does not depend
+// on the file system. A strict load fails on the first malformed file, a lenient load skips malformed files and
+// entries and records them in Skipped.
+func LoadCatalog(root string, options *CatalogLoadOptions) (*Catalog, error) {
+	if options == nil {
+		options = &CatalogLoadOptions{}
+	}
+
+	includes := options.Include
+	if len(includes) == 0 {
+		includes = defaultCatalogIncludes
+	}
+
+	if err := validateGlobPatterns(includes); err != nil {
+		return nil, err
+	}
+	if err := validateGlobPatterns(options.Exclude); err != nil {
+		return nil, err
+	}
+	if err := options.Strictness.Validate(); err != nil {
+		return nil, err
+	}
+	if err := options.FieldMapping.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid field mapping: %w", err)
+	}
+
+	files, err := findCatalogFiles(root, includes, options.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{
+		Files:   files,
+		Entries: []CatalogEntry{},
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+
+		if options.Strictness == CatalogLenient {
+			entries, skipped := decodeCatalogEntriesLenient(file, data)
+			catalog.Entries = append(catalog.Entries, entries...)
+			catalog.Skipped = append(catalog.Skipped, skipped...)
+			continue
+		}
+
+		catalogFile, err := DecodeCatalogFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse catalog file %s: %w", file, err)
+		}
+
+		catalog.Entries = append(catalog.Entries, newCatalogEntries(file, catalogFile.MachineImages)...)
+	}
+
+	for i := range catalog.Entries {
+		catalog.Entries[i].Version = options.FieldMapping.applyToVersion(catalog.Entries[i].Version)
+	}
+	return catalog, nil
+}
+
+// MachineImages returns the entries of the catalog grouped by image name. Images and versions keep the order in which
+// they were loaded.
+func (c *Catalog) MachineImages() []MachineImage {
+	result := []MachineImage{}
+	index := map[string]int{}
+
+	for _, entry := range c.Entries {
+		i, ok := index[entry.Name]
+		if !ok {
+			i = len(result)
+			index[entry.Name] = i
+			result = append(result, MachineImage{Name: entry.Name})
+		}
+		result[i].Versions = append(result[i].Versions, entry.Version)
+	}
+
+	return result
+}
+
+// SourcesOf returns the sources of all entries with the given image name and version.
+func (c *Catalog) SourcesOf(name, version string) []CatalogSource {
+	result := []CatalogSource{}
+	for _, entry := range c.Entries {
+		v := entry.Version.getVersion()
+		if entry.Name == name && v != nil && *v == version {
+			result = append(result, entry.Source)
+		}
+	}
+	return result
+}
+
+func newCatalogEntries(file string, machineImages []MachineImage) []CatalogEntry {
+	result := []CatalogEntry{}
+	for i, image := range machineImages {
+		for j, version := range image.Versions {
+			result = append(result, CatalogEntry{
+				OsImage: OsImage{
+					Name:    image.Name,
+					Version: version,
+				},
+				Source: CatalogSource{
+					File: file,
+					Path: fmt.Sprintf("[%d].versions[%d]", i, j),
+				},
+			})
+		}
+	}
+	return result
+}
+
+func findCatalogFiles(root string, includes, excludes []string) ([]string, error) {
+	files := []string{}
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matchesAnyGlob(includes, rel) && !matchesAnyGlob(excludes, rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("catalog directory %s does not exist", root)
+		}
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func validateGlobPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		for _, segment := range strings.Split(pattern, "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+func matchesAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(strings.Split(pattern, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches the segments of a slash separated path against the segments of a pattern. The segment "**"
+// matches zero or more path segments, all other segments are matched with path.Match.
+func matchGlob(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchGlob(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(name) == 0 {
+		return false
+	}
+
+	matched, err := path.Match(pattern[0], name[0])
+	if err != nil || !matched {
+		return false
+	}
+
+	return matchGlob(pattern[1:], name[1:])
+}
@@ -0,0 +1,278 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// catalogDocument is a catalog file decoded into yaml nodes. Edits of the document keep the comments and the order of
+// the keys of the file, which a decode and encode of the CatalogFile would lose.
+type catalogDocument struct {
+	root *yamlv3.Node
+	// images is the sequence node of the machine images.
+	images *yamlv3.Node
+}
+
+// parseCatalogDocument parses a catalog file. Files of the legacy format are converted into the current format, an
+// empty file results in an empty catalog.
+func parseCatalogDocument(data []byte) (*catalogDocument, error) {
+	// the conversions validate the content of the file
+	if _, err := DecodeCatalogFile(data); err != nil {
+		return nil, err
+	}
+
+	root := &yamlv3.Node{}
+	if err := yamlv3.Unmarshal(data, root); err != nil {
+		return nil, err
+	}
+	if root.Kind != yamlv3.DocumentNode || len(root.Content) == 0 {
+		root = &yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{mappingNode()}}
+	}
+
+	content := root.Content[0]
+	if content.Kind == yamlv3.SequenceNode {
+		// legacy list of machine images
+		file := mappingNode()
+		file.HeadComment, content.HeadComment = content.HeadComment, ""
+		file.Content = append(file.Content, scalarNode("machineImages"), content)
+		root.Content[0] = file
+		content = file
+	}
+	if content.Kind != yamlv3.MappingNode {
+		return nil, fmt.Errorf("catalog file must contain a list or an object, but found %s", content.Tag)
+	}
+
+	if mappingValue(content, "apiVersion") == nil {
+		content.Content = append([]*yamlv3.Node{
+			scalarNode("apiVersion"), scalarNode(CatalogAPIVersion),
+			scalarNode("kind"), scalarNode(CatalogKind),
+		}, content.Content...)
+	}
+	images := mappingValue(content, "machineImages")
+	if images == nil || images.Tag == "!!null" {
+		if images == nil {
+			images = &yamlv3.Node{}
+			content.Content = append(content.Content, scalarNode("machineImages"), images)
+		}
+		*images = yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq", LineComment: images.LineComment}
+	}
+	return &catalogDocument{root: root, images: images}, nil
+}
+
+// appendVersion adds the version to the image. Images which are not yet part of the catalog are added at the end.
+func (d *catalogDocument) appendVersion(name string, version MachineImageVersion) error {
+	value := &yamlv3.Node{}
+	if err := value.Encode(map[string]interface{}(version)); err != nil {
+		return err
+	}
+	// the keys are sorted, but the version number comes first like in handwritten catalogs
+	for i := 2; i+1 < len(value.Content); i += 2 {
+		if value.Content[i].Value == "version" {
+			pair := []*yamlv3.Node{value.Content[i], value.Content[i+1]}
+			copy(value.Content[2:i+2], value.Content[:i])
+			copy(value.Content, pair)
+			break
+		}
+	}
+
+	var image *yamlv3.Node
+	for _, node := range d.images.Content {
+		if n := mappingValue(node, "name"); n != nil && n.Value == name {
+			image = node
+			break
+		}
+	}
+	if image == nil {
+		image = mappingNode()
+		image.Content = append(image.Content, scalarNode("name"), scalarNode(name))
+		d.images.Content = append(d.images.Content, image)
+	}
+
+	versions := mappingValue(image, "versions")
+	if versions == nil || versions.Kind != yamlv3.SequenceNode {
+		if versions == nil {
+			versions = &yamlv3.Node{}
+			image.Content = append(image.Content, scalarNode("versions"), versions)
+		}
+		*versions = yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq"}
+	}
+	versions.Content = append(versions.Content, value)
+	return nil
+}
+
+// setVersionField sets a string field of the version at the path of a CatalogSource, e.g. "[0].versions[1]". The
+// style of an existing value is kept.
+func (d *catalogDocument) setVersionField(path, key, value string) error {
+	var i, j int
+	if _, err := fmt.Sscanf(path, "[%d].versions[%d]", &i, &j); err != nil {
+		return fmt.Errorf("invalid catalog path %s: %w", path, err)
+	}
+	if i < 0 || i >= len(d.images.Content) {
+		return fmt.Errorf("invalid catalog path %s: no image at index %d", path, i)
+	}
+	versions := mappingValue(d.images.Content[i], "versions")
+	if versions == nil || j < 0 || j >= len(versions.Content) || versions.Content[j].Kind != yamlv3.MappingNode {
+		return fmt.Errorf("invalid catalog path %s: no version at index %d", path, j)
+	}
+
+	version := versions.Content[j]
+	if node := mappingValue(version, key); node != nil {
+		node.Kind, node.Value, node.Content = yamlv3.ScalarNode, value, nil
+		if node.Tag != "!!timestamp" {
+			node.Tag = "!!str"
+		}
+		return nil
+	}
+	version.Content = append(version.Content, scalarNode(key), scalarNode(value))
+	return nil
+}
+
+// encode encodes the document as yaml, or as json with the keys in the order of the document.
+func (d *catalogDocument) encode(asJSON bool) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if asJSON {
+		if err := writeJSONNode(buf, d.root.Content[0], ""); err != nil {
+			return nil, err
+		}
+		buf.WriteString("\n")
+		return buf.Bytes(), nil
+	}
+	encoder := yamlv3.NewEncoder(buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(d.root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// editCatalogFile applies the edit to the catalog file at the path and replaces the file atomically. A file which
+// does not exist is created.
+func editCatalogFile(path string, edit func(doc *catalogDocument) error) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	doc, err := parseCatalogDocument(data)
+	if err != nil {
+		return fmt.Errorf("unable to parse catalog file %s: %w", path, err)
+	}
+	if err := edit(doc); err != nil {
+		return fmt.Errorf("unable to edit catalog file %s: %w", path, err)
+	}
+	out, err := doc.encode(filepath.Ext(path) == ".json")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, out, 0644)
+}
+
+// writeFileAtomic writes the data to a temporary file next to the path and renames it, so that readers never see a
+// partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func writeJSONNode(buf *bytes.Buffer, node *yamlv3.Node, indent string) error {
+	switch node.Kind {
+	case yamlv3.AliasNode:
+		return writeJSONNode(buf, node.Alias, indent)
+	case yamlv3.MappingNode, yamlv3.SequenceNode:
+		start, end, step := "[", "]", 1
+		if node.Kind == yamlv3.MappingNode {
+			start, end, step = "{", "}", 2
+		}
+		if len(node.Content) == 0 {
+			buf.WriteString(start + end)
+			return nil
+		}
+		buf.WriteString(start)
+		for i := 0; i < len(node.Content); i += step {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString("\n" + indent + "  ")
+			if step == 2 {
+				key, err := json.Marshal(node.Content[i].Value)
+				if err != nil {
+					return err
+				}
+				buf.Write(key)
+				buf.WriteString(": ")
+			}
+			if err := writeJSONNode(buf, node.Content[i+step-1], indent+"  "); err != nil {
+				return err
+			}
+		}
+		buf.WriteString("\n" + indent + end)
+		return nil
+	default:
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		return nil
+	}
+}
+
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func mappingNode() *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+}
+
+func scalarNode(value string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("catalog editor", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "catalog-edit")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	edit := func(file, content string, edit func(doc *catalogDocument) error) string {
+		path := filepath.Join(dir, file)
+		if len(content) > 0 {
+			Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		}
+		Expect(editCatalogFile(path, edit)).To(Succeed())
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		return string(data)
+	}
+
+	It("should keep comments and the order of keys", func() {
+		out := edit("images.yaml", `apiVersion: machineimages.landscaper.gardener.cloud/v1alpha1
+kind: MachineImageCatalog
+machineImages:
+- name: gardenlinux # the os
+  versions:
+  # maintained until march
+  - version: 318.9.0
+    expirationDate: "2022-03-01T00:00:00Z"
+    classification: supported
+`, func(doc *catalogDocument) error {
+			if err := doc.setVersionField("[0].versions[0]", "expirationDate", "2022-06-01T00:00:00Z"); err != nil {
+				return err
+			}
+			return doc.appendVersion(OsNameGardenLinux, MachineImageVersion{"version": "934.0.0", "classification": "preview"})
+		})
+		Expect(out).To(Equal(`apiVersion: machineimages.landscaper.gardener.cloud/v1alpha1
+kind: MachineImageCatalog
+machineImages:
+  - name: gardenlinux # the os
+    versions:
+      # maintained until march
+      - version: 318.9.0
+        expirationDate: "2022-06-01T00:00:00Z"
+        classification: supported
+      - version: 934.0.0
+        classification: preview
+`))
+	})
+
+	It("should convert legacy lists and create missing files", func() {
+		out := edit("legacy.yaml", "- name: ubuntu\n  versions: []\n", func(doc *catalogDocument) error {
+			return doc.appendVersion(OsNameUbuntu, MachineImageVersion{"version": "18.4.0"})
+		})
+		Expect(out).To(HavePrefix("apiVersion: " + CatalogAPIVersion + "\nkind: " + CatalogKind + "\nmachineImages:\n"))
+
+		out = edit("new/images.yaml", "", func(doc *catalogDocument) error {
+			return doc.appendVersion(OsNameUbuntu, MachineImageVersion{"version": "18.4.0"})
+		})
+		file, err := DecodeCatalogFile([]byte(out))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.MachineImages).To(Equal([]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "18.4.0"}}}}))
+	})
+
+	It("should write json files as json in the order of the keys", func() {
+		out := edit("images.json", `{"machineImages": [{"name": "ubuntu", "versions": [{"version": "18.4.0", "expirationDate": "2022-03-01T00:00:00Z", "cri": [{"name": "containerd"}]}]}], "kind": "MachineImageCatalog", "apiVersion": "machineimages.landscaper.gardener.cloud/v1alpha1"}`,
+			func(doc *catalogDocument) error {
+				return doc.setVersionField("[0].versions[0]", "expirationDate", "2022-06-01T00:00:00Z")
+			})
+		Expect(out).To(Equal(`{
+  "machineImages": [
+    {
+      "name": "ubuntu",
+      "versions": [
+        {
+          "version": "18.4.0",
+          "expirationDate": "2022-06-01T00:00:00Z",
+          "cri": [
+            {
+              "name": "containerd"
+            }
+          ]
+        }
+      ]
+    }
+  ],
+  "kind": "MachineImageCatalog",
+  "apiVersion": "machineimages.landscaper.gardener.cloud/v1alpha1"
+}
+`))
+	})
+
+	It("should not replace the file if the edit fails", func() {
+		path := filepath.Join(dir, "images.yaml")
+		Expect(ioutil.WriteFile(path, []byte("- name: ubuntu\n"), 0644)).To(Succeed())
+		err := editCatalogFile(path, func(doc *catalogDocument) error {
+			return doc.setVersionField("[0].versions[3]", "expirationDate", "2022-06-01T00:00:00Z")
+		})
+		Expect(err).To(MatchError(ContainSubstring("invalid catalog path [0].versions[3]: no version at index 3")))
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("- name: ubuntu\n"))
+		entries, err := ioutil.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,123 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// CatalogAPIVersionV1Alpha1 is the first versioned format of catalog files.
+	CatalogAPIVersionV1Alpha1 = "machineimages.landscaper.gardener.cloud/v1alpha1"
+	// CatalogAPIVersion is the format to which all catalog files are converted on load.
+	CatalogAPIVersion = CatalogAPIVersionV1Alpha1
+	// CatalogKind is the kind of versioned catalog files.
+	CatalogKind = "MachineImageCatalog"
+)
+
+// CatalogFile is the content of a catalog file in the current format.
+type CatalogFile struct {
+	APIVersion    string         `json:"apiVersion"`
+	Kind          string         `json:"kind"`
+	MachineImages []MachineImage `json:"machineImages"`
+}
+
+// catalogConversion decodes a catalog file of one format and converts it into the current format.
+type catalogConversion func(data []byte) (*CatalogFile, error)
+
+// catalogConversions contains the conversions of all supported formats. Files without apiVersion use the legacy
+// format, which is either a plain list of machine images or an object with a machineImages field.
+var catalogConversions = map[string]catalogConversion{
+	"":                        convertLegacyCatalog,
+	CatalogAPIVersionV1Alpha1: decodeV1Alpha1Catalog,
+}
+
+type catalogTypeMeta struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+}
+
+// DecodeCatalogFile decodes the content of a catalog file of any supported format and converts it into the current
+// format.
+func DecodeCatalogFile(data []byte) (*CatalogFile, error) {
+	if isYAMLList(data) {
+		return convertLegacyCatalog(data)
+	}
+
+	typeMeta := catalogTypeMeta{}
+	if err := yaml.Unmarshal(data, &typeMeta); err != nil {
+		return nil, err
+	}
+
+	if len(typeMeta.APIVersion) > 0 && typeMeta.Kind != CatalogKind {
+		return nil, fmt.Errorf("unsupported catalog kind %q, expected %q", typeMeta.Kind, CatalogKind)
+	}
+
+	conversion, ok := catalogConversions[typeMeta.APIVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported catalog apiVersion %q", typeMeta.APIVersion)
+	}
+
+	return conversion(data)
+}
+
+// convertLegacyCatalog decodes the machine images of both legacy forms with LoadMachineImagesFromYAML, so that both
+// forms accept the same machine images.
+func convertLegacyCatalog(data []byte) (*CatalogFile, error) {
+	list := data
+	if !isYAMLList(data) {
+		legacy := struct {
+			MachineImages json.RawMessage `json:"machineImages"`
+		}{}
+		if err := yaml.Unmarshal(data, &legacy); err != nil {
+			return nil, err
+		}
+		list = legacy.MachineImages
+	}
+
+	machineImages := []MachineImage{}
+	if len(list) > 0 {
+		var err error
+		if machineImages, err = LoadMachineImagesFromYAML(list, nil); err != nil {
+			return nil, err
+		}
+	}
+	if machineImages == nil {
+		machineImages = []MachineImage{}
+	}
+
+	return &CatalogFile{
+		APIVersion:    CatalogAPIVersion,
+		Kind:          CatalogKind,
+		MachineImages: machineImages,
+	}, nil
+}
+
+func decodeV1Alpha1Catalog(data []byte) (*CatalogFile, error) {
+	file := &CatalogFile{}
+	if err := yaml.UnmarshalStrict(data, file); err != nil {
+		return nil, err
+	}
+	if file.MachineImages == nil {
+		file.MachineImages = []MachineImage{}
+	}
+	return file, nil
+}
+
+// isYAMLList returns whether the first significant character of a yaml or json document starts a list.
+func isYAMLList(data []byte) bool {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] == '#' || bytes.Equal(trimmed, []byte("---")) {
+			continue
+		}
+		return trimmed[0] == '-' || trimmed[0] == '['
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("catalog format", func() {
+
+	Context("DecodeCatalogFile", func() {
+
+		expected := []MachineImage{{
+			Name:     OsNameUbuntu,
+			Versions: []MachineImageVersion{{"version": "18.4.20210415"}},
+		}}
+
+		It("should convert a legacy list", func() {
+			file, err := DecodeCatalogFile([]byte("# comment\n- name: ubuntu\n  versions:\n  - version: 18.4.20210415\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.APIVersion).To(Equal(CatalogAPIVersion))
+			Expect(file.Kind).To(Equal(CatalogKind))
+			Expect(file.MachineImages).To(Equal(expected))
+		})
+
+		It("should convert a legacy object", func() {
+			file, err := DecodeCatalogFile([]byte("machineImages:\n- name: ubuntu\n  versions:\n  - version: 18.4.20210415\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.APIVersion).To(Equal(CatalogAPIVersion))
+			Expect(file.MachineImages).To(Equal(expected))
+		})
+
+		It("should reject the same machine images in both legacy forms", func() {
+			for _, images := range []string{
+				"- name: ubuntu\n  versions: 18.4.20210415\n",
+				"- name: [ubuntu]\n",
+				"- name: ubuntu\n  versions:\n  - 18.4.20210415\n",
+			} {
+				_, listErr := DecodeCatalogFile([]byte(images))
+				Expect(listErr).To(MatchError(ContainSubstring("unable to parse machine images")), images)
+				_, objectErr := DecodeCatalogFile([]byte("machineImages:\n" + images))
+				Expect(objectErr).To(MatchError(listErr.Error()), images)
+			}
+		})
+
+		It("should convert a legacy object without machine images", func() {
+			for _, data := range []string{"other: value\n", "machineImages:\n", "machineImages: []\n"} {
+				file, err := DecodeCatalogFile([]byte(data))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(file.MachineImages).To(Equal([]MachineImage{}))
+			}
+		})
+
+		It("should decode a v1alpha1 file", func() {
+			file, err := DecodeCatalogFile([]byte(`{"apiVersion": "machineimages.landscaper.gardener.cloud/v1alpha1", "kind": "MachineImageCatalog",
+"machineImages": [{"name": "ubuntu", "versions": [{"version": "18.4.20210415"}]}]}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.MachineImages).To(Equal(expected))
+		})
+
+		It("should decode an empty file", func() {
+			file, err := DecodeCatalogFile([]byte(""))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file.MachineImages).To(BeEmpty())
+		})
+
+		It("should reject unknown versions and kinds", func() {
+			_, err := DecodeCatalogFile([]byte("apiVersion: machineimages.landscaper.gardener.cloud/v9\nkind: MachineImageCatalog\n"))
+			Expect(err).To(HaveOccurred())
+
+			_, err = DecodeCatalogFile([]byte("apiVersion: machineimages.landscaper.gardener.cloud/v1alpha1\nkind: Other\n"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject unknown fields in a v1alpha1 file", func() {
+			_, err := DecodeCatalogFile([]byte("apiVersion: machineimages.landscaper.gardener.cloud/v1alpha1\nkind: MachineImageCatalog\nimages: []\n"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build go1.23
+// +build go1.23
+
+package machineimages
+
+import "iter"
+
+// All returns an iterator over the entries of the catalog in the order in which they were loaded. In contrast to
+// MachineImages it does not allocate the grouped result, so large catalogs can be consumed lazily.
+func (c *Catalog) All() iter.Seq[CatalogEntry] {
+	return func(yield func(CatalogEntry) bool) {
+		for _, entry := range c.Entries {
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+}
+
+// VersionsOf returns an iterator over the versions of the image with the given name in the order in which they were
+// loaded.
+func (c *Catalog) VersionsOf(name string) iter.Seq[MachineImageVersion] {
+	return func(yield func(MachineImageVersion) bool) {
+		for entry := range c.All() {
+			if entry.Name == name && !yield(entry.Version) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build go1.23
+// +build go1.23
+
+package machineimages
+
+import (
+	"slices"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("catalog iterators", func() {
+
+	var catalog *Catalog
+
+	BeforeEach(func() {
+		var err error
+		catalog, err = LoadCatalog("./resources/catalog", nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should iterate over all entries", func() {
+		Expect(slices.Collect(catalog.All())).To(Equal(catalog.Entries))
+	})
+
+	It("should iterate over the versions of an image", func() {
+		versions := []string{}
+		for v := range catalog.VersionsOf(OsNameGardenLinux) {
+			versions = append(versions, *v.getVersion())
+		}
+		Expect(versions).To(HaveLen(len(catalog.MachineImages()[0].Versions)))
+		Expect(slices.Collect(catalog.VersionsOf("unknown"))).To(BeEmpty())
+	})
+
+	It("should stop when the consumer stops", func() {
+		count := 0
+		for range catalog.All() {
+			count++
+			break
+		}
+		Expect(count).To(Equal(1))
+	})
+})
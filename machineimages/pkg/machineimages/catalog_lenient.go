@@ -0,0 +1,308 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// CatalogStrictness decides how malformed catalog files and entries are handled on load.
+type CatalogStrictness string
+
+const (
+	// CatalogStrict fails the load on the first malformed file.
+	CatalogStrict = CatalogStrictness("strict")
+	// CatalogLenient skips malformed files and entries and records them in the catalog, e.g. for development
+	// landscapes in which one broken entry should not block all others.
+	CatalogLenient = CatalogStrictness("lenient")
+)
+
+// Validate returns an error if the strictness is unknown. An empty strictness is strict.
+func (s CatalogStrictness) Validate() error {
+	switch s {
+	case "", CatalogStrict, CatalogLenient:
+		return nil
+	default:
+		return fmt.Errorf("unknown catalog strictness %q, expected %s or %s", s, CatalogStrict, CatalogLenient)
+	}
+}
+
+// CatalogSkip is a malformed file or entry which was skipped by a lenient load.
+type CatalogSkip struct {
+	// File is the slash separated path of the file relative to the root directory of the catalog.
+	File string `json:"file"`
+	// Line is the line of the entry in the file. It is 0 if the line is unknown, e.g. in json files.
+	Line int `json:"line,omitempty"`
+	// Path is the position of the entry inside the file, e.g. "[0].versions[1]". It is empty if the whole file was
+	// skipped.
+	Path string `json:"path,omitempty"`
+	// Image is the name of the image of the entry, if it is known.
+	Image string `json:"image,omitempty"`
+	// Reason describes why the entry was skipped.
+	Reason string `json:"reason"`
+}
+
+func (s CatalogSkip) String() string {
+	location := s.File
+	if s.Line > 0 {
+		location += ":" + strconv.Itoa(s.Line)
+	} else if len(s.Path) > 0 {
+		location += ":" + s.Path
+	}
+	return location + ": " + s.Reason
+}
+
+// ReportSkipped reports the skipped files and entries of the catalog.
+func (c *Catalog) ReportSkipped(reporter ReportSink) {
+	for _, skip := range c.Skipped {
+		reporter.Report(ReportEntry{
+			Image:   skip.Image,
+			Reason:  ReasonInvalidCatalogEntry,
+			Message: "skipped " + skip.String(),
+		})
+	}
+}
+
+// yamlErrorLine matches the line of yaml syntax errors, e.g. "yaml: line 3: mapping values are not allowed".
+var yamlErrorLine = regexp.MustCompile(`line (\d+)`)
+
+// decodeCatalogEntriesLenient decodes the entries of a catalog file and skips those which would fail a strict load.
+func decodeCatalogEntriesLenient(file string, data []byte) ([]CatalogEntry, []CatalogSkip) {
+	catalogFile, err := DecodeCatalogFile(data)
+	if err == nil {
+		return newCatalogEntries(file, catalogFile.MachineImages), nil
+	}
+
+	skipFile := func(err error) ([]CatalogEntry, []CatalogSkip) {
+		skip := CatalogSkip{File: file, Reason: err.Error()}
+		if match := yamlErrorLine.FindStringSubmatch(err.Error()); match != nil {
+			skip.Line, _ = strconv.Atoi(match[1])
+		}
+		return []CatalogEntry{}, []CatalogSkip{skip}
+	}
+
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return skipFile(err)
+	}
+
+	// only v1alpha1 files are decoded strictly, see decodeV1Alpha1Catalog
+	strict := false
+	rawImages, isList := raw.([]interface{})
+	if !isList {
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return skipFile(err)
+		}
+		typeMeta := catalogTypeMeta{}
+		_ = yaml.Unmarshal(data, &typeMeta)
+		if _, ok := catalogConversions[typeMeta.APIVersion]; !ok || (len(typeMeta.APIVersion) > 0 && typeMeta.Kind != CatalogKind) {
+			return skipFile(err)
+		}
+		strict = len(typeMeta.APIVersion) > 0
+		if strict {
+			for key := range object {
+				if key != "apiVersion" && key != "kind" && key != "machineImages" {
+					return skipFile(err)
+				}
+			}
+		}
+		if object["machineImages"] != nil {
+			if rawImages, ok = object["machineImages"].([]interface{}); !ok {
+				return skipFile(err)
+			}
+		}
+	}
+
+	entries := []CatalogEntry{}
+	skipped := []CatalogSkip{}
+	for i, rawImage := range rawImages {
+		image, versionSkips := decodeCatalogImageLenient(rawImage, strict)
+		for _, skip := range versionSkips {
+			skip.File = file
+			skip.Line = catalogEntryLine(data, !isList, i, skip.index)
+			skip.Path = fmt.Sprintf("[%d]", i)
+			if skip.index >= 0 {
+				skip.Path += fmt.Sprintf(".versions[%d]", skip.index)
+			}
+			skipped = append(skipped, skip.CatalogSkip)
+		}
+		if image == nil {
+			continue
+		}
+
+		for j, version := range image.versions {
+			entries = append(entries, CatalogEntry{
+				OsImage: OsImage{Name: image.name, Version: version},
+				Source:  CatalogSource{File: file, Path: fmt.Sprintf("[%d].versions[%d]", i, image.indices[j])},
+			})
+		}
+	}
+	return entries, skipped
+}
+
+type lenientImage struct {
+	name     string
+	versions []MachineImageVersion
+	// indices are the positions of the versions in the file.
+	indices []int
+}
+
+type lenientSkip struct {
+	CatalogSkip
+	// index is the position of the skipped version or -1 if the whole image was skipped.
+	index int
+}
+
+// decodeCatalogImageLenient decodes an image of a catalog file. Malformed versions are skipped. If the image itself is
+// malformed, nil is returned.
+func decodeCatalogImageLenient(raw interface{}, strict bool) (*lenientImage, []lenientSkip) {
+	unmarshal := yaml.Unmarshal
+	if strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
+
+	object, _ := raw.(map[string]interface{})
+	name, _ := object["name"].(string)
+	rawVersions, _ := object["versions"].([]interface{})
+
+	skips := []lenientSkip{}
+	image := &lenientImage{name: name}
+	valid := make([]interface{}, 0, len(rawVersions))
+	for j, rawVersion := range rawVersions {
+		if _, ok := rawVersion.(map[string]interface{}); !ok && rawVersion != nil {
+			skips = append(skips, lenientSkip{
+				CatalogSkip: CatalogSkip{Image: name, Reason: fmt.Sprintf("version must be an object, got %s", jsonKind(rawVersion))},
+				index:       j,
+			})
+			continue
+		}
+		valid = append(valid, rawVersion)
+		image.indices = append(image.indices, j)
+	}
+	if object != nil && rawVersions != nil {
+		object = copyObject(object)
+		object["versions"] = valid
+		raw = object
+	}
+
+	data, err := json.Marshal(raw)
+	if err == nil {
+		decoded := MachineImage{}
+		if err = unmarshal(data, &decoded); err == nil {
+			image.versions = decoded.Versions
+			return image, skips
+		}
+	}
+	return nil, []lenientSkip{{CatalogSkip: CatalogSkip{Image: name, Reason: err.Error()}, index: -1}}
+}
+
+func copyObject(object map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		result[key] = value
+	}
+	return result
+}
+
+func jsonKind(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "list"
+	default:
+		return "number"
+	}
+}
+
+// catalogLine is a significant line of a yaml document. The content of list items starts after the dash.
+type catalogLine struct {
+	number        int
+	indent        int
+	contentIndent int
+	dash          bool
+	text          string
+}
+
+// catalogEntryLine returns the line of the version of an image in a block style yaml catalog file, or of the image
+// if version is negative. It returns 0 if the line cannot be found, e.g. in json or flow style files.
+func catalogEntryLine(data []byte, nested bool, image, version int) int {
+	lines := []catalogLine{}
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		text := strings.TrimRight(string(line), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if len(trimmed) == 0 || trimmed[0] == '#' || trimmed == "---" {
+			continue
+		}
+		l := catalogLine{number: i + 1, indent: len(text) - len(trimmed), text: trimmed}
+		l.contentIndent = l.indent
+		if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+			content := strings.TrimLeft(strings.TrimPrefix(trimmed, "-"), " ")
+			l.dash, l.text, l.contentIndent = true, content, len(text)-len(content)
+		}
+		lines = append(lines, l)
+	}
+
+	images := lines
+	if nested {
+		images = nil
+		for i, l := range lines {
+			if !l.dash && l.indent == 0 && strings.HasPrefix(l.text, "machineImages:") {
+				images = lines[i+1:]
+				break
+			}
+		}
+	}
+
+	items := yamlListItems(images)
+	if image >= len(items) {
+		return 0
+	}
+	if version < 0 {
+		return items[image][0].number
+	}
+
+	item := items[image]
+	for i, l := range item {
+		if l.contentIndent == item[0].contentIndent && (i == 0 || !l.dash) && strings.HasPrefix(l.text, "versions:") {
+			versions := yamlListItems(item[i+1:])
+			if version < len(versions) {
+				return versions[version][0].number
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+// yamlListItems splits the block list at the start of the lines into the lines of its items.
+func yamlListItems(lines []catalogLine) [][]catalogLine {
+	if len(lines) == 0 || !lines[0].dash {
+		return nil
+	}
+
+	indent := lines[0].indent
+	items := [][]catalogLine{}
+	for _, l := range lines {
+		if l.indent < indent || (l.indent == indent && !l.dash) {
+			break
+		}
+		if l.indent == indent {
+			items = append(items, []catalogLine{})
+		}
+		items[len(items)-1] = append(items[len(items)-1], l)
+	}
+	return items
+}
@@ -0,0 +1,130 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("lenient catalog", func() {
+
+	var dir string
+
+	write := func(file, content string) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, file), []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "catalog")
+		Expect(err).NotTo(HaveOccurred())
+
+		write("a-valid.yaml", `- name: gardenlinux
+  versions:
+  - version: 318.8.0
+`)
+		write("b-broken.yaml", `- name: ubuntu
+  versions:
+    - version: 18.4.0
+  classification: supported: true
+`)
+		write("c-entries.yaml", `apiVersion: machineimages.landscaper.gardener.cloud/v1alpha1
+kind: MachineImageCatalog
+machineImages:
+- name: flatcar
+  versions:
+  - version: 2905.2.0
+  - 2905.2.1
+  - version: 2905.2.2
+- name: [suse]
+  versions:
+  - version: 15.3.0
+- name: memoryone
+  region: eu
+  versions:
+  - version: 2.0.0
+`)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should fail a strict load on the first malformed file", func() {
+		_, err := LoadCatalog(dir, nil)
+		Expect(err).To(MatchError(ContainSubstring("unable to parse catalog file b-broken.yaml")))
+
+		_, err = LoadCatalog(dir, &CatalogLoadOptions{Strictness: CatalogStrict})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should skip malformed files and entries", func() {
+		catalog, err := LoadCatalog(dir, &CatalogLoadOptions{Strictness: CatalogLenient})
+		Expect(err).NotTo(HaveOccurred())
+
+		refs := []string{}
+		for _, entry := range catalog.Entries {
+			refs = append(refs, entry.Name+"/"+versionOrEmpty(entry.Version)+"@"+entry.Source.String())
+		}
+		Expect(refs).To(Equal([]string{
+			"gardenlinux/318.8.0@a-valid.yaml:[0].versions[0]",
+			"flatcar/2905.2.0@c-entries.yaml:[0].versions[0]",
+			"flatcar/2905.2.2@c-entries.yaml:[0].versions[2]",
+		}))
+
+		Expect(catalog.Skipped).To(HaveLen(4))
+		Expect(catalog.Skipped[0].File).To(Equal("b-broken.yaml"))
+		Expect(catalog.Skipped[0].Line).To(Equal(4))
+		Expect(catalog.Skipped[0].Path).To(BeEmpty())
+		Expect(catalog.Skipped[1]).To(Equal(CatalogSkip{
+			File:   "c-entries.yaml",
+			Line:   7,
+			Path:   "[0].versions[1]",
+			Image:  "flatcar",
+			Reason: "version must be an object, got string",
+		}))
+		Expect(catalog.Skipped[2].Line).To(Equal(9))
+		Expect(catalog.Skipped[2].Path).To(Equal("[1]"))
+		Expect(catalog.Skipped[3].Line).To(Equal(12))
+		Expect(catalog.Skipped[3].Image).To(Equal("memoryone"))
+		Expect(catalog.Skipped[3].Reason).To(ContainSubstring(`unknown field "region"`))
+	})
+
+	It("should report the skipped entries", func() {
+		catalog, err := LoadCatalog(dir, &CatalogLoadOptions{Strictness: CatalogLenient})
+		Expect(err).NotTo(HaveOccurred())
+
+		report := NewReport()
+		catalog.ReportSkipped(report)
+		Expect(report.Entries()).To(HaveLen(4))
+		Expect(report.Entries()[1]).To(Equal(ReportEntry{
+			Image:   "flatcar",
+			Reason:  ReasonInvalidCatalogEntry,
+			Message: "skipped c-entries.yaml:7: version must be an object, got string",
+		}))
+	})
+
+	It("should keep the path if the line is unknown", func() {
+		Expect(os.Remove(filepath.Join(dir, "b-broken.yaml"))).To(Succeed())
+		Expect(os.Remove(filepath.Join(dir, "c-entries.yaml"))).To(Succeed())
+		write("d.json", `[{"name": "gardenlinux", "versions": [{"version": "1.0.0"}, 1]}]`)
+
+		catalog, err := LoadCatalog(dir, &CatalogLoadOptions{Strictness: CatalogLenient})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(catalog.Entries).To(HaveLen(2))
+		Expect(catalog.Skipped).To(HaveLen(1))
+		Expect(catalog.Skipped[0].String()).To(Equal("d.json:[0].versions[1]: version must be an object, got number"))
+	})
+
+	It("should reject an unknown strictness", func() {
+		_, err := LoadCatalog(dir, &CatalogLoadOptions{Strictness: "loose"})
+		Expect(err).To(MatchError(`unknown catalog strictness "loose", expected strict or lenient`))
+	})
+})
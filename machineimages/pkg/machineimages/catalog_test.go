@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("catalog", func() {
+
+	Context("LoadCatalog", func() {
+
+		It("should load all yaml and json files in lexical order", func() {
+			catalog, err := LoadCatalog("./resources/catalog", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(catalog.Files).To(Equal([]string{
+				"aws/gardenlinux.yaml",
+				"aws/ubuntu.yaml",
+				"gcp/draft.yaml",
+				"gcp/gardenlinux.yml",
+			}))
+
+			machineImages := catalog.MachineImages()
+			Expect(machineImages).To(HaveLen(3))
+			Expect(machineImages[0].Name).To(Equal(OsNameGardenLinux))
+			Expect(machineImages[0].Versions).To(HaveLen(3))
+			Expect(machineImages[1].Name).To(Equal(OsNameUbuntu))
+			Expect(machineImages[2].Name).To(Equal(OsNameFlatcar))
+		})
+
+		It("should apply include and exclude patterns", func() {
+			catalog, err := LoadCatalog("./resources/catalog", &CatalogLoadOptions{
+				Include: []string{"**/gardenlinux.*", "gcp/*.yaml"},
+				Exclude: []string{"gcp/draft.yaml"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(catalog.Files).To(Equal([]string{"aws/gardenlinux.yaml", "gcp/gardenlinux.yml"}))
+		})
+
+		It("should record the source of each entry", func() {
+			catalog, err := LoadCatalog("./resources/catalog", nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(catalog.SourcesOf(OsNameGardenLinux, "318.9.0")).To(Equal([]CatalogSource{
+				{File: "aws/gardenlinux.yaml", Path: "[0].versions[1]"},
+			}))
+			Expect(catalog.SourcesOf(OsNameGardenLinux, "1.0.0")).To(BeEmpty())
+		})
+
+		It("should reject invalid patterns", func() {
+			_, err := LoadCatalog("./resources/catalog", &CatalogLoadOptions{Include: []string{"[a-"}})
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail for a missing directory", func() {
+			_, err := LoadCatalog("./resources/does-not-exist", nil)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("matchGlob", func() {
+
+		It("should match double star patterns", func() {
+			Expect(matchesAnyGlob([]string{"**/*.yaml"}, "a.yaml")).To(BeTrue())
+			Expect(matchesAnyGlob([]string{"**/*.yaml"}, "a/b/c.yaml")).To(BeTrue())
+			Expect(matchesAnyGlob([]string{"a/**/c.yaml"}, "a/c.yaml")).To(BeTrue())
+			Expect(matchesAnyGlob([]string{"a/*.yaml"}, "a/b/c.yaml")).To(BeFalse())
+		})
+	})
+})
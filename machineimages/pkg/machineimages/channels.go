@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultChannelsConfigMapKey is the data key of the channels in a ConfigMap.
+const DefaultChannelsConfigMapKey = "channels"
+
+// Names of the channels.
+const (
+	// ChannelCurrent is the catalog which the cloud profile of the shoots is built from.
+	ChannelCurrent = "current"
+	// ChannelCandidate is the most recently computed catalog, which is validated, e.g. with a cloud profile in a
+	// staging project, before it is promoted to the current channel.
+	ChannelCandidate = "candidate"
+)
+
+// Channels keep the catalogs of a blue/green profile strategy. Every computation updates the candidate, the current
+// catalog only changes with an explicit promotion.
+type Channels struct {
+	// Current are the machine images of the current channel.
+	Current []MachineImage `json:"current"`
+	// Candidate are the machine images of the candidate channel.
+	Candidate []MachineImage `json:"candidate"`
+	// PromotedAt is the time of the last promotion.
+	PromotedAt *time.Time `json:"promotedAt,omitempty"`
+}
+
+// Update sets the candidate. The first candidate also becomes the current catalog, so that a landscape has a catalog
+// before the first promotion. It returns whether the channels changed. Catalogs are compared by their json encoding,
+// so that a candidate equals the candidate which was loaded from a store, whose numbers and lists differ in their type.
+func (c *Channels) Update(candidate []MachineImage) (bool, error) {
+	if candidate == nil {
+		candidate = []MachineImage{}
+	}
+	changed := false
+	if c.Current == nil {
+		c.Current = candidate
+		changed = true
+	}
+	same, err := sameCatalog(c.Candidate, candidate)
+	if err != nil {
+		return false, err
+	}
+	if c.Candidate == nil || !same {
+		c.Candidate = candidate
+		changed = true
+	}
+	return changed, nil
+}
+
+// Promote replaces the current catalog with the candidate. It returns whether the current catalog changed. An empty
+// candidate is not promoted, as it would remove all machine images from the cloud profile.
+func (c *Channels) Promote(now time.Time) (bool, error) {
+	if c.Candidate == nil {
+		return false, fmt.Errorf("there is no %s to promote", ChannelCandidate)
+	}
+	if len(c.Candidate) == 0 {
+		return false, fmt.Errorf("the %s has no machine images", ChannelCandidate)
+	}
+	same, err := sameCatalog(c.Current, c.Candidate)
+	if err != nil {
+		return false, err
+	}
+	if same {
+		return false, nil
+	}
+	c.Current = c.Candidate
+	promotedAt := now.UTC()
+	c.PromotedAt = &promotedAt
+	return true, nil
+}
+
+// Channel returns the machine images of a channel.
+func (c *Channels) Channel(name string) ([]MachineImage, error) {
+	switch name {
+	case ChannelCurrent:
+		return c.Current, nil
+	case ChannelCandidate:
+		return c.Candidate, nil
+	default:
+		return nil, fmt.Errorf("unknown channel %q, expected %s or %s", name, ChannelCurrent, ChannelCandidate)
+	}
+}
+
+// sameCatalog returns whether the catalogs have the same json encoding.
+func sameCatalog(a, b []MachineImage) (bool, error) {
+	dataA, err := json.Marshal(a)
+	if err != nil {
+		return false, fmt.Errorf("unable to encode catalog: %w", err)
+	}
+	dataB, err := json.Marshal(b)
+	if err != nil {
+		return false, fmt.Errorf("unable to encode catalog: %w", err)
+	}
+	return bytes.Equal(dataA, dataB), nil
+}
+
+// Exports returns the current catalog as result and the candidate as the candidate result of the exports.
+func (c *Channels) Exports() *Exports {
+	return &Exports{ResultMachineImages: c.Current, ResultMachineImagesCandidate: c.Candidate}
+}
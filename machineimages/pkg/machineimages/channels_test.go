@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("channels", func() {
+
+	catalog := func(versions ...string) []MachineImage {
+		image := MachineImage{Name: OsNameGardenLinux}
+		for _, version := range versions {
+			image.Versions = append(image.Versions, MachineImageVersion{"version": version})
+		}
+		return []MachineImage{image}
+	}
+
+	It("should initialize the current channel with the first candidate", func() {
+		channels := &Channels{}
+		Expect(channels.Update(catalog("934.1.0"))).To(BeTrue())
+		Expect(channels.Current).To(Equal(catalog("934.1.0")))
+		Expect(channels.Candidate).To(Equal(catalog("934.1.0")))
+		Expect(channels.Update(catalog("934.1.0"))).To(BeFalse())
+	})
+
+	It("should compare the candidate with a loaded candidate by its encoding", func() {
+		candidate := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.1.0", "architectures": []string{"amd64"}, "cpu": 2},
+		}}}
+		channels := &Channels{}
+		Expect(channels.Update(candidate)).To(BeTrue())
+		data, err := json.Marshal(channels)
+		Expect(err).NotTo(HaveOccurred())
+
+		loaded := &Channels{}
+		Expect(json.Unmarshal(data, loaded)).To(Succeed())
+		Expect(loaded.Update(candidate)).To(BeFalse())
+		Expect(loaded.Promote(time.Now())).To(BeFalse())
+	})
+
+	It("should update an empty candidate", func() {
+		channels := &Channels{}
+		Expect(channels.Update(nil)).To(BeTrue())
+		Expect(channels.Candidate).To(Equal([]MachineImage{}))
+		Expect(channels.Update(nil)).To(BeFalse())
+	})
+
+	It("should only change the current channel with a promotion", func() {
+		channels := &Channels{}
+		Expect(channels.Update(catalog("934.1.0"))).To(BeTrue())
+		Expect(channels.Update(catalog("934.2.0", "934.1.0"))).To(BeTrue())
+		Expect(channels.Current).To(Equal(catalog("934.1.0")))
+		Expect(channels.Exports()).To(Equal(&Exports{
+			ResultMachineImages:          catalog("934.1.0"),
+			ResultMachineImagesCandidate: catalog("934.2.0", "934.1.0"),
+		}))
+
+		now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		promoted, err := channels.Promote(now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(promoted).To(BeTrue())
+		Expect(channels.Current).To(Equal(catalog("934.2.0", "934.1.0")))
+		Expect(channels.PromotedAt).To(Equal(&now))
+
+		promoted, err = channels.Promote(now.Add(time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(promoted).To(BeFalse())
+		Expect(channels.PromotedAt).To(Equal(&now))
+	})
+
+	It("should not promote without candidate", func() {
+		_, err := (&Channels{}).Promote(time.Now())
+		Expect(err).To(MatchError("there is no candidate to promote"))
+
+		_, err = (&Channels{Current: catalog("934.1.0"), Candidate: []MachineImage{}}).Promote(time.Now())
+		Expect(err).To(MatchError("the candidate has no machine images"))
+	})
+
+	It("should return the channels by name", func() {
+		channels := &Channels{}
+		Expect(channels.Update(catalog("934.1.0"))).To(BeTrue())
+		Expect(channels.Channel(ChannelCandidate)).To(Equal(catalog("934.1.0")))
+		_, err := channels.Channel("next")
+		Expect(err).To(MatchError(`unknown channel "next", expected current or candidate`))
+	})
+})
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+)
+
+// DefaultClientComponent is the component name in the User-Agent of outbound requests.
+const DefaultClientComponent = "gardener-machineimages"
+
+// TraceHeaders are the headers of the w3c trace context and baggage, which are propagated from incoming to outbound
+// requests.
+var TraceHeaders = []string{"traceparent", "tracestate", "baggage"}
+
+// ClientIdentity identifies this tool in outbound requests, so that operators of registries and apis can attribute the
+// traffic.
+type ClientIdentity struct {
+	// Component defaults to DefaultClientComponent.
+	Component string
+	// Version defaults to the version of the main module of the binary.
+	Version string
+	// Landscape is the landscape for which the requests are sent, if any.
+	Landscape string
+}
+
+// UserAgent returns the User-Agent header of the identity, e.g. "gardener-machineimages/v0.3.0 (landscape dev)".
+func (i ClientIdentity) UserAgent() string {
+	component := i.Component
+	if len(component) == 0 {
+		component = DefaultClientComponent
+	}
+	version := i.Version
+	if len(version) == 0 {
+		version = buildVersion()
+	}
+
+	userAgent := component + "/" + version
+	if len(i.Landscape) > 0 {
+		userAgent += " (landscape " + i.Landscape + ")"
+	}
+	return userAgent
+}
+
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && len(info.Main.Version) > 0 {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+type clientIdentityKey struct{}
+
+// WithClientIdentity returns a context in which outbound requests identify with the identity.
+func WithClientIdentity(ctx context.Context, identity ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, identity)
+}
+
+// ClientIdentityFromContext returns the identity of the context or the default identity.
+func ClientIdentityFromContext(ctx context.Context) ClientIdentity {
+	identity, _ := ctx.Value(clientIdentityKey{}).(ClientIdentity)
+	return identity
+}
+
+type traceHeadersKey struct{}
+
+// WithTraceHeaders returns a context in which outbound requests carry the trace headers of the given headers, usually
+// the headers of an incoming request.
+func WithTraceHeaders(ctx context.Context, header http.Header) context.Context {
+	trace := http.Header{}
+	for _, name := range TraceHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			trace[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	if len(trace) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, traceHeadersKey{}, trace)
+}
+
+// PropagateTraceHeaders wraps a handler, so that outbound requests of the handler carry the trace headers of the
+// incoming request.
+func PropagateTraceHeaders(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r.WithContext(WithTraceHeaders(r.Context(), r.Header)))
+	})
+}
+
+// NewHTTPClient returns the client for outbound requests. Its requests respect the network policy guard, identify with
+// the client identity of their context in the User-Agent header, unless it is set explicitly, and carry the trace
+// headers of their context. If base is nil, the transport of SetDefaultTransport is used.
+func NewHTTPClient(base http.RoundTripper) *http.Client {
+	return &http.Client{Transport: NewClientTransport(base)}
+}
+
+// NewClientTransport returns the transport of NewHTTPClient, for clients which need further settings, e.g. a timeout.
+func NewClientTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = &defaultTransportProxy{}
+	}
+	return &identityTransport{base: NewGuardedTransport(base)}
+}
+
+// defaultTransportProxy sends requests with the default transport at the time of the request, so that clients which
+// are created before SetDefaultTransport use it as well.
+type defaultTransportProxy struct{}
+
+func (t *defaultTransportProxy) RoundTrip(req *http.Request) (*http.Response, error) {
+	return getDefaultTransport().RoundTrip(req)
+}
+
+type identityTransport struct {
+	base http.RoundTripper
+}
+
+func (t *identityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace, _ := req.Context().Value(traceHeadersKey{}).(http.Header)
+	if len(req.Header.Get("User-Agent")) > 0 && len(trace) == 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	// round trippers must not modify the request
+	req = req.Clone(req.Context())
+	if len(req.Header.Get("User-Agent")) == 0 {
+		req.Header.Set("User-Agent", ClientIdentityFromContext(req.Context()).UserAgent())
+	}
+	for name, values := range trace {
+		if len(req.Header.Values(name)) == 0 {
+			req.Header[name] = append([]string{}, values...)
+		}
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("client", func() {
+
+	var (
+		server  *httptest.Server
+		headers http.Header
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers = r.Header.Clone()
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	get := func(ctx context.Context, header http.Header) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		resp, err := NewHTTPClient(nil).Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+	}
+
+	It("should identify with the client identity of the context", func() {
+		get(context.Background(), nil)
+		Expect(headers.Get("User-Agent")).To(HavePrefix(DefaultClientComponent + "/"))
+
+		ctx := WithClientIdentity(context.Background(), ClientIdentity{Component: "catalog", Version: "v1.0.0", Landscape: "dev"})
+		get(ctx, nil)
+		Expect(headers.Get("User-Agent")).To(Equal("catalog/v1.0.0 (landscape dev)"))
+
+		get(ctx, http.Header{"User-Agent": {"explicit"}})
+		Expect(headers.Get("User-Agent")).To(Equal("explicit"))
+	})
+
+	It("should propagate the trace headers of incoming requests", func() {
+		handler := PropagateTraceHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			get(r.Context(), nil)
+		}))
+		incoming := httptest.NewRequest(http.MethodGet, "/", nil)
+		incoming.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+		incoming.Header.Set("Authorization", "Bearer token")
+		handler.ServeHTTP(httptest.NewRecorder(), incoming)
+
+		Expect(headers.Get("traceparent")).To(Equal("00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"))
+		Expect(headers.Get("Authorization")).To(BeEmpty())
+	})
+
+	It("should respect the network policy guard", func() {
+		req, err := http.NewRequestWithContext(WithNetworkPolicyGuard(context.Background()), http.MethodGet, server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = NewHTTPClient(nil).Do(req)
+		Expect(IsNetworkAccessDenied(err)).To(BeTrue())
+	})
+})
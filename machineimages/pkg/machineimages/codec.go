@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LoadOptions configures the loading of machine images.
+type LoadOptions struct {
+	// Strict rejects unknown fields of machine images and imports, duplicate keys in yaml and unknown fields of
+	// versions, see KnownVersionFields.
+	Strict bool
+	// KnownFields are further fields of versions which Strict accepts.
+	KnownFields []string
+}
+
+// LoadMachineImagesFromYAML decodes a yaml list of machine images. Unknown fields of versions are reported as a
+// *ValidationError of the machine images with the field paths of the fields, e.g. "machineImages[0].versions[1].digest".
+func LoadMachineImagesFromYAML(data []byte, options *LoadOptions) ([]MachineImage, error) {
+	if options == nil {
+		options = &LoadOptions{}
+	}
+	unmarshal := yaml.Unmarshal
+	if options.Strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
+
+	images := []MachineImage{}
+	if err := unmarshal(data, &images); err != nil {
+		return nil, fmt.Errorf("unable to parse machine images: %w", err)
+	}
+	if err := options.checkFields(images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// LoadMachineImagesFromJSON decodes a json list of machine images like LoadMachineImagesFromYAML. Data after the list
+// is rejected.
+func LoadMachineImagesFromJSON(data []byte, options *LoadOptions) ([]MachineImage, error) {
+	if options == nil {
+		options = &LoadOptions{}
+	}
+
+	images := []MachineImage{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if options.Strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(&images); err != nil {
+		return nil, fmt.Errorf("unable to parse machine images: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("unable to parse machine images: unexpected data after the list")
+	}
+	if err := options.checkFields(images); err != nil {
+		return nil, err
+	}
+	return images, nil
+}
+
+// LoadImports decodes yaml or json imports. With Strict, the versions of the image lists without field mapping must
+// only have the known fields of the options of the imports, e.g. also the artifact reference field of the artifact
+// probe. The imports are not validated, see ValidateImports.
+func LoadImports(data []byte, options *LoadOptions) (*Imports, error) {
+	if options == nil {
+		options = &LoadOptions{}
+	}
+	unmarshal := yaml.Unmarshal
+	if options.Strict {
+		unmarshal = yaml.UnmarshalStrict
+	}
+
+	imports := &Imports{}
+	if err := unmarshal(data, imports); err != nil {
+		return nil, fmt.Errorf("unable to parse imports: %w", err)
+	}
+	if !options.Strict {
+		return imports, nil
+	}
+
+	known := append(imports.knownVersionFields(), options.KnownFields...)
+	lists := []*[]MachineImage{&imports.MachineImages, &imports.MachineImagesLs, &imports.MachineImagesProvider,
+		&imports.MachineImagesProviderLs}
+	problems := []string{}
+	for i, field := range MachineImageListFields {
+		if imports.FieldMappings[field] != nil {
+			continue
+		}
+		problems = append(problems, unknownVersionFields(field, *lists[i], known)...)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return imports, nil
+}
+
+// MarshalMachineImagesYAML encodes the machine images as yaml. The keys of versions are sorted, so that equal machine
+// images always yield the same document.
+func MarshalMachineImagesYAML(images []MachineImage) ([]byte, error) {
+	return yaml.Marshal(canonicalMachineImages(images))
+}
+
+// MarshalMachineImagesJSON encodes the machine images as indented json with sorted keys like
+// MarshalMachineImagesYAML.
+func MarshalMachineImagesJSON(images []MachineImage) ([]byte, error) {
+	data, err := json.MarshalIndent(canonicalMachineImages(images), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// canonicalMachineImages encodes nil lists like empty lists. The json encoding sorts the keys of maps.
+func canonicalMachineImages(images []MachineImage) []MachineImage {
+	if images == nil {
+		return []MachineImage{}
+	}
+	return images
+}
+
+func (o *LoadOptions) checkFields(images []MachineImage) error {
+	if !o.Strict {
+		return nil
+	}
+	if problems := unknownVersionFields("machineImages", images, append(KnownVersionFields(), o.KnownFields...)); len(problems) > 0 {
+		return &ValidationError{Problems: problems, Subject: "machine images"}
+	}
+	return nil
+}
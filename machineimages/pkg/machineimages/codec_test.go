@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("machine images codec", func() {
+
+	images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+		{"version": "934.7.0", "classification": "supported", "cri": []interface{}{map[string]interface{}{"name": "containerd"}}},
+	}}}
+
+	It("should load yaml and json machine images", func() {
+		yamlImages, err := LoadMachineImagesFromYAML([]byte(`
+- name: gardenlinux
+  versions:
+  - version: 934.7.0
+    classification: supported
+    cri:
+    - name: containerd
+`), &LoadOptions{Strict: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(yamlImages).To(Equal(images))
+
+		jsonImages, err := LoadMachineImagesFromJSON([]byte(`[{"name": "gardenlinux", "versions": [
+			{"version": "934.7.0", "classification": "supported", "cri": [{"name": "containerd"}]}]}]`), &LoadOptions{Strict: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(jsonImages).To(Equal(images))
+	})
+
+	It("should reject unknown fields only in strict mode", func() {
+		data := []byte(`[{"name": "gardenlinux", "versions": [{"version": "934.7.0", "clasification": "supported"}]}]`)
+
+		_, err := LoadMachineImagesFromJSON(data, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = LoadMachineImagesFromJSON(data, &LoadOptions{Strict: true})
+		Expect(err).To(MatchError("invalid machine images: machineImages[0].versions[0].clasification: unknown field"))
+		_, err = LoadMachineImagesFromYAML(data, &LoadOptions{Strict: true})
+		Expect(err).To(MatchError("invalid machine images: machineImages[0].versions[0].clasification: unknown field"))
+		_, err = LoadMachineImagesFromYAML(data, &LoadOptions{Strict: true, KnownFields: []string{"clasification"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = LoadMachineImagesFromJSON([]byte(`[{"name": "gardenlinux", "version": []}]`), &LoadOptions{Strict: true})
+		Expect(err).To(MatchError(ContainSubstring(`unknown field "version"`)))
+		_, err = LoadMachineImagesFromYAML([]byte("- name: gardenlinux\n  name: ubuntu\n"), &LoadOptions{Strict: true})
+		Expect(err).To(MatchError(ContainSubstring("already set")))
+	})
+
+	It("should reject data after the json list", func() {
+		_, err := LoadMachineImagesFromJSON([]byte(`[] []`), nil)
+		Expect(err).To(MatchError("unable to parse machine images: unexpected data after the list"))
+	})
+
+	It("should load strict imports with the known fields of their options", func() {
+		data := []byte(`
+machineImages:
+- name: gardenlinux
+  versions:
+  - version: 934.7.0
+    artifact: ghcr.io/gardenlinux/gardenlinux:934.7.0
+machineImagesLs:
+- name: gardenlinux
+  versions:
+  - version: 934.7.0
+    digest: sha256:abc
+artifactProbe:
+  referenceField: artifact
+`)
+		_, err := LoadImports(data, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = LoadImports(data, &LoadOptions{Strict: true})
+		Expect(err).To(MatchError("invalid imports: machineImagesLs[0].versions[0].digest: unknown field"))
+
+		imports, err := LoadImports(append(data, []byte("fieldMappings:\n  machineImagesLs:\n    fields: {}\n")...), &LoadOptions{Strict: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imports.MachineImagesLs).To(HaveLen(1))
+
+		_, err = LoadImports([]byte("machineImage: []\n"), &LoadOptions{Strict: true})
+		Expect(err).To(MatchError(ContainSubstring(`unknown field "machineImage"`)))
+	})
+
+	It("should marshal machine images with sorted keys", func() {
+		data, err := MarshalMachineImagesYAML(images)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`- name: gardenlinux
+  versions:
+  - classification: supported
+    cri:
+    - name: containerd
+    version: 934.7.0
+`))
+
+		data, err = MarshalMachineImagesJSON(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("[]\n"))
+
+		data, err = MarshalMachineImagesJSON(images)
+		Expect(err).NotTo(HaveOccurred())
+		loaded, err := LoadMachineImagesFromJSON(data, &LoadOptions{Strict: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(images))
+	})
+})
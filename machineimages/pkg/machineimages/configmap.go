@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultConfigMapKey is the data key of the machine images in a ConfigMap if no key is configured.
+const DefaultConfigMapKey = "machineImages"
+
+// ConfigMapOutput configures that the machine images are written into a ConfigMap instead of being exported directly.
+type ConfigMapOutput struct {
+	Name      string `json:"name" yaml:"name"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Key is the data key of the machine images. Defaults to DefaultConfigMapKey.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// ObjectMeta contains the metadata of the generated kubernetes objects.
+type ObjectMeta struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ConfigMap is a kubernetes ConfigMap.
+type ConfigMap struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   ObjectMeta        `json:"metadata"`
+	Data       map[string]string `json:"data"`
+}
+
+// ConfigMapKeyReference references a data key of a ConfigMap.
+type ConfigMapKeyReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Key       string `json:"key"`
+}
+
+// MachineImagesReference replaces the machine images of a CloudProfile for tooling which resolves the machine images
+// from a ConfigMap.
+type MachineImagesReference struct {
+	ConfigMapRef ConfigMapKeyReference `json:"configMapRef"`
+}
+
+// NewMachineImagesConfigMap returns a ConfigMap which contains the machine images and a reference to it.
+func NewMachineImagesConfigMap(machineImages []MachineImage, output *ConfigMapOutput) (*ConfigMap, *MachineImagesReference, error) {
+	if output == nil || len(output.Name) == 0 {
+		return nil, nil, errors.New("a config map name must be provided")
+	}
+
+	key := output.Key
+	if len(key) == 0 {
+		key = DefaultConfigMapKey
+	}
+
+	data, err := MarshalMachineImagesYAML(machineImages)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configMap := &ConfigMap{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata: ObjectMeta{
+			Name:      output.Name,
+			Namespace: output.Namespace,
+		},
+		Data: map[string]string{
+			key: string(data),
+		},
+	}
+
+	reference := &MachineImagesReference{
+		ConfigMapRef: ConfigMapKeyReference{
+			Name:      output.Name,
+			Namespace: output.Namespace,
+			Key:       key,
+		},
+	}
+
+	return configMap, reference, nil
+}
+
+// MachineImagesFromConfigMap resolves the machine images of a reference from the given ConfigMap.
+func MachineImagesFromConfigMap(configMap *ConfigMap, reference *MachineImagesReference) ([]MachineImage, error) {
+	ref := reference.ConfigMapRef
+	if configMap.Metadata.Name != ref.Name || configMap.Metadata.Namespace != ref.Namespace {
+		return nil, fmt.Errorf("config map %s/%s does not match the reference to %s/%s",
+			configMap.Metadata.Namespace, configMap.Metadata.Name, ref.Namespace, ref.Name)
+	}
+
+	data, ok := configMap.Data[ref.Key]
+	if !ok {
+		return nil, fmt.Errorf("config map %s/%s has no key %s", ref.Namespace, ref.Name, ref.Key)
+	}
+
+	return LoadMachineImagesFromYAML([]byte(data), nil)
+}
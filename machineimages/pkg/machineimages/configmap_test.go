@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("config map output", func() {
+
+	machineImages := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.9.0"}}},
+	}
+
+	It("should write the machine images into a config map", func() {
+		configMap, reference, err := NewMachineImagesConfigMap(machineImages, &ConfigMapOutput{Name: "images", Namespace: "garden"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configMap.Metadata).To(Equal(ObjectMeta{Name: "images", Namespace: "garden"}))
+		Expect(configMap.Data).To(HaveKey(DefaultConfigMapKey))
+		Expect(reference.ConfigMapRef).To(Equal(ConfigMapKeyReference{Name: "images", Namespace: "garden", Key: DefaultConfigMapKey}))
+
+		resolved, err := MachineImagesFromConfigMap(configMap, reference)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resolved).To(Equal(machineImages))
+	})
+
+	It("should reject a missing name", func() {
+		_, _, err := NewMachineImagesConfigMap(machineImages, &ConfigMapOutput{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject a config map which does not match the reference", func() {
+		configMap, reference, err := NewMachineImagesConfigMap(machineImages, &ConfigMapOutput{Name: "images", Key: "data"})
+		Expect(err).NotTo(HaveOccurred())
+
+		reference.ConfigMapRef.Key = "other"
+		_, err = MachineImagesFromConfigMap(configMap, reference)
+		Expect(err).To(HaveOccurred())
+
+		reference.ConfigMapRef.Name = "other"
+		_, err = MachineImagesFromConfigMap(configMap, reference)
+		Expect(err).To(HaveOccurred())
+	})
+})
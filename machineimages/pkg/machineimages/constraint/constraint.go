@@ -0,0 +1,315 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package constraint implements the version constraint language used by the machine images library, so that external
+// tools can validate and evaluate constraints consistently with it.
+//
+// A constraint consists of alternatives separated by "||". An alternative is a list of terms separated by whitespace
+// or commas, which must all be satisfied. A term is an operator followed by a version, e.g. ">=576.0 <600 || =318.9.0".
+// Supported operators are =, !=, >, >=, <, <=, ~ (same minor line and not lower) and ^ (same major line and not lower).
+// A term without operator is an exact match.
+package constraint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Operator is the comparison of a term.
+type Operator string
+
+const (
+	OperatorEqual          = Operator("=")
+	OperatorNotEqual       = Operator("!=")
+	OperatorGreater        = Operator(">")
+	OperatorGreaterOrEqual = Operator(">=")
+	OperatorLess           = Operator("<")
+	OperatorLessOrEqual    = Operator("<=")
+	OperatorTilde          = Operator("~")
+	OperatorCaret          = Operator("^")
+)
+
+// operators are ordered such that no operator is preceded by one of its prefixes.
+var operators = []Operator{
+	OperatorNotEqual,
+	OperatorGreaterOrEqual,
+	OperatorLessOrEqual,
+	OperatorEqual,
+	OperatorGreater,
+	OperatorLess,
+	OperatorTilde,
+	OperatorCaret,
+}
+
+// Term is a single comparison of a constraint.
+type Term struct {
+	Operator Operator
+	Version  string
+}
+
+func (t Term) String() string {
+	return string(t.Operator) + t.Version
+}
+
+// Matches returns whether the version satisfies the term.
+func (t Term) Matches(version string) bool {
+	c := CompareVersions(version, t.Version)
+	switch t.Operator {
+	case OperatorEqual:
+		return c == 0
+	case OperatorNotEqual:
+		return c != 0
+	case OperatorGreater:
+		return c > 0
+	case OperatorGreaterOrEqual:
+		return c >= 0
+	case OperatorLess:
+		return c < 0
+	case OperatorLessOrEqual:
+		return c <= 0
+	case OperatorTilde:
+		return c >= 0 && hasSamePrefix(version, t.Version, tildePrefixLength(t.Version))
+	case OperatorCaret:
+		return c >= 0 && hasSamePrefix(version, t.Version, 1)
+	default:
+		return false
+	}
+}
+
+// Constraint is a parsed version constraint.
+type Constraint struct {
+	alternatives [][]Term
+}
+
+// Parse parses a version constraint.
+func Parse(constraint string) (*Constraint, error) {
+	result := &Constraint{}
+
+	for _, alternative := range strings.Split(constraint, "||") {
+		terms, err := parseAlternative(alternative)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", constraint, err)
+		}
+		result.alternatives = append(result.alternatives, terms)
+	}
+
+	return result, nil
+}
+
+// MustParse parses a version constraint and panics if it is invalid.
+func MustParse(constraint string) *Constraint {
+	c, err := Parse(constraint)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Evaluate parses the constraint and returns whether the version satisfies it.
+func Evaluate(constraint, version string) (bool, error) {
+	c, err := Parse(constraint)
+	if err != nil {
+		return false, err
+	}
+	return c.Evaluate(version), nil
+}
+
+// Evaluate returns whether the version satisfies the constraint.
+func (c *Constraint) Evaluate(version string) bool {
+	for _, alternative := range c.alternatives {
+		if matchesAll(alternative, version) {
+			return true
+		}
+	}
+	return false
+}
+
+// Alternatives returns the terms of the constraint grouped by alternative.
+func (c *Constraint) Alternatives() [][]Term {
+	result := make([][]Term, len(c.alternatives))
+	for i, alternative := range c.alternatives {
+		result[i] = append([]Term{}, alternative...)
+	}
+	return result
+}
+
+// String returns the normalized form of the constraint.
+func (c *Constraint) String() string {
+	alternatives := make([]string, len(c.alternatives))
+	for i, alternative := range c.alternatives {
+		terms := make([]string, len(alternative))
+		for j, term := range alternative {
+			terms[j] = term.String()
+		}
+		alternatives[i] = strings.Join(terms, " ")
+	}
+	return strings.Join(alternatives, " || ")
+}
+
+// Intersects returns whether a version satisfies both constraints. The candidates are the lowest version "0" and,
+// for every term of both constraints, its version and the version right above it, which covers all alternatives
+// whose terms have numeric versions.
+func (c *Constraint) Intersects(other *Constraint) bool {
+	candidates := []string{"0"}
+	for _, constraint := range []*Constraint{c, other} {
+		for _, alternative := range constraint.alternatives {
+			for _, term := range alternative {
+				candidates = append(candidates, term.Version, term.Version+".0")
+			}
+		}
+	}
+	for _, candidate := range candidates {
+		if c.Evaluate(candidate) && other.Evaluate(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// TermResult is the evaluation result of a single term.
+type TermResult struct {
+	Term      Term `json:"term"`
+	Satisfied bool `json:"satisfied"`
+}
+
+// Explanation describes why a version does or does not satisfy a constraint.
+type Explanation struct {
+	Constraint   string         `json:"constraint"`
+	Version      string         `json:"version"`
+	Satisfied    bool           `json:"satisfied"`
+	Alternatives [][]TermResult `json:"alternatives"`
+}
+
+// Explain evaluates all terms of the constraint for the version.
+func (c *Constraint) Explain(version string) *Explanation {
+	explanation := &Explanation{
+		Constraint: c.String(),
+		Version:    version,
+	}
+
+	for _, alternative := range c.alternatives {
+		results := make([]TermResult, len(alternative))
+		for i, term := range alternative {
+			results[i] = TermResult{Term: term, Satisfied: term.Matches(version)}
+		}
+		explanation.Alternatives = append(explanation.Alternatives, results)
+	}
+	explanation.Satisfied = c.Evaluate(version)
+
+	return explanation
+}
+
+func (e *Explanation) String() string {
+	verdict := "satisfies"
+	if !e.Satisfied {
+		verdict = "does not satisfy"
+	}
+
+	alternatives := make([]string, len(e.Alternatives))
+	for i, alternative := range e.Alternatives {
+		terms := make([]string, len(alternative))
+		for j, result := range alternative {
+			mark := "ok"
+			if !result.Satisfied {
+				mark = "failed"
+			}
+			terms[j] = fmt.Sprintf("%s %s", result.Term, mark)
+		}
+		alternatives[i] = strings.Join(terms, ", ")
+	}
+
+	return fmt.Sprintf("%s %s %q (%s)", e.Version, verdict, e.Constraint, strings.Join(alternatives, " | "))
+}
+
+func parseAlternative(alternative string) ([]Term, error) {
+	fields := strings.FieldsFunc(alternative, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("empty alternative")
+	}
+
+	terms := []Term{}
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if isOperator(field) && i+1 < len(fields) {
+			i++
+			field += fields[i]
+		}
+
+		term, err := parseTerm(field)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+
+	return terms, nil
+}
+
+func parseTerm(s string) (Term, error) {
+	op := OperatorEqual
+	for _, candidate := range operators {
+		if strings.HasPrefix(s, string(candidate)) {
+			op = candidate
+			s = s[len(candidate):]
+			break
+		}
+	}
+
+	if len(s) == 0 {
+		return Term{}, fmt.Errorf("missing version after operator %s", op)
+	}
+	if !isDigit(s[0]) {
+		return Term{}, fmt.Errorf("version %q must start with a digit", s)
+	}
+	for _, candidate := range operators {
+		if strings.Contains(s, string(candidate)) && candidate != OperatorEqual {
+			return Term{}, fmt.Errorf("unexpected operator in version %q", s)
+		}
+	}
+
+	return Term{Operator: op, Version: s}, nil
+}
+
+func isOperator(s string) bool {
+	for _, op := range operators {
+		if s == string(op) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAll(terms []Term, version string) bool {
+	for _, term := range terms {
+		if !term.Matches(version) {
+			return false
+		}
+	}
+	return true
+}
+
+// tildePrefixLength returns the number of dot separated parts which must be equal for the tilde operator: the major
+// and minor part if the version has a minor part, otherwise the major part.
+func tildePrefixLength(version string) int {
+	if strings.Contains(version, ".") {
+		return 2
+	}
+	return 1
+}
+
+func hasSamePrefix(a, b string, n int) bool {
+	pa := strings.Split(a, ".")
+	pb := strings.Split(b, ".")
+	if len(pa) < n || len(pb) < n {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		if CompareVersions(pa[i], pb[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
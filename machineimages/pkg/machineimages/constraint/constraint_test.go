@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package constraint
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("constraint", func() {
+
+	Context("Parse", func() {
+
+		It("should normalize the constraint", func() {
+			c, err := Parse(">= 576.0, <600 || 318.9.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.String()).To(Equal(">=576.0 <600 || =318.9.0"))
+			Expect(c.Alternatives()).To(Equal([][]Term{
+				{{Operator: OperatorGreaterOrEqual, Version: "576.0"}, {Operator: OperatorLess, Version: "600"}},
+				{{Operator: OperatorEqual, Version: "318.9.0"}},
+			}))
+		})
+
+		It("should reject invalid constraints", func() {
+			for _, invalid := range []string{"", ">=", "1.0 ||", ">=abc", ">=1.0<2.0"} {
+				_, err := Parse(invalid)
+				Expect(err).To(HaveOccurred(), invalid)
+			}
+		})
+	})
+
+	Context("Evaluate", func() {
+
+		It("should evaluate all operators", func() {
+			cases := map[string]map[string]bool{
+				"=1.2.3":          {"1.2.3": true, "1.2.4": false},
+				"!=1.2.3":         {"1.2.3": false, "1.2.4": true},
+				">1.2.3":          {"1.2.3": false, "1.10.0": true},
+				">=1.2.3":         {"1.2.3": true, "1.2.2": false},
+				"<600":            {"576.1.0": true, "600": false},
+				"<=600":           {"600": true, "601": false},
+				"~318.9":          {"318.9.7": true, "318.10.0": false, "318.8.0": false},
+				"^318.9.0":        {"318.10.0": true, "319.0.0": false},
+				">=576.0 <600":    {"576.4.0": true, "600.0.0": false},
+				"<1.0 || >=2.0.0": {"0.9": true, "1.5": false, "2.0.0": true},
+			}
+			for constraint, versions := range cases {
+				for version, expected := range versions {
+					matched, err := Evaluate(constraint, version)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(matched).To(Equal(expected), constraint+" "+version)
+				}
+			}
+		})
+	})
+
+	Context("Intersects", func() {
+
+		It("should return whether a version satisfies both constraints", func() {
+			cases := map[[2]string]bool{
+				{"^576", "~576.2"}:        true,
+				{">=576.0 <600", "^576"}:  true,
+				{">576", "<577"}:          true,
+				{"<576", "<600"}:          true,
+				{"=576.2.0", "!=576.2.0"}: false,
+				{"^576", "^318"}:          false,
+				{"~576.1", "~576.2"}:      false,
+				{"<576 || >=600", "^576"}: false,
+				{"<576 || >=600", "^600"}: true,
+			}
+			for constraints, expected := range cases {
+				a, b := MustParse(constraints[0]), MustParse(constraints[1])
+				Expect(a.Intersects(b)).To(Equal(expected), constraints[0]+" "+constraints[1])
+				Expect(b.Intersects(a)).To(Equal(expected), constraints[1]+" "+constraints[0])
+			}
+		})
+	})
+
+	Context("Explain", func() {
+
+		It("should explain the result of every term", func() {
+			explanation := MustParse(">=576.0 <600 || =318.9.0").Explain("318.9.0")
+			Expect(explanation.Satisfied).To(BeTrue())
+			Expect(explanation.Alternatives).To(Equal([][]TermResult{
+				{
+					{Term: Term{Operator: OperatorGreaterOrEqual, Version: "576.0"}, Satisfied: false},
+					{Term: Term{Operator: OperatorLess, Version: "600"}, Satisfied: true},
+				},
+				{
+					{Term: Term{Operator: OperatorEqual, Version: "318.9.0"}, Satisfied: true},
+				},
+			}))
+			Expect(explanation.String()).To(Equal(
+				`318.9.0 satisfies ">=576.0 <600 || =318.9.0" (>=576.0 failed, <600 ok | =318.9.0 ok)`))
+		})
+	})
+})
@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package constraint
+
+import (
+	"strconv"
+)
+
+// CompareVersions compares two version strings by splitting them into runs of digits and non-digits. Runs of digits
+// are compared numerically, all other runs lexically. It returns -1, 0 or 1 if a is lower, equal or higher than b.
+func CompareVersions(a, b string) int {
+	ta := tokenizeVersion(a)
+	tb := tokenizeVersion(b)
+
+	for i := 0; i < len(ta) && i < len(tb); i++ {
+		if c := compareVersionTokens(ta[i], tb[i]); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(ta) < len(tb):
+		return -1
+	case len(ta) > len(tb):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareVersionTokens(a, b string) int {
+	na, errA := strconv.ParseUint(a, 10, 64)
+	nb, errB := strconv.ParseUint(b, 10, 64)
+
+	switch {
+	case errA == nil && errB == nil:
+		if na < nb {
+			return -1
+		} else if na > nb {
+			return 1
+		}
+		return 0
+	case errA == nil:
+		// numbers are lower than other tokens
+		return -1
+	case errB == nil:
+		return 1
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func tokenizeVersion(v string) []string {
+	tokens := []string{}
+	start := 0
+	for i := 1; i <= len(v); i++ {
+		if i == len(v) || isDigit(v[i]) != isDigit(v[i-1]) {
+			tokens = append(tokens, v[start:i])
+			start = i
+		}
+	}
+	return tokens
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+)
+
+type loggerContextKey struct{}
+
+type reporterContextKey struct{}
+
+// NewContext returns a context which carries the logger and the reporter, so that nested stages of the computation can
+// log and report without additional parameters. A nil reporter discards all entries.
+func NewContext(ctx context.Context, log logr.Logger, reporter ReportSink) context.Context {
+	if log != nil {
+		ctx = context.WithValue(ctx, loggerContextKey{}, log)
+	}
+	if reporter != nil {
+		ctx = context.WithValue(ctx, reporterContextKey{}, reporter)
+	}
+	return ctx
+}
+
+// FromContext returns the logger and the reporter of the context. If the context carries none of them, a logger and a
+// reporter are returned which discard everything.
+func FromContext(ctx context.Context) (logr.Logger, ReportSink) {
+	return LoggerFromContext(ctx), ReporterFromContext(ctx)
+}
+
+// LoggerFromContext returns the logger of the context or a logger which discards everything.
+func LoggerFromContext(ctx context.Context) logr.Logger {
+	if log, ok := ctx.Value(loggerContextKey{}).(logr.Logger); ok {
+		return log
+	}
+	return logr.Discard()
+}
+
+// ReporterFromContext returns the reporter of the context or a reporter which discards everything.
+func ReporterFromContext(ctx context.Context) ReportSink {
+	if reporter, ok := ctx.Value(reporterContextKey{}).(ReportSink); ok {
+		return reporter
+	}
+	return discardReporter{}
+}
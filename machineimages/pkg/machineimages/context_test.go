@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("context", func() {
+
+	It("should return discarding defaults for an empty context", func() {
+		log, reporter := FromContext(context.Background())
+		Expect(log).NotTo(BeNil())
+		Expect(reporter).To(Equal(discardReporter{}))
+	})
+
+	It("should return the logger and reporter of the context", func() {
+		log := logr.Discard().WithName("test")
+		report := NewReport()
+
+		ctx := NewContext(context.Background(), log, report)
+		Expect(LoggerFromContext(ctx)).To(BeIdenticalTo(log))
+		Expect(ReporterFromContext(ctx)).To(BeIdenticalTo(report))
+	})
+
+	It("should report dropped versions of the computation", func() {
+		report := NewReport()
+		_, err := ComputeMachineImagesWithOptions(
+			context.Background(),
+			logr.Discard(),
+			[]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0"}, {"version": "2.0.0"}}}},
+			nil,
+			[]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0"}, {"version": "2.0.0"}}}},
+			nil,
+			nil,
+			nil,
+			nil,
+			&ComputeMachineImagesOptions{
+				MinVersions: map[string]string{OsNameUbuntu: "2.0.0"},
+				Reporter:    report,
+			},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Entries()).To(ConsistOf(ReportEntry{
+			Image:   OsNameUbuntu,
+			Version: "1.0.0",
+			Reason:  ReasonBelowMinVersion,
+			Message: "version is lower than the minimum version 2.0.0",
+		}))
+	})
+})
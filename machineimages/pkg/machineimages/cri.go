@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KnownCRINames are the container runtime interfaces which gardener supports in the cri field of versions.
+var KnownCRINames = []string{"containerd", "docker"}
+
+// CRIMergeStrategy determines how the cri lists of a landscape and an LSS version are merged.
+type CRIMergeStrategy string
+
+const (
+	// CRIMergeStrategyOverride takes the cri list of the landscape version, if it has one.
+	CRIMergeStrategyOverride = CRIMergeStrategy("override")
+	// CRIMergeStrategyUnion merges the cri lists by name. The container runtimes of CRIs in both lists are merged by
+	// type. CRIs and container runtimes of the LSS version come first.
+	CRIMergeStrategyUnion = CRIMergeStrategy("union")
+)
+
+// Validate returns an error if the strategy is unknown. An empty strategy is CRIMergeStrategyOverride.
+func (s CRIMergeStrategy) Validate() error {
+	switch s {
+	case "", CRIMergeStrategyOverride, CRIMergeStrategyUnion:
+		return nil
+	}
+	return fmt.Errorf("cri merge strategy does not exist %s", s)
+}
+
+// validateCRI returns an error if the cri field of the version is not a list of CRIs with a known, unique name and
+// container runtimes with a unique type. Versions without cri field are valid.
+func validateCRI(version MachineImageVersion) error {
+	value, ok := version["cri"]
+	if !ok {
+		return nil
+	}
+	entries, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("cri must be a list, got %T", value)
+	}
+
+	names := map[string]bool{}
+	for _, entry := range entries {
+		cri, ok := entry.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("cri entries must be objects, got %T", entry)
+		}
+		name, _ := cri["name"].(string)
+		if !contains(KnownCRINames, name) {
+			return fmt.Errorf("unknown cri %q, expected one of %s", name, strings.Join(KnownCRINames, ", "))
+		}
+		if names[name] {
+			return fmt.Errorf("duplicate cri %s", name)
+		}
+		names[name] = true
+
+		runtimes, ok := cri["containerRuntimes"]
+		if !ok {
+			continue
+		}
+		list, ok := runtimes.([]interface{})
+		if !ok {
+			return fmt.Errorf("container runtimes of cri %s must be a list, got %T", name, runtimes)
+		}
+		types := map[string]bool{}
+		for _, runtime := range list {
+			object, _ := runtime.(map[string]interface{})
+			runtimeType, _ := object["type"].(string)
+			if len(runtimeType) == 0 {
+				return fmt.Errorf("container runtime of cri %s without type", name)
+			}
+			if types[runtimeType] {
+				return fmt.Errorf("duplicate container runtime %s of cri %s", runtimeType, name)
+			}
+			types[runtimeType] = true
+		}
+	}
+	return nil
+}
+
+// mergeCRI returns the union of the cri lists, see CRIMergeStrategyUnion. The lists are not modified. Values which
+// are no valid cri lists are left to validateCRI, the landscape value is returned for them.
+func mergeCRI(lss, landscape interface{}) interface{} {
+	lssEntries, lssOK := lss.([]interface{})
+	landscapeEntries, landscapeOK := landscape.([]interface{})
+	if !lssOK || !landscapeOK {
+		return landscape
+	}
+
+	result := make([]interface{}, 0, len(lssEntries)+len(landscapeEntries))
+	index := map[string]int{}
+	for _, entries := range [][]interface{}{lssEntries, landscapeEntries} {
+		for _, entry := range entries {
+			cri, ok := entry.(map[string]interface{})
+			if !ok {
+				return landscape
+			}
+			name, _ := cri["name"].(string)
+			i, seen := index[name]
+			if !seen {
+				index[name] = len(result)
+				result = append(result, cri)
+				continue
+			}
+			result[i] = mergeContainerRuntimes(result[i].(map[string]interface{}), cri)
+		}
+	}
+	return result
+}
+
+// mergeContainerRuntimes returns a copy of the first cri with the container runtimes of both CRIs, merged by type.
+func mergeContainerRuntimes(first, second map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for key, value := range first {
+		merged[key] = value
+	}
+
+	firstRuntimes, _ := first["containerRuntimes"].([]interface{})
+	secondRuntimes, _ := second["containerRuntimes"].([]interface{})
+	if len(secondRuntimes) == 0 {
+		return merged
+	}
+	runtimes := append([]interface{}{}, firstRuntimes...)
+	for _, runtime := range secondRuntimes {
+		if !containsContainerRuntime(runtimes, runtime) {
+			runtimes = append(runtimes, runtime)
+		}
+	}
+	merged["containerRuntimes"] = runtimes
+	return merged
+}
+
+func containsContainerRuntime(runtimes []interface{}, runtime interface{}) bool {
+	object, _ := runtime.(map[string]interface{})
+	for _, existing := range runtimes {
+		existingObject, _ := existing.(map[string]interface{})
+		if existingObject["type"] == object["type"] {
+			return true
+		}
+	}
+	return false
+}
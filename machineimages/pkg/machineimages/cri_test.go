@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cri", func() {
+
+	containerd := func(runtimes ...string) map[string]interface{} {
+		cri := map[string]interface{}{"name": "containerd"}
+		if len(runtimes) > 0 {
+			list := []interface{}{}
+			for _, runtime := range runtimes {
+				list = append(list, map[string]interface{}{"type": runtime})
+			}
+			cri["containerRuntimes"] = list
+		}
+		return cri
+	}
+	docker := map[string]interface{}{"name": "docker"}
+
+	var lss, landscape []MachineImage
+
+	BeforeEach(func() {
+		lss = []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "318.8.0", "cri": []interface{}{docker, containerd("gvisor")}},
+		}}}
+		landscape = []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "318.8.0", "cri": []interface{}{containerd("kata", "gvisor")}},
+		}}}
+	})
+
+	merge := func(strategy MergeStrategy, criStrategy CRIMergeStrategy, report *Report) ([]OsImage, error) {
+		ctx := NewContext(context.Background(), logr.Discard(), report)
+		return mergeLayers(ctx, flatImages(landscape), flatImages(lss), strategy, criStrategy)
+	}
+
+	It("should take the cri list of the landscape by default", func() {
+		for _, strategy := range []CRIMergeStrategy{"", CRIMergeStrategyOverride} {
+			merged, err := merge("", strategy, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(merged).To(HaveLen(1))
+			Expect(merged[0].Version["cri"]).To(Equal([]interface{}{containerd("kata", "gvisor")}))
+		}
+	})
+
+	It("should merge the cri lists by name and container runtime type", func() {
+		merged, err := merge(MergeStrategyOverlay, CRIMergeStrategyUnion, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(merged[0].Version["cri"]).To(Equal([]interface{}{docker, containerd("gvisor", "kata")}))
+
+		Expect(lss[0].Versions[0]["cri"]).To(Equal([]interface{}{docker, containerd("gvisor")}))
+		Expect(landscape[0].Versions[0]["cri"]).To(Equal([]interface{}{containerd("kata", "gvisor")}))
+	})
+
+	It("should not report differing cri lists as conflict with the union", func() {
+		report := NewReport()
+		_, err := merge(MergeStrategyError, CRIMergeStrategyUnion, report)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = merge(MergeStrategyError, CRIMergeStrategyOverride, report)
+		Expect(err).To(BeAssignableToTypeOf(&MergeConflictError{}))
+	})
+
+	It("should take the preferred version as it is", func() {
+		merged, err := merge(MergeStrategyPreferLSS, CRIMergeStrategyUnion, NewReport())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(merged[0].Version["cri"]).To(Equal([]interface{}{docker, containerd("gvisor")}))
+	})
+
+	It("should validate the cri lists", func() {
+		Expect(validateCRI(MachineImageVersion{"version": "1.0.0"})).To(Succeed())
+		Expect(validateCRI(MachineImageVersion{"cri": []interface{}{docker, containerd("gvisor")}})).To(Succeed())
+		Expect(validateCRI(MachineImageVersion{"cri": "containerd"})).To(MatchError("cri must be a list, got string"))
+		Expect(validateCRI(MachineImageVersion{"cri": []interface{}{"containerd"}})).To(MatchError("cri entries must be objects, got string"))
+		Expect(validateCRI(MachineImageVersion{"cri": []interface{}{map[string]interface{}{"name": "crio"}}})).To(
+			MatchError(`unknown cri "crio", expected one of containerd, docker`))
+		Expect(validateCRI(MachineImageVersion{"cri": []interface{}{docker, docker}})).To(MatchError("duplicate cri docker"))
+		Expect(validateCRI(MachineImageVersion{"cri": []interface{}{containerd("gvisor", "gvisor")}})).To(
+			MatchError("duplicate container runtime gvisor of cri containerd"))
+		Expect(validateCRI(MachineImageVersion{"cri": []interface{}{map[string]interface{}{
+			"name": "containerd", "containerRuntimes": []interface{}{map[string]interface{}{}},
+		}}})).To(MatchError("container runtime of cri containerd without type"))
+	})
+
+	It("should fail computations and imports with invalid cri lists", func() {
+		landscape[0].Versions[0]["cri"] = []interface{}{map[string]interface{}{"name": "crio"}}
+		_, err := ComputeMachineImagesWithOptions(context.Background(), logr.Discard(), lss, landscape, nil, nil,
+			nil, nil, nil, &ComputeMachineImagesOptions{CRIMergeStrategy: CRIMergeStrategyUnion})
+		Expect(err).To(MatchError(ContainSubstring(`machineImagesLs[0].versions[0].cri: unknown cri "crio"`)))
+
+		err = ValidateImports(&Imports{
+			MachineImages:               landscape,
+			ComputeMachineImagesOptions: ComputeMachineImagesOptions{CRIMergeStrategy: "merge"},
+		})
+		Expect(err).To(MatchError(ContainSubstring(`machineImages[0].versions[0].cri: unknown cri "crio"`)))
+		Expect(err).To(MatchError(ContainSubstring(`criMergeStrategy: unknown strategy "merge"`)))
+	})
+})
@@ -0,0 +1,149 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// CycloneDXSpecVersion is the version of the CycloneDX specification of generated boms.
+	CycloneDXSpecVersion = "1.4"
+	// cycloneDXPropertyPrefix is the namespace of the properties of the generated components.
+	cycloneDXPropertyPrefix = "gardener:machineimages:"
+)
+
+// CycloneDXOptions configures the generated bom.
+type CycloneDXOptions struct {
+	// Timestamp of the bom. If nil, the bom has no timestamp, so that it only depends on the machine images.
+	Timestamp *time.Time
+	// ToolVersion is the version of this tool recorded in the metadata of the bom.
+	ToolVersion string
+}
+
+// CycloneDXBOM is a CycloneDX bill of materials.
+type CycloneDXBOM struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     CycloneDXMetadata    `json:"metadata"`
+	Components   []CycloneDXComponent `json:"components"`
+}
+
+// CycloneDXMetadata is the metadata of a bom.
+type CycloneDXMetadata struct {
+	Timestamp string          `json:"timestamp,omitempty"`
+	Tools     []CycloneDXTool `json:"tools"`
+}
+
+// CycloneDXTool is a tool which created a bom.
+type CycloneDXTool struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// CycloneDXComponent is a component of a bom.
+type CycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version"`
+	Properties []CycloneDXProperty `json:"properties,omitempty"`
+}
+
+// CycloneDXProperty is a name value pair of a component.
+type CycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NewCycloneDXBOM returns a bom with one operating-system component per advertised version. All fields of a version
+// except the version itself, e.g. the classification and the artifact references of the provider, are recorded as
+// properties. The serial number is derived from the components, so identical machine images yield identical boms.
+func NewCycloneDXBOM(images []MachineImage, options *CycloneDXOptions) (*CycloneDXBOM, error) {
+	if options == nil {
+		options = &CycloneDXOptions{}
+	}
+
+	bom := &CycloneDXBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: CycloneDXSpecVersion,
+		Version:     1,
+		Metadata: CycloneDXMetadata{
+			Tools: []CycloneDXTool{{Vendor: "gardener", Name: "machineimages", Version: options.ToolVersion}},
+		},
+		Components: []CycloneDXComponent{},
+	}
+	if options.Timestamp != nil {
+		bom.Metadata.Timestamp = options.Timestamp.UTC().Format(time.RFC3339)
+	}
+
+	for _, image := range images {
+		for _, v := range image.Versions {
+			component, err := newCycloneDXComponent(image.Name, v)
+			if err != nil {
+				return nil, err
+			}
+			bom.Components = append(bom.Components, component)
+		}
+	}
+
+	data, err := json.Marshal(bom.Components)
+	if err != nil {
+		return nil, err
+	}
+	bom.SerialNumber = uuidURN(data)
+
+	return bom, nil
+}
+
+func newCycloneDXComponent(name string, v MachineImageVersion) (CycloneDXComponent, error) {
+	version := versionOrEmpty(v)
+	component := CycloneDXComponent{
+		Type:    "operating-system",
+		BOMRef:  name + "@" + version,
+		Name:    name,
+		Version: version,
+	}
+
+	keys := make([]string, 0, len(v))
+	for key := range v {
+		if key != "version" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, ok := v[key].(string)
+		if !ok {
+			data, err := json.Marshal(v[key])
+			if err != nil {
+				return CycloneDXComponent{}, fmt.Errorf("unable to encode field %s of %s: %w", key, component.BOMRef, err)
+			}
+			value = string(data)
+		}
+		component.Properties = append(component.Properties, CycloneDXProperty{
+			Name:  cycloneDXPropertyPrefix + key,
+			Value: value,
+		})
+	}
+
+	return component, nil
+}
+
+// uuidURN returns a name based uuid urn for the data.
+func uuidURN(data []byte) string {
+	sum := sha256.Sum256(data)
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
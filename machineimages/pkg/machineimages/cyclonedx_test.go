@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cyclonedx", func() {
+
+	images := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "318.9.0", "classification": "supported", "image": "ami-1", "cri": []interface{}{map[string]interface{}{"name": "containerd"}}},
+		}},
+		{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "18.4.0"}}},
+	}
+
+	It("should list all versions as components", func() {
+		timestamp := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+		bom, err := NewCycloneDXBOM(images, &CycloneDXOptions{Timestamp: &timestamp, ToolVersion: "v0.1.0"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bom.BOMFormat).To(Equal("CycloneDX"))
+		Expect(bom.Metadata.Timestamp).To(Equal("2021-06-01T12:00:00Z"))
+		Expect(bom.SerialNumber).To(MatchRegexp(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+		Expect(bom.Components).To(Equal([]CycloneDXComponent{
+			{
+				Type:    "operating-system",
+				BOMRef:  "gardenlinux@318.9.0",
+				Name:    OsNameGardenLinux,
+				Version: "318.9.0",
+				Properties: []CycloneDXProperty{
+					{Name: "gardener:machineimages:classification", Value: "supported"},
+					{Name: "gardener:machineimages:cri", Value: `[{"name":"containerd"}]`},
+					{Name: "gardener:machineimages:image", Value: "ami-1"},
+				},
+			},
+			{Type: "operating-system", BOMRef: "ubuntu@18.4.0", Name: OsNameUbuntu, Version: "18.4.0"},
+		}))
+	})
+
+	It("should be reproducible", func() {
+		first, err := NewCycloneDXBOM(images, nil)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := NewCycloneDXBOM(images, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		a, err := json.Marshal(first)
+		Expect(err).NotTo(HaveOccurred())
+		b, err := json.Marshal(second)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a).To(Equal(b))
+
+		other, err := NewCycloneDXBOM(images[1:], nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other.SerialNumber).NotTo(Equal(first.SerialNumber))
+	})
+})
@@ -0,0 +1,46 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// removeDuplicates removes all images which are deeply equal to a preceding image and keeps the order of the others.
+// Images are bucketed by their name, version and canonical json encoding, whose map keys are sorted, so that only the
+// images of a bucket are compared. The comparison within a bucket keeps the semantics of reflect.DeepEqual also for
+// values which encode alike, e.g. int and float64 numbers. Garden Linux flavors are compared in their canonical form, so
+// that the same flavor with another order of its features is a duplicate, see ParseGardenLinuxFlavor.
+func removeDuplicates(images []OsImage) []OsImage {
+	result := []OsImage{}
+	buckets := map[string][]OsImage{}
+	for _, nextImage := range images {
+		canonical := canonicalFlavor(nextImage)
+		key := dedupKey(canonical)
+		found := false
+		for _, nextResult := range buckets[key] {
+			if reflect.DeepEqual(canonical, nextResult) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			buckets[key] = append(buckets[key], canonical)
+			result = append(result, nextImage)
+		}
+	}
+	return result
+}
+
+// dedupKey returns the bucket of an image. Images whose version cannot be encoded share the bucket of their name and
+// version number.
+func dedupKey(image OsImage) string {
+	key := image.Name + "\x00" + versionOrEmpty(image.Version)
+	if data, err := json.Marshal(image.Version); err == nil {
+		key += "\x00" + string(data)
+	}
+	return key
+}
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// removeDuplicatesPairwise is the former implementation of removeDuplicates, which compares every pair of images. It
+// is the reference of the semantics and the baseline of the benchmark.
+func removeDuplicatesPairwise(images []OsImage) []OsImage {
+	result := []OsImage{}
+	for _, nextImage := range images {
+		found := false
+		for _, nextResult := range result {
+			if reflect.DeepEqual(nextImage, nextResult) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, nextImage)
+		}
+	}
+	return result
+}
+
+// newDuplicatedImages returns the versions of several images with regions, in which every version occurs twice, once
+// from the landscape and once from the lss list.
+func newDuplicatedImages(versions int) []OsImage {
+	images := []OsImage{}
+	for _, name := range []string{OsNameGardenLinux, OsNameUbuntu, "suse-chost"} {
+		for i := 0; i < versions; i++ {
+			newVersion := func() MachineImageVersion {
+				return MachineImageVersion{
+					"version":        fmt.Sprintf("1.%d.0", i),
+					"classification": ClassificationSupported,
+					"regions": []interface{}{
+						map[string]interface{}{"name": "eu-west-1", "ami": fmt.Sprintf("ami-%d", i)},
+						map[string]interface{}{"name": "us-east-1", "ami": fmt.Sprintf("ami-%d", i)},
+					},
+				}
+			}
+			images = append(images, OsImage{Name: name, Version: newVersion()}, OsImage{Name: name, Version: newVersion()})
+		}
+	}
+	return images
+}
+
+var _ = Describe("removeDuplicates", func() {
+
+	It("should keep the first of equal images in order", func() {
+		images := []OsImage{
+			{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "2.0.0"}},
+			{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": "1.0.0", "cri": []interface{}{"containerd"}}},
+			{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "2.0.0"}},
+			{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": "1.0.0", "cri": []interface{}{"containerd"}}},
+			{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": "1.0.0", "cri": []interface{}{"docker"}}},
+		}
+		Expect(removeDuplicates(images)).To(Equal([]OsImage{images[0], images[1], images[4]}))
+	})
+
+	It("should distinguish values which encode alike", func() {
+		images := []OsImage{
+			{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "1.0.0", "size": 1}},
+			{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "1.0.0", "size": 1.0}},
+		}
+		Expect(removeDuplicates(images)).To(Equal(images))
+	})
+
+	It("should compare images which cannot be encoded", func() {
+		images := []OsImage{
+			{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "1.0.0", "labels": map[interface{}]interface{}{1: "a"}}},
+			{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "1.0.0", "labels": map[interface{}]interface{}{1: "a"}}},
+			{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "1.0.0", "labels": map[interface{}]interface{}{1: "b"}}},
+		}
+		Expect(removeDuplicates(images)).To(Equal([]OsImage{images[0], images[2]}))
+	})
+
+	It("should yield the result of the pairwise comparison", func() {
+		images := newDuplicatedImages(50)
+		Expect(removeDuplicates(images)).To(Equal(removeDuplicatesPairwise(images)))
+		Expect(removeDuplicates(images)).To(HaveLen(150))
+	})
+})
+
+func BenchmarkRemoveDuplicates(b *testing.B) {
+	for _, versions := range []int{10, 100, 500} {
+		images := newDuplicatedImages(versions)
+		b.Run(fmt.Sprintf("hashed/%d", len(images)), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				removeDuplicates(images)
+			}
+		})
+		b.Run(fmt.Sprintf("pairwise/%d", len(images)), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				removeDuplicatesPairwise(images)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultVersionStrategy determines how the default version of a machine image is selected.
+type DefaultVersionStrategy string
+
+const (
+	// DefaultVersionStrategyHighestSupported selects the highest version with classification supported.
+	DefaultVersionStrategyHighestSupported = DefaultVersionStrategy("highestSupported")
+	// DefaultVersionStrategyHighestNonExpired selects the highest version which is not expired.
+	DefaultVersionStrategyHighestNonExpired = DefaultVersionStrategy("highestNonExpired")
+	// DefaultVersionStrategyPinned selects the version configured for the image in DefaultVersionOptions.Pinned.
+	DefaultVersionStrategyPinned = DefaultVersionStrategy("pinned")
+)
+
+// DefaultVersionOptions configures the selection of default versions.
+type DefaultVersionOptions struct {
+	// Strategy is the selection strategy. Defaults to DefaultVersionStrategyHighestSupported.
+	Strategy DefaultVersionStrategy `json:"strategy,omitempty"`
+	// Pinned maps image names to their default version. Only used by DefaultVersionStrategyPinned.
+	Pinned map[string]string `json:"pinned,omitempty"`
+	// Now is the reference time to determine expired versions. Defaults to the current time.
+	Now time.Time `json:"-"`
+}
+
+// SelectDefaultVersion returns the default version of a machine image according to the given options. The second
+// return value is false if the image has no version which qualifies as default.
+func SelectDefaultVersion(image MachineImage, options *DefaultVersionOptions) (string, bool, error) {
+	if options == nil {
+		options = &DefaultVersionOptions{}
+	}
+
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	switch options.Strategy {
+	case "", DefaultVersionStrategyHighestSupported:
+		return highestVersion(image, func(v MachineImageVersion) (bool, error) {
+			return v.hasClassification(ClassificationSupported), nil
+		})
+	case DefaultVersionStrategyHighestNonExpired:
+		return highestVersion(image, func(v MachineImageVersion) (bool, error) {
+			expired, err := v.isExpiredAt(now)
+			return !expired, err
+		})
+	case DefaultVersionStrategyPinned:
+		pinned, ok := options.Pinned[image.Name]
+		if !ok {
+			return "", false, fmt.Errorf("no pinned default version for machine image %s", image.Name)
+		}
+		for _, v := range image.Versions {
+			if version := v.getVersion(); version != nil && *version == pinned {
+				return pinned, true, nil
+			}
+		}
+		return "", false, fmt.Errorf("pinned default version %s of machine image %s does not exist", pinned, image.Name)
+	default:
+		return "", false, fmt.Errorf("default version strategy does not exist %s", options.Strategy)
+	}
+}
+
+// SelectDefaultVersions returns the default versions of all machine images which have one, keyed by image name.
+func SelectDefaultVersions(images []MachineImage, options *DefaultVersionOptions) (map[string]string, error) {
+	result := map[string]string{}
+	for _, image := range images {
+		version, ok, err := SelectDefaultVersion(image, options)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result[image.Name] = version
+		}
+	}
+	return result, nil
+}
+
+// ApplyDefaultVersions makes the default version of every machine image the version which gardener defaults shoots to,
+// the highest version which is neither preview nor expired. Higher versions which are neither preview nor expired are
+// classified as preview. It returns copies of the images, images without default version are copied unchanged.
+func ApplyDefaultVersions(images []MachineImage, options *DefaultVersionOptions) ([]MachineImage, error) {
+	if options == nil {
+		options = &DefaultVersionOptions{}
+	}
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	selectOptions := *options
+	selectOptions.Now = now
+
+	result := make([]MachineImage, 0, len(images))
+	for _, image := range images {
+		image = image.DeepCopy()
+		defaultVersion, ok, err := SelectDefaultVersion(image, &selectOptions)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			result = append(result, image)
+			continue
+		}
+
+		for _, v := range image.Versions {
+			version := v.getVersion()
+			if version == nil {
+				continue
+			}
+			expired, err := v.isExpiredAt(now)
+			if err != nil {
+				return nil, err
+			}
+			if *version == defaultVersion && (expired || v.hasClassification(ClassificationPreview)) {
+				return nil, fmt.Errorf("default version %s of machine image %s is a preview or expired version, which shoots are not defaulted to",
+					defaultVersion, image.Name)
+			}
+			if !expired && !v.hasClassification(ClassificationPreview) && compareVersions(*version, defaultVersion) > 0 {
+				v["classification"] = ClassificationPreview
+			}
+		}
+		result = append(result, image)
+	}
+	return result, nil
+}
+
+func highestVersion(image MachineImage, qualifies func(MachineImageVersion) (bool, error)) (string, bool, error) {
+	var highest *string
+	for _, v := range image.Versions {
+		version := v.getVersion()
+		if version == nil {
+			continue
+		}
+
+		ok, err := qualifies(v)
+		if err != nil {
+			return "", false, err
+		}
+
+		if ok && (highest == nil || compareVersions(*version, *highest) > 0) {
+			highest = version
+		}
+	}
+
+	if highest == nil {
+		return "", false, nil
+	}
+	return *highest, true, nil
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("default version", func() {
+
+	image := MachineImage{
+		Name: OsNameGardenLinux,
+		Versions: []MachineImageVersion{
+			{"version": "318.9.0", "classification": ClassificationPreview},
+			{"version": "318.10.0", "classification": ClassificationSupported},
+			{"version": "318.8.0", "classification": ClassificationSupported},
+			{"version": "400.0.0", "classification": ClassificationDeprecated, "expirationDate": "2022-01-15T23:59:59Z"},
+		},
+	}
+
+	It("should select the highest supported version by default", func() {
+		version, ok, err := SelectDefaultVersion(image, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(version).To(Equal("318.10.0"))
+	})
+
+	It("should select the highest non expired version", func() {
+		options := &DefaultVersionOptions{Strategy: DefaultVersionStrategyHighestNonExpired}
+
+		options.Now = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		version, _, err := SelectDefaultVersion(image, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("400.0.0"))
+
+		options.Now = time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+		version, _, err = SelectDefaultVersion(image, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version).To(Equal("318.10.0"))
+	})
+
+	It("should select the pinned version", func() {
+		options := &DefaultVersionOptions{
+			Strategy: DefaultVersionStrategyPinned,
+			Pinned:   map[string]string{OsNameGardenLinux: "318.8.0"},
+		}
+		version, ok, err := SelectDefaultVersion(image, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(version).To(Equal("318.8.0"))
+
+		options.Pinned[OsNameGardenLinux] = "1.0.0"
+		_, _, err = SelectDefaultVersion(image, options)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should skip images without qualifying versions", func() {
+		versions, err := SelectDefaultVersions([]MachineImage{
+			image,
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0", "classification": ClassificationPreview}}},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versions).To(Equal(map[string]string{OsNameGardenLinux: "318.10.0"}))
+	})
+
+	It("should classify the versions above the default version as preview", func() {
+		options := &DefaultVersionOptions{Strategy: DefaultVersionStrategyPinned, Pinned: map[string]string{OsNameGardenLinux: "318.8.0"},
+			Now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+		images, err := ApplyDefaultVersions([]MachineImage{image}, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(images).To(Equal([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "318.9.0", "classification": ClassificationPreview},
+				{"version": "318.10.0", "classification": ClassificationPreview},
+				{"version": "318.8.0", "classification": ClassificationSupported},
+				{"version": "400.0.0", "classification": ClassificationDeprecated, "expirationDate": "2022-01-15T23:59:59Z"},
+			}},
+		}))
+		Expect(image.Versions[1]).To(HaveKeyWithValue("classification", ClassificationSupported))
+
+		options.Pinned[OsNameGardenLinux] = "318.9.0"
+		_, err = ApplyDefaultVersions([]MachineImage{image}, options)
+		Expect(err).To(MatchError(ContainSubstring("default version 318.9.0 of machine image gardenlinux is a preview or expired version")))
+	})
+})
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"reflect"
+)
+
+// VersionRef identifies a version of a machine image.
+type VersionRef struct {
+	Image   string `json:"image"`
+	Version string `json:"version"`
+}
+
+// MachineImagesDiff lists the versions which differ between two lists of machine images.
+type MachineImagesDiff struct {
+	Added   []VersionRef `json:"added"`
+	Removed []VersionRef `json:"removed"`
+	Changed []VersionRef `json:"changed"`
+	// Superseded links removed versions to the versions which supersede them, in the order of Removed. Removed versions
+	// without superseding version are not listed.
+	Superseded []Supersession `json:"superseded,omitempty"`
+}
+
+// Supersession links a removed version to the version which supersedes it, i.e. the next higher version of the same
+// image which is retained. Shoots of the removed version are upgraded to it.
+type Supersession struct {
+	Removed      VersionRef `json:"removed"`
+	SupersededBy VersionRef `json:"supersededBy"`
+}
+
+// Empty returns whether both lists contain the same versions.
+func (d *MachineImagesDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffMachineImages compares the versions of two lists of machine images. A version is changed if any of its fields
+// differ. The result is ordered like the versions in the lists. Removed versions are linked to the versions of the new
+// list which supersede them.
+func DiffMachineImages(oldImages, newImages []MachineImage) *MachineImagesDiff {
+	diff := &MachineImagesDiff{
+		Added:   []VersionRef{},
+		Removed: []VersionRef{},
+		Changed: []VersionRef{},
+	}
+
+	oldVersions := indexVersions(oldImages)
+	newVersions := indexVersions(newImages)
+
+	for _, ref := range versionRefs(newImages) {
+		oldVersion, ok := oldVersions[ref]
+		if !ok {
+			diff.Added = append(diff.Added, ref)
+		} else if !reflect.DeepEqual(oldVersion, newVersions[ref]) {
+			diff.Changed = append(diff.Changed, ref)
+		}
+	}
+
+	for _, ref := range versionRefs(oldImages) {
+		if _, ok := newVersions[ref]; !ok {
+			diff.Removed = append(diff.Removed, ref)
+			if supersededBy, ok := supersedingVersion(ref, newImages); ok {
+				diff.Superseded = append(diff.Superseded, Supersession{Removed: ref, SupersededBy: supersededBy})
+			}
+		}
+	}
+
+	return diff
+}
+
+// supersedingVersion returns the lowest version of the image of the ref which is higher than the version of the ref.
+func supersedingVersion(ref VersionRef, images []MachineImage) (VersionRef, bool) {
+	result, found := VersionRef{}, false
+	for _, image := range images {
+		if image.Name != ref.Image {
+			continue
+		}
+		for _, v := range image.Versions {
+			version := versionOrEmpty(v)
+			if compareVersions(version, ref.Version) <= 0 {
+				continue
+			}
+			if !found || compareVersions(version, result.Version) < 0 {
+				result, found = VersionRef{Image: image.Name, Version: version}, true
+			}
+		}
+	}
+	return result, found
+}
+
+func versionRefs(images []MachineImage) []VersionRef {
+	result := []VersionRef{}
+	seen := map[VersionRef]bool{}
+	for _, image := range images {
+		for _, v := range image.Versions {
+			ref := VersionRef{Image: image.Name, Version: versionOrEmpty(v)}
+			if !seen[ref] {
+				seen[ref] = true
+				result = append(result, ref)
+			}
+		}
+	}
+	return result
+}
+
+func indexVersions(images []MachineImage) map[VersionRef]MachineImageVersion {
+	result := map[VersionRef]MachineImageVersion{}
+	for _, image := range images {
+		for _, v := range image.Versions {
+			ref := VersionRef{Image: image.Name, Version: versionOrEmpty(v)}
+			if _, ok := result[ref]; !ok {
+				result[ref] = v
+			}
+		}
+	}
+	return result
+}
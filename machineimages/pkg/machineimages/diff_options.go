@@ -0,0 +1,229 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DiffOptions configures which differences of versions are cosmetic and do not change a version, see
+// DiffMachineImagesWithOptions.
+type DiffOptions struct {
+	// IgnoreFields are selectors of the fields of versions which are not compared. A selector is a path of field
+	// names separated by dots, optionally starting with "$.". "*" matches every field and a field followed by "[*]"
+	// selects the fields of all elements of its list, e.g. "annotations", "provenance.*" or "regions[*].checksum".
+	IgnoreFields []string `json:"ignoreFields,omitempty" yaml:"ignoreFields,omitempty"`
+	// IgnoreOrder compares the lists of versions regardless of the order of their elements, e.g. of regions.
+	IgnoreOrder bool `json:"ignoreOrder,omitempty" yaml:"ignoreOrder,omitempty"`
+}
+
+// fieldSelector is a parsed selector of IgnoreFields.
+type fieldSelector []selectorSegment
+
+type selectorSegment struct {
+	// field is the name of the field or "*".
+	field string
+	// elements applies the rest of the selector to the elements of the list of the field.
+	elements bool
+}
+
+// Validate checks that the selectors are valid.
+func (o *DiffOptions) Validate() error {
+	_, err := o.selectors()
+	return err
+}
+
+func (o *DiffOptions) selectors() ([]fieldSelector, error) {
+	if o == nil {
+		return nil, nil
+	}
+	result := make([]fieldSelector, 0, len(o.IgnoreFields))
+	for _, selector := range o.IgnoreFields {
+		parsed, err := parseFieldSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parsed)
+	}
+	return result, nil
+}
+
+func parseFieldSelector(selector string) (fieldSelector, error) {
+	path := strings.TrimPrefix(strings.TrimPrefix(selector, "$"), ".")
+	if len(path) == 0 {
+		return nil, fmt.Errorf("ignoreFields: selector %q selects no field", selector)
+	}
+
+	result := fieldSelector{}
+	for _, part := range strings.Split(path, ".") {
+		segment := selectorSegment{field: part}
+		if strings.HasSuffix(part, "[*]") {
+			segment = selectorSegment{field: strings.TrimSuffix(part, "[*]"), elements: true}
+		}
+		if len(segment.field) == 0 || strings.ContainsAny(segment.field, "[]") {
+			return nil, fmt.Errorf("ignoreFields: invalid selector %q", selector)
+		}
+		result = append(result, segment)
+	}
+	if result[len(result)-1].elements {
+		return nil, fmt.Errorf("ignoreFields: selector %q must end with a field", selector)
+	}
+	if len(result) == 1 && (result[0].field == "version" || result[0].field == "*") {
+		return nil, fmt.Errorf("ignoreFields: selector %q must not select the version", selector)
+	}
+	return result, nil
+}
+
+// remove returns a copy of the value without the selected fields. Values which the selector does not match are
+// returned unchanged.
+func (s fieldSelector) remove(value interface{}) interface{} {
+	fields, ok := asFields(value)
+	if !ok {
+		return value
+	}
+
+	segment, rest := s[0], s[1:]
+	result := make(map[string]interface{}, len(fields))
+	for field, fieldValue := range fields {
+		switch {
+		case segment.field != "*" && segment.field != field:
+			result[field] = fieldValue
+		case len(rest) == 0:
+			// the field is ignored
+		case segment.elements:
+			if elements, ok := fieldValue.([]interface{}); ok {
+				removed := make([]interface{}, 0, len(elements))
+				for _, element := range elements {
+					removed = append(removed, rest.remove(element))
+				}
+				fieldValue = removed
+			}
+			result[field] = fieldValue
+		default:
+			result[field] = rest.remove(fieldValue)
+		}
+	}
+	return result
+}
+
+func asFields(value interface{}) (map[string]interface{}, bool) {
+	switch typed := value.(type) {
+	case MachineImageVersion:
+		return typed, true
+	case map[string]interface{}:
+		return typed, true
+	}
+	return nil, false
+}
+
+// sortLists returns a copy of the value whose lists are ordered by the json encoding of their elements.
+func sortLists(value interface{}) interface{} {
+	if fields, ok := asFields(value); ok {
+		result := make(map[string]interface{}, len(fields))
+		for field, fieldValue := range fields {
+			result[field] = sortLists(fieldValue)
+		}
+		return result
+	}
+
+	var elements []interface{}
+	switch typed := value.(type) {
+	case []interface{}:
+		for _, element := range typed {
+			elements = append(elements, sortLists(element))
+		}
+	case []string:
+		for _, element := range typed {
+			elements = append(elements, element)
+		}
+	default:
+		return value
+	}
+	keys := make([]string, len(elements))
+	for i, element := range elements {
+		data, _ := json.Marshal(element)
+		keys[i] = string(data)
+	}
+	sort.Sort(byKeys{keys: keys, elements: elements})
+	return elements
+}
+
+type byKeys struct {
+	keys     []string
+	elements []interface{}
+}
+
+func (b byKeys) Len() int           { return len(b.keys) }
+func (b byKeys) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKeys) Swap(i, j int) {
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+	b.elements[i], b.elements[j] = b.elements[j], b.elements[i]
+}
+
+// NormalizeMachineImages returns copies of the images whose versions have no ignored fields and, with IgnoreOrder,
+// ordered lists, so that versions which only differ cosmetically are deeply equal. Without options the images are
+// returned unchanged.
+func (o *DiffOptions) NormalizeMachineImages(images []MachineImage) ([]MachineImage, error) {
+	selectors, err := o.selectors()
+	if err != nil {
+		return nil, err
+	}
+	if len(selectors) == 0 && (o == nil || !o.IgnoreOrder) {
+		return images, nil
+	}
+
+	result := make([]MachineImage, 0, len(images))
+	for _, image := range images {
+		versions := make([]MachineImageVersion, 0, len(image.Versions))
+		for _, v := range image.Versions {
+			var value interface{} = v
+			for _, selector := range selectors {
+				value = selector.remove(value)
+			}
+			if o.IgnoreOrder {
+				value = sortLists(value)
+			}
+			fields, _ := asFields(value)
+			versions = append(versions, MachineImageVersion(fields))
+		}
+		result = append(result, MachineImage{Name: image.Name, Versions: versions})
+	}
+	return result, nil
+}
+
+// EqualMachineImages returns whether the images only differ cosmetically, i.e. whether their normalized images, see
+// NormalizeMachineImages, have the same json encoding. Unlike reflect.DeepEqual, the encoding also makes images equal
+// which were read from yaml, e.g. with lists of values instead of lists of strings. Nil and empty images are equal.
+func (o *DiffOptions) EqualMachineImages(a, b []MachineImage) (bool, error) {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b), nil
+	}
+	normalizedA, err := o.NormalizeMachineImages(a)
+	if err != nil {
+		return false, err
+	}
+	normalizedB, err := o.NormalizeMachineImages(b)
+	if err != nil {
+		return false, err
+	}
+	return sameCatalog(normalizedA, normalizedB)
+}
+
+// DiffMachineImagesWithOptions is DiffMachineImages which ignores the cosmetic differences of the options. The
+// options may be nil.
+func DiffMachineImagesWithOptions(oldImages, newImages []MachineImage, options *DiffOptions) (*MachineImagesDiff, error) {
+	normalizedOld, err := options.NormalizeMachineImages(oldImages)
+	if err != nil {
+		return nil, err
+	}
+	normalizedNew, err := options.NormalizeMachineImages(newImages)
+	if err != nil {
+		return nil, err
+	}
+	return DiffMachineImages(normalizedOld, normalizedNew), nil
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("diff options", func() {
+
+	oldImages := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+		{"version": "934.7.0", "annotations": map[string]interface{}{"built": "monday"},
+			"provenance": map[string]interface{}{"source": "a", "commit": "1"}, "architectures": []string{"amd64", "arm64"},
+			"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1", "checksum": "x"}}},
+		{"version": "934.6.0", "classification": "supported"},
+	}}}
+	newImages := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+		{"version": "934.7.0", "annotations": map[string]interface{}{"built": "tuesday"},
+			"provenance": map[string]interface{}{"source": "b", "commit": "2"}, "architectures": []interface{}{"arm64", "amd64"},
+			"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1", "checksum": "y"}}},
+		{"version": "934.6.0", "classification": "deprecated"},
+	}}}
+
+	It("should ignore the selected fields and the order of lists", func() {
+		diff, err := DiffMachineImagesWithOptions(oldImages, newImages, &DiffOptions{
+			IgnoreFields: []string{"annotations", "$.provenance.*", "regions[*].checksum"},
+			IgnoreOrder:  true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.Changed).To(Equal([]VersionRef{{Image: OsNameGardenLinux, Version: "934.6.0"}}))
+
+		diff, err = DiffMachineImagesWithOptions(oldImages, newImages, &DiffOptions{IgnoreFields: []string{"annotations", "provenance"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff.Changed).To(Equal([]VersionRef{{Image: OsNameGardenLinux, Version: "934.7.0"}, {Image: OsNameGardenLinux, Version: "934.6.0"}}))
+	})
+
+	It("should compare like DiffMachineImages without options", func() {
+		diff, err := DiffMachineImagesWithOptions(oldImages, newImages, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(diff).To(Equal(DiffMachineImages(oldImages, newImages)))
+	})
+
+	It("should not modify the machine images", func() {
+		normalized, err := (&DiffOptions{IgnoreFields: []string{"regions[*].checksum", "annotations"}, IgnoreOrder: true}).NormalizeMachineImages(oldImages)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(normalized[0].Versions[0]).NotTo(HaveKey("annotations"))
+		Expect(normalized[0].Versions[0]["regions"]).To(Equal([]interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}))
+		Expect(oldImages[0].Versions[0]).To(HaveKey("annotations"))
+		Expect(oldImages[0].Versions[0]["regions"]).To(Equal([]interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1", "checksum": "x"}}))
+	})
+
+	firstVersion := func(images []MachineImage) []MachineImage {
+		return []MachineImage{{Name: images[0].Name, Versions: images[0].Versions[:1]}}
+	}
+
+	It("should only compare the normalized machine images", func() {
+		options := &DiffOptions{IgnoreFields: []string{"annotations", "provenance", "regions[*].checksum"}}
+		equal, err := options.EqualMachineImages(oldImages, newImages)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(equal).To(BeFalse())
+
+		equal, err = options.EqualMachineImages(firstVersion(oldImages), firstVersion(newImages))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(equal).To(BeFalse())
+
+		options.IgnoreOrder = true
+		equal, err = options.EqualMachineImages(firstVersion(oldImages), firstVersion(newImages))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(equal).To(BeTrue())
+
+		equal, err = (*DiffOptions)(nil).EqualMachineImages(nil, []MachineImage{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(equal).To(BeTrue())
+	})
+
+	It("should keep the previous exports if the machine images only differ cosmetically", func() {
+		imports := &Imports{ComputeMachineImagesOptions: ComputeMachineImagesOptions{
+			Diff: &DiffOptions{IgnoreFields: []string{"annotations", "provenance", "regions[*].checksum"}, IgnoreOrder: true},
+		}}
+		previous := &Exports{ResultMachineImages: firstVersion(oldImages)}
+		computed := &Exports{ResultMachineImages: firstVersion(newImages)}
+
+		exports, err := KeepCosmeticExports(imports, previous, computed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exports.ResultMachineImages).To(Equal(previous.ResultMachineImages))
+
+		exports, err = KeepCosmeticExports(imports, &Exports{ResultMachineImages: oldImages}, computed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exports).To(BeIdenticalTo(computed))
+
+		exports, err = KeepCosmeticExports(&Imports{}, previous, computed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exports).To(BeIdenticalTo(computed))
+	})
+
+	It("should reject invalid selectors", func() {
+		for selector, message := range map[string]string{
+			"":                "selects no field",
+			"$":               "selects no field",
+			"regions[*]":      "must end with a field",
+			"regions[0].ami":  "invalid selector",
+			"provenance..key": "invalid selector",
+			"version":         "must not select the version",
+			"*":               "must not select the version",
+		} {
+			Expect((&DiffOptions{IgnoreFields: []string{selector}}).Validate()).To(MatchError(ContainSubstring(message)), selector)
+		}
+		Expect((&DiffOptions{IgnoreFields: []string{"provenance.version"}}).Validate()).To(Succeed())
+
+		err := ValidateImports(&Imports{ComputeMachineImagesOptions: ComputeMachineImagesOptions{Diff: &DiffOptions{IgnoreFields: []string{"version"}}}})
+		Expect(err).To(MatchError(ContainSubstring(`diff: ignoreFields: selector "version" must not select the version`)))
+	})
+})
@@ -0,0 +1,50 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("diff", func() {
+
+	It("should list added, removed and changed versions", func() {
+		oldImages := []MachineImage{
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0"}, {"version": "2.0.0", "image": "a"}}},
+		}
+		newImages := []MachineImage{
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "2.0.0", "image": "b"}, {"version": "3.0.0"}}},
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.9.0"}}},
+		}
+
+		diff := DiffMachineImages(oldImages, newImages)
+		Expect(diff.Empty()).To(BeFalse())
+		Expect(diff.Added).To(Equal([]VersionRef{{OsNameUbuntu, "3.0.0"}, {OsNameGardenLinux, "318.9.0"}}))
+		Expect(diff.Removed).To(Equal([]VersionRef{{OsNameUbuntu, "1.0.0"}}))
+		Expect(diff.Changed).To(Equal([]VersionRef{{OsNameUbuntu, "2.0.0"}}))
+
+		Expect(DiffMachineImages(newImages, newImages).Empty()).To(BeTrue())
+	})
+
+	It("should link removed versions to the next higher retained version", func() {
+		oldImages := []MachineImage{
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0"}, {"version": "1.1.0"}, {"version": "2.0.0"}}},
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.9.0"}, {"version": "184.0.0"}}},
+		}
+		newImages := []MachineImage{
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "3.0.0"}, {"version": "1.2.0"}}},
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "184.0.0"}}},
+		}
+
+		diff := DiffMachineImages(oldImages, newImages)
+		Expect(diff.Removed).To(Equal([]VersionRef{{OsNameUbuntu, "1.0.0"}, {OsNameUbuntu, "1.1.0"}, {OsNameUbuntu, "2.0.0"}, {OsNameGardenLinux, "318.9.0"}}))
+		Expect(diff.Superseded).To(Equal([]Supersession{
+			{Removed: VersionRef{OsNameUbuntu, "1.0.0"}, SupersededBy: VersionRef{OsNameUbuntu, "1.2.0"}},
+			{Removed: VersionRef{OsNameUbuntu, "1.1.0"}, SupersededBy: VersionRef{OsNameUbuntu, "1.2.0"}},
+			{Removed: VersionRef{OsNameUbuntu, "2.0.0"}, SupersededBy: VersionRef{OsNameUbuntu, "3.0.0"}},
+		}))
+	})
+})
@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// UnmatchedDisablePatternsError is returned with StrictDisableMachineImages if patterns of the disabled machine images
+// match no image or version of the computation.
+type UnmatchedDisablePatternsError struct {
+	// Patterns are the unmatched patterns in the order of the disabled machine images.
+	Patterns []string
+}
+
+func (e *UnmatchedDisablePatternsError) Error() string {
+	return "disabled machine images match nothing: " + strings.Join(e.Patterns, ", ")
+}
+
+// disablePattern is a parsed entry of the disabled machine images. An entry is an image pattern, optionally followed
+// by a colon and a version pattern, e.g. "suse-*" or "gardenlinux:934.7". Image patterns are glob patterns, see
+// path.Match, or regular expressions enclosed in slashes, e.g. "/^suse-(chost|sles)$/", which must match the whole
+// name. Version patterns are glob patterns. A version pattern without wildcard also matches the versions which it
+// prefixes at a dot, so that "934.7" disables 934.7 and all its patch versions.
+type disablePattern struct {
+	pattern string
+	image   func(name string) bool
+	version string
+}
+
+// ValidateDisableMachineImages returns an error for the first invalid pattern of the disabled machine images.
+func ValidateDisableMachineImages(patterns []string) error {
+	_, err := parseDisablePatterns(patterns)
+	return err
+}
+
+func parseDisablePatterns(patterns []string) ([]*disablePattern, error) {
+	result := make([]*disablePattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		parsed, err := parseDisablePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parsed)
+	}
+	return result, nil
+}
+
+func parseDisablePattern(pattern string) (*disablePattern, error) {
+	image, version := pattern, ""
+	if strings.HasPrefix(pattern, "/") {
+		end := strings.LastIndex(pattern, "/")
+		if end == 0 {
+			return nil, fmt.Errorf("invalid disabled machine image %q: unterminated regular expression", pattern)
+		}
+		image = pattern[:end+1]
+		if rest := pattern[end+1:]; len(rest) > 0 {
+			if !strings.HasPrefix(rest, ":") {
+				return nil, fmt.Errorf("invalid disabled machine image %q: expected a colon after the regular expression", pattern)
+			}
+			version = rest[1:]
+			if len(version) == 0 {
+				return nil, fmt.Errorf("invalid disabled machine image %q: empty version", pattern)
+			}
+		}
+	} else if i := strings.Index(pattern, ":"); i >= 0 {
+		image, version = pattern[:i], pattern[i+1:]
+		if len(version) == 0 {
+			return nil, fmt.Errorf("invalid disabled machine image %q: empty version", pattern)
+		}
+	}
+	if len(image) == 0 {
+		return nil, fmt.Errorf("invalid disabled machine image %q: empty image", pattern)
+	}
+	if len(version) > 0 {
+		if _, err := path.Match(version, ""); err != nil {
+			return nil, fmt.Errorf("invalid disabled machine image %q: invalid version pattern: %w", pattern, err)
+		}
+	}
+
+	result := &disablePattern{pattern: pattern, version: version}
+	if strings.HasPrefix(image, "/") {
+		expression, err := regexp.Compile("^(?:" + image[1:len(image)-1] + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid disabled machine image %q: %w", pattern, err)
+		}
+		result.image = expression.MatchString
+	} else {
+		if _, err := path.Match(image, ""); err != nil {
+			return nil, fmt.Errorf("invalid disabled machine image %q: %w", pattern, err)
+		}
+		result.image = func(name string) bool {
+			matched, _ := path.Match(image, name)
+			return matched
+		}
+	}
+	return result, nil
+}
+
+// disablesImage returns whether the pattern disables all versions of the image.
+func (p *disablePattern) disablesImage(name string) bool {
+	return len(p.version) == 0 && p.image(name)
+}
+
+// disablesVersion returns whether the pattern disables the version of the image.
+func (p *disablePattern) disablesVersion(name, version string) bool {
+	if !p.image(name) {
+		return false
+	}
+	if len(p.version) == 0 {
+		return true
+	}
+	if matched, _ := path.Match(p.version, version); matched {
+		return true
+	}
+	return !strings.ContainsAny(p.version, `*?[\`) && strings.HasPrefix(version, p.version+".")
+}
+
+// disabledVersion returns the first pattern which disables the version of the image, or nil.
+func disabledVersion(patterns []*disablePattern, name, version string) *disablePattern {
+	for _, pattern := range patterns {
+		if pattern.disablesVersion(name, version) {
+			return pattern
+		}
+	}
+	return nil
+}
+
+// checkDisablePatterns reports the patterns which match no version of the images, or returns an
+// *UnmatchedDisablePatternsError for them if strict is set.
+func checkDisablePatterns(ctx context.Context, patterns []*disablePattern, images []MachineImage, strict bool) error {
+	unmatched := []string{}
+	for _, pattern := range patterns {
+		if !pattern.matchesAny(images) {
+			unmatched = append(unmatched, pattern.pattern)
+		}
+	}
+	if len(unmatched) == 0 {
+		return nil
+	}
+	if strict {
+		return &UnmatchedDisablePatternsError{Patterns: unmatched}
+	}
+
+	_, reporter := FromContext(ctx)
+	for _, pattern := range unmatched {
+		reporter.Report(ReportEntry{
+			Reason:  ReasonUnmatchedDisablePattern,
+			Message: fmt.Sprintf("disabled machine image %s matches nothing", pattern),
+		})
+	}
+	return nil
+}
+
+func (p *disablePattern) matchesAny(images []MachineImage) bool {
+	for _, image := range images {
+		if p.disablesImage(image.Name) {
+			return true
+		}
+		for _, version := range image.Versions {
+			if p.disablesVersion(image.Name, versionOrEmpty(version)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("disabled providers", func() {
+
+	regions := func(ami string) []interface{} {
+		return []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": ami}}
+	}
+
+	imports := func() *Imports {
+		return &Imports{
+			MachineImages: []MachineImage{
+				{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0"}, {"version": "934.6.0"}}},
+			},
+			MachineImagesProvider: []MachineImage{
+				{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+					{"version": "934.7.0", "regions": regions("ami-1")}, {"version": "934.6.0", "regions": regions("ami-2")},
+				}},
+			},
+			ComputeMachineImagesOptions: ComputeMachineImagesOptions{Provider: "aws"},
+		}
+	}
+
+	It("should suppress the output of a disabled provider and report the suppressed versions", func() {
+		disabled := imports()
+		disabled.DisableProviders = []string{"aws"}
+		result, warnings, err := ComputeMachineImagesWithWarnings(context.Background(), logr.Discard(), disabled)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(BeEmpty())
+		Expect(warnings).To(Equal([]ReportEntry{
+			{Provider: "aws", Image: OsNameGardenLinux, Version: "934.7.0", Reason: ReasonProviderDisabled, Message: "provider aws is disabled"},
+			{Provider: "aws", Image: OsNameGardenLinux, Version: "934.6.0", Reason: ReasonProviderDisabled, Message: "provider aws is disabled"},
+		}))
+	})
+
+	It("should combine the disabled providers and the provider enable flags", func() {
+		options := &ComputeMachineImagesOptions{
+			DisableProviders: []string{"gcp", "aws"},
+			ProviderEnabled:  map[string]bool{"aws": true, "azure": false, "openstack": true},
+		}
+		Expect(options.ProviderDisabled("aws")).To(BeTrue())
+		Expect(options.ProviderDisabled("azure")).To(BeTrue())
+		Expect(options.ProviderDisabled("openstack")).To(BeFalse())
+		Expect(options.DisabledProviders()).To(Equal([]string{"aws", "azure", "gcp"}))
+
+		enabled := imports()
+		enabled.ProviderEnabled = map[string]bool{"aws": true, "gcp": false}
+		result, err := ComputeMachineImagesFromImports(context.Background(), logr.Discard(), enabled)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(enabled.MachineImagesProvider))
+	})
+
+	It("should omit the partitions of disabled providers", func() {
+		partitions, err := PartitionByProvider(imports().MachineImagesProvider,
+			&PartitionOptions{Providers: []string{"aws", "gcp"}, Disabled: []string{"gcp"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(partitions).To(HaveKey("aws"))
+		Expect(partitions).NotTo(HaveKey("gcp"))
+	})
+
+	It("should reject empty provider types", func() {
+		invalid := imports()
+		invalid.DisableProviders = []string{""}
+		invalid.ProviderEnabled = map[string]bool{"": false}
+		err := ValidateImports(invalid)
+		Expect(err).To(MatchError(ContainSubstring("disableProviders: empty provider type")))
+		Expect(err).To(MatchError(ContainSubstring("providerEnabled: empty provider type")))
+	})
+})
@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("disabled machine images", func() {
+
+	var (
+		images   []MachineImage
+		provider []MachineImage
+	)
+
+	compute := func(disabled []string, options *ComputeMachineImagesOptions) ([]string, error) {
+		result, err := ComputeMachineImagesWithOptions(context.Background(), logr.Discard(), images, nil, provider, nil,
+			disabled, nil, nil, options)
+		if err != nil {
+			return nil, err
+		}
+		refs := []string{}
+		for _, image := range result {
+			for _, version := range image.Versions {
+				refs = append(refs, image.Name+":"+versionOrEmpty(version))
+			}
+		}
+		return refs, nil
+	}
+
+	BeforeEach(func() {
+		images = []MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0"}, {"version": "934.7.1"}, {"version": "934.8.0"}}},
+			{Name: "suse-chost", Versions: []MachineImageVersion{{"version": "15.3.0"}}},
+			{Name: "suse-sles", Versions: []MachineImageVersion{{"version": "15.2.0"}}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "18.4.0"}}},
+		}
+		provider = []MachineImage{}
+		for _, image := range images {
+			versions := []MachineImageVersion{}
+			for _, version := range image.Versions {
+				versions = append(versions, MachineImageVersion{"version": version["version"], "ami": "ami-1"})
+			}
+			provider = append(provider, MachineImage{Name: image.Name, Versions: versions})
+		}
+	})
+
+	It("should disable images by exact names, globs and regular expressions", func() {
+		Expect(compute([]string{OsNameUbuntu}, nil)).To(Equal([]string{
+			"gardenlinux:934.8.0", "gardenlinux:934.7.1", "gardenlinux:934.7.0", "suse-chost:15.3.0", "suse-sles:15.2.0",
+		}))
+		Expect(compute([]string{"suse-*"}, nil)).To(Equal([]string{
+			"gardenlinux:934.8.0", "gardenlinux:934.7.1", "gardenlinux:934.7.0", "ubuntu:18.4.0",
+		}))
+		Expect(compute([]string{"/garden.*|ubuntu/"}, nil)).To(Equal([]string{"suse-chost:15.3.0", "suse-sles:15.2.0"}))
+	})
+
+	It("should disable versions of images", func() {
+		Expect(compute([]string{"gardenlinux:934.7", "suse-*:15.3.?", "/suse-.*/:15.2.0"}, nil)).To(Equal([]string{
+			"gardenlinux:934.8.0", "ubuntu:18.4.0",
+		}))
+	})
+
+	It("should report or fail on patterns which match nothing", func() {
+		report := NewReport()
+		_, err := compute([]string{"ubuntu", "suse-chst", "gardenlinux:1.0"}, &ComputeMachineImagesOptions{Reporter: report})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Entries()).To(Equal([]ReportEntry{
+			{Reason: ReasonUnmatchedDisablePattern, Message: "disabled machine image suse-chst matches nothing"},
+			{Reason: ReasonUnmatchedDisablePattern, Message: "disabled machine image gardenlinux:1.0 matches nothing"},
+			{Image: OsNameUbuntu, Version: "18.4.0", Reason: ReasonDisabled, Message: "disabled by ubuntu"},
+		}))
+
+		_, err = compute([]string{"ubuntu", "suse-chst", "gardenlinux:1.0"}, &ComputeMachineImagesOptions{StrictDisableMachineImages: true})
+		Expect(err).To(Equal(&UnmatchedDisablePatternsError{Patterns: []string{"suse-chst", "gardenlinux:1.0"}}))
+		Expect(err).To(MatchError("disabled machine images match nothing: suse-chst, gardenlinux:1.0"))
+	})
+
+	It("should validate the patterns", func() {
+		Expect(ValidateDisableMachineImages([]string{"suse-*", "gardenlinux:934.*", "/^suse-(chost|sles)$/:15"})).To(Succeed())
+		Expect(ValidateDisableMachineImages([]string{"suse-["})).To(MatchError(ContainSubstring(`invalid disabled machine image "suse-["`)))
+		Expect(ValidateDisableMachineImages([]string{"/suse-(/"})).To(HaveOccurred())
+		Expect(ValidateDisableMachineImages([]string{"/suse"})).To(MatchError(ContainSubstring("unterminated regular expression")))
+		Expect(ValidateDisableMachineImages([]string{"gardenlinux:"})).To(MatchError(ContainSubstring("empty version")))
+		Expect(ValidateDisableMachineImages([]string{":1.0"})).To(MatchError(ContainSubstring("empty image")))
+
+		_, err := compute([]string{"/suse-(/"}, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(ValidateImports(&Imports{DisableMachineImages: []string{"suse-["}})).To(MatchError(ContainSubstring("disableMachineImages: invalid disabled machine image")))
+	})
+
+	It("should explain versions disabled by patterns", func() {
+		explanation, err := ExplainVersion(context.Background(), logr.Discard(), &Imports{
+			MachineImages:         images,
+			MachineImagesProvider: provider,
+			DisableMachineImages:  []string{"gardenlinux:934.7"},
+		}, OsNameGardenLinux, "934.7.1")
+		Expect(err).NotTo(HaveOccurred())
+		last := explanation.Steps[len(explanation.Steps)-1]
+		Expect(last.Stage).To(Equal(StageDisabled))
+		Expect(last.Passed).To(BeFalse())
+		Expect(last.Message).To(Equal("disabled by gardenlinux:934.7"))
+	})
+})
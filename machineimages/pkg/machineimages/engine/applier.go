@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"time"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+// Applier applies the result of a run to state which outlives the run, e.g. a state store. Appliers run after all
+// emitters.
+type Applier interface {
+	Apply(ctx context.Context, result *Result) error
+}
+
+// ApplierFunc is an Applier which calls the function.
+type ApplierFunc func(ctx context.Context, result *Result) error
+
+// Apply calls the function.
+func (f ApplierFunc) Apply(ctx context.Context, result *Result) error {
+	return f(ctx, result)
+}
+
+// SoakApplier records the versions of the machine images which the landscape did not see before in the soak state of
+// the store, like the soak state of the machineimages command.
+func SoakApplier(store state.Store, landscape string) Applier {
+	return ApplierFunc(func(ctx context.Context, result *Result) error {
+		tracker, err := state.LoadSoakTracker(ctx, store)
+		if err != nil {
+			return err
+		}
+		if !tracker.Observe(landscape, result.MachineImages, time.Now()) {
+			return nil
+		}
+
+		mi.LoggerFromContext(ctx).Info("Writing soak state", "landscape", landscape)
+		return state.SaveSoakTracker(ctx, store, tracker)
+	})
+}
+
+// HistoryApplier records the machine images as revision in the history of the store, see mi.History.Record. Machine
+// images which only differ cosmetically from the newest revision, see the diff options of the imports, are no new
+// revision.
+func HistoryApplier(store state.Store, maxRevisions int) Applier {
+	return ApplierFunc(func(ctx context.Context, result *Result) error {
+		history, err := state.LoadHistory(ctx, store)
+		if err != nil {
+			return err
+		}
+		changed, err := history.RecordWithOptions(result.MachineImages, time.Now(), maxRevisions, result.Imports.Diff)
+		if err != nil || !changed {
+			return err
+		}
+
+		mi.LoggerFromContext(ctx).Info("Writing revision history")
+		return state.SaveHistory(ctx, store, history)
+	})
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+var _ = Describe("appliers", func() {
+
+	result := &Result{Imports: &mi.Imports{}, MachineImages: []mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{
+		{"version": "22.4.0"},
+	}}}}
+
+	It("should record the versions in the soak state", func() {
+		store := state.NewMemoryStore()
+		Expect(SoakApplier(store, "dev").Apply(context.Background(), result)).To(Succeed())
+
+		tracker, err := state.LoadSoakTracker(context.Background(), store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tracker.Records).To(HaveLen(1))
+		Expect(tracker.Records[0].Landscape).To(Equal("dev"))
+	})
+
+	It("should record the machine images in the history", func() {
+		store := state.NewMemoryStore()
+		applier := HistoryApplier(store, 0)
+		Expect(applier.Apply(context.Background(), result)).To(Succeed())
+		Expect(applier.Apply(context.Background(), result)).To(Succeed())
+
+		history, err := state.LoadHistory(context.Background(), store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.Revisions).To(HaveLen(1))
+	})
+
+	It("should not record machine images which only differ cosmetically in the history", func() {
+		store := state.NewMemoryStore()
+		applier := HistoryApplier(store, 0)
+		Expect(applier.Apply(context.Background(), result)).To(Succeed())
+		Expect(applier.Apply(context.Background(), &Result{
+			Imports: &mi.Imports{ComputeMachineImagesOptions: mi.ComputeMachineImagesOptions{
+				Diff: &mi.DiffOptions{IgnoreFields: []string{"annotations"}},
+			}},
+			MachineImages: []mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{
+				{"version": "22.4.0", "annotations": map[string]interface{}{"built": "monday"}},
+			}}},
+		})).To(Succeed())
+
+		history, err := state.LoadHistory(context.Background(), store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.Revisions).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/cloudprofile"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// Emitter writes the result of a run in some format, e.g. to a file.
+type Emitter interface {
+	Emit(ctx context.Context, result *Result) error
+}
+
+// EmitterFunc is an Emitter which calls the function.
+type EmitterFunc func(ctx context.Context, result *Result) error
+
+// Emit calls the function.
+func (f EmitterFunc) Emit(ctx context.Context, result *Result) error {
+	return f(ctx, result)
+}
+
+// FileEmitter writes the data which Marshal returns for the result to a file.
+type FileEmitter struct {
+	// Name is the name of the format in the logs.
+	Name    string
+	Path    string
+	Marshal func(result *Result) ([]byte, error)
+}
+
+// Emit writes the file, the parent directories are created if they do not exist.
+func (e *FileEmitter) Emit(ctx context.Context, result *Result) error {
+	data, err := e.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	mi.LoggerFromContext(ctx).Info("Writing "+e.Name, "path", e.Path)
+	if err := os.MkdirAll(filepath.Dir(e.Path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(e.Path, data, os.ModePerm)
+}
+
+// ExportsFile writes the exports as yaml, like the exports path of the machineimages command.
+func ExportsFile(path string) Emitter {
+	return &FileEmitter{Name: "exports", Path: path, Marshal: func(result *Result) ([]byte, error) {
+		return yaml.Marshal(result.Exports)
+	}}
+}
+
+// CycloneDXFile writes a CycloneDX bom of the machine images, see mi.NewCycloneDXBOM. The options may be nil.
+func CycloneDXFile(path string, options *mi.CycloneDXOptions) Emitter {
+	return &FileEmitter{Name: "CycloneDX bom", Path: path, Marshal: func(result *Result) ([]byte, error) {
+		bom, err := mi.NewCycloneDXBOM(result.MachineImages, options)
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(bom, "", "  ")
+	}}
+}
+
+// TerraformVariablesFile writes the image ids of the machine images as terraform variables, as json if the path ends
+// with .json and as hcl otherwise. The options may be nil. If the options have no aliases, the version aliases of the
+// exports are written.
+func TerraformVariablesFile(path string, options *mi.TerraformOptions) Emitter {
+	return &FileEmitter{Name: "terraform variables", Path: path, Marshal: func(result *Result) ([]byte, error) {
+		format := mi.TerraformFormatHCL
+		if filepath.Ext(path) == ".json" {
+			format = mi.TerraformFormatJSON
+		}
+		idOptions := &mi.TerraformOptions{}
+		if options != nil {
+			*idOptions = *options
+		}
+		if idOptions.Aliases == nil {
+			idOptions.Aliases = result.Exports.ResultVersionAliases
+		}
+		return mi.MarshalTerraformVariables(mi.NewTerraformImageIDs(result.MachineImages, idOptions), format, options)
+	}}
+}
+
+// CAPIImageLookupFile writes the cluster api image lookup of the machine images, including the version aliases of
+// the exports, as yaml.
+func CAPIImageLookupFile(path string) Emitter {
+	return &FileEmitter{Name: "cluster api image lookup", Path: path, Marshal: func(result *Result) ([]byte, error) {
+		lookup := mi.NewCAPIImageLookup(result.MachineImages)
+		lookup.AddAliases(result.Exports.ResultVersionAliases)
+		return yaml.Marshal(lookup)
+	}}
+}
+
+// ImageVectorFile writes the image vector of the machine images, including the version aliases of the exports, as
+// yaml.
+func ImageVectorFile(path string) Emitter {
+	return &FileEmitter{Name: "image vector", Path: path, Marshal: func(result *Result) ([]byte, error) {
+		return yaml.Marshal(mi.NewImageVector(result.MachineImages, result.Exports.ResultVersionAliases))
+	}}
+}
+
+// PrewarmManifestFile writes the pre-warm manifest of the machine images as yaml. The options may be nil.
+func PrewarmManifestFile(path string, options *mi.PrewarmOptions) Emitter {
+	return &FileEmitter{Name: "pre-warm manifest", Path: path, Marshal: func(result *Result) ([]byte, error) {
+		return yaml.Marshal(mi.NewPrewarmManifest(result.MachineImages, options))
+	}}
+}
+
+// CloudProfileFile writes the cloud profile with the name, the inputs and the machine images of the result as yaml, in
+// the target api version of the inputs, see cloudprofile.BuildConvertedCloudProfile. The machine images of the inputs
+// are ignored.
+func CloudProfileFile(path, name string, inputs *cloudprofile.Inputs) Emitter {
+	return &FileEmitter{Name: "cloud profile", Path: path, Marshal: func(result *Result) ([]byte, error) {
+		profileInputs := *inputs
+		profileInputs.MachineImages = result.MachineImages
+		profile, err := cloudprofile.BuildConvertedCloudProfile(name, &profileInputs)
+		if err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(profile)
+	}}
+}
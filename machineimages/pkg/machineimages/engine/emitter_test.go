@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/cloudprofile"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/kubernetesversions"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machinetypes"
+)
+
+var _ = Describe("emitters", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "engine")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	images := []mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{
+		{"version": "22.4.0", "image": "ami-1"},
+	}}}
+	result := &Result{MachineImages: images, Exports: &mi.Exports{ResultMachineImages: images}}
+
+	It("should write the exports and create the parent directories", func() {
+		path := filepath.Join(dir, "out", "exports.yaml")
+		Expect(ExportsFile(path).Emit(context.Background(), result)).To(Succeed())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		exports := &mi.Exports{}
+		Expect(yaml.Unmarshal(data, exports)).To(Succeed())
+		Expect(exports.ResultMachineImages).To(Equal(images))
+	})
+
+	It("should write the formats of the machineimages command", func() {
+		emitters := map[string]Emitter{
+			"bom.json":     CycloneDXFile(filepath.Join(dir, "bom.json"), nil),
+			"images.json":  TerraformVariablesFile(filepath.Join(dir, "images.json"), nil),
+			"capi.yaml":    CAPIImageLookupFile(filepath.Join(dir, "capi.yaml")),
+			"images.yaml":  ImageVectorFile(filepath.Join(dir, "images.yaml")),
+			"prewarm.yaml": PrewarmManifestFile(filepath.Join(dir, "prewarm.yaml"), nil),
+		}
+		for name, emitter := range emitters {
+			Expect(emitter.Emit(context.Background(), result)).To(Succeed())
+			Expect(filepath.Join(dir, name)).To(BeAnExistingFile())
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "images.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(ContainSubstring("ami-1"))
+	})
+
+	It("should write the version aliases of the exports", func() {
+		aliased := &Result{MachineImages: images, Exports: &mi.Exports{ResultMachineImages: images,
+			ResultVersionAliases: mi.VersionAliases{mi.OsNameUbuntu: {"latest": "22.4.0"}}}}
+		path := filepath.Join(dir, "images.json")
+		Expect(TerraformVariablesFile(path, nil).Emit(context.Background(), aliased)).To(Succeed())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(ContainSubstring(`"latest": {`))
+	})
+
+	It("should write the cloud profile with the machine images of the result", func() {
+		inputs := &cloudprofile.Inputs{
+			Type:               "aws",
+			KubernetesVersions: []kubernetesversions.KubernetesVersion{{Version: "1.28.0"}},
+			MachineTypes:       []machinetypes.MachineType{{Name: "m5.large", CPU: "2", Memory: "8Gi"}},
+			Regions:            []cloudprofile.Region{{Name: "eu-west-1"}},
+			Capabilities:       true,
+		}
+		path := filepath.Join(dir, "cloudprofile.yaml")
+		Expect(CloudProfileFile(path, "aws", inputs).Emit(context.Background(), result)).To(Succeed())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		profile, err := cloudprofile.DecodeCloudProfile(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(profile.Metadata.Name).To(Equal("aws"))
+		Expect(profile.Spec.MachineImages).To(HaveLen(1))
+		Expect(profile.Spec.MachineImages[0].Versions[0]).To(HaveKeyWithValue("architectures", []interface{}{"amd64"}))
+		Expect(inputs.MachineImages).To(BeNil())
+	})
+})
@@ -0,0 +1,320 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package engine embeds the workflow of the machineimages command in other Go programs: it reads the imports from a
+// source, validates them, computes the machine images, post-processes them in stages and passes the result to emitters
+// and appliers. Programs which need more control compose the functions of the machineimages package directly.
+//
+//	e, err := engine.NewBuilder().
+//		WithImportsFile("imports.yaml").
+//		WithEmitter(engine.ExportsFile("exports.yaml")).
+//		Build()
+//	if err != nil {
+//		return err
+//	}
+//	result, err := e.Run(ctx)
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/go-logr/logr"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/attestation"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+// Validator checks the imports in addition to mi.ValidateImports, e.g. against the rules of an organization.
+type Validator func(imports *mi.Imports) error
+
+// Stage transforms the computed machine images before they are emitted and applied.
+type Stage func(ctx context.Context, images []mi.MachineImage) ([]mi.MachineImage, error)
+
+// ResultStage transforms the result after the approval and before it is emitted, e.g. to export release channels
+// instead of the computed machine images. Result stages which replace the exports also replace the machine images by
+// the exported machine images.
+type ResultStage func(ctx context.Context, result *Result) error
+
+// Result is the result of a run of the engine.
+type Result struct {
+	// Imports are the validated imports with resolved selections and secrets.
+	Imports *mi.Imports
+	// MachineImages are the computed machine images after all stages.
+	MachineImages []mi.MachineImage
+	// Exports are the exports of the machine images, in a config map if the imports configure a config map output.
+	Exports *mi.Exports
+}
+
+// Engine runs the workflow which a Builder configured. An engine may be run repeatedly, e.g. whenever the imports of
+// the source changed.
+type Engine struct {
+	log               logr.Logger
+	source            Source
+	selectionResolver *mi.SelectionResolver
+	secretResolver    *mi.SecretResolver
+	validators        []Validator
+	stages            []Stage
+	resultStages      []ResultStage
+	emitters          []Emitter
+	appliers          []Applier
+	approval          *state.Approval
+
+	attestationPath string
+	policy          *attestation.VerificationPolicy
+}
+
+// Run reads the imports from the source, resolves their selections, validates them, resolves their secrets and computes
+// the machine images. With focus, the machine images are merged into the focus baseline of the imports or, without
+// baseline, into the machine images which the approval recorded as applied, see mi.MergeFocusedBaseline. The stages
+// transform the machine images in their order, before their exports are built, see mi.NewExports. With an approval, the
+// change from the applied machine images must be approved. Then the result stages transform the result, which is passed
+// to the emitters and afterwards to the appliers, and is recorded as applied. The errors are classified like the errors
+// of the machineimages command, see mi.ClassOf.
+func (e *Engine) Run(ctx context.Context) (*Result, error) {
+	ctx = mi.NewContext(ctx, e.log, nil)
+	imports, err := e.readImports(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := imports.ResolveSelection(ctx, e.selectionResolver); err != nil {
+		return nil, mi.ClassifyError(err, mi.ErrorClassFetch)
+	}
+
+	if err := mi.ValidateImports(imports); err != nil {
+		return nil, err
+	}
+	for _, validate := range e.validators {
+		if err := validate(imports); err != nil {
+			return nil, mi.ClassifyError(err, mi.ErrorClassValidation)
+		}
+	}
+
+	if err := imports.ResolveSecrets(ctx, e.secretResolver); err != nil {
+		return nil, mi.ClassifyError(err, mi.ErrorClassFetch)
+	}
+
+	images, warnings, err := mi.ComputeMachineImagesWithWarnings(ctx, e.log, imports)
+	if err != nil {
+		return nil, err
+	}
+	if len(imports.Focus) > 0 && imports.FocusBaseline == nil && e.approval != nil {
+		if imports.FocusBaseline, err = state.LoadFocusBaseline(ctx, e.approval.Store); err != nil {
+			return nil, mi.ClassifyError(err, mi.ErrorClassFetch)
+		}
+	}
+	if images, warnings, err = imports.MergeFocusedBaseline(images, warnings); err != nil {
+		return nil, err
+	}
+	for _, stage := range e.stages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if images, err = stage(ctx, images); err != nil {
+			return nil, err
+		}
+	}
+
+	exports, err := mi.NewExports(imports, images, warnings)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{Imports: imports, MachineImages: images, Exports: exports}
+
+	var request *mi.ApprovalRequest
+	if e.approval != nil {
+		if request, err = e.approval.Check(ctx, imports.Diff, images); err != nil {
+			return nil, mi.ClassifyError(err, mi.ErrorClassFetch)
+		}
+	}
+
+	for _, stage := range e.resultStages {
+		if err := stage(ctx, result); err != nil {
+			return nil, mi.ClassifyError(err, mi.ErrorClassApply)
+		}
+	}
+	for _, emitter := range e.emitters {
+		if err := emitter.Emit(ctx, result); err != nil {
+			return nil, mi.ClassifyError(err, mi.ErrorClassApply)
+		}
+	}
+	for _, applier := range e.appliers {
+		if err := applier.Apply(ctx, result); err != nil {
+			return nil, mi.ClassifyError(err, mi.ErrorClassApply)
+		}
+	}
+	if e.approval != nil {
+		if err := e.approval.Applied(ctx, request, images); err != nil {
+			return nil, mi.ClassifyError(err, mi.ErrorClassApply)
+		}
+	}
+	return result, nil
+}
+
+// readImports reads the imports from the source. If an attestation is configured, the content of the imports must be
+// attested according to the policy.
+func (e *Engine) readImports(ctx context.Context) (*mi.Imports, error) {
+	if e.policy == nil {
+		return e.source.Imports(ctx)
+	}
+
+	content, imports, err := e.source.(ContentSource).Content(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(e.attestationPath)
+	if err != nil {
+		return nil, mi.ClassifyError(err, mi.ErrorClassPolicy)
+	}
+	envelope, err := attestation.ParseEnvelope(data)
+	if err != nil {
+		return nil, mi.ClassifyError(fmt.Errorf("unable to parse attestation %s: %w", e.attestationPath, err),
+			mi.ErrorClassPolicy)
+	}
+	if _, err := attestation.Verify(envelope, content, e.policy); err != nil {
+		return nil, mi.ClassifyError(err, mi.ErrorClassPolicy)
+	}
+	mi.LoggerFromContext(ctx).Info("Attestation of the imports verified", "attestation-path", e.attestationPath)
+	return imports, nil
+}
+
+// Builder configures an engine. Only the source is required.
+type Builder struct {
+	engine *Engine
+}
+
+// NewBuilder returns a builder of an engine which logs nothing.
+func NewBuilder() *Builder {
+	return &Builder{engine: &Engine{log: logr.Discard()}}
+}
+
+// WithLogger sets the logger of the computation and of the emitters and appliers.
+func (b *Builder) WithLogger(log logr.Logger) *Builder {
+	b.engine.log = log
+	return b
+}
+
+// WithSource sets the source of the imports.
+func (b *Builder) WithSource(source Source) *Builder {
+	b.engine.source = source
+	return b
+}
+
+// WithImportsFile reads the imports from the yaml or json file at the path, see FileSource.
+func (b *Builder) WithImportsFile(path string) *Builder {
+	return b.WithSource(&FileSource{Path: path})
+}
+
+// WithImports uses the given imports, see StaticSource.
+func (b *Builder) WithImports(imports *mi.Imports) *Builder {
+	return b.WithSource(StaticSource(imports))
+}
+
+// WithSelectionResolver sets the resolver of the selections of the imports. Without resolver, selections are only read
+// from files.
+func (b *Builder) WithSelectionResolver(resolver *mi.SelectionResolver) *Builder {
+	b.engine.selectionResolver = resolver
+	return b
+}
+
+// WithSecretResolver sets the resolver of the secret values of the imports. Without resolver, secrets are only read
+// from files and environment variables.
+func (b *Builder) WithSecretResolver(resolver *mi.SecretResolver) *Builder {
+	b.engine.secretResolver = resolver
+	return b
+}
+
+// WithValidator adds a validator of the imports.
+func (b *Builder) WithValidator(validator Validator) *Builder {
+	b.engine.validators = append(b.engine.validators, validator)
+	return b
+}
+
+// WithStage adds a stage after the stages which were added before.
+func (b *Builder) WithStage(stage Stage) *Builder {
+	b.engine.stages = append(b.engine.stages, stage)
+	return b
+}
+
+// WithResultStage adds a result stage after the result stages which were added before.
+func (b *Builder) WithResultStage(stage ResultStage) *Builder {
+	b.engine.resultStages = append(b.engine.resultStages, stage)
+	return b
+}
+
+// WithEmitter adds an emitter of the result.
+func (b *Builder) WithEmitter(emitter Emitter) *Builder {
+	b.engine.emitters = append(b.engine.emitters, emitter)
+	return b
+}
+
+// WithApplier adds an applier of the result.
+func (b *Builder) WithApplier(applier Applier) *Builder {
+	b.engine.appliers = append(b.engine.appliers, applier)
+	return b
+}
+
+// WithApproval requires that changes of the machine images are approved before they are emitted and applied, see
+// state.Approval.
+func (b *Builder) WithApproval(approval *state.Approval) *Builder {
+	b.engine.approval = approval
+	return b
+}
+
+// WithAttestation requires that the attestation at the path attests the content of the imports according to the
+// policy. The engine refuses to run otherwise. The source must be a ContentSource, e.g. a FileSource.
+func (b *Builder) WithAttestation(path string, policy *attestation.VerificationPolicy) *Builder {
+	b.engine.attestationPath = path
+	b.engine.policy = policy
+	return b
+}
+
+// Build returns the configured engine. Further changes of the builder do not modify the engine.
+func (b *Builder) Build() (*Engine, error) {
+	if b.engine.source == nil {
+		return nil, errors.New("a source of the imports must be provided")
+	}
+	if b.engine.policy != nil {
+		if len(b.engine.attestationPath) == 0 {
+			return nil, errors.New("an attestation path must be provided together with the verification policy")
+		}
+		if _, ok := b.engine.source.(ContentSource); !ok {
+			return nil, fmt.Errorf("the verification of attestations requires a content source, but the source is a %T",
+				b.engine.source)
+		}
+	}
+	if b.engine.approval != nil {
+		if err := b.engine.approval.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	for i, validator := range b.engine.validators {
+		if validator == nil {
+			return nil, fmt.Errorf("validator %d must not be nil", i)
+		}
+	}
+	for i, stage := range b.engine.stages {
+		if stage == nil {
+			return nil, fmt.Errorf("stage %d must not be nil", i)
+		}
+	}
+
+	for i, stage := range b.engine.resultStages {
+		if stage == nil {
+			return nil, fmt.Errorf("result stage %d must not be nil", i)
+		}
+	}
+
+	engine := *b.engine
+	engine.validators = append([]Validator{}, b.engine.validators...)
+	engine.stages = append([]Stage{}, b.engine.stages...)
+	engine.resultStages = append([]ResultStage{}, b.engine.resultStages...)
+	engine.emitters = append([]Emitter{}, b.engine.emitters...)
+	engine.appliers = append([]Applier{}, b.engine.appliers...)
+	return &engine, nil
+}
@@ -0,0 +1,317 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/attestation"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+var _ = Describe("engine", func() {
+
+	imports := func() *mi.Imports {
+		return &mi.Imports{
+			MachineImages: []mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{
+				{"version": "22.4.0"}, {"version": "20.4.0"},
+			}}},
+			MachineImagesProvider: []mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{
+				{"version": "22.4.0", "image": "a"}, {"version": "20.4.0", "image": "b"},
+			}}},
+		}
+	}
+
+	It("should require a source", func() {
+		_, err := NewBuilder().Build()
+		Expect(err).To(MatchError("a source of the imports must be provided"))
+	})
+
+	It("should run the stages, emitters and appliers in their order", func() {
+		calls := []string{}
+		e, err := NewBuilder().
+			WithImports(imports()).
+			WithStage(func(ctx context.Context, images []mi.MachineImage) ([]mi.MachineImage, error) {
+				calls = append(calls, "stage")
+				images[0].Versions = images[0].Versions[:1]
+				return images, nil
+			}).
+			WithApplier(ApplierFunc(func(ctx context.Context, result *Result) error {
+				calls = append(calls, "applier")
+				return nil
+			})).
+			WithEmitter(EmitterFunc(func(ctx context.Context, result *Result) error {
+				calls = append(calls, "emitter")
+				Expect(result.Exports.ResultMachineImages).To(Equal(result.MachineImages))
+				return nil
+			})).
+			Build()
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := e.Run(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal([]string{"stage", "emitter", "applier"}))
+		Expect(result.MachineImages).To(Equal([]mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{
+			{"version": "22.4.0", "image": "a"},
+		}}}))
+	})
+
+	It("should emit the result of the result stages", func() {
+		current := []mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "20.4.0"}}}}
+		calls := []string{}
+		e, err := NewBuilder().
+			WithImports(imports()).
+			WithEmitter(EmitterFunc(func(ctx context.Context, result *Result) error {
+				calls = append(calls, "emitter")
+				Expect(result.MachineImages).To(Equal(current))
+				Expect(result.Exports.ResultMachineImagesCandidate).To(HaveLen(1))
+				return nil
+			})).
+			WithResultStage(func(ctx context.Context, result *Result) error {
+				calls = append(calls, "result stage")
+				result.Exports = &mi.Exports{ResultMachineImages: current, ResultMachineImagesCandidate: result.MachineImages}
+				result.MachineImages = current
+				return nil
+			}).
+			Build()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = e.Run(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls).To(Equal([]string{"result stage", "emitter"}))
+
+		_, err = NewBuilder().WithImports(imports()).WithResultStage(nil).Build()
+		Expect(err).To(MatchError("result stage 0 must not be nil"))
+	})
+
+	It("should export the machine images in a config map if configured", func() {
+		i := imports()
+		i.ConfigMapOutput = &mi.ConfigMapOutput{Name: "images", Namespace: "default"}
+		e, err := NewBuilder().WithImports(i).Build()
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := e.Run(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.MachineImages).To(HaveLen(1))
+		Expect(result.Exports.ResultMachineImages).To(BeEmpty())
+		Expect(result.Exports.ResultMachineImagesConfigMap).NotTo(BeNil())
+	})
+
+	It("should classify the errors of the validators and appliers", func() {
+		e, err := NewBuilder().
+			WithImports(imports()).
+			WithValidator(func(imports *mi.Imports) error { return errors.New("no ubuntu allowed") }).
+			Build()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = e.Run(context.Background())
+		Expect(err).To(MatchError("no ubuntu allowed"))
+		Expect(mi.ClassOf(err)).To(Equal(mi.ErrorClassValidation))
+
+		e, err = NewBuilder().
+			WithImports(imports()).
+			WithApplier(ApplierFunc(func(ctx context.Context, result *Result) error { return errors.New("store unavailable") })).
+			Build()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = e.Run(context.Background())
+		Expect(mi.ClassOf(err)).To(Equal(mi.ErrorClassApply))
+	})
+
+	It("should validate the imports before the custom validators", func() {
+		called := false
+		e, err := NewBuilder().
+			WithImports(&mi.Imports{IncludeFilters: []mi.OsImagesFilterKind{"unknown"}}).
+			WithValidator(func(imports *mi.Imports) error {
+				called = true
+				return nil
+			}).
+			Build()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = e.Run(context.Background())
+		Expect(mi.ClassOf(err)).To(Equal(mi.ErrorClassValidation))
+		Expect(called).To(BeFalse())
+	})
+
+	It("should not be modified by later changes of the builder", func() {
+		builder := NewBuilder().WithImports(imports())
+		e, err := builder.Build()
+		Expect(err).NotTo(HaveOccurred())
+		builder.WithStage(func(ctx context.Context, images []mi.MachineImage) ([]mi.MachineImage, error) {
+			return nil, errors.New("unexpected stage")
+		})
+
+		_, err = e.Run(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should gate removals of the applied machine images and consume the approval once applied", func() {
+		store := state.NewMemoryStore()
+		approval := &state.Approval{Gate: state.NewAcknowledgementGate(store), Store: store,
+			Threshold: &mi.ApprovalThreshold{Impact: mi.ImpactRemoval}}
+		run := func(i *mi.Imports) (bool, error) {
+			emitted := false
+			e, err := NewBuilder().WithImports(i).WithApproval(approval).
+				WithEmitter(EmitterFunc(func(ctx context.Context, result *Result) error {
+					emitted = true
+					return nil
+				})).
+				Build()
+			Expect(err).NotTo(HaveOccurred())
+			_, err = e.Run(context.Background())
+			return emitted, err
+		}
+
+		Expect(run(imports())).To(BeTrue())
+		applied, ok, err := state.LoadApplied(context.Background(), store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(applied[0].Versions).To(HaveLen(2))
+
+		removal := imports()
+		removal.MachineImages[0].Versions = removal.MachineImages[0].Versions[:1]
+		emitted, err := run(removal)
+		var pendingErr *mi.ApprovalPendingError
+		Expect(errors.As(err, &pendingErr)).To(BeTrue())
+		Expect(mi.ClassOf(err)).To(Equal(mi.ErrorClassPolicy))
+		Expect(emitted).To(BeFalse())
+
+		acknowledgement := state.ApprovalKeyPrefix + pendingErr.Request.ID
+		Expect(store.Put(context.Background(), acknowledgement, []byte("jane"))).To(Succeed())
+		Expect(run(removal)).To(BeTrue())
+		_, err = store.Get(context.Background(), acknowledgement)
+		Expect(err).To(MatchError(state.ErrNotFound))
+		applied, _, err = state.LoadApplied(context.Background(), store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied[0].Versions).To(HaveLen(1))
+	})
+
+	It("should merge the focused images into the applied machine images of the approval", func() {
+		store := state.NewMemoryStore()
+		approval := &state.Approval{Gate: state.NewAcknowledgementGate(store), Store: store,
+			Threshold: &mi.ApprovalThreshold{Impact: mi.ImpactRemoval}}
+		focused := imports()
+		focused.Focus = []string{mi.OsNameUbuntu}
+		e, err := NewBuilder().WithImports(focused).WithApproval(approval).Build()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = e.Run(context.Background())
+		Expect(mi.ClassOf(err)).To(Equal(mi.ErrorClassValidation))
+
+		flatcar := mi.MachineImage{Name: mi.OsNameFlatcar, Versions: []mi.MachineImageVersion{{"version": "3033.2.0"}}}
+		Expect(state.SaveApplied(context.Background(), store, []mi.MachineImage{flatcar})).To(Succeed())
+		result, err := e.Run(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.MachineImages).To(HaveLen(2))
+		Expect(result.MachineImages[0].Name).To(Equal(mi.OsNameFlatcar))
+		Expect(result.Exports.ResultMachineImages).To(Equal(result.MachineImages))
+	})
+
+	It("should require a valid approval", func() {
+		_, err := NewBuilder().WithImports(imports()).WithApproval(&state.Approval{}).Build()
+		Expect(err).To(MatchError("an approval requires a gate and a store"))
+	})
+
+	Context("attestation", func() {
+		var (
+			dir     string
+			policy  *attestation.VerificationPolicy
+			signer  *attestation.LocalSigner
+			content = []byte("machineImages:\n- name: ubuntu\n  versions:\n  - version: 22.4.0\n" +
+				"machineImagesProvider:\n- name: ubuntu\n  versions:\n  - version: 22.4.0\n    image: a\n")
+			attested = func(subject []byte) string {
+				statement, err := attestation.NewStatement(nil, []attestation.Artifact{{Name: "imports.yaml", Content: subject}}, nil)
+				Expect(err).NotTo(HaveOccurred())
+				envelope, err := attestation.Sign(context.Background(), statement, signer)
+				Expect(err).NotTo(HaveOccurred())
+				data, err := json.Marshal(envelope)
+				Expect(err).NotTo(HaveOccurred())
+				path := filepath.Join(dir, "attestation.json")
+				Expect(ioutil.WriteFile(path, data, 0600)).To(Succeed())
+				return path
+			}
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "engine")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "imports.yaml"), content, 0600)).To(Succeed())
+
+			public, private, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			der, err := x509.MarshalPKCS8PrivateKey(private)
+			Expect(err).NotTo(HaveOccurred())
+			signer, err = attestation.NewLocalSigner(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+			Expect(err).NotTo(HaveOccurred())
+			der, err = x509.MarshalPKIXPublicKey(public)
+			Expect(err).NotTo(HaveOccurred())
+			verifier, err := attestation.NewPublicKeyVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+			Expect(err).NotTo(HaveOccurred())
+			policy = &attestation.VerificationPolicy{Verifiers: []attestation.Verifier{verifier}}
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		It("should run if the imports are attested", func() {
+			e, err := NewBuilder().
+				WithImportsFile(filepath.Join(dir, "imports.yaml")).
+				WithAttestation(attested(content), policy).
+				Build()
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := e.Run(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.MachineImages).To(HaveLen(1))
+		})
+
+		It("should refuse imports which are not attested", func() {
+			applied := false
+			e, err := NewBuilder().
+				WithImportsFile(filepath.Join(dir, "imports.yaml")).
+				WithAttestation(attested([]byte("other imports")), policy).
+				WithApplier(ApplierFunc(func(ctx context.Context, result *Result) error {
+					applied = true
+					return nil
+				})).
+				Build()
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = e.Run(context.Background())
+			Expect(attestation.IsVerificationError(err)).To(BeTrue())
+			Expect(mi.ClassOf(err)).To(Equal(mi.ErrorClassPolicy))
+			Expect(applied).To(BeFalse())
+		})
+
+		It("should refuse to run without an attestation", func() {
+			e, err := NewBuilder().
+				WithImportsFile(filepath.Join(dir, "imports.yaml")).
+				WithAttestation(filepath.Join(dir, "missing.json"), policy).
+				Build()
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = e.Run(context.Background())
+			Expect(mi.ClassOf(err)).To(Equal(mi.ErrorClassPolicy))
+		})
+
+		It("should require a content source", func() {
+			_, err := NewBuilder().WithImports(imports()).WithAttestation("attestation.json", policy).Build()
+			Expect(err).To(MatchError(ContainSubstring("requires a content source")))
+		})
+	})
+})
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// Source provides the imports of a run of the engine. The engine modifies the returned imports, so sources return new
+// imports on every call.
+type Source interface {
+	Imports(ctx context.Context) (*mi.Imports, error)
+}
+
+// SourceFunc is a Source which calls the function.
+type SourceFunc func(ctx context.Context) (*mi.Imports, error)
+
+// Imports calls the function.
+func (f SourceFunc) Imports(ctx context.Context) (*mi.Imports, error) {
+	return f(ctx)
+}
+
+// ContentSource is a Source whose imports are parsed from content, e.g. from a file. Engines which verify attestations
+// require a ContentSource, so that the verified content is the content the imports are parsed from.
+type ContentSource interface {
+	Source
+	// Content returns the imports and the content they are parsed from.
+	Content(ctx context.Context) ([]byte, *mi.Imports, error)
+}
+
+// FileSource reads the imports from a yaml or json file, like the imports path of the machineimages command.
+type FileSource struct {
+	Path string
+}
+
+// Imports reads and parses the file.
+func (s *FileSource) Imports(ctx context.Context) (*mi.Imports, error) {
+	_, imports, err := s.Content(ctx)
+	return imports, err
+}
+
+// Content reads and parses the file and returns its content.
+func (s *FileSource) Content(ctx context.Context) ([]byte, *mi.Imports, error) {
+	mi.LoggerFromContext(ctx).Info("Reading imports", "imports-path", s.Path)
+
+	data, err := ioutil.ReadFile(s.Path)
+	if err != nil {
+		return nil, nil, mi.ClassifyError(err, mi.ErrorClassValidation)
+	}
+
+	imports, err := mi.LoadImports(data, nil)
+	if err != nil {
+		return nil, nil, mi.ClassifyError(fmt.Errorf("%s: %w", s.Path, err), mi.ErrorClassValidation)
+	}
+	return data, imports, nil
+}
+
+// StaticSource returns a source of the given imports, e.g. imports which a program assembled in memory. Every call
+// returns a copy whose selection lists can be extended without modifying the given imports. The image lists and
+// options are shared with the given imports.
+func StaticSource(imports *mi.Imports) Source {
+	return SourceFunc(func(ctx context.Context) (*mi.Imports, error) {
+		if imports == nil {
+			return nil, mi.ClassifyError(errors.New("no imports provided"), mi.ErrorClassValidation)
+		}
+		copied := *imports
+		copied.DisableMachineImages = append([]string{}, imports.DisableMachineImages...)
+		copied.IncludeFilters = append([]mi.OsImagesFilterKind{}, imports.IncludeFilters...)
+		copied.ExcludeFilters = append([]mi.OsImagesFilterKind{}, imports.ExcludeFilters...)
+		return &copied, nil
+	})
+}
@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package engine
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+var _ = Describe("sources", func() {
+
+	It("should read the imports of a file", func() {
+		dir, err := ioutil.TempDir("", "engine")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "imports.yaml")
+		Expect(ioutil.WriteFile(path, []byte("machineImages:\n- name: ubuntu\n  versions:\n  - version: 22.4.0\n"), 0600)).To(Succeed())
+
+		imports, err := (&FileSource{Path: path}).Imports(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imports.MachineImages).To(Equal([]mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{
+			{"version": "22.4.0"},
+		}}}))
+
+		_, err = (&FileSource{Path: filepath.Join(dir, "missing.yaml")}).Imports(context.Background())
+		Expect(mi.ClassOf(err)).To(Equal(mi.ErrorClassValidation))
+	})
+
+	It("should not modify static imports", func() {
+		imports := &mi.Imports{DisableMachineImages: []string{"ubuntu:20.4.0"}}
+		source := StaticSource(imports)
+
+		copied, err := source.Imports(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		copied.DisableMachineImages = append(copied.DisableMachineImages, "ubuntu:22.4.0")
+		copied.ExcludeFilters = append(copied.ExcludeFilters, mi.OsImagesFilterKindPreview)
+		Expect(imports.DisableMachineImages).To(Equal([]string{"ubuntu:20.4.0"}))
+		Expect(imports.ExcludeFilters).To(BeEmpty())
+
+		_, err = StaticSource(nil).Imports(context.Background())
+		Expect(err).To(MatchError("no imports provided"))
+	})
+})
@@ -0,0 +1,308 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGardenLinuxReleasesURL lists the releases of Garden Linux.
+	DefaultGardenLinuxReleasesURL = "https://api.github.com/repos/gardenlinux/gardenlinux/releases"
+	// DefaultFlatcarReleasesURL lists the releases of the stable Flatcar channel.
+	DefaultFlatcarReleasesURL = "https://www.flatcar.org/releases-json/releases-stable.json"
+	// DefaultEndOfLifeURL is the api of endoflife.date. The product is appended as "<product>.json".
+	DefaultEndOfLifeURL = "https://endoflife.date/api/"
+)
+
+// VersionMetadata is additional information about a version from the vendor of the operating system.
+type VersionMetadata struct {
+	ReleaseNotesURL string     `json:"releaseNotesURL,omitempty"`
+	KernelVersion   string     `json:"kernelVersion,omitempty"`
+	EndOfLife       *time.Time `json:"endOfLife,omitempty"`
+}
+
+// merge sets all fields which are empty in m from other.
+func (m *VersionMetadata) merge(other *VersionMetadata) {
+	if len(m.ReleaseNotesURL) == 0 {
+		m.ReleaseNotesURL = other.ReleaseNotesURL
+	}
+	if len(m.KernelVersion) == 0 {
+		m.KernelVersion = other.KernelVersion
+	}
+	if m.EndOfLife == nil {
+		m.EndOfLife = other.EndOfLife
+	}
+}
+
+// EnrichedVersion is a version together with its metadata.
+type EnrichedVersion struct {
+	VersionRef `json:",inline"`
+	Metadata   VersionMetadata `json:"metadata"`
+}
+
+// Enricher provides metadata of the versions of some images.
+type Enricher interface {
+	// Supports returns whether the enricher provides metadata of the image.
+	Supports(image string) bool
+	// Metadata returns the metadata of a version of the image or nil if the vendor does not know the version.
+	Metadata(ctx context.Context, image, version string) (*VersionMetadata, error)
+}
+
+// EnrichMachineImages returns the metadata of all versions of the images. The metadata of all enrichers which support
+// an image are merged, earlier enrichers take precedence. Versions without metadata are omitted.
+func EnrichMachineImages(ctx context.Context, images []MachineImage, enrichers []Enricher) ([]EnrichedVersion, error) {
+	result := []EnrichedVersion{}
+
+	for _, ref := range versionRefs(images) {
+		metadata := VersionMetadata{}
+		found := false
+
+		for _, enricher := range enrichers {
+			if !enricher.Supports(ref.Image) {
+				continue
+			}
+			m, err := enricher.Metadata(ctx, ref.Image, ref.Version)
+			if err != nil {
+				return nil, err
+			}
+			if m != nil {
+				metadata.merge(m)
+				found = true
+			}
+		}
+
+		if found {
+			result = append(result, EnrichedVersion{VersionRef: ref, Metadata: metadata})
+		}
+	}
+
+	return result, nil
+}
+
+// feedCache fetches a feed once and keeps the result.
+type feedCache struct {
+	mutex   sync.Mutex
+	fetched bool
+}
+
+func (c *feedCache) load(fetch func() error) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.fetched {
+		return nil
+	}
+	if err := fetch(); err != nil {
+		return err
+	}
+	c.fetched = true
+	return nil
+}
+
+// GardenLinuxEnricher provides the release notes of Garden Linux versions from the GitHub releases of Garden Linux.
+type GardenLinuxEnricher struct {
+	// URL of the releases. Defaults to DefaultGardenLinuxReleasesURL.
+	URL string
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+
+	cache    feedCache
+	releases []gardenLinuxRelease
+}
+
+type gardenLinuxRelease struct {
+	TagName string `json:"tag_name"`
+	HTMLURL string `json:"html_url"`
+}
+
+// Supports returns true for Garden Linux.
+func (e *GardenLinuxEnricher) Supports(image string) bool {
+	return image == OsNameGardenLinux
+}
+
+// Metadata returns the release notes of the release whose tag equals the version or the version without a trailing
+// ".0" patch level.
+func (e *GardenLinuxEnricher) Metadata(ctx context.Context, _, version string) (*VersionMetadata, error) {
+	err := e.cache.load(func() error {
+		url := e.URL
+		if len(url) == 0 {
+			url = DefaultGardenLinuxReleasesURL
+		}
+		return fetchJSON(ctx, e.Client, "fetch garden linux releases", url, &e.releases)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, release := range e.releases {
+		if release.TagName == version || release.TagName+".0" == version {
+			return &VersionMetadata{ReleaseNotesURL: release.HTMLURL}, nil
+		}
+	}
+	return nil, nil
+}
+
+// FlatcarEnricher provides release notes and kernel versions of Flatcar versions from the release json of a channel.
+type FlatcarEnricher struct {
+	// URL of the release json. Defaults to DefaultFlatcarReleasesURL.
+	URL string
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+
+	cache    feedCache
+	releases map[string]flatcarRelease
+}
+
+type flatcarRelease struct {
+	MajorSoftware struct {
+		Kernel []string `json:"kernel"`
+	} `json:"major_software"`
+}
+
+// Supports returns true for Flatcar.
+func (e *FlatcarEnricher) Supports(image string) bool {
+	return image == OsNameFlatcar
+}
+
+// Metadata returns the kernel version and release notes of the version.
+func (e *FlatcarEnricher) Metadata(ctx context.Context, _, version string) (*VersionMetadata, error) {
+	err := e.cache.load(func() error {
+		url := e.URL
+		if len(url) == 0 {
+			url = DefaultFlatcarReleasesURL
+		}
+		return fetchJSON(ctx, e.Client, "fetch flatcar releases", url, &e.releases)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	release, ok := e.releases[version]
+	if !ok {
+		return nil, nil
+	}
+
+	metadata := &VersionMetadata{ReleaseNotesURL: "https://www.flatcar.org/releases#release-" + version}
+	if len(release.MajorSoftware.Kernel) > 0 {
+		metadata.KernelVersion = release.MajorSoftware.Kernel[0]
+	}
+	return metadata, nil
+}
+
+// EndOfLifeEnricher provides the end of life dates of versions from endoflife.date.
+type EndOfLifeEnricher struct {
+	// Products maps image names to products of endoflife.date, e.g. ubuntu to "ubuntu".
+	Products map[string]string
+	// URL of the api. Defaults to DefaultEndOfLifeURL.
+	URL string
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+
+	mutex  sync.Mutex
+	cycles map[string][]endOfLifeCycle
+}
+
+type endOfLifeCycle struct {
+	Cycle string `json:"cycle"`
+	// EOL is either a date, false if the end of life is not yet known or true if the cycle ended at an unknown date.
+	EOL interface{} `json:"eol"`
+}
+
+// Supports returns whether a product is configured for the image.
+func (e *EndOfLifeEnricher) Supports(image string) bool {
+	_, ok := e.Products[image]
+	return ok
+}
+
+// Metadata returns the end of life date of the longest release cycle which matches the version.
+func (e *EndOfLifeEnricher) Metadata(ctx context.Context, image, version string) (*VersionMetadata, error) {
+	cycles, err := e.loadCycles(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	var match *endOfLifeCycle
+	for i, cycle := range cycles {
+		if cycleMatches(cycle.Cycle, version) && (match == nil || len(cycle.Cycle) > len(match.Cycle)) {
+			match = &cycles[i]
+		}
+	}
+	if match == nil {
+		return nil, nil
+	}
+
+	switch eol := match.EOL.(type) {
+	case string:
+		t, err := time.Parse("2006-01-02", eol)
+		if err != nil {
+			return nil, fmt.Errorf("invalid end of life %q of %s %s: %w", eol, image, match.Cycle, err)
+		}
+		return &VersionMetadata{EndOfLife: &t}, nil
+	case bool:
+		// false if the end of life is not yet known and true if the cycle ended at an unknown date, neither gives a
+		// date
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("invalid end of life %v of %s %s, expected a date or a boolean", match.EOL, image, match.Cycle)
+	}
+}
+
+func (e *EndOfLifeEnricher) loadCycles(ctx context.Context, image string) ([]endOfLifeCycle, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if cycles, ok := e.cycles[image]; ok {
+		return cycles, nil
+	}
+
+	url := e.URL
+	if len(url) == 0 {
+		url = DefaultEndOfLifeURL
+	}
+	url = strings.TrimSuffix(url, "/") + "/" + e.Products[image] + ".json"
+
+	cycles := []endOfLifeCycle{}
+	if err := fetchJSON(ctx, e.Client, "fetch end of life dates", url, &cycles); err != nil {
+		return nil, err
+	}
+
+	if e.cycles == nil {
+		e.cycles = map[string][]endOfLifeCycle{}
+	}
+	e.cycles[image] = cycles
+	return cycles, nil
+}
+
+// cycleMatches returns whether the segments of the release cycle equal the leading segments of the version. Numeric
+// segments are compared by value, so that the cycle "18.04" matches the version "18.4.20210415".
+func cycleMatches(cycle, version string) bool {
+	cycleSegments := strings.Split(cycle, ".")
+	versionSegments := strings.Split(version, ".")
+	if len(cycleSegments) > len(versionSegments) {
+		return false
+	}
+
+	for i, c := range cycleSegments {
+		a, errA := strconv.Atoi(c)
+		b, errB := strconv.Atoi(versionSegments[i])
+		if errA != nil || errB != nil {
+			if c != versionSegments[i] {
+				return false
+			}
+			continue
+		}
+		if a != b {
+			return false
+		}
+	}
+	return true
+}
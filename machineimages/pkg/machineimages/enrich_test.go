@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("enrich", func() {
+
+	var (
+		server   *httptest.Server
+		requests int
+	)
+
+	BeforeEach(func() {
+		requests = 0
+		mux := http.NewServeMux()
+		mux.HandleFunc("/gardenlinux", func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			_, _ = w.Write([]byte(`[{"tag_name": "318.9", "html_url": "https://github.com/gardenlinux/gardenlinux/releases/tag/318.9"}]`))
+		})
+		mux.HandleFunc("/flatcar", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"2765.2.6": {"channel": "stable", "major_software": {"kernel": ["5.10.43"]}}}`))
+		})
+		mux.HandleFunc("/eol/ubuntu.json", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[{"cycle": "20.04", "eol": "2025-04-02"}, {"cycle": "18.04", "eol": "2023-04-02"}, {"cycle": "22.04", "eol": false}, {"cycle": "16.04", "eol": true}]`))
+		})
+		mux.HandleFunc("/eol/invalid.json", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[{"cycle": "18.04", "eol": 2023}]`))
+		})
+		server = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	images := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.9.0"}, {"version": "934.0.0"}}},
+		{Name: OsNameFlatcar, Versions: []MachineImageVersion{{"version": "2765.2.6"}}},
+		{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "18.4.20210415"}, {"version": "22.4.20220101"}}},
+	}
+
+	It("should attach the metadata of all vendors", func() {
+		enrichers := []Enricher{
+			&GardenLinuxEnricher{URL: server.URL + "/gardenlinux"},
+			&FlatcarEnricher{URL: server.URL + "/flatcar"},
+			&EndOfLifeEnricher{URL: server.URL + "/eol", Products: map[string]string{OsNameUbuntu: "ubuntu"}},
+		}
+
+		result, err := EnrichMachineImages(context.Background(), images, enrichers)
+		Expect(err).NotTo(HaveOccurred())
+
+		eol := time.Date(2023, 4, 2, 0, 0, 0, 0, time.UTC)
+		Expect(result).To(Equal([]EnrichedVersion{
+			{
+				VersionRef: VersionRef{Image: OsNameGardenLinux, Version: "318.9.0"},
+				Metadata:   VersionMetadata{ReleaseNotesURL: "https://github.com/gardenlinux/gardenlinux/releases/tag/318.9"},
+			},
+			{
+				VersionRef: VersionRef{Image: OsNameFlatcar, Version: "2765.2.6"},
+				Metadata: VersionMetadata{
+					ReleaseNotesURL: "https://www.flatcar.org/releases#release-2765.2.6",
+					KernelVersion:   "5.10.43",
+				},
+			},
+			{
+				VersionRef: VersionRef{Image: OsNameUbuntu, Version: "18.4.20210415"},
+				Metadata:   VersionMetadata{EndOfLife: &eol},
+			},
+		}))
+		Expect(requests).To(Equal(1))
+	})
+
+	It("should merge the metadata with precedence of the earlier enrichers", func() {
+		first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		bionic := VersionRef{Image: OsNameUbuntu, Version: "18.4.20210415"}
+		xenial := VersionRef{Image: OsNameUbuntu, Version: "16.4.20200101"}
+		enrichers := []Enricher{
+			staticEnricher{bionic: {EndOfLife: &first}},
+			&EndOfLifeEnricher{URL: server.URL + "/eol", Products: map[string]string{OsNameUbuntu: "ubuntu"}},
+			staticEnricher{bionic: {KernelVersion: "5.4.0"}, xenial: {KernelVersion: "4.15.0"}},
+		}
+
+		result, err := EnrichMachineImages(context.Background(), []MachineImage{
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "18.4.20210415"}, {"version": "16.4.20200101"}}},
+		}, enrichers)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]EnrichedVersion{
+			{VersionRef: bionic, Metadata: VersionMetadata{EndOfLife: &first, KernelVersion: "5.4.0"}},
+			{VersionRef: xenial, Metadata: VersionMetadata{KernelVersion: "4.15.0"}},
+		}))
+	})
+
+	It("should reject end of life values which are neither a date nor a boolean", func() {
+		_, err := EnrichMachineImages(context.Background(), images,
+			[]Enricher{&EndOfLifeEnricher{URL: server.URL + "/eol", Products: map[string]string{OsNameUbuntu: "invalid"}}})
+		Expect(err).To(MatchError("invalid end of life 2023 of ubuntu 18.04, expected a date or a boolean"))
+	})
+
+	It("should respect the network policy guard", func() {
+		_, err := EnrichMachineImages(WithNetworkPolicyGuard(context.Background()), images,
+			[]Enricher{&GardenLinuxEnricher{URL: server.URL + "/gardenlinux"}})
+		Expect(IsNetworkAccessDenied(err)).To(BeTrue())
+	})
+
+	It("should match release cycles numerically", func() {
+		Expect(cycleMatches("18.04", "18.4.20210415")).To(BeTrue())
+		Expect(cycleMatches("20.04", "18.4.20210415")).To(BeFalse())
+		Expect(cycleMatches("1.2.3", "1.2")).To(BeFalse())
+	})
+})
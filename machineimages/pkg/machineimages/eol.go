@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// expirationDateFormat is the format of the expirationDate of versions.
+const expirationDateFormat = "2006-01-02T15:04:05Z"
+
+// EndOfLifePolicy derives the expiration of versions from the end of life dates of their vendors. Versions are
+// deprecated once the vendor end of life has passed and removed after the grace period of their image.
+type EndOfLifePolicy struct {
+	// Products maps image names to products of endoflife.date. It is used if no enrichers are configured.
+	Products map[string]string `json:"products,omitempty" yaml:"products,omitempty"`
+	// DefaultGracePeriodDays is the number of days a version stays available as deprecated after its end of life.
+	DefaultGracePeriodDays int `json:"defaultGracePeriodDays,omitempty" yaml:"defaultGracePeriodDays,omitempty"`
+	// GracePeriodDays overrides the grace period per image name.
+	GracePeriodDays map[string]int `json:"gracePeriodDays,omitempty" yaml:"gracePeriodDays,omitempty"`
+	// Enrichers provide the end of life dates. Defaults to an EndOfLifeEnricher for the products.
+	Enrichers []Enricher `json:"-" yaml:"-"`
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time `json:"-" yaml:"-"`
+}
+
+func (p *EndOfLifePolicy) gracePeriod(image string) time.Duration {
+	days, ok := p.GracePeriodDays[image]
+	if !ok {
+		days = p.DefaultGracePeriodDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// applyEndOfLife sets the expiration date of all versions with a known end of life to the end of life plus the grace
+// period, unless they already expire earlier. Versions past their end of life are deprecated, versions past their
+// expiration are removed. The versions are modified in place, so it must only be applied to the computed result.
+func applyEndOfLife(ctx context.Context, images []MachineImage, policy *EndOfLifePolicy) ([]MachineImage, error) {
+	if policy == nil {
+		return images, nil
+	}
+
+	enrichers := policy.Enrichers
+	if len(enrichers) == 0 {
+		enrichers = []Enricher{&EndOfLifeEnricher{Products: policy.Products}}
+	}
+	now := time.Now
+	if policy.Now != nil {
+		now = policy.Now
+	}
+
+	enriched, err := EnrichMachineImages(ctx, images, enrichers)
+	if err != nil {
+		return nil, err
+	}
+	endOfLife := map[VersionRef]time.Time{}
+	for _, e := range enriched {
+		if e.Metadata.EndOfLife != nil {
+			endOfLife[e.VersionRef] = *e.Metadata.EndOfLife
+		}
+	}
+
+	_, reporter := FromContext(ctx)
+
+	result := []MachineImage{}
+	for _, image := range images {
+		versions := []MachineImageVersion{}
+		for _, v := range image.Versions {
+			version := versionOrEmpty(v)
+			eol, ok := endOfLife[VersionRef{Image: image.Name, Version: version}]
+			if !ok {
+				versions = append(versions, v)
+				continue
+			}
+
+			expiration := eol.Add(policy.gracePeriod(image.Name))
+			if !now().Before(expiration) {
+				reporter.Report(ReportEntry{
+					Image:   image.Name,
+					Version: version,
+					Reason:  ReasonEndOfLife,
+					Message: fmt.Sprintf("vendor end of life %s and grace period have passed", eol.Format("2006-01-02")),
+				})
+				continue
+			}
+
+			current, err := v.ExpirationDate()
+			if err != nil {
+				return nil, err
+			}
+			if current == nil || current.After(expiration) {
+				v.SetExpirationDate(expiration)
+			}
+			if !now().Before(eol) {
+				v.SetClassification(ClassificationDeprecated)
+			}
+			versions = append(versions, v)
+		}
+
+		if len(versions) > 0 {
+			result = append(result, MachineImage{Name: image.Name, Versions: versions})
+		}
+	}
+
+	return result, nil
+}
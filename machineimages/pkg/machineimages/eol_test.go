@@ -0,0 +1,93 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type staticEnricher map[VersionRef]VersionMetadata
+
+func (e staticEnricher) Supports(string) bool {
+	return true
+}
+
+func (e staticEnricher) Metadata(_ context.Context, image, version string) (*VersionMetadata, error) {
+	if m, ok := e[VersionRef{Image: image, Version: version}]; ok {
+		return &m, nil
+	}
+	return nil, nil
+}
+
+var _ = Describe("end of life", func() {
+
+	date := func(year int, month time.Month, day int) *time.Time {
+		t := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	enricher := staticEnricher{
+		{Image: OsNameUbuntu, Version: "16.4.0"}: {EndOfLife: date(2021, 4, 30)},
+		{Image: OsNameUbuntu, Version: "18.4.0"}: {EndOfLife: date(2021, 5, 15)},
+		{Image: OsNameUbuntu, Version: "20.4.0"}: {EndOfLife: date(2025, 4, 2)},
+	}
+
+	compute := func(policy *EndOfLifePolicy, report *Report) ([]MachineImage, error) {
+		return ComputeMachineImagesWithOptions(
+			context.Background(),
+			logr.Discard(),
+			[]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "16.4.0", "classification": "supported"},
+				{"version": "18.4.0", "classification": "supported"},
+				{"version": "20.4.0", "classification": "supported", "expirationDate": "2024-01-01T00:00:00Z"},
+				{"version": "21.4.0", "classification": "supported"},
+			}}},
+			nil,
+			[]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "16.4.0"}, {"version": "18.4.0"}, {"version": "20.4.0"}, {"version": "21.4.0"},
+			}}},
+			nil, nil, nil, nil,
+			&ComputeMachineImagesOptions{EndOfLife: policy, Reporter: report},
+		)
+	}
+
+	It("should deprecate, expire and remove versions according to their end of life", func() {
+		report := NewReport()
+		result, err := compute(&EndOfLifePolicy{
+			Enrichers:       []Enricher{enricher},
+			GracePeriodDays: map[string]int{OsNameUbuntu: 30},
+			Now:             func() time.Time { return *date(2021, 6, 1) },
+		}, report)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+			{"version": "21.4.0", "classification": "supported"},
+			{"version": "20.4.0", "classification": "supported", "expirationDate": "2024-01-01T00:00:00Z"},
+			{"version": "18.4.0", "classification": "deprecated", "expirationDate": "2021-06-14T00:00:00Z"},
+		}}}))
+		Expect(report.Entries()).To(ConsistOf(ReportEntry{
+			Image:   OsNameUbuntu,
+			Version: "16.4.0",
+			Reason:  ReasonEndOfLife,
+			Message: "vendor end of life 2021-04-30 and grace period have passed",
+		}))
+	})
+
+	It("should use the default grace period", func() {
+		result, err := compute(&EndOfLifePolicy{
+			Enrichers:              []Enricher{enricher},
+			DefaultGracePeriodDays: 60,
+			Now:                    func() time.Time { return *date(2021, 6, 1) },
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result[0].Versions).To(HaveLen(4))
+		Expect(result[0].Versions[3]["expirationDate"]).To(Equal("2021-06-29T00:00:00Z"))
+	})
+})
@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"errors"
+)
+
+// ErrorClass classifies why a computation failed, so that callers can react to the kind of failure, e.g. retry fetch
+// errors but not validation errors.
+type ErrorClass string
+
+const (
+	// ErrorClassValidation are invalid imports, catalogs or options.
+	ErrorClassValidation = ErrorClass("validation")
+	// ErrorClassFetch are failed requests to external systems, e.g. incident webhooks.
+	ErrorClassFetch = ErrorClass("fetch")
+	// ErrorClassPolicy are results which a policy rejects, e.g. missing required images, exceeded limits or pending
+	// approvals.
+	ErrorClassPolicy = ErrorClass("policy")
+	// ErrorClassApply are failures to write the results, e.g. the exports or the state.
+	ErrorClassApply = ErrorClass("apply")
+)
+
+// ClassifiedError is an error with its class.
+type ClassifiedError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *ClassifiedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ClassifiedError) Unwrap() error {
+	return e.Err
+}
+
+// ClassifyError returns the error with the class, unless it already has a class, see ClassOf. Nil is returned as nil.
+func ClassifyError(err error, class ErrorClass) error {
+	if err == nil || len(ClassOf(err)) > 0 {
+		return err
+	}
+	return &ClassifiedError{Class: class, Err: err}
+}
+
+// ClassOf returns the class of the error. Explicit classes, see ClassifyError, take precedence over the classes of the
+// error types of this package. An empty class is returned for unclassified errors.
+func ClassOf(err error) ErrorClass {
+	var (
+		classifiedErr *ClassifiedError
+		validationErr *ValidationError
+		conflictErr   *MergeConflictError
+		unmatchedErr  *UnmatchedDisablePatternsError
+		fetchErr      *FetchError
+		deniedErr     *NetworkAccessDeniedError
+		pendingErr    *ApprovalPendingError
+	)
+	switch {
+	case errors.As(err, &classifiedErr):
+		return classifiedErr.Class
+	case errors.As(err, &validationErr), errors.As(err, &conflictErr), errors.As(err, &unmatchedErr):
+		return ErrorClassValidation
+	case errors.As(err, &deniedErr), errors.As(err, &pendingErr):
+		return ErrorClassPolicy
+	case errors.As(err, &fetchErr):
+		return ErrorClassFetch
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("error classes", func() {
+
+	It("should classify the errors of this package", func() {
+		Expect(ClassOf(&ValidationError{Problems: []string{"invalid"}})).To(Equal(ErrorClassValidation))
+		Expect(ClassOf(fmt.Errorf("wrapped: %w", &MergeConflictError{}))).To(Equal(ErrorClassValidation))
+		Expect(ClassOf(&NetworkAccessDeniedError{Operation: "GET", Target: "https://example.com"})).To(Equal(ErrorClassPolicy))
+		Expect(ClassOf(&ApprovalPendingError{Request: &ApprovalRequest{}})).To(Equal(ErrorClassPolicy))
+		Expect(ClassOf(&FetchError{Operation: "fetch incidents", StatusCode: http.StatusNotFound})).To(Equal(ErrorClassFetch))
+		Expect(ClassOf(errors.New("unknown"))).To(BeEmpty())
+	})
+
+	It("should keep existing classes", func() {
+		err := ClassifyError(&FetchError{Operation: "fetch incidents", Err: errors.New("timeout")}, ErrorClassApply)
+		Expect(ClassOf(err)).To(Equal(ErrorClassFetch))
+
+		err = ClassifyError(ClassifyError(errors.New("unable to write"), ErrorClassApply), ErrorClassValidation)
+		Expect(ClassOf(err)).To(Equal(ErrorClassApply))
+		Expect(err).To(MatchError("unable to write"))
+		Expect(ClassifyError(nil, ErrorClassApply)).To(BeNil())
+	})
+
+	It("should prefer explicit classes over the classes of wrapped errors", func() {
+		err := &ClassifiedError{Class: ErrorClassApply, Err: fmt.Errorf("unable to save state: %w", &FetchError{Operation: "update config map"})}
+		Expect(ClassOf(fmt.Errorf("wrapped: %w", err))).To(Equal(ErrorClassApply))
+	})
+
+	It("should classify failed requests as fetch errors", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		source := &WebhookIncidentSource{URL: server.URL}
+		_, err := source.Incidents(context.Background())
+		Expect(err).To(HaveOccurred())
+		Expect(ClassOf(err)).To(Equal(ErrorClassFetch))
+	})
+
+	It("should classify failed computations by their stage", func() {
+		images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0"}}}}
+
+		_, err := ComputeMachineImagesWithOptions(context.Background(), logr.Discard(), images, nil, nil, nil, nil,
+			[]OsImagesFilterKind{"unknown"}, nil, nil)
+		Expect(ClassOf(err)).To(Equal(ErrorClassValidation))
+
+		_, err = ComputeMachineImagesWithOptions(context.Background(), logr.Discard(), images, nil, nil, nil, nil, nil, nil,
+			&ComputeMachineImagesOptions{RequiredImages: []string{"suse-chost"}})
+		Expect(ClassOf(err)).To(Equal(ErrorClassPolicy))
+	})
+})
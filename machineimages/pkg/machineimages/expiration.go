@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"time"
+)
+
+// dropExpiredVersions removes all versions which are expired at the reference time.
+func dropExpiredVersions(ctx context.Context, images []OsImage, now time.Time) ([]OsImage, error) {
+	_, reporter := FromContext(ctx)
+
+	result := make([]OsImage, 0, len(images))
+	for _, image := range images {
+		expiration, err := image.Version.ExpirationDate()
+		if err != nil {
+			return nil, err
+		}
+		if expiration != nil && !now.Before(*expiration) {
+			reporter.Report(ReportEntry{
+				Image:   image.Name,
+				Version: versionOrEmpty(image.Version),
+				Reason:  ReasonExpired,
+				Message: "expired at " + expiration.UTC().Format(expirationDateFormat),
+			})
+			continue
+		}
+		result = append(result, image)
+	}
+	return result, nil
+}
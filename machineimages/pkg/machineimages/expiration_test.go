@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("expiration", func() {
+
+	It("should parse expiration dates", func() {
+		for value, expected := range map[string]time.Time{
+			"2022-01-15T23:59:59Z":      time.Date(2022, 1, 15, 23, 59, 59, 0, time.UTC),
+			"2022-01-15T23:59:59+01:00": time.Date(2022, 1, 15, 22, 59, 59, 0, time.UTC),
+			"2022-01-15":                time.Date(2022, 1, 15, 0, 0, 0, 0, time.UTC),
+		} {
+			expiration, err := MachineImageVersion{"version": "1.0.0", "expirationDate": value}.ExpirationDate()
+			Expect(err).NotTo(HaveOccurred(), value)
+			Expect(expiration.Equal(expected)).To(BeTrue(), value)
+		}
+
+		expiration, err := MachineImageVersion{"version": "1.0.0"}.ExpirationDate()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(expiration).To(BeNil())
+
+		_, err = MachineImageVersion{"version": "1.0.0", "expirationDate": "15.01.2022"}.ExpirationDate()
+		Expect(err).To(MatchError(ContainSubstring(`invalid expirationDate "15.01.2022" of version 1.0.0`)))
+	})
+
+	compute := func(options *ComputeMachineImagesOptions) []MachineImage {
+		result, err := ComputeMachineImagesWithOptions(
+			context.Background(),
+			logr.Discard(),
+			[]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "18.4.0", "classification": "supported", "expirationDate": "2021-05-01T00:00:00Z"},
+				{"version": "20.4.0", "classification": "supported", "expirationDate": "2025-04-02T00:00:00Z"},
+				{"version": "22.4.0", "classification": "supported"},
+			}}},
+			[]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "20.4.0", "classification": "deprecated"},
+				{"version": "22.4.0", "classification": "preview", "expirationDate": "2021-06-01T00:00:00Z"},
+			}}},
+			[]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "18.4.0"}, {"version": "20.4.0"}, {"version": "22.4.0"},
+			}}},
+			nil, nil, nil, nil,
+			options,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		return result
+	}
+
+	It("should merge landscape overrides over the lss versions", func() {
+		Expect(compute(nil)).To(Equal([]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+			{"version": "22.4.0", "classification": "preview", "expirationDate": "2021-06-01T00:00:00Z"},
+			{"version": "20.4.0", "classification": "deprecated", "expirationDate": "2025-04-02T00:00:00Z"},
+			{"version": "18.4.0", "classification": "supported", "expirationDate": "2021-05-01T00:00:00Z"},
+		}}}))
+	})
+
+	It("should drop versions expired at the reference time", func() {
+		report := NewReport()
+		now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+		Expect(compute(&ComputeMachineImagesOptions{DropExpiredVersions: true, ExpirationReferenceTime: &now, Reporter: report})).To(Equal([]MachineImage{
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "20.4.0", "classification": "deprecated", "expirationDate": "2025-04-02T00:00:00Z"},
+			}},
+		}))
+		Expect(report.Entries()).To(ConsistOf(
+			ReportEntry{Image: OsNameUbuntu, Version: "22.4.0", Reason: ReasonExpired, Message: "expired at 2021-06-01T00:00:00Z"},
+			ReportEntry{Image: OsNameUbuntu, Version: "18.4.0", Reason: ReasonExpired, Message: "expired at 2021-05-01T00:00:00Z"},
+		))
+	})
+})
@@ -0,0 +1,205 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+)
+
+// Stages of a version explanation.
+const (
+	StageSource         = "source"
+	StageIncludeFilters = "includeFilters"
+	StageExcludeFilters = "excludeFilters"
+	StageMinVersion     = "minVersion"
+	StageDisabled       = "disabled"
+	StageProviderConfig = "providerConfig"
+	StageResult         = "result"
+)
+
+// ExplanationStep is the outcome of one stage of the computation for a single version.
+type ExplanationStep struct {
+	Stage   string `json:"stage"`
+	Passed  bool   `json:"passed"`
+	Message string `json:"message"`
+}
+
+// VersionExplanation describes why a version is or is not contained in the computed machine images.
+type VersionExplanation struct {
+	Image    string            `json:"image"`
+	Version  string            `json:"version"`
+	Included bool              `json:"included"`
+	Steps    []ExplanationStep `json:"steps"`
+}
+
+// ExplainVersion traces a single version through the stages of the computation. The explanation ends with the first
+// stage which removes the version. Like in the computation, only focused images are traced, the machineImages are
+// fetched from the os image source of the imports, if one is configured, and the versions are looked up after their
+// field mapping.
+func ExplainVersion(ctx context.Context, log logr.Logger, imports *Imports, image, version string) (*VersionExplanation, error) {
+	explanation := &VersionExplanation{Image: image, Version: version, Steps: []ExplanationStep{}}
+	add := func(stage string, passed bool, format string, args ...interface{}) bool {
+		explanation.Steps = append(explanation.Steps, ExplanationStep{
+			Stage:   stage,
+			Passed:  passed,
+			Message: fmt.Sprintf(format, args...),
+		})
+		return passed
+	}
+
+	options := &imports.ComputeMachineImagesOptions
+	if len(options.Focus) > 0 && !contains(options.Focus, image) {
+		add(StageSource, false, "image is not focused")
+		return explanation, nil
+	}
+	for _, source := range FieldMappingSources {
+		if err := options.FieldMappings[source].Validate(); err != nil {
+			return nil, ClassifyError(fmt.Errorf("invalid field mapping of %s: %w", source, err), ErrorClassValidation)
+		}
+	}
+
+	lssOsImages, err := options.sourceOsImages(ctx, log, imports.MachineImages)
+	if err != nil {
+		return nil, err
+	}
+	osImage, origin := findOsImage(image, version, options.focusAndMap("machineImagesLs", imports.MachineImagesLs),
+		options.focusAndMap("machineImages", lssOsImages))
+	if !add(StageSource, osImage != nil, "version is %s", origin) {
+		return explanation, nil
+	}
+
+	includeKinds := imports.IncludeFilters
+	if len(includeKinds) == 0 {
+		includeKinds = []OsImagesFilterKind{OsImagesFilterKindAll}
+	}
+	matched, err := matchingFilterKinds(*osImage, includeKinds)
+	if err != nil {
+		return nil, err
+	}
+	if !add(StageIncludeFilters, len(matched) > 0, "matched include filters %v", matched) {
+		return explanation, nil
+	}
+
+	matched, err = matchingFilterKinds(*osImage, imports.ExcludeFilters)
+	if err != nil {
+		return nil, err
+	}
+	if !add(StageExcludeFilters, len(matched) == 0, "matched exclude filters %v", matched) {
+		return explanation, nil
+	}
+
+	if minVersion, ok := imports.MinVersions[image]; ok {
+		if !add(StageMinVersion, compareVersions(version, minVersion) >= 0, "minimum version is %s", minVersion) {
+			return explanation, nil
+		}
+	}
+
+	disablePatterns, err := parseDisablePatterns(imports.DisableMachineImages)
+	if err != nil {
+		return nil, err
+	}
+	if disabled := disabledVersion(disablePatterns, image, version); disabled != nil {
+		add(StageDisabled, false, "disabled by %s", disabled.pattern)
+		return explanation, nil
+	}
+	add(StageDisabled, true, "not disabled")
+
+	configOrigin := originNone
+	if hasVersionConfig(*osImage, options.focusAndMap("machineImagesProviderLs", imports.MachineImagesProviderLs)) {
+		configOrigin = originLandscape
+	} else if hasVersionConfig(*osImage, options.focusAndMap("machineImagesProvider", imports.MachineImagesProvider)) {
+		configOrigin = originDefault
+	}
+	if !add(StageProviderConfig, configOrigin != originNone, "provider config is %s", configOrigin) {
+		return explanation, nil
+	}
+
+	report := NewReport()
+	computeImports := *imports
+	computeImports.ComputeMachineImagesOptions.Reporter = report
+
+	result, err := ComputeMachineImagesFromImports(ctx, log, &computeImports)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation.Included = containsVersion(result, image, version)
+	reasons := []string{}
+	for _, entry := range report.Entries() {
+		if entry.Image == image && entry.Version == version {
+			reasons = append(reasons, entry.Reason)
+		}
+	}
+	add(StageResult, explanation.Included, "version contained in result: %t, reported reasons: %v",
+		explanation.Included, reasons)
+
+	return explanation, nil
+}
+
+const (
+	originLandscape = "defined in the landscape images"
+	originDefault   = "defined in the default images"
+	originNone      = "not defined"
+)
+
+func findOsImage(image, version string, landscapeImages, defaultImages []MachineImage) (*OsImage, string) {
+	if v := findVersion(image, version, landscapeImages); v != nil {
+		return &OsImage{Name: image, Version: v}, originLandscape
+	}
+	if v := findVersion(image, version, defaultImages); v != nil {
+		return &OsImage{Name: image, Version: v}, originDefault
+	}
+	return nil, originNone
+}
+
+// hasVersionConfig returns whether the provider configs contain a config of the version, for versions with
+// architectures a config of one of their architectures.
+func hasVersionConfig(image OsImage, providerImages []MachineImage) bool {
+	version := versionOrEmpty(image.Version)
+	if architectures := image.Version.getArchitectures(); len(architectures) > 0 {
+		configs, _ := getArchitectureVersionConfigs(image.Name, version, architectures, nil, providerImages)
+		return len(configs) > 0
+	}
+	return getVersionConfig(image.Name, version, nil, providerImages) != nil
+}
+
+func findVersion(image, version string, images []MachineImage) MachineImageVersion {
+	for _, nextImage := range images {
+		if nextImage.Name != image {
+			continue
+		}
+		for _, v := range nextImage.Versions {
+			if versionOrEmpty(v) == version {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+func containsVersion(images []MachineImage, image, version string) bool {
+	return findVersion(image, version, images) != nil
+}
+
+func matchingFilterKinds(image OsImage, kinds []OsImagesFilterKind) ([]OsImagesFilterKind, error) {
+	result := []OsImagesFilterKind{}
+	for _, kind := range kinds {
+		f, err := createFilter(kind)
+		if err != nil {
+			return nil, err
+		}
+		matched, err := f.Match(image)
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			result = append(result, kind)
+		}
+	}
+	return result, nil
+}
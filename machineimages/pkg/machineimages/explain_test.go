@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("explain", func() {
+
+	newImports := func() *Imports {
+		return &Imports{
+			MachineImages: []MachineImage{
+				{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+					{"version": "1.0.0", "classification": ClassificationSupported},
+					{"version": "2.0.0", "classification": ClassificationPreview},
+					{"version": "3.0.0", "classification": ClassificationSupported},
+				}},
+			},
+			MachineImagesProvider: []MachineImage{
+				{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0", "image": "a"}, {"version": "2.0.0", "image": "b"}}},
+			},
+			ExcludeFilters: []OsImagesFilterKind{OsImagesFilterKindPreview},
+		}
+	}
+
+	stages := func(explanation *VersionExplanation) []string {
+		result := []string{}
+		for _, step := range explanation.Steps {
+			result = append(result, step.Stage)
+		}
+		return result
+	}
+
+	It("should explain an included version", func() {
+		explanation, err := ExplainVersion(context.Background(), logr.Discard(), newImports(), OsNameUbuntu, "1.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(explanation.Included).To(BeTrue())
+		Expect(stages(explanation)).To(Equal([]string{StageSource, StageIncludeFilters, StageExcludeFilters,
+			StageDisabled, StageProviderConfig, StageResult}))
+	})
+
+	It("should stop at the stage which removes the version", func() {
+		explanation, err := ExplainVersion(context.Background(), logr.Discard(), newImports(), OsNameUbuntu, "2.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(explanation.Included).To(BeFalse())
+		last := explanation.Steps[len(explanation.Steps)-1]
+		Expect(last.Stage).To(Equal(StageExcludeFilters))
+		Expect(last.Message).To(Equal("matched exclude filters [preview]"))
+
+		explanation, err = ExplainVersion(context.Background(), logr.Discard(), newImports(), OsNameUbuntu, "3.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(explanation.Steps[len(explanation.Steps)-1].Stage).To(Equal(StageProviderConfig))
+
+		explanation, err = ExplainVersion(context.Background(), logr.Discard(), newImports(), OsNameUbuntu, "4.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(explanation.Steps).To(Equal([]ExplanationStep{{Stage: StageSource, Passed: false, Message: "version is not defined"}}))
+	})
+
+	It("should look up the versions after their field mapping", func() {
+		imports := newImports()
+		imports.MachineImages = append(imports.MachineImages, MachineImage{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"imageVersion": "934.1.0"},
+		}})
+		imports.MachineImagesProvider = append(imports.MachineImagesProvider, MachineImage{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"release": map[string]interface{}{"version": "934.1.0"}, "image": "gl"},
+		}})
+		imports.FieldMappings = map[string]*FieldMapping{
+			"machineImages":         {Fields: map[string]string{"version": "imageVersion"}},
+			"machineImagesProvider": {Fields: map[string]string{"version": "release.version"}},
+		}
+
+		explanation, err := ExplainVersion(context.Background(), logr.Discard(), imports, OsNameGardenLinux, "934.1.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(explanation.Included).To(BeTrue())
+		Expect(explanation.Steps[len(explanation.Steps)-2]).To(Equal(ExplanationStep{
+			Stage: StageProviderConfig, Passed: true, Message: "provider config is defined in the default images"}))
+	})
+
+	It("should only explain focused images", func() {
+		imports := newImports()
+		imports.Focus = []string{OsNameGardenLinux}
+
+		explanation, err := ExplainVersion(context.Background(), logr.Discard(), imports, OsNameUbuntu, "1.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(explanation.Included).To(BeFalse())
+		Expect(explanation.Steps).To(Equal([]ExplanationStep{{Stage: StageSource, Passed: false, Message: "image is not focused"}}))
+	})
+})
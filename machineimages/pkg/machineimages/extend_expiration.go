@@ -0,0 +1,205 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/constraint"
+)
+
+// ExpirationExtension postpones the expiration of versions, e.g. to avoid forced upgrades during a change freeze.
+type ExpirationExtension struct {
+	// Images restricts the extension to these images. If empty, the versions of all images are extended.
+	Images []string `json:"images,omitempty"`
+	// Constraint selects the versions which are extended, e.g. "<934.8". If empty, all versions are extended.
+	Constraint string `json:"constraint,omitempty"`
+	// ExpirationDate is the new expiration date. Only versions which expire earlier are extended, versions without
+	// expiration date and versions which expire later are kept.
+	ExpirationDate time.Time `json:"expirationDate"`
+	// Reason is recorded in the audit entry, e.g. the ticket of the change freeze.
+	Reason string `json:"reason"`
+	// Actor is recorded in the audit entry as the one who extended the versions.
+	Actor string `json:"actor,omitempty"`
+	// LoadOptions select the files of the catalogs. Lenient loads and field mappings are not supported, because the
+	// catalog files are written.
+	LoadOptions *CatalogLoadOptions `json:"loadOptions,omitempty"`
+	// DryRun computes the audit entry without writing the catalog files.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// ExtendedVersion is a version whose expiration date was extended.
+type ExtendedVersion struct {
+	VersionRef `json:",inline"`
+	// CatalogDir is the root directory of the catalog of the version.
+	CatalogDir string `json:"catalogDir"`
+	// File is the slash separated path of the catalog file relative to CatalogDir.
+	File string `json:"file"`
+	// PreviousExpirationDate is the expiration date before the extension.
+	PreviousExpirationDate string `json:"previousExpirationDate"`
+}
+
+// ExpirationAuditEntry records an extension of expiration dates.
+type ExpirationAuditEntry struct {
+	Time           time.Time         `json:"time"`
+	Actor          string            `json:"actor,omitempty"`
+	Reason         string            `json:"reason"`
+	Constraint     string            `json:"constraint,omitempty"`
+	Images         []string          `json:"images,omitempty"`
+	ExpirationDate string            `json:"expirationDate"`
+	DryRun         bool              `json:"dryRun,omitempty"`
+	Versions       []ExtendedVersion `json:"versions"`
+}
+
+// ExtendExpirations sets the expiration date of the selected versions of all catalogs in one operation and returns
+// the audit entry of the extension. The changed catalog files keep their comments and the order of their keys, files of
+// the legacy format are converted into the current format.
+func ExtendExpirations(ctx context.Context, catalogDirs []string, extension *ExpirationExtension) (*ExpirationAuditEntry, error) {
+	log := LoggerFromContext(ctx)
+
+	if err := validateExpirationExtension(catalogDirs, extension); err != nil {
+		return nil, err
+	}
+	var versions *constraint.Constraint
+	if len(extension.Constraint) > 0 {
+		var err error
+		if versions, err = constraint.Parse(extension.Constraint); err != nil {
+			return nil, err
+		}
+	}
+
+	expirationDate := extension.ExpirationDate.UTC().Format(expirationDateFormat)
+	audit := &ExpirationAuditEntry{
+		Time:           time.Now().UTC(),
+		Actor:          extension.Actor,
+		Reason:         extension.Reason,
+		Constraint:     extension.Constraint,
+		Images:         extension.Images,
+		ExpirationDate: expirationDate,
+		DryRun:         extension.DryRun,
+		Versions:       []ExtendedVersion{},
+	}
+
+	for _, dir := range catalogDirs {
+		catalog, err := LoadCatalog(dir, extension.LoadOptions)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load catalog %s: %w", dir, err)
+		}
+
+		changes := map[string][]CatalogSource{}
+		for _, entry := range catalog.Entries {
+			version := versionOrEmpty(entry.Version)
+			if len(extension.Images) > 0 && !contains(extension.Images, entry.Name) {
+				continue
+			}
+			if versions != nil && !versions.Evaluate(version) {
+				continue
+			}
+			expiration, err := entry.Version.ExpirationDate()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", entry.Source, err)
+			}
+			if expiration == nil || !expiration.Before(extension.ExpirationDate) {
+				continue
+			}
+
+			previous, ok := entry.Version["expirationDate"].(string)
+			if !ok {
+				previous = expiration.UTC().Format(expirationDateFormat)
+			}
+			changes[entry.Source.File] = append(changes[entry.Source.File], entry.Source)
+			audit.Versions = append(audit.Versions, ExtendedVersion{
+				VersionRef:             VersionRef{Image: entry.Name, Version: version},
+				CatalogDir:             dir,
+				File:                   entry.Source.File,
+				PreviousExpirationDate: previous,
+			})
+		}
+
+		if extension.DryRun {
+			continue
+		}
+		files := make([]string, 0, len(changes))
+		for file := range changes {
+			files = append(files, file)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			path := filepath.Join(dir, filepath.FromSlash(file))
+			log.Info("Extending expiration dates", "file", path, "count", len(changes[file]), "expirationDate", expirationDate)
+			if err := InjectFault(ctx, FaultPointApply, path); err != nil {
+				return nil, fmt.Errorf("unable to extend expiration dates in %s: %w", path, err)
+			}
+			if err := setExpirationDates(path, changes[file], expirationDate); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return audit, nil
+}
+
+// AppendExpirationAudit appends the audit entry as a json line to the audit log file, which is created if it does
+// not exist.
+func AppendExpirationAudit(path string, audit *ExpirationAuditEntry) error {
+	data, err := json.Marshal(audit)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func validateExpirationExtension(catalogDirs []string, extension *ExpirationExtension) error {
+	if extension == nil {
+		return errors.New("an expiration extension must be provided")
+	}
+	if len(catalogDirs) == 0 {
+		return errors.New("at least one catalog directory must be provided")
+	}
+	if extension.ExpirationDate.IsZero() {
+		return errors.New("the expiration date must be provided")
+	}
+	if len(extension.Reason) == 0 {
+		return errors.New("the reason of the extension must be provided")
+	}
+	if options := extension.LoadOptions; options != nil {
+		if options.Strictness == CatalogLenient {
+			return errors.New("expiration dates cannot be extended in leniently loaded catalogs")
+		}
+		if options.FieldMapping != nil {
+			return errors.New("expiration dates cannot be extended in catalogs with a field mapping")
+		}
+	}
+	return nil
+}
+
+// setExpirationDates sets the expiration date of the versions at the sources of a catalog file and replaces the file
+// atomically.
+func setExpirationDates(path string, sources []CatalogSource, expirationDate string) error {
+	return editCatalogFile(path, func(doc *catalogDocument) error {
+		for _, source := range sources {
+			if err := doc.setVersionField(source.Path, "expirationDate", expirationDate); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
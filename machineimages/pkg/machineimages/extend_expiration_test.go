@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("extend expiration", func() {
+
+	var (
+		dir       string
+		until     time.Time
+		extension *ExpirationExtension
+	)
+
+	write := func(file, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+	expirationDates := func(catalogDir string) map[string]string {
+		catalog, err := LoadCatalog(catalogDir, nil)
+		Expect(err).NotTo(HaveOccurred())
+		result := map[string]string{}
+		for _, entry := range catalog.Entries {
+			expirationDate, _ := entry.Version["expirationDate"].(string)
+			result[entry.Name+"/"+versionOrEmpty(entry.Version)] = expirationDate
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "extend")
+		Expect(err).NotTo(HaveOccurred())
+
+		write("dev/images.yaml", `- name: gardenlinux
+  versions:
+  - version: 934.7.0
+    expirationDate: "2024-03-01T00:00:00Z"
+  - version: 934.8.0
+    expirationDate: "2024-03-01T00:00:00Z"
+  - version: 934.6.0
+- name: ubuntu
+  versions:
+  - version: 18.4.0
+    expirationDate: "2024-03-01"
+`)
+		write("prod/images.yaml", `- name: gardenlinux
+  versions:
+  - version: 934.7.0
+    expirationDate: "2024-06-01T00:00:00Z"
+  - version: 934.5.0
+    expirationDate: "2024-02-01T00:00:00Z"
+`)
+
+		until = time.Date(2024, 4, 30, 23, 59, 59, 0, time.UTC)
+		extension = &ExpirationExtension{
+			Images:         []string{OsNameGardenLinux},
+			Constraint:     "<934.8",
+			ExpirationDate: until,
+			Reason:         "change freeze",
+			Actor:          "operator",
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should extend the selected versions of all catalogs", func() {
+		audit, err := ExtendExpirations(context.Background(), []string{filepath.Join(dir, "dev"), filepath.Join(dir, "prod")}, extension)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(audit.Reason).To(Equal("change freeze"))
+		Expect(audit.Actor).To(Equal("operator"))
+		Expect(audit.ExpirationDate).To(Equal("2024-04-30T23:59:59Z"))
+		Expect(audit.Versions).To(Equal([]ExtendedVersion{
+			{VersionRef: VersionRef{Image: OsNameGardenLinux, Version: "934.7.0"}, CatalogDir: filepath.Join(dir, "dev"), File: "images.yaml", PreviousExpirationDate: "2024-03-01T00:00:00Z"},
+			{VersionRef: VersionRef{Image: OsNameGardenLinux, Version: "934.5.0"}, CatalogDir: filepath.Join(dir, "prod"), File: "images.yaml", PreviousExpirationDate: "2024-02-01T00:00:00Z"},
+		}))
+
+		Expect(expirationDates(filepath.Join(dir, "dev"))).To(Equal(map[string]string{
+			"gardenlinux/934.7.0": "2024-04-30T23:59:59Z",
+			"gardenlinux/934.8.0": "2024-03-01T00:00:00Z",
+			"gardenlinux/934.6.0": "",
+			"ubuntu/18.4.0":       "2024-03-01",
+		}))
+		Expect(expirationDates(filepath.Join(dir, "prod"))).To(Equal(map[string]string{
+			"gardenlinux/934.7.0": "2024-06-01T00:00:00Z",
+			"gardenlinux/934.5.0": "2024-04-30T23:59:59Z",
+		}))
+	})
+
+	It("should keep the comments and the order of the keys of the catalog files", func() {
+		write("stage/images.yaml", `apiVersion: machineimages.landscaper.gardener.cloud/v1alpha1
+kind: MachineImageCatalog
+machineImages:
+# maintained by the os team
+- name: gardenlinux
+  versions:
+  - version: 934.7.0
+    expirationDate: 2024-03-01T00:00:00Z # end of maintenance
+    classification: supported
+`)
+		audit, err := ExtendExpirations(context.Background(), []string{filepath.Join(dir, "stage")}, extension)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(audit.Versions).To(HaveLen(1))
+		Expect(audit.Versions[0].PreviousExpirationDate).To(Equal("2024-03-01T00:00:00Z"))
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "stage", "images.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`apiVersion: machineimages.landscaper.gardener.cloud/v1alpha1
+kind: MachineImageCatalog
+machineImages:
+  # maintained by the os team
+  - name: gardenlinux
+    versions:
+      - version: 934.7.0
+        expirationDate: 2024-04-30T23:59:59Z # end of maintenance
+        classification: supported
+`))
+	})
+
+	It("should not write the catalogs in a dry run", func() {
+		extension.DryRun = true
+		audit, err := ExtendExpirations(context.Background(), []string{filepath.Join(dir, "dev")}, extension)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(audit.Versions).To(HaveLen(1))
+		Expect(expirationDates(filepath.Join(dir, "dev"))["gardenlinux/934.7.0"]).To(Equal("2024-03-01T00:00:00Z"))
+	})
+
+	It("should append the audit entries to the audit log", func() {
+		path := filepath.Join(dir, "audit.jsonl")
+		audit, err := ExtendExpirations(context.Background(), []string{filepath.Join(dir, "dev")}, extension)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(AppendExpirationAudit(path, audit)).To(Succeed())
+		Expect(AppendExpirationAudit(path, audit)).To(Succeed())
+
+		data, err := ioutil.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		Expect(lines).To(HaveLen(2))
+		loaded := &ExpirationAuditEntry{}
+		Expect(json.Unmarshal([]byte(lines[1]), loaded)).To(Succeed())
+		Expect(loaded.Versions).To(Equal(audit.Versions))
+	})
+
+	It("should require a reason and a valid constraint", func() {
+		extension.Reason = ""
+		_, err := ExtendExpirations(context.Background(), []string{dir}, extension)
+		Expect(err).To(MatchError("the reason of the extension must be provided"))
+
+		extension.Reason = "freeze"
+		extension.Constraint = ">>1"
+		_, err = ExtendExpirations(context.Background(), []string{dir}, extension)
+		Expect(err).To(MatchError(ContainSubstring("invalid version constraint")))
+	})
+})
@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EnvVarFeatureGates contains a comma separated list of <feature>=<bool> pairs.
+	EnvVarFeatureGates = "MACHINEIMAGES_FEATURE_GATES"
+	// FaultInjectionFeatureGate enables fault injection in binaries which are not built with the faultinjection tag.
+	FaultInjectionFeatureGate = "FaultInjection"
+)
+
+// FeatureGates are the names of all feature gates of EnvVarFeatureGates.
+var FeatureGates = []string{FaultInjectionFeatureGate}
+
+// FaultPoint identifies a code path at which faults can be injected.
+type FaultPoint string
+
+const (
+	// FaultPointFetch is reached before data is fetched from a remote source. The target is the url.
+	FaultPointFetch FaultPoint = "fetch"
+	// FaultPointApply is reached before results are written to their destination, e.g. promoted versions to the
+	// catalog file of the target stage. The target is the destination.
+	FaultPointApply FaultPoint = "apply"
+)
+
+// FaultInjector decides whether a fault point fails or is delayed. It is only used for resilience testing.
+type FaultInjector interface {
+	// Inject is called when a fault point is reached. A returned error is returned by the code path instead of
+	// performing the operation. Implementations may block to simulate slow operations and must respect the context.
+	Inject(ctx context.Context, point FaultPoint, target string) error
+}
+
+type faultInjectorKey struct{}
+
+// FaultInjectionEnabled returns whether fault injection is available. This is the case if the binary is built with the
+// faultinjection tag or the FaultInjection feature gate is enabled.
+func FaultInjectionEnabled() bool {
+	return faultInjectionBuild || featureGateEnabled(os.Getenv(EnvVarFeatureGates), FaultInjectionFeatureGate)
+}
+
+// WithFaultInjector returns a context in which the injector is called at all fault points. The context is returned
+// unchanged if fault injection is not enabled.
+func WithFaultInjector(ctx context.Context, injector FaultInjector) context.Context {
+	if injector == nil || !FaultInjectionEnabled() {
+		return ctx
+	}
+	return context.WithValue(ctx, faultInjectorKey{}, injector)
+}
+
+// InjectFault must be called when a fault point is reached. It returns the error of the fault injector of the context
+// and nil if the context has no injector.
+func InjectFault(ctx context.Context, point FaultPoint, target string) error {
+	injector, ok := ctx.Value(faultInjectorKey{}).(FaultInjector)
+	if !ok {
+		return nil
+	}
+	return injector.Inject(ctx, point, target)
+}
+
+// ValidateFeatureGates returns an error if the feature gates in the format of EnvVarFeatureGates contain an unknown
+// feature gate or a value which is not a bool.
+func ValidateFeatureGates(gates string) error {
+	for _, gate := range strings.Split(gates, ",") {
+		name, value, set := parseFeatureGate(gate)
+		if len(name) == 0 && !set {
+			continue
+		}
+		if !contains(FeatureGates, name) {
+			return fmt.Errorf("unknown feature gate %q, known feature gates are %s", name, strings.Join(FeatureGates, ", "))
+		}
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid value %q of feature gate %s, expected a bool", value, name)
+		}
+	}
+	return nil
+}
+
+// featureGateEnabled returns whether the feature gate is enabled. A feature gate without value is enabled, invalid
+// values are rejected by ValidateFeatureGates and disable the feature gate.
+func featureGateEnabled(gates, name string) bool {
+	for _, gate := range strings.Split(gates, ",") {
+		if n, value, _ := parseFeatureGate(gate); n == name {
+			enabled, err := strconv.ParseBool(value)
+			return err == nil && enabled
+		}
+	}
+	return false
+}
+
+// parseFeatureGate returns the name and the value of a <feature>=<bool> pair. The value of a feature gate without a
+// value is true.
+func parseFeatureGate(gate string) (string, string, bool) {
+	parts := strings.SplitN(strings.TrimSpace(gate), "=", 2)
+	if len(parts) == 1 {
+		return parts[0], "true", false
+	}
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), true
+}
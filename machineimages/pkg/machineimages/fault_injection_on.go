@@ -0,0 +1,10 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build faultinjection
+// +build faultinjection
+
+package machineimages
+
+const faultInjectionBuild = true
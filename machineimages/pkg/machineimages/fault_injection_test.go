@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type failingInjector struct {
+	err error
+}
+
+func (i failingInjector) Inject(_ context.Context, _ FaultPoint, _ string) error {
+	return i.err
+}
+
+var _ = Describe("fault injection", func() {
+
+	It("should parse feature gates", func() {
+		Expect(featureGateEnabled("", FaultInjectionFeatureGate)).To(BeFalse())
+		Expect(featureGateEnabled("FaultInjection=true", FaultInjectionFeatureGate)).To(BeTrue())
+		Expect(featureGateEnabled("Other=true, FaultInjection", FaultInjectionFeatureGate)).To(BeTrue())
+		Expect(featureGateEnabled("FaultInjection=false", FaultInjectionFeatureGate)).To(BeFalse())
+		Expect(featureGateEnabled("FaultInjection = 1", FaultInjectionFeatureGate)).To(BeTrue())
+		Expect(featureGateEnabled("FaultInjection=yes", FaultInjectionFeatureGate)).To(BeFalse())
+	})
+
+	It("should validate feature gates", func() {
+		Expect(ValidateFeatureGates("")).To(Succeed())
+		Expect(ValidateFeatureGates("FaultInjection")).To(Succeed())
+		Expect(ValidateFeatureGates("FaultInjection=false,")).To(Succeed())
+		Expect(ValidateFeatureGates("FaultInjection=yes")).To(MatchError(ContainSubstring(`invalid value "yes"`)))
+		Expect(ValidateFeatureGates("FaultInjektion=true")).To(MatchError(ContainSubstring(`unknown feature gate "FaultInjektion"`)))
+		Expect(ValidateFeatureGates("=true")).To(MatchError(ContainSubstring(`unknown feature gate ""`)))
+	})
+
+	It("should only inject faults if the feature gate is enabled", func() {
+		if faultInjectionBuild {
+			Skip("fault injection is enabled by the build tag")
+		}
+
+		injected := errors.New("injected")
+		Expect(InjectFault(WithFaultInjector(context.Background(), failingInjector{injected}), FaultPointFetch, "")).To(Succeed())
+
+		os.Setenv(EnvVarFeatureGates, FaultInjectionFeatureGate+"=true")
+		defer os.Unsetenv(EnvVarFeatureGates)
+
+		ctx := WithFaultInjector(context.Background(), failingInjector{injected})
+		Expect(InjectFault(ctx, FaultPointFetch, "")).To(MatchError(injected))
+		Expect(InjectFault(context.Background(), FaultPointFetch, "")).To(Succeed())
+	})
+})
@@ -0,0 +1,23 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package faults
+
+import (
+	"os"
+	"testing"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestFaults(t *testing.T) {
+	os.Setenv(mi.EnvVarFeatureGates, mi.FaultInjectionFeatureGate+"=true")
+	defer os.Unsetenv(mi.EnvVarFeatureGates)
+
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Faults Test Suite")
+}
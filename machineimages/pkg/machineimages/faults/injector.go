@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package faults contains a fault injector which simulates slow sources, failing fetches and apply conflicts. It is
+// only effective if fault injection is enabled, see machineimages.FaultInjectionEnabled.
+package faults
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+var (
+	// ErrInjected is wrapped by all errors which are returned by an injector.
+	ErrInjected = errors.New("injected fault")
+	// ErrUnavailable simulates a source which is temporarily not available.
+	ErrUnavailable = &injectedError{message: "source unavailable"}
+	// ErrConflict simulates a conflicting concurrent write of the destination.
+	ErrConflict = &injectedError{message: "conflict"}
+)
+
+type injectedError struct {
+	message string
+}
+
+func (e *injectedError) Error() string {
+	return ErrInjected.Error() + ": " + e.message
+}
+
+func (e *injectedError) Unwrap() error {
+	return ErrInjected
+}
+
+// IsInjected returns whether the error or one of the errors it wraps was returned by an injector.
+func IsInjected(err error) bool {
+	return errors.Is(err, ErrInjected)
+}
+
+// Fault describes a fault of all fault points which match the point and the target.
+type Fault struct {
+	// Point is the fault point of the fault. All fault points match if empty.
+	Point mi.FaultPoint
+	// Target must be contained in the target of the fault point. All targets match if empty.
+	Target string
+	// Delay is the time the fault point is blocked before it continues or fails.
+	Delay time.Duration
+	// Err is returned by the fault point. The fault point only is delayed if nil.
+	Err error
+	// Times is the number of times the fault occurs, afterwards the fault point succeeds. Unlimited if 0.
+	Times int
+	// Probability is the probability with which a matching fault point fails. Always if 0.
+	Probability float64
+}
+
+// Injector injects the first matching fault at a fault point.
+type Injector struct {
+	faults []Fault
+
+	mutex  sync.Mutex
+	rand   *rand.Rand
+	counts []int
+	hits   map[mi.FaultPoint]int
+}
+
+var _ mi.FaultInjector = &Injector{}
+
+// New returns an injector of the faults. The seed makes probabilistic faults reproducible.
+func New(seed int64, faults ...Fault) *Injector {
+	return &Injector{
+		faults: faults,
+		rand:   rand.New(rand.NewSource(seed)),
+		counts: make([]int, len(faults)),
+		hits:   map[mi.FaultPoint]int{},
+	}
+}
+
+// Context returns a context in which the injector is called at all fault points.
+func (i *Injector) Context(ctx context.Context) context.Context {
+	return mi.WithFaultInjector(ctx, i)
+}
+
+// Inject blocks for the delay of the first matching fault and returns its error.
+func (i *Injector) Inject(ctx context.Context, point mi.FaultPoint, target string) error {
+	fault, ok := i.match(point, target)
+	if !ok {
+		return nil
+	}
+
+	if fault.Delay > 0 {
+		timer := time.NewTimer(fault.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return fault.Err
+}
+
+// Hits returns how often a fault was injected at the fault point.
+func (i *Injector) Hits(point mi.FaultPoint) int {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.hits[point]
+}
+
+func (i *Injector) match(point mi.FaultPoint, target string) (Fault, bool) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for n, fault := range i.faults {
+		if len(fault.Point) > 0 && fault.Point != point {
+			continue
+		}
+		if !strings.Contains(target, fault.Target) {
+			continue
+		}
+		if fault.Times > 0 && i.counts[n] >= fault.Times {
+			continue
+		}
+		if fault.Probability > 0 && i.rand.Float64() >= fault.Probability {
+			continue
+		}
+
+		i.counts[n]++
+		i.hits[point]++
+		return fault, true
+	}
+	return Fault{}, false
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package faults
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("injector", func() {
+
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(json.NewEncoder(w).Encode([]mi.Incident{{Image: mi.OsNameUbuntu, Version: "1.0.0"}})).To(Succeed())
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should fail fetches the configured number of times", func() {
+		injector := New(0, Fault{Point: mi.FaultPointFetch, Target: server.URL, Err: ErrUnavailable, Times: 2})
+		ctx := injector.Context(context.Background())
+		source := &mi.WebhookIncidentSource{URL: server.URL}
+
+		for i := 0; i < 2; i++ {
+			_, err := source.Incidents(ctx)
+			Expect(IsInjected(err)).To(BeTrue())
+		}
+
+		incidents, err := source.Incidents(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(incidents).To(HaveLen(1))
+		Expect(injector.Hits(mi.FaultPointFetch)).To(Equal(2))
+	})
+
+	It("should only fail matching targets", func() {
+		injector := New(0, Fault{Target: "other.example.com", Err: ErrUnavailable})
+
+		_, err := (&mi.WebhookIncidentSource{URL: server.URL}).Incidents(injector.Context(context.Background()))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(injector.Hits(mi.FaultPointFetch)).To(Equal(0))
+	})
+
+	It("should fail fetches with the configured probability", func() {
+		injector := New(42, Fault{Point: mi.FaultPointFetch, Err: ErrUnavailable, Probability: 0.5})
+		ctx := injector.Context(context.Background())
+
+		failed := 0
+		for i := 0; i < 100; i++ {
+			if _, err := (&mi.WebhookIncidentSource{URL: server.URL}).Incidents(ctx); err != nil {
+				failed++
+			}
+		}
+		Expect(failed).To(BeNumerically("~", 50, 15))
+		Expect(injector.Hits(mi.FaultPointFetch)).To(Equal(failed))
+	})
+
+	It("should delay slow sources until the context is done", func() {
+		injector := New(0, Fault{Point: mi.FaultPointFetch, Delay: time.Minute})
+		ctx, cancel := context.WithTimeout(injector.Context(context.Background()), 10*time.Millisecond)
+		defer cancel()
+
+		started := time.Now()
+		_, err := (&mi.WebhookIncidentSource{URL: server.URL}).Incidents(ctx)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(time.Since(started)).To(BeNumerically("<", time.Minute))
+	})
+
+	It("should simulate apply conflicts of promotions", func() {
+		dir, err := ioutil.TempDir("", "faults")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		from := mi.Stage{Name: "dev", CatalogDir: filepath.Join(dir, "dev"), File: "images.yaml"}
+		to := mi.Stage{Name: "canary", CatalogDir: filepath.Join(dir, "canary"), File: "images.yaml"}
+		Expect(os.MkdirAll(from.CatalogDir, 0755)).To(Succeed())
+		Expect(os.MkdirAll(to.CatalogDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(from.CatalogDir, "images.yaml"), []byte(`
+- name: gardenlinux
+  versions:
+  - version: 934.0.0
+    classification: supported
+`), 0644)).To(Succeed())
+
+		injector := New(0, Fault{Point: mi.FaultPointApply, Err: ErrConflict, Times: 1})
+		ctx := injector.Context(context.Background())
+
+		_, err = mi.PromoteCatalog(ctx, from, to, &mi.PromotionPolicy{})
+		Expect(err).To(MatchError(ErrConflict))
+		_, err = os.Stat(filepath.Join(to.CatalogDir, "images.yaml"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+
+		result, err := mi.PromoteCatalog(ctx, from, to, &mi.PromotionPolicy{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Promoted).To(HaveLen(1))
+	})
+
+	It("should not inject faults if fault injection is disabled", func() {
+		os.Unsetenv(mi.EnvVarFeatureGates)
+		defer os.Setenv(mi.EnvVarFeatureGates, mi.FaultInjectionFeatureGate+"=true")
+		if mi.FaultInjectionEnabled() {
+			Skip("fault injection is enabled by the build tag")
+		}
+
+		injector := New(0, Fault{Err: ErrUnavailable})
+		_, err := (&mi.WebhookIncidentSource{URL: server.URL}).Incidents(injector.Context(context.Background()))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// FetchError is returned if a request to an http endpoint fails or is answered with an unexpected status or body.
+type FetchError struct {
+	// Operation describes the request, e.g. "fetch incidents".
+	Operation string
+	URL       string
+	// StatusCode is the unexpected status of the response, or zero if the request failed.
+	StatusCode int
+	Err        error
+}
+
+func (e *FetchError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("unable to %s: unexpected status %d of %s", e.Operation, e.StatusCode, e.URL)
+	}
+	return fmt.Sprintf("unable to %s: %v", e.Operation, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// fetchJSON gets the url and decodes the json response into body. The network policy guard of the context is checked
+// before the request, and a guarded client is used if client is nil.
+func fetchJSON(ctx context.Context, client *http.Client, operation, url string, body interface{}) error {
+	return fetchAuthorizedJSON(ctx, client, operation, url, "", body)
+}
+
+// fetchAuthorizedJSON is fetchJSON with a bearer token, which is omitted if empty.
+func fetchAuthorizedJSON(ctx context.Context, client *http.Client, operation, url, token string, body interface{}) error {
+	return doAuthorizedJSON(ctx, client, http.MethodGet, operation, url, token, nil, body)
+}
+
+// postAuthorizedJSON posts the json encoded request to the url and decodes the json response into body, like
+// fetchAuthorizedJSON. The response is ignored if body is nil.
+func postAuthorizedJSON(ctx context.Context, client *http.Client, operation, url, token string, request, body interface{}) error {
+	return doAuthorizedJSON(ctx, client, http.MethodPost, operation, url, token, request, body)
+}
+
+func doAuthorizedJSON(ctx context.Context, client *http.Client, method, operation, url, token string, request, body interface{}) error {
+	if err := CheckNetworkAccess(ctx, operation, url); err != nil {
+		return err
+	}
+
+	if err := InjectFault(ctx, FaultPointFetch, url); err != nil {
+		return &FetchError{Operation: operation, URL: url, Err: err}
+	}
+
+	if client == nil {
+		client = NewHTTPClient(nil)
+	}
+
+	var reader io.Reader
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if request != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &FetchError{Operation: operation, URL: url, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &FetchError{Operation: operation, URL: url, StatusCode: resp.StatusCode}
+	}
+
+	if body == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+		return &FetchError{Operation: operation, URL: url, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FieldMappingSources are the sources of the imports which may have a field mapping, named like their import.
+var FieldMappingSources = []string{"machineImages", "machineImagesLs", "machineImagesProvider", "machineImagesProviderLs"}
+
+// FieldMapping translates the versions of a source with a foreign schema into the internal model, so that catalogs
+// which e.g. name the version "imageVersion" can be consumed without pre-processing.
+type FieldMapping struct {
+	// Fields maps fields of the internal model to the dot separated path of the field in the source, e.g. "version"
+	// to "imageVersion" or to "release.version". The source field is removed and its value set as the internal field,
+	// replacing an existing value. Versions without the source field are kept as they are.
+	Fields map[string]string `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// Validate returns an error if a field or path of the mapping is empty.
+func (m *FieldMapping) Validate() error {
+	if m == nil {
+		return nil
+	}
+	for _, field := range m.fieldNames() {
+		if len(field) == 0 || strings.Contains(field, ".") {
+			return fmt.Errorf("invalid field %q, expected a top level field", field)
+		}
+		for _, segment := range strings.Split(m.Fields[field], ".") {
+			if len(segment) == 0 {
+				return fmt.Errorf("invalid path %q of field %s", m.Fields[field], field)
+			}
+		}
+	}
+	return nil
+}
+
+// Apply returns the images with mapped versions. The images are not modified. A nil mapping returns the images.
+func (m *FieldMapping) Apply(images []MachineImage) []MachineImage {
+	if m == nil || len(m.Fields) == 0 {
+		return images
+	}
+
+	result := make([]MachineImage, 0, len(images))
+	for _, image := range images {
+		mapped := MachineImage{Name: image.Name}
+		if image.Versions != nil {
+			mapped.Versions = make([]MachineImageVersion, 0, len(image.Versions))
+		}
+		for _, version := range image.Versions {
+			mapped.Versions = append(mapped.Versions, m.applyToVersion(version))
+		}
+		result = append(result, mapped)
+	}
+	return result
+}
+
+func (m *FieldMapping) applyToVersion(version MachineImageVersion) MachineImageVersion {
+	if m == nil || len(m.Fields) == 0 || version == nil {
+		return version
+	}
+
+	// all values are read before any field is removed, so that fields can be swapped
+	values := map[string]interface{}{}
+	for _, field := range m.fieldNames() {
+		if value, ok := lookupPath(version, strings.Split(m.Fields[field], ".")); ok {
+			values[field] = value
+		}
+	}
+	if len(values) == 0 {
+		return version
+	}
+
+	result := map[string]interface{}(version)
+	for field := range values {
+		result = removePath(result, strings.Split(m.Fields[field], "."))
+	}
+	for field, value := range values {
+		result[field] = value
+	}
+	return result
+}
+
+func (m *FieldMapping) fieldNames() []string {
+	fields := make([]string, 0, len(m.Fields))
+	for field := range m.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func lookupPath(object map[string]interface{}, path []string) (interface{}, bool) {
+	value, ok := object[path[0]]
+	if !ok || len(path) == 1 {
+		return value, ok
+	}
+	nested, isObject := value.(map[string]interface{})
+	if !isObject {
+		return nil, false
+	}
+	return lookupPath(nested, path[1:])
+}
+
+// removePath returns a copy of the object without the field of the path. Objects which become empty are removed.
+func removePath(object map[string]interface{}, path []string) map[string]interface{} {
+	result := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		result[key] = value
+	}
+	if len(path) == 1 {
+		delete(result, path[0])
+		return result
+	}
+	if nested, ok := result[path[0]].(map[string]interface{}); ok {
+		nested = removePath(nested, path[1:])
+		if len(nested) == 0 {
+			delete(result, path[0])
+		} else {
+			result[path[0]] = nested
+		}
+	}
+	return result
+}
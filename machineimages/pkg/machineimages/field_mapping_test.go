@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("field mapping", func() {
+
+	mapping := &FieldMapping{Fields: map[string]string{
+		"version":        "imageVersion",
+		"classification": "lifecycle.stage",
+	}}
+
+	It("should map the fields of the versions", func() {
+		images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"imageVersion": "318.8.0", "lifecycle": map[string]interface{}{"stage": "supported", "since": "2021-01-01"}},
+			{"imageVersion": "318.9.0", "lifecycle": map[string]interface{}{"stage": "preview"}, "cri": "containerd"},
+			{"version": "318.10.0"},
+		}}}
+
+		Expect(mapping.Apply(images)).To(Equal([]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "318.8.0", "classification": "supported", "lifecycle": map[string]interface{}{"since": "2021-01-01"}},
+			{"version": "318.9.0", "classification": "preview", "cri": "containerd"},
+			{"version": "318.10.0"},
+		}}}))
+		Expect(images[0].Versions[0]).To(HaveKey("imageVersion"))
+	})
+
+	It("should swap fields", func() {
+		swap := &FieldMapping{Fields: map[string]string{"version": "name", "name": "version"}}
+		Expect(swap.applyToVersion(MachineImageVersion{"version": "a", "name": "b"})).To(Equal(MachineImageVersion{"version": "b", "name": "a"}))
+	})
+
+	It("should return the images without mapping", func() {
+		images := []MachineImage{{Name: OsNameUbuntu}}
+		var none *FieldMapping
+		Expect(none.Apply(images)).To(Equal(images))
+		Expect(none.Validate()).To(Succeed())
+	})
+
+	It("should reject invalid fields and paths", func() {
+		Expect((&FieldMapping{Fields: map[string]string{"a.b": "c"}}).Validate()).To(MatchError(`invalid field "a.b", expected a top level field`))
+		Expect((&FieldMapping{Fields: map[string]string{"version": "release..version"}}).Validate()).To(MatchError(`invalid path "release..version" of field version`))
+	})
+
+	It("should map the sources of the computation", func() {
+		result, err := ComputeMachineImagesWithOptions(
+			context.Background(),
+			logr.Discard(),
+			nil,
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"imageVersion": "318.8.0", "lifecycle": map[string]interface{}{"stage": "supported"}}}}},
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.8.0", "image": "gl-318-8"}}}},
+			nil,
+			nil,
+			nil,
+			nil,
+			&ComputeMachineImagesOptions{FieldMappings: map[string]*FieldMapping{"machineImagesLs": mapping}},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "318.8.0", "classification": "supported", "image": "gl-318-8"},
+		}}}))
+	})
+
+	It("should validate the imports with the mapped versions", func() {
+		imports := &Imports{
+			MachineImagesLs: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"imageVersion": "318.8.0"}}}},
+			ComputeMachineImagesOptions: ComputeMachineImagesOptions{
+				FieldMappings: map[string]*FieldMapping{"machineImagesLs": mapping},
+			},
+		}
+		Expect(ValidateImports(imports)).To(Succeed())
+
+		imports.FieldMappings = map[string]*FieldMapping{"catalog": mapping}
+		validationErr, ok := ValidateImports(imports).(*ValidationError)
+		Expect(ok).To(BeTrue())
+		Expect(validationErr.Problems).To(Equal([]string{
+			`fieldMappings: unknown source "catalog", expected one of machineImages, machineImagesLs, machineImagesProvider, machineImagesProviderLs`,
+			"machineImagesLs[0].versions[0].version: must be set",
+		}))
+	})
+
+	It("should map the versions of a catalog", func() {
+		catalog, err := LoadCatalog("./resources/catalog", &CatalogLoadOptions{
+			Include:      []string{"aws/gardenlinux.yaml"},
+			FieldMapping: &FieldMapping{Fields: map[string]string{"stage": "classification"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(catalog.Entries[0].Version).To(Equal(MachineImageVersion{"version": "318.8.0", "stage": "supported"}))
+	})
+})
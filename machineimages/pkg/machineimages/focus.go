@@ -0,0 +1,139 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import "fmt"
+
+// focusImages returns the images whose name is focused. Without focus, all images are returned.
+func focusImages(images []MachineImage, focus []string) []MachineImage {
+	if len(focus) == 0 {
+		return images
+	}
+	result := []MachineImage{}
+	for _, image := range images {
+		if contains(focus, image.Name) {
+			result = append(result, image)
+		}
+	}
+	return result
+}
+
+// focusAndMap returns the focused images of the source, see FieldMappingSources, with the field mapping of the source
+// applied, like they enter the computation.
+func (o *ComputeMachineImagesOptions) focusAndMap(source string, images []MachineImage) []MachineImage {
+	return o.FieldMappings[source].Apply(focusImages(images, o.Focus))
+}
+
+// focusNames returns the names which are focused. Without focus, all names are returned.
+func focusNames(names []string, focus []string) []string {
+	if len(focus) == 0 {
+		return names
+	}
+	result := []string{}
+	for _, name := range names {
+		if contains(focus, name) {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// focusDisablePatterns returns the patterns which may disable a focused image, so that the patterns of the other
+// images are not reported as unmatched. Without focus, all patterns are returned.
+func focusDisablePatterns(patterns []*disablePattern, focus []string) []*disablePattern {
+	if len(focus) == 0 {
+		return patterns
+	}
+	result := []*disablePattern{}
+	for _, pattern := range patterns {
+		for _, name := range focus {
+			if pattern.image(name) {
+				result = append(result, pattern)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// MergeFocusedBaseline merges the images and warnings of a computation with focus into the focus baseline, see
+// MergeFocused. The warnings of the images which are not focused are those of the baseline. A focus without baseline is
+// rejected, as the exports would remove all images which are not focused. Without focus, the images and warnings are
+// returned.
+func (o *ComputeMachineImagesOptions) MergeFocusedBaseline(images []MachineImage, warnings []ReportEntry) ([]MachineImage, []ReportEntry, error) {
+	if len(o.Focus) == 0 {
+		return images, warnings, nil
+	}
+	baseline := o.FocusBaseline
+	if baseline == nil {
+		return nil, nil, &ValidationError{Problems: []string{"focus: the previously applied machine images are required as " +
+			"baseline, otherwise the images which are not focused would be removed"}}
+	}
+	previous := baseline.ResultMachineImages
+	if baseline.ResultMachineImagesConfigMap != nil {
+		var err error
+		if previous, err = MachineImagesFromConfigMap(baseline.ResultMachineImagesConfigMap, baseline.ResultMachineImagesRef); err != nil {
+			return nil, nil, fmt.Errorf("unable to read the focus baseline: %w", err)
+		}
+	}
+
+	mergedWarnings := []ReportEntry{}
+	for _, warning := range baseline.ResultWarnings {
+		if !contains(o.Focus, warning.Image) {
+			mergedWarnings = append(mergedWarnings, warning)
+		}
+	}
+	return MergeFocused(previous, images, o.Focus), append(mergedWarnings, warnings...), nil
+}
+
+// mergeFocusedAliases replaces the aliases of the images which are not focused by the aliases of the focus baseline,
+// if the baseline has aliases for them.
+func (o *ComputeMachineImagesOptions) mergeFocusedAliases(aliases VersionAliases) VersionAliases {
+	if len(o.Focus) == 0 || o.FocusBaseline == nil {
+		return aliases
+	}
+	for image, imageAliases := range o.FocusBaseline.ResultVersionAliases {
+		if contains(o.Focus, image) {
+			continue
+		}
+		if aliases == nil {
+			aliases = VersionAliases{}
+		}
+		aliases[image] = imageAliases
+	}
+	return aliases
+}
+
+// MergeFocused returns the previous machine images with the focused images replaced by the computed images, so that a
+// computation with focus only changes the focused images when it is applied. Focused images which are not computed
+// are removed, computed images which were not previously contained are appended. Without focus, the computed images
+// are returned.
+func MergeFocused(previous, computed []MachineImage, focus []string) []MachineImage {
+	if len(focus) == 0 {
+		return computed
+	}
+
+	result := []MachineImage{}
+	merged := map[string]bool{}
+	for _, image := range previous {
+		if !contains(focus, image.Name) {
+			result = append(result, image)
+			continue
+		}
+		for _, next := range computed {
+			if next.Name == image.Name && !merged[next.Name] {
+				result = append(result, next)
+				merged[next.Name] = true
+			}
+		}
+	}
+	for _, image := range computed {
+		if !merged[image.Name] {
+			result = append(result, image)
+			merged[image.Name] = true
+		}
+	}
+	return result
+}
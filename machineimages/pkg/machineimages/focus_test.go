@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("focus", func() {
+
+	imports := func() *Imports {
+		return &Imports{
+			MachineImages: []MachineImage{
+				{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0"}}},
+				{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}, {"version": "22.4.0"}}},
+			},
+			MachineImagesProvider: []MachineImage{
+				{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0", "image": "a"}}},
+			},
+			DisableMachineImages: []string{"ubuntu:20.4.0"},
+			ComputeMachineImagesOptions: ComputeMachineImagesOptions{
+				RequiredImages:             []string{OsNameGardenLinux, OsNameUbuntu},
+				StrictDisableMachineImages: true,
+			},
+		}
+	}
+
+	It("should only validate and compute the focused images", func() {
+		unfocused := imports()
+		Expect(ValidateImports(unfocused)).To(MatchError("invalid imports: machineImages[1].versions[1].version: duplicate version 22.4.0, first at machineImages[1].versions[0]"))
+		_, err := ComputeMachineImagesFromImports(context.Background(), logr.Discard(), unfocused)
+		Expect(err).To(HaveOccurred())
+
+		focused := imports()
+		focused.Focus = []string{OsNameGardenLinux}
+		Expect(ValidateImports(focused)).To(Succeed())
+		result, err := ComputeMachineImagesFromImports(context.Background(), logr.Discard(), focused)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0", "image": "a"}}},
+		}))
+	})
+
+	It("should reject empty and duplicate focused images", func() {
+		focused := imports()
+		focused.Focus = []string{OsNameGardenLinux, "", OsNameGardenLinux}
+		err := ValidateImports(focused)
+		Expect(err).To(MatchError(ContainSubstring("focus: empty image name")))
+		Expect(err).To(MatchError(ContainSubstring("focus: duplicate image gardenlinux")))
+	})
+
+	It("should replace only the focused images of the previous machine images", func() {
+		previous := []MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.6.0"}}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "20.4.0"}}},
+			{Name: OsNameCoreos, Versions: []MachineImageVersion{{"version": "2303.3.0"}}},
+		}
+		computed := []MachineImage{
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}}},
+			{Name: OsNameFlatcar, Versions: []MachineImageVersion{{"version": "3033.2.0"}}},
+		}
+
+		Expect(MergeFocused(previous, computed, []string{OsNameUbuntu, OsNameCoreos, OsNameFlatcar})).To(Equal([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.6.0"}}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}}},
+			{Name: OsNameFlatcar, Versions: []MachineImageVersion{{"version": "3033.2.0"}}},
+		}))
+		Expect(MergeFocused(previous, computed, nil)).To(Equal(computed))
+	})
+
+	It("should merge the focused exports into the focus baseline", func() {
+		focused := imports()
+		focused.Focus = []string{OsNameGardenLinux}
+		_, err := ComputeExports(context.Background(), logr.Discard(), focused)
+		Expect(err).To(MatchError(ContainSubstring("focus: the previously applied machine images are required as baseline")))
+		Expect(ClassOf(err)).To(Equal(ErrorClassValidation))
+
+		focused.FocusBaseline = &Exports{
+			ResultMachineImages: []MachineImage{
+				{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.6.0", "image": "b"}}},
+				{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "20.4.0", "image": "c"}}},
+			},
+			ResultWarnings: []ReportEntry{
+				{Image: OsNameGardenLinux, Version: "934.5.0", Reason: ReasonDisabled},
+				{Image: OsNameUbuntu, Version: "18.4.0", Reason: ReasonDisabled},
+			},
+			ResultVersionAliases: VersionAliases{
+				OsNameGardenLinux: {"latest": "934.6.0"},
+				OsNameUbuntu:      {"latest": "20.4.0"},
+			},
+		}
+		exports, err := ComputeExports(context.Background(), logr.Discard(), focused)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(exports.ResultMachineImages).To(Equal([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0", "image": "a"}}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "20.4.0", "image": "c"}}},
+		}))
+		Expect(exports.ResultWarnings).To(Equal([]ReportEntry{{Image: OsNameUbuntu, Version: "18.4.0", Reason: ReasonDisabled}}))
+		Expect(exports.ResultVersionAliases).To(Equal(VersionAliases{OsNameUbuntu: {"latest": "20.4.0"}}))
+	})
+})
@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gardener
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// ToMachineImages converts machine images into gardener MachineImages. Fields which are not part of the gardener
+// MachineImageVersion, e.g. provider specific fields like regions, are dropped and returned per version, so that callers
+// can decide whether the loss matters.
+func ToMachineImages(images []mi.MachineImage) ([]gardencorev1beta1.MachineImage, map[string][]string, error) {
+	result := make([]gardencorev1beta1.MachineImage, 0, len(images))
+	dropped := map[string][]string{}
+	for _, image := range images {
+		converted := gardencorev1beta1.MachineImage{Name: image.Name}
+		for _, version := range image.Versions {
+			typed, err := toMachineImageVersion(version)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to convert image %s: %w", image.Name, err)
+			}
+			converted.Versions = append(converted.Versions, *typed)
+
+			untyped, err := FromMachineImageVersion(*typed)
+			if err != nil {
+				return nil, nil, err
+			}
+			if fields := droppedFields(version, untyped); len(fields) > 0 {
+				dropped[image.Name+"@"+typed.Version] = fields
+			}
+		}
+		result = append(result, converted)
+	}
+	return result, dropped, nil
+}
+
+// FromMachineImages converts gardener MachineImages into machine images.
+func FromMachineImages(images []gardencorev1beta1.MachineImage) ([]mi.MachineImage, error) {
+	result := make([]mi.MachineImage, 0, len(images))
+	for _, image := range images {
+		converted := mi.MachineImage{Name: image.Name}
+		for _, version := range image.Versions {
+			untyped, err := FromMachineImageVersion(version)
+			if err != nil {
+				return nil, fmt.Errorf("unable to convert image %s: %w", image.Name, err)
+			}
+			converted.Versions = append(converted.Versions, untyped)
+		}
+		result = append(result, converted)
+	}
+	return result, nil
+}
+
+// FromMachineImageVersion converts a gardener MachineImageVersion into a machine image version.
+func FromMachineImageVersion(version gardencorev1beta1.MachineImageVersion) (mi.MachineImageVersion, error) {
+	data, err := json.Marshal(version)
+	if err != nil {
+		return nil, err
+	}
+	result := mi.MachineImageVersion{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func toMachineImageVersion(version mi.MachineImageVersion) (*gardencorev1beta1.MachineImageVersion, error) {
+	data, err := json.Marshal(version)
+	if err != nil {
+		return nil, err
+	}
+	result := &gardencorev1beta1.MachineImageVersion{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("invalid version %v: %w", version["version"], err)
+	}
+	return result, nil
+}
+
+func droppedFields(original, converted mi.MachineImageVersion) []string {
+	fields := []string{}
+	for key := range original {
+		if _, ok := converted[key]; !ok {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gardener
+
+import (
+	"time"
+
+	gardencorev1beta1 "github.com/gardener/gardener/pkg/apis/core/v1beta1"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+var _ = Describe("convert", func() {
+
+	images := []mi.MachineImage{
+		{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{
+			{
+				"version":        "318.8.0",
+				"classification": "supported",
+				"expirationDate": "2021-12-01T00:00:00Z",
+				"cri":            []interface{}{map[string]interface{}{"name": "containerd"}},
+				"regions":        []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}},
+			},
+		}},
+	}
+
+	It("should convert to typed machine images and report dropped fields", func() {
+		typed, dropped, err := ToMachineImages(images)
+		Expect(err).NotTo(HaveOccurred())
+
+		supported := gardencorev1beta1.ClassificationSupported
+		expirationDate := metav1.NewTime(time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC))
+		Expect(typed).To(HaveLen(1))
+		Expect(typed[0].Name).To(Equal(mi.OsNameGardenLinux))
+		Expect(typed[0].Versions).To(HaveLen(1))
+		version := typed[0].Versions[0]
+		Expect(version.Version).To(Equal("318.8.0"))
+		Expect(version.Classification).To(Equal(&supported))
+		Expect(version.ExpirationDate.Equal(&expirationDate)).To(BeTrue())
+		Expect(version.CRI).To(Equal([]gardencorev1beta1.CRI{{Name: gardencorev1beta1.CRINameContainerD}}))
+		Expect(dropped).To(Equal(map[string][]string{"gardenlinux@318.8.0": {"regions"}}))
+	})
+
+	It("should convert typed machine images back", func() {
+		typed, _, err := ToMachineImages(images)
+		Expect(err).NotTo(HaveOccurred())
+
+		untyped, err := FromMachineImages(typed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(untyped).To(Equal([]mi.MachineImage{{Name: mi.OsNameGardenLinux, Versions: []mi.MachineImageVersion{{
+			"version":        "318.8.0",
+			"classification": "supported",
+			"expirationDate": "2021-12-01T00:00:00Z",
+			"cri":            []interface{}{map[string]interface{}{"name": "containerd"}},
+		}}}}))
+	})
+
+	It("should fail on versions with invalid fields", func() {
+		_, _, err := ToMachineImages([]mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{
+			{"version": "18.4.0", "expirationDate": "tomorrow"},
+		}}})
+		Expect(err).To(HaveOccurred())
+	})
+})
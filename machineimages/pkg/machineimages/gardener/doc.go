@@ -0,0 +1,9 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gardener converts machine images to and from the typed MachineImage structs of the gardener
+// core.gardener.cloud v1beta1 api. The package is a module of its own, so that the dependency to
+// github.com/gardener/gardener stays optional for the consumers of the machineimages module. It is built and tested
+// with "make check-gardener test-gardener" of the machineimages module.
+package gardener
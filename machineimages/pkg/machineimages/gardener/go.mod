@@ -0,0 +1,31 @@
+module github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/gardener
+
+go 1.16
+
+require (
+	github.com/gardener/gardener v1.30.0
+	github.com/gardener/landscaper-utils/machineimages v0.0.0-00010101000000-000000000000
+	github.com/onsi/ginkgo v1.16.4
+	github.com/onsi/gomega v1.13.0
+	k8s.io/apimachinery v0.21.2
+)
+
+replace (
+	github.com/emicklei/go-restful => github.com/emicklei/go-restful v2.9.5+incompatible
+	github.com/envoyproxy/go-control-plane => github.com/envoyproxy/go-control-plane v0.9.4
+	github.com/gardener/gardener-resource-manager/api => github.com/gardener/gardener-resource-manager/api v0.25.0
+	github.com/gardener/landscaper-utils/machineimages => ../../..
+	github.com/googleapis/gnostic => github.com/googleapis/gnostic v0.4.1
+	github.com/prometheus/client_golang => github.com/prometheus/client_golang v1.11.0
+	google.golang.org/grpc => google.golang.org/grpc v1.27.1
+	k8s.io/api => k8s.io/api v0.21.2
+	k8s.io/apiextensions-apiserver => k8s.io/apiextensions-apiserver v0.21.2
+	k8s.io/apimachinery => k8s.io/apimachinery v0.21.2
+	k8s.io/apiserver => k8s.io/apiserver v0.21.2
+	k8s.io/client-go => k8s.io/client-go v0.21.2
+	k8s.io/cluster-bootstrap => k8s.io/cluster-bootstrap v0.21.2
+	k8s.io/code-generator => k8s.io/code-generator v0.21.2
+	k8s.io/component-base => k8s.io/component-base v0.21.2
+	k8s.io/helm => k8s.io/helm v2.13.1+incompatible
+	k8s.io/kube-aggregator => k8s.io/kube-aggregator v0.21.2
+)
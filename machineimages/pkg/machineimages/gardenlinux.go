@@ -0,0 +1,160 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// GardenLinuxFlavorField is the field of Garden Linux versions with their flavor, e.g. "aws-gardener_prod_usi-amd64".
+	GardenLinuxFlavorField = "flavor"
+
+	// OsImagesFilterTypeGardenLinuxFeatures is the filter type of the kinds which match the Garden Linux versions whose
+	// flavor has all features of the kind, separated by "+", e.g. "gardenlinux-features:_usi+_trustedboot". Flags are
+	// written with their leading underscore like in the flavor.
+	OsImagesFilterTypeGardenLinuxFeatures = "gardenlinux-features"
+	// OsImagesFilterKindPrefixGardenLinuxFeatures prefixes the kinds of OsImagesFilterTypeGardenLinuxFeatures.
+	OsImagesFilterKindPrefixGardenLinuxFeatures = OsImagesFilterTypeGardenLinuxFeatures + ":"
+)
+
+// GardenLinuxFlavor is a parsed Garden Linux flavor "<platform>[-<feature>...][-<architecture>]". Features and the
+// platform can be followed by flags, e.g. "_usi" or "_trustedboot", which are features with a leading underscore.
+type GardenLinuxFlavor struct {
+	Platform string
+	// Features are the features and flags of the flavor in lexical order, without duplicates.
+	Features []string
+	// Architecture is empty if the flavor has no architecture.
+	Architecture string
+}
+
+// ParseGardenLinuxFlavor parses a Garden Linux flavor. The order of the features and flags does not matter, e.g.
+// "aws-gardener_usi_prod" and "aws-gardener_prod_usi" are the same flavor.
+func ParseGardenLinuxFlavor(flavor string) (*GardenLinuxFlavor, error) {
+	tokens := strings.Split(flavor, "-")
+	result := &GardenLinuxFlavor{}
+	if last := tokens[len(tokens)-1]; len(tokens) > 1 && (last == ArchitectureAMD64 || last == ArchitectureARM64) {
+		result.Architecture = last
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	features := map[string]bool{}
+	for i, token := range tokens {
+		parts := strings.Split(token, "_")
+		switch head := parts[0]; {
+		case i == 0 && len(head) > 0:
+			result.Platform = head
+		case i > 0 && len(head) > 0:
+			features[head] = true
+		case i == 0 || len(parts) == 1:
+			return nil, fmt.Errorf("invalid garden linux flavor %q", flavor)
+		}
+		for _, flag := range parts[1:] {
+			if len(flag) == 0 {
+				return nil, fmt.Errorf("invalid garden linux flavor %q", flavor)
+			}
+			features["_"+flag] = true
+		}
+	}
+	result.Features = sortedKeys(features)
+	return result, nil
+}
+
+// String returns the canonical form of the flavor, in which the features are ordered lexically and the flags follow
+// the last feature.
+func (f *GardenLinuxFlavor) String() string {
+	features, flags := []string{}, ""
+	for _, feature := range f.Features {
+		if strings.HasPrefix(feature, "_") {
+			flags += feature
+		} else {
+			features = append(features, feature)
+		}
+	}
+	result := strings.Join(append([]string{f.Platform}, features...), "-") + flags
+	if len(f.Architecture) > 0 {
+		result += "-" + f.Architecture
+	}
+	return result
+}
+
+// HasFeatures returns whether the flavor has all features.
+func (f *GardenLinuxFlavor) HasFeatures(features ...string) bool {
+	for _, feature := range features {
+		if i := sort.SearchStrings(f.Features, feature); i == len(f.Features) || f.Features[i] != feature {
+			return false
+		}
+	}
+	return true
+}
+
+// gardenLinuxFlavor returns the parsed flavor of a Garden Linux version or false if the version has no valid flavor.
+func gardenLinuxFlavor(image OsImage) (*GardenLinuxFlavor, bool) {
+	if image.Name != OsNameGardenLinux {
+		return nil, false
+	}
+	value, ok := image.Version[GardenLinuxFlavorField].(string)
+	if !ok {
+		return nil, false
+	}
+	flavor, err := ParseGardenLinuxFlavor(value)
+	if err != nil {
+		return nil, false
+	}
+	return flavor, true
+}
+
+// canonicalFlavor returns the image with the canonical form of its Garden Linux flavor. The version is copied if the
+// flavor is changed.
+func canonicalFlavor(image OsImage) OsImage {
+	flavor, ok := gardenLinuxFlavor(image)
+	if !ok || flavor.String() == image.Version[GardenLinuxFlavorField] {
+		return image
+	}
+	version := MachineImageVersion{}
+	for key, value := range image.Version {
+		version[key] = value
+	}
+	version[GardenLinuxFlavorField] = flavor.String()
+	return OsImage{Name: image.Name, Version: version}
+}
+
+// normalizeFlavors replaces the Garden Linux flavors of all images by their canonical form. The versions are modified
+// in place, so it must only be applied to the computed result.
+func normalizeFlavors(images []MachineImage) {
+	for _, image := range images {
+		for _, v := range image.Versions {
+			if flavor, ok := gardenLinuxFlavor(OsImage{Name: image.Name, Version: v}); ok {
+				v[GardenLinuxFlavorField] = flavor.String()
+			}
+		}
+	}
+}
+
+// gardenLinuxFeaturesFilter matches the Garden Linux versions whose flavor has all features. Versions without valid
+// flavor do not match.
+type gardenLinuxFeaturesFilter struct {
+	features []string
+}
+
+func newGardenLinuxFeaturesFilter(parameters string) (OsImageFilter, error) {
+	if len(parameters) == 0 {
+		return nil, fmt.Errorf("filter has no features")
+	}
+	features := strings.Split(parameters, "+")
+	for _, feature := range features {
+		if len(strings.TrimPrefix(feature, "_")) == 0 {
+			return nil, fmt.Errorf("filter has an empty feature")
+		}
+	}
+	return &gardenLinuxFeaturesFilter{features: features}, nil
+}
+
+func (g *gardenLinuxFeaturesFilter) Match(image OsImage) (bool, error) {
+	flavor, ok := gardenLinuxFlavor(image)
+	return ok && flavor.HasFeatures(g.features...), nil
+}
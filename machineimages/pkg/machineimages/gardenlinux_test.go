@@ -0,0 +1,112 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("garden linux flavors", func() {
+
+	gardenlinux := func(version, flavor string) OsImage {
+		return OsImage{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": version, GardenLinuxFlavorField: flavor}}
+	}
+
+	Context("ParseGardenLinuxFlavor", func() {
+		It("should parse the platform, features, flags and architecture", func() {
+			flavor, err := ParseGardenLinuxFlavor("aws-gardener_prod_usi-amd64")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(flavor).To(Equal(&GardenLinuxFlavor{Platform: "aws", Features: []string{"_prod", "_usi", "gardener"}, Architecture: "amd64"}))
+			Expect(flavor.HasFeatures("gardener", "_usi")).To(BeTrue())
+			Expect(flavor.HasFeatures("_trustedboot")).To(BeFalse())
+
+			flavor, err = ParseGardenLinuxFlavor("metal_pxe-khost-_trustedboot")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(flavor).To(Equal(&GardenLinuxFlavor{Platform: "metal", Features: []string{"_pxe", "_trustedboot", "khost"}}))
+		})
+
+		It("should format the canonical form independent of the order of the features", func() {
+			for _, value := range []string{"aws-gardener_usi_prod-amd64", "aws-gardener_prod-_usi-amd64", "aws_usi-gardener_prod_prod-amd64"} {
+				flavor, err := ParseGardenLinuxFlavor(value)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(flavor.String()).To(Equal("aws-gardener_prod_usi-amd64"), value)
+			}
+		})
+
+		It("should reject invalid flavors", func() {
+			for _, value := range []string{"", "_usi-amd64", "aws--amd64", "aws-gardener__usi", "aws-gardener_"} {
+				_, err := ParseGardenLinuxFlavor(value)
+				Expect(err).To(MatchError(ContainSubstring("invalid garden linux flavor")), value)
+			}
+		})
+	})
+
+	Context("filters", func() {
+		images := []OsImage{
+			gardenlinux("934.7.0", "aws-gardener_prod_usi_trustedboot-amd64"),
+			gardenlinux("934.7.0", "aws-gardener_prod_usi-amd64"),
+			gardenlinux("934.7.0", "aws-gardener_prod-amd64"),
+			{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": "934.6.0"}},
+			{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "22.4.0", GardenLinuxFlavorField: "aws-gardener_usi-amd64"}},
+		}
+
+		It("should include and exclude versions by their features", func() {
+			result, err := filterOsImages(context.Background(), images,
+				[]OsImagesFilterKind{"gardenlinux-features:_usi"}, []OsImagesFilterKind{"gardenlinux-features:_trustedboot+gardener"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal([]OsImage{images[1]}))
+		})
+
+		It("should validate the features of the filter kinds", func() {
+			err := ValidateImports(&Imports{IncludeFilters: []OsImagesFilterKind{"gardenlinux-features:", "gardenlinux-features:_usi+"}})
+			Expect(err).To(MatchError(ContainSubstring(`includeFilters: invalid filter "gardenlinux-features:": filter has no features`)))
+			Expect(err).To(MatchError(ContainSubstring(`includeFilters: invalid filter "gardenlinux-features:_usi+": filter has an empty feature`)))
+			Expect(RegisterOsImagesFilter(OsImagesFilterTypeGardenLinuxFeatures, func(OsImage) (bool, error) { return true, nil })).
+				To(MatchError("filter gardenlinux-features is a built-in filter"))
+		})
+	})
+
+	Context("duplicates", func() {
+		It("should only treat the same flavors as duplicates", func() {
+			images := []OsImage{
+				gardenlinux("934.7.0", "aws-gardener_prod_usi-amd64"),
+				gardenlinux("934.7.0", "aws-gardener_usi_prod-amd64"),
+				gardenlinux("934.7.0", "aws-gardener_prod-amd64"),
+			}
+			Expect(removeDuplicates(images)).To(Equal([]OsImage{images[0], images[2]}))
+		})
+
+		It("should validate the flavors of a version as distinct versions", func() {
+			images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.7.0", GardenLinuxFlavorField: "aws-gardener_prod_usi-amd64"},
+				{"version": "934.7.0", GardenLinuxFlavorField: "aws-gardener_prod-amd64"},
+				{"version": "934.7.0", GardenLinuxFlavorField: "aws-gardener_usi_prod-amd64"},
+			}}}
+			err := ValidateMachineImagesStrict(images, nil, nil, nil)
+			Expect(err).To(MatchError(ContainSubstring("machineImages[0].versions[2].version: duplicate version 934.7.0, first at machineImages[0].versions[0]")))
+			Expect(err.(*ValidationError).Problems).To(HaveLen(1))
+
+			importsErr := ValidateImports(&Imports{MachineImages: images,
+				ComputeMachineImagesOptions: ComputeMachineImagesOptions{StrictVersionFields: true}})
+			Expect(importsErr).To(Equal(err))
+
+			distinct := []MachineImage{{Name: OsNameGardenLinux, Versions: images[0].Versions[:2]}}
+			Expect(ValidateImports(&Imports{MachineImages: distinct, MachineImagesProvider: distinct})).To(Succeed())
+		})
+
+		It("should normalize the flavors of the result", func() {
+			images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.7.0", GardenLinuxFlavorField: "aws-gardener_usi_prod-amd64"},
+				{"version": "934.6.0", GardenLinuxFlavorField: "invalid--flavor"},
+			}}}
+			normalizeFlavors(images)
+			Expect(images[0].Versions[0][GardenLinuxFlavorField]).To(Equal("aws-gardener_prod_usi-amd64"))
+			Expect(images[0].Versions[1][GardenLinuxFlavorField]).To(Equal("invalid--flavor"))
+		})
+	})
+})
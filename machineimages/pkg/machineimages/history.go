@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"time"
+)
+
+// DefaultHistoryConfigMapKey is the data key of the revision history in a ConfigMap.
+const DefaultHistoryConfigMapKey = "history"
+
+// DefaultHistoryRevisions is the number of revisions which a history keeps by default. Every revision contains the
+// complete catalog, so the limit keeps histories in ConfigMaps below the size limit of kubernetes objects.
+const DefaultHistoryRevisions = 20
+
+// Revision is a catalog as it was computed at a time.
+type Revision struct {
+	// Number counts the revisions of the history, starting with 1.
+	Number int `json:"number"`
+	// Time is the time of the computation which created the revision.
+	Time time.Time `json:"time"`
+	// MachineImages are the computed machine images.
+	MachineImages []MachineImage `json:"machineImages"`
+}
+
+// History is the revision history of a catalog, which answers how the catalog looked at a time in the past, e.g. in
+// incident retrospectives. Only the newest revisions are kept, so older questions cannot be answered.
+type History struct {
+	// Revisions are ordered from the oldest to the newest revision.
+	Revisions []Revision `json:"revisions"`
+}
+
+// VersionSpan is a period in which a version was part of the catalog.
+type VersionSpan struct {
+	// Added is the time of the first revision which contains the version. If AddedBeforeHistory is set, the version
+	// was added at or before this time.
+	Added time.Time `json:"added"`
+	// AddedBeforeHistory is set if the version is part of the oldest kept revision.
+	AddedBeforeHistory bool `json:"addedBeforeHistory,omitempty"`
+	// Removed is the time of the first revision which does not contain the version anymore. It is nil if the version
+	// is part of the newest revision.
+	Removed *time.Time `json:"removed,omitempty"`
+}
+
+// Record adds the machine images as a new revision, unless they equal the newest revision, and drops the oldest
+// revisions beyond maxRevisions. A non-positive maxRevisions keeps DefaultHistoryRevisions. It returns whether the
+// history changed.
+func (h *History) Record(images []MachineImage, now time.Time, maxRevisions int) bool {
+	changed, _ := h.RecordWithOptions(images, now, maxRevisions, nil)
+	return changed
+}
+
+// RecordWithOptions is Record which also adds no revision if the machine images only differ cosmetically from the
+// newest revision, see DiffOptions.EqualMachineImages. The options may be nil.
+func (h *History) RecordWithOptions(images []MachineImage, now time.Time, maxRevisions int, options *DiffOptions) (bool, error) {
+	if images == nil {
+		images = []MachineImage{}
+	}
+	if maxRevisions <= 0 {
+		maxRevisions = DefaultHistoryRevisions
+	}
+	if n := len(h.Revisions); n > 0 {
+		equal, err := options.EqualMachineImages(h.Revisions[n-1].MachineImages, images)
+		if err != nil || equal {
+			return false, err
+		}
+	}
+
+	number := 1
+	if n := len(h.Revisions); n > 0 {
+		number = h.Revisions[n-1].Number + 1
+	}
+	h.Revisions = append(h.Revisions, Revision{Number: number, Time: now.UTC(), MachineImages: images})
+	if len(h.Revisions) > maxRevisions {
+		h.Revisions = append([]Revision{}, h.Revisions[len(h.Revisions)-maxRevisions:]...)
+	}
+	return true, nil
+}
+
+// At returns the revision which was the newest at the time, or nil if the time is before the oldest kept revision.
+func (h *History) At(t time.Time) *Revision {
+	var result *Revision
+	for i := range h.Revisions {
+		if h.Revisions[i].Time.After(t) {
+			break
+		}
+		result = &h.Revisions[i]
+	}
+	return result
+}
+
+// Timeline returns the periods in which the version of the image was part of the catalog, from the oldest to the
+// newest period. Versions which were removed and added again have several periods.
+func (h *History) Timeline(image, version string) []VersionSpan {
+	spans := []VersionSpan{}
+	var current *VersionSpan
+	for i, revision := range h.Revisions {
+		contained := containsVersion(revision.MachineImages, image, version)
+		switch {
+		case contained && current == nil:
+			current = &VersionSpan{Added: revision.Time, AddedBeforeHistory: i == 0}
+		case !contained && current != nil:
+			removed := revision.Time
+			current.Removed = &removed
+			spans = append(spans, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		spans = append(spans, *current)
+	}
+	return spans
+}
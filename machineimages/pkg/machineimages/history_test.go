@@ -0,0 +1,82 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("history", func() {
+
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	catalog := func(versions ...string) []MachineImage {
+		image := MachineImage{Name: OsNameGardenLinux}
+		for _, version := range versions {
+			image.Versions = append(image.Versions, MachineImageVersion{"version": version})
+		}
+		return []MachineImage{image}
+	}
+
+	var history *History
+
+	BeforeEach(func() {
+		history = &History{}
+		Expect(history.Record(catalog("1.0.0"), day(1), 0)).To(BeTrue())
+		Expect(history.Record(catalog("1.0.0"), day(2), 0)).To(BeFalse())
+		Expect(history.Record(catalog("1.0.0", "1.1.0"), day(3), 0)).To(BeTrue())
+		Expect(history.Record(catalog("1.1.0"), day(5), 0)).To(BeTrue())
+		Expect(history.Record(catalog("1.0.0", "1.1.0"), day(8), 0)).To(BeTrue())
+	})
+
+	It("should only record changed catalogs", func() {
+		numbers := []int{}
+		for _, revision := range history.Revisions {
+			numbers = append(numbers, revision.Number)
+		}
+		Expect(numbers).To(Equal([]int{1, 2, 3, 4}))
+	})
+
+	It("should return the catalog at a time", func() {
+		Expect(history.At(day(1).Add(-time.Second))).To(BeNil())
+		Expect(history.At(day(2)).MachineImages).To(Equal(catalog("1.0.0")))
+		Expect(history.At(day(3)).Number).To(Equal(2))
+		Expect(history.At(day(6)).MachineImages).To(Equal(catalog("1.1.0")))
+		Expect(history.At(day(30)).Number).To(Equal(4))
+	})
+
+	It("should return when a version was added and removed", func() {
+		removed := day(5)
+		Expect(history.Timeline(OsNameGardenLinux, "1.0.0")).To(Equal([]VersionSpan{
+			{Added: day(1), AddedBeforeHistory: true, Removed: &removed},
+			{Added: day(8)},
+		}))
+		Expect(history.Timeline(OsNameGardenLinux, "1.1.0")).To(Equal([]VersionSpan{{Added: day(3)}}))
+		Expect(history.Timeline(OsNameUbuntu, "1.0.0")).To(BeEmpty())
+	})
+
+	It("should not record catalogs which only differ cosmetically", func() {
+		annotated := catalog("1.0.0", "1.1.0")
+		annotated[0].Versions[1]["annotations"] = map[string]interface{}{"built": "monday"}
+
+		options := &DiffOptions{IgnoreFields: []string{"annotations"}}
+		Expect(history.RecordWithOptions(annotated, day(9), 0, options)).To(BeFalse())
+		Expect(history.RecordWithOptions(annotated, day(9), 0, nil)).To(BeTrue())
+		Expect(history.Revisions).To(HaveLen(5))
+	})
+
+	It("should keep the newest revisions", func() {
+		removed := day(9)
+		Expect(history.Record(catalog("2.0.0"), day(9), 2)).To(BeTrue())
+		Expect(history.Revisions).To(HaveLen(2))
+		Expect(history.Revisions[0].Number).To(Equal(4))
+		Expect(history.Revisions[1].Number).To(Equal(5))
+		Expect(history.Timeline(OsNameGardenLinux, "1.0.0")).To(Equal([]VersionSpan{
+			{Added: day(8), AddedBeforeHistory: true, Removed: &removed},
+		}))
+	})
+})
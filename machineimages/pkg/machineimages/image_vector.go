@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+// ImageVector lists the OCI artifacts of the machine images in the image vector format of gardener, e.g. for image
+// vector overwrites of components which pull machine images.
+type ImageVector struct {
+	Images []ImageVectorEntry `json:"images"`
+}
+
+// ImageVectorEntry is the OCI artifact of a version. The name is "<image>-<version>", e.g. "gardenlinux-934.1.0", and
+// the tag is the tag or the digest of the artifact reference.
+type ImageVectorEntry struct {
+	Name          string   `json:"name"`
+	Repository    string   `json:"repository"`
+	Tag           string   `json:"tag"`
+	Architectures []string `json:"architectures,omitempty"`
+}
+
+// NewImageVector returns the image vector of all versions with a valid artifact reference, see
+// DefaultArtifactReferenceField. Every version which an alias is resolved to is followed by a copy with the alias
+// instead of the version in the name, e.g. "gardenlinux-latest". All other versions are omitted.
+func NewImageVector(images []MachineImage, aliases VersionAliases) *ImageVector {
+	vector := &ImageVector{Images: []ImageVectorEntry{}}
+	for _, image := range images {
+		for _, version := range image.Versions {
+			value, _ := version[DefaultArtifactReferenceField].(string)
+			reference, err := ParseArtifactReference(value)
+			if err != nil {
+				continue
+			}
+			entry := ImageVectorEntry{
+				Repository:    reference.Registry + "/" + reference.Repository,
+				Tag:           reference.Reference,
+				Architectures: version.getArchitectures(),
+			}
+			v := versionOrEmpty(version)
+			for _, name := range append([]string{v}, aliases.aliasesOf(image.Name, v)...) {
+				entry.Name = image.Name + "-" + name
+				vector.Images = append(vector.Images, entry)
+			}
+		}
+	}
+	return vector
+}
@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("image vector", func() {
+
+	It("should list the artifacts of the versions by tag or digest, with the aliases", func() {
+		vector := NewImageVector([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.8.0", DefaultArtifactReferenceField: "ghcr.io/gardenlinux/gardenlinux:934.8",
+					"architectures": []interface{}{"amd64", "arm64"}},
+				{"version": "576.12.0", DefaultArtifactReferenceField: "ghcr.io/gardenlinux/gardenlinux@sha256:576"},
+				{"version": "318.9.0", DefaultArtifactReferenceField: "gardenlinux:318.9"},
+				{"version": "184.0.0", "image": "gl-184"},
+			}},
+		}, VersionAliases{OsNameGardenLinux: {"latest": "934.8.0", "lts": "934.8.0"}})
+		Expect(vector.Images).To(Equal([]ImageVectorEntry{
+			{Name: "gardenlinux-934.8.0", Repository: "ghcr.io/gardenlinux/gardenlinux", Tag: "934.8", Architectures: []string{"amd64", "arm64"}},
+			{Name: "gardenlinux-latest", Repository: "ghcr.io/gardenlinux/gardenlinux", Tag: "934.8", Architectures: []string{"amd64", "arm64"}},
+			{Name: "gardenlinux-lts", Repository: "ghcr.io/gardenlinux/gardenlinux", Tag: "934.8", Architectures: []string{"amd64", "arm64"}},
+			{Name: "gardenlinux-576.12.0", Repository: "ghcr.io/gardenlinux/gardenlinux", Tag: "sha256:576"},
+		}))
+	})
+})
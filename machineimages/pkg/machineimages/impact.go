@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ImpactScore quantifies a change of the machine images, so that plans can be compared and large changes get more
+// attention.
+type ImpactScore struct {
+	AddedVersions   int `json:"addedVersions"`
+	RemovedVersions int `json:"removedVersions"`
+	ChangedVersions int `json:"changedVersions"`
+	// AffectedShoots is the number of shoots which use a removed or changed version.
+	AffectedShoots int `json:"affectedShoots"`
+	// Providers are the provider types, see DefaultProviderFields, whose machine images differ, in lexical order. A
+	// version only touches the providers whose fields it has.
+	Providers []string `json:"providers"`
+}
+
+// ScoreImpact scores the change from the old to the new machine images of the diff. The shoots are optional.
+func ScoreImpact(diff *MachineImagesDiff, oldImages, newImages []MachineImage, shoots []ShootMachineImage) *ImpactScore {
+	score := &ImpactScore{
+		AddedVersions:   len(diff.Added),
+		RemovedVersions: len(diff.Removed),
+		ChangedVersions: len(diff.Changed),
+		Providers:       []string{},
+	}
+
+	affected := map[VersionRef]bool{}
+	for _, ref := range append(append([]VersionRef{}, diff.Removed...), diff.Changed...) {
+		affected[ref] = true
+	}
+	for _, shoot := range shoots {
+		if affected[VersionRef{Image: shoot.Image, Version: shoot.Version}] {
+			score.AffectedShoots++
+		}
+	}
+
+	oldVersions := indexVersions(oldImages)
+	newVersions := indexVersions(newImages)
+	refs := append(append(append([]VersionRef{}, diff.Added...), diff.Removed...), diff.Changed...)
+	for provider, fields := range DefaultProviderFields {
+		for _, ref := range refs {
+			if touchesProvider(oldVersions[ref], newVersions[ref], fields) {
+				score.Providers = append(score.Providers, provider)
+				break
+			}
+		}
+	}
+	sort.Strings(score.Providers)
+	return score
+}
+
+// touchesProvider returns whether one of the versions has fields of the provider and the fields which the provider
+// understands differ. A missing version is nil.
+func touchesProvider(oldVersion, newVersion MachineImageVersion, fields ProviderFields) bool {
+	hasFields := false
+	for _, field := range fields.Version {
+		_, inOld := oldVersion[field]
+		_, inNew := newVersion[field]
+		hasFields = hasFields || inOld || inNew
+	}
+	if !hasFields {
+		return false
+	}
+	if oldVersion == nil || newVersion == nil {
+		return true
+	}
+	return !reflect.DeepEqual(partitionVersion(oldVersion, fields), partitionVersion(newVersion, fields))
+}
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("impact score", func() {
+
+	oldImages := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.7.0", "image": "gl-934-7"},
+			{"version": "934.8.0", "regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}},
+			{"version": "934.9.0"},
+		}},
+		{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "20.4.0", "path": "templates/ubuntu"}}},
+	}
+
+	It("should count the versions, affected shoots and touched providers", func() {
+		newImages := []MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.8.0", "regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-2"}}},
+				{"version": "934.9.0", "classification": "deprecated"},
+				{"version": "934.10.0"},
+			}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "20.4.0", "path": "templates/ubuntu"}}},
+		}
+		shoots := []ShootMachineImage{
+			{Shoot: "a/one", Image: OsNameGardenLinux, Version: "934.7.0"},
+			{Shoot: "a/two", Image: OsNameGardenLinux, Version: "934.9.0"},
+			{Shoot: "b/one", Image: OsNameUbuntu, Version: "20.4.0"},
+		}
+
+		score := ScoreImpact(DiffMachineImages(oldImages, newImages), oldImages, newImages, shoots)
+		Expect(score).To(Equal(&ImpactScore{
+			AddedVersions:   1,
+			RemovedVersions: 1,
+			ChangedVersions: 2,
+			AffectedShoots:  2,
+			// 934.7.0 has the image of gcp and openstack, the ami of 934.8.0 is only understood by aws
+			Providers: []string{"aws", "gcp", "openstack"},
+		}))
+	})
+
+	It("should score a change without differences as zero", func() {
+		score := ScoreImpact(DiffMachineImages(oldImages, oldImages), oldImages, oldImages, nil)
+		Expect(score).To(Equal(&ImpactScore{Providers: []string{}}))
+	})
+
+	It("should be part of approval requests", func() {
+		request, err := NewApprovalRequest("dev", oldImages, oldImages[1:], []ShootMachineImage{
+			{Shoot: "a/one", Image: OsNameGardenLinux, Version: "934.7.0"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request.Score).To(Equal(&ImpactScore{
+			RemovedVersions: 3,
+			AffectedShoots:  1,
+			Providers:       []string{"alicloud", "aws", "gcp", "openstack"},
+		}))
+	})
+})
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Incident implicates a machine image version in an incident.
+type Incident struct {
+	Image   string `json:"image"`
+	Version string `json:"version"`
+	// Description is a human readable description or a link to the incident.
+	Description string `json:"description,omitempty"`
+}
+
+// IncidentSource provides the currently open incidents, e.g. from an incident management system.
+type IncidentSource interface {
+	Incidents(ctx context.Context) ([]Incident, error)
+}
+
+// WebhookIncidentSource fetches incidents from an http endpoint which returns a json list of incidents.
+type WebhookIncidentSource struct {
+	URL string
+	// Token is sent as bearer token, if set.
+	Token string
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+}
+
+// Incidents fetches the incidents from the webhook.
+func (s *WebhookIncidentSource) Incidents(ctx context.Context) ([]Incident, error) {
+	incidents := []Incident{}
+	if err := fetchAuthorizedJSON(ctx, s.Client, "fetch incidents", s.URL, s.Token, &incidents); err != nil {
+		return nil, err
+	}
+	// an incident without image or version would never match and silently block nothing
+	for i, incident := range incidents {
+		if len(incident.Image) == 0 || len(incident.Version) == 0 {
+			return nil, fmt.Errorf("incident %d of %s must have an image and a version", i, s.URL)
+		}
+	}
+	return incidents, nil
+}
+
+// ConfigMapIncidentSource reads incidents from the data of a ConfigMap. The keys have the format "<image>_<version>",
+// e.g. "gardenlinux_934.0.0", and the values describe the incident.
+type ConfigMapIncidentSource struct {
+	ConfigMap *ConfigMap
+}
+
+// Incidents returns one incident per data key of the ConfigMap.
+func (s *ConfigMapIncidentSource) Incidents(_ context.Context) ([]Incident, error) {
+	if s.ConfigMap == nil {
+		return nil, errors.New("the incident source has no config map")
+	}
+	keys := make([]string, 0, len(s.ConfigMap.Data))
+	for key := range s.ConfigMap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	incidents := []Incident{}
+	for _, key := range keys {
+		parts := strings.SplitN(key, "_", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return nil, fmt.Errorf("invalid incident key %q of config map %s/%s, expected <image>_<version>",
+				key, s.ConfigMap.Metadata.Namespace, s.ConfigMap.Metadata.Name)
+		}
+		incidents = append(incidents, Incident{Image: parts[0], Version: parts[1], Description: s.ConfigMap.Data[key]})
+	}
+	return incidents, nil
+}
+
+// IncidentGate is a promotion gate which blocks versions implicated in incidents.
+type IncidentGate struct {
+	Source IncidentSource
+}
+
+// CheckPromotion blocks the entry if it is implicated in an incident.
+func (g *IncidentGate) CheckPromotion(ctx context.Context, _ Stage, entry CatalogEntry) (string, error) {
+	incidents, err := g.Source.Incidents(ctx)
+	if err != nil {
+		return "", err
+	}
+	if incident := findIncident(incidents, entry.Name, versionOrEmpty(entry.Version)); incident != nil {
+		return "implicated in incident: " + incident.Description, nil
+	}
+	return "", nil
+}
+
+// applyIncidents deprecates all versions of the images which are implicated in an incident. The versions are modified
+// in place, so it must only be applied to the computed result which does not share versions with the input.
+func applyIncidents(ctx context.Context, images []MachineImage, source IncidentSource) ([]MachineImage, error) {
+	if source == nil {
+		return images, nil
+	}
+
+	incidents, err := source.Incidents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, reporter := FromContext(ctx)
+	for _, image := range images {
+		for _, v := range image.Versions {
+			version := versionOrEmpty(v)
+			incident := findIncident(incidents, image.Name, version)
+			if incident == nil || v.hasClassification(ClassificationDeprecated) {
+				continue
+			}
+			v.SetClassification(ClassificationDeprecated)
+			reporter.Report(ReportEntry{
+				Image:   image.Name,
+				Version: version,
+				Reason:  ReasonIncident,
+				Message: "deprecated because of incident: " + incident.Description,
+			})
+		}
+	}
+	return images, nil
+}
+
+func findIncident(incidents []Incident, image, version string) *Incident {
+	for i := range incidents {
+		if incidents[i].Image == image && incidents[i].Version == version {
+			return &incidents[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("incident", func() {
+
+	configMapSource := &ConfigMapIncidentSource{ConfigMap: &ConfigMap{
+		Metadata: ObjectMeta{Name: "incidents", Namespace: "garden"},
+		Data:     map[string]string{"gardenlinux_934.0.0": "kernel panic on boot"},
+	}}
+
+	Context("ConfigMapIncidentSource", func() {
+
+		It("should parse the keys", func() {
+			incidents, err := configMapSource.Incidents(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(incidents).To(Equal([]Incident{
+				{Image: OsNameGardenLinux, Version: "934.0.0", Description: "kernel panic on boot"},
+			}))
+		})
+
+		It("should reject invalid keys", func() {
+			source := &ConfigMapIncidentSource{ConfigMap: &ConfigMap{
+				Metadata: ObjectMeta{Name: "incidents", Namespace: "garden"},
+				Data:     map[string]string{"gardenlinux_934.0.0": "", "gardenlinux_": ""},
+			}}
+			_, err := source.Incidents(context.Background())
+			Expect(err).To(MatchError(`invalid incident key "gardenlinux_" of config map garden/incidents, expected <image>_<version>`))
+
+			_, err = (&ConfigMapIncidentSource{}).Incidents(context.Background())
+			Expect(err).To(MatchError("the incident source has no config map"))
+		})
+	})
+
+	Context("WebhookIncidentSource", func() {
+
+		It("should fetch the incidents", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(json.NewEncoder(w).Encode([]Incident{{Image: OsNameUbuntu, Version: "1.0.0"}})).To(Succeed())
+			}))
+			defer server.Close()
+
+			incidents, err := (&WebhookIncidentSource{URL: server.URL}).Incidents(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(incidents).To(Equal([]Incident{{Image: OsNameUbuntu, Version: "1.0.0"}}))
+		})
+
+		It("should reject incidents without version", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(json.NewEncoder(w).Encode([]Incident{{Image: OsNameUbuntu, Version: "1.0.0"}, {Image: OsNameUbuntu}})).To(Succeed())
+			}))
+			defer server.Close()
+
+			_, err := (&WebhookIncidentSource{URL: server.URL}).Incidents(context.Background())
+			Expect(err).To(MatchError("incident 1 of " + server.URL + " must have an image and a version"))
+		})
+
+		It("should respect the network policy guard", func() {
+			_, err := (&WebhookIncidentSource{URL: "http://localhost"}).Incidents(WithNetworkPolicyGuard(context.Background()))
+			Expect(IsNetworkAccessDenied(err)).To(BeTrue())
+		})
+	})
+
+	It("should block promotions of implicated versions", func() {
+		gate := &IncidentGate{Source: configMapSource}
+		entry := CatalogEntry{OsImage: OsImage{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": "934.0.0"}}}
+		reason, err := gate.CheckPromotion(context.Background(), Stage{Name: "canary"}, entry)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(Equal("implicated in incident: kernel panic on boot"))
+	})
+
+	It("should deprecate implicated versions in the result", func() {
+		report := NewReport()
+		result, err := ComputeMachineImagesWithOptions(
+			context.Background(),
+			logr.Discard(),
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "318.9.0", "classification": "supported"},
+				{"version": "934.0.0", "classification": "supported"},
+			}}},
+			nil,
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "318.9.0", "image": "a"},
+				{"version": "934.0.0", "image": "b"},
+			}}},
+			nil, nil, nil, nil,
+			&ComputeMachineImagesOptions{Incidents: configMapSource, Reporter: report},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result[0].Versions).To(Equal([]MachineImageVersion{
+			{"version": "934.0.0", "classification": "deprecated", "image": "b"},
+			{"version": "318.9.0", "classification": "supported", "image": "a"},
+		}))
+		Expect(report.Entries()).To(ConsistOf(ReportEntry{
+			Image:   OsNameGardenLinux,
+			Version: "934.0.0",
+			Reason:  ReasonIncident,
+			Message: "deprecated because of incident: kernel panic on boot",
+		}))
+	})
+})
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// DefaultLandscapesParallelism is the number of landscapes which RunLandscapes processes concurrently by default.
+const DefaultLandscapesParallelism = 4
+
+// LandscapeSource loads the imports of a landscape, e.g. from the configuration directory of the landscape in a
+// monorepo.
+type LandscapeSource struct {
+	// Name of the landscape.
+	Name string
+	// Load reads the imports of the landscape. It is called concurrently for different landscapes.
+	Load func(ctx context.Context) (*Imports, error)
+}
+
+// LandscapesOptions configures RunLandscapes.
+type LandscapesOptions struct {
+	// Parallelism is the number of landscapes which are processed concurrently. Defaults to
+	// DefaultLandscapesParallelism.
+	Parallelism int
+	// ValidateOnly only validates the imports of the landscapes, see ValidateImports, instead of computing them.
+	ValidateOnly bool
+	// SelectionResolver resolves the selections of the imports before they are validated, SecretResolver their secrets
+	// before they are computed, like the engine does.
+	SelectionResolver *SelectionResolver
+	SecretResolver    *SecretResolver
+	// Previous returns the exports of the previous run of a landscape, or nil if there are none. Exports whose machine
+	// images only differ cosmetically from them are replaced, see KeepCosmeticExports. It is called concurrently for
+	// different landscapes.
+	Previous func(ctx context.Context, name string) (*Exports, error)
+	// Computed is called with the exports of every computed landscape, e.g. to write them. It is called concurrently
+	// for different landscapes, an error fails the landscape.
+	Computed func(ctx context.Context, name string, exports *Exports) error
+}
+
+// LandscapeResult is the outcome of a landscape of RunLandscapes.
+type LandscapeResult struct {
+	Name string `json:"name"`
+	// Images and Versions are the number of computed images and versions.
+	Images   int `json:"images,omitempty"`
+	Versions int `json:"versions,omitempty"`
+	// Warnings is the number of findings of the computation, see ResultWarnings.
+	Warnings int `json:"warnings,omitempty"`
+	// Error is the error of a failed landscape and ErrorClass its class, if it has one.
+	Error      string     `json:"error,omitempty"`
+	ErrorClass ErrorClass `json:"errorClass,omitempty"`
+}
+
+// LandscapesReport aggregates the results of all landscapes of RunLandscapes.
+type LandscapesReport struct {
+	// Landscapes are the results in the order of the sources.
+	Landscapes []LandscapeResult `json:"landscapes"`
+	// Failed is the number of failed landscapes.
+	Failed int `json:"failed"`
+}
+
+// RunLandscapes loads, resolves and validates or computes the landscapes concurrently and returns the report of all
+// landscapes. A failed landscape does not stop the others. Landscapes which are not started when the context is done
+// fail with the error of the context.
+func RunLandscapes(ctx context.Context, log logr.Logger, sources []LandscapeSource, options *LandscapesOptions) *LandscapesReport {
+	if options == nil {
+		options = &LandscapesOptions{}
+	}
+	parallelism := options.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultLandscapesParallelism
+	}
+
+	var (
+		mutex   sync.Mutex
+		wg      sync.WaitGroup
+		results = make([]LandscapeResult, len(sources))
+		started = 0
+	)
+
+	// next returns the index of the next landscape or -1 if all landscapes are started
+	next := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if started == len(sources) {
+			return -1
+		}
+		started++
+		return started - 1
+	}
+
+	for i := 0; i < parallelism && i < len(sources); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := next(); index >= 0; index = next() {
+				source := sources[index]
+				result := LandscapeResult{Name: source.Name}
+				if err := runLandscape(ctx, log.WithValues("landscape", source.Name), source, options, &result); err != nil {
+					result.Error = err.Error()
+					result.ErrorClass = ClassOf(err)
+				}
+				results[index] = result
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := &LandscapesReport{Landscapes: results}
+	for _, result := range results {
+		if len(result.Error) > 0 {
+			report.Failed++
+		}
+	}
+	return report
+}
+
+func runLandscape(ctx context.Context, log logr.Logger, source LandscapeSource, options *LandscapesOptions, result *LandscapeResult) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	imports, err := source.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if err := imports.ResolveSelection(ctx, options.SelectionResolver); err != nil {
+		return ClassifyError(err, ErrorClassFetch)
+	}
+	if err := ValidateImports(imports); err != nil {
+		return err
+	}
+	if options.ValidateOnly {
+		return nil
+	}
+	if err := imports.ResolveSecrets(ctx, options.SecretResolver); err != nil {
+		return ClassifyError(err, ErrorClassFetch)
+	}
+
+	log.Info("Computing machine images of landscape")
+	exports, err := ComputeExports(ctx, log, imports)
+	if err != nil {
+		return err
+	}
+	if options.Previous != nil {
+		previous, err := options.Previous(ctx, source.Name)
+		if err != nil {
+			return ClassifyError(err, ErrorClassFetch)
+		}
+		if exports, err = KeepCosmeticExports(imports, previous, exports); err != nil {
+			return err
+		}
+	}
+	images, err := exports.machineImages()
+	if err != nil {
+		return err
+	}
+	result.Images = len(images)
+	for _, image := range images {
+		result.Versions += len(image.Versions)
+	}
+	result.Warnings = len(exports.ResultWarnings)
+
+	if options.Computed != nil {
+		return ClassifyError(options.Computed(ctx, source.Name, exports), ErrorClassApply)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("landscapes", func() {
+
+	landscape := func(name string, imports *Imports, err error) LandscapeSource {
+		return LandscapeSource{Name: name, Load: func(ctx context.Context) (*Imports, error) {
+			return imports, err
+		}}
+	}
+	valid := func() *Imports {
+		return &Imports{
+			MachineImages: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.7.0"}, {"version": "934.6.0"},
+			}}},
+			MachineImagesProvider: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.7.0", "image": "a"},
+			}}},
+		}
+	}
+	invalid := &Imports{MachineImages: []MachineImage{{Versions: []MachineImageVersion{{"version": "934.7.0"}}}}}
+
+	It("should compute all landscapes and report the failed ones", func() {
+		var (
+			mutex    sync.Mutex
+			computed = []string{}
+		)
+		sources := []LandscapeSource{
+			landscape("dev", valid(), nil),
+			landscape("canary", invalid, nil),
+			landscape("live", nil, errors.New("unable to read imports")),
+			landscape("staging", valid(), nil),
+		}
+		report := RunLandscapes(context.Background(), logr.Discard(), sources, &LandscapesOptions{
+			Parallelism: 2,
+			Computed: func(ctx context.Context, name string, exports *Exports) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				computed = append(computed, name)
+				return nil
+			},
+		})
+
+		Expect(report.Failed).To(Equal(2))
+		Expect(report.Landscapes).To(HaveLen(4))
+		Expect(report.Landscapes[0]).To(Equal(LandscapeResult{Name: "dev", Images: 1, Versions: 1, Warnings: 1}))
+		Expect(report.Landscapes[1].Name).To(Equal("canary"))
+		Expect(report.Landscapes[1].ErrorClass).To(Equal(ErrorClassValidation))
+		Expect(report.Landscapes[2]).To(Equal(LandscapeResult{Name: "live", Error: "unable to read imports"}))
+		Expect(report.Landscapes[3].Name).To(Equal("staging"))
+		Expect(computed).To(ConsistOf("dev", "staging"))
+	})
+
+	It("should keep the previous exports of landscapes which only changed cosmetically", func() {
+		imports := valid()
+		imports.Diff = &DiffOptions{IgnoreFields: []string{"annotations"}}
+		var previous, written *Exports
+		options := &LandscapesOptions{
+			Previous: func(ctx context.Context, name string) (*Exports, error) {
+				return previous, nil
+			},
+			Computed: func(ctx context.Context, name string, exports *Exports) error {
+				written = exports
+				return nil
+			},
+		}
+		Expect(RunLandscapes(context.Background(), logr.Discard(), []LandscapeSource{landscape("dev", imports, nil)}, options).Failed).To(BeZero())
+
+		previous = &Exports{ResultMachineImages: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"annotations": "old"},
+		}}}}
+		for field, value := range written.ResultMachineImages[0].Versions[0] {
+			previous.ResultMachineImages[0].Versions[0][field] = value
+		}
+		Expect(RunLandscapes(context.Background(), logr.Discard(), []LandscapeSource{landscape("dev", imports, nil)}, options).Failed).To(BeZero())
+		Expect(written.ResultMachineImages).To(Equal(previous.ResultMachineImages))
+	})
+
+	It("should only validate the landscapes and fail the landscapes after the context is done", func() {
+		report := RunLandscapes(context.Background(), logr.Discard(), []LandscapeSource{
+			landscape("dev", valid(), nil),
+			landscape("canary", invalid, nil),
+		}, &LandscapesOptions{ValidateOnly: true})
+		Expect(report.Failed).To(Equal(1))
+		Expect(report.Landscapes[0]).To(Equal(LandscapeResult{Name: "dev"}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		report = RunLandscapes(ctx, logr.Discard(), []LandscapeSource{landscape("dev", valid(), nil)}, nil)
+		Expect(report.Landscapes).To(Equal([]LandscapeResult{{Name: "dev", Error: context.Canceled.Error()}}))
+	})
+
+	It("should resolve the selections of the landscapes before they are validated", func() {
+		selected := valid()
+		selected.SelectionFrom = []SelectionSource{{File: "selection.yaml"}}
+		missing := valid()
+		missing.SelectionFrom = []SelectionSource{{File: "missing.yaml"}}
+		resolver := &SelectionResolver{ReadFile: func(path string) ([]byte, error) {
+			if path != "selection.yaml" {
+				return nil, errors.New("not found")
+			}
+			return []byte("disableMachineImages: [gardenlinux:934.7.0]"), nil
+		}}
+		report := RunLandscapes(context.Background(), logr.Discard(), []LandscapeSource{
+			landscape("dev", selected, nil),
+			landscape("canary", missing, nil),
+		}, &LandscapesOptions{SelectionResolver: resolver})
+		Expect(report.Failed).To(Equal(1))
+		Expect(report.Landscapes[0]).To(Equal(LandscapeResult{Name: "dev", Warnings: 2}))
+		Expect(report.Landscapes[1].ErrorClass).To(Equal(ErrorClassFetch))
+	})
+})
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// applyLatestPerMinor keeps the newest n versions of every minor line of each image. Versions are grouped and ordered
+// by their semver, e.g. 934.1 and 934.1.3 are both in the line 934.1. Versions with the same semver, e.g. 934.1 and
+// 934.1.0, are ordered by their string, so that the result does not depend on the order of the input. Versions which
+// are no semver form their own line. Versions keep their order within an image.
+func applyLatestPerMinor(ctx context.Context, images []MachineImage, n int) ([]MachineImage, error) {
+	if n <= 0 {
+		return images, nil
+	}
+
+	_, reporter := FromContext(ctx)
+	result := make([]MachineImage, 0, len(images))
+	for _, image := range images {
+		type entry struct {
+			index   int
+			version string
+			parsed  *Version
+		}
+		lines := map[string][]entry{}
+		for i, v := range image.Versions {
+			version := versionOrEmpty(v)
+			parsed, err := ParseVersion(version)
+			line := version
+			if err == nil {
+				line = fmt.Sprintf("%d.%d", parsed.Major, parsed.Minor)
+			}
+			lines[line] = append(lines[line], entry{index: i, version: version, parsed: parsed})
+		}
+
+		keep := map[int]bool{}
+		for _, entries := range lines {
+			sort.Slice(entries, func(i, j int) bool {
+				a, b := entries[i], entries[j]
+				if a.parsed != nil && b.parsed != nil {
+					if c := a.parsed.Compare(b.parsed); c != 0 {
+						return c > 0
+					}
+				}
+				return a.version > b.version
+			})
+			for i, e := range entries {
+				if i < n {
+					keep[e.index] = true
+				}
+			}
+		}
+
+		kept := make([]MachineImageVersion, 0, len(image.Versions))
+		for i, v := range image.Versions {
+			if keep[i] {
+				kept = append(kept, v)
+				continue
+			}
+			reporter.Report(ReportEntry{
+				Image:   image.Name,
+				Version: versionOrEmpty(v),
+				Reason:  ReasonLatestPerMinor,
+				Message: fmt.Sprintf("version is not one of the newest %d versions of its minor line", n),
+			})
+		}
+		if len(kept) > 0 {
+			result = append(result, MachineImage{Name: image.Name, Versions: kept})
+		}
+	}
+	return result, nil
+}
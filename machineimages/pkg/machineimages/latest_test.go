@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("latest per minor", func() {
+
+	newImages := func() []MachineImage {
+		return []MachineImage{
+			{
+				Name: OsNameGardenLinux,
+				Versions: []MachineImageVersion{
+					{"version": "934.3.0"},
+					{"version": "934.2.0"},
+					{"version": "934.1.2"},
+					{"version": "934.1.1"},
+					{"version": "934.1.0"},
+					{"version": "318.9.1"},
+					{"version": "318.9.0"},
+				},
+			},
+			{
+				Name: OsNameUbuntu,
+				Versions: []MachineImageVersion{
+					{"version": "22.4.20230101"},
+					{"version": "22.4.20221201"},
+				},
+			},
+		}
+	}
+
+	versionsOf := func(image MachineImage) []string {
+		result := []string{}
+		for _, v := range image.Versions {
+			result = append(result, *v.getVersion())
+		}
+		return result
+	}
+
+	It("should not change images without limit", func() {
+		result, err := applyLatestPerMinor(context.Background(), newImages(), 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(newImages()))
+	})
+
+	It("should keep the newest versions of every minor line of each image", func() {
+		report := NewReport()
+		ctx := NewContext(context.Background(), logr.Discard(), report)
+		result, err := applyLatestPerMinor(ctx, newImages(), 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(HaveLen(2))
+		Expect(versionsOf(result[0])).To(Equal([]string{"934.3.0", "934.2.0", "934.1.2", "318.9.1"}))
+		Expect(versionsOf(result[1])).To(Equal([]string{"22.4.20230101"}))
+		Expect(report.Entries()).To(ConsistOf(
+			ReportEntry{Image: OsNameGardenLinux, Version: "934.1.1", Reason: ReasonLatestPerMinor, Message: "version is not one of the newest 1 versions of its minor line"},
+			ReportEntry{Image: OsNameGardenLinux, Version: "934.1.0", Reason: ReasonLatestPerMinor, Message: "version is not one of the newest 1 versions of its minor line"},
+			ReportEntry{Image: OsNameGardenLinux, Version: "318.9.0", Reason: ReasonLatestPerMinor, Message: "version is not one of the newest 1 versions of its minor line"},
+			ReportEntry{Image: OsNameUbuntu, Version: "22.4.20221201", Reason: ReasonLatestPerMinor, Message: "version is not one of the newest 1 versions of its minor line"},
+		))
+	})
+
+	It("should keep several versions per minor line", func() {
+		result, err := applyLatestPerMinor(context.Background(), newImages(), 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versionsOf(result[0])).To(Equal([]string{"934.3.0", "934.2.0", "934.1.2", "934.1.1", "318.9.1", "318.9.0"}))
+	})
+
+	It("should break ties independent of the input order", func() {
+		images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.1"}, {"version": "934.1.0"},
+		}}}
+		reversed := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.1.0"}, {"version": "934.1"},
+		}}}
+
+		result, err := applyLatestPerMinor(context.Background(), images, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versionsOf(result[0])).To(Equal([]string{"934.1.0"}))
+		result, err = applyLatestPerMinor(context.Background(), reversed, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versionsOf(result[0])).To(Equal([]string{"934.1.0"}))
+	})
+
+	It("should keep versions which are no semver in their own line", func() {
+		images := []MachineImage{{Name: "custom", Versions: []MachineImageVersion{
+			{"version": "1.0.0"}, {"version": "rolling"}, {"version": "1.0.1"},
+		}}}
+		result, err := applyLatestPerMinor(context.Background(), images, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(versionsOf(result[0])).To(Equal([]string{"rolling", "1.0.1"}))
+	})
+})
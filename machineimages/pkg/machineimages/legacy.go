@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// LegacyLandscape is the part of a garden-setup (sow) acre.yaml which configures machine images.
+type LegacyLandscape struct {
+	Landscape struct {
+		IaaS []LegacyIaaS `json:"iaas"`
+	} `json:"landscape"`
+}
+
+// LegacyIaaS is an infrastructure of a garden-setup landscape.
+type LegacyIaaS struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// MachineImage is the single machine image of older garden-setup versions.
+	MachineImage *LegacyMachineImage `json:"machineImage,omitempty"`
+	// MachineImages are machine images in the format of the CloudProfile.
+	MachineImages []MachineImage `json:"machineImages,omitempty"`
+	// CloudProfile overrides parts of the generated CloudProfile.
+	CloudProfile *LegacyCloudProfile `json:"cloudprofile,omitempty"`
+}
+
+// LegacyMachineImage references a single version of a machine image.
+type LegacyMachineImage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// LegacyCloudProfile is the CloudProfile override of an infrastructure.
+type LegacyCloudProfile struct {
+	Spec struct {
+		MachineImages  []MachineImage `json:"machineImages,omitempty"`
+		ProviderConfig struct {
+			MachineImages []MachineImage `json:"machineImages,omitempty"`
+		} `json:"providerConfig,omitempty"`
+	} `json:"spec,omitempty"`
+}
+
+// LegacyConversion is the result of the conversion of a garden-setup landscape.
+type LegacyConversion struct {
+	// Catalog contains the machine images of all infrastructures.
+	Catalog *CatalogFile
+	// ProviderCatalogs contain the provider specific versions, e.g. the region mappings, by infrastructure type.
+	ProviderCatalogs map[string]*CatalogFile
+	// Warnings describe the parts of the landscape which could not be converted.
+	Warnings []string
+}
+
+// ConvertLegacyLandscape converts the machine images of a garden-setup acre.yaml into catalog files. Spiff expressions
+// can not be evaluated, versions which contain them are skipped with a warning.
+func ConvertLegacyLandscape(data []byte) (*LegacyConversion, error) {
+	landscape := &LegacyLandscape{}
+	if err := yaml.Unmarshal(data, landscape); err != nil {
+		return nil, fmt.Errorf("unable to parse landscape: %w", err)
+	}
+
+	conversion := &LegacyConversion{
+		Catalog:          newCatalogFile(),
+		ProviderCatalogs: map[string]*CatalogFile{},
+	}
+
+	for _, iaas := range landscape.Landscape.IaaS {
+		images := []MachineImage{}
+		if iaas.MachineImage != nil {
+			images = append(images, MachineImage{
+				Name:     iaas.MachineImage.Name,
+				Versions: []MachineImageVersion{{"version": iaas.MachineImage.Version}},
+			})
+		}
+		images = append(images, iaas.MachineImages...)
+
+		var providerImages []MachineImage
+		if iaas.CloudProfile != nil {
+			images = append(images, iaas.CloudProfile.Spec.MachineImages...)
+			providerImages = iaas.CloudProfile.Spec.ProviderConfig.MachineImages
+		}
+
+		conversion.merge(conversion.Catalog, iaas.Name, images)
+
+		if len(providerImages) > 0 {
+			provider, ok := conversion.ProviderCatalogs[iaas.Type]
+			if !ok {
+				provider = newCatalogFile()
+				conversion.ProviderCatalogs[iaas.Type] = provider
+			}
+			conversion.merge(provider, iaas.Name, providerImages)
+		}
+	}
+
+	return conversion, nil
+}
+
+// ProviderTypes returns the infrastructure types of the provider catalogs in lexical order.
+func (c *LegacyConversion) ProviderTypes() []string {
+	types := []string{}
+	for t := range c.ProviderCatalogs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// merge adds the versions to the catalog file. Versions which are already contained are skipped; a warning is added if
+// their content differs.
+func (c *LegacyConversion) merge(file *CatalogFile, iaas string, images []MachineImage) {
+	for _, image := range images {
+		i := 0
+		for ; i < len(file.MachineImages); i++ {
+			if file.MachineImages[i].Name == image.Name {
+				break
+			}
+		}
+
+		for _, version := range image.Versions {
+			v := versionOrEmpty(version)
+			if len(image.Name) == 0 || len(v) == 0 || isSpiffExpression(image.Name) || isSpiffExpression(v) {
+				c.Warnings = append(c.Warnings, fmt.Sprintf("iaas %s: skipping version %q of image %q", iaas, v, image.Name))
+				continue
+			}
+
+			if i == len(file.MachineImages) {
+				file.MachineImages = append(file.MachineImages, MachineImage{Name: image.Name})
+			}
+			if existing := findVersion(image.Name, v, file.MachineImages); existing != nil {
+				if !reflect.DeepEqual(existing, version) && !mergeRegions(existing, version) {
+					c.Warnings = append(c.Warnings, fmt.Sprintf("iaas %s: version %s of image %s differs from a previous infrastructure, keeping the first", iaas, v, image.Name))
+				}
+				continue
+			}
+			file.MachineImages[i].Versions = append(file.MachineImages[i].Versions, version)
+		}
+	}
+}
+
+// mergeRegions adds the regions of the version to the existing version if the versions only differ in their regions,
+// as infrastructures of the same type in different regions configure different region mappings.
+func mergeRegions(existing, version MachineImageVersion) bool {
+	existingRegions, ok := existing["regions"].([]interface{})
+	if !ok {
+		return false
+	}
+	regions, ok := version["regions"].([]interface{})
+	if !ok {
+		return false
+	}
+
+	withoutRegions := func(v MachineImageVersion) MachineImageVersion {
+		result := MachineImageVersion{}
+		for key, value := range v {
+			if key != "regions" {
+				result[key] = value
+			}
+		}
+		return result
+	}
+	if !reflect.DeepEqual(withoutRegions(existing), withoutRegions(version)) {
+		return false
+	}
+
+	for _, region := range regions {
+		found := false
+		for _, existingRegion := range existingRegions {
+			found = found || reflect.DeepEqual(region, existingRegion)
+		}
+		if !found {
+			existingRegions = append(existingRegions, region)
+		}
+	}
+	existing["regions"] = existingRegions
+	return true
+}
+
+func isSpiffExpression(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "((")
+}
+
+func newCatalogFile() *CatalogFile {
+	return &CatalogFile{
+		APIVersion:    CatalogAPIVersion,
+		Kind:          CatalogKind,
+		MachineImages: []MachineImage{},
+	}
+}
@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("legacy landscape", func() {
+
+	acre := `
+landscape:
+  name: dev
+  iaas:
+    - name: aws-eu
+      type: aws
+      region: eu-west-1
+      machineImage:
+        name: coreos
+        version: 2303.3.0
+      cloudprofile:
+        spec:
+          machineImages:
+            - name: gardenlinux
+              versions:
+                - version: 318.8.0
+                  classification: supported
+          providerConfig:
+            machineImages:
+              - name: gardenlinux
+                versions:
+                  - version: 318.8.0
+                    regions:
+                      - name: eu-west-1
+                        ami: ami-1
+    - name: aws-us
+      type: aws
+      machineImages:
+        - name: gardenlinux
+          versions:
+            - version: 318.8.0
+              classification: preview
+            - version: (( .versions.gardenlinux ))
+      cloudprofile:
+        spec:
+          providerConfig:
+            machineImages:
+              - name: gardenlinux
+                versions:
+                  - version: 318.8.0
+                    regions:
+                      - name: us-east-1
+                        ami: ami-2
+    - name: gcp
+      type: gcp
+`
+
+	It("should convert the machine images of all infrastructures", func() {
+		conversion, err := ConvertLegacyLandscape([]byte(acre))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(conversion.Catalog.APIVersion).To(Equal(CatalogAPIVersion))
+		Expect(conversion.Catalog.MachineImages).To(Equal([]MachineImage{
+			{Name: OsNameCoreos, Versions: []MachineImageVersion{{"version": "2303.3.0"}}},
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.8.0", "classification": "supported"}}},
+		}))
+
+		Expect(conversion.ProviderTypes()).To(Equal([]string{"aws"}))
+		Expect(conversion.ProviderCatalogs["aws"].MachineImages).To(HaveLen(1))
+		Expect(conversion.ProviderCatalogs["aws"].MachineImages[0].Versions).To(Equal([]MachineImageVersion{{
+			"version": "318.8.0",
+			"regions": []interface{}{
+				map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"},
+				map[string]interface{}{"name": "us-east-1", "ami": "ami-2"},
+			},
+		}}))
+	})
+
+	It("should warn about conflicting versions and spiff expressions", func() {
+		conversion, err := ConvertLegacyLandscape([]byte(acre))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(conversion.Warnings).To(ConsistOf(
+			ContainSubstring("iaas aws-us: version 318.8.0 of image gardenlinux differs"),
+			ContainSubstring(`iaas aws-us: skipping version "(( .versions.gardenlinux ))"`),
+		))
+	})
+
+	It("should yield empty catalogs for landscapes without machine images", func() {
+		conversion, err := ConvertLegacyLandscape([]byte("landscape:\n  name: dev\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(conversion.Catalog.MachineImages).To(BeEmpty())
+		Expect(conversion.ProviderCatalogs).To(BeEmpty())
+	})
+})
@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// IPFamily restricts servers and clients to an ip family.
+type IPFamily string
+
+const (
+	// IPFamilyAny listens and dials on ipv4 and ipv6, dual-stack if available.
+	IPFamilyAny = IPFamily("")
+	// IPFamilyIPv4 only listens and dials on ipv4.
+	IPFamilyIPv4 = IPFamily("ipv4")
+	// IPFamilyIPv6 only listens and dials on ipv6.
+	IPFamilyIPv6 = IPFamily("ipv6")
+)
+
+// network returns the tcp network of the ip family.
+func (f IPFamily) network() (string, error) {
+	switch f {
+	case IPFamilyAny:
+		return "tcp", nil
+	case IPFamilyIPv4:
+		return "tcp4", nil
+	case IPFamilyIPv6:
+		return "tcp6", nil
+	default:
+		return "", fmt.Errorf("unknown ip family %q, expected %s or %s", f, IPFamilyIPv4, IPFamilyIPv6)
+	}
+}
+
+// Listen listens on the tcp address, e.g. ":8080", "0.0.0.0:8080" or "[::]:8080", in the ip family. With IPFamilyAny,
+// an address without host listens dual-stack, and on ipv6 only clusters on ipv6.
+func Listen(address string, family IPFamily) (net.Listener, error) {
+	network, err := family.network()
+	if err != nil {
+		return nil, err
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil && family != IPFamilyAny {
+		if ip := net.ParseIP(host); ip != nil && (ip.To4() != nil) != (family == IPFamilyIPv4) {
+			return nil, fmt.Errorf("address %s is not an %s address", address, family)
+		}
+	}
+	return net.Listen(network, address)
+}
+
+// ListenLoopback listens on a random port of the ipv4 loopback address, or of the ipv6 loopback address if there is
+// no ipv4 loopback address, e.g. in ipv6 only pods.
+func ListenLoopback() (net.Listener, error) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err == nil {
+		return listener, nil
+	}
+	listener, err6 := net.Listen("tcp6", "[::1]:0")
+	if err6 != nil {
+		return nil, fmt.Errorf("unable to listen on a loopback address: %v, %w", err, err6)
+	}
+	return listener, nil
+}
+
+// newDialContext returns the dial function of a transport which only dials in the ip family and resolves host names
+// with the resolver. The resolver may be nil to use the default resolver.
+func newDialContext(family IPFamily, resolver *net.Resolver) (func(ctx context.Context, network, address string) (net.Conn, error), error) {
+	familyNetwork, err := family.network()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Resolver: resolver}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		switch {
+		case network == "tcp":
+			network = familyNetwork
+		case family != IPFamilyAny && network != familyNetwork:
+			return nil, fmt.Errorf("unable to dial %s %s, only %s is allowed", network, address, family)
+		}
+		return dialer.DialContext(ctx, network, address)
+	}, nil
+}
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("listen", func() {
+
+	It("should listen in the ip family", func() {
+		listener, err := Listen("[::1]:0", IPFamilyIPv6)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(listener.Addr().(*net.TCPAddr).IP.Equal(net.IPv6loopback)).To(BeTrue())
+		Expect(listener.Close()).To(Succeed())
+
+		listener, err = Listen("127.0.0.1:0", IPFamilyIPv4)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(listener.Close()).To(Succeed())
+
+		_, err = Listen("127.0.0.1:0", IPFamilyIPv6)
+		Expect(err).To(MatchError("address 127.0.0.1:0 is not an ipv6 address"))
+		_, err = Listen("[::1]:0", IPFamilyIPv4)
+		Expect(err).To(MatchError("address [::1]:0 is not an ipv4 address"))
+		_, err = Listen(":0", IPFamily("ipv5"))
+		Expect(err).To(MatchError(ContainSubstring("unknown ip family")))
+	})
+
+	It("should listen on a loopback address", func() {
+		listener, err := ListenLoopback()
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+		Expect(listener.Addr().(*net.TCPAddr).IP.IsLoopback()).To(BeTrue())
+	})
+
+	It("should only dial in the ip family of the transport", func() {
+		listener, err := Listen("[::1]:0", IPFamilyIPv6)
+		Expect(err).NotTo(HaveOccurred())
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Listener.Close()
+		server.Listener = listener
+		server.Start()
+		defer server.Close()
+
+		transport, err := NewTransport(&TransportOptions{IPFamily: IPFamilyIPv6})
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Body.Close()).To(Succeed())
+
+		transport, err = NewTransport(&TransportOptions{IPFamily: IPFamilyIPv4})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = (&http.Client{Transport: transport}).Get(server.URL)
+		Expect(err).To(HaveOccurred())
+
+		_, err = NewTransport(&TransportOptions{IPFamily: IPFamily("ipv5")})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject networks of the other ip family", func() {
+		dial, err := newDialContext(IPFamilyIPv6, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = dial(context.Background(), "tcp4", "127.0.0.1:80")
+		Expect(err).To(MatchError("unable to dial tcp4 127.0.0.1:80, only ipv6 is allowed"))
+	})
+})
@@ -0,0 +1,114 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package loadgen generates synthetic catalogs and measures the throughput and latency of computations over them.
+package loadgen
+
+import (
+	"fmt"
+	"math/rand"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+var classifications = []string{"supported", "supported", "preview", "deprecated"}
+
+// GeneratorOptions configures the size and the churn of generated catalogs.
+type GeneratorOptions struct {
+	// Images is the number of machine images.
+	Images int
+	// VersionsPerImage is the number of versions of every image.
+	VersionsPerImage int
+	// Regions is the number of provider regions of every version.
+	Regions int
+	// Churn is the fraction of versions which is replaced by newer versions in every generation.
+	Churn float64
+	// Seed makes the generated catalogs reproducible.
+	Seed int64
+}
+
+// Generator generates a sequence of imports whose catalogs change with the configured churn.
+type Generator struct {
+	options GeneratorOptions
+	rand    *rand.Rand
+	// next is the next patch version of every image
+	next   []int
+	images [][]int
+}
+
+// NewGenerator returns a generator of catalogs of the configured size.
+func NewGenerator(options GeneratorOptions) (*Generator, error) {
+	if options.Images <= 0 || options.VersionsPerImage <= 0 {
+		return nil, fmt.Errorf("images and versions per image must be positive")
+	}
+	if options.Churn < 0 || options.Churn > 1 {
+		return nil, fmt.Errorf("churn must be between 0 and 1")
+	}
+
+	g := &Generator{
+		options: options,
+		rand:    rand.New(rand.NewSource(options.Seed)),
+		next:    make([]int, options.Images),
+		images:  make([][]int, options.Images),
+	}
+	for i := range g.images {
+		for j := 0; j < options.VersionsPerImage; j++ {
+			g.images[i] = append(g.images[i], g.next[i])
+			g.next[i]++
+		}
+	}
+	return g, nil
+}
+
+// Next applies the churn to the catalog and returns its imports. The first call returns the initial catalog.
+func (g *Generator) Next() *mi.Imports {
+	imports := g.imports()
+
+	replaced := int(g.options.Churn * float64(g.options.Images*g.options.VersionsPerImage))
+	for n := 0; n < replaced; n++ {
+		i := g.rand.Intn(g.options.Images)
+		g.images[i] = append(g.images[i][1:], g.next[i])
+		g.next[i]++
+	}
+
+	return imports
+}
+
+func (g *Generator) imports() *mi.Imports {
+	imports := &mi.Imports{
+		MachineImages:         []mi.MachineImage{},
+		MachineImagesProvider: []mi.MachineImage{},
+	}
+
+	for i, patches := range g.images {
+		name := fmt.Sprintf("image-%d", i)
+		image := mi.MachineImage{Name: name}
+		provider := mi.MachineImage{Name: name}
+
+		for _, patch := range patches {
+			version := fmt.Sprintf("1.%d.%d", patch/100, patch%100)
+			image.Versions = append(image.Versions, mi.MachineImageVersion{
+				"version":        version,
+				"classification": classifications[patch%len(classifications)],
+			})
+
+			regions := []interface{}{}
+			for r := 0; r < g.options.Regions; r++ {
+				regions = append(regions, map[string]interface{}{
+					"name": fmt.Sprintf("region-%d", r),
+					"ami":  fmt.Sprintf("ami-%08x", g.rand.Uint32()),
+				})
+			}
+			provider.Versions = append(provider.Versions, mi.MachineImageVersion{
+				"version": version,
+				"regions": regions,
+			})
+		}
+
+		imports.MachineImages = append(imports.MachineImages, image)
+		imports.MachineImagesProvider = append(imports.MachineImagesProvider, provider)
+	}
+
+	return imports
+}
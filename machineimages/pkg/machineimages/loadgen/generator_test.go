@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loadgen
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("generator", func() {
+
+	options := GeneratorOptions{Images: 3, VersionsPerImage: 4, Regions: 2, Churn: 0.25, Seed: 1}
+
+	It("should generate catalogs of the configured size", func() {
+		generator, err := NewGenerator(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		imports := generator.Next()
+		Expect(imports.MachineImages).To(HaveLen(3))
+		Expect(imports.MachineImagesProvider).To(HaveLen(3))
+		for i := range imports.MachineImages {
+			Expect(imports.MachineImages[i].Versions).To(HaveLen(4))
+			Expect(imports.MachineImagesProvider[i].Versions[0]["regions"]).To(HaveLen(2))
+		}
+		Expect(imports.MachineImages[0].Versions[0]["version"]).To(Equal("1.0.0"))
+	})
+
+	It("should replace versions with the configured churn", func() {
+		generator, err := NewGenerator(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		first := generator.Next()
+		second := generator.Next()
+
+		changed := 0
+		for i := range first.MachineImages {
+			Expect(second.MachineImages[i].Versions).To(HaveLen(4))
+			for _, version := range second.MachineImages[i].Versions {
+				found := false
+				for _, old := range first.MachineImages[i].Versions {
+					found = found || old["version"] == version["version"]
+				}
+				if !found {
+					changed++
+				}
+			}
+		}
+		Expect(changed).To(Equal(3))
+	})
+
+	It("should generate the same catalogs for the same seed", func() {
+		a, err := NewGenerator(options)
+		Expect(err).NotTo(HaveOccurred())
+		b, err := NewGenerator(options)
+		Expect(err).NotTo(HaveOccurred())
+
+		for i := 0; i < 3; i++ {
+			Expect(a.Next()).To(Equal(b.Next()))
+		}
+	})
+
+	It("should reject invalid options", func() {
+		_, err := NewGenerator(GeneratorOptions{Images: 1, VersionsPerImage: 1, Churn: 2})
+		Expect(err).To(HaveOccurred())
+		_, err = NewGenerator(GeneratorOptions{})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,267 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/server"
+)
+
+// Target computes the machine images of imports. Its latency is measured.
+type Target interface {
+	Compute(ctx context.Context, imports *mi.Imports) error
+}
+
+// LibraryTarget computes the exports in process.
+type LibraryTarget struct {
+	Log logr.Logger
+}
+
+// Compute computes the exports of the imports.
+func (t *LibraryTarget) Compute(ctx context.Context, imports *mi.Imports) error {
+	_, err := mi.ComputeExports(ctx, t.Log, imports)
+	return err
+}
+
+// ServerTarget requests the computation from a server on a loopback address, so that the latency includes the
+// encoding and the http handling of the server.
+type ServerTarget struct {
+	url string
+	// client is a plain http client without the ca bundle and network policy settings of mi.NewHTTPClient, as the
+	// server is reached on loopback
+	client *http.Client
+	server *http.Server
+	// served is closed once the server stopped with the error serveErr
+	served   chan struct{}
+	serveErr error
+
+	mutex   sync.Mutex
+	imports *mi.Imports
+}
+
+// NewServerTarget starts a server on a random loopback port, on ipv6 if there is no ipv4 loopback address. It must be
+// closed after use.
+func NewServerTarget(log logr.Logger) (*ServerTarget, error) {
+	listener, err := mi.ListenLoopback()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &ServerTarget{
+		url:    "http://" + listener.Addr().String() + "/v1/compute",
+		client: &http.Client{},
+		served: make(chan struct{}),
+	}
+
+	t.server = &http.Server{Handler: server.New(log, t.loadImports, nil).Handler()}
+	go func() {
+		t.serveErr = t.server.Serve(listener)
+		close(t.served)
+	}()
+	return t, nil
+}
+
+// Compute requests the computation of the imports. With concurrent requests, the server computes the latest imports
+// passed to any request.
+func (t *ServerTarget) Compute(ctx context.Context, imports *mi.Imports) error {
+	t.mutex.Lock()
+	t.imports = imports
+	t.mutex.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close stops the server. It returns the error if the server failed before.
+func (t *ServerTarget) Close() error {
+	if err := t.server.Close(); err != nil {
+		return err
+	}
+	<-t.served
+	if !errors.Is(t.serveErr, http.ErrServerClosed) {
+		return fmt.Errorf("the server failed: %w", t.serveErr)
+	}
+	return nil
+}
+
+func (t *ServerTarget) loadImports() (*mi.Imports, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.imports == nil {
+		return nil, errors.New("no imports")
+	}
+	return t.imports, nil
+}
+
+// RunOptions configures a load test.
+type RunOptions struct {
+	// Iterations is the number of computations.
+	Iterations int
+	// Concurrency is the number of concurrent computations. Defaults to 1.
+	Concurrency int
+}
+
+// Result contains the throughput and the latency percentiles of a load test.
+type Result struct {
+	Iterations int `json:"iterations"`
+	Errors     int `json:"errors"`
+	// FirstError is the error of the first failed computation.
+	FirstError string        `json:"firstError,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	// Throughput is the number of computations per second.
+	Throughput float64       `json:"throughput"`
+	P50        time.Duration `json:"p50"`
+	P90        time.Duration `json:"p90"`
+	P99        time.Duration `json:"p99"`
+	Max        time.Duration `json:"max"`
+}
+
+// Run computes the next imports of the generator with the target for the configured number of iterations. A load test
+// is stopped early if the context is cancelled. Failed computations are counted, but if all computations fail, the
+// result is returned together with the first error.
+func Run(ctx context.Context, generator *Generator, target Target, options RunOptions) (*Result, error) {
+	if options.Iterations <= 0 {
+		return nil, errors.New("iterations must be positive")
+	}
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		mutex     sync.Mutex
+		wg        sync.WaitGroup
+		latencies = make([]time.Duration, 0, options.Iterations)
+		errs      = 0
+		firstErr  error
+		remaining = options.Iterations
+	)
+
+	// next returns the imports of the next iteration or nil if all iterations are started
+	next := func() *mi.Imports {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if remaining == 0 || ctx.Err() != nil {
+			return nil
+		}
+		remaining--
+		return generator.Next()
+	}
+
+	started := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for imports := next(); imports != nil; imports = next() {
+				start := time.Now()
+				err := target.Compute(ctx, imports)
+				latency := time.Since(start)
+
+				mutex.Lock()
+				latencies = append(latencies, latency)
+				if err != nil {
+					errs++
+					if firstErr == nil {
+						firstErr = err
+					}
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	duration := time.Since(started)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result := &Result{
+		Iterations: len(latencies),
+		Errors:     errs,
+		Duration:   duration,
+		P50:        percentile(latencies, 50),
+		P90:        percentile(latencies, 90),
+		P99:        percentile(latencies, 99),
+	}
+	if duration > 0 {
+		result.Throughput = float64(len(latencies)) / duration.Seconds()
+	}
+	if len(latencies) > 0 {
+		result.Max = latencies[len(latencies)-1]
+	}
+	if firstErr != nil {
+		result.FirstError = firstErr.Error()
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if errs > 0 && errs == len(latencies) {
+		return result, fmt.Errorf("all %d computations failed: %w", errs, firstErr)
+	}
+	return result, nil
+}
+
+// percentile returns the nearest rank percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// WriteTable writes the result as a table.
+func (r *Result) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	rows := [][2]string{
+		{"ITERATIONS", fmt.Sprint(r.Iterations)},
+		{"ERRORS", fmt.Sprint(r.Errors)},
+		{"DURATION", r.Duration.String()},
+		{"THROUGHPUT", fmt.Sprintf("%.2f/s", r.Throughput)},
+		{"P50", r.P50.String()},
+		{"P90", r.P90.String()},
+		{"P99", r.P99.String()},
+		{"MAX", r.Max.String()},
+	}
+	if len(r.FirstError) > 0 {
+		rows = append(rows, [2]string{"FIRST ERROR", r.FirstError})
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(tw, row[0]+"\t"+row[1]); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,107 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type countingTarget struct {
+	mutex sync.Mutex
+	calls int
+	fail  int
+}
+
+func (t *countingTarget) Compute(_ context.Context, _ *mi.Imports) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.calls++
+	if t.calls <= t.fail {
+		return errors.New("failed")
+	}
+	return nil
+}
+
+var _ = Describe("runner", func() {
+
+	var generator *Generator
+
+	BeforeEach(func() {
+		var err error
+		generator, err = NewGenerator(GeneratorOptions{Images: 2, VersionsPerImage: 3, Regions: 1, Churn: 0.5})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should run the configured number of iterations concurrently", func() {
+		target := &countingTarget{fail: 2}
+		result, err := Run(context.Background(), generator, target, RunOptions{Iterations: 25, Concurrency: 4})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(target.calls).To(Equal(25))
+		Expect(result.Iterations).To(Equal(25))
+		Expect(result.Errors).To(Equal(2))
+		Expect(result.FirstError).To(Equal("failed"))
+		Expect(result.P50).To(BeNumerically("<=", result.P99))
+		Expect(result.P99).To(BeNumerically("<=", result.Max))
+
+		buf := &bytes.Buffer{}
+		Expect(result.WriteTable(buf)).To(Succeed())
+		Expect(buf.String()).To(MatchRegexp(`ITERATIONS +25\n`))
+		Expect(buf.String()).To(ContainSubstring("FIRST ERROR  failed"))
+	})
+
+	It("should fail if all computations fail", func() {
+		result, err := Run(context.Background(), generator, &countingTarget{fail: 3}, RunOptions{Iterations: 3})
+		Expect(err).To(MatchError("all 3 computations failed: failed"))
+		Expect(result.Errors).To(Equal(3))
+	})
+
+	It("should compute with the library", func() {
+		result, err := Run(context.Background(), generator, &LibraryTarget{Log: logr.Discard()}, RunOptions{Iterations: 3})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Errors).To(Equal(0))
+	})
+
+	It("should compute with the server", func() {
+		target, err := NewServerTarget(logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		defer target.Close()
+
+		result, err := Run(context.Background(), generator, target, RunOptions{Iterations: 3, Concurrency: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Errors).To(Equal(0))
+		Expect(target.Close()).To(Succeed())
+	})
+
+	It("should stop if the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := Run(ctx, generator, &countingTarget{}, RunOptions{Iterations: 10})
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(result.Iterations).To(Equal(0))
+	})
+
+	It("should compute nearest rank percentiles", func() {
+		latencies := []time.Duration{}
+		for i := 1; i <= 10; i++ {
+			latencies = append(latencies, time.Duration(i))
+		}
+		Expect(percentile(latencies, 50)).To(Equal(time.Duration(5)))
+		Expect(percentile(latencies, 90)).To(Equal(time.Duration(9)))
+		Expect(percentile(latencies, 99)).To(Equal(time.Duration(10)))
+		Expect(percentile(nil, 50)).To(Equal(time.Duration(0)))
+	})
+})
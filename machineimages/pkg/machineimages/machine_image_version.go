@@ -0,0 +1,180 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import "time"
+
+// Version returns the version number of the version or "" if it has none.
+func (v MachineImageVersion) Version() string {
+	return versionOrEmpty(v)
+}
+
+// Classification returns the classification of the version or "" if it has none.
+func (v MachineImageVersion) Classification() string {
+	if classification := v.getClassification(); classification != nil {
+		return *classification
+	}
+	return ""
+}
+
+// SetVersion sets the version number of the version, e.g. the normalized form of the version number.
+func (v MachineImageVersion) SetVersion(version string) {
+	v["version"] = version
+}
+
+// SetClassification sets the classification of the version, e.g. ClassificationDeprecated.
+func (v MachineImageVersion) SetClassification(classification string) {
+	v["classification"] = classification
+}
+
+// SetExpirationDate sets the expiration date of the version in the format of Gardener cloud profiles, in UTC.
+func (v MachineImageVersion) SetExpirationDate(expirationDate time.Time) {
+	v["expirationDate"] = expirationDate.UTC().Format(expirationDateFormat)
+}
+
+// Architectures returns the architectures of the version, or nil for architecture-agnostic versions.
+func (v MachineImageVersion) Architectures() []string {
+	return v.getArchitectures()
+}
+
+// SetArchitectures sets the architectures of the version.
+func (v MachineImageVersion) SetArchitectures(architectures ...string) {
+	list := make([]interface{}, 0, len(architectures))
+	for _, architecture := range architectures {
+		list = append(list, architecture)
+	}
+	v["architectures"] = list
+}
+
+// ProviderConfig returns a copy of the version number and the provider specific fields of the version, i.e. the
+// fields which are not CoreVersionFields, like the versions of the machine images of a provider config.
+func (v MachineImageVersion) ProviderConfig() MachineImageVersion {
+	config := MachineImageVersion{}
+	for key, value := range v {
+		if key == "version" || !contains(CoreVersionFields, key) {
+			config[key] = deepCopyValue(value)
+		}
+	}
+	return config
+}
+
+// DeepCopy returns a copy of the version which shares no maps and lists with the version, so that either can be
+// modified without affecting the other.
+func (v MachineImageVersion) DeepCopy() MachineImageVersion {
+	if v == nil {
+		return nil
+	}
+	result := make(MachineImageVersion, len(v))
+	for key, value := range v {
+		result[key] = deepCopyValue(value)
+	}
+	return result
+}
+
+// DeepCopy returns a copy of the image with deep copies of its versions.
+func (i MachineImage) DeepCopy() MachineImage {
+	result := MachineImage{Name: i.Name}
+	if i.Versions != nil {
+		result.Versions = make([]MachineImageVersion, 0, len(i.Versions))
+		for _, v := range i.Versions {
+			result.Versions = append(result.Versions, v.DeepCopy())
+		}
+	}
+	return result
+}
+
+// deepCopyValue copies the maps and lists of a value of a version, e.g. decoded from json or yaml. Other values are
+// immutable or not copied.
+func deepCopyValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case MachineImageVersion:
+		return typed.DeepCopy()
+	case map[string]interface{}:
+		if typed == nil {
+			return typed
+		}
+		result := make(map[string]interface{}, len(typed))
+		for key, entry := range typed {
+			result[key] = deepCopyValue(entry)
+		}
+		return result
+	case []interface{}:
+		if typed == nil {
+			return typed
+		}
+		result := make([]interface{}, len(typed))
+		for i, entry := range typed {
+			result[i] = deepCopyValue(entry)
+		}
+		return result
+	case []map[string]interface{}:
+		if typed == nil {
+			return typed
+		}
+		result := make([]map[string]interface{}, len(typed))
+		for i, entry := range typed {
+			result[i], _ = deepCopyValue(entry).(map[string]interface{})
+		}
+		return result
+	case []string:
+		if typed == nil {
+			return typed
+		}
+		return append([]string{}, typed...)
+	default:
+		return value
+	}
+}
+
+// MachineImageVersionBuilder builds a version field by field, e.g. for fixtures or sources which do not decode image
+// lists.
+type MachineImageVersionBuilder struct {
+	version MachineImageVersion
+}
+
+// NewMachineImageVersion returns a builder of a version with the version number.
+func NewMachineImageVersion(version string) *MachineImageVersionBuilder {
+	return &MachineImageVersionBuilder{version: MachineImageVersion{"version": version}}
+}
+
+// Classification sets the classification of the version.
+func (b *MachineImageVersionBuilder) Classification(classification string) *MachineImageVersionBuilder {
+	b.version.SetClassification(classification)
+	return b
+}
+
+// ExpirationDate sets the expiration date of the version.
+func (b *MachineImageVersionBuilder) ExpirationDate(expirationDate time.Time) *MachineImageVersionBuilder {
+	b.version.SetExpirationDate(expirationDate)
+	return b
+}
+
+// Architectures sets the architectures of the version.
+func (b *MachineImageVersionBuilder) Architectures(architectures ...string) *MachineImageVersionBuilder {
+	b.version.SetArchitectures(architectures...)
+	return b
+}
+
+// ProviderConfig sets the provider specific fields of the config, see MachineImageVersion.ProviderConfig. The version
+// number of the config is ignored.
+func (b *MachineImageVersionBuilder) ProviderConfig(config MachineImageVersion) *MachineImageVersionBuilder {
+	for key, value := range config.ProviderConfig() {
+		if key != "version" {
+			b.version[key] = value
+		}
+	}
+	return b
+}
+
+// Field sets a field of the version, e.g. a field which has no setter.
+func (b *MachineImageVersionBuilder) Field(key string, value interface{}) *MachineImageVersionBuilder {
+	b.version[key] = deepCopyValue(value)
+	return b
+}
+
+// Build returns the version. The builder can be reused, later changes do not affect built versions.
+func (b *MachineImageVersionBuilder) Build() MachineImageVersion {
+	return b.version.DeepCopy()
+}
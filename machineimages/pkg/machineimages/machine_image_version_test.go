@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("machine image versions", func() {
+
+	It("should build versions with the typed setters", func() {
+		builder := NewMachineImageVersion("934.7.0").
+			Classification(ClassificationSupported).
+			ExpirationDate(time.Date(2022, 1, 15, 23, 59, 59, 0, time.FixedZone("CET", 3600))).
+			Architectures(ArchitectureAMD64, ArchitectureARM64).
+			ProviderConfig(MachineImageVersion{"version": "1.0.0", "image": "a", "classification": ClassificationPreview}).
+			Field("flavor", "aws-gardener_prod-amd64")
+		v := builder.Build()
+		Expect(v).To(Equal(MachineImageVersion{
+			"version":        "934.7.0",
+			"classification": ClassificationSupported,
+			"expirationDate": "2022-01-15T22:59:59Z",
+			"architectures":  []interface{}{ArchitectureAMD64, ArchitectureARM64},
+			"image":          "a",
+			"flavor":         "aws-gardener_prod-amd64",
+		}))
+		Expect(v.Version()).To(Equal("934.7.0"))
+		Expect(v.Classification()).To(Equal(ClassificationSupported))
+		Expect(v.Architectures()).To(Equal([]string{ArchitectureAMD64, ArchitectureARM64}))
+		Expect(v.ExpirationDate()).To(Equal(ptrTime(time.Date(2022, 1, 15, 22, 59, 59, 0, time.UTC))))
+		Expect(v.ProviderConfig()).To(Equal(MachineImageVersion{"version": "934.7.0", "image": "a", "flavor": "aws-gardener_prod-amd64"}))
+
+		builder.Classification(ClassificationDeprecated)
+		Expect(v.Classification()).To(Equal(ClassificationSupported))
+		Expect(MachineImageVersion{}.Version()).To(BeEmpty())
+		Expect(MachineImageVersion{}.Classification()).To(BeEmpty())
+
+		v.SetVersion("934.8.0")
+		v.SetArchitectures(ArchitectureARM64)
+		Expect(v.Version()).To(Equal("934.8.0"))
+		Expect(v["architectures"]).To(Equal([]interface{}{ArchitectureARM64}))
+	})
+
+	It("should deep copy versions", func() {
+		v := MachineImageVersion{"version": "934.7.0", "architectures": []string{ArchitectureAMD64},
+			"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}}
+		image := MachineImage{Name: OsNameGardenLinux, Versions: []MachineImageVersion{v}}
+		copied := image.DeepCopy()
+		Expect(copied).To(Equal(image))
+
+		copied.Versions[0]["regions"].([]interface{})[0].(map[string]interface{})["ami"] = "ami-2"
+		copied.Versions[0]["architectures"].([]string)[0] = ArchitectureARM64
+		Expect(v["regions"]).To(Equal([]interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}))
+		Expect(v["architectures"]).To(Equal([]string{ArchitectureAMD64}))
+		Expect(MachineImageVersion(nil).DeepCopy()).To(BeNil())
+	})
+
+	It("should not share the values of the inputs with the result", func() {
+		imports := &Imports{
+			MachineImages: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.7.0", "cri": []interface{}{map[string]interface{}{"name": "containerd"}}},
+			}}},
+			MachineImagesProvider: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.7.0", "regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}},
+			}}},
+			ComputeMachineImagesOptions: ComputeMachineImagesOptions{Provider: "aws"},
+		}
+		original := []MachineImage{imports.MachineImages[0].DeepCopy(), imports.MachineImagesProvider[0].DeepCopy()}
+
+		result, err := ComputeMachineImagesFromImports(context.Background(), logr.Discard(), imports)
+		Expect(err).NotTo(HaveOccurred())
+		result[0].Versions[0]["regions"].([]interface{})[0].(map[string]interface{})["ami"] = "ami-2"
+		result[0].Versions[0]["cri"].([]interface{})[0].(map[string]interface{})["name"] = "docker"
+		result[0].Versions[0].SetClassification(ClassificationDeprecated)
+		Expect([]MachineImage{imports.MachineImages[0], imports.MachineImagesProvider[0]}).To(Equal(original))
+	})
+})
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -7,8 +7,8 @@ package machineimages
 import (
 	"context"
 	"fmt"
-	"reflect"
-	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 )
@@ -26,66 +26,504 @@ func ComputeMachineImages(
 ) (
 	[]MachineImage,
 	error,
+) {
+	return ComputeMachineImagesWithOptions(ctx, log, lssOsImages, landscapeOsImages, providerOsImages,
+		providerLandscapeOsImages, disableMachineImages, includeFilters, excludeFilters, nil)
+}
+
+// ComputeMachineImagesWithOptions computes the machine images like ComputeMachineImages and additionally applies the
+// given options. The options may be nil. With focus, only the focused images of the lists are computed, see
+// MergeFocusedBaseline to apply them. If the context is canceled or its deadline is exceeded, the computation stops and
+// returns the error of the context.
+func ComputeMachineImagesWithOptions(
+	ctx context.Context,
+	log logr.Logger,
+	lssOsImages []MachineImage,
+	landscapeOsImages []MachineImage,
+	providerOsImages []MachineImage,
+	providerLandscapeOsImages []MachineImage,
+	disableMachineImages []string,
+	includeFilters []OsImagesFilterKind,
+	excludeFilters []OsImagesFilterKind,
+	options *ComputeMachineImagesOptions,
+) (
+	[]MachineImage,
+	error,
+) {
+	if options == nil {
+		options = &ComputeMachineImagesOptions{}
+	}
+	results, err := computeMachineImages(ctx, log, lssOsImages, landscapeOsImages, []providerInput{{
+		provider:        options.Provider,
+		images:          providerOsImages,
+		landscapeImages: providerLandscapeOsImages,
+	}}, disableMachineImages, includeFilters, excludeFilters, options)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// providerInput are the provider configs of a provider type. The name prefixes the problems and errors of the provider
+// and is empty if only a single provider is computed.
+type providerInput struct {
+	name            string
+	provider        string
+	images          []MachineImage
+	landscapeImages []MachineImage
+}
+
+// computeMachineImages computes the machine images of all providers. The OS images are merged, deduplicated and
+// filtered once and shared by the providers, only the provider configs and the following stages are applied per
+// provider. The results are in the order of the providers.
+func computeMachineImages(
+	ctx context.Context,
+	log logr.Logger,
+	lssOsImages []MachineImage,
+	landscapeOsImages []MachineImage,
+	providers []providerInput,
+	disableMachineImages []string,
+	includeFilters []OsImagesFilterKind,
+	excludeFilters []OsImagesFilterKind,
+	options *ComputeMachineImagesOptions,
+) (
+	[][]MachineImage,
+	error,
 ) {
 	log.Info("Computing machine images")
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	reporter := NewSampledLogSink(log, options.ReportLogSampling, options.Reporter)
+	defer reporter.Flush()
+
+	ctx = NewContext(ctx, log, reporter)
+	if options.NetworkPolicyGuard {
+		ctx = WithNetworkPolicyGuard(ctx)
+	}
+
 	if len(includeFilters) == 0 {
 		includeFilters = append(includeFilters, OsImagesFilterKindAll)
 	}
 
 	err := validateFilters(includeFilters, excludeFilters)
 	if err != nil {
-		return nil, err
+		return nil, ClassifyError(err, ErrorClassValidation)
+	}
+
+	disablePatterns, err := parseDisablePatterns(disableMachineImages)
+	if err != nil {
+		return nil, ClassifyError(err, ErrorClassValidation)
+	}
+
+	if err := options.Sort.Validate(); err != nil {
+		return nil, ClassifyError(err, ErrorClassValidation)
+	}
+
+	for _, source := range FieldMappingSources {
+		if err := options.FieldMappings[source].Validate(); err != nil {
+			return nil, ClassifyError(fmt.Errorf("invalid field mapping of %s: %w", source, err), ErrorClassValidation)
+		}
+	}
+	lssOsImages = options.focusAndMap("machineImages", lssOsImages)
+	landscapeOsImages = options.focusAndMap("machineImagesLs", landscapeOsImages)
+	disablePatterns = focusDisablePatterns(disablePatterns, options.Focus)
+	for i := range providers {
+		providers[i].images = options.focusAndMap("machineImagesProvider", providers[i].images)
+		providers[i].landscapeImages = options.focusAndMap("machineImagesProviderLs", providers[i].landscapeImages)
+	}
+
+	var knownFields []string
+	if options.StrictVersionFields {
+		knownFields = options.knownVersionFields()
+	}
+	problems := machineImageListProblems([][]MachineImage{lssOsImages, landscapeOsImages}, knownFields)
+	for _, provider := range providers {
+		for _, problem := range machineImageListProblems([][]MachineImage{nil, nil, provider.images,
+			provider.landscapeImages}, knownFields) {
+			if len(provider.name) > 0 {
+				problem = provider.name + ": " + problem
+			}
+			problems = append(problems, problem)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
 	}
 
 	flatLandscapeOsImages := flatImages(landscapeOsImages)
 	flatLssOsImages := flatImages(lssOsImages)
-	flatOsImages := append(flatLandscapeOsImages, flatLssOsImages...)
+	flatOsImages, err := mergeLayers(ctx, flatLandscapeOsImages, flatLssOsImages, options.MergeStrategy,
+		options.CRIMergeStrategy)
+	if err != nil {
+		return nil, ClassifyError(err, ErrorClassValidation)
+	}
+	for _, image := range flatOsImages {
+		if err := validateCRI(image.Version); err != nil {
+			return nil, ClassifyError(fmt.Errorf("invalid cri of %s:%s: %w", image.Name, versionOrEmpty(image.Version), err),
+				ErrorClassValidation)
+		}
+	}
 	flatOsImages = removeDuplicates(flatOsImages)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if options.DropExpiredVersions {
+		now := time.Now()
+		if options.ExpirationReferenceTime != nil {
+			now = *options.ExpirationReferenceTime
+		}
+		flatOsImages, err = dropExpiredVersions(ctx, flatOsImages, now)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	flatOsImages, err = filterOsImages(ctx, flatOsImages, includeFilters, excludeFilters)
+	if err != nil {
+		return nil, ClassifyError(err, ErrorClassValidation)
+	}
 
-	flatOsImages, err = filterOsImages(flatOsImages, includeFilters, excludeFilters)
+	flatOsImages, err = applyMinVersions(ctx, flatOsImages, options.MinVersions, options.MinVersionsAction)
 	if err != nil {
+		return nil, ClassifyError(err, ErrorClassValidation)
+	}
+
+	machineImages := []MachineImage{}
+	if len(flatOsImages) > 0 {
+		machineImages = convertOsImagesToMachineImages(flatOsImages)
+		options.Sort.sortImages(machineImages)
+		if err := sortVersions(machineImages, false); err != nil {
+			return nil, ClassifyError(err, ErrorClassValidation)
+		}
+	}
+	if err := checkDisablePatterns(ctx, disablePatterns, machineImages, options.StrictDisableMachineImages); err != nil {
 		return nil, err
 	}
 
-	if len(flatOsImages) == 0 {
+	results := make([][]MachineImage, 0, len(providers))
+	for _, provider := range providers {
+		providerOptions := *options
+		providerOptions.Provider = provider.provider
+		result, err := computeProviderMachineImages(ctx, log, machineImages, disablePatterns, provider, &providerOptions)
+		if err != nil {
+			if len(provider.name) > 0 {
+				err = fmt.Errorf("provider %s: %w", provider.name, err)
+			}
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// computeProviderMachineImages applies the provider configs of the provider and the following stages to the shared
+// machine images. The shared machine images are not modified.
+func computeProviderMachineImages(
+	ctx context.Context,
+	log logr.Logger,
+	machineImages []MachineImage,
+	disablePatterns []*disablePattern,
+	provider providerInput,
+	options *ComputeMachineImagesOptions,
+) (
+	[]MachineImage,
+	error,
+) {
+	if len(provider.provider) > 0 {
+		ctx = NewContext(ctx, nil, providerReportSink{provider: provider.provider, sink: ReporterFromContext(ctx)})
+	}
+
+	var err error
+	if len(machineImages) > 0 {
+		machineImages, err = getFilteredMachineImages(ctx, machineImages, disablePatterns,
+			provider.landscapeImages, provider.images)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(options.Provider) > 0 && options.ProviderDisabled(options.Provider) {
+		reporter := ReporterFromContext(ctx)
+		for _, image := range machineImages {
+			for _, version := range image.Versions {
+				reporter.Report(ReportEntry{Image: image.Name, Version: versionOrEmpty(version), Reason: ReasonProviderDisabled,
+					Message: fmt.Sprintf("provider %s is disabled", options.Provider)})
+			}
+		}
 		return []MachineImage{}, nil
 	}
 
-	machineImages := convertOsImagesToMachineImages(flatOsImages)
-	sort.SliceStable(machineImages, func(i, j int) bool {
-		return machineImages[i].Name < machineImages[j].Name
-	})
-	sort.SliceStable(machineImages, func(i, j int) bool {
-		return machineImages[i].Name == OsNameGardenLinux && machineImages[j].Name != OsNameGardenLinux
-	})
+	if err := validateProviderMappings(machineImages, options.Provider); err != nil {
+		return nil, ClassifyError(err, ErrorClassValidation)
+	}
+
+	machineImages, err = applyRegionScope(ctx, machineImages, options.RegionScope)
+	if err != nil {
+		return nil, err
+	}
+
+	machineImages, err = applyArtifactProbe(ctx, machineImages, options.ArtifactProbe)
+	if err != nil {
+		return nil, err
+	}
+
+	machineImages, err = applyLatestPerMinor(ctx, machineImages, options.LatestPerMinor)
+	if err != nil {
+		return nil, err
+	}
+
+	machineImages = applyMaintenance(machineImages, options.Maintenance)
+
+	machineImages, err = applyEndOfLife(ctx, machineImages, options.EndOfLife)
+	if err != nil {
+		return nil, err
+	}
+
+	machineImages, err = applyVersionBudget(ctx, machineImages, options.Budget)
+	if err != nil {
+		return nil, ClassifyError(err, ErrorClassPolicy)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	incidents, err := options.incidentSource()
+	if err != nil {
+		return nil, err
+	}
+	machineImages, err = applyIncidents(ctx, machineImages, incidents)
+	if err != nil {
+		return nil, err
+	}
+
+	machineImages, err = applyPlugins(ctx, machineImages, options.Plugins)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.NormalizeVersions {
+		if err := normalizeVersions(machineImages); err != nil {
+			return nil, ClassifyError(err, ErrorClassValidation)
+		}
+		normalizeFlavors(machineImages)
+	}
+
+	if err := checkRequiredImages(machineImages, focusNames(options.RequiredImages, options.Focus)); err != nil {
+		return nil, ClassifyError(err, ErrorClassPolicy)
+	}
+
+	// the stages rely on descending versions, so the version order of the options is applied last
+	if options.Sort.versionsAscending() {
+		if err := sortVersions(machineImages, true); err != nil {
+			return nil, ClassifyError(err, ErrorClassValidation)
+		}
+	}
+
+	if options.SizeLimits != nil {
+		estimate, err := EstimateMachineImagesSize(machineImages)
+		if err != nil {
+			return nil, err
+		}
+		if err := estimate.Check(log, options.SizeLimits); err != nil {
+			return nil, ClassifyError(err, ErrorClassPolicy)
+		}
+	}
 
-	machineImages = getFilteredMachineImages(machineImages, disableMachineImages,
-		providerLandscapeOsImages, providerOsImages)
 	return machineImages, nil
 }
 
+// ComputeMachineImagesFromImports computes the machine images from the image lists, filters and options of the imports.
+// The machineImages are fetched from the os image source of the imports, if one is configured.
+func ComputeMachineImagesFromImports(ctx context.Context, log logr.Logger, imports *Imports) ([]MachineImage, error) {
+	lssOsImages, err := imports.sourceOsImages(ctx, log, imports.MachineImages)
+	if err != nil {
+		return nil, err
+	}
+
+	return ComputeMachineImagesWithOptions(
+		ctx,
+		log,
+		lssOsImages,
+		imports.MachineImagesLs,
+		imports.MachineImagesProvider,
+		imports.MachineImagesProviderLs,
+		imports.DisableMachineImages,
+		imports.IncludeFilters,
+		imports.ExcludeFilters,
+		&imports.ComputeMachineImagesOptions,
+	)
+}
+
+// sourceOsImages returns the OS images of the configured source, or the given OS images if no source is configured.
+func (o *ComputeMachineImagesOptions) sourceOsImages(ctx context.Context, log logr.Logger, lssOsImages []MachineImage) ([]MachineImage, error) {
+	source, err := o.osImageSource()
+	if err != nil {
+		return nil, ClassifyError(err, ErrorClassValidation)
+	}
+	if source == nil {
+		return lssOsImages, nil
+	}
+	log.Info("Fetching os images")
+	if lssOsImages, err = source.OsImages(NewContext(ctx, log, o.Reporter)); err != nil {
+		return nil, ClassifyError(err, ErrorClassFetch)
+	}
+	return lssOsImages, nil
+}
+
+// ComputeMachineImagesWithWarnings computes the machine images of the imports like ComputeMachineImagesFromImports and
+// additionally returns the findings of the computation as warnings, e.g. every dropped version with the reason, see
+// ReportEntry. The findings are still passed to the reporter of the imports.
+func ComputeMachineImagesWithWarnings(ctx context.Context, log logr.Logger, imports *Imports) (
+	[]MachineImage,
+	[]ReportEntry,
+	error,
+) {
+	report := NewReport()
+	computeImports := *imports
+	computeImports.Reporter = report
+	if imports.Reporter != nil {
+		computeImports.Reporter = multiReportSink{imports.Reporter, report}
+	}
+
+	result, err := ComputeMachineImagesFromImports(ctx, log, &computeImports)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, report.Entries(), nil
+}
+
+// ComputeExports computes the machine images of the imports, merges them into the focus baseline and returns them as
+// exports, see MergeFocusedBaseline and NewExports.
+func ComputeExports(ctx context.Context, log logr.Logger, imports *Imports) (*Exports, error) {
+	result, warnings, err := ComputeMachineImagesWithWarnings(ctx, log, imports)
+	if err != nil {
+		return nil, err
+	}
+	if result, warnings, err = imports.MergeFocusedBaseline(result, warnings); err != nil {
+		return nil, err
+	}
+	return NewExports(imports, result, warnings)
+}
+
+// NewExports returns the exports of the machine images which were computed for the imports, together with the warnings
+// of the computation and the resolved version aliases. If the imports configure a config map output, the machine images
+// are only contained in the config map and its reference. With focus, the images which are not focused keep the
+// aliases of the focus baseline.
+func NewExports(imports *Imports, images []MachineImage, warnings []ReportEntry) (*Exports, error) {
+	if len(warnings) == 0 {
+		warnings = nil
+	}
+	aliases, err := imports.ResolveVersionAliases(images)
+	if err != nil {
+		return nil, ClassifyError(err, ErrorClassPolicy)
+	}
+	aliases = imports.mergeFocusedAliases(aliases)
+
+	if imports.ConfigMapOutput == nil {
+		return &Exports{ResultMachineImages: images, ResultWarnings: warnings, ResultVersionAliases: aliases}, nil
+	}
+
+	configMap, reference, err := NewMachineImagesConfigMap(images, imports.ConfigMapOutput)
+	if err != nil {
+		return nil, err
+	}
+	return &Exports{
+		ResultMachineImages:          []MachineImage{},
+		ResultMachineImagesRef:       reference,
+		ResultMachineImagesConfigMap: configMap,
+		ResultWarnings:               warnings,
+		ResultVersionAliases:         aliases,
+	}, nil
+}
+
+// KeepCosmeticExports returns the exports of the machine images of the previous exports, see NewExports, if the
+// machine images of the exports only differ cosmetically from them according to the diff options of the imports, see
+// DiffOptions.EqualMachineImages. Writing the exports then writes the previous exports again, so that cosmetic changes
+// trigger no applies. The candidate channel of previous exports with channels is compared. Without diff options or
+// previous exports, or if the machine images changed, the exports are returned.
+func KeepCosmeticExports(imports *Imports, previous, exports *Exports) (*Exports, error) {
+	if imports.Diff == nil || previous == nil {
+		return exports, nil
+	}
+	previousImages := previous.ResultMachineImagesCandidate
+	if previousImages == nil {
+		var err error
+		if previousImages, err = previous.machineImages(); err != nil {
+			return nil, err
+		}
+	}
+	images, err := exports.machineImages()
+	if err != nil {
+		return nil, err
+	}
+	equal, err := imports.Diff.EqualMachineImages(previousImages, images)
+	if err != nil || !equal {
+		return exports, err
+	}
+	return NewExports(imports, previousImages, exports.ResultWarnings)
+}
+
+// machineImages returns the machine images of the exports, also if they are exported in a config map.
+func (e *Exports) machineImages() ([]MachineImage, error) {
+	if e.ResultMachineImagesConfigMap != nil {
+		return MachineImagesFromConfigMap(e.ResultMachineImagesConfigMap, e.ResultMachineImagesRef)
+	}
+	return e.ResultMachineImages, nil
+}
+
+// getFilteredMachineImages merges the provider configs into the versions which are not disabled and drops the versions
+// without provider config. The dropped versions are reported. It returns the error of the context if the context is done, as looking up the provider
+// configs of large landscapes takes a while.
 func getFilteredMachineImages(
+	ctx context.Context,
 	machineImages []MachineImage,
-	disableMachineImages []string,
+	disablePatterns []*disablePattern,
 	providerLandscapeOsImages []MachineImage,
 	providerOsImages []MachineImage,
-) []MachineImage {
+) ([]MachineImage, error) {
+	reporter := ReporterFromContext(ctx)
 	filteredImages := []MachineImage{}
 	for _, nextImage := range machineImages {
-		if contains(disableMachineImages, nextImage.Name) {
-			continue
-		}
-
 		versionsWithConfig := []MachineImageVersion{}
 		for _, nextVersion := range nextImage.Versions {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			versionNumber := nextVersion.getVersion()
-			config := getVersionConfig(nextImage.Name, *versionNumber, providerLandscapeOsImages, providerOsImages)
-			if config != nil {
-				for nextKey, nextValue := range *config {
-					nextVersion[nextKey] = nextValue
+			if disabled := disabledVersion(disablePatterns, nextImage.Name, *versionNumber); disabled != nil {
+				reporter.Report(ReportEntry{Image: nextImage.Name, Version: *versionNumber, Reason: ReasonDisabled,
+					Message: fmt.Sprintf("disabled by %s", disabled.pattern)})
+				continue
+			}
+			var configs []MachineImageVersion
+			if architectures := nextVersion.getArchitectures(); len(architectures) > 0 {
+				var missing []string
+				configs, missing = getArchitectureVersionConfigs(nextImage.Name, *versionNumber, architectures,
+					providerLandscapeOsImages, providerOsImages)
+				if len(configs) > 0 && len(missing) > 0 {
+					reporter.Report(ReportEntry{Image: nextImage.Name, Version: *versionNumber, Reason: ReasonNoProviderConfig,
+						Message: fmt.Sprintf("no provider config found for the architectures %s", strings.Join(missing, ", "))})
+				}
+			} else if config := getVersionConfig(nextImage.Name, *versionNumber, providerLandscapeOsImages, providerOsImages); config != nil {
+				configs = []MachineImageVersion{*config}
+			}
+			if len(configs) == 0 {
+				reporter.Report(ReportEntry{Image: nextImage.Name, Version: *versionNumber, Reason: ReasonNoProviderConfig,
+					Message: "no provider config found"})
+				continue
+			}
+			for _, config := range configs {
+				// merge into a deep copy, so that later stages and the caller can modify the result without modifying
+				// the input, e.g. its regions, and repeated computations yield the same result
+				versionWithConfig := nextVersion.DeepCopy()
+				for nextKey, nextValue := range config {
+					versionWithConfig[nextKey] = deepCopyValue(nextValue)
 				}
-				versionsWithConfig = append(versionsWithConfig, nextVersion)
+				versionsWithConfig = append(versionsWithConfig, versionWithConfig)
 			}
 		}
 
@@ -97,32 +535,42 @@ func getFilteredMachineImages(
 		}
 	}
 
-	return filteredImages
+	return filteredImages, nil
 }
 
+// getVersionConfig returns the config of the exact version, or else the first config whose version constraint the
+// version satisfies. Exact versions take precedence over version constraints in both lists, the landscape configs
+// take precedence over the default configs of the same kind.
 func getVersionConfig(imageName, versionNumber string, providerLandscapeOsImages, providerOsImages []MachineImage) *MachineImageVersion {
-	config := getVersionConfigInternal(imageName, versionNumber, providerLandscapeOsImages)
-
-	if config != nil {
-		return config
+	landscapeExact, landscapeRange := getVersionConfigInternal(imageName, versionNumber, providerLandscapeOsImages)
+	providerExact, providerRange := getVersionConfigInternal(imageName, versionNumber, providerOsImages)
+	for _, config := range []*MachineImageVersion{landscapeExact, providerExact, landscapeRange, providerRange} {
+		if config != nil {
+			return config
+		}
 	}
-
-	config = getVersionConfigInternal(imageName, versionNumber, providerOsImages)
-	return config
+	return nil
 }
 
-func getVersionConfigInternal(imageName, versionNumber string, images []MachineImage) *MachineImageVersion {
+// getVersionConfigInternal returns the config of the exact version in the images and the first config whose version
+// constraint the version satisfies.
+func getVersionConfigInternal(imageName, versionNumber string, images []MachineImage) (*MachineImageVersion, *MachineImageVersion) {
+	var rangeConfig *MachineImageVersion
 	for _, nextImage := range images {
 		if nextImage.Name == imageName {
 			for _, nextVersion := range nextImage.Versions {
 				if nextVersion.getVersion() != nil && *nextVersion.getVersion() == versionNumber {
-					return &nextVersion
+					return &nextVersion, rangeConfig
+				}
+				if rangeConfig == nil && matchesVersionConstraint(nextVersion, versionNumber) {
+					config := withoutVersionConstraint(nextVersion)
+					rangeConfig = &config
 				}
 			}
 		}
 	}
 
-	return nil
+	return nil, rangeConfig
 }
 
 func contains(s []string, str string) bool {
@@ -135,23 +583,6 @@ func contains(s []string, str string) bool {
 	return false
 }
 
-func removeDuplicates(images []OsImage) []OsImage {
-	result := []OsImage{}
-	for _, nextImage := range images {
-		found := false
-		for _, nextResult := range result {
-			if reflect.DeepEqual(nextImage, nextResult) {
-				found = true
-				break
-			}
-		}
-		if !found {
-			result = append(result, nextImage)
-		}
-	}
-	return result
-}
-
 func flatImages(images []MachineImage) []OsImage {
 	result := []OsImage{}
 	for _, nextImage := range images {
@@ -165,25 +596,20 @@ func flatImages(images []MachineImage) []OsImage {
 	return result
 }
 
+// convertOsImagesToMachineImages groups the os images by name. Images are ordered by their first occurrence and versions
+// keep their order, so that the result only depends on the input and not on map iteration.
 func convertOsImagesToMachineImages(images []OsImage) []MachineImage {
-	m := map[string][]MachineImageVersion{}
+	result := []MachineImage{}
+	index := map[string]int{}
 
 	for _, image := range images {
-		versions, ok := m[image.Name]
+		i, ok := index[image.Name]
 		if !ok {
-			versions = []MachineImageVersion{image.Version}
-		} else {
-			versions = append(versions, image.Version)
+			i = len(result)
+			index[image.Name] = i
+			result = append(result, MachineImage{Name: image.Name})
 		}
-		m[image.Name] = versions
-	}
-
-	result := []MachineImage{}
-	for name, versions := range m {
-		result = append(result, MachineImage{
-			Name:     name,
-			Versions: versions,
-		})
+		result[i].Versions = append(result[i].Versions, image.Version)
 	}
 
 	return result
@@ -6,7 +6,14 @@ package machineimages
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-logr/logr"
 
@@ -48,22 +55,10 @@ var _ = Describe("machine images", func() {
 				{Name: OsNameCoreos, Version: newVersion("0.21.0")},
 			}
 			machineImages := convertOsImagesToMachineImages(osImages)
-			Expect(machineImages).To(HaveLen(2))
-			for _, machineImage := range machineImages {
-				if machineImage.Name == OsNameUbuntu {
-					Expect(machineImage.Versions).To(ConsistOf(
-						newVersion("0.10.0"),
-						newVersion("0.11.0"),
-					))
-				} else if machineImage.Name == OsNameCoreos {
-					Expect(machineImage.Versions).To(ConsistOf(
-						newVersion("0.20.0"),
-						newVersion("0.21.0"),
-					))
-				} else {
-					Fail("unexpected os image name")
-				}
-			}
+			Expect(machineImages).To(Equal([]MachineImage{
+				{Name: OsNameUbuntu, Versions: []MachineImageVersion{newVersion("0.10.0"), newVersion("0.11.0")}},
+				{Name: OsNameCoreos, Versions: []MachineImageVersion{newVersion("0.20.0"), newVersion("0.21.0")}},
+			}))
 		})
 	})
 
@@ -118,5 +113,225 @@ var _ = Describe("machine images", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(machineImages).To(Equal(expectedImages))
 		})
+
+		It("should yield byte-identical output for repeated runs", func() {
+			imports := &Imports{}
+			var err error
+			imports.MachineImages, err = readMachineImages("./resources/images.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			imports.MachineImagesLs, err = readMachineImages("./resources/images-ls.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			imports.MachineImagesProvider, err = readMachineImages("./resources/images-pr.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			imports.MachineImagesProviderLs, err = readMachineImages("./resources/images-ls-pr.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			imports.ConfigMapOutput = &ConfigMapOutput{Name: "images"}
+
+			before, err := yaml.Marshal(imports)
+			Expect(err).NotTo(HaveOccurred())
+
+			var first []byte
+			for i := 0; i < 20; i++ {
+				exports, err := ComputeExports(context.Background(), logr.Discard(), imports)
+				Expect(err).NotTo(HaveOccurred())
+				out, err := yaml.Marshal(exports)
+				Expect(err).NotTo(HaveOccurred())
+				if first == nil {
+					first = out
+				}
+				Expect(string(out)).To(Equal(string(first)))
+			}
+
+			after, err := yaml.Marshal(imports)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(after)).To(Equal(string(before)), "the imports must not be modified")
+		})
+
+		It("should fail if a required image has no versions", func() {
+			lssOsImages, err := readMachineImages("./resources/images.yaml")
+			Expect(err).NotTo(HaveOccurred())
+
+			providerOsImages, err := readMachineImages("./resources/images-pr.yaml")
+			Expect(err).NotTo(HaveOccurred())
+
+			options := &ComputeMachineImagesOptions{
+				RequiredImages: []string{OsNameGardenLinux, OsNameUbuntu},
+			}
+
+			_, err = ComputeMachineImagesWithOptions(
+				context.Background(),
+				logr.Discard(),
+				lssOsImages,
+				nil,
+				providerOsImages,
+				nil,
+				nil,
+				nil,
+				nil,
+				options,
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = ComputeMachineImagesWithOptions(
+				context.Background(),
+				logr.Discard(),
+				lssOsImages,
+				nil,
+				providerOsImages,
+				nil,
+				[]string{OsNameGardenLinux},
+				nil,
+				nil,
+				options,
+			)
+			Expect(err).To(MatchError(ContainSubstring(OsNameGardenLinux)))
+			Expect(err).NotTo(MatchError(ContainSubstring(OsNameUbuntu)))
+		})
+	})
+
+	Context("ComputeExports", func() {
+
+		imports := func() *Imports {
+			return &Imports{
+				MachineImages: []MachineImage{
+					{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0"}}},
+				},
+				MachineImagesProvider: []MachineImage{
+					{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0", "image": "a"}}},
+				},
+			}
+		}
+
+		It("should export the machine images", func() {
+			exports, err := ComputeExports(context.Background(), logr.Discard(), imports())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exports.ResultMachineImages).To(HaveLen(1))
+			Expect(exports.ResultMachineImagesConfigMap).To(BeNil())
+		})
+
+		It("should export a config map if configured", func() {
+			i := imports()
+			i.ConfigMapOutput = &ConfigMapOutput{Name: "images", Namespace: "default"}
+
+			exports, err := ComputeExports(context.Background(), logr.Discard(), i)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exports.ResultMachineImages).To(BeEmpty())
+			Expect(exports.ResultMachineImagesConfigMap).NotTo(BeNil())
+			Expect(exports.ResultMachineImagesRef.ConfigMapRef.Name).To(Equal("images"))
+		})
+	})
+
+	Context("warnings", func() {
+
+		It("should return every dropped version with the reason", func() {
+			imports := &Imports{
+				MachineImages: []MachineImage{
+					{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}, {"version": "20.4.0"}}},
+					{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0"}, {"version": "934.6.0"}}},
+					{Name: OsNameCoreos, Versions: []MachineImageVersion{{"version": "2303.3.0"}}},
+				},
+				MachineImagesProvider: []MachineImage{
+					{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0", "image": "a"}, {"version": "20.4.0", "image": "b"}}},
+					{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0", "image": "c"}}},
+				},
+				DisableMachineImages: []string{"ubuntu:20.4.0"},
+				ExcludeFilters:       []OsImagesFilterKind{OsImagesFilterKindCoreos},
+			}
+			report := NewReport()
+			imports.Reporter = report
+
+			result, warnings, err := ComputeMachineImagesWithWarnings(context.Background(), logr.Discard(), imports)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal([]MachineImage{
+				{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0", "image": "c"}}},
+				{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0", "image": "a"}}},
+			}))
+			Expect(warnings).To(Equal([]ReportEntry{
+				{Image: OsNameCoreos, Version: "2303.3.0", Reason: ReasonFilteredOut, Message: "matched by an exclude filter"},
+				{Image: OsNameGardenLinux, Version: "934.6.0", Reason: ReasonNoProviderConfig, Message: "no provider config found"},
+				{Image: OsNameUbuntu, Version: "20.4.0", Reason: ReasonDisabled, Message: "disabled by ubuntu:20.4.0"},
+			}))
+			Expect(report.Entries()).To(Equal(warnings))
+			Expect(imports.Reporter).To(BeIdenticalTo(report))
+		})
+
+		It("should export the warnings", func() {
+			imports := &Imports{
+				MachineImages:         []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}}}},
+				MachineImagesProvider: []MachineImage{},
+			}
+
+			exports, err := ComputeExports(context.Background(), logr.Discard(), imports)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exports.ResultWarnings).To(Equal([]ReportEntry{
+				{Image: OsNameUbuntu, Version: "22.4.0", Reason: ReasonNoProviderConfig, Message: "no provider config found"},
+			}))
+
+			imports.MachineImagesProvider = []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}}}}
+			exports, err = ComputeExports(context.Background(), logr.Discard(), imports)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(exports.ResultWarnings).To(BeNil())
+		})
+	})
+
+	Context("cancellation", func() {
+
+		It("should not start computations with a done context", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			images := []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0"}}}}
+			_, err := ComputeMachineImages(ctx, logr.Discard(), images, nil, images, nil, nil, nil, nil)
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		})
+
+		It("should stop probing artifacts once the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var requests int32
+			registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				cancel()
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			defer registry.Close()
+
+			versions := []MachineImageVersion{}
+			for i := 0; i < 100; i++ {
+				versions = append(versions, MachineImageVersion{"version": fmt.Sprintf("934.%d.0", i)})
+			}
+			images := []MachineImage{{Name: OsNameGardenLinux, Versions: versions}}
+			options := &ComputeMachineImagesOptions{ArtifactProbe: &ArtifactProbe{
+				Repositories: map[string]string{OsNameGardenLinux: strings.TrimPrefix(registry.URL, "http://") + "/gardenlinux/gardenlinux"},
+				PlainHTTP:    true,
+			}}
+
+			_, err := ComputeMachineImagesWithOptions(ctx, logr.Discard(), images, nil, images, nil, nil, nil, nil, options)
+			Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+			Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+		})
+
+		It("should abort large computations within a bounded time after the deadline", func() {
+			images := []MachineImage{}
+			providerImages := []MachineImage{}
+			for i := 0; i < 20; i++ {
+				versions := []MachineImageVersion{}
+				providerVersions := []MachineImageVersion{}
+				for j := 0; j < 2000; j++ {
+					version := fmt.Sprintf("%d.%d.0", j/100, j%100)
+					versions = append(versions, MachineImageVersion{"version": version})
+					providerVersions = append(providerVersions, MachineImageVersion{"version": version, "image": "image"})
+				}
+				images = append(images, MachineImage{Name: fmt.Sprintf("os-%d", i), Versions: versions})
+				providerImages = append(providerImages, MachineImage{Name: fmt.Sprintf("os-%d", i), Versions: providerVersions})
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			_, err := ComputeMachineImages(ctx, logr.Discard(), images, nil, providerImages, nil, nil, nil, nil)
+			Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+			Expect(time.Since(start)).To(BeNumerically("<", 2*time.Second))
+		})
 	})
 })
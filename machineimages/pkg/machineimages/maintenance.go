@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import "sort"
+
+// MaintenancePolicy classifies the versions of an image by their position among its versions, like the lifecycle of
+// the versions of a cloud profile: the newest versions are preview, the next ones supported and the older ones
+// deprecated.
+type MaintenancePolicy struct {
+	// PreviewVersions is the number of newest versions which are classified as preview.
+	PreviewVersions int `json:"previewVersions,omitempty" yaml:"previewVersions,omitempty"`
+	// SupportedVersions is the number of versions after the preview versions which are classified as supported. Older
+	// versions are deprecated. Zero classifies all older versions as supported.
+	SupportedVersions int `json:"supportedVersions,omitempty" yaml:"supportedVersions,omitempty"`
+}
+
+// classify returns the classification of the version at the rank, where the newest version has rank zero.
+func (p *MaintenancePolicy) classify(rank int) string {
+	switch {
+	case rank < p.PreviewVersions:
+		return ClassificationPreview
+	case p.SupportedVersions == 0 || rank < p.PreviewVersions+p.SupportedVersions:
+		return ClassificationSupported
+	default:
+		return ClassificationDeprecated
+	}
+}
+
+// applyMaintenance sets the classification of the versions of all images with a maintenance policy. Versions are
+// ranked by their semver, versions with the same version, e.g. of different architectures, share their rank. Versions
+// which are no semver and versions which are already deprecated keep their classification.
+func applyMaintenance(images []MachineImage, policies map[string]*MaintenancePolicy) []MachineImage {
+	if len(policies) == 0 {
+		return images
+	}
+
+	result := make([]MachineImage, 0, len(images))
+	for _, image := range images {
+		policy, ok := policies[image.Name]
+		if !ok || policy == nil {
+			result = append(result, image)
+			continue
+		}
+
+		parsed := map[string]*Version{}
+		for _, v := range image.Versions {
+			version := versionOrEmpty(v)
+			if p, err := ParseVersion(version); err == nil {
+				parsed[version] = p
+			}
+		}
+		ordered := make([]string, 0, len(parsed))
+		for version := range parsed {
+			ordered = append(ordered, version)
+		}
+		sort.Slice(ordered, func(i, j int) bool {
+			if c := parsed[ordered[i]].Compare(parsed[ordered[j]]); c != 0 {
+				return c > 0
+			}
+			return ordered[i] > ordered[j]
+		})
+		ranks := map[string]int{}
+		for i, version := range ordered {
+			ranks[version] = i
+		}
+
+		versions := make([]MachineImageVersion, 0, len(image.Versions))
+		for _, v := range image.Versions {
+			rank, ok := ranks[versionOrEmpty(v)]
+			if !ok || v.hasClassification(ClassificationDeprecated) {
+				versions = append(versions, v)
+				continue
+			}
+			classified := MachineImageVersion{}
+			for key, value := range v {
+				classified[key] = value
+			}
+			classified["classification"] = policy.classify(rank)
+			versions = append(versions, classified)
+		}
+		result = append(result, MachineImage{Name: image.Name, Versions: versions})
+	}
+	return result
+}
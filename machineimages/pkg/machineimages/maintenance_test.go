@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("maintenance", func() {
+
+	images := func() []MachineImage {
+		return []MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.8.0", "architecture": "amd64"},
+				{"version": "934.8.0", "architecture": "arm64"},
+				{"version": "934.6.0"},
+				{"version": "934.7.0", "classification": ClassificationSupported},
+				{"version": "934.5.0"},
+				{"version": "934.4.0", "classification": ClassificationDeprecated},
+				{"version": "latest"},
+			}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}}},
+		}
+	}
+
+	It("should classify the versions by their rank", func() {
+		result := applyMaintenance(images(), map[string]*MaintenancePolicy{
+			OsNameGardenLinux: {PreviewVersions: 1, SupportedVersions: 2},
+		})
+		Expect(result).To(Equal([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.8.0", "architecture": "amd64", "classification": ClassificationPreview},
+				{"version": "934.8.0", "architecture": "arm64", "classification": ClassificationPreview},
+				{"version": "934.6.0", "classification": ClassificationSupported},
+				{"version": "934.7.0", "classification": ClassificationSupported},
+				{"version": "934.5.0", "classification": ClassificationDeprecated},
+				{"version": "934.4.0", "classification": ClassificationDeprecated},
+				{"version": "latest"},
+			}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}}},
+		}))
+	})
+
+	It("should support all older versions without supported versions and not modify the input", func() {
+		input := images()
+		result := applyMaintenance(input, map[string]*MaintenancePolicy{OsNameGardenLinux: {}})
+		Expect(result[0].Versions[4]).To(Equal(MachineImageVersion{"version": "934.5.0", "classification": ClassificationSupported}))
+		Expect(input[0].Versions[4]).To(Equal(MachineImageVersion{"version": "934.5.0"}))
+	})
+
+	It("should classify the computed versions and reject invalid policies", func() {
+		imports := &Imports{
+			MachineImages: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.7.0"}, {"version": "934.6.0"},
+			}}},
+			MachineImagesProvider: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.7.0", "image": "a"}, {"version": "934.6.0", "image": "b"},
+			}}},
+			ComputeMachineImagesOptions: ComputeMachineImagesOptions{
+				Maintenance: map[string]*MaintenancePolicy{OsNameGardenLinux: {PreviewVersions: 1, SupportedVersions: 0}},
+			},
+		}
+		Expect(ValidateImports(imports)).To(Succeed())
+		result, err := ComputeMachineImagesFromImports(context.Background(), logr.Discard(), imports)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.7.0", "image": "a", "classification": ClassificationPreview},
+			{"version": "934.6.0", "image": "b", "classification": ClassificationSupported},
+		}}}))
+
+		imports.Maintenance = map[string]*MaintenancePolicy{OsNameGardenLinux: {PreviewVersions: -1}, OsNameUbuntu: nil}
+		imports.ProviderEnabled = map[string]bool{"": true, "aws": false}
+		for i := 0; i < 10; i++ {
+			Expect(ValidateImports(imports)).To(MatchError("invalid imports: " +
+				"maintenance: version counts of image gardenlinux must not be negative; " +
+				"maintenance: policy of image ubuntu must be set; providerEnabled: empty provider type"))
+		}
+	})
+})
@@ -0,0 +1,212 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MergeStrategy determines how versions which are part of the landscape and the LSS list are merged.
+type MergeStrategy string
+
+const (
+	// MergeStrategyOverlay sets the fields of the landscape version over the fields of the LSS version. Fields of the
+	// LSS version which the landscape version does not set, e.g. the expirationDate, are preserved.
+	MergeStrategyOverlay = MergeStrategy("overlay")
+	// MergeStrategyPreferLandscape takes the landscape version as it is.
+	MergeStrategyPreferLandscape = MergeStrategy("landscape")
+	// MergeStrategyPreferLSS takes the LSS version as it is.
+	MergeStrategyPreferLSS = MergeStrategy("lss")
+	// MergeStrategyError fails the computation if the versions conflict.
+	MergeStrategyError = MergeStrategy("error")
+)
+
+// MergeConflict is a version of the landscape and the LSS list whose fields differ.
+type MergeConflict struct {
+	VersionRef `json:",inline"`
+	// Fields are the conflicting fields in lexical order.
+	Fields []FieldConflict `json:"fields"`
+}
+
+// FieldConflict is a field which is set to different values in the landscape and the LSS version.
+type FieldConflict struct {
+	Field     string      `json:"field"`
+	Landscape interface{} `json:"landscape"`
+	LSS       interface{} `json:"lss"`
+}
+
+// fieldNames returns the names of the conflicting fields.
+func (c MergeConflict) fieldNames() []string {
+	names := make([]string, 0, len(c.Fields))
+	for _, field := range c.Fields {
+		names = append(names, field.Field)
+	}
+	return names
+}
+
+// MergeConflictError is returned by MergeStrategyError.
+type MergeConflictError struct {
+	Conflicts []MergeConflict
+}
+
+func (e *MergeConflictError) Error() string {
+	conflicts := make([]string, 0, len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		conflicts = append(conflicts, fmt.Sprintf("%s:%s (%s)", conflict.Image, conflict.Version, strings.Join(conflict.fieldNames(), ", ")))
+	}
+	return "machine image versions of the landscape and the lss conflict: " + strings.Join(conflicts, ", ")
+}
+
+// DetectMergeConflicts returns the versions of both lists with the same image and version whose common fields differ.
+// Fields which are only set in one of the versions are no conflict. The conflicts are in the order of the landscape
+// versions.
+func DetectMergeConflicts(landscapeOsImages, lssOsImages []MachineImage) []MergeConflict {
+	conflicts := []MergeConflict{}
+	lss := firstVersions(flatImages(lssOsImages))
+	for _, image := range flatImages(landscapeOsImages) {
+		defaults, ok := lss[VersionRef{Image: image.Name, Version: versionOrEmpty(image.Version)}]
+		if !ok {
+			continue
+		}
+		if conflict, ok := mergeConflict(image, defaults); ok {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	return conflicts
+}
+
+// mergeConflict returns the conflicting fields of the versions, except for the ignored fields. Values with the same
+// json encoding are no conflict, e.g. the int and float64 numbers or the []string and []interface{} lists of versions
+// which were decoded differently.
+func mergeConflict(landscape OsImage, lss MachineImageVersion, ignored ...string) (MergeConflict, bool) {
+	conflict := MergeConflict{VersionRef: VersionRef{Image: landscape.Name, Version: versionOrEmpty(landscape.Version)}}
+	for field, value := range landscape.Version {
+		if contains(ignored, field) {
+			continue
+		}
+		if lssValue, ok := lss[field]; ok && !sameValue(value, lssValue) {
+			conflict.Fields = append(conflict.Fields, FieldConflict{Field: field, Landscape: value, LSS: lssValue})
+		}
+	}
+	sort.Slice(conflict.Fields, func(i, j int) bool {
+		return conflict.Fields[i].Field < conflict.Fields[j].Field
+	})
+	return conflict, len(conflict.Fields) > 0
+}
+
+// sameValue returns whether the values are deeply equal or have the same json encoding.
+func sameValue(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
+
+// firstVersions returns the first version of every image and version.
+func firstVersions(images []OsImage) map[VersionRef]MachineImageVersion {
+	versions := map[VersionRef]MachineImageVersion{}
+	for _, image := range images {
+		ref := VersionRef{Image: image.Name, Version: versionOrEmpty(image.Version)}
+		if _, ok := versions[ref]; !ok {
+			versions[ref] = image.Version
+		}
+	}
+	return versions
+}
+
+// mergeLayers returns the versions of the landscape followed by the versions of the LSS which the landscape does not
+// override. A landscape version overrides the LSS version of the same image and version according to the strategy.
+// Without strategy, the versions are merged like with MergeStrategyOverlay, but conflicts are not reported. The cri
+// lists of merged versions are merged according to the cri strategy, with CRIMergeStrategyUnion differing cri lists
+// are no conflict.
+func mergeLayers(ctx context.Context, landscapeOsImages, lssOsImages []OsImage, strategy MergeStrategy, criStrategy CRIMergeStrategy) ([]OsImage, error) {
+	switch strategy {
+	case "", MergeStrategyOverlay, MergeStrategyPreferLandscape, MergeStrategyPreferLSS, MergeStrategyError:
+	default:
+		return nil, fmt.Errorf("merge strategy does not exist %s", strategy)
+	}
+	if err := criStrategy.Validate(); err != nil {
+		return nil, err
+	}
+	ignored := []string{}
+	if criStrategy == CRIMergeStrategyUnion && strategy != MergeStrategyPreferLandscape && strategy != MergeStrategyPreferLSS {
+		ignored = append(ignored, "cri")
+	}
+
+	_, reporter := FromContext(ctx)
+	lss := firstVersions(lssOsImages)
+	result := make([]OsImage, 0, len(landscapeOsImages)+len(lssOsImages))
+	overridden := map[VersionRef]bool{}
+	conflicts := []MergeConflict{}
+	for _, image := range landscapeOsImages {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ref := VersionRef{Image: image.Name, Version: versionOrEmpty(image.Version)}
+		defaults, ok := lss[ref]
+		if !ok {
+			result = append(result, image)
+			continue
+		}
+
+		if strategy != "" {
+			if conflict, ok := mergeConflict(image, defaults, ignored...); ok {
+				conflicts = append(conflicts, conflict)
+				if strategy != MergeStrategyError {
+					reporter.Report(ReportEntry{
+						Image:   ref.Image,
+						Version: ref.Version,
+						Reason:  ReasonMergeConflict,
+						Message: fmt.Sprintf("fields %s of landscape and lss differ, merged with strategy %s",
+							strings.Join(conflict.fieldNames(), ", "), strategy),
+					})
+				}
+			}
+		}
+
+		switch strategy {
+		case MergeStrategyPreferLSS:
+			// the lss version is added with the other lss versions
+			continue
+		case MergeStrategyPreferLandscape:
+			result = append(result, image)
+		default:
+			// merge into a copy, so that the input is not modified
+			merged := MachineImageVersion{}
+			for key, value := range defaults {
+				merged[key] = value
+			}
+			for key, value := range image.Version {
+				merged[key] = value
+			}
+			lssCRI, lssOK := defaults["cri"]
+			landscapeCRI, landscapeOK := image.Version["cri"]
+			if criStrategy == CRIMergeStrategyUnion && lssOK && landscapeOK {
+				merged["cri"] = mergeCRI(lssCRI, landscapeCRI)
+			}
+			result = append(result, OsImage{Name: image.Name, Version: merged})
+		}
+		overridden[ref] = true
+	}
+
+	if strategy == MergeStrategyError && len(conflicts) > 0 {
+		return nil, &MergeConflictError{Conflicts: conflicts}
+	}
+
+	for _, image := range lssOsImages {
+		if !overridden[VersionRef{Image: image.Name, Version: versionOrEmpty(image.Version)}] {
+			result = append(result, image)
+		}
+	}
+	return result, nil
+}
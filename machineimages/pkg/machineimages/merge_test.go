@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("merge", func() {
+
+	lss := []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+		{"version": "20.4.0", "classification": "supported", "expirationDate": "2025-04-02T00:00:00Z"},
+		{"version": "22.4.0", "classification": "supported"},
+	}}}
+	landscape := []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+		{"version": "20.4.0", "classification": "deprecated", "cri": []interface{}{"containerd"}},
+		{"version": "22.4.0", "classification": "supported", "cri": []interface{}{"containerd"}},
+	}}}
+
+	merge := func(strategy MergeStrategy, report *Report) ([]OsImage, error) {
+		ctx := NewContext(context.Background(), logr.Discard(), report)
+		return mergeLayers(ctx, flatImages(landscape), flatImages(lss), strategy, "")
+	}
+
+	It("should detect conflicting fields", func() {
+		Expect(DetectMergeConflicts(landscape, lss)).To(Equal([]MergeConflict{{
+			VersionRef: VersionRef{Image: OsNameUbuntu, Version: "20.4.0"},
+			Fields:     []FieldConflict{{Field: "classification", Landscape: "deprecated", LSS: "supported"}},
+		}}))
+	})
+
+	It("should overlay the landscape fields without reporting conflicts by default", func() {
+		report := NewReport()
+		result, err := merge("", report)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result[0].Version).To(Equal(MachineImageVersion{
+			"version": "20.4.0", "classification": "deprecated", "expirationDate": "2025-04-02T00:00:00Z", "cri": []interface{}{"containerd"},
+		}))
+		Expect(report.Entries()).To(BeEmpty())
+	})
+
+	It("should report conflicts of the overlay strategy", func() {
+		report := NewReport()
+		_, err := merge(MergeStrategyOverlay, report)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Entries()).To(ConsistOf(ReportEntry{
+			Image:   OsNameUbuntu,
+			Version: "20.4.0",
+			Reason:  ReasonMergeConflict,
+			Message: "fields classification of landscape and lss differ, merged with strategy overlay",
+		}))
+	})
+
+	It("should prefer the landscape versions", func() {
+		result, err := merge(MergeStrategyPreferLandscape, NewReport())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(flatImages(landscape)))
+	})
+
+	It("should prefer the lss versions", func() {
+		result, err := merge(MergeStrategyPreferLSS, NewReport())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(flatImages(lss)))
+	})
+
+	It("should fail on conflicts", func() {
+		_, err := merge(MergeStrategyError, NewReport())
+		Expect(err).To(MatchError("machine image versions of the landscape and the lss conflict: ubuntu:20.4.0 (classification)"))
+		conflictErr, ok := err.(*MergeConflictError)
+		Expect(ok).To(BeTrue())
+		Expect(conflictErr.Conflicts).To(HaveLen(1))
+	})
+
+	It("should not report values with the same encoding as conflicts", func() {
+		Expect(DetectMergeConflicts(
+			[]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "22.4.0", "architectures": []string{"amd64"}, "cpu": 2},
+			}}},
+			[]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "22.4.0", "architectures": []interface{}{"amd64"}, "cpu": float64(2)},
+			}}},
+		)).To(BeEmpty())
+	})
+
+	It("should reject unknown strategies", func() {
+		_, err := merge("newest", NewReport())
+		Expect(err).To(MatchError("merge strategy does not exist newest"))
+	})
+})
@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-logr/logr"
+)
+
+// ProviderMachineImages are the provider configs of a provider type.
+type ProviderMachineImages struct {
+	MachineImagesProvider   []MachineImage `json:"machineImagesProvider,omitempty" yaml:"machineImagesProvider,omitempty"`
+	MachineImagesProviderLs []MachineImage `json:"machineImagesProviderLs,omitempty" yaml:"machineImagesProviderLs,omitempty"`
+}
+
+// ComputeMachineImagesMultiProvider computes the machine images of several provider types in a single pass, like
+// ComputeMachineImagesWithOptions per provider: the OS images are merged, deduplicated and filtered once, only the
+// provider configs and the following stages are applied per provider. The results are keyed by provider type and the
+// Provider of the options is ignored. Validation problems and errors of a provider are prefixed by its type. Like for
+// the imports, the lssOsImages are fetched from the os image source of the options, if one is configured.
+func ComputeMachineImagesMultiProvider(
+	ctx context.Context,
+	log logr.Logger,
+	lssOsImages []MachineImage,
+	landscapeOsImages []MachineImage,
+	providers map[string]ProviderMachineImages,
+	disableMachineImages []string,
+	includeFilters []OsImagesFilterKind,
+	excludeFilters []OsImagesFilterKind,
+	options *ComputeMachineImagesOptions,
+) (
+	map[string][]MachineImage,
+	error,
+) {
+	if options == nil {
+		options = &ComputeMachineImagesOptions{}
+	}
+	lssOsImages, err := options.sourceOsImages(ctx, log, lssOsImages)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	inputs := make([]providerInput, 0, len(names))
+	for _, name := range names {
+		inputs = append(inputs, providerInput{
+			name:            name,
+			provider:        name,
+			images:          providers[name].MachineImagesProvider,
+			landscapeImages: providers[name].MachineImagesProviderLs,
+		})
+	}
+
+	results, err := computeMachineImages(ctx, log, lssOsImages, landscapeOsImages, inputs, disableMachineImages,
+		includeFilters, excludeFilters, options)
+	if err != nil {
+		return nil, err
+	}
+	resultsByProvider := make(map[string][]MachineImage, len(names))
+	for i, name := range names {
+		resultsByProvider[name] = results[i]
+	}
+	return resultsByProvider, nil
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("multi provider computation", func() {
+
+	images := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0"}, {"version": "934.6.0"}}},
+		{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}}},
+	}
+	providers := map[string]ProviderMachineImages{
+		ProviderAWS: {MachineImagesProvider: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.7.0", "regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}},
+		}}}},
+		ProviderGCP: {
+			MachineImagesProvider: []MachineImage{
+				{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.6.0", "image": "gardenlinux-934-6"}}},
+				{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0", "image": "ubuntu-22-4"}}},
+			},
+			MachineImagesProviderLs: []MachineImage{
+				{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0", "image": "ubuntu-22-4-ls"}}},
+			},
+		},
+	}
+
+	It("should compute the same results as the computations per provider", func() {
+		options := &ComputeMachineImagesOptions{Maintenance: map[string]*MaintenancePolicy{OsNameGardenLinux: {PreviewVersions: 1}}}
+		results, err := ComputeMachineImagesMultiProvider(context.Background(), logr.Discard(), images, nil, providers,
+			[]string{"ubuntu:20.4.0"}, nil, nil, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(2))
+
+		for provider, configs := range providers {
+			providerOptions := *options
+			providerOptions.Provider = provider
+			expected, err := ComputeMachineImagesWithOptions(context.Background(), logr.Discard(), images, nil,
+				configs.MachineImagesProvider, configs.MachineImagesProviderLs, []string{"ubuntu:20.4.0"}, nil, nil, &providerOptions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[provider]).To(Equal(expected))
+		}
+		Expect(results[ProviderGCP]).To(ContainElement(MachineImage{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+			{"version": "22.4.0", "image": "ubuntu-22-4-ls"},
+		}}))
+		Expect(images[0].Versions[0]).To(Equal(MachineImageVersion{"version": "934.7.0"}))
+	})
+
+	It("should prefix the problems and errors of the providers", func() {
+		invalid := map[string]ProviderMachineImages{
+			ProviderAWS: providers[ProviderAWS],
+			ProviderGCP: {MachineImagesProvider: []MachineImage{{Versions: []MachineImageVersion{{"version": "934.6.0"}}}}},
+		}
+		_, err := ComputeMachineImagesMultiProvider(context.Background(), logr.Discard(), images, nil, invalid, nil, nil, nil, nil)
+		Expect(err).To(MatchError(ContainSubstring("gcp: machineImagesProvider[0].name: must not be empty")))
+		Expect(ClassOf(err)).To(Equal(ErrorClassValidation))
+
+		invalid[ProviderGCP] = ProviderMachineImages{MachineImagesProvider: []MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.6.0"}}},
+		}}
+		_, err = ComputeMachineImagesMultiProvider(context.Background(), logr.Discard(), images, nil, invalid, nil, nil, nil, nil)
+		Expect(err).To(MatchError(ContainSubstring("provider gcp: ")))
+		Expect(ClassOf(err)).To(Equal(ErrorClassValidation))
+	})
+
+	It("should return empty results for disabled providers and report the provider of the findings", func() {
+		report := NewReport()
+		results, err := ComputeMachineImagesMultiProvider(context.Background(), logr.Discard(), images, nil, providers,
+			nil, nil, nil, &ComputeMachineImagesOptions{DisableProviders: []string{ProviderAWS, ProviderGCP}, Reporter: report})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results[ProviderAWS]).To(BeEmpty())
+		Expect(results[ProviderGCP]).To(BeEmpty())
+		Expect(report.Entries()).To(Equal([]ReportEntry{
+			{Provider: ProviderAWS, Image: OsNameGardenLinux, Version: "934.6.0", Reason: ReasonNoProviderConfig, Message: "no provider config found"},
+			{Provider: ProviderAWS, Image: OsNameUbuntu, Version: "22.4.0", Reason: ReasonNoProviderConfig, Message: "no provider config found"},
+			{Provider: ProviderAWS, Image: OsNameGardenLinux, Version: "934.7.0", Reason: ReasonProviderDisabled, Message: "provider aws is disabled"},
+			{Provider: ProviderGCP, Image: OsNameGardenLinux, Version: "934.7.0", Reason: ReasonNoProviderConfig, Message: "no provider config found"},
+			{Provider: ProviderGCP, Image: OsNameGardenLinux, Version: "934.6.0", Reason: ReasonProviderDisabled, Message: "provider gcp is disabled"},
+			{Provider: ProviderGCP, Image: OsNameUbuntu, Version: "22.4.0", Reason: ReasonProviderDisabled, Message: "provider gcp is disabled"},
+		}))
+	})
+})
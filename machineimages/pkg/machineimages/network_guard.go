@@ -0,0 +1,73 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// NetworkAccessDeniedError is returned by all code paths which would access the network while the network policy
+// guard is enabled.
+type NetworkAccessDeniedError struct {
+	// Operation describes the denied operation, e.g. "GET" or "resolve".
+	Operation string
+	// Target is the address or url which would have been accessed.
+	Target string
+}
+
+func (e *NetworkAccessDeniedError) Error() string {
+	return fmt.Sprintf("network access denied by network policy guard: %s %s", e.Operation, e.Target)
+}
+
+// IsNetworkAccessDenied returns whether the error or one of the errors it wraps is a NetworkAccessDeniedError.
+func IsNetworkAccessDenied(err error) bool {
+	var denied *NetworkAccessDeniedError
+	return errors.As(err, &denied)
+}
+
+type networkPolicyGuardKey struct{}
+
+// WithNetworkPolicyGuard returns a context in which all network access of this package is denied.
+func WithNetworkPolicyGuard(ctx context.Context) context.Context {
+	return context.WithValue(ctx, networkPolicyGuardKey{}, true)
+}
+
+// NetworkPolicyGuardEnabled returns whether network access is denied in the given context.
+func NetworkPolicyGuardEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(networkPolicyGuardKey{}).(bool)
+	return enabled
+}
+
+// CheckNetworkAccess must be called before any network access. It returns a NetworkAccessDeniedError if the network
+// policy guard is enabled in the given context.
+func CheckNetworkAccess(ctx context.Context, operation, target string) error {
+	if NetworkPolicyGuardEnabled(ctx) {
+		return &NetworkAccessDeniedError{Operation: operation, Target: target}
+	}
+	return nil
+}
+
+// NewGuardedTransport returns a transport which denies all requests whose context has the network policy guard
+// enabled. If base is nil, http.DefaultTransport is used.
+func NewGuardedTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &guardedTransport{base: base}
+}
+
+type guardedTransport struct {
+	base http.RoundTripper
+}
+
+func (t *guardedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := CheckNetworkAccess(req.Context(), req.Method, req.URL.String()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
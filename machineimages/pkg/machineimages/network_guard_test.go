@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("network policy guard", func() {
+
+	It("should deny network access only if enabled", func() {
+		Expect(CheckNetworkAccess(context.Background(), "GET", "https://example.com")).To(Succeed())
+
+		ctx := WithNetworkPolicyGuard(context.Background())
+		err := CheckNetworkAccess(ctx, "GET", "https://example.com")
+		Expect(err).To(HaveOccurred())
+		Expect(IsNetworkAccessDenied(err)).To(BeTrue())
+		Expect(IsNetworkAccessDenied(fmt.Errorf("wrapped: %w", err))).To(BeTrue())
+		Expect(IsNetworkAccessDenied(fmt.Errorf("other"))).To(BeFalse())
+	})
+
+	It("should deny requests of the guarded transport", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client := &http.Client{Transport: NewGuardedTransport(nil)}
+
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := client.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+
+		req, err = http.NewRequestWithContext(WithNetworkPolicyGuard(context.Background()), http.MethodGet, server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Do(req)
+		Expect(IsNetworkAccessDenied(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,327 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Notifications routes the notifications about computations to the maintainers of the OS names, so that every team
+// only receives what concerns its images.
+type Notifications struct {
+	// Maintainers are the contacts per OS name, e.g. gardenlinux. Contacts with the same url receive one notification
+	// for all their OS names.
+	Maintainers map[string]*MaintainerContact `json:"maintainers,omitempty" yaml:"maintainers,omitempty"`
+	// Default receives the notifications about OS names without maintainers and about failed computations. Without
+	// default contact, failed computations are sent to all maintainers and all other notifications are dropped.
+	Default *MaintainerContact `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// MaintainerContact is an http endpoint, usually the incoming webhook of a team channel, which receives notifications
+// as json.
+type MaintainerContact struct {
+	// Team names the maintainers in the notifications.
+	Team string `json:"team,omitempty" yaml:"team,omitempty"`
+	URL  string `json:"url" yaml:"url"`
+	// Token is sent as bearer token, if set.
+	Token *SecretValue `json:"token,omitempty" yaml:"token,omitempty"`
+}
+
+// Notification informs the maintainers of some OS names about a computation.
+type Notification struct {
+	Landscape string `json:"landscape,omitempty"`
+	Team      string `json:"team,omitempty"`
+	// Text summarizes the notification, so that chat webhooks which only render a text field are readable.
+	Text string `json:"text"`
+	// OsNames are the OS names about which the notification informs, in lexical order. Findings without image are
+	// listed under an empty name.
+	OsNames []string `json:"osNames"`
+	// Changes are the changed versions of the OS names.
+	Changes *MachineImagesDiff `json:"changes,omitempty"`
+	// Score is the impact score of the changes. It is only set by NotifyMaintainers.
+	Score *ImpactScore `json:"score,omitempty"`
+	// Findings are the report entries of the OS names.
+	Findings []ReportEntry `json:"findings,omitempty"`
+	// Error is the error of a failed computation.
+	Error string `json:"error,omitempty"`
+	// Key identifies the content of the notification, so that repeated notifications can be deduplicated.
+	Key string `json:"key"`
+
+	contact *MaintainerContact
+}
+
+// SentNotifications remembers the notifications which were sent last, so that repeated computations with the same
+// outcome do not notify the maintainers again.
+type SentNotifications struct {
+	// Keys map the hashes of the contact urls, which may contain secrets, to the key of the notification which was
+	// sent last.
+	Keys map[string]string `json:"keys"`
+}
+
+// Validate returns an error for the first contact without url.
+func (n *Notifications) Validate() error {
+	names := make([]string, 0, len(n.Maintainers))
+	for name := range n.Maintainers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if len(name) == 0 {
+			return errors.New("maintainers must not contain an empty os name")
+		}
+		if contact := n.Maintainers[name]; contact == nil || len(contact.URL) == 0 {
+			return fmt.Errorf("url of the maintainers of %s must be set", name)
+		}
+	}
+	if n.Default != nil && len(n.Default.URL) == 0 {
+		return errors.New("url of the default contact must be set")
+	}
+	return nil
+}
+
+// ResolveSecrets resolves the tokens of all contacts.
+func (n *Notifications) ResolveSecrets(ctx context.Context, resolver *SecretResolver) error {
+	for name, contact := range n.Maintainers {
+		if contact == nil {
+			continue
+		}
+		if err := contact.Token.Resolve(ctx, resolver); err != nil {
+			return fmt.Errorf("unable to resolve token of the maintainers of %s: %w", name, err)
+		}
+	}
+	if n.Default != nil {
+		if err := n.Default.Token.Resolve(ctx, resolver); err != nil {
+			return fmt.Errorf("unable to resolve token of the default contact: %w", err)
+		}
+	}
+	return nil
+}
+
+// contact returns the contact of the OS name, or nil if the notification is dropped.
+func (n *Notifications) contact(osName string) *MaintainerContact {
+	if contact, ok := n.Maintainers[osName]; ok && contact != nil {
+		return contact
+	}
+	return n.Default
+}
+
+// RouteNotifications splits the changes and findings of a computation by the contacts of their OS names. A non-nil
+// computeErr is routed to the default contact, or to all maintainers without default contact. Contacts without
+// changes, findings or error receive no notification. The notifications of the maintainers are ordered by their first
+// OS name, the one of the default contact comes last.
+func RouteNotifications(notifications *Notifications, landscape string, diff *MachineImagesDiff, findings []ReportEntry, computeErr error) []*Notification {
+	byURL := map[string]*Notification{}
+	get := func(contact *MaintainerContact) *Notification {
+		if notification, ok := byURL[contact.URL]; ok {
+			return notification
+		}
+		notification := &Notification{
+			Landscape: landscape,
+			Team:      contact.Team,
+			OsNames:   []string{},
+			Changes:   &MachineImagesDiff{Added: []VersionRef{}, Removed: []VersionRef{}, Changed: []VersionRef{}},
+			contact:   contact,
+		}
+		byURL[contact.URL] = notification
+		return notification
+	}
+	route := func(osName string) *Notification {
+		contact := notifications.contact(osName)
+		if contact == nil {
+			return nil
+		}
+		notification := get(contact)
+		if !contains(notification.OsNames, osName) {
+			notification.OsNames = append(notification.OsNames, osName)
+		}
+		return notification
+	}
+
+	if diff != nil {
+		for _, ref := range diff.Added {
+			if n := route(ref.Image); n != nil {
+				n.Changes.Added = append(n.Changes.Added, ref)
+			}
+		}
+		for _, ref := range diff.Removed {
+			if n := route(ref.Image); n != nil {
+				n.Changes.Removed = append(n.Changes.Removed, ref)
+			}
+		}
+		for _, ref := range diff.Changed {
+			if n := route(ref.Image); n != nil {
+				n.Changes.Changed = append(n.Changes.Changed, ref)
+			}
+		}
+		for _, supersession := range diff.Superseded {
+			if n := route(supersession.Removed.Image); n != nil {
+				n.Changes.Superseded = append(n.Changes.Superseded, supersession)
+			}
+		}
+	}
+	for _, entry := range findings {
+		if n := route(entry.Image); n != nil {
+			n.Findings = append(n.Findings, entry)
+		}
+	}
+	if computeErr != nil {
+		contacts := []*MaintainerContact{notifications.Default}
+		if notifications.Default == nil {
+			contacts = contacts[:0]
+			for _, contact := range notifications.Maintainers {
+				if contact != nil {
+					contacts = append(contacts, contact)
+				}
+			}
+		}
+		for _, contact := range contacts {
+			get(contact).Error = computeErr.Error()
+		}
+	}
+
+	result := make([]*Notification, 0, len(byURL))
+	var defaultNotification *Notification
+	for _, notification := range byURL {
+		sort.Strings(notification.OsNames)
+		if notification.Changes.Empty() {
+			notification.Changes = nil
+		}
+		notification.finish()
+		if notifications.Default != nil && notification.contact.URL == notifications.Default.URL {
+			defaultNotification = notification
+			continue
+		}
+		result = append(result, notification)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return firstOsName(result[i]) < firstOsName(result[j])
+	})
+	if defaultNotification != nil {
+		result = append(result, defaultNotification)
+	}
+	return result
+}
+
+func firstOsName(notification *Notification) string {
+	if len(notification.OsNames) == 0 {
+		return ""
+	}
+	return notification.OsNames[0]
+}
+
+// finish sets the text and the key of the notification.
+func (n *Notification) finish() {
+	n.Text = n.summary()
+	n.Key = ""
+	data, err := json.Marshal(n)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+	n.Key = hex.EncodeToString(sum[:8])
+}
+
+// summary returns the text of the notification.
+func (n *Notification) summary() string {
+	subject := "machine images"
+	if names := strings.Join(n.OsNames, ", "); len(names) > 0 {
+		subject = "machine images " + names
+	}
+	if len(n.Landscape) > 0 {
+		subject += " of landscape " + n.Landscape
+	}
+
+	parts := []string{}
+	if n.Changes != nil {
+		parts = append(parts, fmt.Sprintf("%d added, %d removed and %d changed versions",
+			len(n.Changes.Added), len(n.Changes.Removed), len(n.Changes.Changed)))
+	}
+	if n.Score != nil {
+		if n.Score.AffectedShoots > 0 {
+			parts = append(parts, fmt.Sprintf("%d affected shoots", n.Score.AffectedShoots))
+		}
+		if len(n.Score.Providers) > 0 {
+			parts = append(parts, "touched providers "+strings.Join(n.Score.Providers, ", "))
+		}
+	}
+	if len(n.Findings) > 0 {
+		parts = append(parts, fmt.Sprintf("%d findings", len(n.Findings)))
+	}
+	if len(n.Error) > 0 {
+		parts = append(parts, "computation failed: "+n.Error)
+	}
+	return subject + ": " + strings.Join(parts, ", ")
+}
+
+// SendNotifications posts every notification to its contact. All notifications are sent, also if some fail.
+func SendNotifications(ctx context.Context, client *http.Client, notifications []*Notification) error {
+	return sendNotifications(ctx, client, notifications, nil)
+}
+
+// sendNotifications posts the notifications. With sent notifications, the notifications which a contact received last
+// are skipped and the sent notifications are updated to the notifications of this call.
+func sendNotifications(ctx context.Context, client *http.Client, notifications []*Notification, sent *SentNotifications) error {
+	log := LoggerFromContext(ctx)
+	keys := map[string]string{}
+	failed := []string{}
+	for _, notification := range notifications {
+		contact := notification.contact
+		sum := sha256.Sum256([]byte(contact.URL))
+		contactKey := hex.EncodeToString(sum[:8])
+		if sent != nil && sent.Keys[contactKey] == notification.Key {
+			log.Info("Skipping notification which was sent before", "team", contact.Team, "key", notification.Key)
+			keys[contactKey] = notification.Key
+			continue
+		}
+
+		token, err := contact.Token.Secret()
+		if err == nil {
+			log.Info("Sending notification", "team", contact.Team, "osNames", strings.Join(notification.OsNames, ","))
+			err = postAuthorizedJSON(ctx, client, "send notification", contact.URL, token, notification, nil)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", contact.URL, err))
+			continue
+		}
+		keys[contactKey] = notification.Key
+	}
+	if sent != nil {
+		sent.Keys = keys
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to send notifications: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// NotifyMaintainers sends the changes from the applied to the computed machine images with their impact score, the
+// findings and the error of a computation to the maintainers. The computed machine images are ignored if computeErr is
+// set. The shoots are optional and only counted in the impact scores, see ScoreImpact. If sent notifications are given,
+// the notifications which the contacts received last are not sent again, see SentNotifications.
+func NotifyMaintainers(ctx context.Context, notifications *Notifications, landscape string, applied, computed []MachineImage, shoots []ShootMachineImage, findings []ReportEntry, computeErr error, sent *SentNotifications) error {
+	if notifications == nil {
+		return nil
+	}
+	var diff *MachineImagesDiff
+	if computeErr == nil {
+		diff = DiffMachineImages(applied, computed)
+	}
+	routed := RouteNotifications(notifications, landscape, diff, findings, computeErr)
+	for _, notification := range routed {
+		if notification.Changes != nil {
+			notification.Score = ScoreImpact(notification.Changes, applied, computed, shoots)
+			notification.finish()
+		}
+	}
+	return sendNotifications(ctx, nil, routed, sent)
+}
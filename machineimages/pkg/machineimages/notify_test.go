@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("notifications", func() {
+
+	var notifications *Notifications
+
+	BeforeEach(func() {
+		notifications = &Notifications{
+			Maintainers: map[string]*MaintainerContact{
+				OsNameGardenLinux: {Team: "gardenlinux", URL: "https://chat.example/gardenlinux"},
+				"suse-chost":      {Team: "suse", URL: "https://chat.example/suse"},
+				"suse-sles":       {Team: "suse", URL: "https://chat.example/suse"},
+			},
+			Default: &MaintainerContact{Team: "operators", URL: "https://chat.example/operators"},
+		}
+	})
+
+	It("should route changes and findings to the maintainers of their os names", func() {
+		diff := &MachineImagesDiff{
+			Added:   []VersionRef{{Image: OsNameGardenLinux, Version: "934.8.0"}, {Image: "suse-sles", Version: "15.4.0"}},
+			Removed: []VersionRef{{Image: "ubuntu", Version: "20.4.0"}},
+			Changed: []VersionRef{{Image: "suse-chost", Version: "15.3.0"}},
+		}
+		findings := []ReportEntry{
+			{Image: OsNameGardenLinux, Version: "934.6.0", Reason: ReasonExpired},
+			{Reason: ReasonUnmatchedDisablePattern},
+		}
+
+		routed := RouteNotifications(notifications, "dev", diff, findings, nil)
+		Expect(routed).To(HaveLen(3))
+
+		Expect(routed[0].Team).To(Equal("gardenlinux"))
+		Expect(routed[0].OsNames).To(Equal([]string{OsNameGardenLinux}))
+		Expect(routed[0].Changes.Added).To(Equal([]VersionRef{{Image: OsNameGardenLinux, Version: "934.8.0"}}))
+		Expect(routed[0].Findings).To(Equal(findings[:1]))
+		Expect(routed[0].Text).To(Equal("machine images gardenlinux of landscape dev: 1 added, 0 removed and 0 changed versions, 1 findings"))
+
+		Expect(routed[1].Team).To(Equal("suse"))
+		Expect(routed[1].OsNames).To(Equal([]string{"suse-chost", "suse-sles"}))
+		Expect(routed[1].Changes.Added).To(HaveLen(1))
+		Expect(routed[1].Changes.Changed).To(HaveLen(1))
+		Expect(routed[1].Findings).To(BeEmpty())
+
+		Expect(routed[2].Team).To(Equal("operators"))
+		Expect(routed[2].OsNames).To(Equal([]string{"", "ubuntu"}))
+		Expect(routed[2].Changes.Removed).To(Equal([]VersionRef{{Image: "ubuntu", Version: "20.4.0"}}))
+		Expect(routed[2].Findings).To(Equal(findings[1:]))
+	})
+
+	It("should only notify contacts with news", func() {
+		diff := &MachineImagesDiff{Added: []VersionRef{{Image: OsNameGardenLinux, Version: "934.8.0"}}}
+
+		routed := RouteNotifications(notifications, "", diff, nil, nil)
+		Expect(routed).To(HaveLen(1))
+		Expect(routed[0].Team).To(Equal("gardenlinux"))
+		Expect(RouteNotifications(notifications, "", &MachineImagesDiff{}, nil, nil)).To(BeEmpty())
+	})
+
+	It("should drop notifications without contact", func() {
+		notifications.Default = nil
+		diff := &MachineImagesDiff{Removed: []VersionRef{{Image: "ubuntu", Version: "20.4.0"}}}
+
+		Expect(RouteNotifications(notifications, "", diff, nil, nil)).To(BeEmpty())
+	})
+
+	It("should route failed computations to the default contact", func() {
+		routed := RouteNotifications(notifications, "", nil, nil, errors.New("invalid cri"))
+		Expect(routed).To(HaveLen(1))
+		Expect(routed[0].Team).To(Equal("operators"))
+		Expect(routed[0].Error).To(Equal("invalid cri"))
+		Expect(routed[0].Changes).To(BeNil())
+		Expect(routed[0].Text).To(Equal("machine images: computation failed: invalid cri"))
+	})
+
+	It("should route failed computations to all maintainers without default contact", func() {
+		notifications.Default = nil
+
+		routed := RouteNotifications(notifications, "", nil, nil, errors.New("invalid cri"))
+		Expect(routed).To(HaveLen(2))
+		for _, notification := range routed {
+			Expect(notification.Error).To(Equal("invalid cri"))
+		}
+	})
+
+	It("should validate the contacts", func() {
+		Expect(notifications.Validate()).To(Succeed())
+
+		notifications.Maintainers["ubuntu"] = &MaintainerContact{Team: "ubuntu"}
+		Expect(notifications.Validate()).To(MatchError("url of the maintainers of ubuntu must be set"))
+	})
+
+	It("should post the notifications and ignore the responses", func() {
+		var (
+			lock     sync.Mutex
+			received = map[string]*Notification{}
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			notification := &Notification{}
+			Expect(json.NewDecoder(r.Body).Decode(notification)).To(Succeed())
+			lock.Lock()
+			received[r.URL.Path] = notification
+			lock.Unlock()
+			_, _ = w.Write([]byte("ok"))
+		}))
+		defer server.Close()
+
+		notifications.Maintainers[OsNameGardenLinux].URL = server.URL + "/gardenlinux"
+		notifications.Default.URL = server.URL + "/operators"
+		applied := []MachineImage{{Name: "ubuntu", Versions: []MachineImageVersion{{"version": "20.4.0"}}}}
+		computed := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.8.0"}}}}
+		shoots := []ShootMachineImage{{Shoot: "garden-dev/a", Image: "ubuntu", Version: "20.4.0"}}
+
+		Expect(NotifyMaintainers(context.Background(), notifications, "dev", applied, computed, shoots, nil, nil, nil)).To(Succeed())
+		Expect(received).To(HaveLen(2))
+		Expect(received["/gardenlinux"].Changes.Added).To(Equal([]VersionRef{{Image: OsNameGardenLinux, Version: "934.8.0"}}))
+		Expect(received["/gardenlinux"].Score).To(Equal(&ImpactScore{AddedVersions: 1, Providers: []string{}}))
+		Expect(received["/operators"].Changes.Removed).To(Equal([]VersionRef{{Image: "ubuntu", Version: "20.4.0"}}))
+		Expect(received["/operators"].Score).To(Equal(&ImpactScore{RemovedVersions: 1, AffectedShoots: 1, Providers: []string{}}))
+		Expect(received["/operators"].Text).To(Equal("machine images ubuntu of landscape dev: 0 added, 1 removed and 0 changed versions, 1 affected shoots"))
+	})
+
+	It("should send all notifications and return the failures", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/gardenlinux" {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}))
+		defer server.Close()
+
+		notifications.Maintainers[OsNameGardenLinux].URL = server.URL + "/gardenlinux"
+		notifications.Default.URL = server.URL + "/operators"
+		diff := &MachineImagesDiff{Added: []VersionRef{{Image: OsNameGardenLinux, Version: "934.8.0"}, {Image: "ubuntu", Version: "20.4.0"}}}
+		err := SendNotifications(context.Background(), nil, RouteNotifications(notifications, "", diff, nil, nil))
+		Expect(err).To(MatchError(ContainSubstring("/gardenlinux")))
+		Expect(err).NotTo(MatchError(ContainSubstring("/operators")))
+	})
+
+	It("should not send the notifications which the contacts received last", func() {
+		var (
+			lock     sync.Mutex
+			received = []string{}
+		)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			received = append(received, r.URL.Path)
+			lock.Unlock()
+		}))
+		defer server.Close()
+
+		notifications.Maintainers[OsNameGardenLinux].URL = server.URL + "/gardenlinux"
+		notifications.Default.URL = server.URL + "/operators"
+		sent := &SentNotifications{}
+		notify := func(computed []MachineImage, findings []ReportEntry) {
+			received = []string{}
+			Expect(NotifyMaintainers(context.Background(), notifications, "dev", nil, computed, nil, findings, nil, sent)).To(Succeed())
+		}
+		computed := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.8.0"}}}}
+		findings := []ReportEntry{{Reason: ReasonUnmatchedDisablePattern}}
+
+		notify(computed, findings)
+		Expect(received).To(ConsistOf("/gardenlinux", "/operators"))
+		Expect(sent.Keys).To(HaveLen(2))
+		notify(computed, findings)
+		Expect(received).To(BeEmpty())
+		notify(computed, nil)
+		Expect(received).To(BeEmpty())
+		Expect(sent.Keys).To(HaveLen(1))
+		notify(computed, findings)
+		Expect(received).To(Equal([]string{"/operators"}))
+	})
+})
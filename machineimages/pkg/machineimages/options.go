@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ComputeMachineImagesOptions contains optional settings for the computation of machine images.
+type ComputeMachineImagesOptions struct {
+	// MergeStrategy determines how versions which are part of the landscape and the LSS list are merged and whether
+	// conflicting fields are reported. Without strategy, the fields are merged like with MergeStrategyOverlay and
+	// conflicts are not reported.
+	MergeStrategy MergeStrategy `json:"mergeStrategy,omitempty" yaml:"mergeStrategy,omitempty"`
+	// CRIMergeStrategy determines how the cri lists of versions which are merged with MergeStrategyOverlay are merged.
+	// Defaults to CRIMergeStrategyOverride.
+	CRIMergeStrategy CRIMergeStrategy `json:"criMergeStrategy,omitempty" yaml:"criMergeStrategy,omitempty"`
+	// FieldMappings map the sources of the imports, see FieldMappingSources, to the mapping of their versions into
+	// the internal model. Sources without mapping must use the internal field names.
+	FieldMappings map[string]*FieldMapping `json:"fieldMappings,omitempty" yaml:"fieldMappings,omitempty"`
+	// StrictDisableMachineImages fails the computation with an *UnmatchedDisablePatternsError if patterns of the
+	// disabled machine images match nothing, e.g. because of a typo. Otherwise, unmatched patterns are reported.
+	StrictDisableMachineImages bool `json:"strictDisableMachineImages,omitempty" yaml:"strictDisableMachineImages,omitempty"`
+	// Sort determines the order of the images and versions of the result. Defaults to gardenlinux first, the other
+	// images in alphabetical order and descending versions.
+	Sort *SortOptions `json:"sort,omitempty" yaml:"sort,omitempty"`
+	// RequiredImages are the names of machine images which must be contained in the result with at least one version.
+	RequiredImages []string `json:"requiredImages,omitempty" yaml:"requiredImages,omitempty"`
+	// MinVersions maps image names to the lowest version which may be contained in the result.
+	MinVersions map[string]string `json:"minVersions,omitempty" yaml:"minVersions,omitempty"`
+	// MinVersionsAction determines whether versions lower than their minimum version are dropped or cause an error.
+	// Defaults to PolicyActionDrop.
+	MinVersionsAction PolicyAction `json:"minVersionsAction,omitempty" yaml:"minVersionsAction,omitempty"`
+	// LatestPerMinor keeps only the newest LatestPerMinor versions of every minor line of each image, e.g. the newest
+	// two patch versions of 934.1. Zero keeps all versions.
+	LatestPerMinor int `json:"latestPerMinor,omitempty" yaml:"latestPerMinor,omitempty"`
+	// Budget limits the number of versions in the result.
+	Budget *VersionBudget `json:"budget,omitempty" yaml:"budget,omitempty"`
+	// Maintenance maps image names to the policies which classify their versions as preview, supported or deprecated.
+	// End of life dates and incidents still deprecate versions of images with a policy.
+	Maintenance map[string]*MaintenancePolicy `json:"maintenance,omitempty" yaml:"maintenance,omitempty"`
+	// VersionAliases are resolved to versions of the result and exported, e.g. latest. The Terraform variables and the
+	// Cluster API image lookup of the CLI contain entries for the aliases.
+	VersionAliases []VersionAlias `json:"versionAliases,omitempty" yaml:"versionAliases,omitempty"`
+	// EndOfLife deprecates and removes versions according to the end of life dates of their vendors.
+	EndOfLife *EndOfLifePolicy `json:"endOfLife,omitempty" yaml:"endOfLife,omitempty"`
+	// DropExpiredVersions removes versions which are expired at the ExpirationReferenceTime, before the filters are
+	// applied.
+	DropExpiredVersions bool `json:"dropExpiredVersions,omitempty" yaml:"dropExpiredVersions,omitempty"`
+	// ExpirationReferenceTime is the reference time of DropExpiredVersions. Defaults to the time of the computation.
+	ExpirationReferenceTime *time.Time `json:"expirationReferenceTime,omitempty" yaml:"expirationReferenceTime,omitempty"`
+	// NormalizeVersions replaces the versions of the result by their normalized form "<major>.<minor>.<patch>", e.g.
+	// "934.1" by "934.1.0", and the Garden Linux flavors by their canonical form, see GardenLinuxFlavor. Provider
+	// configs, incidents and end of life dates are still matched with the original versions.
+	NormalizeVersions bool `json:"normalizeVersions,omitempty" yaml:"normalizeVersions,omitempty"`
+	// StrictVersionFields rejects versions of the image lists with fields which are not known, see
+	// ValidateMachineImagesStrict. The artifact reference field of the ArtifactProbe is known.
+	StrictVersionFields bool `json:"strictVersionFields,omitempty" yaml:"strictVersionFields,omitempty"`
+	// Provider is the provider type of the provider configs, e.g. aws. If set, the provider configs of the versions
+	// must be valid image mappings of the provider, see DecodeProviderMapping.
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty"`
+	// DisableProviders are provider types, e.g. aws, whose output is suppressed: if the provider of the computation is
+	// disabled, its result is empty, and the partitions of disabled providers are not written. The suppressed versions
+	// are reported.
+	DisableProviders []string `json:"disableProviders,omitempty" yaml:"disableProviders,omitempty"`
+	// ProviderEnabled enables or disables the output of provider types individually, e.g. {aws: false}. A provider is
+	// disabled if it is disabled here or contained in DisableProviders.
+	ProviderEnabled map[string]bool `json:"providerEnabled,omitempty" yaml:"providerEnabled,omitempty"`
+	// RegionScope scopes the machine images to the active regions of the landscape.
+	RegionScope *RegionScope `json:"regionScope,omitempty" yaml:"regionScope,omitempty"`
+	// ArtifactProbe checks that the OCI artifacts of versions exist before they are advertised.
+	ArtifactProbe *ArtifactProbe `json:"artifactProbe,omitempty" yaml:"artifactProbe,omitempty"`
+	// SizeLimits enables the size estimation of a CloudProfile with the resulting machine images. A warning is logged
+	// or an error returned if the estimated size exceeds the limits.
+	SizeLimits *SizeLimits `json:"sizeLimits,omitempty" yaml:"sizeLimits,omitempty"`
+	// NetworkPolicyGuard denies all network access during the computation. Code paths which would access the network
+	// return a NetworkAccessDeniedError instead.
+	NetworkPolicyGuard bool `json:"networkPolicyGuard,omitempty" yaml:"networkPolicyGuard,omitempty"`
+	// Plugins transform the machine images in separate processes, in their order, after the incidents are applied.
+	Plugins []*Plugin `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	// Focus restricts the computation to the images with the given names, e.g. while the configuration of a single
+	// image is changed. The other images are neither validated nor computed, the exports take them from the focus
+	// baseline, see MergeFocusedBaseline.
+	Focus []string `json:"focus,omitempty" yaml:"focus,omitempty"`
+	// FocusBaseline are the previously applied exports, e.g. of the state store, into which the focused images are
+	// merged.
+	FocusBaseline *Exports `json:"-" yaml:"-"`
+	// OsImageSource provides the machineImages of the imports, e.g. from a remote catalog. It takes precedence over
+	// RemoteOsImages.
+	OsImageSource OsImageSource `json:"-" yaml:"-"`
+	// RemoteOsImages fetches the machineImages of the imports from an http endpoint or an OCI artifact, if
+	// OsImageSource is not set. The machineImages of the imports must then be empty.
+	RemoteOsImages *RemoteOsImages `json:"remoteOsImages,omitempty" yaml:"remoteOsImages,omitempty"`
+	// Incidents provides incidents. Versions implicated in an incident are deprecated in the result.
+	Incidents IncidentSource `json:"-" yaml:"-"`
+	// IncidentsWebhook configures a WebhookIncidentSource, if Incidents is not set.
+	IncidentsWebhook *IncidentsWebhook `json:"incidentsWebhook,omitempty" yaml:"incidentsWebhook,omitempty"`
+	// Notifications sends the changes, findings and failures of computations to the maintainers of the OS names. They
+	// are sent by the CLI, see NotifyMaintainers.
+	Notifications *Notifications `json:"notifications,omitempty" yaml:"notifications,omitempty"`
+	// Diff ignores cosmetic differences of versions when the CLI compares machine images, e.g. for approvals,
+	// notifications, the revision history, cloud profile diffs and audits. Exports which only differ cosmetically from
+	// the previous exports keep the previous machine images, see KeepCosmeticExports.
+	Diff *DiffOptions `json:"diff,omitempty" yaml:"diff,omitempty"`
+	// ReportLogSampling limits how many findings of every reason are logged. All findings are passed to the Reporter.
+	ReportLogSampling *ReportLogSampling `json:"reportLogSampling,omitempty" yaml:"reportLogSampling,omitempty"`
+	// Reporter receives the findings of the computation, e.g. dropped versions. It is also available to nested stages
+	// via ReporterFromContext.
+	Reporter ReportSink `json:"-" yaml:"-"`
+}
+
+// IncidentsWebhook configures an http endpoint which returns a json list of incidents.
+type IncidentsWebhook struct {
+	URL string `json:"url" yaml:"url"`
+	// Token is sent as bearer token.
+	Token *SecretValue `json:"token,omitempty" yaml:"token,omitempty"`
+}
+
+// ResolveSecrets resolves all referenced secret values of the options.
+func (o *ComputeMachineImagesOptions) ResolveSecrets(ctx context.Context, resolver *SecretResolver) error {
+	if o.IncidentsWebhook != nil {
+		if err := o.IncidentsWebhook.Token.Resolve(ctx, resolver); err != nil {
+			return fmt.Errorf("unable to resolve token of incidents webhook: %w", err)
+		}
+	}
+	if o.ArtifactProbe != nil {
+		if err := o.ArtifactProbe.Token.Resolve(ctx, resolver); err != nil {
+			return fmt.Errorf("unable to resolve token of artifact probe: %w", err)
+		}
+	}
+	if o.RemoteOsImages != nil {
+		if err := o.RemoteOsImages.ResolveSecrets(ctx, resolver); err != nil {
+			return err
+		}
+	}
+	if o.Notifications != nil {
+		if err := o.Notifications.ResolveSecrets(ctx, resolver); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// knownVersionFields returns the fields of versions which StrictVersionFields accepts.
+func (o *ComputeMachineImagesOptions) knownVersionFields() []string {
+	fields := KnownVersionFields()
+	if o.ArtifactProbe != nil && len(o.ArtifactProbe.ReferenceField) > 0 {
+		fields = append(fields, o.ArtifactProbe.ReferenceField)
+	}
+	return fields
+}
+
+// ResolveVersionAliases resolves the version aliases of the options for the images at the ExpirationReferenceTime,
+// which defaults to the current time.
+func (o *ComputeMachineImagesOptions) ResolveVersionAliases(images []MachineImage) (VersionAliases, error) {
+	now := time.Now()
+	if o.ExpirationReferenceTime != nil {
+		now = *o.ExpirationReferenceTime
+	}
+	return ResolveVersionAliases(images, o.VersionAliases, now)
+}
+
+// ProviderDisabled returns whether the output for the provider type is disabled, see DisableProviders and
+// ProviderEnabled.
+func (o *ComputeMachineImagesOptions) ProviderDisabled(provider string) bool {
+	if enabled, ok := o.ProviderEnabled[provider]; ok && !enabled {
+		return true
+	}
+	return contains(o.DisableProviders, provider)
+}
+
+// DisabledProviders returns the disabled provider types in lexical order.
+func (o *ComputeMachineImagesOptions) DisabledProviders() []string {
+	disabled := map[string]bool{}
+	for _, provider := range o.DisableProviders {
+		disabled[provider] = true
+	}
+	for provider, enabled := range o.ProviderEnabled {
+		if !enabled {
+			disabled[provider] = true
+		}
+	}
+	result := []string{}
+	for provider := range disabled {
+		result = append(result, provider)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// incidentSource returns the configured incident source.
+func (o *ComputeMachineImagesOptions) incidentSource() (IncidentSource, error) {
+	if o.Incidents != nil || o.IncidentsWebhook == nil {
+		return o.Incidents, nil
+	}
+
+	token, err := o.IncidentsWebhook.Token.Secret()
+	if err != nil {
+		return nil, fmt.Errorf("invalid token of incidents webhook: %w", err)
+	}
+	return &WebhookIncidentSource{URL: o.IncidentsWebhook.URL, Token: token}, nil
+}
+
+// osImageSource returns the configured source of the machineImages of the imports, or nil.
+func (o *ComputeMachineImagesOptions) osImageSource() (OsImageSource, error) {
+	if o.OsImageSource != nil || o.RemoteOsImages == nil {
+		return o.OsImageSource, nil
+	}
+	return o.RemoteOsImages.Source()
+}
+
+// checkRequiredImages returns an error listing all required images which have no version in the result.
+func checkRequiredImages(machineImages []MachineImage, requiredImages []string) error {
+	missing := []string{}
+	for _, name := range requiredImages {
+		found := false
+		for _, image := range machineImages {
+			if image.Name == name && len(image.Versions) > 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("required machine images have no versions in the result: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
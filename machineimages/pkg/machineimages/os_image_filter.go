@@ -5,7 +5,11 @@
 package machineimages
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 )
 
 type OsImagesFilterKind string
@@ -24,6 +28,22 @@ const (
 	OsImagesFilterKindMemoryoneChost = OsImagesFilterKind("memoryone-chost")
 )
 
+// OsImagesFilterKinds are the built-in filter kinds without parameters in the order in which they are documented.
+// Further kinds can be registered with RegisterOsImagesFilterType or RegisterOsImagesFilter.
+var OsImagesFilterKinds = []OsImagesFilterKind{
+	OsImagesFilterKindAll,
+	OsImagesFilterKindOutdated,
+	OsImagesFilterKindPreview,
+	OsImagesFilterKindSupported,
+	OsImagesFilterKindDeprecated,
+	OsImagesFilterKindGardenlinux,
+	OsImagesFilterKindSuseChost,
+	OsImagesFilterKindUbuntu,
+	OsImagesFilterKindCoreos,
+	OsImagesFilterKindFlatcar,
+	OsImagesFilterKindMemoryoneChost,
+}
+
 const (
 	ClassificationDeprecated = "deprecated"
 	ClassificationPreview    = "preview"
@@ -39,21 +59,26 @@ const (
 	OsNameMemoryoneChost = "memoryone-chost"
 )
 
+// OsImageFilter matches versions of images. Filters are created from their kind by an OsImagesFilterType.
 type OsImageFilter interface {
-	match(image OsImage) (bool, error)
+	// Match returns whether the version of the image matches the filter.
+	Match(image OsImage) (bool, error)
 }
 
-func filter(images []OsImage, f OsImageFilter) ([]OsImage, error) {
+// filter returns the images which match the filter and passes the others to dropped.
+func filter(images []OsImage, f OsImageFilter, dropped func(image OsImage)) ([]OsImage, error) {
 	result := []OsImage{}
 
 	for _, image := range images {
-		matched, err := f.match(image)
+		matched, err := f.Match(image)
 		if err != nil {
 			return nil, err
 		}
 
 		if matched {
 			result = append(result, image)
+		} else {
+			dropped(image)
 		}
 	}
 
@@ -64,9 +89,9 @@ type negatedFilter struct {
 	filter OsImageFilter
 }
 
-// match returns true iff the original filter does not match.
-func (n negatedFilter) match(image OsImage) (bool, error) {
-	matched, err := n.filter.match(image)
+// Match returns true iff the original filter does not match.
+func (n negatedFilter) Match(image OsImage) (bool, error) {
+	matched, err := n.filter.Match(image)
 	if err != nil {
 		return false, err
 	}
@@ -78,10 +103,10 @@ type anyFilter struct {
 	filters []OsImageFilter
 }
 
-// match returns true iff at least one of the original filters matches.
-func (a anyFilter) match(image OsImage) (bool, error) {
+// Match returns true iff at least one of the original filters matches.
+func (a anyFilter) Match(image OsImage) (bool, error) {
 	for _, f := range a.filters {
-		matched, err := f.match(image)
+		matched, err := f.Match(image)
 		if err != nil {
 			return false, err
 		}
@@ -94,6 +119,7 @@ func (a anyFilter) match(image OsImage) (bool, error) {
 }
 
 func filterOsImages(
+	ctx context.Context,
 	images []OsImage,
 	includeFilterKinds []OsImagesFilterKind,
 	excludeFilterKinds []OsImagesFilterKind,
@@ -108,12 +134,20 @@ func filterOsImages(
 		return nil, err
 	}
 
-	result, err := filter(images, anyFilter{includeFilters})
+	reporter := ReporterFromContext(ctx)
+	report := func(message string) func(image OsImage) {
+		return func(image OsImage) {
+			reporter.Report(ReportEntry{Image: image.Name, Version: versionOrEmpty(image.Version),
+				Reason: ReasonFilteredOut, Message: message})
+		}
+	}
+
+	result, err := filter(images, anyFilter{includeFilters}, report("matched by no include filter"))
 	if err != nil {
 		return nil, err
 	}
 
-	result, err = filter(result, negatedFilter{anyFilter{excludeFilters}})
+	result, err = filter(result, negatedFilter{anyFilter{excludeFilters}}, report("matched by an exclude filter"))
 	if err != nil {
 		return nil, err
 	}
@@ -137,43 +171,180 @@ func createFilters(filterKinds []OsImagesFilterKind) ([]OsImageFilter, error) {
 }
 
 func createFilter(filterKind OsImagesFilterKind) (OsImageFilter, error) {
-	switch filterKind {
-	case OsImagesFilterKindAll:
-		return &allowAllFilter{}, nil
-	case OsImagesFilterKindOutdated:
-		return &outdatedFilter{}, nil
-	case OsImagesFilterKindDeprecated:
-		return &classificationImagesFilter{classification: ClassificationDeprecated}, nil
-	case OsImagesFilterKindPreview:
-		return &classificationImagesFilter{classification: ClassificationPreview}, nil
-	case OsImagesFilterKindSupported:
-		return &classificationImagesFilter{classification: ClassificationSupported}, nil
-	case OsImagesFilterKindGardenlinux:
-		return &osNameImagesFilter{osName: OsNameGardenLinux}, nil
-	case OsImagesFilterKindSuseChost:
-		return &osNameImagesFilter{osName: OsNameSuseChost}, nil
-	case OsImagesFilterKindUbuntu:
-		return &osNameImagesFilter{osName: OsNameUbuntu}, nil
-	case OsImagesFilterKindCoreos:
-		return &osNameImagesFilter{osName: OsNameCoreos}, nil
-	case OsImagesFilterKindFlatcar:
-		return &osNameImagesFilter{osName: OsNameFlatcar}, nil
-	case OsImagesFilterKindMemoryoneChost:
-		return &osNameImagesFilter{osName: OsNameMemoryoneChost}, nil
-	default:
+	filterType, parameters := lookupOsImagesFilterType(filterKind)
+	if filterType == nil {
 		return nil, fmt.Errorf("filter does not exist %s", filterKind)
 	}
+	filter, err := parseOsImagesFilter(filterType, filterKind, parameters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %s: %w", filterKind, err)
+	}
+	return filter, nil
+}
+
+// parseOsImagesFilter parses the parameters of a kind with its type. Kinds of types without parameters must not have
+// ":", also with empty parameters, and types must not return a nil filter, which would fail on the first match.
+func parseOsImagesFilter(filterType OsImagesFilterType, kind OsImagesFilterKind, parameters string) (OsImageFilter, error) {
+	if _, ok := filterType.(parameterlessFilterType); ok && strings.Contains(string(kind), ":") {
+		return nil, fmt.Errorf("filter has no parameters")
+	}
+	filter, err := filterType.Parse(parameters)
+	if err != nil {
+		return nil, err
+	}
+	if filter == nil {
+		return nil, fmt.Errorf("filter type returned no filter")
+	}
+	return filter, nil
+}
+
+// OsImagesFilterType implements the filter kinds of a name. A kind is the name of its type, optionally followed by
+// ":" and parameters, e.g. "preview" or "gardenlinux-features:_usi". Types are registered with
+// RegisterOsImagesFilterType, so that other packages can add filter semantics.
+type OsImagesFilterType interface {
+	// Parse validates the parameters of a kind and returns its filter, which must not be nil. The parameters are empty
+	// for the kind without ":". Parse is called whenever imports are validated or filtered, so it must not have side
+	// effects.
+	Parse(parameters string) (OsImageFilter, error)
+}
+
+// OsImagesFilterTypeFunc is a function which implements an OsImagesFilterType.
+type OsImagesFilterTypeFunc func(parameters string) (OsImageFilter, error)
+
+// Parse calls the function.
+func (f OsImagesFilterTypeFunc) Parse(parameters string) (OsImageFilter, error) {
+	return f(parameters)
+}
+
+// OsImagesFilterPredicate returns whether a version of an image matches a registered filter kind.
+type OsImagesFilterPredicate func(image OsImage) (bool, error)
+
+// Match calls the predicate.
+func (p OsImagesFilterPredicate) Match(image OsImage) (bool, error) {
+	return p(image)
+}
+
+// parameterlessFilterType is the type of a kind without parameters.
+type parameterlessFilterType struct {
+	filter OsImageFilter
+}
+
+// Parse returns the filter, or an error if there are parameters.
+func (t parameterlessFilterType) Parse(parameters string) (OsImageFilter, error) {
+	if len(parameters) > 0 {
+		return nil, fmt.Errorf("filter has no parameters")
+	}
+	return t.filter, nil
+}
+
+// withoutParameters returns the type of a kind without parameters.
+func withoutParameters(filter OsImageFilter) OsImagesFilterType {
+	return parameterlessFilterType{filter: filter}
+}
+
+// builtInOsImagesFilterTypes are the types of OsImagesFilterKinds and of the Garden Linux features kinds.
+var builtInOsImagesFilterTypes = map[string]OsImagesFilterType{
+	string(OsImagesFilterKindAll):            withoutParameters(&allowAllFilter{}),
+	string(OsImagesFilterKindOutdated):       withoutParameters(&outdatedFilter{}),
+	string(OsImagesFilterKindDeprecated):     withoutParameters(&classificationImagesFilter{classification: ClassificationDeprecated}),
+	string(OsImagesFilterKindPreview):        withoutParameters(&classificationImagesFilter{classification: ClassificationPreview}),
+	string(OsImagesFilterKindSupported):      withoutParameters(&classificationImagesFilter{classification: ClassificationSupported}),
+	string(OsImagesFilterKindGardenlinux):    withoutParameters(&osNameImagesFilter{osName: OsNameGardenLinux}),
+	string(OsImagesFilterKindSuseChost):      withoutParameters(&osNameImagesFilter{osName: OsNameSuseChost}),
+	string(OsImagesFilterKindUbuntu):         withoutParameters(&osNameImagesFilter{osName: OsNameUbuntu}),
+	string(OsImagesFilterKindCoreos):         withoutParameters(&osNameImagesFilter{osName: OsNameCoreos}),
+	string(OsImagesFilterKindFlatcar):        withoutParameters(&osNameImagesFilter{osName: OsNameFlatcar}),
+	string(OsImagesFilterKindMemoryoneChost): withoutParameters(&osNameImagesFilter{osName: OsNameMemoryoneChost}),
+	OsImagesFilterTypeGardenLinuxFeatures:    OsImagesFilterTypeFunc(newGardenLinuxFeaturesFilter),
+}
+
+var osImagesFilterRegistry = struct {
+	sync.RWMutex
+	types map[string]OsImagesFilterType
+}{types: map[string]OsImagesFilterType{}}
+
+// RegisterOsImagesFilterType registers the type of the filter kinds with the name, e.g. "label" for kinds like
+// "label:team=os". Registered kinds can be used as include and exclude filters like the built-in kinds and are
+// validated by parsing them. Types are usually registered in init functions, before imports are validated. It returns
+// an error if the name is empty, contains ":", is a built-in type or already registered.
+func RegisterOsImagesFilterType(name string, filterType OsImagesFilterType) error {
+	if len(name) == 0 {
+		return fmt.Errorf("filter name must not be empty")
+	}
+	if strings.Contains(name, ":") {
+		return fmt.Errorf("filter name %s must not contain \":\"", name)
+	}
+	if filterType == nil {
+		return fmt.Errorf("type of filter %s must not be nil", name)
+	}
+	if _, ok := builtInOsImagesFilterTypes[name]; ok {
+		return fmt.Errorf("filter %s is a built-in filter", name)
+	}
+
+	osImagesFilterRegistry.Lock()
+	defer osImagesFilterRegistry.Unlock()
+	if _, ok := osImagesFilterRegistry.types[name]; ok {
+		return fmt.Errorf("filter %s is already registered", name)
+	}
+	osImagesFilterRegistry.types[name] = filterType
+	return nil
+}
+
+// RegisterOsImagesFilter registers a filter kind without parameters with a custom predicate, e.g. "fips" for versions
+// with a fips flag, see RegisterOsImagesFilterType.
+func RegisterOsImagesFilter(name string, predicate OsImagesFilterPredicate) error {
+	if predicate == nil {
+		return fmt.Errorf("predicate of filter %s must not be nil", name)
+	}
+	return RegisterOsImagesFilterType(name, withoutParameters(predicate))
+}
+
+// UnregisterOsImagesFilter removes a registered filter type, e.g. after a test. Built-in types cannot be removed.
+func UnregisterOsImagesFilter(name string) {
+	osImagesFilterRegistry.Lock()
+	defer osImagesFilterRegistry.Unlock()
+	delete(osImagesFilterRegistry.types, name)
+}
+
+// RegisteredOsImagesFilterKinds returns the built-in filter kinds followed by the names of the registered types in
+// alphabetical order.
+func RegisteredOsImagesFilterKinds() []OsImagesFilterKind {
+	osImagesFilterRegistry.RLock()
+	defer osImagesFilterRegistry.RUnlock()
+
+	registered := []OsImagesFilterKind{}
+	for name := range osImagesFilterRegistry.types {
+		registered = append(registered, OsImagesFilterKind(name))
+	}
+	sort.Slice(registered, func(i, j int) bool { return registered[i] < registered[j] })
+	return append(append([]OsImagesFilterKind{}, OsImagesFilterKinds...), registered...)
+}
+
+// lookupOsImagesFilterType returns the type and the parameters of a kind, or nil if the type does not exist.
+func lookupOsImagesFilterType(kind OsImagesFilterKind) (OsImagesFilterType, string) {
+	parts := strings.SplitN(string(kind), ":", 2)
+	parameters := ""
+	if len(parts) == 2 {
+		parameters = parts[1]
+	}
+	if filterType, ok := builtInOsImagesFilterTypes[parts[0]]; ok {
+		return filterType, parameters
+	}
+
+	osImagesFilterRegistry.RLock()
+	defer osImagesFilterRegistry.RUnlock()
+	return osImagesFilterRegistry.types[parts[0]], parameters
 }
 
 type allowAllFilter struct{}
 
-func (a *allowAllFilter) match(_ OsImage) (bool, error) {
+func (a *allowAllFilter) Match(_ OsImage) (bool, error) {
 	return true, nil
 }
 
 type outdatedFilter struct{}
 
-func (a *outdatedFilter) match(image OsImage) (bool, error) {
+func (a *outdatedFilter) Match(image OsImage) (bool, error) {
 	expired, err := image.Version.isExpired()
 	if err != nil {
 		return false, err
@@ -185,7 +356,7 @@ type classificationImagesFilter struct {
 	classification string
 }
 
-func (a *classificationImagesFilter) match(image OsImage) (bool, error) {
+func (a *classificationImagesFilter) Match(image OsImage) (bool, error) {
 	return image.Version.hasClassification(a.classification), nil
 }
 
@@ -193,6 +364,6 @@ type osNameImagesFilter struct {
 	osName string
 }
 
-func (a *osNameImagesFilter) match(image OsImage) (bool, error) {
+func (a *osNameImagesFilter) Match(image OsImage) (bool, error) {
 	return image.Name == a.osName, nil
 }
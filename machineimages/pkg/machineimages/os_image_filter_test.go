@@ -5,6 +5,10 @@
 package machineimages
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -16,6 +20,7 @@ var _ = Describe("os image filter", func() {
 		It("should include all images", func() {
 			images := []OsImage{{Name: OsNameUbuntu}, {Name: OsNameCoreos}, {Name: OsNameGardenLinux}}
 			filteredImages, err := filterOsImages(
+				context.Background(),
 				images,
 				[]OsImagesFilterKind{OsImagesFilterKindAll},
 				nil)
@@ -26,6 +31,7 @@ var _ = Describe("os image filter", func() {
 		It("should exclude all images", func() {
 			images := []OsImage{{Name: OsNameUbuntu}, {Name: OsNameCoreos}, {Name: OsNameGardenLinux}}
 			filteredImages, err := filterOsImages(
+				context.Background(),
 				images,
 				nil,
 				[]OsImagesFilterKind{OsImagesFilterKindAll})
@@ -36,6 +42,7 @@ var _ = Describe("os image filter", func() {
 		It("should include some images using the name filter", func() {
 			images := []OsImage{{Name: OsNameUbuntu}, {Name: OsNameCoreos}, {Name: OsNameGardenLinux}}
 			filteredImages, err := filterOsImages(
+				context.Background(),
 				images,
 				[]OsImagesFilterKind{OsNameUbuntu, OsNameGardenLinux},
 				nil)
@@ -46,6 +53,7 @@ var _ = Describe("os image filter", func() {
 		It("should exclude some images using the name filter", func() {
 			images := []OsImage{{Name: OsNameUbuntu}, {Name: OsNameCoreos}, {Name: OsNameGardenLinux}}
 			filteredImages, err := filterOsImages(
+				context.Background(),
 				images,
 				[]OsImagesFilterKind{OsImagesFilterKindAll},
 				[]OsImagesFilterKind{OsNameUbuntu, OsNameGardenLinux})
@@ -53,4 +61,140 @@ var _ = Describe("os image filter", func() {
 			Expect(filteredImages).To(ConsistOf(OsImage{Name: OsNameCoreos}))
 		})
 	})
+
+	Context("RegisterOsImagesFilter", func() {
+
+		fips := func(image OsImage) (bool, error) {
+			enabled, _ := image.Version["fips"].(bool)
+			return enabled, nil
+		}
+
+		BeforeEach(func() {
+			Expect(RegisterOsImagesFilter("fips", fips)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			UnregisterOsImagesFilter("fips")
+		})
+
+		It("should include and exclude images with registered filters", func() {
+			images := []OsImage{
+				{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "22.4.0", "fips": true}},
+				{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": "934.7.0"}},
+			}
+
+			included, err := filterOsImages(context.Background(), images, []OsImagesFilterKind{"fips"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(included).To(Equal(images[:1]))
+
+			excluded, err := filterOsImages(context.Background(), images, []OsImagesFilterKind{OsImagesFilterKindAll}, []OsImagesFilterKind{"fips"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(excluded).To(Equal(images[1:]))
+		})
+
+		It("should validate registered filters like the built-in filters", func() {
+			imports := &Imports{IncludeFilters: []OsImagesFilterKind{"fips"}, ExcludeFilters: []OsImagesFilterKind{"secureboot"}}
+			Expect(ValidateImports(imports)).To(MatchError(`invalid imports: excludeFilters: unknown filter "secureboot"`))
+
+			imports.ExcludeFilters = []OsImagesFilterKind{"fips"}
+			Expect(ValidateImports(imports)).To(MatchError(ContainSubstring("exclude filter list contains element of include list")))
+		})
+
+		It("should reject invalid registrations", func() {
+			Expect(RegisterOsImagesFilter("fips", fips)).To(MatchError("filter fips is already registered"))
+			Expect(RegisterOsImagesFilter(string(OsImagesFilterKindPreview), fips)).To(MatchError("filter preview is a built-in filter"))
+			Expect(RegisterOsImagesFilter("", fips)).To(MatchError("filter name must not be empty"))
+			Expect(RegisterOsImagesFilter("secureboot", nil)).To(MatchError("predicate of filter secureboot must not be nil"))
+		})
+
+		It("should reject invalid registrations of filter types", func() {
+			label := OsImagesFilterTypeFunc(func(string) (OsImageFilter, error) { return &allowAllFilter{}, nil })
+			Expect(RegisterOsImagesFilterType("label:team", label)).To(MatchError(`filter name label:team must not contain ":"`))
+			Expect(RegisterOsImagesFilterType("label", nil)).To(MatchError("type of filter label must not be nil"))
+			Expect(RegisterOsImagesFilterType(string(OsImagesFilterKindAll), label)).To(MatchError("filter all is a built-in filter"))
+		})
+
+		It("should list the registered filters after the built-in filters", func() {
+			Expect(RegisterOsImagesFilter("secureboot", fips)).To(Succeed())
+			defer UnregisterOsImagesFilter("secureboot")
+
+			kinds := RegisteredOsImagesFilterKinds()
+			Expect(kinds[:len(OsImagesFilterKinds)]).To(Equal(OsImagesFilterKinds))
+			Expect(kinds[len(OsImagesFilterKinds):]).To(Equal([]OsImagesFilterKind{"fips", "secureboot"}))
+			Expect(GetCapabilities().FilterKinds).To(Equal(kinds))
+		})
+	})
+
+	Context("RegisterOsImagesFilterType", func() {
+
+		// label matches the versions whose labels contain all key=value pairs of the parameters
+		label := OsImagesFilterTypeFunc(func(parameters string) (OsImageFilter, error) {
+			selector := map[string]string{}
+			for _, pair := range strings.Split(parameters, ",") {
+				parts := strings.SplitN(pair, "=", 2)
+				if len(parts) != 2 || len(parts[0]) == 0 {
+					return nil, fmt.Errorf("invalid label selector %q", pair)
+				}
+				selector[parts[0]] = parts[1]
+			}
+			return OsImagesFilterPredicate(func(image OsImage) (bool, error) {
+				labels, _ := image.Version["labels"].(map[string]interface{})
+				for key, value := range selector {
+					if labels[key] != value {
+						return false, nil
+					}
+				}
+				return true, nil
+			}), nil
+		})
+
+		BeforeEach(func() {
+			Expect(RegisterOsImagesFilterType("label", label)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			UnregisterOsImagesFilter("label")
+		})
+
+		It("should filter with the parameters of the kinds", func() {
+			images := []OsImage{
+				{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "22.4.0", "labels": map[string]interface{}{"team": "os", "tier": "1"}}},
+				{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "22.3.0", "labels": map[string]interface{}{"team": "os"}}},
+				{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": "934.7.0"}},
+			}
+
+			result, err := filterOsImages(context.Background(), images, []OsImagesFilterKind{"label:team=os"}, []OsImagesFilterKind{"label:tier=1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(images[1:2]))
+			Expect(RegisteredOsImagesFilterKinds()).To(ContainElement(OsImagesFilterKind("label")))
+		})
+
+		It("should validate the parameters of the kinds", func() {
+			err := ValidateImports(&Imports{IncludeFilters: []OsImagesFilterKind{"label:team", "preview:stable", "tier:1"}})
+			Expect(err).To(MatchError(ContainSubstring(`includeFilters: invalid filter "label:team": invalid label selector "team"`)))
+			Expect(err).To(MatchError(ContainSubstring(`includeFilters: invalid filter "preview:stable": filter has no parameters`)))
+			Expect(err).To(MatchError(ContainSubstring(`includeFilters: unknown filter "tier:1"`)))
+
+			_, err = filterOsImages(context.Background(), nil, []OsImagesFilterKind{"label:"}, nil)
+			Expect(err).To(MatchError(`invalid filter label:: invalid label selector ""`))
+
+			err = ValidateImports(&Imports{IncludeFilters: []OsImagesFilterKind{"preview:"}})
+			Expect(err).To(MatchError(`invalid imports: includeFilters: invalid filter "preview:": filter has no parameters`))
+			_, err = filterOsImages(context.Background(), nil, []OsImagesFilterKind{"preview:"}, nil)
+			Expect(err).To(MatchError(`invalid filter preview:: filter has no parameters`))
+		})
+
+		It("should reject types which return no filter", func() {
+			Expect(RegisterOsImagesFilterType("none", OsImagesFilterTypeFunc(func(string) (OsImageFilter, error) {
+				return nil, nil
+			}))).To(Succeed())
+			defer UnregisterOsImagesFilter("none")
+
+			err := ValidateImports(&Imports{IncludeFilters: []OsImagesFilterKind{"none"}})
+			Expect(err).To(MatchError(`invalid imports: includeFilters: invalid filter "none": filter type returned no filter`))
+			_, err = filterOsImages(context.Background(), []OsImage{{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "22.4.0"}}},
+				[]OsImagesFilterKind{"none"}, nil)
+			Expect(err).To(MatchError(`invalid filter none: filter type returned no filter`))
+		})
+	})
 })
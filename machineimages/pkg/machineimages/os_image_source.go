@@ -0,0 +1,413 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultOsImageSourceTimeout is the default timeout of fetching the OS images of a remote source.
+	DefaultOsImageSourceTimeout = 30 * time.Second
+	// DefaultOsImageCacheMaxAge is the default age up to which a cached image list of a remote source is used if the
+	// fetch fails.
+	DefaultOsImageCacheMaxAge = 24 * time.Hour
+	// maxOsImagesBytes limits the size of fetched catalog files.
+	maxOsImagesBytes = 32 << 20
+)
+
+// OsImageSource provides the default OS images of the LSS, which are otherwise the machineImages of the imports.
+type OsImageSource interface {
+	OsImages(ctx context.Context) ([]MachineImage, error)
+}
+
+// RemoteOsImages configures a remote source of the machineImages of the imports, so that the image list does not
+// have to be vendored into the imports. Exactly one of HTTP and OCI must be set.
+type RemoteOsImages struct {
+	// HTTP fetches the image list from an http(s) endpoint.
+	HTTP *RemoteOsImagesHTTP `json:"http,omitempty" yaml:"http,omitempty"`
+	// OCI fetches the image list from a layer of an OCI artifact.
+	OCI *RemoteOsImagesOCI `json:"oci,omitempty" yaml:"oci,omitempty"`
+	// SHA256 is the expected hex encoded sha256 digest of the image list. If set, other image lists are rejected.
+	SHA256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	// TimeoutSeconds limits fetching the image list. Defaults to DefaultOsImageSourceTimeout.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty"`
+	// CacheDir is a directory in which fetched image lists are cached, see OsImageCache.
+	CacheDir string `json:"cacheDir,omitempty" yaml:"cacheDir,omitempty"`
+	// CacheMaxAgeSeconds is the age up to which a cached image list is used if the fetch fails. Defaults to
+	// DefaultOsImageCacheMaxAge.
+	CacheMaxAgeSeconds int `json:"cacheMaxAgeSeconds,omitempty" yaml:"cacheMaxAgeSeconds,omitempty"`
+}
+
+// RemoteOsImagesHTTP is an http(s) endpoint which returns a catalog file, see DecodeCatalogFile.
+type RemoteOsImagesHTTP struct {
+	URL string `json:"url" yaml:"url"`
+	// Token is sent as bearer token.
+	Token *SecretValue `json:"token,omitempty" yaml:"token,omitempty"`
+}
+
+// RemoteOsImagesOCI is an OCI artifact with a catalog file as layer, e.g. the image reference of a resource of the
+// component descriptor.
+type RemoteOsImagesOCI struct {
+	// Reference is the reference of the artifact with tag or digest, e.g. "eu.gcr.io/gardener/os-images:1.2.0".
+	Reference string `json:"reference" yaml:"reference"`
+	// MediaType is the media type of the layer with the catalog file. Defaults to the first layer.
+	MediaType string `json:"mediaType,omitempty" yaml:"mediaType,omitempty"`
+	// Token is sent as bearer token to the registry. Without token, anonymous pull tokens are requested from
+	// registries which require them.
+	Token *SecretValue `json:"token,omitempty" yaml:"token,omitempty"`
+	// PlainHTTP fetches the artifact via http instead of https, e.g. from local registries.
+	PlainHTTP bool `json:"plainHTTP,omitempty" yaml:"plainHTTP,omitempty"`
+}
+
+// Validate checks that exactly one source is configured and that the source, digest and timeout are valid.
+func (r *RemoteOsImages) Validate() error {
+	if (r.HTTP != nil) == (r.OCI != nil) {
+		return errors.New("exactly one of http and oci must be set")
+	}
+	if r.HTTP != nil && !strings.HasPrefix(r.HTTP.URL, "http://") && !strings.HasPrefix(r.HTTP.URL, "https://") {
+		return errors.New("http: url must be an http or https url")
+	}
+	if r.OCI != nil {
+		if _, err := ParseArtifactReference(r.OCI.Reference); err != nil {
+			return fmt.Errorf("oci: %w", err)
+		}
+	}
+	if len(r.SHA256) > 0 {
+		if decoded, err := hex.DecodeString(r.SHA256); err != nil || len(decoded) != sha256.Size {
+			return errors.New("sha256 must be a hex encoded sha256 digest")
+		}
+	}
+	if r.TimeoutSeconds < 0 {
+		return errors.New("timeoutSeconds must not be negative")
+	}
+	if r.CacheMaxAgeSeconds < 0 {
+		return errors.New("cacheMaxAgeSeconds must not be negative")
+	}
+	return nil
+}
+
+// ResolveSecrets resolves the tokens of the sources.
+func (r *RemoteOsImages) ResolveSecrets(ctx context.Context, resolver *SecretResolver) error {
+	if r.HTTP != nil {
+		if err := r.HTTP.Token.Resolve(ctx, resolver); err != nil {
+			return fmt.Errorf("unable to resolve token of remote os images: %w", err)
+		}
+	}
+	if r.OCI != nil {
+		if err := r.OCI.Token.Resolve(ctx, resolver); err != nil {
+			return fmt.Errorf("unable to resolve token of remote os images: %w", err)
+		}
+	}
+	return nil
+}
+
+// Source returns the configured source.
+func (r *RemoteOsImages) Source() (OsImageSource, error) {
+	timeout := time.Duration(r.TimeoutSeconds) * time.Second
+	expectedSHA256 := strings.ToLower(r.SHA256)
+	var cache *OsImageCache
+	if len(r.CacheDir) > 0 {
+		cache = &OsImageCache{Dir: r.CacheDir, MaxAge: time.Duration(r.CacheMaxAgeSeconds) * time.Second}
+		if cache.MaxAge == 0 {
+			cache.MaxAge = DefaultOsImageCacheMaxAge
+		}
+	}
+
+	if r.HTTP != nil {
+		token, err := r.HTTP.Token.Secret()
+		if err != nil {
+			return nil, fmt.Errorf("invalid token of remote os images: %w", err)
+		}
+		return &HTTPOsImageSource{URL: r.HTTP.URL, Token: token, SHA256: expectedSHA256, Timeout: timeout, Cache: cache}, nil
+	}
+	token, err := r.OCI.Token.Secret()
+	if err != nil {
+		return nil, fmt.Errorf("invalid token of remote os images: %w", err)
+	}
+	return &OCIOsImageSource{Reference: r.OCI.Reference, MediaType: r.OCI.MediaType, Token: token,
+		PlainHTTP: r.OCI.PlainHTTP, SHA256: expectedSHA256, Timeout: timeout, Cache: cache}, nil
+}
+
+// OsImageCache caches fetched image lists in a directory, keyed by their location. A cached image list with the
+// expected digest is used without fetching it. Without expected digest, the image list is fetched and the cached
+// image list is only used if the fetch fails, which is reported, see ReasonCachedOsImages.
+type OsImageCache struct {
+	Dir string
+	// MaxAge is the age up to which a cached image list is used if the fetch fails. Zero is no limit.
+	MaxAge time.Duration
+}
+
+func (c *OsImageCache) path(location string) string {
+	key := sha256.Sum256([]byte(location))
+	return filepath.Join(c.Dir, hex.EncodeToString(key[:])+".yaml")
+}
+
+func (c *OsImageCache) get(location string) ([]byte, bool) {
+	data, err := ioutil.ReadFile(c.path(location))
+	return data, err == nil
+}
+
+// getFresh returns the cached image list of the location and the time it was cached, if it is not older than the max
+// age.
+func (c *OsImageCache) getFresh(location string) ([]byte, time.Time, bool) {
+	info, err := os.Stat(c.path(location))
+	if err != nil || c.MaxAge > 0 && time.Since(info.ModTime()) > c.MaxAge {
+		return nil, time.Time{}, false
+	}
+	data, ok := c.get(location)
+	return data, info.ModTime(), ok
+}
+
+func (c *OsImageCache) put(location string, data []byte) error {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path(location), data, 0600)
+}
+
+// fetchOsImages returns the image list of the location, which is fetched by fetch with the timeout unless it is
+// cached with the expected digest. The digest is only verified if it is set, the cache is only used if it is set.
+func fetchOsImages(ctx context.Context, location, expectedSHA256 string, timeout time.Duration, cache *OsImageCache,
+	fetch func(ctx context.Context) ([]byte, error)) ([]MachineImage, error) {
+	if cache != nil && len(expectedSHA256) > 0 {
+		if data, ok := cache.get(location); ok && sha256Hex(data) == expectedSHA256 {
+			return decodeOsImages(location, data)
+		}
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultOsImageSourceTimeout
+	}
+	fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data, err := fetch(fetchCtx)
+	if err != nil {
+		if cache != nil && len(expectedSHA256) == 0 {
+			if cached, cachedAt, ok := cache.getFresh(location); ok {
+				LoggerFromContext(ctx).Error(err, "Unable to fetch os images, using the cached os images", "location", location)
+				ReporterFromContext(ctx).Report(ReportEntry{Reason: ReasonCachedOsImages, Message: fmt.Sprintf(
+					"unable to fetch os images from %s, using the os images cached at %s: %v", location,
+					cachedAt.UTC().Format(time.RFC3339), err)})
+				return decodeOsImages(location, cached)
+			}
+		}
+		return nil, err
+	}
+	if len(expectedSHA256) > 0 {
+		if digest := sha256Hex(data); digest != expectedSHA256 {
+			return nil, &FetchError{Operation: "fetch os images", URL: location,
+				Err: fmt.Errorf("sha256 digest %s is not the expected digest %s", digest, expectedSHA256)}
+		}
+	}
+
+	images, err := decodeOsImages(location, data)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		if err := cache.put(location, data); err != nil {
+			LoggerFromContext(ctx).Error(err, "Unable to cache os images", "location", location)
+		}
+	}
+	return images, nil
+}
+
+func decodeOsImages(location string, data []byte) ([]MachineImage, error) {
+	catalog, err := DecodeCatalogFile(data)
+	if err != nil {
+		return nil, &FetchError{Operation: "fetch os images", URL: location, Err: fmt.Errorf("invalid image list: %w", err)}
+	}
+	return catalog.MachineImages, nil
+}
+
+func sha256Hex(data []byte) string {
+	digest := sha256.Sum256(data)
+	return hex.EncodeToString(digest[:])
+}
+
+// readLimited reads the body up to the limit of image lists.
+func readLimited(body io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxOsImagesBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxOsImagesBytes {
+		return nil, fmt.Errorf("image list exceeds %d bytes", maxOsImagesBytes)
+	}
+	return data, nil
+}
+
+// HTTPOsImageSource fetches the image list from an http(s) endpoint which returns a catalog file, see
+// DecodeCatalogFile.
+type HTTPOsImageSource struct {
+	URL string
+	// Token is sent as bearer token, if set.
+	Token string
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+	// SHA256 is the expected hex encoded sha256 digest of the image list, if set.
+	SHA256 string
+	// Timeout limits the fetch. Defaults to DefaultOsImageSourceTimeout.
+	Timeout time.Duration
+	// Cache caches the image list, if set.
+	Cache *OsImageCache
+}
+
+// OsImages fetches the image list from the endpoint.
+func (s *HTTPOsImageSource) OsImages(ctx context.Context) ([]MachineImage, error) {
+	return fetchOsImages(ctx, s.URL, s.SHA256, s.Timeout, s.Cache, func(ctx context.Context) ([]byte, error) {
+		const operation = "fetch os images"
+		if err := CheckNetworkAccess(ctx, operation, s.URL); err != nil {
+			return nil, err
+		}
+		if err := InjectFault(ctx, FaultPointFetch, s.URL); err != nil {
+			return nil, &FetchError{Operation: operation, URL: s.URL, Err: err}
+		}
+
+		client := s.Client
+		if client == nil {
+			client = NewHTTPClient(nil)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(s.Token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+s.Token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, &FetchError{Operation: operation, URL: s.URL, Err: err}
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, &FetchError{Operation: operation, URL: s.URL, StatusCode: resp.StatusCode}
+		}
+		data, err := readLimited(resp.Body)
+		if err != nil {
+			return nil, &FetchError{Operation: operation, URL: s.URL, Err: err}
+		}
+		return data, nil
+	})
+}
+
+// OCIOsImageSource fetches the image list from a layer of an OCI artifact with the OCI distribution api. The digest
+// of the layer is verified.
+type OCIOsImageSource struct {
+	// Reference is the reference of the artifact with tag or digest.
+	Reference string
+	// MediaType is the media type of the layer with the image list. Defaults to the first layer.
+	MediaType string
+	// Token is sent as bearer token, if set. Without token, anonymous pull tokens are requested from registries which
+	// require them.
+	Token string
+	// PlainHTTP fetches the artifact via http instead of https.
+	PlainHTTP bool
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+	// SHA256 is the expected hex encoded sha256 digest of the image list, if set.
+	SHA256 string
+	// Timeout limits the fetch. Defaults to DefaultOsImageSourceTimeout.
+	Timeout time.Duration
+	// Cache caches the image list, if set.
+	Cache *OsImageCache
+}
+
+// OsImages fetches the image list from the layer of the artifact.
+func (s *OCIOsImageSource) OsImages(ctx context.Context) ([]MachineImage, error) {
+	return fetchOsImages(ctx, s.Reference+"#"+s.MediaType, s.SHA256, s.Timeout, s.Cache, s.fetch)
+}
+
+func (s *OCIOsImageSource) fetch(ctx context.Context) ([]byte, error) {
+	const operation = "fetch os images"
+	reference, err := ParseArtifactReference(s.Reference)
+	if err != nil {
+		return nil, err
+	}
+	scheme := "https"
+	if s.PlainHTTP {
+		scheme = "http"
+	}
+	client := s.Client
+	if client == nil {
+		client = NewHTTPClient(nil)
+	}
+	session := &registrySession{
+		client:    client,
+		baseURL:   fmt.Sprintf("%s://%s/v2/%s", scheme, reference.Registry, reference.Repository),
+		token:     s.Token,
+		anonymous: len(s.Token) == 0,
+	}
+
+	get := func(path, accept string) ([]byte, error) {
+		resp, err := session.request(ctx, http.MethodGet, path, accept)
+		if err != nil {
+			return nil, &FetchError{Operation: operation, URL: s.Reference, Err: err}
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, &FetchError{Operation: operation, URL: session.baseURL + path, StatusCode: resp.StatusCode}
+		}
+		data, err := readLimited(resp.Body)
+		if err != nil {
+			return nil, &FetchError{Operation: operation, URL: session.baseURL + path, Err: err}
+		}
+		return data, nil
+	}
+
+	data, err := get("/manifests/"+reference.Reference,
+		"application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json")
+	if err != nil {
+		return nil, err
+	}
+	manifest := &struct {
+		Layers []struct {
+			MediaType string `json:"mediaType"`
+			Digest    string `json:"digest"`
+		} `json:"layers"`
+	}{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, &FetchError{Operation: operation, URL: s.Reference, Err: fmt.Errorf("invalid manifest: %w", err)}
+	}
+
+	digest := ""
+	for _, layer := range manifest.Layers {
+		if len(s.MediaType) == 0 || layer.MediaType == s.MediaType {
+			digest = layer.Digest
+			break
+		}
+	}
+	if len(digest) == 0 {
+		return nil, &FetchError{Operation: operation, URL: s.Reference,
+			Err: fmt.Errorf("artifact has no layer with media type %q", s.MediaType)}
+	}
+	if !strings.HasPrefix(digest, "sha256:") {
+		return nil, &FetchError{Operation: operation, URL: s.Reference, Err: fmt.Errorf("unsupported layer digest %s", digest)}
+	}
+
+	data, err = get("/blobs/"+digest, "")
+	if err != nil {
+		return nil, err
+	}
+	if actual := "sha256:" + sha256Hex(data); actual != digest {
+		return nil, &FetchError{Operation: operation, URL: s.Reference,
+			Err: fmt.Errorf("digest %s of layer is not the digest %s of the manifest", actual, digest)}
+	}
+	return data, nil
+}
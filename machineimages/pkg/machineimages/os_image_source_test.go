@@ -0,0 +1,241 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("os image sources", func() {
+
+	const catalog = `
+- name: gardenlinux
+  versions:
+  - version: 934.7.0
+`
+	expected := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0"}}}}
+
+	var (
+		server   *httptest.Server
+		requests int
+		healthy  bool
+		cacheDir string
+	)
+
+	BeforeEach(func() {
+		requests = 0
+		healthy = true
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if !healthy {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(catalog))
+		}))
+		var err error
+		cacheDir, err = ioutil.TempDir("", "os-images")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(cacheDir)).To(Succeed())
+	})
+
+	Context("http", func() {
+		It("should fetch the image list with the token", func() {
+			source := &HTTPOsImageSource{URL: server.URL, Token: "secret"}
+			Expect(source.OsImages(context.Background())).To(Equal(expected))
+
+			source.Token = ""
+			_, err := source.OsImages(context.Background())
+			Expect(err).To(HaveOccurred())
+			Expect(ClassOf(err)).To(Equal(ErrorClassFetch))
+		})
+
+		It("should reject an image list with another digest", func() {
+			source := &HTTPOsImageSource{URL: server.URL, Token: "secret", SHA256: sha256Hex([]byte("other"))}
+			_, err := source.OsImages(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("is not the expected digest")))
+			Expect(ClassOf(err)).To(Equal(ErrorClassFetch))
+		})
+
+		It("should use a cached image list with the expected digest without fetching it", func() {
+			source := &HTTPOsImageSource{URL: server.URL, Token: "secret", SHA256: sha256Hex([]byte(catalog)),
+				Cache: &OsImageCache{Dir: cacheDir}}
+			Expect(source.OsImages(context.Background())).To(Equal(expected))
+			Expect(source.OsImages(context.Background())).To(Equal(expected))
+			Expect(requests).To(Equal(1))
+		})
+
+		It("should fall back to the cached image list up to its max age if the fetch fails and report it", func() {
+			cache := &OsImageCache{Dir: cacheDir, MaxAge: time.Hour}
+			source := &HTTPOsImageSource{URL: server.URL, Token: "secret", Cache: cache}
+			Expect(source.OsImages(context.Background())).To(Equal(expected))
+
+			healthy = false
+			report := NewReport()
+			Expect(source.OsImages(NewContext(context.Background(), nil, report))).To(Equal(expected))
+			Expect(requests).To(Equal(2))
+			Expect(report.Entries()).To(HaveLen(1))
+			Expect(report.Entries()[0].Reason).To(Equal(ReasonCachedOsImages))
+			Expect(report.Entries()[0].Message).To(ContainSubstring("unable to fetch os images from " + server.URL))
+
+			stale := time.Now().Add(-2 * time.Hour)
+			Expect(os.Chtimes(cache.path(server.URL), stale, stale)).To(Succeed())
+			_, err := source.OsImages(context.Background())
+			Expect(ClassOf(err)).To(Equal(ErrorClassFetch))
+
+			source.Cache = nil
+			_, err = source.OsImages(context.Background())
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should stop fetching after the timeout", func() {
+			slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+			}))
+			defer slow.Close()
+
+			source := &HTTPOsImageSource{URL: slow.URL, Timeout: 50 * time.Millisecond}
+			_, err := source.OsImages(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("deadline exceeded")))
+			Expect(ClassOf(err)).To(Equal(ErrorClassFetch))
+		})
+	})
+
+	Context("oci", func() {
+		var registry *httptest.Server
+
+		BeforeEach(func() {
+			layer := sha256Hex([]byte(catalog))
+			registry = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/v2/gardener/os-images/manifests/1.2.0":
+					if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.manifest.v1+json") {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					_, _ = w.Write([]byte(`{"layers": [
+						{"mediaType": "application/octet-stream", "digest": "sha256:` + sha256Hex([]byte("other")) + `"},
+						{"mediaType": "application/vnd.gardener.os-images.v1+yaml", "digest": "sha256:` + layer + `"},
+						{"mediaType": "application/vnd.gardener.tampered+yaml", "digest": "sha256:tampered"}]}`))
+				default:
+					if !strings.HasPrefix(r.URL.Path, "/v2/gardener/os-images/blobs/") {
+						w.WriteHeader(http.StatusNotFound)
+						return
+					}
+					// every blob is the image list, so that layers with other digests are tampered
+					_, _ = w.Write([]byte(catalog))
+				}
+			}))
+		})
+
+		AfterEach(func() {
+			registry.Close()
+		})
+
+		source := func(mediaType string) *OCIOsImageSource {
+			return &OCIOsImageSource{
+				Reference: strings.TrimPrefix(registry.URL, "http://") + "/gardener/os-images:1.2.0",
+				MediaType: mediaType,
+				PlainHTTP: true,
+			}
+		}
+
+		It("should fetch the image list from the layer with the media type", func() {
+			Expect(source("application/vnd.gardener.os-images.v1+yaml").OsImages(context.Background())).To(Equal(expected))
+		})
+
+		It("should verify the digest of the layer", func() {
+			_, err := source("application/vnd.gardener.tampered+yaml").OsImages(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("is not the digest sha256:tampered of the manifest")))
+			_, err = source("").OsImages(context.Background())
+			Expect(err).To(MatchError(ContainSubstring("of layer is not the digest")))
+		})
+
+		It("should fail for missing layers", func() {
+			_, err := source("application/unknown").OsImages(context.Background())
+			Expect(err).To(MatchError(ContainSubstring(`artifact has no layer with media type "application/unknown"`)))
+		})
+	})
+
+	Context("imports", func() {
+		It("should validate the remote os images", func() {
+			imports := &Imports{
+				MachineImages: expected,
+				ComputeMachineImagesOptions: ComputeMachineImagesOptions{RemoteOsImages: &RemoteOsImages{
+					HTTP: &RemoteOsImagesHTTP{URL: server.URL},
+					OCI:  &RemoteOsImagesOCI{Reference: "eu.gcr.io/gardener/os-images:1.2.0"},
+				}},
+			}
+			err := ValidateImports(imports)
+			Expect(err).To(MatchError(ContainSubstring("remoteOsImages: exactly one of http and oci must be set")))
+			Expect(err).To(MatchError(ContainSubstring("remoteOsImages: machineImages must be empty")))
+
+			Expect((&RemoteOsImages{OCI: &RemoteOsImagesOCI{Reference: "os-images:1.2.0"}}).Validate()).To(MatchError(ContainSubstring("has no registry")))
+			Expect((&RemoteOsImages{HTTP: &RemoteOsImagesHTTP{URL: "ftp://images"}}).Validate()).To(MatchError(ContainSubstring("must be an http or https url")))
+			Expect((&RemoteOsImages{HTTP: &RemoteOsImagesHTTP{URL: server.URL}, SHA256: "abc"}).Validate()).To(MatchError(ContainSubstring("sha256 must be")))
+		})
+
+		It("should compute the machine images from the remote os images", func() {
+			imports := &Imports{
+				MachineImagesProvider: expected,
+				ComputeMachineImagesOptions: ComputeMachineImagesOptions{RemoteOsImages: &RemoteOsImages{
+					HTTP:   &RemoteOsImagesHTTP{URL: server.URL, Token: &SecretValue{Value: "secret"}},
+					SHA256: strings.ToUpper(sha256Hex([]byte(catalog))),
+				}},
+			}
+			Expect(ValidateImports(imports)).To(Succeed())
+			Expect(ComputeMachineImagesFromImports(context.Background(), logr.Discard(), imports)).To(Equal(expected))
+
+			imports.OsImageSource = &HTTPOsImageSource{URL: server.URL}
+			_, err := ComputeMachineImagesFromImports(context.Background(), logr.Discard(), imports)
+			Expect(ClassOf(err)).To(Equal(ErrorClassFetch))
+		})
+
+		It("should explain and compute several providers with the remote os images", func() {
+			imports := &Imports{
+				MachineImagesProvider: expected,
+				ComputeMachineImagesOptions: ComputeMachineImagesOptions{RemoteOsImages: &RemoteOsImages{
+					HTTP: &RemoteOsImagesHTTP{URL: server.URL, Token: &SecretValue{Value: "secret"}},
+				}},
+			}
+			explanation, err := ExplainVersion(context.Background(), logr.Discard(), imports, OsNameGardenLinux, "934.7.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(explanation.Included).To(BeTrue())
+
+			gcp := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "934.7.0", "image": "gl"}}}}
+			results, err := ComputeMachineImagesMultiProvider(context.Background(), logr.Discard(), nil, nil,
+				map[string]ProviderMachineImages{ProviderGCP: {MachineImagesProvider: gcp}}, nil, nil, nil,
+				&imports.ComputeMachineImagesOptions)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(results[ProviderGCP]).To(Equal(gcp))
+
+			healthy = false
+			_, err = ExplainVersion(context.Background(), logr.Discard(), imports, OsNameGardenLinux, "934.7.0")
+			Expect(ClassOf(err)).To(Equal(ErrorClassFetch))
+		})
+	})
+})
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CoreVersionFields are the fields of machine image versions which all provider extensions understand.
+var CoreVersionFields = []string{"version", "classification", "expirationDate", "cri", "architectures"}
+
+// ProviderFields are the provider specific fields of machine image versions which a provider extension understands.
+type ProviderFields struct {
+	// Version are the fields of a version.
+	Version []string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Region are the fields of the entries of the regions list of a version. It is only used if Version contains
+	// "regions".
+	Region []string `json:"region,omitempty" yaml:"region,omitempty"`
+}
+
+// DefaultProviderFields maps provider types to the fields of the machine images of their provider config.
+var DefaultProviderFields = map[string]ProviderFields{
+	"alicloud":  {Version: []string{"regions"}, Region: []string{"name", "id"}},
+	"aws":       {Version: []string{"regions"}, Region: []string{"name", "ami", "architecture"}},
+	"azure":     {Version: []string{"urn", "id", "communityGalleryImageID", "sharedGalleryImageID", "acceleratedNetworking", "architecture"}},
+	"gcp":       {Version: []string{"image", "architecture"}},
+	"openstack": {Version: []string{"image", "regions"}, Region: []string{"name", "id", "architecture"}},
+	"vsphere":   {Version: []string{"path", "guestId"}},
+}
+
+// PartitionOptions configures the partitioning of machine images per provider.
+type PartitionOptions struct {
+	// Providers are the provider types to partition for. Defaults to all providers with known fields.
+	Providers []string
+	// Fields overrides or extends DefaultProviderFields.
+	Fields map[string]ProviderFields
+	// Disabled are provider types whose partitions are omitted, also if they are contained in Providers.
+	Disabled []string
+}
+
+// PartitionByProvider splits machine images per provider type. The versions of a partition only contain the core
+// fields and the fields of its provider, so that one catalog can feed several provider extensions without failing
+// their validation because of foreign fields. All versions are contained in every partition.
+func PartitionByProvider(images []MachineImage, options *PartitionOptions) (map[string][]MachineImage, error) {
+	if options == nil {
+		options = &PartitionOptions{}
+	}
+
+	fields := map[string]ProviderFields{}
+	for provider, providerFields := range DefaultProviderFields {
+		fields[provider] = providerFields
+	}
+	for provider, providerFields := range options.Fields {
+		fields[provider] = providerFields
+	}
+
+	providers := options.Providers
+	if len(providers) == 0 {
+		for provider := range fields {
+			providers = append(providers, provider)
+		}
+		sort.Strings(providers)
+	}
+
+	partitions := map[string][]MachineImage{}
+	for _, provider := range providers {
+		if contains(options.Disabled, provider) {
+			continue
+		}
+		providerFields, ok := fields[provider]
+		if !ok {
+			return nil, fmt.Errorf("unknown fields of provider %s", provider)
+		}
+
+		partition := make([]MachineImage, 0, len(images))
+		for _, image := range images {
+			partitioned := MachineImage{Name: image.Name, Versions: make([]MachineImageVersion, 0, len(image.Versions))}
+			for _, version := range image.Versions {
+				partitioned.Versions = append(partitioned.Versions, partitionVersion(version, providerFields))
+			}
+			partition = append(partition, partitioned)
+		}
+		partitions[provider] = partition
+	}
+	return partitions, nil
+}
+
+func partitionVersion(version MachineImageVersion, fields ProviderFields) MachineImageVersion {
+	result := MachineImageVersion{}
+	for _, field := range CoreVersionFields {
+		if value, ok := version[field]; ok {
+			result[field] = value
+		}
+	}
+	for _, field := range fields.Version {
+		value, ok := version[field]
+		if !ok {
+			continue
+		}
+		regions, isList := value.([]interface{})
+		if field != "regions" || !isList {
+			result[field] = value
+			continue
+		}
+
+		partitionedRegions := make([]interface{}, 0, len(regions))
+		for _, entry := range regions {
+			region, ok := entry.(map[string]interface{})
+			if !ok {
+				partitionedRegions = append(partitionedRegions, entry)
+				continue
+			}
+			partitionedRegion := map[string]interface{}{}
+			for _, regionField := range fields.Region {
+				if regionValue, ok := region[regionField]; ok {
+					partitionedRegion[regionField] = regionValue
+				}
+			}
+			partitionedRegions = append(partitionedRegions, partitionedRegion)
+		}
+		result[field] = partitionedRegions
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("partition", func() {
+
+	images := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{
+				"version":        "318.8.0",
+				"classification": "supported",
+				"cri":            []interface{}{map[string]interface{}{"name": "containerd"}},
+				"regions": []interface{}{
+					map[string]interface{}{"name": "eu-west-1", "ami": "ami-1", "id": "image-1"},
+				},
+				"image": "projects/gardenlinux/318-8-0",
+				"urn":   "sap:gardenlinux:greatest:318.8.0",
+			},
+		}},
+	}
+
+	It("should keep the core fields and the fields of the provider", func() {
+		partitions, err := PartitionByProvider(images, &PartitionOptions{Providers: []string{"aws", "gcp", "azure"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(partitions).To(HaveLen(3))
+
+		core := MachineImageVersion{
+			"version":        "318.8.0",
+			"classification": "supported",
+			"cri":            []interface{}{map[string]interface{}{"name": "containerd"}},
+		}
+		with := func(fields MachineImageVersion) []MachineImage {
+			version := MachineImageVersion{}
+			for key, value := range core {
+				version[key] = value
+			}
+			for key, value := range fields {
+				version[key] = value
+			}
+			return []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{version}}}
+		}
+
+		Expect(partitions["aws"]).To(Equal(with(MachineImageVersion{
+			"regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}},
+		})))
+		Expect(partitions["gcp"]).To(Equal(with(MachineImageVersion{"image": "projects/gardenlinux/318-8-0"})))
+		Expect(partitions["azure"]).To(Equal(with(MachineImageVersion{"urn": "sap:gardenlinux:greatest:318.8.0"})))
+	})
+
+	It("should not modify the machine images", func() {
+		_, err := PartitionByProvider(images, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(images[0].Versions[0]).To(HaveKey("urn"))
+		Expect(images[0].Versions[0]["regions"]).To(ContainElement(HaveKey("id")))
+	})
+
+	It("should partition for all known and configured providers", func() {
+		partitions, err := PartitionByProvider(images, &PartitionOptions{Fields: map[string]ProviderFields{
+			"metal": {Version: []string{"id"}},
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(partitions).To(HaveLen(len(DefaultProviderFields) + 1))
+		Expect(partitions["metal"][0].Versions[0]).NotTo(HaveKey("regions"))
+	})
+
+	It("should fail for unknown providers", func() {
+		_, err := PartitionByProvider(images, &PartitionOptions{Providers: []string{"unknown"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
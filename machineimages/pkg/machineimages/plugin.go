@@ -0,0 +1,240 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PluginAPIVersion is the api version of the requests to plugins.
+const PluginAPIVersion = "machineimages.gardener.cloud/plugin/v1"
+
+const (
+	// DefaultPluginTimeout is the time after which plugins without timeout are killed.
+	DefaultPluginTimeout = 30 * time.Second
+	// DefaultPluginMaxOutputBytes limits the response of plugins without output limit.
+	DefaultPluginMaxOutputBytes = 16 << 20
+
+	// pluginMaxErrorBytes limits the standard error of plugins which is added to their errors.
+	pluginMaxErrorBytes = 4 << 10
+)
+
+// ReasonPlugin is the reason of the findings of plugins without reason.
+const ReasonPlugin = "Plugin"
+
+// Plugin is a transformation of the computed machine images which runs in a separate process, so that
+// landscape-specific transformations do not have to be linked into the binary. The command is executed without shell
+// in an empty temporary working directory and with only the configured environment. It reads a PluginRequest as json
+// from its standard input and writes a PluginResponse as json to its standard output. A plugin which exits with a
+// non-zero code fails the computation, its standard error is part of the error. On timeout, the plugin is killed
+// together with the processes it started, except on platforms without process groups.
+//
+// The process is not isolated beyond this, it can access the network and the file system with the permissions of the
+// computation, so only trusted commands must be configured. With the network policy guard, plugins are not executed.
+type Plugin struct {
+	// Name identifies the plugin in errors, logs and findings.
+	Name string `json:"name" yaml:"name"`
+	// Command is the executable and its arguments.
+	Command []string `json:"command" yaml:"command"`
+	// Env are the environment variables of the plugin. The environment of the computation is not inherited, so that
+	// its secrets are not exposed to the plugin.
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	// TimeoutSeconds is the time after which the plugin is killed. Defaults to DefaultPluginTimeout.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty"`
+	// MaxOutputBytes limits the size of the response. Defaults to DefaultPluginMaxOutputBytes.
+	MaxOutputBytes int `json:"maxOutputBytes,omitempty" yaml:"maxOutputBytes,omitempty"`
+}
+
+// PluginRequest is the standard input of plugins.
+type PluginRequest struct {
+	APIVersion    string         `json:"apiVersion"`
+	MachineImages []MachineImage `json:"machineImages"`
+}
+
+// PluginResponse is the standard output of plugins. Unknown fields are rejected.
+type PluginResponse struct {
+	// MachineImages replace the machine images of the request.
+	MachineImages []MachineImage `json:"machineImages"`
+	// Findings are reported to the reporter of the computation. Findings without reason get ReasonPlugin.
+	Findings []ReportEntry `json:"findings,omitempty"`
+}
+
+// Validate returns an error if the plugin is invalid.
+func (p *Plugin) Validate() error {
+	if len(p.Name) == 0 {
+		return errors.New("name must be set")
+	}
+	if len(p.Command) == 0 || len(p.Command[0]) == 0 {
+		return fmt.Errorf("plugin %s: command must be set", p.Name)
+	}
+	if p.TimeoutSeconds < 0 || p.MaxOutputBytes < 0 {
+		return fmt.Errorf("plugin %s: timeoutSeconds and maxOutputBytes must not be negative", p.Name)
+	}
+	for key := range p.Env {
+		if len(key) == 0 || strings.ContainsAny(key, "=\x00") {
+			return fmt.Errorf("plugin %s: invalid environment variable %q", p.Name, key)
+		}
+	}
+	return nil
+}
+
+// Transform executes the plugin with the machine images and returns the machine images of its response. The
+// signature matches the stages of the engine package.
+func (p *Plugin) Transform(ctx context.Context, images []MachineImage) ([]MachineImage, error) {
+	if err := CheckNetworkAccess(ctx, "exec plugin", p.Name); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	request, err := json.Marshal(&PluginRequest{APIVersion: PluginAPIVersion, MachineImages: images})
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "machineimages-plugin-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	timeout := DefaultPluginTimeout
+	if p.TimeoutSeconds > 0 {
+		timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	maxOutput := DefaultPluginMaxOutputBytes
+	if p.MaxOutputBytes > 0 {
+		maxOutput = p.MaxOutputBytes
+	}
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stdout := &limitedBuffer{limit: maxOutput}
+	stderr := &limitedBuffer{limit: pluginMaxErrorBytes}
+	cmd := exec.Command(p.Command[0], p.Command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = p.environment()
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	LoggerFromContext(ctx).Info("Executing plugin", "plugin", p.Name)
+	if err := startPluginProcess(cmd); err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w", p.Name, err)
+	}
+	// the process group is killed, as processes which the plugin started would keep the output open and block Wait
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-runCtx.Done():
+			_ = killPluginProcess(cmd)
+		case <-exited:
+		}
+	}()
+	err = cmd.Wait()
+	close(exited)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if runCtx.Err() != nil {
+		return nil, fmt.Errorf("plugin %s timed out after %s", p.Name, timeout)
+	}
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if len(message) > 0 {
+			return nil, fmt.Errorf("plugin %s failed: %w: %s", p.Name, err, message)
+		}
+		return nil, fmt.Errorf("plugin %s failed: %w", p.Name, err)
+	}
+	if stdout.exceeded {
+		return nil, fmt.Errorf("plugin %s: response exceeds %d bytes", p.Name, maxOutput)
+	}
+
+	response := &PluginResponse{}
+	decoder := json.NewDecoder(bytes.NewReader(stdout.Bytes()))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(response); err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid response: %w", p.Name, err)
+	}
+	if problems := validateMachineImageList("machineImages", response.MachineImages); len(problems) > 0 {
+		return nil, fmt.Errorf("plugin %s: invalid response: %s", p.Name, strings.Join(problems, "; "))
+	}
+
+	_, reporter := FromContext(ctx)
+	for _, finding := range response.Findings {
+		if len(finding.Reason) == 0 {
+			finding.Reason = ReasonPlugin
+		}
+		finding.Message = fmt.Sprintf("plugin %s: %s", p.Name, finding.Message)
+		reporter.Report(finding)
+	}
+	if response.MachineImages == nil {
+		response.MachineImages = []MachineImage{}
+	}
+	return response.MachineImages, nil
+}
+
+// environment returns the sorted environment of the plugin. It is never nil, as a nil environment would inherit the
+// environment of the computation.
+func (p *Plugin) environment() []string {
+	env := []string{}
+	for key, value := range p.Env {
+		env = append(env, key+"="+value)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// applyPlugins transforms the machine images with the plugins in their order.
+func applyPlugins(ctx context.Context, images []MachineImage, plugins []*Plugin) ([]MachineImage, error) {
+	for _, plugin := range plugins {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var err error
+		if images, err = plugin.Transform(ctx, images); err != nil {
+			return nil, err
+		}
+	}
+	return images, nil
+}
+
+// limitedBuffer keeps the first limit bytes which are written to it and discards the rest. It does not embed the
+// buffer, as io.Copy would bypass the limit via the ReadFrom method of the buffer.
+type limitedBuffer struct {
+	buffer   bytes.Buffer
+	limit    int
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	if free := b.limit - b.buffer.Len(); len(data) > free {
+		b.exceeded = true
+		if free > 0 {
+			b.buffer.Write(data[:free])
+		}
+		return len(data), nil
+	}
+	return b.buffer.Write(data)
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buffer.Bytes()
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buffer.String()
+}
@@ -0,0 +1,25 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !js && !wasip1 && !windows
+// +build !js,!wasip1,!windows
+
+package machineimages
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// startPluginProcess starts the plugin in its own process group, so that killPluginProcess also kills the processes
+// which the plugin started.
+func startPluginProcess(cmd *exec.Cmd) error {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd.Start()
+}
+
+// killPluginProcess kills the process group of the plugin.
+func killPluginProcess(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,22 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build js || wasip1 || windows
+// +build js wasip1 windows
+
+package machineimages
+
+import (
+	"os/exec"
+)
+
+// startPluginProcess starts the plugin. Without process groups, only the plugin itself is killed.
+func startPluginProcess(cmd *exec.Cmd) error {
+	return cmd.Start()
+}
+
+// killPluginProcess kills the plugin.
+func killPluginProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("plugins", func() {
+
+	var (
+		ctx      context.Context
+		reporter *Report
+	)
+
+	BeforeEach(func() {
+		reporter = NewReport()
+		ctx = NewContext(context.Background(), logr.Discard(), reporter)
+	})
+
+	images := func() []MachineImage {
+		return []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0"}}}}
+	}
+
+	shell := func(script string) *Plugin {
+		return &Plugin{Name: "test", Command: []string{"/bin/sh", "-c", script}, Env: map[string]string{"PATH": "/usr/bin:/bin"}}
+	}
+
+	It("should pass the machine images on stdin and return the machine images of the response", func() {
+		plugin := shell(`sed -e 's/"apiVersion":"[^"]*",//' -e 's/"22.4.0"/"22.4.1"/'`)
+
+		result, err := plugin.Transform(ctx, images())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.1"}}}}))
+	})
+
+	It("should only pass the configured environment and report the findings", func() {
+		Expect(os.Setenv("MACHINEIMAGES_PLUGIN_SECRET", "secret")).To(Succeed())
+		defer os.Unsetenv("MACHINEIMAGES_PLUGIN_SECRET")
+		plugin := shell(`printf '{"machineImages": [], "findings": [{"image": "ubuntu", "message": "%s/%s"}]}' "$MACHINEIMAGES_PLUGIN_SECRET" "$GIVEN"`)
+		plugin.Env["GIVEN"] = "given"
+
+		result, err := plugin.Transform(ctx, images())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(BeEmpty())
+		Expect(reporter.Entries()).To(Equal([]ReportEntry{{Image: OsNameUbuntu, Reason: ReasonPlugin, Message: "plugin test: /given"}}))
+	})
+
+	It("should fail with the standard error of failed plugins", func() {
+		_, err := shell(`echo broken >&2; exit 3`).Transform(ctx, images())
+		Expect(err).To(MatchError("plugin test failed: exit status 3: broken"))
+	})
+
+	It("should kill plugins after their timeout", func() {
+		plugin := shell(`exec sleep 10`)
+		plugin.TimeoutSeconds = 1
+
+		_, err := plugin.Transform(ctx, images())
+		Expect(err).To(MatchError("plugin test timed out after 1s"))
+	})
+
+	It("should kill the processes which plugins started after their timeout", func() {
+		plugin := shell(`sleep 10; true`)
+		plugin.TimeoutSeconds = 1
+
+		started := time.Now()
+		_, err := plugin.Transform(ctx, images())
+		Expect(err).To(MatchError("plugin test timed out after 1s"))
+		Expect(time.Since(started)).To(BeNumerically("<", 5*time.Second))
+	})
+
+	It("should reject invalid responses", func() {
+		_, err := shell(`echo '{"machineImages": [], "unknown": true}'`).Transform(ctx, images())
+		Expect(err).To(MatchError(ContainSubstring(`plugin test: invalid response: json: unknown field "unknown"`)))
+
+		_, err = shell(`echo '{"machineImages": [{"name": "ubuntu", "versions": [{}]}]}'`).Transform(ctx, images())
+		Expect(err).To(MatchError("plugin test: invalid response: machineImages[0].versions[0].version: must be set"))
+
+		plugin := shell(`echo '{"machineImages": []}'`)
+		plugin.MaxOutputBytes = 8
+		_, err = plugin.Transform(ctx, images())
+		Expect(err).To(MatchError("plugin test: response exceeds 8 bytes"))
+	})
+
+	It("should not execute plugins with the network policy guard", func() {
+		_, err := shell(`echo '{"machineImages": []}'`).Transform(WithNetworkPolicyGuard(ctx), images())
+		Expect(IsNetworkAccessDenied(err)).To(BeTrue())
+	})
+
+	It("should apply the plugins of the options in the computation", func() {
+		providerImages := []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0", "image": "a"}}}}
+		options := &ComputeMachineImagesOptions{Plugins: []*Plugin{
+			shell(`sed -e 's/"apiVersion":"[^"]*",//' -e 's/"image":"a"/"image":"b"/'`),
+		}}
+
+		result, err := ComputeMachineImagesWithOptions(ctx, logr.Discard(), images(), nil, providerImages, nil, nil, nil, nil, options)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0", "image": "b"}}}}))
+	})
+
+	It("should validate the plugins of the imports", func() {
+		imports := &Imports{ComputeMachineImagesOptions: ComputeMachineImagesOptions{Plugins: []*Plugin{
+			{Name: "a", Command: []string{"a"}},
+			{Name: "a", Command: []string{"a"}, TimeoutSeconds: -1},
+			{Name: "b", Env: map[string]string{"A=B": "c"}},
+		}}}
+
+		err := ValidateImports(imports)
+		Expect(err).To(MatchError(ContainSubstring("plugins[1]: plugin a: timeoutSeconds and maxOutputBytes must not be negative")))
+		Expect(err).To(MatchError(ContainSubstring("plugins[1]: duplicate plugin a")))
+		Expect(err).To(MatchError(ContainSubstring("plugins[2]: plugin b: command must be set")))
+	})
+})
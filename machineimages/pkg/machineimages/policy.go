@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PolicyAction determines what happens with versions which violate a policy.
+type PolicyAction string
+
+const (
+	// PolicyActionDrop removes violating versions from the result.
+	PolicyActionDrop = PolicyAction("drop")
+	// PolicyActionError fails the computation if there are violating versions.
+	PolicyActionError = PolicyAction("error")
+)
+
+// applyMinVersions removes or reports all versions which are lower than the minimum version configured for their
+// image name. Images without minimum version are not changed.
+func applyMinVersions(ctx context.Context, images []OsImage, minVersions map[string]string, action PolicyAction) ([]OsImage, error) {
+	if len(minVersions) == 0 {
+		return images, nil
+	}
+
+	if action == "" {
+		action = PolicyActionDrop
+	}
+	if action != PolicyActionDrop && action != PolicyActionError {
+		return nil, fmt.Errorf("policy action does not exist %s", action)
+	}
+
+	_, reporter := FromContext(ctx)
+
+	result := []OsImage{}
+	violations := []string{}
+	for _, image := range images {
+		minVersion, ok := minVersions[image.Name]
+		version := image.Version.getVersion()
+		if ok && version != nil && compareVersions(*version, minVersion) < 0 {
+			violations = append(violations, fmt.Sprintf("%s:%s < %s", image.Name, *version, minVersion))
+			if action == PolicyActionDrop {
+				reporter.Report(ReportEntry{
+					Image:   image.Name,
+					Version: *version,
+					Reason:  ReasonBelowMinVersion,
+					Message: fmt.Sprintf("version is lower than the minimum version %s", minVersion),
+				})
+			}
+			continue
+		}
+		result = append(result, image)
+	}
+
+	if action == PolicyActionError && len(violations) > 0 {
+		sort.Strings(violations)
+		return nil, fmt.Errorf("machine image versions are lower than the minimum version: %s", strings.Join(violations, ", "))
+	}
+
+	return result, nil
+}
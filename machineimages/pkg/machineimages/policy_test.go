@@ -0,0 +1,48 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("policy", func() {
+
+	Context("applyMinVersions", func() {
+
+		images := []OsImage{
+			{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": "184.0.0"}},
+			{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": "318.9.0"}},
+			{Name: OsNameUbuntu, Version: MachineImageVersion{"version": "18.4.20210415"}},
+		}
+		minVersions := map[string]string{OsNameGardenLinux: "318.8.0"}
+
+		It("should drop versions lower than the minimum version", func() {
+			result, err := applyMinVersions(context.Background(), images, minVersions, "")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(images[1:]))
+		})
+
+		It("should report versions lower than the minimum version", func() {
+			_, err := applyMinVersions(context.Background(), images, minVersions, PolicyActionError)
+			Expect(err).To(MatchError(ContainSubstring("gardenlinux:184.0.0 < 318.8.0")))
+		})
+
+		It("should reject unknown actions", func() {
+			_, err := applyMinVersions(context.Background(), images, minVersions, PolicyAction("warn"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reject invalid minimum versions", func() {
+			imports := &Imports{ComputeMachineImagesOptions: ComputeMachineImagesOptions{
+				MinVersions: map[string]string{OsNameUbuntu: "latest", OsNameGardenLinux: ""}}}
+			Expect(ValidateImports(imports)).To(MatchError("invalid imports: minVersions: empty minimum version of image gardenlinux; " +
+				`minVersions: invalid minimum version of image ubuntu: invalid version "latest": part "latest" is not a number`))
+		})
+	})
+})
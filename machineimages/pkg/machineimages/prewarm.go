@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"sort"
+)
+
+// PrewarmGlobalRegion is the region of the artifacts of a pre-warm manifest without seed regions, if the artifacts
+// are not specific to a region.
+const PrewarmGlobalRegion = "global"
+
+// defaultPrewarmArtifactFields are the fields of versions and their regions which reference an artifact, in order of
+// precedence.
+var defaultPrewarmArtifactFields = append(append([]string{}, defaultTerraformIDFields...), DefaultArtifactReferenceField)
+
+// PrewarmManifest lists the artifacts per seed region which registry and image cache operators pull before shoots
+// start to use the versions.
+type PrewarmManifest struct {
+	Regions []PrewarmRegion `json:"regions"`
+}
+
+// PrewarmRegion lists the artifacts of a region.
+type PrewarmRegion struct {
+	Name      string            `json:"name"`
+	Artifacts []PrewarmArtifact `json:"artifacts"`
+}
+
+// PrewarmArtifact is an artifact of a machine image version, e.g. an ami, an image id or an OCI reference.
+type PrewarmArtifact struct {
+	Image          string `json:"image"`
+	Version        string `json:"version"`
+	Artifact       string `json:"artifact"`
+	Architecture   string `json:"architecture,omitempty"`
+	Classification string `json:"classification,omitempty"`
+}
+
+// PrewarmOptions configures the pre-warm manifest.
+type PrewarmOptions struct {
+	// Regions are the seed regions. Artifacts which are not specific to a region, e.g. gcp images or OCI artifacts, are
+	// listed in every seed region. Defaults to the regions of the versions, or PrewarmGlobalRegion if there are none.
+	Regions []string
+	// Classifications are the classifications of the versions to pre-warm. Defaults to preview and supported, the
+	// versions which shoots start to use. Versions without classification are always pre-warmed.
+	Classifications []string
+	// ArtifactFields are the fields of versions and regions which reference an artifact, in order of precedence.
+	// Defaults to ami, id, image, urn and ociReference.
+	ArtifactFields []string
+}
+
+// NewPrewarmManifest returns the artifacts of the machine images per region. Regions are ordered by name, artifacts in
+// the order of the machine images.
+func NewPrewarmManifest(images []MachineImage, options *PrewarmOptions) *PrewarmManifest {
+	if options == nil {
+		options = &PrewarmOptions{}
+	}
+	fields := options.ArtifactFields
+	if len(fields) == 0 {
+		fields = defaultPrewarmArtifactFields
+	}
+	classifications := options.Classifications
+	if len(classifications) == 0 {
+		classifications = []string{ClassificationPreview, ClassificationSupported}
+	}
+
+	type versionArtifacts struct {
+		global   []PrewarmArtifact
+		regional map[string][]PrewarmArtifact
+	}
+	collected := []versionArtifacts{}
+	discovered := map[string]bool{}
+	hasGlobal := false
+	for _, image := range images {
+		for _, v := range image.Versions {
+			classification := v.getClassification()
+			if classification != nil && !contains(classifications, *classification) {
+				continue
+			}
+			newArtifact := func(fields map[string]interface{}, artifact string) PrewarmArtifact {
+				result := PrewarmArtifact{Image: image.Name, Version: versionOrEmpty(v), Artifact: artifact}
+				result.Architecture, _ = fields["architecture"].(string)
+				if classification != nil {
+					result.Classification = *classification
+				}
+				return result
+			}
+
+			artifacts := versionArtifacts{regional: map[string][]PrewarmArtifact{}}
+			if artifact, ok := terraformImageID(v, fields); ok {
+				artifacts.global = append(artifacts.global, newArtifact(v, artifact))
+				hasGlobal = true
+			}
+			regions, _ := v["regions"].([]interface{})
+			for _, entry := range regions {
+				region, ok := entry.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := region["name"].(string)
+				if artifact, ok := terraformImageID(region, fields); ok && len(name) > 0 {
+					artifacts.regional[name] = append(artifacts.regional[name], newArtifact(region, artifact))
+					discovered[name] = true
+				}
+			}
+			collected = append(collected, artifacts)
+		}
+	}
+
+	regionNames := append([]string{}, options.Regions...)
+	if len(regionNames) == 0 {
+		for name := range discovered {
+			regionNames = append(regionNames, name)
+		}
+		if len(regionNames) == 0 && hasGlobal {
+			regionNames = []string{PrewarmGlobalRegion}
+		}
+	}
+	sort.Strings(regionNames)
+
+	manifest := &PrewarmManifest{Regions: []PrewarmRegion{}}
+	for _, name := range regionNames {
+		region := PrewarmRegion{Name: name, Artifacts: []PrewarmArtifact{}}
+		for _, artifacts := range collected {
+			region.Artifacts = append(region.Artifacts, artifacts.global...)
+			region.Artifacts = append(region.Artifacts, artifacts.regional[name]...)
+		}
+		manifest.Regions = append(manifest.Regions, region)
+	}
+	return manifest
+}
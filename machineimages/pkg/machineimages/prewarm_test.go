@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("prewarm", func() {
+
+	images := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.1.0", "classification": "preview", "ociReference": "ghcr.io/gardenlinux/gardenlinux:934.1.0"},
+			{"version": "318.9.0", "classification": "supported", "regions": []interface{}{
+				map[string]interface{}{"name": "eu-west-1", "ami": "ami-a"},
+				map[string]interface{}{"name": "eu-west-1", "ami": "ami-b", "architecture": "arm64"},
+				map[string]interface{}{"name": "us-east-1", "ami": "ami-c"},
+			}},
+			{"version": "184.0.0", "classification": "deprecated", "regions": []interface{}{
+				map[string]interface{}{"name": "eu-west-1", "ami": "ami-d"},
+			}},
+		}},
+		{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0", "regions": []interface{}{
+			map[string]interface{}{"name": "us-east-1", "ami": "ami-e"},
+		}}}},
+	}
+
+	It("should list the artifacts of new versions per region", func() {
+		Expect(NewPrewarmManifest(images, nil)).To(Equal(&PrewarmManifest{Regions: []PrewarmRegion{
+			{Name: "eu-west-1", Artifacts: []PrewarmArtifact{
+				{Image: OsNameGardenLinux, Version: "934.1.0", Artifact: "ghcr.io/gardenlinux/gardenlinux:934.1.0", Classification: "preview"},
+				{Image: OsNameGardenLinux, Version: "318.9.0", Artifact: "ami-a", Classification: "supported"},
+				{Image: OsNameGardenLinux, Version: "318.9.0", Artifact: "ami-b", Architecture: "arm64", Classification: "supported"},
+			}},
+			{Name: "us-east-1", Artifacts: []PrewarmArtifact{
+				{Image: OsNameGardenLinux, Version: "934.1.0", Artifact: "ghcr.io/gardenlinux/gardenlinux:934.1.0", Classification: "preview"},
+				{Image: OsNameGardenLinux, Version: "318.9.0", Artifact: "ami-c", Classification: "supported"},
+				{Image: OsNameUbuntu, Version: "22.4.0", Artifact: "ami-e"},
+			}},
+		}}))
+	})
+
+	It("should only list the given seed regions and classifications", func() {
+		Expect(NewPrewarmManifest(images, &PrewarmOptions{Regions: []string{"eu-west-1", "ap-south-1"}, Classifications: []string{"deprecated"}})).To(Equal(&PrewarmManifest{Regions: []PrewarmRegion{
+			{Name: "ap-south-1", Artifacts: []PrewarmArtifact{}},
+			{Name: "eu-west-1", Artifacts: []PrewarmArtifact{
+				{Image: OsNameGardenLinux, Version: "184.0.0", Artifact: "ami-d", Classification: "deprecated"},
+			}},
+		}}))
+	})
+
+	It("should use the global region without regions", func() {
+		manifest := NewPrewarmManifest([]MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0", "image": "ubuntu-2204"}}}}, nil)
+		Expect(manifest.Regions).To(Equal([]PrewarmRegion{{Name: PrewarmGlobalRegion, Artifacts: []PrewarmArtifact{
+			{Image: OsNameUbuntu, Version: "22.4.0", Artifact: "ubuntu-2204"},
+		}}}))
+	})
+})
@@ -0,0 +1,177 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// Stage is a landscape stage with its own catalog, e.g. dev, canary or prod.
+type Stage struct {
+	// Name of the stage.
+	Name string `json:"name"`
+	// CatalogDir is the root directory of the catalog of the stage.
+	CatalogDir string `json:"catalogDir"`
+	// File is the slash separated path of the catalog file, relative to CatalogDir, to which promoted versions are
+	// written.
+	File string `json:"file"`
+	// LoadOptions select the files of the catalog and its strictness, e.g. lenient for dev and strict for prod.
+	// Optional.
+	LoadOptions *CatalogLoadOptions `json:"loadOptions,omitempty"`
+}
+
+// PromotionGate decides whether a version of a stage may be promoted to the next stage.
+type PromotionGate interface {
+	// CheckPromotion returns a reason if the promotion of the entry is blocked and an empty string otherwise.
+	CheckPromotion(ctx context.Context, from Stage, entry CatalogEntry) (string, error)
+}
+
+// PromotionPolicy decides which versions of a stage are promoted.
+type PromotionPolicy struct {
+	// Images restricts the promotion to these images. If empty, all images are promoted.
+	Images []string `json:"images,omitempty"`
+	// Classifications are the classifications of versions which are promoted. If empty, only supported versions are
+	// promoted.
+	Classifications []string `json:"classifications,omitempty"`
+	// Gates are consulted for each candidate, all of them must allow the promotion.
+	Gates []PromotionGate `json:"-"`
+	// DryRun computes the result without writing the catalog of the target stage.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// PromotionSkip is a version which was not promoted.
+type PromotionSkip struct {
+	VersionRef `json:",inline"`
+	Reason     string `json:"reason"`
+}
+
+// PromotionResult lists the outcome of a promotion.
+type PromotionResult struct {
+	// Promoted are the versions which were added to the catalog of the target stage.
+	Promoted []VersionRef `json:"promoted"`
+	// Skipped are the candidates which were not promoted together with the reason.
+	Skipped []PromotionSkip `json:"skipped"`
+}
+
+// PromoteCatalog copies the versions of the source stage which are not yet part of the catalog of the target stage
+// and which satisfy the policy into the catalog file of the target stage. The file is created if it does not exist
+// and is written in the current catalog format, its comments are kept.
+func PromoteCatalog(ctx context.Context, from, to Stage, policy *PromotionPolicy) (*PromotionResult, error) {
+	log := LoggerFromContext(ctx).WithValues("from", from.Name, "to", to.Name)
+
+	if policy == nil {
+		policy = &PromotionPolicy{}
+	}
+	classifications := policy.Classifications
+	if len(classifications) == 0 {
+		classifications = []string{ClassificationSupported}
+	}
+
+	source, err := LoadCatalog(from.CatalogDir, from.LoadOptions)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load catalog of stage %s: %w", from.Name, err)
+	}
+	target, err := LoadCatalog(to.CatalogDir, to.LoadOptions)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load catalog of stage %s: %w", to.Name, err)
+	}
+	source.ReportSkipped(ReporterFromContext(ctx))
+	target.ReportSkipped(ReporterFromContext(ctx))
+
+	result := &PromotionResult{
+		Promoted: []VersionRef{},
+		Skipped:  []PromotionSkip{},
+	}
+	promoted := []CatalogEntry{}
+
+	for _, entry := range source.Entries {
+		ref := VersionRef{Image: entry.Name, Version: versionOrEmpty(entry.Version)}
+		if len(target.SourcesOf(ref.Image, ref.Version)) > 0 || containsEntry(promoted, ref) {
+			continue
+		}
+		if len(policy.Images) > 0 && !contains(policy.Images, ref.Image) {
+			continue
+		}
+
+		reason, err := checkPromotion(ctx, from, entry, classifications, policy.Gates)
+		if err != nil {
+			return nil, err
+		}
+		if len(reason) > 0 {
+			log.V(1).Info("Skipping promotion", "image", ref.Image, "version", ref.Version, "reason", reason)
+			result.Skipped = append(result.Skipped, PromotionSkip{VersionRef: ref, Reason: reason})
+			continue
+		}
+
+		promoted = append(promoted, entry)
+		result.Promoted = append(result.Promoted, ref)
+	}
+
+	if len(promoted) == 0 || policy.DryRun {
+		return result, nil
+	}
+
+	log.Info("Promoting versions", "count", len(promoted), "file", to.File)
+	path := filepath.Join(to.CatalogDir, filepath.FromSlash(to.File))
+	if err := InjectFault(ctx, FaultPointApply, path); err != nil {
+		return nil, fmt.Errorf("unable to promote versions to %s: %w", to.Name, err)
+	}
+	if err := appendToCatalogFile(path, promoted); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func checkPromotion(ctx context.Context, from Stage, entry CatalogEntry, classifications []string, gates []PromotionGate) (string, error) {
+	classification := entry.Version.getClassification()
+	if classification == nil || !contains(classifications, *classification) {
+		return "classification is not promoted", nil
+	}
+
+	expired, err := entry.Version.isExpired()
+	if err != nil {
+		return "", err
+	}
+	if expired {
+		return "version is expired", nil
+	}
+
+	for _, gate := range gates {
+		reason, err := gate.CheckPromotion(ctx, from, entry)
+		if err != nil {
+			return "", err
+		}
+		if len(reason) > 0 {
+			return reason, nil
+		}
+	}
+	return "", nil
+}
+
+// appendToCatalogFile adds the entries to the images of a catalog file with the round-trip editor, so that the
+// comments and the order of the keys of the file are kept. Versions of images which are not yet part of the file are
+// added as new images at the end.
+func appendToCatalogFile(path string, entries []CatalogEntry) error {
+	return editCatalogFile(path, func(doc *catalogDocument) error {
+		for _, entry := range entries {
+			if err := doc.appendVersion(entry.Name, entry.Version); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func containsEntry(entries []CatalogEntry, ref VersionRef) bool {
+	for _, entry := range entries {
+		if entry.Name == ref.Image && versionOrEmpty(entry.Version) == ref.Version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type blockingGate struct {
+	version string
+}
+
+func (g blockingGate) CheckPromotion(_ context.Context, _ Stage, entry CatalogEntry) (string, error) {
+	if versionOrEmpty(entry.Version) == g.version {
+		return "blocked", nil
+	}
+	return "", nil
+}
+
+var _ = Describe("promote", func() {
+
+	var (
+		dir      string
+		from, to Stage
+	)
+
+	writeFile := func(path, content string) {
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "promote")
+		Expect(err).NotTo(HaveOccurred())
+
+		from = Stage{Name: "dev", CatalogDir: filepath.Join(dir, "dev"), File: "images.yaml"}
+		to = Stage{Name: "canary", CatalogDir: filepath.Join(dir, "canary"), File: "images.yaml"}
+
+		writeFile(filepath.Join(from.CatalogDir, "images.yaml"), `
+- name: gardenlinux
+  versions:
+  - version: 318.9.0
+    classification: supported
+  - version: 934.0.0
+    classification: supported
+  - version: 935.0.0
+    classification: preview
+- name: ubuntu
+  versions:
+  - version: 18.4.0
+    classification: supported
+`)
+		writeFile(filepath.Join(to.CatalogDir, "images.yaml"), `
+- name: gardenlinux
+  versions:
+  - version: 318.9.0
+    classification: supported
+`)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should promote new supported versions", func() {
+		result, err := PromoteCatalog(context.Background(), from, to, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Promoted).To(Equal([]VersionRef{
+			{Image: OsNameGardenLinux, Version: "934.0.0"},
+			{Image: OsNameUbuntu, Version: "18.4.0"},
+		}))
+		Expect(result.Skipped).To(Equal([]PromotionSkip{
+			{VersionRef: VersionRef{Image: OsNameGardenLinux, Version: "935.0.0"}, Reason: "classification is not promoted"},
+		}))
+
+		catalog, err := LoadCatalog(to.CatalogDir, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(catalog.MachineImages()).To(Equal([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "318.9.0", "classification": "supported"},
+				{"version": "934.0.0", "classification": "supported"},
+			}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "18.4.0", "classification": "supported"},
+			}},
+		}))
+
+		data, err := ioutil.ReadFile(filepath.Join(to.CatalogDir, to.File))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("apiVersion: " + CatalogAPIVersion))
+	})
+
+	It("should promote each version once and keep the comments of the target catalog", func() {
+		writeFile(filepath.Join(to.CatalogDir, "images.yaml"), `# promoted from dev
+- name: gardenlinux
+  versions:
+  - version: 318.9.0 # first release
+    classification: supported
+`)
+		_, err := PromoteCatalog(context.Background(), from, to, nil)
+		Expect(err).NotTo(HaveOccurred())
+		result, err := PromoteCatalog(context.Background(), from, to, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Promoted).To(BeEmpty())
+
+		data, err := ioutil.ReadFile(filepath.Join(to.CatalogDir, to.File))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring("# promoted from dev"))
+		Expect(string(data)).To(ContainSubstring("- version: 318.9.0 # first release"))
+		catalog, err := LoadCatalog(to.CatalogDir, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(catalog.Entries).To(HaveLen(3))
+	})
+
+	It("should skip expired versions", func() {
+		writeFile(filepath.Join(from.CatalogDir, "images.yaml"), `
+- name: ubuntu
+  versions:
+  - version: 16.4.0
+    classification: supported
+    expirationDate: "2020-01-01T00:00:00Z"
+`)
+		result, err := PromoteCatalog(context.Background(), from, to, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Promoted).To(BeEmpty())
+		Expect(result.Skipped).To(Equal([]PromotionSkip{
+			{VersionRef: VersionRef{Image: OsNameUbuntu, Version: "16.4.0"}, Reason: "version is expired"},
+		}))
+	})
+
+	It("should respect images and gates of the policy", func() {
+		result, err := PromoteCatalog(context.Background(), from, to, &PromotionPolicy{
+			Images: []string{OsNameGardenLinux},
+			Gates:  []PromotionGate{blockingGate{version: "934.0.0"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Promoted).To(BeEmpty())
+		Expect(result.Skipped).To(ContainElement(
+			PromotionSkip{VersionRef: VersionRef{Image: OsNameGardenLinux, Version: "934.0.0"}, Reason: "blocked"}))
+	})
+
+	It("should not write the catalog in dry run mode", func() {
+		to.File = "promoted/images.yaml"
+		result, err := PromoteCatalog(context.Background(), from, to, &PromotionPolicy{DryRun: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Promoted).To(HaveLen(2))
+		_, err = os.Stat(filepath.Join(to.CatalogDir, "promoted"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+})
@@ -0,0 +1,230 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Provider types with typed image mappings.
+const (
+	ProviderAlicloud  = "alicloud"
+	ProviderAWS       = "aws"
+	ProviderAzure     = "azure"
+	ProviderGCP       = "gcp"
+	ProviderOpenStack = "openstack"
+)
+
+// ProviderMapping is the typed provider config of a machine image version, which maps the version to the images of
+// the infrastructure.
+type ProviderMapping interface {
+	// Validate checks that the fields which the provider extension requires are present.
+	Validate() error
+}
+
+// AWSImageMapping maps a version to the AMIs of its regions.
+type AWSImageMapping struct {
+	Regions []AWSRegionMapping `json:"regions"`
+}
+
+// AWSRegionMapping is the AMI of a version in a region.
+type AWSRegionMapping struct {
+	Name         string `json:"name"`
+	AMI          string `json:"ami"`
+	Architecture string `json:"architecture,omitempty"`
+}
+
+// Validate checks that there is an AMI for every region.
+func (m *AWSImageMapping) Validate() error {
+	if len(m.Regions) == 0 {
+		return errors.New("regions must not be empty")
+	}
+	for i, region := range m.Regions {
+		if len(region.Name) == 0 {
+			return fmt.Errorf("regions[%d]: name must be provided", i)
+		}
+		if len(region.AMI) == 0 {
+			return fmt.Errorf("regions[%d]: ami of region %s must be provided", i, region.Name)
+		}
+		if err := validateArchitecture(region.Architecture); err != nil {
+			return fmt.Errorf("regions[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// AzureImageMapping maps a version to a marketplace urn or to an image id or gallery image of Azure.
+type AzureImageMapping struct {
+	URN                     string `json:"urn,omitempty"`
+	ID                      string `json:"id,omitempty"`
+	CommunityGalleryImageID string `json:"communityGalleryImageID,omitempty"`
+	SharedGalleryImageID    string `json:"sharedGalleryImageID,omitempty"`
+	AcceleratedNetworking   *bool  `json:"acceleratedNetworking,omitempty"`
+	Architecture            string `json:"architecture,omitempty"`
+}
+
+// Validate checks that exactly one image reference is present.
+func (m *AzureImageMapping) Validate() error {
+	references := 0
+	for _, reference := range []string{m.URN, m.ID, m.CommunityGalleryImageID, m.SharedGalleryImageID} {
+		if len(reference) > 0 {
+			references++
+		}
+	}
+	if references != 1 {
+		return errors.New("exactly one of urn, id, communityGalleryImageID and sharedGalleryImageID must be provided")
+	}
+	return validateArchitecture(m.Architecture)
+}
+
+// GCPImageMapping maps a version to a global image of GCP.
+type GCPImageMapping struct {
+	Image        string `json:"image"`
+	Architecture string `json:"architecture,omitempty"`
+}
+
+// Validate checks that the image is present.
+func (m *GCPImageMapping) Validate() error {
+	if len(m.Image) == 0 {
+		return errors.New("image must be provided")
+	}
+	return validateArchitecture(m.Architecture)
+}
+
+// OpenStackImageMapping maps a version to an image name, which is looked up in every region, or to the image ids of
+// its regions.
+type OpenStackImageMapping struct {
+	Image   string                   `json:"image,omitempty"`
+	Regions []OpenStackRegionMapping `json:"regions,omitempty"`
+}
+
+// OpenStackRegionMapping is the image id of a version in a region.
+type OpenStackRegionMapping struct {
+	Name         string `json:"name"`
+	ID           string `json:"id"`
+	Architecture string `json:"architecture,omitempty"`
+}
+
+// Validate checks that there is an image name or an image id for every region.
+func (m *OpenStackImageMapping) Validate() error {
+	if len(m.Image) == 0 && len(m.Regions) == 0 {
+		return errors.New("image or regions must be provided")
+	}
+	for i, region := range m.Regions {
+		if len(region.Name) == 0 {
+			return fmt.Errorf("regions[%d]: name must be provided", i)
+		}
+		if len(region.ID) == 0 {
+			return fmt.Errorf("regions[%d]: id of region %s must be provided", i, region.Name)
+		}
+		if err := validateArchitecture(region.Architecture); err != nil {
+			return fmt.Errorf("regions[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// AlicloudImageMapping maps a version to the image ids of its regions.
+type AlicloudImageMapping struct {
+	Regions []AlicloudRegionMapping `json:"regions"`
+}
+
+// AlicloudRegionMapping is the image id of a version in a region.
+type AlicloudRegionMapping struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// Validate checks that there is an image id for every region.
+func (m *AlicloudImageMapping) Validate() error {
+	if len(m.Regions) == 0 {
+		return errors.New("regions must not be empty")
+	}
+	for i, region := range m.Regions {
+		if len(region.Name) == 0 {
+			return fmt.Errorf("regions[%d]: name must be provided", i)
+		}
+		if len(region.ID) == 0 {
+			return fmt.Errorf("regions[%d]: id of region %s must be provided", i, region.Name)
+		}
+	}
+	return nil
+}
+
+// newProviderMappings returns the empty mappings of the provider types.
+var newProviderMappings = map[string]func() ProviderMapping{
+	ProviderAlicloud:  func() ProviderMapping { return &AlicloudImageMapping{} },
+	ProviderAWS:       func() ProviderMapping { return &AWSImageMapping{} },
+	ProviderAzure:     func() ProviderMapping { return &AzureImageMapping{} },
+	ProviderGCP:       func() ProviderMapping { return &GCPImageMapping{} },
+	ProviderOpenStack: func() ProviderMapping { return &OpenStackImageMapping{} },
+}
+
+// MappingProviders returns the provider types with typed image mappings in lexical order.
+func MappingProviders() []string {
+	providers := make([]string, 0, len(newProviderMappings))
+	for provider := range newProviderMappings {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// DecodeProviderMapping decodes the provider config of a version into the typed mapping of the provider type, e.g. an
+// *AWSImageMapping for aws. Fields which are not part of the mapping, like the version and its classification, are
+// ignored. The mapping is not validated.
+func DecodeProviderMapping(provider string, version MachineImageVersion) (ProviderMapping, error) {
+	newMapping, ok := newProviderMappings[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q, expected one of %s", provider, strings.Join(MappingProviders(), ", "))
+	}
+
+	data, err := json.Marshal(version)
+	if err != nil {
+		return nil, err
+	}
+	mapping := newMapping()
+	if err := json.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("invalid %s provider config: %w", provider, err)
+	}
+	return mapping, nil
+}
+
+// validateProviderMappings checks that the provider configs of all versions are valid mappings of the provider type.
+func validateProviderMappings(images []MachineImage, provider string) error {
+	if len(provider) == 0 {
+		return nil
+	}
+
+	problems := []string{}
+	for _, image := range images {
+		for _, v := range image.Versions {
+			mapping, err := DecodeProviderMapping(provider, v)
+			if err == nil {
+				err = mapping.Validate()
+			}
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s version %s: %v", image.Name, versionOrEmpty(v), err))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid %s provider configs of machine image versions: %s", provider, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func validateArchitecture(architecture string) error {
+	switch architecture {
+	case "", ArchitectureAMD64, ArchitectureARM64:
+		return nil
+	default:
+		return fmt.Errorf("unknown architecture %q", architecture)
+	}
+}
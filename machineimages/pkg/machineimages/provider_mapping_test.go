@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("provider mapping", func() {
+
+	Context("DecodeProviderMapping", func() {
+
+		It("should decode the regions of aws", func() {
+			mapping, err := DecodeProviderMapping(ProviderAWS, MachineImageVersion{
+				"version":        "934.1.0",
+				"classification": ClassificationSupported,
+				"regions": []interface{}{
+					map[string]interface{}{"name": "eu-west-1", "ami": "ami-1", "architecture": ArchitectureARM64},
+				},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mapping).To(Equal(&AWSImageMapping{Regions: []AWSRegionMapping{
+				{Name: "eu-west-1", AMI: "ami-1", Architecture: ArchitectureARM64},
+			}}))
+			Expect(mapping.Validate()).To(Succeed())
+		})
+
+		It("should reject unknown providers", func() {
+			_, err := DecodeProviderMapping("vsphere", MachineImageVersion{})
+			Expect(err).To(MatchError(`unknown provider "vsphere", expected one of alicloud, aws, azure, gcp, openstack`))
+		})
+
+		It("should reject configs of the wrong type", func() {
+			_, err := DecodeProviderMapping(ProviderGCP, MachineImageVersion{"image": []interface{}{}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(HavePrefix("invalid gcp provider config: "))
+		})
+	})
+
+	Context("Validate", func() {
+
+		validate := func(provider string, version MachineImageVersion) error {
+			mapping, err := DecodeProviderMapping(provider, version)
+			Expect(err).NotTo(HaveOccurred())
+			return mapping.Validate()
+		}
+
+		It("should require an ami for every region of aws", func() {
+			Expect(validate(ProviderAWS, MachineImageVersion{})).To(MatchError("regions must not be empty"))
+			Expect(validate(ProviderAWS, MachineImageVersion{"regions": []interface{}{
+				map[string]interface{}{"name": "eu-west-1"},
+			}})).To(MatchError("regions[0]: ami of region eu-west-1 must be provided"))
+		})
+
+		It("should require exactly one image reference of azure", func() {
+			Expect(validate(ProviderAzure, MachineImageVersion{"urn": "sap:gardenlinux:greatest:934.1.0"})).To(Succeed())
+			Expect(validate(ProviderAzure, MachineImageVersion{})).To(MatchError("exactly one of urn, id, communityGalleryImageID and sharedGalleryImageID must be provided"))
+			Expect(validate(ProviderAzure, MachineImageVersion{"urn": "a", "id": "b"})).To(MatchError("exactly one of urn, id, communityGalleryImageID and sharedGalleryImageID must be provided"))
+		})
+
+		It("should require the image of gcp", func() {
+			Expect(validate(ProviderGCP, MachineImageVersion{"image": "projects/sap/global/images/gardenlinux"})).To(Succeed())
+			Expect(validate(ProviderGCP, MachineImageVersion{})).To(MatchError("image must be provided"))
+			Expect(validate(ProviderGCP, MachineImageVersion{"image": "gardenlinux", "architecture": "s390x"})).To(MatchError(`unknown architecture "s390x"`))
+		})
+
+		It("should require the image or the region ids of openstack", func() {
+			Expect(validate(ProviderOpenStack, MachineImageVersion{"image": "gardenlinux"})).To(Succeed())
+			Expect(validate(ProviderOpenStack, MachineImageVersion{})).To(MatchError("image or regions must be provided"))
+			Expect(validate(ProviderOpenStack, MachineImageVersion{"regions": []interface{}{
+				map[string]interface{}{"name": "eu-de-1"},
+			}})).To(MatchError("regions[0]: id of region eu-de-1 must be provided"))
+		})
+
+		It("should require named regions of alicloud", func() {
+			Expect(validate(ProviderAlicloud, MachineImageVersion{"regions": []interface{}{
+				map[string]interface{}{"id": "m-1"},
+			}})).To(MatchError("regions[0]: name must be provided"))
+		})
+	})
+
+	It("should validate the provider configs of the computed versions", func() {
+		_, err := ComputeMachineImagesWithOptions(
+			context.Background(),
+			logr.Discard(),
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.2.0", "classification": ClassificationPreview},
+				{"version": "934.1.0", "classification": ClassificationSupported},
+			}}},
+			nil,
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.2.0", "regions": []interface{}{map[string]interface{}{"name": "eu-west-1"}}},
+				{"version": "934.1.0", "regions": []interface{}{map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"}}},
+			}}},
+			nil,
+			nil,
+			nil,
+			nil,
+			&ComputeMachineImagesOptions{Provider: ProviderAWS},
+		)
+		Expect(err).To(MatchError("invalid aws provider configs of machine image versions: gardenlinux version 934.2.0: regions[0]: ami of region eu-west-1 must be provided"))
+	})
+})
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RegionScope scopes the machine images to the active regions of a landscape, usually the regions of its seeds, so
+// that the cloud profile does not carry the images of regions in which no shoot can run.
+type RegionScope struct {
+	// Regions are the active regions.
+	Regions []string `json:"regions,omitempty" yaml:"regions,omitempty"`
+	// Action determines whether versions which are not available in any active region are dropped or cause an error.
+	// Defaults to PolicyActionDrop.
+	Action PolicyAction `json:"action,omitempty" yaml:"action,omitempty"`
+}
+
+// applyRegionScope removes the entries of inactive regions from the regions of the versions. Versions without regions,
+// e.g. gcp images, are available in all regions and are not changed. Versions whose regions are all inactive are
+// dropped or rejected. Regions without name cannot be scoped and fail the computation.
+func applyRegionScope(ctx context.Context, images []MachineImage, scope *RegionScope) ([]MachineImage, error) {
+	if scope == nil {
+		return images, nil
+	}
+	action := scope.Action
+	if action == "" {
+		action = PolicyActionDrop
+	}
+	if action != PolicyActionDrop && action != PolicyActionError {
+		return nil, fmt.Errorf("policy action does not exist %s", action)
+	}
+
+	active := map[string]bool{}
+	for _, region := range scope.Regions {
+		active[region] = true
+	}
+
+	_, reporter := FromContext(ctx)
+	result := make([]MachineImage, 0, len(images))
+	unavailable := []string{}
+	for _, image := range images {
+		versions := make([]MachineImageVersion, 0, len(image.Versions))
+		for _, v := range image.Versions {
+			value, ok := v["regions"]
+			if !ok {
+				versions = append(versions, v)
+				continue
+			}
+			regions, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("regions of %s version %s must be a list, got %T", image.Name, versionOrEmpty(v), value)
+			}
+
+			scoped := make([]interface{}, 0, len(regions))
+			for i, entry := range regions {
+				region, _ := entry.(map[string]interface{})
+				name, _ := region["name"].(string)
+				if len(name) == 0 {
+					return nil, fmt.Errorf("region %d of %s version %s has no name", i, image.Name, versionOrEmpty(v))
+				}
+				if active[name] {
+					scoped = append(scoped, entry)
+				}
+			}
+			if len(scoped) > 0 {
+				trimmed := MachineImageVersion{}
+				for key, value := range v {
+					trimmed[key] = value
+				}
+				trimmed["regions"] = scoped
+				versions = append(versions, trimmed)
+				continue
+			}
+
+			unavailable = append(unavailable, image.Name+":"+versionOrEmpty(v))
+			if action == PolicyActionDrop {
+				reporter.Report(ReportEntry{
+					Image:   image.Name,
+					Version: versionOrEmpty(v),
+					Reason:  ReasonNoActiveRegion,
+					Message: "version is not available in any active region",
+				})
+			}
+		}
+		if len(versions) > 0 {
+			result = append(result, MachineImage{Name: image.Name, Versions: versions})
+		}
+	}
+
+	if action == PolicyActionError && len(unavailable) > 0 {
+		sort.Strings(unavailable)
+		return nil, fmt.Errorf("machine image versions are not available in any active region: %s", strings.Join(unavailable, ", "))
+	}
+	return result, nil
+}
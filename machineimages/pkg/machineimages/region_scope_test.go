@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("region scope", func() {
+
+	newImages := func() []MachineImage {
+		return []MachineImage{
+			{
+				Name: OsNameGardenLinux,
+				Versions: []MachineImageVersion{
+					{"version": "934.2.0", "regions": []interface{}{
+						map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"},
+						map[string]interface{}{"name": "ap-south-1", "ami": "ami-2"},
+					}},
+					{"version": "934.1.0", "regions": []interface{}{
+						map[string]interface{}{"name": "ap-south-1", "ami": "ami-3"},
+					}},
+				},
+			},
+			{
+				Name: OsNameUbuntu,
+				Versions: []MachineImageVersion{
+					{"version": "22.4.0", "image": "projects/ubuntu/22-4"},
+				},
+			},
+		}
+	}
+
+	It("should not change images without scope", func() {
+		result, err := applyRegionScope(context.Background(), newImages(), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(newImages()))
+	})
+
+	It("should trim the regions and drop versions without active region", func() {
+		report := NewReport()
+		ctx := NewContext(context.Background(), logr.Discard(), report)
+		images := newImages()
+		result, err := applyRegionScope(ctx, images, &RegionScope{Regions: []string{"eu-west-1", "us-east-1"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{
+			{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "934.2.0", "regions": []interface{}{
+					map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"},
+				}},
+			}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "22.4.0", "image": "projects/ubuntu/22-4"},
+			}},
+		}))
+		Expect(images).To(Equal(newImages()))
+		Expect(report.Entries()).To(ConsistOf(
+			ReportEntry{Image: OsNameGardenLinux, Version: "934.1.0", Reason: ReasonNoActiveRegion, Message: "version is not available in any active region"},
+		))
+	})
+
+	It("should reject versions without active region", func() {
+		_, err := applyRegionScope(context.Background(), newImages(), &RegionScope{Regions: []string{"eu-west-1"}, Action: PolicyActionError})
+		Expect(err).To(MatchError("machine image versions are not available in any active region: gardenlinux:934.1.0"))
+	})
+
+	It("should reject regions which cannot be scoped", func() {
+		images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.2.0", "regions": []interface{}{map[string]interface{}{"ami": "ami-1"}}},
+		}}}
+		_, err := applyRegionScope(context.Background(), images, &RegionScope{Regions: []string{"eu-west-1"}})
+		Expect(err).To(MatchError("region 0 of gardenlinux version 934.2.0 has no name"))
+
+		images[0].Versions[0]["regions"] = []map[string]interface{}{{"name": "eu-west-1"}}
+		_, err = applyRegionScope(context.Background(), images, &RegionScope{Regions: []string{"eu-west-1"}})
+		Expect(err).To(MatchError("regions of gardenlinux version 934.2.0 must be a list, got []map[string]interface {}"))
+	})
+
+	It("should reject unknown actions", func() {
+		_, err := applyRegionScope(context.Background(), newImages(), &RegionScope{Action: "keep"})
+		Expect(err).To(MatchError("policy action does not exist keep"))
+	})
+})
@@ -0,0 +1,92 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import "sync"
+
+// Reasons of report entries.
+const (
+	ReasonMergeConflict           = "MergeConflict"
+	ReasonBelowMinVersion         = "BelowMinVersion"
+	ReasonNoActiveRegion          = "NoActiveRegion"
+	ReasonLatestPerMinor          = "LatestPerMinor"
+	ReasonBudgetExceeded          = "BudgetExceeded"
+	ReasonIncident                = "Incident"
+	ReasonExpired                 = "Expired"
+	ReasonEndOfLife               = "EndOfLife"
+	ReasonArtifactNotFound        = "ArtifactNotFound"
+	ReasonArchitectureMismatch    = "ArchitectureMismatch"
+	ReasonInvalidCatalogEntry     = "InvalidCatalogEntry"
+	ReasonUnmatchedDisablePattern = "UnmatchedDisablePattern"
+	ReasonFilteredOut             = "FilteredOut"
+	ReasonDisabled                = "Disabled"
+	ReasonNoProviderConfig        = "NoProviderConfig"
+	ReasonProviderDisabled        = "ProviderDisabled"
+	ReasonCachedOsImages          = "CachedOsImages"
+)
+
+// ReportEntry describes a finding of the computation which is not an error, e.g. a version which was dropped.
+// Findings of the provider specific stages have the provider type, if it is known.
+type ReportEntry struct {
+	Provider string `json:"provider,omitempty"`
+	Image    string `json:"image,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Reason   string `json:"reason"`
+	Message  string `json:"message,omitempty"`
+}
+
+// ReportSink collects report entries. Implementations must be safe for concurrent use.
+type ReportSink interface {
+	Report(entry ReportEntry)
+}
+
+// Report is a ReportSink which keeps all entries in memory.
+type Report struct {
+	mutex   sync.Mutex
+	entries []ReportEntry
+}
+
+// NewReport returns an empty report.
+func NewReport() *Report {
+	return &Report{entries: []ReportEntry{}}
+}
+
+// Report adds an entry to the report.
+func (r *Report) Report(entry ReportEntry) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Entries returns a copy of all entries in the order in which they were reported.
+func (r *Report) Entries() []ReportEntry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]ReportEntry{}, r.entries...)
+}
+
+// multiReportSink forwards the entries to all sinks.
+type multiReportSink []ReportSink
+
+func (m multiReportSink) Report(entry ReportEntry) {
+	for _, sink := range m {
+		sink.Report(entry)
+	}
+}
+
+// providerReportSink sets the provider of the entries and forwards them to the sink.
+type providerReportSink struct {
+	provider string
+	sink     ReportSink
+}
+
+func (p providerReportSink) Report(entry ReportEntry) {
+	entry.Provider = p.provider
+	p.sink.Report(entry)
+}
+
+type discardReporter struct{}
+
+func (discardReporter) Report(_ ReportEntry) {}
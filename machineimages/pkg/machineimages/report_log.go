@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// DefaultReportLogFirst is the number of report entries of every reason which are logged if no sampling is configured.
+const DefaultReportLogFirst = 10
+
+// ReportLogSampling limits the logging of report entries, so that huge catalogs do not flood the logs.
+type ReportLogSampling struct {
+	// First is the number of entries of every reason which are logged individually. The remaining entries of a reason
+	// are only counted and logged as summary. Defaults to DefaultReportLogFirst, a negative value logs all entries.
+	First int `json:"first,omitempty" yaml:"first,omitempty"`
+}
+
+// SampledLogSink logs the first entries of every reason and forwards all entries to another sink.
+type SampledLogSink struct {
+	log   logr.Logger
+	first int
+	next  ReportSink
+
+	mutex   sync.Mutex
+	reasons []string
+	counts  map[string]int
+}
+
+var _ ReportSink = &SampledLogSink{}
+
+// NewSampledLogSink returns a sink which logs the entries with verbosity 1. The sampling and next may be nil.
+func NewSampledLogSink(log logr.Logger, sampling *ReportLogSampling, next ReportSink) *SampledLogSink {
+	first := DefaultReportLogFirst
+	if sampling != nil && sampling.First != 0 {
+		first = sampling.First
+	}
+	if next == nil {
+		next = discardReporter{}
+	}
+
+	return &SampledLogSink{
+		log:    log,
+		first:  first,
+		next:   next,
+		counts: map[string]int{},
+	}
+}
+
+// Report logs the entry if less than the configured number of entries of its reason were logged and forwards it.
+func (s *SampledLogSink) Report(entry ReportEntry) {
+	s.mutex.Lock()
+	count, ok := s.counts[entry.Reason]
+	if !ok {
+		s.reasons = append(s.reasons, entry.Reason)
+	}
+	s.counts[entry.Reason] = count + 1
+	s.mutex.Unlock()
+
+	if s.first < 0 || count < s.first {
+		keysAndValues := []interface{}{"image", entry.Image, "version", entry.Version, "reason", entry.Reason}
+		if len(entry.Provider) > 0 {
+			keysAndValues = append(keysAndValues, "provider", entry.Provider)
+		}
+		s.log.V(1).Info(entry.Message, keysAndValues...)
+	}
+	s.next.Report(entry)
+}
+
+// Flush logs the number of entries which were not logged for every reason.
+func (s *SampledLogSink) Flush() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.first < 0 {
+		return
+	}
+	for _, reason := range s.reasons {
+		if suppressed := s.counts[reason] - s.first; suppressed > 0 {
+			s.log.V(1).Info("Suppressed report entries", "reason", reason, "count", suppressed, "total", s.counts[reason])
+		}
+	}
+}
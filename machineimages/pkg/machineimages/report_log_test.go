@@ -0,0 +1,108 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// recordingLogger records the messages and values of all info logs.
+type recordingLogger struct {
+	mutex    *sync.Mutex
+	messages *[]string
+}
+
+func newRecordingLogger() recordingLogger {
+	return recordingLogger{mutex: &sync.Mutex{}, messages: &[]string{}}
+}
+
+func (l recordingLogger) Enabled() bool { return true }
+
+func (l recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	*l.messages = append(*l.messages, strings.TrimSpace(fmt.Sprintln(append([]interface{}{msg}, keysAndValues...)...)))
+}
+
+func (l recordingLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.Info(msg, append(keysAndValues, "error", err)...)
+}
+
+func (l recordingLogger) V(_ int) logr.Logger                     { return l }
+func (l recordingLogger) WithValues(_ ...interface{}) logr.Logger { return l }
+func (l recordingLogger) WithName(_ string) logr.Logger           { return l }
+
+func (l recordingLogger) Messages() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string{}, *l.messages...)
+}
+
+var _ = Describe("report log", func() {
+
+	reportEntries := func(sink ReportSink, reason string, count int) {
+		for i := 0; i < count; i++ {
+			sink.Report(ReportEntry{Image: "gardenlinux", Version: fmt.Sprintf("%d.0.0", i), Reason: reason})
+		}
+	}
+
+	It("should log the first entries of every reason and a summary of the others", func() {
+		log := newRecordingLogger()
+		report := NewReport()
+		sink := NewSampledLogSink(log, &ReportLogSampling{First: 2}, report)
+
+		reportEntries(sink, ReasonBudgetExceeded, 5)
+		reportEntries(sink, ReasonIncident, 1)
+		sink.Flush()
+
+		Expect(report.Entries()).To(HaveLen(6))
+		messages := log.Messages()
+		Expect(messages).To(HaveLen(4))
+		Expect(messages[3]).To(ContainSubstring("Suppressed report entries"))
+		Expect(messages[3]).To(ContainSubstring("count 3"))
+	})
+
+	It("should log all entries if the sampling is negative", func() {
+		log := newRecordingLogger()
+		sink := NewSampledLogSink(log, &ReportLogSampling{First: -1}, nil)
+
+		reportEntries(sink, ReasonBudgetExceeded, DefaultReportLogFirst+5)
+		sink.Flush()
+		Expect(log.Messages()).To(HaveLen(DefaultReportLogFirst + 5))
+	})
+
+	It("should sample the findings of a computation", func() {
+		log := newRecordingLogger()
+		report := NewReport()
+
+		images := []MachineImage{{Name: OsNameGardenLinux}}
+		for i := 0; i < 5; i++ {
+			images[0].Versions = append(images[0].Versions, MachineImageVersion{
+				"version": fmt.Sprintf("%d.0.0", i), "classification": "supported",
+			})
+		}
+
+		_, err := ComputeMachineImagesWithOptions(context.Background(), log, images, nil, images, nil, nil, nil, nil,
+			&ComputeMachineImagesOptions{
+				MinVersions:       map[string]string{OsNameGardenLinux: "4.0.0"},
+				ReportLogSampling: &ReportLogSampling{First: 1},
+				Reporter:          report,
+			})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Entries()).To(HaveLen(4))
+		Expect(log.Messages()).To(ContainElement(And(
+			ContainSubstring("Suppressed report entries"),
+			ContainSubstring("count 3"),
+		)))
+	})
+})
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// defaultDNSPort is the port of dns servers which are given without port.
+const defaultDNSPort = "53"
+
+// NewResolver returns a resolver which sends all dns queries to the given servers instead of the servers of the node,
+// e.g. "10.0.0.10", "10.0.0.10:53" or "[fd00::10]:53". The servers are queried in turn, so that retries of a failed
+// query go to the next server. Static hosts of /etc/hosts are still respected.
+func NewResolver(servers []string) (*net.Resolver, error) {
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no dns servers given")
+	}
+
+	addresses := make([]string, 0, len(servers))
+	for _, server := range servers {
+		address, err := dnsServerAddress(server)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+
+	var next uint32
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	return &net.Resolver{
+		// the go resolver is required, the resolver of the c library does not use Dial
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			address := addresses[int(atomic.AddUint32(&next, 1)-1)%len(addresses)]
+			return dialer.DialContext(ctx, network, address)
+		},
+	}, nil
+}
+
+// dnsServerAddress returns the host and port of a dns server, which defaults to port 53.
+func dnsServerAddress(server string) (string, error) {
+	if ip := net.ParseIP(server); ip != nil {
+		return net.JoinHostPort(ip.String(), defaultDNSPort), nil
+	}
+	host, port, err := net.SplitHostPort(server)
+	if err != nil || net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid dns server %q, expected an ip address with optional port", server)
+	}
+	return net.JoinHostPort(host, port), nil
+}
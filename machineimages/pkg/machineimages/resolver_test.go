@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// serveDNS answers all A queries on the connection with the ip and all other queries without answers.
+func serveDNS(conn net.PacketConn, ip net.IP) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		query := buf[:n]
+		if len(query) < 12 {
+			continue
+		}
+		// the question ends after the labels of the name, the type and the class
+		end := 12
+		for end < len(query) && query[end] != 0 {
+			end += int(query[end]) + 1
+		}
+		end += 5
+		if end > len(query) {
+			continue
+		}
+		qtype := binary.BigEndian.Uint16(query[end-4 : end-2])
+
+		resp := append([]byte{}, query[:2]...)
+		resp = append(resp, 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0)
+		resp = append(resp, query[12:end]...)
+		if qtype == 1 {
+			resp[7] = 1
+			resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+			resp = append(resp, ip.To4()...)
+		}
+		_, _ = conn.WriteTo(resp, addr)
+	}
+}
+
+var _ = Describe("resolver", func() {
+
+	It("should resolve with the given dns servers", func() {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		defer conn.Close()
+		go serveDNS(conn, net.IPv4(127, 0, 0, 1))
+
+		resolver, err := NewResolver([]string{conn.LocalAddr().String()})
+		Expect(err).NotTo(HaveOccurred())
+		addrs, err := resolver.LookupHost(context.Background(), "registry.hermetic.example")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(addrs).To(ConsistOf("127.0.0.1"))
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		u, err := url.Parse(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+
+		transport, err := NewTransport(&TransportOptions{DNSServers: []string{conn.LocalAddr().String()}})
+		Expect(err).NotTo(HaveOccurred())
+		transport.Proxy = nil
+		resp, err := (&http.Client{Transport: transport}).Get("http://registry.hermetic.example:" + u.Port())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+
+	It("should default the port of dns servers", func() {
+		Expect(dnsServerAddress("10.0.0.10")).To(Equal("10.0.0.10:53"))
+		Expect(dnsServerAddress("fd00::10")).To(Equal("[fd00::10]:53"))
+		Expect(dnsServerAddress("[fd00::10]:5353")).To(Equal("[fd00::10]:5353"))
+	})
+
+	It("should reject invalid dns servers", func() {
+		_, err := NewResolver(nil)
+		Expect(err).To(HaveOccurred())
+		_, err = NewResolver([]string{"dns.example.com"})
+		Expect(err).To(HaveOccurred())
+		_, err = NewTransport(&TransportOptions{DNSServers: []string{"10.0.0.10:53", "dns.example.com:53"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
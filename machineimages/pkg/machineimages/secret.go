@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// RedactedValue replaces the values of secrets in logs and dumps.
+const RedactedValue = "<redacted>"
+
+// SecretValue is a sensitive value, e.g. a token, which is either given literally or read from a file, an environment
+// variable or a key of a Kubernetes secret. Values are never contained in the json or yaml encoding or the string
+// representation of a SecretValue.
+type SecretValue struct {
+	// Value is the literal value.
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	// ValueFrom references the value. It takes precedence over Value.
+	ValueFrom *SecretValueSource `json:"valueFrom,omitempty" yaml:"valueFrom,omitempty"`
+
+	resolved *string
+}
+
+// SecretValueSource references a secret value. Exactly one of the fields must be set.
+type SecretValueSource struct {
+	// File is the path of a file which contains the value. Trailing newlines are removed.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// Env is the name of an environment variable which contains the value.
+	Env string `json:"env,omitempty" yaml:"env,omitempty"`
+	// SecretKeyRef selects a key of a Kubernetes secret.
+	SecretKeyRef *SecretKeySelector `json:"secretKeyRef,omitempty" yaml:"secretKeyRef,omitempty"`
+}
+
+// SecretKeySelector selects a key of a Kubernetes secret.
+type SecretKeySelector struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name" yaml:"name"`
+	Key       string `json:"key" yaml:"key"`
+}
+
+// SecretResolver reads referenced secret values.
+type SecretResolver struct {
+	// LookupEnv looks up environment variables. Defaults to os.LookupEnv.
+	LookupEnv func(name string) (string, bool)
+	// ReadFile reads files. Defaults to ioutil.ReadFile.
+	ReadFile func(path string) ([]byte, error)
+	// GetSecretKey returns the value of a key of a Kubernetes secret. If nil, secret key references cannot be resolved.
+	GetSecretKey func(ctx context.Context, namespace, name, key string) ([]byte, error)
+}
+
+// Resolve reads the referenced value, so that it is available via Secret.
+func (v *SecretValue) Resolve(ctx context.Context, resolver *SecretResolver) error {
+	if v == nil || v.ValueFrom == nil {
+		return nil
+	}
+	if resolver == nil {
+		resolver = &SecretResolver{}
+	}
+
+	source := v.ValueFrom
+	var value string
+	switch {
+	case len(source.File) > 0:
+		readFile := resolver.ReadFile
+		if readFile == nil {
+			readFile = ioutil.ReadFile
+		}
+		data, err := readFile(source.File)
+		if err != nil {
+			return fmt.Errorf("unable to read secret value from file: %w", err)
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+	case len(source.Env) > 0:
+		lookupEnv := resolver.LookupEnv
+		if lookupEnv == nil {
+			lookupEnv = os.LookupEnv
+		}
+		var ok bool
+		if value, ok = lookupEnv(source.Env); !ok {
+			return fmt.Errorf("environment variable %s of secret value is not set", source.Env)
+		}
+	case source.SecretKeyRef != nil:
+		ref := source.SecretKeyRef
+		if resolver.GetSecretKey == nil {
+			return fmt.Errorf("unable to read key %s of secret %s/%s: no kubernetes access configured", ref.Key, ref.Namespace, ref.Name)
+		}
+		data, err := resolver.GetSecretKey(ctx, ref.Namespace, ref.Name, ref.Key)
+		if err != nil {
+			return fmt.Errorf("unable to read key %s of secret %s/%s: %w", ref.Key, ref.Namespace, ref.Name, err)
+		}
+		value = string(data)
+	default:
+		return errors.New("valueFrom of secret value must reference a file, an environment variable or a secret key")
+	}
+
+	v.resolved = &value
+	return nil
+}
+
+// Secret returns the value. An error is returned if the value is referenced but was not resolved.
+func (v *SecretValue) Secret() (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	if v.ValueFrom == nil {
+		return v.Value, nil
+	}
+	if v.resolved == nil {
+		return "", errors.New("secret value is referenced but was not resolved")
+	}
+	return *v.resolved, nil
+}
+
+// String returns a redacted representation of the value.
+func (v SecretValue) String() string {
+	return RedactedValue
+}
+
+// MarshalJSON encodes the reference of the value, or a literal value as RedactedValue.
+func (v SecretValue) MarshalJSON() ([]byte, error) {
+	type secretValue SecretValue
+	redacted := secretValue{ValueFrom: v.ValueFrom}
+	if redacted.ValueFrom == nil && len(v.Value) > 0 {
+		redacted.Value = RedactedValue
+	}
+	return json.Marshal(redacted)
+}
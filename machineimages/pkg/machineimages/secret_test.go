@@ -0,0 +1,115 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+var _ = Describe("secret", func() {
+
+	ctx := context.Background()
+
+	It("should return literal values", func() {
+		value := &SecretValue{Value: "token"}
+		Expect(value.Resolve(ctx, nil)).To(Succeed())
+		Expect(value.Secret()).To(Equal("token"))
+	})
+
+	It("should resolve values from files", func() {
+		dir, err := ioutil.TempDir("", "secret")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "token")
+		Expect(ioutil.WriteFile(path, []byte("token\n"), 0600)).To(Succeed())
+
+		value := &SecretValue{ValueFrom: &SecretValueSource{File: path}}
+		_, err = value.Secret()
+		Expect(err).To(HaveOccurred())
+		Expect(value.Resolve(ctx, nil)).To(Succeed())
+		Expect(value.Secret()).To(Equal("token"))
+	})
+
+	It("should resolve values from environment variables and secrets", func() {
+		resolver := &SecretResolver{
+			LookupEnv: func(name string) (string, bool) { return "env-" + name, name == "TOKEN" },
+			GetSecretKey: func(_ context.Context, namespace, name, key string) ([]byte, error) {
+				return []byte(fmt.Sprintf("%s/%s/%s", namespace, name, key)), nil
+			},
+		}
+
+		value := &SecretValue{ValueFrom: &SecretValueSource{Env: "TOKEN"}}
+		Expect(value.Resolve(ctx, resolver)).To(Succeed())
+		Expect(value.Secret()).To(Equal("env-TOKEN"))
+
+		value = &SecretValue{ValueFrom: &SecretValueSource{Env: "OTHER"}}
+		Expect(value.Resolve(ctx, resolver)).NotTo(Succeed())
+
+		value = &SecretValue{ValueFrom: &SecretValueSource{SecretKeyRef: &SecretKeySelector{Namespace: "garden", Name: "webhook", Key: "token"}}}
+		Expect(value.Resolve(ctx, resolver)).To(Succeed())
+		Expect(value.Secret()).To(Equal("garden/webhook/token"))
+	})
+
+	It("should fail without kubernetes access or on errors", func() {
+		value := &SecretValue{ValueFrom: &SecretValueSource{SecretKeyRef: &SecretKeySelector{Namespace: "garden", Name: "webhook", Key: "token"}}}
+		Expect(value.Resolve(ctx, nil)).To(MatchError(ContainSubstring("no kubernetes access")))
+
+		resolver := &SecretResolver{GetSecretKey: func(context.Context, string, string, string) ([]byte, error) {
+			return nil, errors.New("forbidden")
+		}}
+		Expect(value.Resolve(ctx, resolver)).To(MatchError(ContainSubstring("forbidden")))
+
+		Expect((&SecretValue{ValueFrom: &SecretValueSource{}}).Resolve(ctx, nil)).NotTo(Succeed())
+	})
+
+	It("should redact values in dumps", func() {
+		value := &SecretValue{Value: "token"}
+		data, err := yaml.Marshal(&IncidentsWebhook{URL: "https://incidents", Token: value})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("token: token"))
+		Expect(string(data)).To(ContainSubstring(RedactedValue))
+		Expect(fmt.Sprint(value)).To(Equal(RedactedValue))
+
+		value = &SecretValue{ValueFrom: &SecretValueSource{Env: "TOKEN"}}
+		Expect(value.Resolve(ctx, &SecretResolver{LookupEnv: func(string) (string, bool) { return "secret", true }})).To(Succeed())
+		data, err = yaml.Marshal(value)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("valueFrom:\n  env: TOKEN\n"))
+	})
+
+	It("should send the resolved token of the incidents webhook", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`[{"image": "gardenlinux", "version": "318.8.0"}]`))
+		}))
+		defer server.Close()
+
+		options := &ComputeMachineImagesOptions{IncidentsWebhook: &IncidentsWebhook{
+			URL:   server.URL,
+			Token: &SecretValue{ValueFrom: &SecretValueSource{Env: "TOKEN"}},
+		}}
+		_, err := options.incidentSource()
+		Expect(err).To(HaveOccurred())
+
+		Expect(options.ResolveSecrets(ctx, &SecretResolver{LookupEnv: func(string) (string, bool) { return "secret", true }})).To(Succeed())
+		source, err := options.incidentSource()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source.Incidents(ctx)).To(Equal([]Incident{{Image: "gardenlinux", Version: "318.8.0"}}))
+	})
+})
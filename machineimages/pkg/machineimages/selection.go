@@ -0,0 +1,152 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Selection are the disable list and the filters, which select the images and versions of the result.
+type Selection struct {
+	DisableMachineImages []string             `json:"disableMachineImages,omitempty" yaml:"disableMachineImages,omitempty"`
+	IncludeFilters       []OsImagesFilterKind `json:"includeFilters,omitempty" yaml:"includeFilters,omitempty"`
+	ExcludeFilters       []OsImagesFilterKind `json:"excludeFilters,omitempty" yaml:"excludeFilters,omitempty"`
+}
+
+// SelectionSource references a Selection, which is read at runtime, so that images can be toggled without rendering
+// the installation again. Exactly one of the fields must be set.
+type SelectionSource struct {
+	// File is the path of a yaml or json file which contains the selection.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// ConfigMapKeyRef selects a key of a ConfigMap which contains the selection as yaml or json.
+	ConfigMapKeyRef *ConfigMapKeySelector `json:"configMapKeyRef,omitempty" yaml:"configMapKeyRef,omitempty"`
+	// DataObjectRef selects a Landscaper DataObject whose data is the selection.
+	DataObjectRef *DataObjectSelector `json:"dataObjectRef,omitempty" yaml:"dataObjectRef,omitempty"`
+}
+
+// ConfigMapKeySelector selects a key of a Kubernetes ConfigMap.
+type ConfigMapKeySelector struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name" yaml:"name"`
+	Key       string `json:"key" yaml:"key"`
+}
+
+// DataObjectSelector selects a Landscaper DataObject.
+type DataObjectSelector struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Name      string `json:"name" yaml:"name"`
+}
+
+// SelectionResolver reads referenced selections.
+type SelectionResolver struct {
+	// ReadFile reads files. Defaults to ioutil.ReadFile.
+	ReadFile func(path string) ([]byte, error)
+	// GetConfigMapKey returns the value of a key of a Kubernetes ConfigMap. If nil, config map references cannot be
+	// resolved.
+	GetConfigMapKey func(ctx context.Context, namespace, name, key string) ([]byte, error)
+	// GetDataObject returns the json encoded data of a Landscaper DataObject. If nil, data object references cannot be
+	// resolved.
+	GetDataObject func(ctx context.Context, namespace, name string) ([]byte, error)
+}
+
+// Resolve reads the referenced selection.
+func (s *SelectionSource) Resolve(ctx context.Context, resolver *SelectionResolver) (*Selection, error) {
+	if resolver == nil {
+		resolver = &SelectionResolver{}
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case len(s.File) > 0:
+		readFile := resolver.ReadFile
+		if readFile == nil {
+			readFile = ioutil.ReadFile
+		}
+		if data, err = readFile(s.File); err != nil {
+			return nil, fmt.Errorf("unable to read selection from file: %w", err)
+		}
+	case s.ConfigMapKeyRef != nil:
+		ref := s.ConfigMapKeyRef
+		if resolver.GetConfigMapKey == nil {
+			return nil, fmt.Errorf("unable to read key %s of config map %s/%s: no kubernetes access configured", ref.Key, ref.Namespace, ref.Name)
+		}
+		if data, err = resolver.GetConfigMapKey(ctx, ref.Namespace, ref.Name, ref.Key); err != nil {
+			return nil, fmt.Errorf("unable to read key %s of config map %s/%s: %w", ref.Key, ref.Namespace, ref.Name, err)
+		}
+	case s.DataObjectRef != nil:
+		ref := s.DataObjectRef
+		if resolver.GetDataObject == nil {
+			return nil, fmt.Errorf("unable to read data object %s/%s: no kubernetes access configured", ref.Namespace, ref.Name)
+		}
+		if data, err = resolver.GetDataObject(ctx, ref.Namespace, ref.Name); err != nil {
+			return nil, fmt.Errorf("unable to read data object %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+	default:
+		return nil, errors.New("selection source must reference a file, a config map key or a data object")
+	}
+
+	selection := &Selection{}
+	if err := yaml.Unmarshal(data, selection); err != nil {
+		return nil, fmt.Errorf("unable to parse selection of %s: %w", s, err)
+	}
+	return selection, nil
+}
+
+// String returns the reference of the source.
+func (s *SelectionSource) String() string {
+	switch {
+	case len(s.File) > 0:
+		return "file " + s.File
+	case s.ConfigMapKeyRef != nil:
+		return fmt.Sprintf("key %s of config map %s/%s", s.ConfigMapKeyRef.Key, s.ConfigMapKeyRef.Namespace, s.ConfigMapKeyRef.Name)
+	case s.DataObjectRef != nil:
+		return fmt.Sprintf("data object %s/%s", s.DataObjectRef.Namespace, s.DataObjectRef.Name)
+	default:
+		return "empty selection source"
+	}
+}
+
+// ResolveSelection reads the selections of SelectionFrom and adds their disable lists and filters to the ones of the
+// imports. Entries which are already part of the imports are not added again.
+func (i *Imports) ResolveSelection(ctx context.Context, resolver *SelectionResolver) error {
+	for _, source := range i.SelectionFrom {
+		selection, err := source.Resolve(ctx, resolver)
+		if err != nil {
+			return err
+		}
+		for _, image := range selection.DisableMachineImages {
+			if !contains(i.DisableMachineImages, image) {
+				i.DisableMachineImages = append(i.DisableMachineImages, image)
+			}
+		}
+		i.IncludeFilters = appendFilters(i.IncludeFilters, selection.IncludeFilters)
+		i.ExcludeFilters = appendFilters(i.ExcludeFilters, selection.ExcludeFilters)
+	}
+	return nil
+}
+
+func appendFilters(filters, add []OsImagesFilterKind) []OsImagesFilterKind {
+	for _, kind := range add {
+		found := false
+		for _, existing := range filters {
+			if existing == kind {
+				found = true
+				break
+			}
+		}
+		if !found {
+			filters = append(filters, kind)
+		}
+	}
+	return filters
+}
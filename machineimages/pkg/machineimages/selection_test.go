@@ -0,0 +1,68 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("selection", func() {
+
+	resolver := &SelectionResolver{
+		ReadFile: func(path string) ([]byte, error) {
+			if path != "/etc/selection.yaml" {
+				return nil, errors.New("not found")
+			}
+			return []byte("disableMachineImages: [suse-chost]\nexcludeFilters: [deprecated]\n"), nil
+		},
+		GetConfigMapKey: func(_ context.Context, namespace, name, key string) ([]byte, error) {
+			return []byte("disableMachineImages: [ubuntu, suse-chost]"), nil
+		},
+		GetDataObject: func(_ context.Context, namespace, name string) ([]byte, error) {
+			return []byte(`{"includeFilters": ["supported"]}`), nil
+		},
+	}
+
+	It("should add the referenced selections to the imports", func() {
+		imports := &Imports{
+			DisableMachineImages: []string{OsNameUbuntu},
+			IncludeFilters:       []OsImagesFilterKind{OsImagesFilterKindPreview},
+			SelectionFrom: []SelectionSource{
+				{File: "/etc/selection.yaml"},
+				{ConfigMapKeyRef: &ConfigMapKeySelector{Namespace: "garden", Name: "selection", Key: "selection"}},
+				{DataObjectRef: &DataObjectSelector{Namespace: "garden", Name: "selection"}},
+			},
+		}
+		Expect(imports.ResolveSelection(context.Background(), resolver)).To(Succeed())
+		Expect(imports.DisableMachineImages).To(Equal([]string{OsNameUbuntu, "suse-chost"}))
+		Expect(imports.IncludeFilters).To(Equal([]OsImagesFilterKind{OsImagesFilterKindPreview, OsImagesFilterKindSupported}))
+		Expect(imports.ExcludeFilters).To(Equal([]OsImagesFilterKind{OsImagesFilterKindDeprecated}))
+	})
+
+	It("should fail without kubernetes access", func() {
+		imports := &Imports{SelectionFrom: []SelectionSource{
+			{DataObjectRef: &DataObjectSelector{Namespace: "garden", Name: "selection"}},
+		}}
+		Expect(imports.ResolveSelection(context.Background(), nil)).To(MatchError("unable to read data object garden/selection: no kubernetes access configured"))
+	})
+
+	It("should fail on invalid selections", func() {
+		source := &SelectionSource{File: "/etc/selection.yaml"}
+		_, err := source.Resolve(context.Background(), &SelectionResolver{
+			ReadFile: func(string) ([]byte, error) { return []byte("disableMachineImages: suse-chost"), nil },
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(HavePrefix("unable to parse selection of file /etc/selection.yaml: "))
+	})
+
+	It("should fail on empty sources", func() {
+		_, err := (&SelectionSource{}).Resolve(context.Background(), resolver)
+		Expect(err).To(MatchError("selection source must reference a file, a config map key or a data object"))
+	})
+})
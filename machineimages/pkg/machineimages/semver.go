@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed machine image version. Parsing is tolerant: a leading "v" is ignored, missing minor and patch
+// parts are zero, e.g. the Garden Linux version "934.1" is 934.1.0, and suffixes after "-" and "+" are kept as
+// prerelease and build, e.g. "15.2.20210913-gen2" or "934.1.0+20230101".
+type Version struct {
+	Major, Minor, Patch uint64
+	// Prerelease is the suffix after "-". Versions with prerelease are lower than the same version without it.
+	Prerelease string
+	// Build is the suffix after "+". It is only used to order versions which are equal otherwise.
+	Build string
+}
+
+// ParseVersion parses a version.
+func ParseVersion(version string) (*Version, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	result := &Version{}
+	if i := strings.Index(rest, "+"); i >= 0 {
+		rest, result.Build = rest[:i], rest[i+1:]
+		if len(result.Build) == 0 {
+			return nil, fmt.Errorf("invalid version %q: empty build suffix", version)
+		}
+	}
+	if i := strings.Index(rest, "-"); i >= 0 {
+		rest, result.Prerelease = rest[:i], rest[i+1:]
+		if len(result.Prerelease) == 0 {
+			return nil, fmt.Errorf("invalid version %q: empty prerelease suffix", version)
+		}
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("invalid version %q: expected at most three numeric parts", version)
+	}
+	numbers := []*uint64{&result.Major, &result.Minor, &result.Patch}
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q: part %q is not a number", version, part)
+		}
+		*numbers[i] = n
+	}
+	return result, nil
+}
+
+// String returns the normalized version "<major>.<minor>.<patch>" with prerelease and build suffix, if any.
+func (v *Version) String() string {
+	result := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		result += "-" + v.Prerelease
+	}
+	if len(v.Build) > 0 {
+		result += "+" + v.Build
+	}
+	return result
+}
+
+// Compare returns -1, 0 or 1 if v is lower, equal or higher than other. Prereleases are ordered like in semver.
+func (v *Version) Compare(other *Version) int {
+	for _, c := range []int{
+		compareUint(v.Major, other.Major),
+		compareUint(v.Minor, other.Minor),
+		compareUint(v.Patch, other.Patch),
+		comparePrerelease(v.Prerelease, other.Prerelease),
+		strings.Compare(v.Build, other.Build),
+	} {
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareVersions returns -1, 0 or 1 if version a is lower, equal or higher than version b, see Version.Compare.
+// Versions which cannot be parsed are lower than all others and are compared lexically among each other, but the image
+// lists are validated, so that they only occur in inputs like cloud profiles.
+func compareVersions(a, b string) int {
+	va, errA := ParseVersion(a)
+	vb, errB := ParseVersion(b)
+	switch {
+	case errA == nil && errB == nil:
+		return va.Compare(vb)
+	case errA == nil:
+		return 1
+	case errB == nil:
+		return -1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// comparePrerelease compares the dot separated identifiers of prereleases. Numeric identifiers are compared
+// numerically and are lower than other identifiers. Versions without prerelease are higher.
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(pa) && i < len(pb); i++ {
+		na, errA := strconv.ParseUint(pa[i], 10, 64)
+		nb, errB := strconv.ParseUint(pb[i], 10, 64)
+		var c int
+		switch {
+		case errA == nil && errB == nil:
+			c = compareUint(na, nb)
+		case errA == nil:
+			c = -1
+		case errB == nil:
+			c = 1
+		default:
+			c = strings.Compare(pa[i], pb[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(pa)), uint64(len(pb)))
+}
+
+// sortVersions sorts the versions of every image descending, or ascending if set. It returns an error listing all
+// versions which cannot be parsed.
+func sortVersions(images []MachineImage, ascending bool) error {
+	invalid := []string{}
+	for _, image := range images {
+		parsed := make([]*Version, len(image.Versions))
+		for i, v := range image.Versions {
+			version, err := ParseVersion(versionOrEmpty(v))
+			if err != nil {
+				invalid = append(invalid, fmt.Sprintf("image %s: %v", image.Name, err))
+				continue
+			}
+			parsed[i] = version
+		}
+		if len(invalid) > 0 {
+			continue
+		}
+
+		indexes := make([]int, len(image.Versions))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		sort.SliceStable(indexes, func(i, j int) bool {
+			if ascending {
+				return parsed[indexes[i]].Compare(parsed[indexes[j]]) < 0
+			}
+			return parsed[indexes[i]].Compare(parsed[indexes[j]]) > 0
+		})
+		sorted := make([]MachineImageVersion, len(indexes))
+		for i, index := range indexes {
+			sorted[i] = image.Versions[index]
+		}
+		copy(image.Versions, sorted)
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid machine image versions: %s", strings.Join(invalid, "; "))
+	}
+	return nil
+}
+
+// normalizeVersions replaces the versions of all images by their normalized form. The versions are modified in place,
+// so it must only be applied to the computed result.
+func normalizeVersions(images []MachineImage) error {
+	for _, image := range images {
+		for _, v := range image.Versions {
+			version, err := ParseVersion(versionOrEmpty(v))
+			if err != nil {
+				return fmt.Errorf("image %s: %w", image.Name, err)
+			}
+			v.SetVersion(version.String())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("semver", func() {
+
+	It("should parse versions tolerantly", func() {
+		for version, normalized := range map[string]string{
+			"934.1":              "934.1.0",
+			"v1.2.3":             "1.2.3",
+			"27":                 "27.0.0",
+			"15.2.20210913-gen2": "15.2.20210913-gen2",
+			"934.1.0+20230101":   "934.1.0+20230101",
+			"1.0.0-rc.1+build.5": "1.0.0-rc.1+build.5",
+		} {
+			parsed, err := ParseVersion(version)
+			Expect(err).NotTo(HaveOccurred(), version)
+			Expect(parsed.String()).To(Equal(normalized), version)
+		}
+	})
+
+	It("should reject invalid versions", func() {
+		for _, version := range []string{"", "latest", "1.2.3.4", "1..2", "1.0.0-", "1.0.0+"} {
+			_, err := ParseVersion(version)
+			Expect(err).To(MatchError(ContainSubstring("invalid version")), version)
+		}
+	})
+
+	It("should order versions like semver", func() {
+		ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0", "1.0.0+1", "1.1", "934.1.0"}
+		for i := 0; i < len(ordered)-1; i++ {
+			a, err := ParseVersion(ordered[i])
+			Expect(err).NotTo(HaveOccurred())
+			b, err := ParseVersion(ordered[i+1])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(a.Compare(b)).To(Equal(-1), ordered[i])
+			Expect(b.Compare(a)).To(Equal(1), ordered[i])
+			Expect(a.Compare(a)).To(Equal(0), ordered[i])
+		}
+	})
+
+	It("should compare version strings and order unparsable versions first", func() {
+		Expect(compareVersions("318.10.0", "318.9.0")).To(Equal(1))
+		Expect(compareVersions("934.1", "934.1.0")).To(Equal(0))
+		Expect(compareVersions("15.2.20210913-gen2", "15.2.20210913")).To(Equal(-1))
+		Expect(compareVersions("latest", "1.0.0")).To(Equal(-1))
+		Expect(compareVersions("1.0.0", "latest")).To(Equal(1))
+		Expect(compareVersions("latest", "edge")).To(Equal(1))
+	})
+
+	compute := func(versions []MachineImageVersion, options *ComputeMachineImagesOptions) ([]MachineImage, error) {
+		providerVersions := []MachineImageVersion{}
+		for _, v := range versions {
+			providerVersions = append(providerVersions, MachineImageVersion{"version": v["version"]})
+		}
+		return ComputeMachineImagesWithOptions(context.Background(), logr.Discard(),
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: versions}}, nil,
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: providerVersions}}, nil,
+			nil, nil, nil, options)
+	}
+
+	It("should sort the versions of the result descending", func() {
+		result, err := compute([]MachineImageVersion{{"version": "318.9"}, {"version": "934.1.0"}, {"version": "318.10.0"}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.1.0"}, {"version": "318.10.0"}, {"version": "318.9"},
+		}}}))
+	})
+
+	It("should normalize the versions of the result", func() {
+		result, err := compute([]MachineImageVersion{{"version": "318.9"}, {"version": "v934.1.0"}},
+			&ComputeMachineImagesOptions{NormalizeVersions: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.1.0"}, {"version": "318.9.0"},
+		}}}))
+	})
+
+	It("should report all invalid versions", func() {
+		_, err := compute([]MachineImageVersion{{"version": "318.9"}, {"version": "latest"}, {"version": "1.2.3.4"}}, nil)
+		Expect(err).To(MatchError(ContainSubstring(`machineImages[0].versions[1].version: invalid version "latest"`)))
+		Expect(err).To(MatchError(ContainSubstring(`invalid version "1.2.3.4"`)))
+	})
+})
@@ -0,0 +1,186 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Role is a permission which is required to call an endpoint.
+type Role string
+
+const (
+	// RoleRead allows to read the computed machine images, their diff and changes.
+	RoleRead = Role("read")
+	// RoleExplain allows to explain the computation of single versions.
+	RoleExplain = Role("explain")
+	// RoleApply allows to change catalogs or cloud profiles.
+	RoleApply = Role("apply")
+)
+
+// ErrUnauthenticated is returned by authenticators if a request has no valid credentials.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// User is an authenticated user.
+type User struct {
+	Name   string   `json:"name"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// Authenticator determines the user of a request.
+type Authenticator interface {
+	// Authenticate returns the user of the request or an error wrapping ErrUnauthenticated if the request has no
+	// valid credentials.
+	Authenticate(r *http.Request) (*User, error)
+}
+
+// Authorizer decides whether a user has a role.
+type Authorizer interface {
+	Authorize(user *User, role Role) bool
+}
+
+// RoleBinding grants a role to users and groups.
+type RoleBinding struct {
+	Role   Role     `json:"role"`
+	Users  []string `json:"users,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// RBACAuthorizer grants roles according to a list of role bindings.
+type RBACAuthorizer struct {
+	Bindings []RoleBinding `json:"bindings"`
+}
+
+// Validate checks that the bindings only grant known roles, so that a misspelled role does not silently grant
+// nothing.
+func (a *RBACAuthorizer) Validate() error {
+	for i, binding := range a.Bindings {
+		switch binding.Role {
+		case RoleRead, RoleExplain, RoleApply:
+		default:
+			return fmt.Errorf("bindings[%d]: unknown role %q", i, binding.Role)
+		}
+		if len(binding.Users) == 0 && len(binding.Groups) == 0 {
+			return fmt.Errorf("bindings[%d]: the role %s is bound to no users or groups", i, binding.Role)
+		}
+	}
+	return nil
+}
+
+// Authorize returns whether one of the bindings grants the role to the user or one of its groups.
+func (a *RBACAuthorizer) Authorize(user *User, role Role) bool {
+	for _, binding := range a.Bindings {
+		if binding.Role != role {
+			continue
+		}
+		if containsString(binding.Users, user.Name) {
+			return true
+		}
+		for _, group := range user.Groups {
+			if containsString(binding.Groups, group) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StaticTokenAuthenticator authenticates bearer tokens against a fixed set of tokens. The sha256 digests of the tokens
+// are compared in constant time, so that the time of a comparison reveals nothing about the tokens.
+type StaticTokenAuthenticator struct {
+	// Tokens maps tokens to their users.
+	Tokens map[string]User
+}
+
+// Authenticate returns the user of the bearer token of the request.
+func (a *StaticTokenAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(token))
+	var result *User
+	for known, user := range a.Tokens {
+		knownDigest := sha256.Sum256([]byte(known))
+		if subtle.ConstantTimeCompare(digest[:], knownDigest[:]) == 1 {
+			user := user
+			result = &user
+		}
+	}
+	if result == nil {
+		return nil, ErrUnauthenticated
+	}
+	return result, nil
+}
+
+// UnionAuthenticator tries all authenticators in order and returns the first authenticated user.
+type UnionAuthenticator []Authenticator
+
+// Authenticate returns the user of the first authenticator which accepts the request.
+func (u UnionAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	for _, authenticator := range u {
+		user, err := authenticator.Authenticate(r)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, ErrUnauthenticated) {
+			return nil, err
+		}
+	}
+	return nil, ErrUnauthenticated
+}
+
+// withAuth wraps the handler such that only authenticated users with the role can call it. If no authenticator is
+// configured, all requests are allowed.
+func (s *Server) withAuth(role Role, handler http.HandlerFunc) http.HandlerFunc {
+	if s.authenticator == nil {
+		return handler
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, err := s.authenticator.Authenticate(r)
+		if err != nil {
+			if errors.Is(err, ErrUnauthenticated) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				s.writeError(w, http.StatusUnauthorized, ErrUnauthenticated)
+				return
+			}
+			s.log.Error(err, "unable to authenticate request")
+			s.writeError(w, http.StatusInternalServerError, errors.New("unable to authenticate request"))
+			return
+		}
+
+		if s.authorizer == nil || !s.authorizer.Authorize(user, role) {
+			s.writeError(w, http.StatusForbidden, errors.New("user "+user.Name+" does not have the role "+string(role)))
+			return
+		}
+
+		handler(w, r)
+	}
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) || len(header) == len(prefix) {
+		return "", ErrUnauthenticated
+	}
+	return strings.TrimSpace(header[len(prefix):]), nil
+}
+
+func containsString(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// DefaultOIDCKeyRefreshInterval is the minimum time between two fetches of the signing keys of an issuer.
+const DefaultOIDCKeyRefreshInterval = 30 * time.Second
+
+// OIDCAuthenticator authenticates bearer tokens which are RS256 signed id tokens of an OpenID Connect issuer.
+type OIDCAuthenticator struct {
+	// IssuerURL is the url of the issuer. The signing keys are discovered via its openid configuration.
+	IssuerURL string
+	// ClientID must be contained in the audience of the tokens.
+	ClientID string
+	// UsernameClaim is the claim with the user name. Defaults to "sub".
+	UsernameClaim string
+	// GroupsClaim is the claim with the groups of the user. Defaults to "groups".
+	GroupsClaim string
+	// Client is the http client for the issuer. Defaults to the client of mi.NewHTTPClient.
+	Client *http.Client
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+	// KeyRefreshInterval is the minimum time between two fetches of the signing keys, so that tokens with unknown
+	// key ids cannot make the server fetch the keys for every request. Defaults to DefaultOIDCKeyRefreshInterval.
+	KeyRefreshInterval time.Duration
+
+	mutex sync.Mutex
+	keys  map[string]*rsa.PublicKey
+	// fetchedAt is the time of the last fetch of the keys.
+	fetchedAt time.Time
+	// fetching is closed when the running fetch of the keys completes, nil if no fetch is running.
+	fetching chan struct{}
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+type jsonWebKeySet struct {
+	Keys []struct {
+		KeyType string `json:"kty"`
+		KeyID   string `json:"kid"`
+		N       string `json:"n"`
+		E       string `json:"e"`
+	} `json:"keys"`
+}
+
+// Authenticate verifies the id token of the request and returns the user of its claims.
+func (a *OIDCAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrUnauthenticated)
+	}
+
+	header := &jwtHeader{}
+	if err := decodeSegment(parts[0], header); err != nil {
+		return nil, err
+	}
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported signing algorithm %s", ErrUnauthenticated, header.Algorithm)
+	}
+
+	key, err := a.key(r, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrUnauthenticated)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("%w: invalid signature", ErrUnauthenticated)
+	}
+
+	claims := map[string]interface{}{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	return a.userFromClaims(claims)
+}
+
+func (a *OIDCAuthenticator) userFromClaims(claims map[string]interface{}) (*User, error) {
+	if issuer, _ := claims["iss"].(string); issuer != a.IssuerURL {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrUnauthenticated, issuer)
+	}
+	if !hasAudience(claims["aud"], a.ClientID) {
+		return nil, fmt.Errorf("%w: token is not issued for client %s", ErrUnauthenticated, a.ClientID)
+	}
+
+	now := a.now
+	exp, ok := claims["exp"].(float64)
+	if !ok || now().After(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("%w: token is expired", ErrUnauthenticated)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now().Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("%w: token is not yet valid", ErrUnauthenticated)
+	}
+
+	usernameClaim := a.UsernameClaim
+	if len(usernameClaim) == 0 {
+		usernameClaim = "sub"
+	}
+	groupsClaim := a.GroupsClaim
+	if len(groupsClaim) == 0 {
+		groupsClaim = "groups"
+	}
+
+	name, _ := claims[usernameClaim].(string)
+	if len(name) == 0 {
+		return nil, fmt.Errorf("%w: claim %s is missing", ErrUnauthenticated, usernameClaim)
+	}
+
+	user := &User{Name: name}
+	if groups, ok := claims[groupsClaim].([]interface{}); ok {
+		for _, group := range groups {
+			if g, ok := group.(string); ok {
+				user.Groups = append(user.Groups, g)
+			}
+		}
+	}
+	return user, nil
+}
+
+// key returns the signing key with the given id. The keys of the issuer are fetched again if the id is unknown, so
+// that key rotations are picked up, but at most once per KeyRefreshInterval. Concurrent requests wait for the running
+// fetch instead of starting their own, the mutex is not held during the fetch.
+func (a *OIDCAuthenticator) key(r *http.Request, keyID string) (*rsa.PublicKey, error) {
+	for {
+		a.mutex.Lock()
+		if key, ok := a.keys[keyID]; ok {
+			a.mutex.Unlock()
+			return key, nil
+		}
+		if fetching := a.fetching; fetching != nil {
+			a.mutex.Unlock()
+			select {
+			case <-fetching:
+				continue
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			}
+		}
+		if !a.fetchedAt.IsZero() && a.now().Sub(a.fetchedAt) < a.keyRefreshInterval() {
+			a.mutex.Unlock()
+			return nil, fmt.Errorf("%w: unknown signing key %q", ErrUnauthenticated, keyID)
+		}
+		fetching := make(chan struct{})
+		a.fetching = fetching
+		a.mutex.Unlock()
+
+		keys, err := a.fetchKeys(r)
+
+		a.mutex.Lock()
+		// failed fetches count as well, so that an unavailable issuer is not called for every request
+		a.fetchedAt = a.now()
+		if err == nil {
+			a.keys = keys
+		}
+		a.fetching = nil
+		close(fetching)
+		a.mutex.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (a *OIDCAuthenticator) now() time.Time {
+	if a.Now != nil {
+		return a.Now()
+	}
+	return time.Now()
+}
+
+func (a *OIDCAuthenticator) keyRefreshInterval() time.Duration {
+	if a.KeyRefreshInterval > 0 {
+		return a.KeyRefreshInterval
+	}
+	return DefaultOIDCKeyRefreshInterval
+}
+
+func (a *OIDCAuthenticator) fetchKeys(r *http.Request) (map[string]*rsa.PublicKey, error) {
+	discovery := struct {
+		JWKSURI string `json:"jwks_uri"`
+	}{}
+	if err := a.getJSON(r, strings.TrimSuffix(a.IssuerURL, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+
+	set := &jsonWebKeySet{}
+	if err := a.getJSON(r, discovery.JWKSURI, set); err != nil {
+		return nil, err
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.KeyType != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %s: %w", k.KeyID, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %s: %w", k.KeyID, err)
+		}
+		keys[k.KeyID] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
+
+func (a *OIDCAuthenticator) getJSON(r *http.Request, url string, body interface{}) error {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	client := a.Client
+	if client == nil {
+		client = mi.NewHTTPClient(nil)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to get %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to get %s: unexpected status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(body)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return fmt.Errorf("%w: malformed token", ErrUnauthenticated)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: malformed token", ErrUnauthenticated)
+	}
+	return nil
+}
+
+func hasAudience(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
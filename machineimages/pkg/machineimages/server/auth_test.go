@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+var _ = Describe("auth", func() {
+
+	request := func(method, url, token string) int {
+		req, err := http.NewRequest(method, url, nil)
+		Expect(err).NotTo(HaveOccurred())
+		if len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	get := func(url, token string) int {
+		return request(http.MethodGet, url, token)
+	}
+	post := func(url, token string) int {
+		return request(http.MethodPost, url, token)
+	}
+
+	Context("middleware", func() {
+
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			loader := func() (*mi.Imports, error) {
+				return &mi.Imports{}, nil
+			}
+			store := state.NewMemoryStore()
+			server = httptest.NewServer(New(logr.Discard(), loader, &Options{
+				Authenticator: &StaticTokenAuthenticator{Tokens: map[string]User{
+					"reader":   {Name: "alice"},
+					"operator": {Name: "bob", Groups: []string{"operators"}},
+					"deployer": {Name: "carol"},
+				}},
+				Authorizer: &RBACAuthorizer{Bindings: []RoleBinding{
+					{Role: RoleRead, Users: []string{"alice"}, Groups: []string{"operators"}},
+					{Role: RoleExplain, Groups: []string{"operators"}},
+					{Role: RoleApply, Users: []string{"carol"}},
+				}},
+				Approval: &state.Approval{Gate: state.NewAcknowledgementGate(store), Store: store,
+					Threshold: &mi.ApprovalThreshold{Impact: mi.ImpactRemoval}},
+			}).Handler())
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should reject requests without valid credentials", func() {
+			Expect(get(server.URL+"/v1/compute", "")).To(Equal(http.StatusUnauthorized))
+			Expect(get(server.URL+"/v1/compute", "unknown")).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("should reject users without the role", func() {
+			Expect(get(server.URL+"/v1/explain?image=ubuntu&version=1.0.0", "reader")).To(Equal(http.StatusForbidden))
+			Expect(post(server.URL+"/v1/apply", "reader")).To(Equal(http.StatusForbidden))
+			Expect(post(server.URL+"/v1/apply", "operator")).To(Equal(http.StatusForbidden))
+		})
+
+		It("should allow users with the role", func() {
+			Expect(get(server.URL+"/v1/compute", "reader")).To(Equal(http.StatusOK))
+			Expect(get(server.URL+"/v1/explain?image=ubuntu&version=1.0.0", "operator")).To(Equal(http.StatusOK))
+			Expect(post(server.URL+"/v1/apply", "deployer")).To(Equal(http.StatusOK))
+		})
+	})
+
+	Context("UnionAuthenticator", func() {
+
+		It("should return the first authenticated user", func() {
+			union := UnionAuthenticator{
+				&StaticTokenAuthenticator{Tokens: map[string]User{"a": {Name: "alice"}}},
+				&StaticTokenAuthenticator{Tokens: map[string]User{"b": {Name: "bob"}}},
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer b")
+			user, err := union.Authenticate(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user.Name).To(Equal("bob"))
+		})
+	})
+
+	Context("TokenReviewAuthenticator", func() {
+
+		It("should return the user of the token review", func() {
+			apiserver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal(tokenReviewPath))
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer reviewer"))
+
+				review := &tokenReview{}
+				Expect(json.NewDecoder(r.Body).Decode(review)).To(Succeed())
+				switch review.Spec.Token {
+				case "valid":
+					review.Status.Authenticated = true
+					review.Status.User.Username = "alice"
+					review.Status.User.Groups = []string{"operators"}
+				case "anonymous":
+					review.Status.Authenticated = true
+				}
+				w.WriteHeader(http.StatusCreated)
+				Expect(json.NewEncoder(w).Encode(review)).To(Succeed())
+			}))
+			defer apiserver.Close()
+
+			authenticator := &TokenReviewAuthenticator{Host: apiserver.URL, Token: "reviewer"}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer valid")
+			user, err := authenticator.Authenticate(req)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user).To(Equal(&User{Name: "alice", Groups: []string{"operators"}}))
+
+			req.Header.Set("Authorization", "Bearer invalid")
+			_, err = authenticator.Authenticate(req)
+			Expect(errors.Is(err, ErrUnauthenticated)).To(BeTrue())
+
+			req.Header.Set("Authorization", "Bearer anonymous")
+			_, err = authenticator.Authenticate(req)
+			Expect(err).To(MatchError("token review authenticated the token without a user name"))
+		})
+	})
+
+	Context("RBACAuthorizer", func() {
+
+		It("should reject bindings of unknown roles or without subjects", func() {
+			Expect((&RBACAuthorizer{Bindings: []RoleBinding{{Role: RoleApply, Groups: []string{"operators"}}}}).Validate()).To(Succeed())
+			Expect((&RBACAuthorizer{Bindings: []RoleBinding{{Role: "write", Users: []string{"alice"}}}}).Validate()).
+				To(MatchError(`bindings[0]: unknown role "write"`))
+			Expect((&RBACAuthorizer{Bindings: []RoleBinding{{Role: RoleRead}}}).Validate()).
+				To(MatchError("bindings[0]: the role read is bound to no users or groups"))
+		})
+	})
+
+	Context("OIDCAuthenticator", func() {
+
+		var (
+			key    *rsa.PrivateKey
+			issuer *httptest.Server
+			now    time.Time
+		)
+
+		encode := func(v interface{}) string {
+			data, err := json.Marshal(v)
+			Expect(err).NotTo(HaveOccurred())
+			return base64.RawURLEncoding.EncodeToString(data)
+		}
+
+		sign := func(kid string, claims map[string]interface{}) string {
+			unsigned := encode(map[string]string{"alg": "RS256", "kid": kid}) + "." + encode(claims)
+			digest := sha256.Sum256([]byte(unsigned))
+			signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+			Expect(err).NotTo(HaveOccurred())
+			return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+		}
+
+		authenticate := func(authenticator *OIDCAuthenticator, token string) (*User, error) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			return authenticator.Authenticate(req)
+		}
+
+		BeforeEach(func() {
+			var err error
+			key, err = rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+			now = time.Unix(1600000000, 0)
+
+			mux := http.NewServeMux()
+			issuer = httptest.NewServer(mux)
+			mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+				Expect(json.NewEncoder(w).Encode(map[string]string{"jwks_uri": issuer.URL + "/keys"})).To(Succeed())
+			})
+			mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+				Expect(json.NewEncoder(w).Encode(map[string]interface{}{
+					"keys": []map[string]string{{
+						"kty": "RSA",
+						"kid": "key-1",
+						"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+						"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+					}},
+				})).To(Succeed())
+			})
+		})
+
+		AfterEach(func() {
+			issuer.Close()
+		})
+
+		claims := func() map[string]interface{} {
+			return map[string]interface{}{
+				"iss":    issuer.URL,
+				"aud":    "machineimages",
+				"sub":    "alice",
+				"groups": []string{"operators"},
+				"exp":    now.Add(time.Hour).Unix(),
+			}
+		}
+
+		It("should return the user of a valid id token", func() {
+			authenticator := &OIDCAuthenticator{IssuerURL: issuer.URL, ClientID: "machineimages", Now: func() time.Time { return now }}
+			user, err := authenticate(authenticator, sign("key-1", claims()))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(user).To(Equal(&User{Name: "alice", Groups: []string{"operators"}}))
+		})
+
+		It("should reject expired tokens and tokens of other clients", func() {
+			authenticator := &OIDCAuthenticator{IssuerURL: issuer.URL, ClientID: "machineimages", Now: func() time.Time { return now.Add(2 * time.Hour) }}
+			_, err := authenticate(authenticator, sign("key-1", claims()))
+			Expect(errors.Is(err, ErrUnauthenticated)).To(BeTrue())
+
+			authenticator = &OIDCAuthenticator{IssuerURL: issuer.URL, ClientID: "other", Now: func() time.Time { return now }}
+			_, err = authenticate(authenticator, sign("key-1", claims()))
+			Expect(errors.Is(err, ErrUnauthenticated)).To(BeTrue())
+		})
+
+		It("should fetch the keys for unknown key ids at most once per refresh interval", func() {
+			fetches := 0
+			authenticator := &OIDCAuthenticator{IssuerURL: issuer.URL, ClientID: "machineimages", Now: func() time.Time { return now },
+				KeyRefreshInterval: time.Minute}
+			counting := issuer.Config.Handler
+			issuer.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/keys" {
+					fetches++
+				}
+				counting.ServeHTTP(w, r)
+			})
+
+			for i := 0; i < 3; i++ {
+				_, err := authenticate(authenticator, sign("key-2", claims()))
+				Expect(errors.Is(err, ErrUnauthenticated)).To(BeTrue())
+			}
+			Expect(fetches).To(Equal(1))
+
+			_, err := authenticate(authenticator, sign("key-1", claims()))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fetches).To(Equal(1))
+
+			now = now.Add(time.Minute)
+			_, err = authenticate(authenticator, sign("key-2", claims()))
+			Expect(errors.Is(err, ErrUnauthenticated)).To(BeTrue())
+			Expect(fetches).To(Equal(2))
+		})
+
+		It("should reject tokens with an unknown key or an invalid signature", func() {
+			authenticator := &OIDCAuthenticator{IssuerURL: issuer.URL, ClientID: "machineimages", Now: func() time.Time { return now }}
+			_, err := authenticate(authenticator, sign("key-2", claims()))
+			Expect(errors.Is(err, ErrUnauthenticated)).To(BeTrue())
+
+			token := sign("key-1", claims())
+			_, err = authenticate(authenticator, token[:len(token)-4]+"AAAA")
+			Expect(errors.Is(err, ErrUnauthenticated)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,105 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+const tokenReviewPath = "/apis/authentication.k8s.io/v1/tokenreviews"
+
+// TokenReviewAuthenticator authenticates bearer tokens with the TokenReview API of a kubernetes cluster.
+type TokenReviewAuthenticator struct {
+	// Host is the url of the kube-apiserver.
+	Host string
+	// Token is the bearer token used to create token reviews.
+	Token string
+	// Audiences are the audiences the reviewed token must be valid for. Optional.
+	Audiences []string
+	// Client is the http client for the kube-apiserver. Defaults to the client of mi.NewHTTPClient.
+	Client *http.Client
+}
+
+type tokenReview struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Spec       tokenReviewSpec   `json:"spec"`
+	Status     tokenReviewStatus `json:"status,omitempty"`
+}
+
+type tokenReviewSpec struct {
+	Token     string   `json:"token"`
+	Audiences []string `json:"audiences,omitempty"`
+}
+
+type tokenReviewStatus struct {
+	Authenticated bool `json:"authenticated,omitempty"`
+	User          struct {
+		Username string   `json:"username,omitempty"`
+		Groups   []string `json:"groups,omitempty"`
+	} `json:"user,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Authenticate reviews the bearer token of the request.
+func (a *TokenReviewAuthenticator) Authenticate(r *http.Request) (*User, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(&tokenReview{
+		APIVersion: "authentication.k8s.io/v1",
+		Kind:       "TokenReview",
+		Spec:       tokenReviewSpec{Token: token, Audiences: a.Audiences},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost,
+		strings.TrimSuffix(a.Host, "/")+tokenReviewPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+
+	client := a.Client
+	if client == nil {
+		client = mi.NewHTTPClient(nil)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create token review: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to create token review: unexpected status %d", resp.StatusCode)
+	}
+
+	review := &tokenReview{}
+	if err := json.NewDecoder(resp.Body).Decode(review); err != nil {
+		return nil, fmt.Errorf("unable to decode token review: %w", err)
+	}
+
+	if !review.Status.Authenticated {
+		return nil, fmt.Errorf("%w: %s", ErrUnauthenticated, review.Status.Error)
+	}
+	if len(review.Status.User.Username) == 0 {
+		return nil, errors.New("token review authenticated the token without a user name")
+	}
+
+	return &User{Name: review.Status.User.Username, Groups: review.Status.User.Groups}, nil
+}
@@ -0,0 +1,172 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	machineimagesv1alpha1 "github.com/gardener/landscaper-utils/machineimages/apis/proto/machineimages/v1alpha1"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// grpcService implements the gRPC service MachineImages of apis/proto with the computation of the server. It serves
+// the same entries and events as /v1/entries and /v1/watch, with the same authorization and approval.
+type grpcService struct {
+	machineimagesv1alpha1.UnimplementedMachineImagesServer
+	s *Server
+}
+
+// RegisterGRPC registers the gRPC service MachineImages of the server, see apis/proto.
+func (s *Server) RegisterGRPC(registrar grpc.ServiceRegistrar) {
+	machineimagesv1alpha1.RegisterMachineImagesServer(registrar, &grpcService{s: s})
+}
+
+// ListenAndServeGRPC serves the gRPC service on the given address, e.g. ":8081", until the context is cancelled.
+func (s *Server) ListenAndServeGRPC(ctx context.Context, address string) error {
+	listener, err := mi.Listen(address, s.ipFamily)
+	if err != nil {
+		return err
+	}
+	return s.ServeGRPC(ctx, listener)
+}
+
+// ServeGRPC serves the gRPC service on the listener until the context is cancelled.
+func (s *Server) ServeGRPC(ctx context.Context, listener net.Listener) error {
+	grpcServer := grpc.NewServer()
+	s.RegisterGRPC(grpcServer)
+
+	go func() {
+		<-ctx.Done()
+		grpcServer.GracefulStop()
+	}()
+
+	s.log.Info("Starting grpc server", "address", listener.Addr().String())
+	if err := grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// ListEntries streams the computed versions, one entry per message.
+func (g *grpcService) ListEntries(_ *machineimagesv1alpha1.ListEntriesRequest, stream machineimagesv1alpha1.MachineImages_ListEntriesServer) error {
+	if err := g.s.authorizeGRPC(stream.Context(), RoleRead); err != nil {
+		return err
+	}
+
+	_, result, _, err := g.s.computeApproved(stream.Context())
+	if err != nil {
+		return grpcError(err)
+	}
+
+	for _, entry := range Entries(result) {
+		message, err := toProtoEntry(entry)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if err := stream.Send(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Watch streams the changes of the computed versions until the client cancels the call, see Watch.
+func (g *grpcService) Watch(request *machineimagesv1alpha1.WatchRequest, stream machineimagesv1alpha1.MachineImages_WatchServer) error {
+	if err := g.s.authorizeGRPC(stream.Context(), RoleRead); err != nil {
+		return err
+	}
+	if request.IntervalSeconds < 0 {
+		return status.Error(codes.InvalidArgument, "interval_seconds must not be negative")
+	}
+
+	err := Watch(stream.Context(), watchInterval(request.IntervalSeconds), g.s.computeImports, func(event Event) error {
+		entry, err := toProtoEntry(event.Entry)
+		if err != nil {
+			return err
+		}
+		return stream.Send(&machineimagesv1alpha1.Event{Type: protoEventTypes[event.Type], Entry: entry})
+	})
+	if err != nil {
+		g.s.log.Error(err, "watch failed")
+		return grpcError(err)
+	}
+	return nil
+}
+
+// authorizeGRPC authenticates the bearer token in the authorization metadata of the call and checks the role of the
+// user, like withAuth.
+func (s *Server) authorizeGRPC(ctx context.Context, role Role) error {
+	if s.authenticator == nil {
+		return nil
+	}
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/", nil)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			r.Header.Add("Authorization", value)
+		}
+	}
+
+	user, err := s.authenticator.Authenticate(r)
+	if err != nil {
+		if errors.Is(err, ErrUnauthenticated) {
+			return status.Error(codes.Unauthenticated, ErrUnauthenticated.Error())
+		}
+		s.log.Error(err, "unable to authenticate request")
+		return status.Error(codes.Internal, "unable to authenticate request")
+	}
+
+	if s.authorizer == nil || !s.authorizer.Authorize(user, role) {
+		return status.Error(codes.PermissionDenied, "user "+user.Name+" does not have the role "+string(role))
+	}
+	return nil
+}
+
+var protoEventTypes = map[EventType]machineimagesv1alpha1.Event_Type{
+	EventTypeAdded:    machineimagesv1alpha1.Event_ADDED,
+	EventTypeModified: machineimagesv1alpha1.Event_MODIFIED,
+	EventTypeDeleted:  machineimagesv1alpha1.Event_DELETED,
+}
+
+// toProtoEntry converts an entry into its message. The data is converted via json, so that it matches the entries of
+// /v1/entries.
+func toProtoEntry(entry Entry) (*machineimagesv1alpha1.Entry, error) {
+	data, err := json.Marshal(entry.Data)
+	if err != nil {
+		return nil, err
+	}
+	message := &machineimagesv1alpha1.Entry{Image: entry.Image, Version: entry.Version, Data: &structpb.Struct{}}
+	if err := protojson.Unmarshal(data, message.Data); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// grpcError converts an error of computeApproved into a gRPC status: a pending approval fails the precondition of
+// the call, invalid imports are an invalid argument.
+func grpcError(err error) error {
+	switch statusOf(err) {
+	case http.StatusConflict:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case http.StatusUnprocessableEntity:
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
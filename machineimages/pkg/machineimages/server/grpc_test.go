@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	machineimagesv1alpha1 "github.com/gardener/landscaper-utils/machineimages/apis/proto/machineimages/v1alpha1"
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+var _ = Describe("grpc", func() {
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	loader := func() (*mi.Imports, error) {
+		return &mi.Imports{
+			MachineImages: []mi.MachineImage{
+				{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0"}, {"version": "2.0.0"}}},
+			},
+			MachineImagesProvider: []mi.MachineImage{
+				{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0", "image": "a"}}},
+			},
+		}, nil
+	}
+
+	serve := func(options *Options) machineimagesv1alpha1.MachineImagesClient {
+		// the goroutines outlive the test, they must not read the variable of the next test
+		ctx := ctx
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).NotTo(HaveOccurred())
+		go func() {
+			defer GinkgoRecover()
+			Expect(New(logr.Discard(), loader, options).ServeGRPC(ctx, listener)).To(Succeed())
+		}()
+
+		conn, err := grpc.DialContext(ctx, listener.Addr().String(), grpc.WithInsecure())
+		Expect(err).NotTo(HaveOccurred())
+		go func() {
+			<-ctx.Done()
+			conn.Close()
+		}()
+		return machineimagesv1alpha1.NewMachineImagesClient(conn)
+	}
+
+	It("should stream the entries", func() {
+		stream, err := serve(nil).ListEntries(ctx, &machineimagesv1alpha1.ListEntriesRequest{})
+		Expect(err).NotTo(HaveOccurred())
+
+		entry, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Image).To(Equal(mi.OsNameUbuntu))
+		Expect(entry.Version).To(Equal("1.0.0"))
+		Expect(entry.Data.AsMap()).To(Equal(map[string]interface{}{"version": "1.0.0", "image": "a"}))
+
+		_, err = stream.Recv()
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("should stream the changes", func() {
+		stream, err := serve(nil).Watch(ctx, &machineimagesv1alpha1.WatchRequest{IntervalSeconds: 1})
+		Expect(err).NotTo(HaveOccurred())
+
+		event, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(event.Type).To(Equal(machineimagesv1alpha1.Event_ADDED))
+		Expect(event.Entry.Version).To(Equal("1.0.0"))
+	})
+
+	It("should reject negative intervals", func() {
+		stream, err := serve(nil).Watch(ctx, &machineimagesv1alpha1.WatchRequest{IntervalSeconds: -1})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = stream.Recv()
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should authorize the calls", func() {
+		client := serve(&Options{
+			Authenticator: &StaticTokenAuthenticator{Tokens: map[string]User{
+				"reader":    {Name: "alice"},
+				"explainer": {Name: "bob"},
+			}},
+			Authorizer: &RBACAuthorizer{Bindings: []RoleBinding{
+				{Role: RoleRead, Users: []string{"alice"}},
+				{Role: RoleExplain, Users: []string{"bob"}},
+			}},
+		})
+
+		code := func(token string) codes.Code {
+			callCtx := ctx
+			if len(token) > 0 {
+				callCtx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+			}
+			stream, err := client.ListEntries(callCtx, &machineimagesv1alpha1.ListEntriesRequest{})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = stream.Recv()
+			return status.Code(err)
+		}
+
+		Expect(code("")).To(Equal(codes.Unauthenticated))
+		Expect(code("unknown")).To(Equal(codes.Unauthenticated))
+		Expect(code("explainer")).To(Equal(codes.PermissionDenied))
+		Expect(code("reader")).To(Equal(codes.OK))
+	})
+
+	It("should not stream changes which are not approved", func() {
+		store := state.NewMemoryStore()
+		images := []mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "0.1.0", "image": "z"}}}}
+		Expect(state.SaveApplied(context.Background(), store, images)).To(Succeed())
+		client := serve(&Options{Approval: &state.Approval{Gate: state.NewAcknowledgementGate(store), Store: store,
+			Threshold: &mi.ApprovalThreshold{Impact: mi.ImpactRemoval}}})
+
+		entries, err := client.ListEntries(ctx, &machineimagesv1alpha1.ListEntriesRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = entries.Recv()
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+
+		events, err := client.Watch(ctx, &machineimagesv1alpha1.WatchRequest{})
+		Expect(err).NotTo(HaveOccurred())
+		event, err := events.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(event.Entry.Version).To(Equal("0.1.0"))
+	})
+})
@@ -0,0 +1,469 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package server exposes the computation of machine images via http and gRPC.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+const (
+	// readHeaderTimeout limits reading the headers of a request, so that slow clients cannot hold connections.
+	readHeaderTimeout = 10 * time.Second
+	// readTimeout limits reading a whole request. Responses are not limited, as watches stream until the client
+	// disconnects.
+	readTimeout = time.Minute
+	// idleTimeout closes keep-alive connections without requests.
+	idleTimeout = 2 * time.Minute
+	// maxRequestBodyBytes limits the bodies of requests, e.g. the machine images of /v1/diff.
+	maxRequestBodyBytes = 4 * mi.DefaultMaxObjectSize
+)
+
+// ImportsLoader returns the imports which back the server. It is called for every request, so that changes of the
+// underlying configuration become effective without restart.
+type ImportsLoader func() (*mi.Imports, error)
+
+// DiffRequest is the body of a diff request.
+type DiffRequest struct {
+	// MachineImages are the current machine images, e.g. of an existing CloudProfile.
+	MachineImages []mi.MachineImage `json:"machineImages"`
+	// Options ignore cosmetic differences of the versions. They may be omitted.
+	Options *mi.DiffOptions `json:"options,omitempty"`
+}
+
+// ErrorResponse is the body of all failed requests.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Options contains optional settings of the server.
+type Options struct {
+	// Authenticator determines the users of requests. If nil, all requests are allowed.
+	Authenticator Authenticator
+	// Authorizer decides whether an authenticated user may call an endpoint. If nil, all authenticated requests are
+	// forbidden.
+	Authorizer Authorizer
+	// SLO configures the service level objective of the computations. Defaults are used if nil.
+	SLO *SLOOptions
+	// IPFamily restricts ListenAndServe to an ip family. By default, addresses without host are served dual-stack.
+	IPFamily mi.IPFamily
+	// Approval gates the changes of the machine images which /v1/compute, /v1/diff, /v1/entries and /v1/watch serve,
+	// changes which are not approved are answered with 409 Conflict. Watches serve the applied machine images while a
+	// change is pending. Only POST /v1/apply records the machine images as applied. /v1/explain serves no machine
+	// images and is not gated.
+	Approval *state.Approval
+}
+
+// Server serves the endpoints /v1/compute, /v1/explain and /v1/diff and the streaming endpoints /v1/entries and
+// /v1/watch, which write one json document per line. With an approval, /v1/apply records the approved machine images
+// as applied. The success of the computations is exposed by /v1/slo and /metrics.
+type Server struct {
+	log           logr.Logger
+	loadImports   ImportsLoader
+	authenticator Authenticator
+	authorizer    Authorizer
+	slo           *SLOTracker
+	ipFamily      mi.IPFamily
+	approval      *state.Approval
+	mux           *http.ServeMux
+}
+
+// New returns a server which computes the machine images from the imports returned by the loader. The options may
+// be nil.
+func New(log logr.Logger, loadImports ImportsLoader, options *Options) *Server {
+	if options == nil {
+		options = &Options{}
+	}
+
+	s := &Server{
+		log:           log,
+		loadImports:   loadImports,
+		authenticator: options.Authenticator,
+		authorizer:    options.Authorizer,
+		slo:           NewSLOTracker(options.SLO),
+		ipFamily:      options.IPFamily,
+		approval:      options.Approval,
+		mux:           http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/v1/compute", s.withAuth(RoleRead, s.handleCompute))
+	if s.approval != nil {
+		s.mux.HandleFunc("/v1/apply", s.withAuth(RoleApply, s.handleApply))
+	}
+	s.mux.HandleFunc("/v1/explain", s.withAuth(RoleExplain, s.handleExplain))
+	s.mux.HandleFunc("/v1/diff", s.withAuth(RoleRead, s.handleDiff))
+	s.mux.HandleFunc("/v1/entries", s.withAuth(RoleRead, s.handleEntries))
+	s.mux.HandleFunc("/v1/watch", s.withAuth(RoleRead, s.handleWatch))
+	s.mux.HandleFunc("/v1/slo", s.withAuth(RoleRead, s.handleSLO))
+	s.mux.HandleFunc("/v1/capabilities", s.withAuth(RoleRead, s.handleCapabilities))
+	s.mux.HandleFunc("/metrics", s.withAuth(RoleRead, s.handleMetrics))
+
+	return s
+}
+
+// Handler returns the http handler of the server.
+func (s *Server) Handler() http.Handler {
+	return mi.PropagateTraceHeaders(s.mux)
+}
+
+// ListenAndServe serves on the given address, e.g. ":8080" or "[::]:8080", until the context is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context, address string) error {
+	listener, err := mi.Listen(address, s.ipFamily)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves on the listener until the context is cancelled.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	httpServer := s.newHTTPServer()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			s.log.Error(err, "unable to shutdown server")
+		}
+	}()
+
+	if s.authenticator == nil {
+		s.log.Info("Warning: authentication is disabled, all requests are allowed")
+	}
+
+	s.log.Info("Starting server", "address", listener.Addr().String())
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// newHTTPServer returns the http server of the handler with timeouts for slow clients.
+func (s *Server) newHTTPServer() *http.Server {
+	return &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+}
+
+// handleCompute returns the computed machine images in the format of the exports, see computeApproved. It does not
+// record the machine images as applied.
+func (s *Server) handleCompute(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	_, result, _, ok := s.compute(w, r)
+	if !ok {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, &mi.Exports{ResultMachineImages: result})
+}
+
+// handleApply records the approved machine images as applied, consumes their approval and returns them in the format
+// of the exports.
+func (s *Server) handleApply(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	_, result, request, ok := s.compute(w, r)
+	if !ok {
+		return
+	}
+	if err := s.approval.Applied(r.Context(), request, result); err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, &mi.Exports{ResultMachineImages: result})
+}
+
+// handleExplain explains why the version given by the query parameters image and version is or is not computed.
+func (s *Server) handleExplain(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	image := r.URL.Query().Get("image")
+	version := r.URL.Query().Get("version")
+	if len(image) == 0 || len(version) == 0 {
+		s.writeError(w, http.StatusBadRequest, errors.New("the query parameters image and version are required"))
+		return
+	}
+
+	imports, err := s.loadImports()
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	explanation, err := mi.ExplainVersion(r.Context(), s.log, imports, image, version)
+	if err != nil {
+		s.writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, explanation)
+}
+
+// handleDiff compares the machine images of the request body with the computed machine images.
+func (s *Server) handleDiff(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	request := &DiffRequest{}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)).Decode(request); err != nil {
+		status := http.StatusBadRequest
+		if strings.Contains(err.Error(), "request body too large") {
+			status = http.StatusRequestEntityTooLarge
+		}
+		s.writeError(w, status, err)
+		return
+	}
+	if err := request.Options.Validate(); err != nil {
+		s.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	_, result, _, ok := s.compute(w, r)
+	if !ok {
+		return
+	}
+
+	diff, err := mi.DiffMachineImagesWithOptions(request.MachineImages, result, request.Options)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, diff)
+}
+
+// handleEntries streams the computed versions, one entry per line.
+func (s *Server) handleEntries(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	_, result, _, ok := s.compute(w, r)
+	if !ok {
+		return
+	}
+
+	stream := newJSONStream(w)
+	for _, entry := range Entries(result) {
+		if err := stream.send(entry); err != nil {
+			s.log.Error(err, "unable to stream entry")
+			return
+		}
+	}
+}
+
+// handleWatch streams the changes of the computed versions until the client disconnects. The query parameter
+// intervalSeconds overrides the interval in which the machine images are recomputed, see watchInterval.
+func (s *Server) handleWatch(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	interval := DefaultWatchInterval
+	if value := r.URL.Query().Get("intervalSeconds"); len(value) > 0 {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds <= 0 {
+			s.writeError(w, http.StatusBadRequest, errors.New("intervalSeconds must be a positive integer"))
+			return
+		}
+		interval = watchInterval(int64(seconds))
+	}
+
+	stream := newJSONStream(w)
+	err := Watch(r.Context(), interval, s.computeImports, func(event Event) error {
+		return stream.send(event)
+	})
+	if err != nil {
+		s.log.Error(err, "watch failed")
+		_ = stream.send(&ErrorResponse{Error: err.Error()})
+	}
+}
+
+// handleCapabilities returns the capabilities of the deployed version.
+func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, mi.GetCapabilities())
+}
+
+// handleSLO returns the status of the service level objective of the computations.
+func (s *Server) handleSLO(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	s.writeJSON(w, http.StatusOK, s.slo.Status())
+}
+
+// handleMetrics returns the metrics of the computations in the prometheus text format.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !s.allowMethod(w, r, http.MethodGet) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	if err := s.slo.WriteMetrics(w); err != nil {
+		s.log.Error(err, "unable to write metrics")
+	}
+}
+
+func (s *Server) compute(w http.ResponseWriter, r *http.Request) (*mi.Imports, []mi.MachineImage, *mi.ApprovalRequest, bool) {
+	imports, result, request, err := s.computeApproved(r.Context())
+	if err != nil {
+		s.writeError(w, statusOf(err), err)
+		return nil, nil, nil, false
+	}
+	return imports, result, request, true
+}
+
+// computeApproved computes the machine images of the current imports and tracks the outcome. With focus, the machine
+// images are merged into the focus baseline of the imports or the applied machine images of the approval. With an
+// approval, the change from the applied machine images must be approved, see state.Approval.Check, and the returned
+// request must be passed to Applied once the machine images are applied. The errors carry the status of their
+// response, see statusOf.
+func (s *Server) computeApproved(ctx context.Context) (*mi.Imports, []mi.MachineImage, *mi.ApprovalRequest, error) {
+	imports, err := s.loadImports()
+	if err != nil {
+		s.slo.Record(err)
+		return nil, nil, nil, err
+	}
+
+	result, err := mi.ComputeMachineImagesFromImports(ctx, s.log, imports)
+	s.slo.Record(err)
+	if err != nil {
+		return nil, nil, nil, withStatus(http.StatusUnprocessableEntity, err)
+	}
+
+	if len(imports.Focus) > 0 {
+		if imports.FocusBaseline == nil && s.approval != nil {
+			if imports.FocusBaseline, err = state.LoadFocusBaseline(ctx, s.approval.Store); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		if result, _, err = imports.MergeFocusedBaseline(result, nil); err != nil {
+			return nil, nil, nil, withStatus(http.StatusUnprocessableEntity, err)
+		}
+	}
+
+	if s.approval == nil {
+		return imports, result, nil, nil
+	}
+	request, err := s.approval.Check(ctx, imports.Diff, result)
+	var pendingErr *mi.ApprovalPendingError
+	if errors.As(err, &pendingErr) {
+		return nil, nil, nil, withStatus(http.StatusConflict, err)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return imports, result, request, nil
+}
+
+// computeImports computes the machine images which watches serve, see computeApproved. While a change is pending, the
+// applied machine images are served.
+func (s *Server) computeImports(ctx context.Context) ([]mi.MachineImage, error) {
+	_, result, _, err := s.computeApproved(ctx)
+	var pendingErr *mi.ApprovalPendingError
+	if errors.As(err, &pendingErr) {
+		applied, _, err := state.LoadApplied(ctx, s.approval.Store)
+		return applied, err
+	}
+	return result, err
+}
+
+// statusError is an error with the status of its response.
+type statusError struct {
+	status int
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+func withStatus(status int, err error) error {
+	return &statusError{status: status, err: err}
+}
+
+// statusOf returns the status of the response of an error, by default 500 Internal Server Error.
+func statusOf(err error) int {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		s.writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return false
+	}
+	return true
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.log.Error(err, "unable to write response")
+	}
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, err error) {
+	s.writeJSON(w, status, &ErrorResponse{Error: err.Error()})
+}
+
+// jsonStream writes json documents separated by newlines and flushes after every document.
+type jsonStream struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+	started bool
+}
+
+func newJSONStream(w http.ResponseWriter) *jsonStream {
+	return &jsonStream{w: w, encoder: json.NewEncoder(w)}
+}
+
+func (j *jsonStream) send(body interface{}) error {
+	if !j.started {
+		j.w.Header().Set("Content-Type", "application/x-ndjson")
+		j.w.WriteHeader(http.StatusOK)
+		j.started = true
+	}
+	if err := j.encoder.Encode(body); err != nil {
+		return err
+	}
+	if flusher, ok := j.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/yaml"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/state"
+)
+
+var _ = Describe("server", func() {
+
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		loader := func() (*mi.Imports, error) {
+			return &mi.Imports{
+				MachineImages: []mi.MachineImage{
+					{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0"}, {"version": "2.0.0"}}},
+				},
+				MachineImagesProvider: []mi.MachineImage{
+					{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0", "image": "a"}}},
+				},
+			}, nil
+		}
+		server = httptest.NewServer(New(logr.Discard(), loader, nil).Handler())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	decode := func(resp *http.Response, body interface{}) {
+		defer resp.Body.Close()
+		Expect(json.NewDecoder(resp.Body).Decode(body)).To(Succeed())
+	}
+
+	It("should compute the machine images", func() {
+		resp, err := http.Get(server.URL + "/v1/compute")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		exports := &mi.Exports{}
+		decode(resp, exports)
+		Expect(exports.ResultMachineImages).To(Equal([]mi.MachineImage{
+			{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0", "image": "a"}}},
+		}))
+	})
+
+	It("should only serve approved changes and record them as applied on apply", func() {
+		store := state.NewMemoryStore()
+		images := []mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "0.1.0", "image": "z"}}}}
+		Expect(state.SaveApplied(context.Background(), store, images)).To(Succeed())
+		loader := func() (*mi.Imports, error) {
+			return &mi.Imports{MachineImages: []mi.MachineImage{}}, nil
+		}
+		approval := &state.Approval{Gate: state.NewAcknowledgementGate(store), Store: store,
+			Threshold: &mi.ApprovalThreshold{Impact: mi.ImpactRemoval}}
+		gated := httptest.NewServer(New(logr.Discard(), loader, &Options{Approval: approval}).Handler())
+		defer gated.Close()
+
+		for _, path := range []string{"/v1/compute", "/v1/entries", "/v1/apply"} {
+			resp, err := http.Post(gated.URL+path, "application/json", nil)
+			if path != "/v1/apply" {
+				resp, err = http.Get(gated.URL + path)
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusConflict), path)
+			resp.Body.Close()
+		}
+		resp, err := http.Post(gated.URL+"/v1/diff", "application/json", strings.NewReader(`{"machineImages": []}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusConflict))
+		resp.Body.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, gated.URL+"/v1/watch", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err = http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		event := &Event{}
+		Expect(json.NewDecoder(resp.Body).Decode(event)).To(Succeed())
+		cancel()
+		resp.Body.Close()
+		Expect(event.Entry.Data).To(Equal(mi.MachineImageVersion{"version": "0.1.0", "image": "z"}))
+
+		data, err := store.Get(context.Background(), state.PendingApprovalKey)
+		Expect(err).NotTo(HaveOccurred())
+		request := &mi.ApprovalRequest{}
+		Expect(yaml.Unmarshal(data, request)).To(Succeed())
+		Expect(store.Put(context.Background(), state.ApprovalKeyPrefix+request.ID, []byte("jane"))).To(Succeed())
+
+		resp, err = http.Get(gated.URL + "/v1/compute")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		resp.Body.Close()
+		applied, _, err := state.LoadApplied(context.Background(), store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(Equal(images))
+
+		resp, err = http.Post(gated.URL+"/v1/apply", "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		resp.Body.Close()
+		Expect(store.Keys(context.Background())).To(Equal([]string{state.AppliedKey}))
+		applied, _, err = state.LoadApplied(context.Background(), store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(BeEmpty())
+	})
+
+	It("should only serve the apply endpoint with an approval", func() {
+		resp, err := http.Post(server.URL+"/v1/apply", "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+
+	It("should merge focused machine images into the applied machine images", func() {
+		store := state.NewMemoryStore()
+		loader := func() (*mi.Imports, error) {
+			return &mi.Imports{
+				MachineImages: []mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0"}}}},
+				MachineImagesProvider: []mi.MachineImage{
+					{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0", "image": "a"}}},
+				},
+				ComputeMachineImagesOptions: mi.ComputeMachineImagesOptions{Focus: []string{mi.OsNameUbuntu}},
+			}, nil
+		}
+		approval := &state.Approval{Gate: state.NewAcknowledgementGate(store), Store: store,
+			Threshold: &mi.ApprovalThreshold{Impact: mi.ImpactRemoval}}
+		focused := httptest.NewServer(New(logr.Discard(), loader, &Options{Approval: approval}).Handler())
+		defer focused.Close()
+
+		resp, err := http.Get(focused.URL + "/v1/compute")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		resp.Body.Close()
+
+		flatcar := mi.MachineImage{Name: mi.OsNameFlatcar, Versions: []mi.MachineImageVersion{{"version": "3033.2.0"}}}
+		Expect(state.SaveApplied(context.Background(), store, []mi.MachineImage{flatcar})).To(Succeed())
+		resp, err = http.Get(focused.URL + "/v1/compute")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		exports := &mi.Exports{}
+		decode(resp, exports)
+		Expect(exports.ResultMachineImages).To(Equal([]mi.MachineImage{flatcar,
+			{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0", "image": "a"}}},
+		}))
+	})
+
+	It("should explain a version", func() {
+		resp, err := http.Get(server.URL + "/v1/explain?image=ubuntu&version=2.0.0")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		explanation := &mi.VersionExplanation{}
+		decode(resp, explanation)
+		Expect(explanation.Included).To(BeFalse())
+		Expect(explanation.Steps[len(explanation.Steps)-1].Stage).To(Equal(mi.StageProviderConfig))
+
+		resp, err = http.Get(server.URL + "/v1/explain?image=ubuntu")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+
+	It("should diff against the given machine images", func() {
+		resp, err := http.Post(server.URL+"/v1/diff", "application/json",
+			strings.NewReader(`{"machineImages": [{"name": "ubuntu", "versions": [{"version": "0.9.0"}]}]}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		diff := &mi.MachineImagesDiff{}
+		decode(resp, diff)
+		Expect(diff.Added).To(Equal([]mi.VersionRef{{Image: mi.OsNameUbuntu, Version: "1.0.0"}}))
+		Expect(diff.Removed).To(Equal([]mi.VersionRef{{Image: mi.OsNameUbuntu, Version: "0.9.0"}}))
+	})
+
+	It("should diff with options", func() {
+		resp, err := http.Post(server.URL+"/v1/diff", "application/json",
+			strings.NewReader(`{"machineImages": [{"name": "ubuntu", "versions": [{"version": "1.0.0", "image": "b"}]}], "options": {"ignoreFields": ["image"]}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		diff := &mi.MachineImagesDiff{}
+		decode(resp, diff)
+		Expect(diff.Empty()).To(BeTrue())
+
+		resp, err = http.Post(server.URL+"/v1/diff", "application/json",
+			strings.NewReader(`{"machineImages": [], "options": {"ignoreFields": ["version"]}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+
+	It("should reject too large diff requests", func() {
+		body := `{"machineImages": [{"name": "ubuntu", "versions": [{"version": "0.9.0", "data": "` +
+			strings.Repeat("a", maxRequestBodyBytes) + `"}]}]}`
+		resp, err := http.Post(server.URL+"/v1/diff", "application/json", strings.NewReader(body))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+
+	It("should limit the time to read requests and idle connections", func() {
+		httpServer := New(logr.Discard(), nil, nil).newHTTPServer()
+		Expect(httpServer.ReadHeaderTimeout).To(Equal(readHeaderTimeout))
+		Expect(httpServer.ReadTimeout).To(Equal(readTimeout))
+		Expect(httpServer.IdleTimeout).To(Equal(idleTimeout))
+		Expect(httpServer.WriteTimeout).To(BeZero())
+	})
+
+	It("should stream the entries", func() {
+		resp, err := http.Get(server.URL + "/v1/entries")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/x-ndjson"))
+
+		entry := &Entry{}
+		decode(resp, entry)
+		Expect(entry).To(Equal(&Entry{Image: mi.OsNameUbuntu, Version: "1.0.0", Data: mi.MachineImageVersion{"version": "1.0.0", "image": "a"}}))
+	})
+
+	It("should return the capabilities", func() {
+		resp, err := http.Get(server.URL + "/v1/capabilities")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		capabilities := &mi.Capabilities{}
+		decode(resp, capabilities)
+		Expect(capabilities).To(Equal(mi.GetCapabilities()))
+	})
+
+	It("should reject wrong methods", func() {
+		resp, err := http.Get(server.URL + "/v1/diff")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+		Expect(resp.Body.Close()).To(Succeed())
+	})
+	It("should serve on an ipv6 listener", func() {
+		loader := func() (*mi.Imports, error) {
+			return &mi.Imports{}, nil
+		}
+		listener, err := mi.Listen("[::1]:0", mi.IPFamilyIPv6)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- New(logr.Discard(), loader, &Options{IPFamily: mi.IPFamilyIPv6}).Serve(ctx, listener)
+		}()
+
+		resp, err := http.Get("http://" + listener.Addr().String() + "/v1/compute")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Body.Close()).To(Succeed())
+
+		cancel()
+		Eventually(done).Should(Receive(BeNil()))
+	})
+})
@@ -0,0 +1,206 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSLOWindow is the rolling window over which the success of computations is tracked.
+	DefaultSLOWindow = time.Hour
+	// DefaultSLOObjective is the ratio of computations which must succeed within the window.
+	DefaultSLOObjective = 0.99
+
+	// ConditionTypeDegraded is the type of the condition which reports a burned error budget.
+	ConditionTypeDegraded = "Degraded"
+
+	sloBuckets = 60
+	// minSLOWindow is the shortest window whose buckets span at least a second.
+	minSLOWindow = sloBuckets * time.Second
+)
+
+// SLOOptions configures the service level objective of the computations of the server.
+type SLOOptions struct {
+	// Window is the rolling window over which computations are tracked. Defaults to DefaultSLOWindow.
+	Window time.Duration
+	// Objective is the ratio of computations which must succeed. Defaults to DefaultSLOObjective.
+	Objective float64
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Validate returns an error if the window is too short to be divided into buckets or the objective is not a ratio
+// between 0 and 1. Zero values select the defaults.
+func (o *SLOOptions) Validate() error {
+	if o.Window != 0 && o.Window < minSLOWindow {
+		return fmt.Errorf("the slo window must be at least %s, but is %s", minSLOWindow, o.Window)
+	}
+	if o.Objective != 0 && (o.Objective <= 0 || o.Objective >= 1) {
+		return fmt.Errorf("the slo objective must be greater than 0 and less than 1, but is %g", o.Objective)
+	}
+	return nil
+}
+
+// Condition reports whether the server is degraded.
+type Condition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// SLOStatus is the state of the service level objective within the current window.
+type SLOStatus struct {
+	Window    string  `json:"window"`
+	Objective float64 `json:"objective"`
+	Total     int     `json:"total"`
+	Failures  int     `json:"failures"`
+	// SuccessRatio is the ratio of successful computations, 1 if there were none.
+	SuccessRatio float64 `json:"successRatio"`
+	// ErrorBudgetRemaining is the fraction of the allowed failures which is not yet used. It is negative if the
+	// objective is violated.
+	ErrorBudgetRemaining float64   `json:"errorBudgetRemaining"`
+	Condition            Condition `json:"condition"`
+}
+
+// SLOTracker counts successful and failed computations in buckets of a rolling window.
+type SLOTracker struct {
+	window    time.Duration
+	objective float64
+	now       func() time.Time
+
+	mutex   sync.Mutex
+	buckets [sloBuckets]sloBucket
+	// totals are the counts since the start of the server
+	totalSuccesses int
+	totalFailures  int
+}
+
+type sloBucket struct {
+	start     time.Time
+	successes int
+	failures  int
+}
+
+// NewSLOTracker returns a tracker of the objective. The options may be nil. Invalid options are replaced by the
+// defaults, see SLOOptions.Validate.
+func NewSLOTracker(options *SLOOptions) *SLOTracker {
+	t := &SLOTracker{
+		window:    DefaultSLOWindow,
+		objective: DefaultSLOObjective,
+		now:       time.Now,
+	}
+	if options != nil {
+		if options.Window >= minSLOWindow {
+			t.window = options.Window
+		}
+		if options.Objective > 0 && options.Objective < 1 {
+			t.objective = options.Objective
+		}
+		if options.Now != nil {
+			t.now = options.Now
+		}
+	}
+	return t
+}
+
+// Record tracks the outcome of a computation.
+func (t *SLOTracker) Record(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	resolution := t.window / sloBuckets
+	start := t.now().Truncate(resolution)
+	bucket := &t.buckets[start.UnixNano()/int64(resolution)%sloBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = sloBucket{start: start}
+	}
+
+	if err != nil {
+		bucket.failures++
+		t.totalFailures++
+	} else {
+		bucket.successes++
+		t.totalSuccesses++
+	}
+}
+
+// Status returns the state of the objective within the current window.
+func (t *SLOTracker) Status() SLOStatus {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	status := SLOStatus{
+		Window:       t.window.String(),
+		Objective:    t.objective,
+		SuccessRatio: 1,
+	}
+
+	oldest := t.now().Add(-t.window)
+	for _, bucket := range t.buckets {
+		if bucket.start.After(oldest) {
+			status.Total += bucket.successes + bucket.failures
+			status.Failures += bucket.failures
+		}
+	}
+
+	if status.Total > 0 {
+		status.SuccessRatio = 1 - float64(status.Failures)/float64(status.Total)
+	}
+	allowed := (1 - t.objective) * float64(status.Total)
+	status.ErrorBudgetRemaining = 1
+	if status.Failures > 0 {
+		status.ErrorBudgetRemaining = 1 - float64(status.Failures)/allowed
+	}
+
+	status.Condition = Condition{
+		Type:    ConditionTypeDegraded,
+		Status:  "False",
+		Reason:  "ErrorBudgetAvailable",
+		Message: fmt.Sprintf("%d of %d computations failed within %s", status.Failures, status.Total, status.Window),
+	}
+	if status.ErrorBudgetRemaining <= 0 {
+		status.Condition.Status = "True"
+		status.Condition.Reason = "ErrorBudgetBurned"
+	}
+	return status
+}
+
+// WriteMetrics writes the metrics of the tracker in the prometheus text format.
+func (t *SLOTracker) WriteMetrics(w io.Writer) error {
+	status := t.Status()
+
+	t.mutex.Lock()
+	successes, failures := t.totalSuccesses, t.totalFailures
+	t.mutex.Unlock()
+
+	degraded := 0
+	if status.Condition.Status == "True" {
+		degraded = 1
+	}
+
+	_, err := fmt.Fprintf(w, `# HELP machineimages_computations_total The number of computations by result.
+# TYPE machineimages_computations_total counter
+machineimages_computations_total{result="success"} %d
+machineimages_computations_total{result="failure"} %d
+# HELP machineimages_slo_objective The ratio of computations which must succeed.
+# TYPE machineimages_slo_objective gauge
+machineimages_slo_objective %g
+# HELP machineimages_slo_success_ratio The ratio of successful computations within the window.
+# TYPE machineimages_slo_success_ratio gauge
+machineimages_slo_success_ratio %g
+# HELP machineimages_slo_error_budget_remaining The fraction of the error budget which is not used within the window.
+# TYPE machineimages_slo_error_budget_remaining gauge
+machineimages_slo_error_budget_remaining %g
+# HELP machineimages_degraded Whether the error budget is burned.
+# TYPE machineimages_degraded gauge
+machineimages_degraded %d
+`, successes, failures, status.Objective, status.SuccessRatio, status.ErrorBudgetRemaining, degraded)
+	return err
+}
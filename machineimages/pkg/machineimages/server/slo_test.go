@@ -0,0 +1,150 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+var _ = Describe("slo", func() {
+
+	var (
+		now     time.Time
+		tracker *SLOTracker
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+		tracker = NewSLOTracker(&SLOOptions{Window: time.Hour, Objective: 0.9, Now: func() time.Time { return now }})
+	})
+
+	record := func(successes, failures int) {
+		for i := 0; i < successes; i++ {
+			tracker.Record(nil)
+		}
+		for i := 0; i < failures; i++ {
+			tracker.Record(errors.New("failed"))
+		}
+	}
+
+	It("should not be degraded while the error budget is available", func() {
+		record(19, 1)
+
+		status := tracker.Status()
+		Expect(status.Total).To(Equal(20))
+		Expect(status.Failures).To(Equal(1))
+		Expect(status.SuccessRatio).To(BeNumerically("~", 0.95, 0.0001))
+		Expect(status.ErrorBudgetRemaining).To(BeNumerically("~", 0.5, 0.0001))
+		Expect(status.Condition.Status).To(Equal("False"))
+	})
+
+	It("should be degraded if the error budget is burned", func() {
+		record(8, 2)
+
+		status := tracker.Status()
+		Expect(status.ErrorBudgetRemaining).To(BeNumerically("<=", 0))
+		Expect(status.Condition).To(Equal(Condition{
+			Type:    ConditionTypeDegraded,
+			Status:  "True",
+			Reason:  "ErrorBudgetBurned",
+			Message: "2 of 10 computations failed within 1h0m0s",
+		}))
+	})
+
+	It("should forget computations which are older than the window", func() {
+		record(0, 5)
+		now = now.Add(30 * time.Minute)
+		record(10, 0)
+		Expect(tracker.Status().Failures).To(Equal(5))
+
+		now = now.Add(31 * time.Minute)
+		status := tracker.Status()
+		Expect(status.Total).To(Equal(10))
+		Expect(status.Failures).To(Equal(0))
+		Expect(status.Condition.Status).To(Equal("False"))
+	})
+
+	It("should not divide by an empty error budget", func() {
+		status := tracker.Status()
+		Expect(status.Total).To(Equal(0))
+		Expect(status.SuccessRatio).To(Equal(1.0))
+		Expect(status.ErrorBudgetRemaining).To(Equal(1.0))
+		Expect(status.Condition.Status).To(Equal("False"))
+	})
+
+	It("should use the default window if the window is too short for its buckets", func() {
+		tracker = NewSLOTracker(&SLOOptions{Window: time.Nanosecond, Now: func() time.Time { return now }})
+		record(1, 1)
+		Expect(tracker.Status().Window).To(Equal(DefaultSLOWindow.String()))
+		Expect(tracker.Status().Total).To(Equal(2))
+	})
+
+	It("should validate the options", func() {
+		Expect((&SLOOptions{}).Validate()).To(Succeed())
+		Expect((&SLOOptions{Window: time.Minute, Objective: 0.5}).Validate()).To(Succeed())
+		Expect((&SLOOptions{Window: time.Second}).Validate()).To(MatchError(ContainSubstring("at least 1m0s")))
+		Expect((&SLOOptions{Window: -time.Hour}).Validate()).To(HaveOccurred())
+		Expect((&SLOOptions{Objective: 1}).Validate()).To(MatchError(ContainSubstring("less than 1")))
+		Expect((&SLOOptions{Objective: -0.5}).Validate()).To(HaveOccurred())
+	})
+
+	It("should write the metrics", func() {
+		record(3, 1)
+
+		buf := &bytes.Buffer{}
+		Expect(tracker.WriteMetrics(buf)).To(Succeed())
+		Expect(buf.String()).To(ContainSubstring(`machineimages_computations_total{result="success"} 3`))
+		Expect(buf.String()).To(ContainSubstring(`machineimages_computations_total{result="failure"} 1`))
+		Expect(buf.String()).To(ContainSubstring("machineimages_degraded 1"))
+	})
+
+	It("should track the computations of the server", func() {
+		fail := false
+		loader := func() (*mi.Imports, error) {
+			if fail {
+				return nil, errors.New("unable to read imports")
+			}
+			return &mi.Imports{}, nil
+		}
+		server := httptest.NewServer(New(logr.Discard(), loader, nil).Handler())
+		defer server.Close()
+
+		for _, f := range []bool{false, false, true} {
+			fail = f
+			resp, err := http.Get(server.URL + "/v1/compute")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.Body.Close()).To(Succeed())
+		}
+
+		resp, err := http.Get(server.URL + "/v1/slo")
+		Expect(err).NotTo(HaveOccurred())
+		status := &SLOStatus{}
+		Expect(json.NewDecoder(resp.Body).Decode(status)).To(Succeed())
+		Expect(resp.Body.Close()).To(Succeed())
+		Expect(status.Total).To(Equal(3))
+		Expect(status.Failures).To(Equal(1))
+		Expect(status.Condition.Status).To(Equal("True"))
+
+		resp, err = http.Get(server.URL + "/metrics")
+		Expect(err).NotTo(HaveOccurred())
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+		Expect(string(body)).To(ContainSubstring(`machineimages_computations_total{result="success"} 2`))
+	})
+})
@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"time"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+const (
+	// DefaultWatchInterval is the interval in which watches recompute the machine images.
+	DefaultWatchInterval = 30 * time.Second
+	// MinWatchInterval is the shortest interval which clients may request, as every recomputation loads and computes
+	// the imports.
+	MinWatchInterval = 5 * time.Second
+)
+
+// EventType is the kind of change of a watch event.
+type EventType string
+
+const (
+	EventTypeAdded    = EventType("ADDED")
+	EventTypeModified = EventType("MODIFIED")
+	EventTypeDeleted  = EventType("DELETED")
+)
+
+// Entry is a single version of a machine image.
+type Entry struct {
+	Image   string                 `json:"image"`
+	Version string                 `json:"version"`
+	Data    mi.MachineImageVersion `json:"data,omitempty"`
+}
+
+// Event is a change of the computed machine images.
+type Event struct {
+	Type  EventType `json:"type"`
+	Entry Entry     `json:"entry"`
+}
+
+// ComputeFunc computes the current machine images.
+type ComputeFunc func(ctx context.Context) ([]mi.MachineImage, error)
+
+// Entries flattens machine images into one entry per version.
+func Entries(images []mi.MachineImage) []Entry {
+	result := []Entry{}
+	for _, image := range images {
+		for _, v := range image.Versions {
+			version, _ := v["version"].(string)
+			result = append(result, Entry{Image: image.Name, Version: version, Data: v})
+		}
+	}
+	return result
+}
+
+// Watch recomputes the machine images in the given interval and sends an event for every change to the handler. The
+// first computation sends an ADDED event for every version. Watch returns when the context is cancelled or the
+// computation or the handler fails.
+func Watch(ctx context.Context, interval time.Duration, compute ComputeFunc, handle func(Event) error) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	var previous []mi.MachineImage
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		current, err := compute(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events(previous, current) {
+			if err := handle(event); err != nil {
+				return err
+			}
+		}
+		previous = current
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchInterval returns the interval of a watch for the requested seconds. Zero requests the DefaultWatchInterval,
+// shorter intervals than MinWatchInterval are raised to it.
+func watchInterval(seconds int64) time.Duration {
+	if seconds == 0 {
+		return DefaultWatchInterval
+	}
+	if interval := time.Duration(seconds) * time.Second; interval > MinWatchInterval {
+		return interval
+	}
+	return MinWatchInterval
+}
+
+func events(previous, current []mi.MachineImage) []Event {
+	diff := mi.DiffMachineImages(previous, current)
+	currentEntries := indexEntries(current)
+	previousEntries := indexEntries(previous)
+
+	result := []Event{}
+	for _, ref := range diff.Added {
+		result = append(result, Event{Type: EventTypeAdded, Entry: currentEntries[ref]})
+	}
+	for _, ref := range diff.Changed {
+		result = append(result, Event{Type: EventTypeModified, Entry: currentEntries[ref]})
+	}
+	for _, ref := range diff.Removed {
+		result = append(result, Event{Type: EventTypeDeleted, Entry: previousEntries[ref]})
+	}
+	return result
+}
+
+func indexEntries(images []mi.MachineImage) map[mi.VersionRef]Entry {
+	result := map[mi.VersionRef]Entry{}
+	for _, entry := range Entries(images) {
+		ref := mi.VersionRef{Image: entry.Image, Version: entry.Version}
+		if _, ok := result[ref]; !ok {
+			result[ref] = entry
+		}
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+var _ = Describe("stream", func() {
+
+	It("should clamp the requested interval", func() {
+		Expect(watchInterval(0)).To(Equal(DefaultWatchInterval))
+		Expect(watchInterval(1)).To(Equal(MinWatchInterval))
+		Expect(watchInterval(60)).To(Equal(time.Minute))
+	})
+
+	It("should send the initial state and all changes", func() {
+		states := [][]mi.MachineImage{
+			{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0"}, {"version": "2.0.0"}}}},
+			{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "2.0.0", "image": "b"}, {"version": "3.0.0"}}}},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		calls := 0
+		compute := func(_ context.Context) ([]mi.MachineImage, error) {
+			state := states[calls]
+			calls++
+			if calls == len(states) {
+				cancel()
+			}
+			return state, nil
+		}
+
+		events := []Event{}
+		err := Watch(ctx, time.Millisecond, compute, func(event Event) error {
+			events = append(events, event)
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		types := []string{}
+		for _, event := range events {
+			types = append(types, string(event.Type)+" "+event.Entry.Version)
+		}
+		Expect(types).To(Equal([]string{
+			"ADDED 1.0.0",
+			"ADDED 2.0.0",
+			"ADDED 3.0.0",
+			"MODIFIED 2.0.0",
+			"DELETED 1.0.0",
+		}))
+	})
+})
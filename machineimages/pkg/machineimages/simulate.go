@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// ImpactRemoved marks shoots whose machine image version is no longer offered.
+	ImpactRemoved = "removed"
+	// ImpactChanged marks shoots whose machine image version is still offered with a different configuration.
+	ImpactChanged = "changed"
+)
+
+// SimulationChanges are hypothetical changes of the imports.
+type SimulationChanges struct {
+	// DisableMachineImages are images which are disabled in addition to the disabled images of the imports.
+	DisableMachineImages []string `json:"disableMachineImages,omitempty"`
+	// MinVersions are minimum versions which override the minimum versions of the imports per image.
+	MinVersions map[string]string `json:"minVersions,omitempty"`
+	// IncludeFilters replace the include filters of the imports if not empty.
+	IncludeFilters []OsImagesFilterKind `json:"includeFilters,omitempty"`
+	// ExcludeFilters are filters which are applied in addition to the exclude filters of the imports.
+	ExcludeFilters []OsImagesFilterKind `json:"excludeFilters,omitempty"`
+}
+
+// ShootMachineImage is a machine image version which is used by a shoot.
+type ShootMachineImage struct {
+	// Shoot identifies the shoot, e.g. "<project>/<name>".
+	Shoot   string `json:"shoot"`
+	Image   string `json:"image"`
+	Version string `json:"version"`
+}
+
+// AffectedShoot is a shoot which uses a version that is removed or changed by the simulated changes.
+type AffectedShoot struct {
+	ShootMachineImage `json:",inline"`
+	// Impact is either ImpactRemoved or ImpactChanged.
+	Impact string `json:"impact"`
+}
+
+// SimulationResult is the outcome of a simulation.
+type SimulationResult struct {
+	// Diff lists the versions which differ between the current and the simulated result.
+	Diff *MachineImagesDiff `json:"diff"`
+	// AffectedShoots are the shoots which use a removed or changed version, in the order of the given shoots.
+	AffectedShoots []AffectedShoot `json:"affectedShoots"`
+	// Score quantifies the impact of the simulated changes.
+	Score *ImpactScore `json:"score"`
+}
+
+// Simulate computes the machine images of the imports with and without the changes and returns the difference
+// together with the shoots which would be affected. Neither the imports nor anything else are modified.
+func Simulate(
+	ctx context.Context,
+	log logr.Logger,
+	imports *Imports,
+	changes *SimulationChanges,
+	shoots []ShootMachineImage,
+) (*SimulationResult, error) {
+	log.Info("Simulating changes")
+
+	current, err := ComputeMachineImagesFromImports(ctx, logr.Discard(), imports)
+	if err != nil {
+		return nil, err
+	}
+
+	simulated, err := ComputeMachineImagesFromImports(ctx, logr.Discard(), applySimulationChanges(imports, changes))
+	if err != nil {
+		return nil, err
+	}
+
+	diff := DiffMachineImages(current, simulated)
+
+	impacts := map[VersionRef]string{}
+	for _, ref := range diff.Removed {
+		impacts[ref] = ImpactRemoved
+	}
+	for _, ref := range diff.Changed {
+		impacts[ref] = ImpactChanged
+	}
+
+	affected := []AffectedShoot{}
+	for _, shoot := range shoots {
+		if impact, ok := impacts[VersionRef{Image: shoot.Image, Version: shoot.Version}]; ok {
+			affected = append(affected, AffectedShoot{ShootMachineImage: shoot, Impact: impact})
+		}
+	}
+
+	return &SimulationResult{
+		Diff:           diff,
+		AffectedShoots: affected,
+		Score:          ScoreImpact(diff, current, simulated, shoots),
+	}, nil
+}
+
+// applySimulationChanges returns a copy of the imports with the changes applied. The lists and maps of the imports
+// are copied before they are extended.
+func applySimulationChanges(imports *Imports, changes *SimulationChanges) *Imports {
+	result := *imports
+	if changes == nil {
+		return &result
+	}
+
+	result.DisableMachineImages = append(append([]string{}, imports.DisableMachineImages...),
+		changes.DisableMachineImages...)
+
+	if len(changes.IncludeFilters) > 0 {
+		result.IncludeFilters = changes.IncludeFilters
+	}
+	result.ExcludeFilters = append(append([]OsImagesFilterKind{}, imports.ExcludeFilters...),
+		changes.ExcludeFilters...)
+
+	if len(changes.MinVersions) > 0 {
+		minVersions := map[string]string{}
+		for name, version := range imports.MinVersions {
+			minVersions[name] = version
+		}
+		for name, version := range changes.MinVersions {
+			minVersions[name] = version
+		}
+		result.MinVersions = minVersions
+	}
+
+	return &result
+}
@@ -0,0 +1,83 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("simulate", func() {
+
+	var imports *Imports
+
+	BeforeEach(func() {
+		imports = &Imports{
+			MachineImages: []MachineImage{
+				{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0"}, {"version": "2.0.0"}}},
+				{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.9.0"}}},
+			},
+			MachineImagesProvider: []MachineImage{
+				{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0", "image": "a"}, {"version": "2.0.0", "image": "b"}}},
+				{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.9.0", "image": "c"}}},
+			},
+			DisableMachineImages: []string{OsNameSuseChost},
+		}
+	})
+
+	shoots := []ShootMachineImage{
+		{Shoot: "dev/a", Image: OsNameUbuntu, Version: "1.0.0"},
+		{Shoot: "dev/b", Image: OsNameUbuntu, Version: "2.0.0"},
+		{Shoot: "dev/c", Image: OsNameGardenLinux, Version: "318.9.0"},
+	}
+
+	It("should report the diff and the affected shoots", func() {
+		result, err := Simulate(context.Background(), logr.Discard(), imports, &SimulationChanges{
+			DisableMachineImages: []string{OsNameGardenLinux},
+			MinVersions:          map[string]string{OsNameUbuntu: "2.0.0"},
+		}, shoots)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Diff.Added).To(BeEmpty())
+		Expect(result.Diff.Removed).To(ConsistOf(
+			VersionRef{Image: OsNameUbuntu, Version: "1.0.0"},
+			VersionRef{Image: OsNameGardenLinux, Version: "318.9.0"},
+		))
+		Expect(result.AffectedShoots).To(Equal([]AffectedShoot{
+			{ShootMachineImage: shoots[0], Impact: ImpactRemoved},
+			{ShootMachineImage: shoots[2], Impact: ImpactRemoved},
+		}))
+		Expect(result.Score.RemovedVersions).To(Equal(2))
+		Expect(result.Score.AffectedShoots).To(Equal(2))
+	})
+
+	It("should apply filters", func() {
+		result, err := Simulate(context.Background(), logr.Discard(), imports, &SimulationChanges{
+			IncludeFilters: []OsImagesFilterKind{OsImagesFilterKindUbuntu},
+		}, shoots)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Diff.Removed).To(Equal([]VersionRef{{Image: OsNameGardenLinux, Version: "318.9.0"}}))
+	})
+
+	It("should not modify the imports", func() {
+		_, err := Simulate(context.Background(), logr.Discard(), imports, &SimulationChanges{
+			DisableMachineImages: []string{OsNameGardenLinux},
+			MinVersions:          map[string]string{OsNameUbuntu: "2.0.0"},
+		}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imports.DisableMachineImages).To(Equal([]string{OsNameSuseChost}))
+		Expect(imports.MinVersions).To(BeNil())
+	})
+
+	It("should return an empty diff without changes", func() {
+		result, err := Simulate(context.Background(), logr.Discard(), imports, nil, shoots)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.Diff.Empty()).To(BeTrue())
+		Expect(result.AffectedShoots).To(BeEmpty())
+	})
+})
@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+)
+
+const (
+	// DefaultMaxObjectSize is the default maximum size of a request to etcd, which limits the size of a CloudProfile.
+	DefaultMaxObjectSize = 1572864
+	// DefaultWarnObjectSize is the size above which a warning is logged by default.
+	DefaultWarnObjectSize = DefaultMaxObjectSize * 8 / 10
+)
+
+// SizeLimits configures when the estimated size of a CloudProfile causes a warning or an error.
+type SizeLimits struct {
+	// WarnBytes is the size above which a warning is logged. Defaults to DefaultWarnObjectSize.
+	WarnBytes int `json:"warnBytes,omitempty" yaml:"warnBytes,omitempty"`
+	// MaxBytes is the size above which the computation fails. Defaults to DefaultMaxObjectSize.
+	MaxBytes int `json:"maxBytes,omitempty" yaml:"maxBytes,omitempty"`
+}
+
+// SizeSection is the serialized size of a part of an object.
+type SizeSection struct {
+	// Path identifies the part, e.g. "spec.machineImages" or "spec.machineImages[gardenlinux]".
+	Path  string `json:"path"`
+	Bytes int    `json:"bytes"`
+}
+
+// SizeEstimate is the serialized size of an object with a breakdown by section.
+type SizeEstimate struct {
+	TotalBytes int `json:"totalBytes"`
+	// Sections are ordered by size, largest first.
+	Sections []SizeSection `json:"sections"`
+}
+
+// EstimateSize computes the json serialized size of an object. The breakdown contains the top level fields, the fields
+// of "spec" and for lists of named objects the size of every element.
+func EstimateSize(obj interface{}) (*SizeEstimate, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	generic := map[string]interface{}{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("unable to estimate size of a non object value: %w", err)
+	}
+
+	estimate := &SizeEstimate{
+		TotalBytes: len(data),
+		Sections:   []SizeSection{},
+	}
+	if err := estimate.addSections("", generic, 2); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(estimate.Sections, func(i, j int) bool {
+		if estimate.Sections[i].Bytes != estimate.Sections[j].Bytes {
+			return estimate.Sections[i].Bytes > estimate.Sections[j].Bytes
+		}
+		return estimate.Sections[i].Path < estimate.Sections[j].Path
+	})
+
+	return estimate, nil
+}
+
+// EstimateMachineImagesSize computes the size of a CloudProfile which contains only the given machine images.
+func EstimateMachineImagesSize(machineImages []MachineImage) (*SizeEstimate, error) {
+	return EstimateSize(map[string]interface{}{
+		"apiVersion": "core.gardener.cloud/v1beta1",
+		"kind":       "CloudProfile",
+		"spec": map[string]interface{}{
+			"machineImages": machineImages,
+		},
+	})
+}
+
+// Check logs a warning or returns an error if the estimated size exceeds the limits.
+func (e *SizeEstimate) Check(log logr.Logger, limits *SizeLimits) error {
+	warnBytes := DefaultWarnObjectSize
+	maxBytes := DefaultMaxObjectSize
+	if limits != nil && limits.WarnBytes > 0 {
+		warnBytes = limits.WarnBytes
+	}
+	if limits != nil && limits.MaxBytes > 0 {
+		maxBytes = limits.MaxBytes
+	}
+
+	if e.TotalBytes > maxBytes {
+		return fmt.Errorf("estimated cloud profile size of %d bytes exceeds the limit of %d bytes, largest sections: %v",
+			e.TotalBytes, maxBytes, e.largestSections(3))
+	}
+
+	if e.TotalBytes > warnBytes {
+		log.Info("Warning: estimated cloud profile size is approaching the limit",
+			"bytes", e.TotalBytes, "warnBytes", warnBytes, "maxBytes", maxBytes, "largestSections", e.largestSections(3))
+	}
+
+	return nil
+}
+
+func (e *SizeEstimate) largestSections(n int) []SizeSection {
+	if len(e.Sections) < n {
+		return e.Sections
+	}
+	return e.Sections[:n]
+}
+
+func (e *SizeEstimate) addSections(prefix string, obj map[string]interface{}, depth int) error {
+	for key, value := range obj {
+		path := key
+		if len(prefix) > 0 {
+			path = prefix + "." + key
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		e.Sections = append(e.Sections, SizeSection{Path: path, Bytes: len(data)})
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if depth > 1 && key == "spec" {
+				if err := e.addSections(path, v, depth-1); err != nil {
+					return err
+				}
+			}
+		case []interface{}:
+			for _, item := range v {
+				named, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, ok := named["name"].(string)
+				if !ok {
+					continue
+				}
+				data, err := json.Marshal(item)
+				if err != nil {
+					return err
+				}
+				e.Sections = append(e.Sections, SizeSection{Path: fmt.Sprintf("%s[%s]", path, name), Bytes: len(data)})
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("size", func() {
+
+	machineImages := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.9.0"}, {"version": "318.8.0"}}},
+		{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "18.4.20210415"}}},
+	}
+
+	It("should estimate the size with a breakdown by section", func() {
+		estimate, err := EstimateMachineImagesSize(machineImages)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.TotalBytes).To(BeNumerically(">", 0))
+
+		sizes := map[string]int{}
+		for _, section := range estimate.Sections {
+			sizes[section.Path] = section.Bytes
+		}
+		Expect(sizes).To(HaveKey("spec"))
+		Expect(sizes).To(HaveKey("spec.machineImages"))
+		Expect(sizes).To(HaveKey("spec.machineImages[gardenlinux]"))
+		Expect(sizes).To(HaveKey("spec.machineImages[ubuntu]"))
+		Expect(sizes["spec.machineImages[gardenlinux]"]).To(BeNumerically(">", sizes["spec.machineImages[ubuntu]"]))
+		Expect(estimate.Sections[0].Path).To(Equal("spec"))
+	})
+
+	It("should fail if the size exceeds the limit", func() {
+		estimate, err := EstimateMachineImagesSize(machineImages)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.Check(logr.Discard(), nil)).To(Succeed())
+		Expect(estimate.Check(logr.Discard(), &SizeLimits{WarnBytes: 10})).To(Succeed())
+		Expect(estimate.Check(logr.Discard(), &SizeLimits{MaxBytes: 10})).To(MatchError(ContainSubstring("exceeds the limit")))
+	})
+})
@@ -0,0 +1,101 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+)
+
+// The snapshots in resources/snapshots are anonymized imports of real landscapes, <name>.imports.yaml.gz, with the
+// exports, <name>.exports.yaml.gz, which compute returned when they were recorded. Image ids, accounts and internal
+// versions are replaced before recording and expiration dates are moved far into the past or future, so that the
+// exports do not change over time.
+//
+// Intended changes of the exports are recorded with
+//
+//	UPDATE_SNAPSHOTS=true go test ./pkg/machineimages/...
+//
+// and reviewed like code.
+const snapshotsDir = "./resources/snapshots"
+
+const importsSnapshotSuffix = ".imports.yaml.gz"
+
+func readSnapshot(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+func writeSnapshot(path string, data []byte) error {
+	buf := &bytes.Buffer{}
+	// no timestamp, so that unchanged snapshots are written byte-identical
+	writer := gzip.NewWriter(buf)
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+var _ = Describe("snapshots", func() {
+
+	paths, err := filepath.Glob(filepath.Join(snapshotsDir, "*"+importsSnapshotSuffix))
+	if err != nil {
+		panic(err)
+	}
+
+	It("should have snapshots", func() {
+		Expect(paths).NotTo(BeEmpty())
+	})
+
+	for _, path := range paths {
+		path := path
+		name := strings.TrimSuffix(filepath.Base(path), importsSnapshotSuffix)
+		exportsPath := filepath.Join(snapshotsDir, name+".exports.yaml.gz")
+
+		It("should compute the recorded exports of "+name, func() {
+			data, err := readSnapshot(path)
+			Expect(err).NotTo(HaveOccurred())
+			imports := &Imports{}
+			Expect(yaml.UnmarshalStrict(data, imports)).To(Succeed())
+
+			exports, err := ComputeExports(context.Background(), logr.Discard(), imports)
+			Expect(err).NotTo(HaveOccurred())
+			actual, err := yaml.Marshal(exports)
+			Expect(err).NotTo(HaveOccurred())
+
+			if os.Getenv("UPDATE_SNAPSHOTS") == "true" {
+				Expect(writeSnapshot(exportsPath, actual)).To(Succeed())
+			}
+
+			expected, err := readSnapshot(exportsPath)
+			Expect(err).NotTo(HaveOccurred(), "record the exports with UPDATE_SNAPSHOTS=true")
+			Expect(string(actual)).To(Equal(string(expected)), "record intended changes with UPDATE_SNAPSHOTS=true")
+		})
+	}
+})
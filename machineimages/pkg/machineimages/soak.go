@@ -0,0 +1,162 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultSoakConfigMapKey is the data key of the soak state in a ConfigMap.
+const DefaultSoakConfigMapKey = "soak"
+
+// SoakRecord is the time at which a version was first seen in a landscape.
+type SoakRecord struct {
+	Landscape string    `json:"landscape"`
+	Image     string    `json:"image"`
+	Version   string    `json:"version"`
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+// SoakTracker tracks since when versions are live in landscapes.
+type SoakTracker struct {
+	// Records are ordered by the time at which they were observed.
+	Records []SoakRecord `json:"records"`
+}
+
+// Observe records all versions of the images which were not yet seen in the landscape with the given time. It returns
+// whether new records were added.
+func (t *SoakTracker) Observe(landscape string, images []MachineImage, now time.Time) bool {
+	changed := false
+	for _, ref := range versionRefs(images) {
+		if _, ok := t.FirstSeen(landscape, ref.Image, ref.Version); ok {
+			continue
+		}
+		t.Records = append(t.Records, SoakRecord{
+			Landscape: landscape,
+			Image:     ref.Image,
+			Version:   ref.Version,
+			FirstSeen: now.UTC(),
+		})
+		changed = true
+	}
+	return changed
+}
+
+// FirstSeen returns the time at which the version was first seen in the landscape.
+func (t *SoakTracker) FirstSeen(landscape, image, version string) (time.Time, bool) {
+	for _, record := range t.Records {
+		if record.Landscape == landscape && record.Image == image && record.Version == version {
+			return record.FirstSeen, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// SoakDuration returns for how long the version has been live in the landscape. It returns zero for unknown versions.
+func (t *SoakTracker) SoakDuration(landscape, image, version string, now time.Time) time.Duration {
+	firstSeen, ok := t.FirstSeen(landscape, image, version)
+	if !ok || now.Before(firstSeen) {
+		return 0
+	}
+	return now.Sub(firstSeen)
+}
+
+// LoadSoakTracker reads the soak state from a file. A missing file yields an empty tracker.
+func LoadSoakTracker(path string) (*SoakTracker, error) {
+	tracker := &SoakTracker{Records: []SoakRecord{}}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return tracker, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, tracker); err != nil {
+		return nil, fmt.Errorf("unable to parse soak state %s: %w", path, err)
+	}
+	return tracker, nil
+}
+
+// Save writes the soak state to a file.
+func (t *SoakTracker) Save(path string) error {
+	data, err := yaml.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// NewSoakConfigMap returns a ConfigMap which persists the soak state in the cluster.
+func NewSoakConfigMap(tracker *SoakTracker, name, namespace string) (*ConfigMap, error) {
+	if len(name) == 0 {
+		return nil, errors.New("a config map name must be provided")
+	}
+
+	data, err := yaml.Marshal(tracker)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigMap{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata: ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Data: map[string]string{
+			DefaultSoakConfigMapKey: string(data),
+		},
+	}, nil
+}
+
+// SoakTrackerFromConfigMap reads the soak state from a ConfigMap created by NewSoakConfigMap.
+func SoakTrackerFromConfigMap(configMap *ConfigMap) (*SoakTracker, error) {
+	tracker := &SoakTracker{Records: []SoakRecord{}}
+
+	data, ok := configMap.Data[DefaultSoakConfigMapKey]
+	if !ok {
+		return tracker, nil
+	}
+
+	if err := yaml.Unmarshal([]byte(data), tracker); err != nil {
+		return nil, fmt.Errorf("unable to parse soak state of config map %s/%s: %w",
+			configMap.Metadata.Namespace, configMap.Metadata.Name, err)
+	}
+	return tracker, nil
+}
+
+// SoakGate is a promotion gate which blocks versions until they were live long enough in the source stage.
+type SoakGate struct {
+	Tracker *SoakTracker
+	// MinSoak is the time a version must be live in the source stage before it is promoted.
+	MinSoak time.Duration
+	// Now returns the current time. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// CheckPromotion blocks versions which are live in the source stage for less than the minimum soak time.
+func (g *SoakGate) CheckPromotion(_ context.Context, from Stage, entry CatalogEntry) (string, error) {
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+
+	version := versionOrEmpty(entry.Version)
+	soak := g.Tracker.SoakDuration(from.Name, entry.Name, version, now())
+	if soak < g.MinSoak {
+		return fmt.Sprintf("soaked %s in %s, requires %s", soak.Round(time.Minute), from.Name, g.MinSoak), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("soak", func() {
+
+	now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	images := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.9.0"}, {"version": "934.0.0"}}},
+	}
+
+	It("should keep the first seen time of each version", func() {
+		tracker := &SoakTracker{}
+		Expect(tracker.Observe("canary", images[:1], now)).To(BeTrue())
+		Expect(tracker.Observe("canary", images, now.Add(time.Hour))).To(BeFalse())
+
+		firstSeen, ok := tracker.FirstSeen("canary", OsNameGardenLinux, "934.0.0")
+		Expect(ok).To(BeTrue())
+		Expect(firstSeen).To(Equal(now))
+		Expect(tracker.SoakDuration("canary", OsNameGardenLinux, "934.0.0", now.Add(48*time.Hour))).To(Equal(48 * time.Hour))
+		Expect(tracker.SoakDuration("live", OsNameGardenLinux, "934.0.0", now)).To(BeZero())
+	})
+
+	It("should not record versions which only changed cosmetically", func() {
+		tracker := &SoakTracker{}
+		Expect(tracker.Observe("canary", images, now)).To(BeTrue())
+
+		annotated := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "318.9.0", "annotations": map[string]interface{}{"built": "monday"}}, {"version": "934.0.0"},
+		}}}
+		Expect(tracker.Observe("canary", annotated, now.Add(time.Hour))).To(BeFalse())
+		Expect(tracker.Records).To(HaveLen(2))
+	})
+
+	It("should persist the state in files and config maps", func() {
+		tracker := &SoakTracker{}
+		tracker.Observe("canary", images, now)
+
+		dir, err := ioutil.TempDir("", "soak")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "soak.yaml")
+		empty, err := LoadSoakTracker(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(empty.Records).To(BeEmpty())
+
+		Expect(tracker.Save(path)).To(Succeed())
+		loaded, err := LoadSoakTracker(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(tracker))
+
+		configMap, err := NewSoakConfigMap(tracker, "soak", "garden")
+		Expect(err).NotTo(HaveOccurred())
+		fromConfigMap, err := SoakTrackerFromConfigMap(configMap)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fromConfigMap).To(Equal(tracker))
+	})
+
+	It("should block promotions until the minimum soak time has passed", func() {
+		tracker := &SoakTracker{}
+		tracker.Observe("canary", images, now)
+
+		entry := CatalogEntry{OsImage: OsImage{Name: OsNameGardenLinux, Version: MachineImageVersion{"version": "934.0.0"}}}
+		gate := &SoakGate{Tracker: tracker, MinSoak: 7 * 24 * time.Hour, Now: func() time.Time { return now.Add(24 * time.Hour) }}
+
+		reason, err := gate.CheckPromotion(context.Background(), Stage{Name: "canary"}, entry)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(Equal("soaked 24h0m0s in canary, requires 168h0m0s"))
+
+		gate.Now = func() time.Time { return now.Add(8 * 24 * time.Hour) }
+		reason, err = gate.CheckPromotion(context.Background(), Stage{Name: "canary"}, entry)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(BeEmpty())
+	})
+})
@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ImageOrder determines the order of the images which are not preferred.
+type ImageOrder string
+
+const (
+	// ImageOrderAlphabetical orders the images by name.
+	ImageOrderAlphabetical = ImageOrder("alphabetical")
+	// ImageOrderInsertion keeps the images in the order in which they first appear in the landscape list followed by
+	// the LSS list.
+	ImageOrderInsertion = ImageOrder("insertion")
+)
+
+// VersionOrder determines the order of the versions of every image.
+type VersionOrder string
+
+const (
+	// VersionOrderDescending orders the versions from the newest to the oldest.
+	VersionOrderDescending = VersionOrder("descending")
+	// VersionOrderAscending orders the versions from the oldest to the newest.
+	VersionOrderAscending = VersionOrder("ascending")
+)
+
+// SortOptions determine the order of the computed machine images.
+type SortOptions struct {
+	// PreferredImages are placed first, in the order of the list. If nil, gardenlinux is preferred, an empty list
+	// prefers no image.
+	PreferredImages []string `json:"preferredImages,omitempty" yaml:"preferredImages,omitempty"`
+	// ImageOrder orders the other images. Defaults to ImageOrderAlphabetical.
+	ImageOrder ImageOrder `json:"imageOrder,omitempty" yaml:"imageOrder,omitempty"`
+	// VersionOrder defaults to VersionOrderDescending.
+	VersionOrder VersionOrder `json:"versionOrder,omitempty" yaml:"versionOrder,omitempty"`
+}
+
+// Validate returns an error if an order is unknown. Nil options are valid.
+func (o *SortOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	switch o.ImageOrder {
+	case "", ImageOrderAlphabetical, ImageOrderInsertion:
+	default:
+		return fmt.Errorf("image order does not exist %s", o.ImageOrder)
+	}
+	switch o.VersionOrder {
+	case "", VersionOrderDescending, VersionOrderAscending:
+	default:
+		return fmt.Errorf("version order does not exist %s", o.VersionOrder)
+	}
+	return nil
+}
+
+// sortImages orders the images in place. The images must be in insertion order.
+func (o *SortOptions) sortImages(images []MachineImage) {
+	preferred := []string{OsNameGardenLinux}
+	order := ImageOrderAlphabetical
+	if o != nil {
+		if o.PreferredImages != nil {
+			preferred = o.PreferredImages
+		}
+		if len(o.ImageOrder) > 0 {
+			order = o.ImageOrder
+		}
+	}
+
+	rank := func(name string) int {
+		for i, image := range preferred {
+			if image == name {
+				return i
+			}
+		}
+		return len(preferred)
+	}
+	sort.SliceStable(images, func(i, j int) bool {
+		ri, rj := rank(images[i].Name), rank(images[j].Name)
+		if ri != rj {
+			return ri < rj
+		}
+		return order == ImageOrderAlphabetical && images[i].Name < images[j].Name
+	})
+}
+
+func (o *SortOptions) versionsAscending() bool {
+	return o != nil && o.VersionOrder == VersionOrderAscending
+}
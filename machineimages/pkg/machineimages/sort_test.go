@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sort", func() {
+
+	landscape := []MachineImage{
+		{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "18.4.0"}, {"version": "20.4.0"}}},
+		{Name: "suse-chost", Versions: []MachineImageVersion{{"version": "15.3.0"}}},
+	}
+	lss := []MachineImage{
+		{Name: "flatcar", Versions: []MachineImageVersion{{"version": "2905.2.0"}}},
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.8.0"}, {"version": "934.7.0"}}},
+	}
+
+	compute := func(options *SortOptions) ([]string, error) {
+		provider := []MachineImage{}
+		for _, image := range append(append([]MachineImage{}, landscape...), lss...) {
+			versions := []MachineImageVersion{}
+			for _, version := range image.Versions {
+				versions = append(versions, MachineImageVersion{"version": version["version"], "ami": "ami-1"})
+			}
+			provider = append(provider, MachineImage{Name: image.Name, Versions: versions})
+		}
+
+		result, err := ComputeMachineImagesWithOptions(context.Background(), logr.Discard(), lss, landscape, provider, nil,
+			nil, nil, nil, &ComputeMachineImagesOptions{Sort: options})
+		if err != nil {
+			return nil, err
+		}
+		refs := []string{}
+		for _, image := range result {
+			for _, version := range image.Versions {
+				refs = append(refs, image.Name+":"+versionOrEmpty(version))
+			}
+		}
+		return refs, nil
+	}
+
+	It("should prefer gardenlinux and order the other images alphabetically by default", func() {
+		expected := []string{
+			"gardenlinux:934.7.0", "gardenlinux:318.8.0", "flatcar:2905.2.0", "suse-chost:15.3.0", "ubuntu:20.4.0", "ubuntu:18.4.0",
+		}
+		Expect(compute(nil)).To(Equal(expected))
+		Expect(compute(&SortOptions{})).To(Equal(expected))
+	})
+
+	It("should pin the preferred images to the front", func() {
+		Expect(compute(&SortOptions{PreferredImages: []string{OsNameUbuntu, "flatcar"}})).To(Equal([]string{
+			"ubuntu:20.4.0", "ubuntu:18.4.0", "flatcar:2905.2.0", "gardenlinux:934.7.0", "gardenlinux:318.8.0", "suse-chost:15.3.0",
+		}))
+		Expect(compute(&SortOptions{PreferredImages: []string{}})).To(Equal([]string{
+			"flatcar:2905.2.0", "gardenlinux:934.7.0", "gardenlinux:318.8.0", "suse-chost:15.3.0", "ubuntu:20.4.0", "ubuntu:18.4.0",
+		}))
+	})
+
+	It("should keep the insertion order and sort the versions ascending", func() {
+		Expect(compute(&SortOptions{PreferredImages: []string{}, ImageOrder: ImageOrderInsertion, VersionOrder: VersionOrderAscending})).To(Equal([]string{
+			"ubuntu:18.4.0", "ubuntu:20.4.0", "suse-chost:15.3.0", "flatcar:2905.2.0", "gardenlinux:318.8.0", "gardenlinux:934.7.0",
+		}))
+	})
+
+	It("should reject unknown orders", func() {
+		_, err := compute(&SortOptions{ImageOrder: "random"})
+		Expect(err).To(MatchError("image order does not exist random"))
+		Expect((&SortOptions{VersionOrder: "newest"}).Validate()).To(MatchError("version order does not exist newest"))
+		Expect(ValidateImports(&Imports{ComputeMachineImagesOptions: ComputeMachineImagesOptions{Sort: &SortOptions{ImageOrder: "random"}}})).To(
+			MatchError(ContainSubstring("sort: image order does not exist random")))
+	})
+})
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// PendingApprovalKey is the key of the approval request which waits for an acknowledgement.
+const PendingApprovalKey = "pending-approval"
+
+// ApprovalKeyPrefix is the prefix of the keys which acknowledge approval requests, followed by the id of the request.
+const ApprovalKeyPrefix = "approved-"
+
+// AcknowledgementGate is an mi.ApprovalGate which waits for a manual acknowledgement in a store, usually a ConfigMap.
+// A change is approved once the store contains the key ApprovalKeyPrefix + its id, e.g. "approved-3f2a9c0b1d4e5f60",
+// whose value may name the approver. Until then, the request is written to PendingApprovalKey, so that operators can
+// review it.
+type AcknowledgementGate struct {
+	Store Store
+}
+
+// NewAcknowledgementGate returns a gate which waits for acknowledgements in the store.
+func NewAcknowledgementGate(store Store) *AcknowledgementGate {
+	return &AcknowledgementGate{Store: store}
+}
+
+// CheckApproval approves the request if it is acknowledged in the store.
+func (g *AcknowledgementGate) CheckApproval(ctx context.Context, request *mi.ApprovalRequest) (string, error) {
+	key := ApprovalKeyPrefix + request.ID
+	_, err := g.Store.Get(ctx, key)
+	if err == nil {
+		return "", g.Store.Delete(ctx, PendingApprovalKey)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+
+	data, err := yaml.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	if err := g.Store.Put(ctx, PendingApprovalKey, data); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("waiting for the acknowledgement %s, the request is in %s", key, PendingApprovalKey), nil
+}
+
+// ConsumeApproval deletes the acknowledgement of the request.
+func (g *AcknowledgementGate) ConsumeApproval(ctx context.Context, request *mi.ApprovalRequest) error {
+	return g.Store.Delete(ctx, ApprovalKeyPrefix+request.ID)
+}
+
+// Approval gates the changes of the applied machine images, which are recorded in a store. The computed machine
+// images are compared with the machine images which were applied last, so that removals are detected across runs.
+type Approval struct {
+	Gate      mi.ApprovalGate
+	Threshold *mi.ApprovalThreshold
+	// Store records the applied machine images under AppliedKey.
+	Store     Store
+	Landscape string
+	// Shoots are optional and only counted in the impact score.
+	Shoots []mi.ShootMachineImage
+}
+
+// Validate returns an error if the gate, the store or a valid threshold is missing.
+func (a *Approval) Validate() error {
+	if a.Gate == nil || a.Store == nil {
+		return errors.New("an approval requires a gate and a store")
+	}
+	return a.Threshold.Validate()
+}
+
+// Check asks the gate whether the computed machine images may replace the applied machine images, see
+// mi.CheckApproval. Cosmetic differences of the diff options are no changes. The returned request must be passed to
+// Applied once the computed machine images are applied.
+func (a *Approval) Check(ctx context.Context, diff *mi.DiffOptions, computed []mi.MachineImage) (*mi.ApprovalRequest, error) {
+	applied, _, err := LoadApplied(ctx, a.Store)
+	if err != nil {
+		return nil, err
+	}
+	if applied, err = diff.NormalizeMachineImages(applied); err != nil {
+		return nil, err
+	}
+	if computed, err = diff.NormalizeMachineImages(computed); err != nil {
+		return nil, err
+	}
+
+	request, err := mi.NewApprovalRequest(a.Landscape, applied, computed, a.Shoots)
+	if err != nil {
+		return nil, err
+	}
+	if err := mi.CheckApproval(ctx, a.Gate, request, a.Threshold); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// Applied records the computed machine images as applied and consumes the approval of the request.
+func (a *Approval) Applied(ctx context.Context, request *mi.ApprovalRequest, computed []mi.MachineImage) error {
+	if err := SaveApplied(ctx, a.Store, computed); err != nil {
+		return err
+	}
+	return mi.ConsumeApproval(ctx, a.Gate, request)
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"errors"
+
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("acknowledgement gate", func() {
+	ctx := context.Background()
+
+	It("should wait for the acknowledgement of the request", func() {
+		store := NewMemoryStore()
+		gate := NewAcknowledgementGate(store)
+		request, err := mi.NewApprovalRequest("dev",
+			[]mi.MachineImage{{Name: "gardenlinux", Versions: []mi.MachineImageVersion{{"version": "1.0.0"}}}},
+			[]mi.MachineImage{}, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		reason, err := gate.CheckApproval(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reason).To(Equal("waiting for the acknowledgement approved-" + request.ID + ", the request is in pending-approval"))
+
+		data, err := store.Get(ctx, PendingApprovalKey)
+		Expect(err).NotTo(HaveOccurred())
+		pending := &mi.ApprovalRequest{}
+		Expect(yaml.Unmarshal(data, pending)).To(Succeed())
+		Expect(pending).To(Equal(request))
+
+		Expect(store.Put(ctx, ApprovalKeyPrefix+request.ID, []byte("jane"))).To(Succeed())
+		Expect(gate.CheckApproval(ctx, request)).To(BeEmpty())
+		Expect(store.Keys(ctx)).To(Equal([]string{ApprovalKeyPrefix + request.ID}))
+	})
+
+	It("should diff against the applied machine images and consume the approval", func() {
+		store := NewMemoryStore()
+		approval := &Approval{Gate: NewAcknowledgementGate(store), Store: store,
+			Threshold: &mi.ApprovalThreshold{Impact: mi.ImpactRemoval}}
+		images := []mi.MachineImage{{Name: "gardenlinux", Versions: []mi.MachineImageVersion{{"version": "1.0.0"}}}}
+
+		request, err := approval.Check(ctx, nil, images)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(request.Impact).To(Equal(mi.ImpactAddition))
+		Expect(approval.Applied(ctx, request, images)).To(Succeed())
+
+		_, err = approval.Check(ctx, nil, []mi.MachineImage{})
+		var pendingErr *mi.ApprovalPendingError
+		Expect(errors.As(err, &pendingErr)).To(BeTrue())
+		Expect(pendingErr.Request.Impact).To(Equal(mi.ImpactRemoval))
+
+		Expect(store.Put(ctx, ApprovalKeyPrefix+pendingErr.Request.ID, []byte("jane"))).To(Succeed())
+		request, err = approval.Check(ctx, nil, []mi.MachineImage{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(approval.Applied(ctx, request, nil)).To(Succeed())
+		Expect(store.Keys(ctx)).To(Equal([]string{AppliedKey}))
+		applied, ok, err := LoadApplied(ctx, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(applied).To(BeEmpty())
+	})
+})
@@ -0,0 +1,217 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// encryptedPrefix marks encrypted documents. It is followed by the id of the key and the base64 encoded nonce and
+// ciphertext, so that encrypted documents are valid utf-8 and can be kept in ConfigMaps.
+const encryptedPrefix = "enc:aesgcm:v1:"
+
+// EncryptionKeySize is the size of encryption keys, which select AES-256.
+const EncryptionKeySize = 32
+
+// LoadEncryptionKey reads a base64 encoded encryption key from a file.
+func LoadEncryptionKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %s is not base64 encoded: %w", path, err)
+	}
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("encryption key %s must have %d bytes, got %d", path, EncryptionKeySize, len(key))
+	}
+	return key, nil
+}
+
+// EncryptionKeyID identifies a key in encrypted documents without revealing it.
+func EncryptionKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// EncryptedStore encrypts the documents of a store with AES-GCM. Documents are written with the primary key and read
+// with the key they were written with, which may be the primary key or one of the decryption keys. Unencrypted
+// documents are rejected unless AllowPlaintext is set, so that a store cannot be tampered with by writing plaintext
+// documents. RotateKeys always encrypts existing unencrypted documents.
+//
+// Keys are rotated without downtime by first configuring all readers and writers with the new primary key and the old
+// key as decryption key, then re-encrypting the existing documents with RotateKeys and finally removing the old key.
+type EncryptedStore struct {
+	// AllowPlaintext returns unencrypted documents as they are, e.g. while existing state is migrated.
+	AllowPlaintext bool
+
+	store   Store
+	primary cipher.AEAD
+	keyID   string
+	keys    map[string]cipher.AEAD
+
+	// mutex is held exclusively by a rotation, so that writes of the store are not overwritten with the documents
+	// the rotation read before
+	mutex sync.RWMutex
+}
+
+// NewEncryptedStore returns a store which encrypts the documents of the store with the primary key.
+func NewEncryptedStore(store Store, primary []byte, decryptionKeys ...[]byte) (*EncryptedStore, error) {
+	s := &EncryptedStore{store: store, keyID: EncryptionKeyID(primary), keys: map[string]cipher.AEAD{}}
+	for _, key := range append([][]byte{primary}, decryptionKeys...) {
+		if len(key) != EncryptionKeySize {
+			return nil, fmt.Errorf("encryption keys must have %d bytes, got %d", EncryptionKeySize, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		s.keys[EncryptionKeyID(key)] = aead
+	}
+	s.primary = s.keys[s.keyID]
+	return s, nil
+}
+
+// Get returns the decrypted document of the key.
+func (s *EncryptedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	data, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return s.decrypt(key, data, s.AllowPlaintext)
+}
+
+// Put encrypts the document with the primary key.
+func (s *EncryptedStore) Put(ctx context.Context, key string, data []byte) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.put(ctx, key, data)
+}
+
+func (s *EncryptedStore) put(ctx context.Context, key string, data []byte) error {
+	nonce := make([]byte, s.primary.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	// the key is authenticated, so that documents cannot be swapped between keys
+	sealed := s.primary.Seal(nonce, nonce, data, []byte(key))
+	encrypted := encryptedPrefix + s.keyID + ":" + base64.StdEncoding.EncodeToString(sealed)
+	return s.store.Put(ctx, key, []byte(encrypted))
+}
+
+// Delete removes the key.
+func (s *EncryptedStore) Delete(ctx context.Context, key string) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.store.Delete(ctx, key)
+}
+
+// Keys returns all keys in lexical order.
+func (s *EncryptedStore) Keys(ctx context.Context) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.store.Keys(ctx)
+}
+
+func (s *EncryptedStore) decrypt(key string, data []byte, allowPlaintext bool) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encryptedPrefix)) {
+		if allowPlaintext {
+			return data, nil
+		}
+		return nil, fmt.Errorf("state %s is not encrypted", key)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(string(data), encryptedPrefix), ":", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid encrypted state %s", key)
+	}
+	aead, ok := s.keys[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("state %s is encrypted with unknown key %s", key, parts[0])
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("invalid encrypted state %s", key)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt state %s: %w", key, err)
+	}
+	return plain, nil
+}
+
+// encryptedWithPrimary returns whether the document is encrypted with the primary key.
+func (s *EncryptedStore) encryptedWithPrimary(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(encryptedPrefix+s.keyID+":"))
+}
+
+// RotateKeys re-encrypts all documents of the store, which are encrypted with one of the old keys or not encrypted
+// at all, with the new key. It returns the keys of the re-encrypted documents. Documents which are already encrypted
+// with the new key are not written again, so that an interrupted rotation can be repeated.
+func RotateKeys(ctx context.Context, store Store, newKey []byte, oldKeys ...[]byte) ([]string, error) {
+	encrypted, err := NewEncryptedStore(store, newKey, oldKeys...)
+	if err != nil {
+		return nil, err
+	}
+	return encrypted.RotateKeys(ctx)
+}
+
+// RotateKeys re-encrypts all documents which are not encrypted with the primary key, see RotateKeys. Reads and writes
+// of the store wait until the rotation finished. Writers with other EncryptedStores, e.g. in other processes, must
+// use the primary key of the store before the rotation starts. Unencrypted documents are encrypted regardless of
+// AllowPlaintext, as the rotation is how existing state is migrated.
+func (s *EncryptedStore) RotateKeys(ctx context.Context) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keys, err := s.store.Keys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := []string{}
+	for _, key := range keys {
+		data, err := s.store.Get(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return rotated, err
+		}
+		if s.encryptedWithPrimary(data) {
+			continue
+		}
+
+		plain, err := s.decrypt(key, data, true)
+		if err != nil {
+			return rotated, err
+		}
+		if err := s.put(ctx, key, plain); err != nil {
+			return rotated, fmt.Errorf("unable to re-encrypt state %s: %w", key, err)
+		}
+		rotated = append(rotated, key)
+	}
+	return rotated, nil
+}
@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// blockingStore blocks the first read of a key until it is released.
+type blockingStore struct {
+	Store
+	key      string
+	reading  chan struct{}
+	released chan struct{}
+}
+
+func (s *blockingStore) Get(ctx context.Context, key string) ([]byte, error) {
+	if key == s.key && s.reading != nil {
+		close(s.reading)
+		s.reading = nil
+		<-s.released
+	}
+	return s.Store.Get(ctx, key)
+}
+
+var _ = Describe("encrypted store", func() {
+
+	ctx := context.Background()
+	oldKey := bytes.Repeat([]byte{1}, EncryptionKeySize)
+	newKey := bytes.Repeat([]byte{2}, EncryptionKeySize)
+
+	newEncryptedStore := func(store Store, primary []byte, decryptionKeys ...[]byte) *EncryptedStore {
+		encrypted, err := NewEncryptedStore(store, primary, decryptionKeys...)
+		Expect(err).NotTo(HaveOccurred())
+		return encrypted
+	}
+
+	describeStore(func() Store { return newEncryptedStore(NewMemoryStore(), oldKey) })
+
+	It("should not store documents in plain text", func() {
+		memory := NewMemoryStore()
+		Expect(newEncryptedStore(memory, oldKey).Put(ctx, "revision", []byte("secret"))).To(Succeed())
+
+		data, err := memory.Get(ctx, "revision")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(HavePrefix(encryptedPrefix + EncryptionKeyID(oldKey) + ":"))
+		Expect(string(data)).NotTo(ContainSubstring("secret"))
+	})
+
+	It("should only read unencrypted documents if allowed and fail for unknown keys or swapped documents", func() {
+		memory := NewMemoryStore()
+		Expect(memory.Put(ctx, "plain", []byte("1"))).To(Succeed())
+		_, err := newEncryptedStore(memory, oldKey).Get(ctx, "plain")
+		Expect(err).To(MatchError("state plain is not encrypted"))
+		migrating := newEncryptedStore(memory, oldKey)
+		migrating.AllowPlaintext = true
+		Expect(migrating.Get(ctx, "plain")).To(Equal([]byte("1")))
+
+		Expect(newEncryptedStore(memory, oldKey).Put(ctx, "revision", []byte("2"))).To(Succeed())
+		_, err = newEncryptedStore(memory, newKey).Get(ctx, "revision")
+		Expect(err).To(MatchError(ContainSubstring("unknown key")))
+
+		data, err := memory.Get(ctx, "revision")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(memory.Put(ctx, "checkpoint", data)).To(Succeed())
+		_, err = newEncryptedStore(memory, oldKey).Get(ctx, "checkpoint")
+		Expect(err).To(MatchError(ContainSubstring("unable to decrypt")))
+	})
+
+	It("should rotate keys while readers use both keys", func() {
+		memory := NewMemoryStore()
+		Expect(newEncryptedStore(memory, oldKey).Put(ctx, "revision", []byte("1"))).To(Succeed())
+		Expect(memory.Put(ctx, "plain", []byte("2"))).To(Succeed())
+
+		reader := newEncryptedStore(memory, newKey, oldKey)
+		Expect(reader.Put(ctx, "checkpoint", []byte("3"))).To(Succeed())
+
+		rotated, err := RotateKeys(ctx, memory, newKey, oldKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rotated).To(Equal([]string{"plain", "revision"}))
+
+		rotated, err = RotateKeys(ctx, memory, newKey, oldKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rotated).To(BeEmpty())
+
+		onlyNew := newEncryptedStore(memory, newKey)
+		for key, value := range map[string]string{"revision": "1", "plain": "2", "checkpoint": "3"} {
+			Expect(onlyNew.Get(ctx, key)).To(Equal([]byte(value)))
+		}
+	})
+
+	It("should not overwrite writes during a rotation", func() {
+		memory := NewMemoryStore()
+		Expect(newEncryptedStore(memory, oldKey).Put(ctx, "revision", []byte("1"))).To(Succeed())
+		store := &blockingStore{Store: memory, key: "revision", reading: make(chan struct{}), released: make(chan struct{})}
+		encrypted := newEncryptedStore(store, newKey, oldKey)
+		reading := store.reading
+
+		rotated := make(chan error)
+		go func() {
+			defer GinkgoRecover()
+			_, err := encrypted.RotateKeys(ctx)
+			rotated <- err
+		}()
+		<-reading
+
+		written := make(chan error)
+		go func() {
+			defer GinkgoRecover()
+			written <- encrypted.Put(ctx, "revision", []byte("2"))
+		}()
+		Consistently(written, "50ms").ShouldNot(Receive())
+
+		close(store.released)
+		Expect(<-rotated).To(Succeed())
+		Expect(<-written).To(Succeed())
+		Expect(encrypted.Get(ctx, "revision")).To(Equal([]byte("2")))
+	})
+
+	It("should load base64 encoded keys", func() {
+		dir, err := ioutil.TempDir("", "keys")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "key")
+		Expect(ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(newKey)+"\n"), 0600)).To(Succeed())
+		Expect(LoadEncryptionKey(path)).To(Equal(newKey))
+
+		Expect(ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString([]byte("short"))), 0600)).To(Succeed())
+		_, err = LoadEncryptionKey(path)
+		Expect(err).To(HaveOccurred())
+	})
+})
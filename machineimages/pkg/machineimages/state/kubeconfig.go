@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+// KubeconfigConfig returns the connection to the kube-apiserver of the current context of the kubeconfig. The path
+// may list several kubeconfig files separated by the path list separator, like $KUBECONFIG, which are merged like
+// kubectl merges them. Users authenticate with all methods of client-go, including exec and auth provider plugins,
+// e.g. gardenlogin, but like with kubectl only against https servers.
+func KubeconfigConfig(path string) (*KubernetesConfig, error) {
+	rules := &clientcmd.ClientConfigLoadingRules{Precedence: filepath.SplitList(path)}
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to load kubeconfig %s: %w", path, err)
+	}
+	transport, err := rest.TransportFor(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure the transport of kubeconfig %s: %w", path, err)
+	}
+	// servers without scheme use https if the kubeconfig configures tls, like in client-go
+	tls := restConfig.Insecure || len(restConfig.CAData) > 0 || len(restConfig.CAFile) > 0 ||
+		len(restConfig.CertData) > 0 || len(restConfig.CertFile) > 0
+	host, _, err := rest.DefaultServerURL(restConfig.Host, "", schema.GroupVersion{}, tls)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server of kubeconfig %s: %w", path, err)
+	}
+
+	// the transport authenticates the requests, so that the config has no token
+	return &KubernetesConfig{
+		Host:   host.String(),
+		Client: &http.Client{Transport: mi.NewClientTransport(transport), Timeout: 30 * time.Second},
+	}, nil
+}
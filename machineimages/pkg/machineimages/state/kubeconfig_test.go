@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("kubeconfig", func() {
+
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "kubeconfig-")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+		return path
+	}
+
+	It("should connect to the cluster of the current context with the token of its user", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/apis/core.gardener.cloud/v1beta1/cloudprofiles/aws" || r.Header.Get("Authorization") != "Bearer live" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"kind": "CloudProfile"}`))
+		}))
+		defer server.Close()
+
+		write("token", "live\n")
+		path := write("kubeconfig", `
+current-context: live
+contexts:
+- name: other
+  context: {cluster: other, user: other}
+- name: live
+  context: {cluster: garden, user: operator}
+clusters:
+- name: other
+  cluster: {server: "https://other"}
+- name: garden
+  cluster: {server: "`+server.URL+`", insecure-skip-tls-verify: true}
+users:
+- name: other
+  user: {token: other}
+- name: operator
+  user: {tokenFile: token}
+`)
+
+		config, err := KubeconfigConfig(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config.Host).To(Equal(server.URL))
+
+		profile, err := config.GetCloudProfile(context.Background(), "aws")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(profile)).To(Equal(`{"kind": "CloudProfile"}`))
+
+		_, err = config.GetCloudProfile(context.Background(), "gcp")
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should authenticate with exec plugins and merge the kubeconfigs of a path list", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer exec" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"kind": "CloudProfile"}`))
+		}))
+		defer server.Close()
+
+		clusters := write("clusters", `
+current-context: live
+contexts:
+- name: live
+  context: {cluster: garden, user: operator}
+clusters:
+- name: garden
+  cluster: {server: "`+server.URL+`", insecure-skip-tls-verify: true}
+`)
+		users := write("users", `
+users:
+- name: operator
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: /bin/sh
+      args:
+      - -c
+      - 'echo "{\"apiVersion\": \"client.authentication.k8s.io/v1beta1\", \"kind\": \"ExecCredential\", \"status\": {\"token\": \"exec\"}}"'
+`)
+
+		config, err := KubeconfigConfig(clusters + string(filepath.ListSeparator) + users)
+		Expect(err).NotTo(HaveOccurred())
+		profile, err := config.GetCloudProfile(context.Background(), "aws")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(profile)).To(Equal(`{"kind": "CloudProfile"}`))
+	})
+
+	It("should reject kubeconfigs without current context", func() {
+		path := write("kubeconfig", `
+current-context: missing
+contexts: []
+`)
+		_, err := KubeconfigConfig(path)
+		Expect(err).To(MatchError(ContainSubstring("unable to load kubeconfig")))
+	})
+})
@@ -0,0 +1,389 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+const (
+	// StateAPIVersion is the api version of the MachineImagesState custom resource.
+	StateAPIVersion = "machineimages.gardener.cloud/v1alpha1"
+	// StateKind is the kind of the MachineImagesState custom resource.
+	StateKind = "MachineImagesState"
+
+	statePlural             = "machineimagesstates"
+	serviceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountCAPath    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// KubernetesConfig contains the connection to a kube-apiserver.
+type KubernetesConfig struct {
+	// Host is the url of the kube-apiserver.
+	Host string
+	// Token is the bearer token of the requests.
+	Token string
+	// Client is used for the requests. Defaults to a client which respects the network policy guard.
+	Client *http.Client
+}
+
+// InClusterConfig returns the connection to the kube-apiserver of the cluster in which the process runs, using the
+// token and the ca bundle of its service account.
+func InClusterConfig() (*KubernetesConfig, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if len(host) == 0 || len(port) == 0 {
+		return nil, errors.New("not running in a cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT must be set")
+	}
+
+	token, err := ioutil.ReadFile(serviceAccountTokenPath)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(serviceAccountCAPath)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates in %s", serviceAccountCAPath)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &KubernetesConfig{
+		Host:   "https://" + net.JoinHostPort(host, port),
+		Token:  strings.TrimSpace(string(token)),
+		Client: &http.Client{Transport: mi.NewClientTransport(transport), Timeout: 30 * time.Second},
+	}, nil
+}
+
+// kubernetesObject is a ConfigMap or a MachineImagesState. ConfigMaps keep the documents in data, states in spec.data.
+type kubernetesObject struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   kubernetesMeta    `json:"metadata"`
+	Data       map[string]string `json:"data,omitempty"`
+	Spec       *stateSpec        `json:"spec,omitempty"`
+}
+
+type kubernetesMeta struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace,omitempty"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type stateSpec struct {
+	Data map[string]string `json:"data,omitempty"`
+}
+
+func (o *kubernetesObject) documents() map[string]string {
+	if o.Spec != nil {
+		if o.Spec.Data == nil {
+			o.Spec.Data = map[string]string{}
+		}
+		return o.Spec.Data
+	}
+	if o.Data == nil {
+		o.Data = map[string]string{}
+	}
+	return o.Data
+}
+
+// KubernetesStore keeps the documents as data keys of a single ConfigMap or MachineImagesState. Documents must be
+// valid utf-8. Writes patch only the document of their key, so that the other fields of the object like its labels and
+// annotations are kept. They use optimistic locking and return ErrConflict if the object was modified concurrently.
+type KubernetesStore struct {
+	config    KubernetesConfig
+	namespace string
+	name      string
+	crd       bool
+}
+
+// NewConfigMapStore returns a store which keeps the documents in the ConfigMap.
+func NewConfigMapStore(config KubernetesConfig, namespace, name string) *KubernetesStore {
+	return &KubernetesStore{config: config, namespace: namespace, name: name}
+}
+
+// NewCustomResourceStore returns a store which keeps the documents in the MachineImagesState custom resource. Its
+// definition is in apis/crd.
+func NewCustomResourceStore(config KubernetesConfig, namespace, name string) *KubernetesStore {
+	return &KubernetesStore{config: config, namespace: namespace, name: name, crd: true}
+}
+
+// Get returns the document of the key.
+func (s *KubernetesStore) Get(ctx context.Context, key string) ([]byte, error) {
+	object, err := s.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, ok := object.documents()[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return []byte(data), nil
+}
+
+// Put creates the object if it does not exist and sets the document of the key.
+func (s *KubernetesStore) Put(ctx context.Context, key string, data []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	object, err := s.get(ctx)
+	if errors.Is(err, ErrNotFound) {
+		object = s.newObject()
+		object.documents()[key] = string(data)
+		return s.do(ctx, http.MethodPost, s.collectionPath(), object, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	value := string(data)
+	return s.patch(ctx, object.Metadata.ResourceVersion, key, &value)
+}
+
+// Delete removes the document of the key. The object is kept.
+func (s *KubernetesStore) Delete(ctx context.Context, key string) error {
+	object, err := s.get(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, ok := object.documents()[key]; !ok {
+		return nil
+	}
+
+	return s.patch(ctx, object.Metadata.ResourceVersion, key, nil)
+}
+
+// Keys returns the keys of all documents.
+func (s *KubernetesStore) Keys(ctx context.Context) ([]string, error) {
+	object, err := s.get(ctx)
+	if errors.Is(err, ErrNotFound) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string][]byte{}
+	for key := range object.documents() {
+		data[key] = nil
+	}
+	return sortedKeys(data), nil
+}
+
+// patch sets the document of the key, or removes it if the value is nil, with a json merge patch. The patch fails
+// with ErrConflict if the resource version of the object is no longer the given one.
+func (s *KubernetesStore) patch(ctx context.Context, resourceVersion, key string, value *string) error {
+	documents := map[string]*string{key: value}
+	patch := map[string]interface{}{
+		"metadata": map[string]string{"resourceVersion": resourceVersion},
+	}
+	if s.crd {
+		patch["spec"] = map[string]interface{}{"data": documents}
+	} else {
+		patch["data"] = documents
+	}
+	return s.do(ctx, http.MethodPatch, s.objectPath(), patch, nil)
+}
+
+func (s *KubernetesStore) newObject() *kubernetesObject {
+	object := &kubernetesObject{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   kubernetesMeta{Name: s.name, Namespace: s.namespace},
+	}
+	if s.crd {
+		object.APIVersion = StateAPIVersion
+		object.Kind = StateKind
+		object.Spec = &stateSpec{}
+	}
+	return object
+}
+
+func (s *KubernetesStore) get(ctx context.Context) (*kubernetesObject, error) {
+	object := &kubernetesObject{}
+	if err := s.do(ctx, http.MethodGet, s.objectPath(), nil, object); err != nil {
+		return nil, err
+	}
+	if s.crd && object.Spec == nil {
+		object.Spec = &stateSpec{}
+	}
+	return object, nil
+}
+
+func (s *KubernetesStore) collectionPath() string {
+	if s.crd {
+		return "/apis/" + StateAPIVersion + "/namespaces/" + s.namespace + "/" + statePlural
+	}
+	return "/api/v1/namespaces/" + s.namespace + "/configmaps"
+}
+
+func (s *KubernetesStore) objectPath() string {
+	return s.collectionPath() + "/" + s.name
+}
+
+func (s *KubernetesStore) do(ctx context.Context, method, path string, body, result interface{}) error {
+	return s.config.do(ctx, method, path, body, result)
+}
+
+// GetSecretKey returns the value of a key of a secret. It can be used as mi.SecretResolver.GetSecretKey.
+func (c KubernetesConfig) GetSecretKey(ctx context.Context, namespace, name, key string) ([]byte, error) {
+	secret := &struct {
+		Data map[string][]byte `json:"data"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/namespaces/"+namespace+"/secrets/"+name, nil, secret); err != nil {
+		return nil, err
+	}
+	value, ok := secret.Data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+// GetConfigMapKey returns the value of a key of a ConfigMap. It can be used as mi.SelectionResolver.GetConfigMapKey.
+func (c KubernetesConfig) GetConfigMapKey(ctx context.Context, namespace, name, key string) ([]byte, error) {
+	configMap := &struct {
+		Data map[string]string `json:"data"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/namespaces/"+namespace+"/configmaps/"+name, nil, configMap); err != nil {
+		return nil, err
+	}
+	value, ok := configMap.Data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return []byte(value), nil
+}
+
+// GetDataObject returns the json encoded data of a Landscaper DataObject. It can be used as
+// mi.SelectionResolver.GetDataObject.
+func (c KubernetesConfig) GetDataObject(ctx context.Context, namespace, name string) ([]byte, error) {
+	dataObject := &struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	path := "/apis/landscaper.gardener.cloud/v1alpha1/namespaces/" + namespace + "/dataobjects/" + name
+	if err := c.do(ctx, http.MethodGet, path, nil, dataObject); err != nil {
+		return nil, err
+	}
+	if len(dataObject.Data) == 0 {
+		return nil, ErrNotFound
+	}
+	return dataObject.Data, nil
+}
+
+// GetCloudProfile returns the json encoded Gardener CloudProfile with the name.
+func (c KubernetesConfig) GetCloudProfile(ctx context.Context, name string) ([]byte, error) {
+	profile := json.RawMessage{}
+	if err := c.do(ctx, http.MethodGet, "/apis/core.gardener.cloud/v1beta1/cloudprofiles/"+name, nil, &profile); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// SeedRegions returns the regions of the Gardener seeds of the cluster, usually a garden cluster, in lexical order. If
+// the provider type is not empty, only the regions of the seeds of the provider type are returned.
+func (c KubernetesConfig) SeedRegions(ctx context.Context, providerType string) ([]string, error) {
+	seeds := &struct {
+		Items []struct {
+			Spec struct {
+				Provider struct {
+					Type   string `json:"type"`
+					Region string `json:"region"`
+				} `json:"provider"`
+			} `json:"spec"`
+		} `json:"items"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, "/apis/core.gardener.cloud/v1beta1/seeds", nil, seeds); err != nil {
+		return nil, fmt.Errorf("unable to list seeds: %w", err)
+	}
+
+	found := map[string]bool{}
+	for _, seed := range seeds.Items {
+		provider := seed.Spec.Provider
+		if len(provider.Region) > 0 && (len(providerType) == 0 || provider.Type == providerType) {
+			found[provider.Region] = true
+		}
+	}
+	regions := make([]string, 0, len(found))
+	for region := range found {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+func (c KubernetesConfig) do(ctx context.Context, method, path string, body, result interface{}) error {
+	url := strings.TrimSuffix(c.Host, "/") + path
+	if err := mi.CheckNetworkAccess(ctx, method, url); err != nil {
+		return err
+	}
+
+	data := []byte{}
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	contentType := "application/json"
+	if method == http.MethodPatch {
+		contentType = "application/merge-patch+json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	if len(c.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = mi.NewHTTPClient(nil)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("unexpected status %d of %s %s", resp.StatusCode, method, path)
+	}
+
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,279 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeAPIServer keeps objects by path, applies json merge patches and implements optimistic locking with resource
+// versions.
+type fakeAPIServer struct {
+	mutex   sync.Mutex
+	objects map[string]map[string]interface{}
+	version int
+}
+
+func (f *fakeAPIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := r.URL.Path
+	if r.Method == http.MethodGet {
+		object, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(object)
+		return
+	}
+
+	body := map[string]interface{}{}
+	Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+	metadata, _ := body["metadata"].(map[string]interface{})
+
+	switch r.Method {
+	case http.MethodPost:
+		path = path + "/" + metadata["name"].(string)
+		if _, ok := f.objects[path]; ok {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+	case http.MethodPatch:
+		Expect(r.Header.Get("Content-Type")).To(Equal("application/merge-patch+json"))
+		existing, ok := f.objects[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if version, ok := metadata["resourceVersion"]; ok &&
+			version != existing["metadata"].(map[string]interface{})["resourceVersion"] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		body = mergePatch(existing, body).(map[string]interface{})
+		metadata = body["metadata"].(map[string]interface{})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	f.version++
+	metadata["resourceVersion"] = strconv.Itoa(f.version)
+	f.objects[path] = body
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// object returns the object at the path.
+func (f *fakeAPIServer) object(path string) *kubernetesObject {
+	data, err := json.Marshal(f.objects[path])
+	Expect(err).NotTo(HaveOccurred())
+	object := &kubernetesObject{}
+	Expect(json.Unmarshal(data, object)).To(Succeed())
+	return object
+}
+
+// mergePatch applies a json merge patch according to RFC 7386.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+var _ = Describe("kubernetes store", func() {
+
+	var (
+		fake   *fakeAPIServer
+		server *httptest.Server
+		config KubernetesConfig
+	)
+
+	BeforeEach(func() {
+		fake = &fakeAPIServer{objects: map[string]map[string]interface{}{}}
+		server = httptest.NewServer(fake)
+		config = KubernetesConfig{Host: server.URL, Token: "token"}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("ConfigMap", func() {
+		describeStore(func() Store { return NewConfigMapStore(config, "garden", "machine-images-state") })
+
+		It("should keep the documents in the data of the config map", func() {
+			store := NewConfigMapStore(config, "garden", "machine-images-state")
+			Expect(store.Put(context.Background(), "revision", []byte("1"))).To(Succeed())
+
+			object := fake.object("/api/v1/namespaces/garden/configmaps/machine-images-state")
+			Expect(object.Kind).To(Equal("ConfigMap"))
+			Expect(object.Data).To(Equal(map[string]string{"revision": "1"}))
+		})
+	})
+
+	Context("MachineImagesState", func() {
+		describeStore(func() Store { return NewCustomResourceStore(config, "garden", "state") })
+
+		It("should keep the documents in the spec of the custom resource", func() {
+			store := NewCustomResourceStore(config, "garden", "state")
+			Expect(store.Put(context.Background(), "revision", []byte("1"))).To(Succeed())
+
+			object := fake.object("/apis/machineimages.gardener.cloud/v1alpha1/namespaces/garden/machineimagesstates/state")
+			Expect(object.Kind).To(Equal(StateKind))
+			Expect(object.Spec.Data).To(Equal(map[string]string{"revision": "1"}))
+		})
+	})
+
+	It("should return conflicts of concurrent writes", func() {
+		store := NewConfigMapStore(config, "garden", "state")
+		Expect(store.Put(context.Background(), "revision", []byte("1"))).To(Succeed())
+
+		path := "/api/v1/namespaces/garden/configmaps/state"
+		object, err := store.get(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		fake.objects[path]["metadata"].(map[string]interface{})["resourceVersion"] = "other"
+
+		value := "2"
+		Expect(store.patch(context.Background(), object.Metadata.ResourceVersion, "revision", &value)).To(MatchError(ErrConflict))
+		Expect(fake.object(path).Data).To(Equal(map[string]string{"revision": "1"}))
+	})
+
+	It("should keep the other fields of the object", func() {
+		path := "/apis/machineimages.gardener.cloud/v1alpha1/namespaces/garden/machineimagesstates/state"
+		fake.objects[path] = map[string]interface{}{
+			"apiVersion": StateAPIVersion,
+			"kind":       StateKind,
+			"metadata": map[string]interface{}{
+				"name":            "state",
+				"namespace":       "garden",
+				"resourceVersion": "7",
+				"labels":          map[string]interface{}{"app": "machineimages"},
+				"annotations":     map[string]interface{}{"owner": "team"},
+			},
+			"spec":   map[string]interface{}{"data": map[string]interface{}{"history": "h"}},
+			"status": map[string]interface{}{"observed": "yes"},
+		}
+		store := NewCustomResourceStore(config, "garden", "state")
+
+		Expect(store.Put(context.Background(), "revision", []byte("1"))).To(Succeed())
+		Expect(store.Delete(context.Background(), "history")).To(Succeed())
+
+		object := fake.objects[path]
+		metadata := object["metadata"].(map[string]interface{})
+		Expect(metadata["labels"]).To(Equal(map[string]interface{}{"app": "machineimages"}))
+		Expect(metadata["annotations"]).To(Equal(map[string]interface{}{"owner": "team"}))
+		Expect(object["status"]).To(Equal(map[string]interface{}{"observed": "yes"}))
+		Expect(fake.object(path).Spec.Data).To(Equal(map[string]string{"revision": "1"}))
+	})
+
+	It("should read keys of secrets", func() {
+		secrets := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v1/namespaces/garden/secrets/webhook" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data": {"token": "c2VjcmV0"}}`))
+		}))
+		defer secrets.Close()
+
+		config := KubernetesConfig{Host: secrets.URL}
+		value, err := config.GetSecretKey(context.Background(), "garden", "webhook", "token")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(value)).To(Equal("secret"))
+
+		_, err = config.GetSecretKey(context.Background(), "garden", "webhook", "other")
+		Expect(err).To(MatchError(ErrNotFound))
+		_, err = config.GetSecretKey(context.Background(), "garden", "other", "token")
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should read keys of config maps and the data of data objects", func() {
+		objects := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v1/namespaces/garden/configmaps/selection":
+				_, _ = w.Write([]byte(`{"data": {"selection": "disableMachineImages: [suse-chost]"}}`))
+			case "/apis/landscaper.gardener.cloud/v1alpha1/namespaces/garden/dataobjects/selection":
+				_, _ = w.Write([]byte(`{"data": {"includeFilters": ["supported"]}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer objects.Close()
+
+		config := KubernetesConfig{Host: objects.URL}
+		value, err := config.GetConfigMapKey(context.Background(), "garden", "selection", "selection")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(value)).To(Equal("disableMachineImages: [suse-chost]"))
+		_, err = config.GetConfigMapKey(context.Background(), "garden", "selection", "other")
+		Expect(err).To(MatchError(ErrNotFound))
+
+		data, err := config.GetDataObject(context.Background(), "garden", "selection")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"includeFilters": ["supported"]}`))
+		_, err = config.GetDataObject(context.Background(), "garden", "other")
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should list the regions of the seeds", func() {
+		seeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/apis/core.gardener.cloud/v1beta1/seeds" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{"items": [
+				{"spec": {"provider": {"type": "aws", "region": "eu-west-1"}}},
+				{"spec": {"provider": {"type": "gcp", "region": "europe-west1"}}},
+				{"spec": {"provider": {"type": "aws", "region": "us-east-1"}}},
+				{"spec": {"provider": {"type": "aws", "region": "eu-west-1"}}}
+			]}`))
+		}))
+		defer seeds.Close()
+
+		config := KubernetesConfig{Host: seeds.URL}
+		regions, err := config.SeedRegions(context.Background(), "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(regions).To(Equal([]string{"eu-west-1", "europe-west1", "us-east-1"}))
+
+		regions, err = config.SeedRegions(context.Background(), "aws")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(regions).To(Equal([]string{"eu-west-1", "us-east-1"}))
+	})
+
+	It("should respect the network policy guard", func() {
+		store := NewConfigMapStore(KubernetesConfig{Host: "https://kube-apiserver"}, "garden", "state")
+		_, err := store.Get(mi.WithNetworkPolicyGuard(context.Background()), "revision")
+		Expect(mi.IsNetworkAccessDenied(err)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("kube-apiserver"))
+	})
+})
@@ -0,0 +1,380 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package state persists state which outlives a single computation, e.g. soak timestamps, revisions or checkpoints,
+// in the file system or in a kubernetes cluster.
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+var (
+	// ErrNotFound is returned if a key does not exist in a store.
+	ErrNotFound = errors.New("state not found")
+	// ErrConflict is returned if the state was modified concurrently.
+	ErrConflict = errors.New("state was modified concurrently")
+)
+
+// keyPattern are the valid keys, which are also valid ConfigMap data keys and file names.
+var keyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// SoakKey is the key of the soak state.
+const SoakKey = mi.DefaultSoakConfigMapKey
+
+// ChannelsKey is the key of the current and the candidate catalog.
+const ChannelsKey = mi.DefaultChannelsConfigMapKey
+
+// HistoryKey is the key of the revision history of the catalog.
+const HistoryKey = mi.DefaultHistoryConfigMapKey
+
+// AppliedKey is the key of the machine images which were applied last, the baseline of approvals.
+const AppliedKey = "applied"
+
+// SentNotificationsKey is the key of the notifications which were sent last.
+const SentNotificationsKey = "sent-notifications"
+
+// Store persists documents by key. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the document of the key or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Put creates or replaces the document of the key.
+	Put(ctx context.Context, key string, data []byte) error
+	// Delete removes the key. Deleting a missing key is no error.
+	Delete(ctx context.Context, key string) error
+	// Keys returns all keys in lexical order.
+	Keys(ctx context.Context) ([]string, error)
+}
+
+// ValidateKey returns an error if the key is not a valid key of all stores.
+func ValidateKey(key string) error {
+	if !keyPattern.MatchString(key) || key == "." || key == ".." {
+		return fmt.Errorf("invalid state key %q: must consist of alphanumeric characters, '-', '_' or '.'", key)
+	}
+	return nil
+}
+
+// MemoryStore keeps the documents in memory.
+type MemoryStore struct {
+	mutex sync.Mutex
+	data  map[string][]byte
+}
+
+// NewMemoryStore returns an empty store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: map[string][]byte{}}
+}
+
+// Get returns the document of the key.
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	data, ok := s.data[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte{}, data...), nil
+}
+
+// Put stores a copy of the document.
+func (s *MemoryStore) Put(_ context.Context, key string, data []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data[key] = append([]byte{}, data...)
+	return nil
+}
+
+// Delete removes the key.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.data, key)
+	return nil
+}
+
+// Keys returns all keys.
+func (s *MemoryStore) Keys(_ context.Context) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return sortedKeys(s.data), nil
+}
+
+// FileStore keeps every document in a file of a directory, which is created on the first write.
+type FileStore struct {
+	Dir string
+}
+
+// Get reads the file of the key.
+func (s *FileStore) Get(_ context.Context, key string) ([]byte, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(filepath.Join(s.Dir, key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// Put writes the file of the key. The file is replaced atomically, so that readers never see partial documents.
+func (s *FileStore) Put(_ context.Context, key string, data []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(s.Dir, "."+key+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.Dir, key))
+}
+
+// Delete removes the file of the key.
+func (s *FileStore) Delete(_ context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(s.Dir, key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Keys returns the names of all files of the directory except temporary files.
+func (s *FileStore) Keys(_ context.Context) ([]string, error) {
+	entries, err := ioutil.ReadDir(s.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			keys = append(keys, entry.Name())
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// LoadSoakTracker reads the soak state from the store. A missing state yields an empty tracker.
+func LoadSoakTracker(ctx context.Context, store Store) (*mi.SoakTracker, error) {
+	tracker := &mi.SoakTracker{Records: []mi.SoakRecord{}}
+
+	data, err := store.Get(ctx, SoakKey)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return tracker, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, tracker); err != nil {
+		return nil, fmt.Errorf("unable to parse soak state: %w", err)
+	}
+	return tracker, nil
+}
+
+// SaveSoakTracker writes the soak state to the store.
+func SaveSoakTracker(ctx context.Context, store Store, tracker *mi.SoakTracker) error {
+	data, err := yaml.Marshal(tracker)
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, SoakKey, data)
+}
+
+// LoadChannels reads the channels from the store. Missing channels yield empty channels.
+func LoadChannels(ctx context.Context, store Store) (*mi.Channels, error) {
+	channels := &mi.Channels{}
+
+	data, err := store.Get(ctx, ChannelsKey)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return channels, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, channels); err != nil {
+		return nil, fmt.Errorf("unable to parse channels: %w", err)
+	}
+	return channels, nil
+}
+
+// SaveChannels writes the channels to the store.
+func SaveChannels(ctx context.Context, store Store, channels *mi.Channels) error {
+	data, err := yaml.Marshal(channels)
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, ChannelsKey, data)
+}
+
+// LoadHistory reads the revision history from the store. A missing history yields an empty history.
+func LoadHistory(ctx context.Context, store Store) (*mi.History, error) {
+	history := &mi.History{}
+
+	data, err := store.Get(ctx, HistoryKey)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return history, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, history); err != nil {
+		return nil, fmt.Errorf("unable to parse history: %w", err)
+	}
+	return history, nil
+}
+
+// SaveHistory writes the revision history to the store.
+func SaveHistory(ctx context.Context, store Store, history *mi.History) error {
+	data, err := yaml.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, HistoryKey, data)
+}
+
+// LoadApplied reads the machine images which were applied last from the store. It returns whether they were ever
+// applied, missing machine images yield an empty list.
+func LoadApplied(ctx context.Context, store Store) ([]mi.MachineImage, bool, error) {
+	data, err := store.Get(ctx, AppliedKey)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return []mi.MachineImage{}, false, nil
+		}
+		return nil, false, err
+	}
+
+	images, err := mi.LoadMachineImagesFromYAML(data, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("unable to load the applied machine images: %w", err)
+	}
+	return images, true, nil
+}
+
+// LoadFocusBaseline returns the applied machine images of the store as focus baseline, see
+// mi.ComputeMachineImagesOptions.FocusBaseline, or nil if no machine images were applied yet.
+func LoadFocusBaseline(ctx context.Context, store Store) (*mi.Exports, error) {
+	applied, found, err := LoadApplied(ctx, store)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &mi.Exports{ResultMachineImages: applied}, nil
+}
+
+// SaveApplied writes the applied machine images to the store.
+func SaveApplied(ctx context.Context, store Store, images []mi.MachineImage) error {
+	data, err := mi.MarshalMachineImagesYAML(images)
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, AppliedKey, data)
+}
+
+// LoadSentNotifications reads the notifications which were sent last from the store. Missing notifications yield
+// empty sent notifications.
+func LoadSentNotifications(ctx context.Context, store Store) (*mi.SentNotifications, error) {
+	sent := &mi.SentNotifications{Keys: map[string]string{}}
+
+	data, err := store.Get(ctx, SentNotificationsKey)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return sent, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, sent); err != nil {
+		return nil, fmt.Errorf("unable to parse sent notifications: %w", err)
+	}
+	return sent, nil
+}
+
+// SaveSentNotifications writes the sent notifications to the store.
+func SaveSentNotifications(ctx context.Context, store Store, sent *mi.SentNotifications) error {
+	data, err := yaml.Marshal(sent)
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, SentNotificationsKey, data)
+}
+
+func sortedKeys(data map[string][]byte) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Store references which select a kubernetes store by their scheme.
+const (
+	SchemeConfigMap      = "configmap://"
+	SchemeCustomResource = "crd://"
+)
+
+// NewStore returns the store of a reference. The reference is either a directory of a FileStore or
+// configmap://<namespace>/<name> or crd://<namespace>/<name> for a KubernetesStore in the cluster in which the process
+// runs.
+func NewStore(ref string) (Store, error) {
+	var (
+		newStore func(KubernetesConfig, string, string) *KubernetesStore
+		object   string
+	)
+	switch {
+	case strings.HasPrefix(ref, SchemeConfigMap):
+		newStore, object = NewConfigMapStore, strings.TrimPrefix(ref, SchemeConfigMap)
+	case strings.HasPrefix(ref, SchemeCustomResource):
+		newStore, object = NewCustomResourceStore, strings.TrimPrefix(ref, SchemeCustomResource)
+	default:
+		return &FileStore{Dir: ref}, nil
+	}
+
+	parts := strings.Split(object, "/")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil, fmt.Errorf("state store %q must have the format <scheme>://<namespace>/<name>", ref)
+	}
+
+	config, err := InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	return newStore(*config, parts[0], parts[1]), nil
+}
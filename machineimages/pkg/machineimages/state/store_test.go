@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package state
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"time"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// describeStore runs the specs which all stores must satisfy.
+func describeStore(newStore func() Store) {
+	ctx := context.Background()
+
+	It("should put, get and delete documents", func() {
+		store := newStore()
+
+		_, err := store.Get(ctx, "revision")
+		Expect(err).To(MatchError(ErrNotFound))
+
+		Expect(store.Put(ctx, "revision", []byte("1"))).To(Succeed())
+		Expect(store.Put(ctx, "checkpoint", []byte("a"))).To(Succeed())
+		Expect(store.Put(ctx, "revision", []byte("2"))).To(Succeed())
+
+		data, err := store.Get(ctx, "revision")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal("2"))
+		Expect(store.Keys(ctx)).To(Equal([]string{"checkpoint", "revision"}))
+
+		Expect(store.Delete(ctx, "revision")).To(Succeed())
+		Expect(store.Delete(ctx, "revision")).To(Succeed())
+		Expect(store.Keys(ctx)).To(Equal([]string{"checkpoint"}))
+	})
+
+	It("should reject invalid keys", func() {
+		Expect(newStore().Put(ctx, "../soak", []byte{})).NotTo(Succeed())
+	})
+
+	It("should persist the soak state", func() {
+		store := newStore()
+
+		tracker, err := LoadSoakTracker(ctx, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tracker.Records).To(BeEmpty())
+
+		now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		tracker.Observe("dev", []mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0"}}}}, now)
+		Expect(SaveSoakTracker(ctx, store, tracker)).To(Succeed())
+
+		loaded, err := LoadSoakTracker(ctx, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(tracker))
+	})
+
+	It("should persist the channels", func() {
+		store := newStore()
+
+		channels, err := LoadChannels(ctx, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(channels.Current).To(BeNil())
+
+		channels.Update([]mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0"}}}})
+		Expect(SaveChannels(ctx, store, channels)).To(Succeed())
+
+		loaded, err := LoadChannels(ctx, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(channels))
+	})
+
+	It("should persist the history", func() {
+		store := newStore()
+
+		history, err := LoadHistory(ctx, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history.Revisions).To(BeEmpty())
+
+		now := time.Date(2021, 10, 1, 0, 0, 0, 0, time.UTC)
+		history.Record([]mi.MachineImage{{Name: mi.OsNameUbuntu, Versions: []mi.MachineImageVersion{{"version": "1.0.0"}}}}, now, 0)
+		Expect(SaveHistory(ctx, store, history)).To(Succeed())
+
+		loaded, err := LoadHistory(ctx, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(history))
+	})
+
+	It("should persist the sent notifications", func() {
+		store := newStore()
+
+		sent, err := LoadSentNotifications(ctx, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sent.Keys).To(BeEmpty())
+
+		sent.Keys["0a1b"] = "2c3d"
+		Expect(SaveSentNotifications(ctx, store, sent)).To(Succeed())
+
+		loaded, err := LoadSentNotifications(ctx, store)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(loaded).To(Equal(sent))
+	})
+}
+
+var _ = Describe("store", func() {
+
+	Context("MemoryStore", func() {
+		describeStore(func() Store { return NewMemoryStore() })
+	})
+
+	Context("FileStore", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "state")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(os.RemoveAll(dir)).To(Succeed())
+		})
+
+		describeStore(func() Store { return &FileStore{Dir: dir + "/state"} })
+	})
+
+	It("should create file stores from directories", func() {
+		store, err := NewStore("/tmp/state")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(store).To(Equal(&FileStore{Dir: "/tmp/state"}))
+	})
+
+	It("should reject invalid kubernetes references", func() {
+		_, err := NewStore("configmap://garden")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultTerraformVariable is the name of the generated Terraform variable.
+	DefaultTerraformVariable = "machine_images"
+	// TerraformGlobalRegion is the region key of image ids which are not specific to a region, e.g. gcp images.
+	TerraformGlobalRegion = "global"
+)
+
+// TerraformFormat is the format of generated Terraform variables.
+type TerraformFormat string
+
+const (
+	// TerraformFormatJSON is the format of terraform.tfvars.json files.
+	TerraformFormatJSON TerraformFormat = "json"
+	// TerraformFormatHCL is the format of terraform.tfvars files.
+	TerraformFormatHCL TerraformFormat = "hcl"
+)
+
+// terraformIdentifier matches the names of Terraform variables.
+var terraformIdentifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_-]*$`)
+
+// defaultTerraformIDFields are the fields of versions and their regions which contain the id of an image, in order of
+// precedence.
+var defaultTerraformIDFields = []string{"ami", "id", "image", "urn"}
+
+// TerraformOptions configures the generated Terraform variables.
+type TerraformOptions struct {
+	// Variable is the name of the variable. Defaults to DefaultTerraformVariable.
+	Variable string
+	// IDFields are the fields which contain the id of an image, in order of precedence. Defaults to ami, id, image and
+	// urn.
+	IDFields []string
+	// Aliases are recorded like versions with the image ids of the versions they are resolved to.
+	Aliases VersionAliases
+}
+
+// TerraformImageIDs maps image names to versions to regions to image ids.
+type TerraformImageIDs map[string]map[string]map[string]string
+
+// NewTerraformImageIDs returns the image ids of all versions per region. Regions are read from the regions list of a
+// version, an id field of the version itself is recorded under TerraformGlobalRegion. Versions without any id are
+// omitted.
+func NewTerraformImageIDs(images []MachineImage, options *TerraformOptions) TerraformImageIDs {
+	idFields := defaultTerraformIDFields
+	if options != nil && len(options.IDFields) > 0 {
+		idFields = options.IDFields
+	}
+	var aliases VersionAliases
+	if options != nil {
+		aliases = options.Aliases
+	}
+
+	ids := TerraformImageIDs{}
+	for _, image := range images {
+		for _, version := range image.Versions {
+			regionIDs := map[string]string{}
+			if id, ok := terraformImageID(version, idFields); ok {
+				regionIDs[TerraformGlobalRegion] = id
+			}
+			regions, _ := version["regions"].([]interface{})
+			for _, entry := range regions {
+				region, ok := entry.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := region["name"].(string)
+				if id, ok := terraformImageID(region, idFields); ok && len(name) > 0 {
+					regionIDs[name] = id
+				}
+			}
+
+			if len(regionIDs) == 0 {
+				continue
+			}
+			if _, ok := ids[image.Name]; !ok {
+				ids[image.Name] = map[string]map[string]string{}
+			}
+			ids[image.Name][versionOrEmpty(version)] = regionIDs
+			for _, alias := range aliases.aliasesOf(image.Name, versionOrEmpty(version)) {
+				ids[image.Name][alias] = regionIDs
+			}
+		}
+	}
+	return ids
+}
+
+func terraformImageID(fields map[string]interface{}, idFields []string) (string, bool) {
+	for _, field := range idFields {
+		if id, ok := fields[field].(string); ok && len(id) > 0 {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// MarshalTerraformVariables encodes the image ids as Terraform variables file in the given format.
+func MarshalTerraformVariables(ids TerraformImageIDs, format TerraformFormat, options *TerraformOptions) ([]byte, error) {
+	variable := DefaultTerraformVariable
+	if options != nil && len(options.Variable) > 0 {
+		variable = options.Variable
+	}
+	if !terraformIdentifier.MatchString(variable) {
+		return nil, fmt.Errorf("invalid terraform variable name %q", variable)
+	}
+
+	switch format {
+	case TerraformFormatJSON:
+		data, err := json.MarshalIndent(map[string]TerraformImageIDs{variable: ids}, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case TerraformFormatHCL:
+		buf := &bytes.Buffer{}
+		fmt.Fprintf(buf, "%s = {\n", variable)
+		for _, image := range sortedKeys(ids) {
+			fmt.Fprintf(buf, "  %s = {\n", hclQuote(image))
+			versions := ids[image]
+			for _, version := range sortedKeys(versions) {
+				fmt.Fprintf(buf, "    %s = {\n", hclQuote(version))
+				regions := versions[version]
+				for _, region := range sortedKeys(regions) {
+					fmt.Fprintf(buf, "      %s = %s\n", hclQuote(region), hclQuote(regions[region]))
+				}
+				buf.WriteString("    }\n")
+			}
+			buf.WriteString("  }\n")
+		}
+		buf.WriteString("}\n")
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported terraform format %q", format)
+	}
+}
+
+// hclQuote returns the string as quoted HCL string. Besides quotes, backslashes and control characters, the template
+// sequences ${ and %{ are escaped, so that the string is not interpolated.
+func hclQuote(str string) string {
+	buf := &strings.Builder{}
+	buf.WriteByte('"')
+	for i, r := range str {
+		switch {
+		case r == '"':
+			buf.WriteString(`\"`)
+		case r == '\\':
+			buf.WriteString(`\\`)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case (r == '$' || r == '%') && strings.HasPrefix(str[i+1:], "{"):
+			buf.WriteRune(r)
+			buf.WriteRune(r)
+		case unicode.IsControl(r):
+			fmt.Fprintf(buf, `\u%04x`, r)
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// sortedKeys returns the sorted keys of a map with string keys.
+func sortedKeys(m interface{}) []string {
+	keys := []string{}
+	switch typed := m.(type) {
+	case TerraformImageIDs:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	case map[string]map[string]string:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	case map[string]string:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	case map[string]bool:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	case map[string]int:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	case map[string]*MaintenancePolicy:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	case MachineImageVersion:
+		for key := range typed {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
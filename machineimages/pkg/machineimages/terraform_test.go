@@ -0,0 +1,90 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("terraform", func() {
+
+	images := []MachineImage{
+		{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "318.9.0", "regions": []interface{}{
+				map[string]interface{}{"name": "eu-west-1", "ami": "ami-1"},
+				map[string]interface{}{"name": "us-east-1", "ami": "ami-2"},
+			}},
+			{"version": "318.8.0", "image": "projects/gardenlinux/images/318-8-0"},
+		}},
+		{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "18.4.0"}}},
+	}
+
+	It("should map all versions to their image ids per region", func() {
+		Expect(NewTerraformImageIDs(images, nil)).To(Equal(TerraformImageIDs{
+			OsNameGardenLinux: {
+				"318.9.0": {"eu-west-1": "ami-1", "us-east-1": "ami-2"},
+				"318.8.0": {TerraformGlobalRegion: "projects/gardenlinux/images/318-8-0"},
+			},
+		}))
+	})
+
+	It("should only read the configured id fields", func() {
+		Expect(NewTerraformImageIDs(images, &TerraformOptions{IDFields: []string{"image"}})).To(Equal(TerraformImageIDs{
+			OsNameGardenLinux: {"318.8.0": {TerraformGlobalRegion: "projects/gardenlinux/images/318-8-0"}},
+		}))
+	})
+
+	It("should marshal the variables as json", func() {
+		data, err := MarshalTerraformVariables(NewTerraformImageIDs(images, nil), TerraformFormatJSON, &TerraformOptions{Variable: "images"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"images": {"gardenlinux": {
+			"318.8.0": {"global": "projects/gardenlinux/images/318-8-0"},
+			"318.9.0": {"eu-west-1": "ami-1", "us-east-1": "ami-2"}
+		}}}`))
+	})
+
+	It("should marshal the variables as hcl", func() {
+		data, err := MarshalTerraformVariables(NewTerraformImageIDs(images, nil), TerraformFormatHCL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`machine_images = {
+  "gardenlinux" = {
+    "318.8.0" = {
+      "global" = "projects/gardenlinux/images/318-8-0"
+    }
+    "318.9.0" = {
+      "eu-west-1" = "ami-1"
+      "us-east-1" = "ami-2"
+    }
+  }
+}
+`))
+	})
+
+	It("should escape strings in hcl", func() {
+		ids := TerraformImageIDs{`a"b`: {`1\2`: {"${region}": "%{if x}ami\n"}}}
+		data, err := MarshalTerraformVariables(ids, TerraformFormatHCL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`machine_images = {
+  "a\"b" = {
+    "1\\2" = {
+      "$${region}" = "%%{if x}ami\n"
+    }
+  }
+}
+`))
+		Expect(hclQuote("$ and % and \x00")).To(Equal(`"$ and % and \u0000"`))
+	})
+
+	It("should reject invalid variable names", func() {
+		_, err := MarshalTerraformVariables(TerraformImageIDs{}, TerraformFormatHCL, &TerraformOptions{Variable: "a = 1\nb"})
+		Expect(err).To(MatchError(ContainSubstring("invalid terraform variable name")))
+	})
+
+	It("should reject unknown formats", func() {
+		_, err := MarshalTerraformVariables(TerraformImageIDs{}, "xml", nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// TransportOptions configure the base transport of outbound requests, which is usually set once per process with
+// SetDefaultTransport.
+type TransportOptions struct {
+	// ProxyURL is the proxy of all requests. If empty, the proxy is taken from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY
+	// environment variables.
+	ProxyURL string
+	// NoProxy are the hosts which are reached without ProxyURL: host names, which also match their subdomains, ip
+	// addresses, cidrs or "*" for all hosts. Entries may have a port.
+	NoProxy []string
+	// CABundleFile is the path to pem encoded certificates which are trusted in addition to the system certificates,
+	// e.g. the private ca of a corporate proxy.
+	CABundleFile string
+	// IPFamily restricts outbound connections to an ip family. By default, both families are dialed.
+	IPFamily IPFamily
+	// DNSServers are the dns servers which resolve the hosts of outbound requests instead of the servers of the node,
+	// e.g. in hermetic bootstrap environments. See NewResolver.
+	DNSServers []string
+}
+
+var (
+	defaultTransportMutex sync.RWMutex
+	defaultTransport      http.RoundTripper
+)
+
+// NewTransport returns a clone of http.DefaultTransport with the proxy, the ip family, the dns servers and the ca bundle
+// of the options. The options may be nil.
+func NewTransport(options *TransportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if options == nil {
+		return transport, nil
+	}
+
+	if len(options.ProxyURL) > 0 {
+		proxy, err := url.Parse(options.ProxyURL)
+		if err != nil || len(proxy.Host) == 0 {
+			return nil, fmt.Errorf("invalid proxy url %q", options.ProxyURL)
+		}
+		noProxy := options.NoProxy
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			if bypassProxy(req.URL, noProxy) {
+				return nil, nil
+			}
+			return proxy, nil
+		}
+	}
+
+	if options.IPFamily != IPFamilyAny || len(options.DNSServers) > 0 {
+		var resolver *net.Resolver
+		if len(options.DNSServers) > 0 {
+			var err error
+			resolver, err = NewResolver(options.DNSServers)
+			if err != nil {
+				return nil, err
+			}
+		}
+		dialContext, err := newDialContext(options.IPFamily, resolver)
+		if err != nil {
+			return nil, err
+		}
+		transport.DialContext = dialContext
+	}
+
+	if len(options.CABundleFile) > 0 {
+		data, err := ioutil.ReadFile(options.CABundleFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in ca bundle %s", options.CABundleFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return transport, nil
+}
+
+// SetDefaultTransport sets the base transport of the clients of NewHTTPClient and NewClientTransport, which are
+// created without explicit base transport, to NewTransport of the options.
+func SetDefaultTransport(options *TransportOptions) error {
+	transport, err := NewTransport(options)
+	if err != nil {
+		return err
+	}
+
+	defaultTransportMutex.Lock()
+	defer defaultTransportMutex.Unlock()
+	defaultTransport = transport
+	return nil
+}
+
+// getDefaultTransport returns the transport of SetDefaultTransport or http.DefaultTransport.
+func getDefaultTransport() http.RoundTripper {
+	defaultTransportMutex.RLock()
+	defer defaultTransportMutex.RUnlock()
+	if defaultTransport == nil {
+		return http.DefaultTransport
+	}
+	return defaultTransport
+}
+
+// bypassProxy returns whether the url matches one of the no proxy entries.
+func bypassProxy(target *url.URL, noProxy []string) bool {
+	host, port := target.Hostname(), target.Port()
+	if len(port) == 0 {
+		port = map[string]string{"http": "80", "https": "443"}[target.Scheme]
+	}
+	ip := net.ParseIP(host)
+
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "*" {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(entry); err == nil {
+			if ip != nil && cidr.Contains(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost, entryPort := entry, ""
+		if h, p, err := net.SplitHostPort(entry); err == nil {
+			entryHost, entryPort = h, p
+		}
+		if len(entryPort) > 0 && entryPort != port {
+			continue
+		}
+		if entryIP := net.ParseIP(entryHost); entryIP != nil {
+			if ip != nil && entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		entryHost = strings.TrimPrefix(entryHost, "*")
+		domain := strings.TrimPrefix(entryHost, ".")
+		if len(domain) > 0 && (strings.ToLower(host) == domain || strings.HasSuffix(strings.ToLower(host), "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("transport", func() {
+
+	It("should send requests via the explicit proxy except for no proxy hosts", func() {
+		transport, err := NewTransport(&TransportOptions{
+			ProxyURL: "http://proxy:3128",
+			NoProxy:  []string{"internal.example.com", "10.0.0.0/8", "registry:5000", "::1"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		for target, proxied := range map[string]bool{
+			"https://registry.example.com":     true,
+			"https://internal.example.com":     false,
+			"https://api.internal.example.com": false,
+			"https://notinternal.example.com":  true,
+			"http://10.1.2.3":                  false,
+			"http://registry:5000/v2":          false,
+			"http://registry/v2":               true,
+			"http://[::1]:8080":                false,
+			"http://[2001:db8::1]:8080/v1/slo": true,
+		} {
+			u, err := url.Parse(target)
+			Expect(err).NotTo(HaveOccurred())
+			proxy, err := transport.Proxy(&http.Request{URL: u})
+			Expect(err).NotTo(HaveOccurred())
+			if proxied {
+				Expect(proxy).To(Equal(&url.URL{Scheme: "http", Host: "proxy:3128"}), target)
+			} else {
+				Expect(proxy).To(BeNil(), target)
+			}
+		}
+	})
+
+	It("should reject invalid proxy urls and ca bundles", func() {
+		_, err := NewTransport(&TransportOptions{ProxyURL: "proxy"})
+		Expect(err).To(HaveOccurred())
+
+		dir, err := ioutil.TempDir("", "transport")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "ca.pem")
+		Expect(ioutil.WriteFile(path, []byte("no certificate"), 0600)).To(Succeed())
+		_, err = NewTransport(&TransportOptions{CABundleFile: path})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should trust the ca bundle in the default transport", func() {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		dir, err := ioutil.TempDir("", "transport")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "ca.pem")
+		Expect(ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)).To(Succeed())
+
+		client := NewHTTPClient(nil)
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Do(req)
+		Expect(err).To(HaveOccurred())
+
+		Expect(SetDefaultTransport(&TransportOptions{CABundleFile: path})).To(Succeed())
+		defer func() { Expect(SetDefaultTransport(nil)).To(Succeed()) }()
+		resp, err := client.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+	})
+})
@@ -4,7 +4,10 @@
 
 package machineimages
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type Imports struct {
 	MachineImages           []MachineImage       `json:"machineImages" yaml:"machineImages"`
@@ -14,10 +17,25 @@ type Imports struct {
 	IncludeFilters          []OsImagesFilterKind `json:"includeFilters" yaml:"includeFilters"`
 	ExcludeFilters          []OsImagesFilterKind `json:"excludeFilters" yaml:"excludeFilters"`
 	DisableMachineImages    []string             `json:"disableMachineImages" yaml:"disableMachineImages"`
+	ConfigMapOutput         *ConfigMapOutput     `json:"configMapOutput,omitempty" yaml:"configMapOutput,omitempty"`
+	// SelectionFrom references further disable lists and filters, which are read at runtime with ResolveSelection.
+	SelectionFrom []SelectionSource `json:"selectionFrom,omitempty" yaml:"selectionFrom,omitempty"`
+
+	ComputeMachineImagesOptions `json:",inline" yaml:",inline"`
 }
 
 type Exports struct {
-	ResultMachineImages []MachineImage `json:"resultMachineImages" yaml:"resultMachineImages"`
+	ResultMachineImages          []MachineImage          `json:"resultMachineImages" yaml:"resultMachineImages"`
+	ResultMachineImagesRef       *MachineImagesReference `json:"resultMachineImagesRef,omitempty" yaml:"resultMachineImagesRef,omitempty"`
+	ResultMachineImagesConfigMap *ConfigMap              `json:"resultMachineImagesConfigMap,omitempty" yaml:"resultMachineImagesConfigMap,omitempty"`
+	// ResultMachineImagesCandidate are the machine images of the candidate channel, if the channels are maintained.
+	// ResultMachineImages are then the machine images of the current channel.
+	ResultMachineImagesCandidate []MachineImage `json:"resultMachineImagesCandidate,omitempty" yaml:"resultMachineImagesCandidate,omitempty"`
+	// ResultWarnings are the findings of the computation, e.g. the dropped versions and why they were dropped, so that
+	// they can be shown in the status of the installation.
+	ResultWarnings []ReportEntry `json:"resultWarnings,omitempty" yaml:"resultWarnings,omitempty"`
+	// ResultVersionAliases are the versions of the result which the version aliases of the options are resolved to.
+	ResultVersionAliases VersionAliases `json:"resultVersionAliases,omitempty" yaml:"resultVersionAliases,omitempty"`
 }
 
 type MachineImage struct {
@@ -52,28 +70,36 @@ func (v MachineImageVersion) hasClassification(classification string) bool {
 	return c != nil && *c == classification
 }
 
-func (v MachineImageVersion) getExpirationDate() (*time.Time, error) {
+// ExpirationDate returns the expirationDate of the version or nil if it has none. Expiration dates are RFC 3339
+// timestamps like in Gardener cloud profiles, e.g. "2022-01-15T23:59:59Z", or dates, e.g. "2022-01-15", which
+// expire at the start of the day in UTC.
+func (v MachineImageVersion) ExpirationDate() (*time.Time, error) {
 	m := map[string]interface{}(v)
 	value, ok := m["expirationDate"].(string)
 	if !ok {
 		return nil, nil
 	}
 
-	t, err := time.Parse("2006-01-02T15:04:05Z", value)
-	if err != nil {
-		return nil, err
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
 	}
-
-	return &t, nil
+	return nil, fmt.Errorf("invalid expirationDate %q of version %s, expected a timestamp like %s",
+		value, versionOrEmpty(v), expirationDateFormat)
 }
 
 func (v MachineImageVersion) isExpired() (bool, error) {
-	t, err := v.getExpirationDate()
+	return v.isExpiredAt(time.Now())
+}
+
+func (v MachineImageVersion) isExpiredAt(now time.Time) (bool, error) {
+	t, err := v.ExpirationDate()
 	if err != nil {
 		return false, err
 	}
 
-	return t != nil && time.Now().After(*t), nil
+	return t != nil && now.After(*t), nil
 }
 
 type OsImage struct {
@@ -0,0 +1,269 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError lists all problems of invalid imports or machine images.
+type ValidationError struct {
+	Problems []string
+	// Subject is what is invalid in the message of the error. Defaults to imports.
+	Subject string
+}
+
+func (e *ValidationError) Error() string {
+	subject := e.Subject
+	if len(subject) == 0 {
+		subject = "imports"
+	}
+	return "invalid " + subject + ": " + strings.Join(e.Problems, "; ")
+}
+
+// ValidateImports checks the syntax of the filters, the image lists with the checks of ValidateMachineImages and
+// that the policies are sane. With focus, only the focused images of the lists are checked. It returns a
+// *ValidationError with all problems or nil.
+func ValidateImports(imports *Imports) error {
+	problems := []string{}
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	for _, filters := range []struct {
+		field string
+		kinds []OsImagesFilterKind
+	}{{"includeFilters", imports.IncludeFilters}, {"excludeFilters", imports.ExcludeFilters}} {
+		for _, kind := range filters.kinds {
+			filterType, parameters := lookupOsImagesFilterType(kind)
+			if filterType == nil {
+				add("%s: unknown filter %q", filters.field, kind)
+			} else if _, err := parseOsImagesFilter(filterType, kind, parameters); err != nil {
+				add("%s: invalid filter %q: %v", filters.field, kind, err)
+			}
+		}
+	}
+	if err := validateFilters(imports.IncludeFilters, imports.ExcludeFilters); err != nil {
+		add("excludeFilters: %s", err)
+	}
+
+	for _, pattern := range imports.DisableMachineImages {
+		if _, err := parseDisablePattern(pattern); err != nil {
+			add("disableMachineImages: %v", err)
+		}
+	}
+
+	mappings := imports.FieldMappings
+	for source, mapping := range mappings {
+		if !contains(FieldMappingSources, source) {
+			add("fieldMappings: unknown source %q, expected one of %s", source, strings.Join(FieldMappingSources, ", "))
+		}
+		if err := mapping.Validate(); err != nil {
+			add("fieldMappings: source %s: %v", source, err)
+		}
+	}
+
+	var knownFields []string
+	if imports.StrictVersionFields {
+		knownFields = imports.knownVersionFields()
+	}
+	focus := imports.Focus
+	seenFocus := map[string]bool{}
+	for _, name := range focus {
+		if len(name) == 0 {
+			add("focus: empty image name")
+		} else if seenFocus[name] {
+			add("focus: duplicate image %s", name)
+		}
+		seenFocus[name] = true
+	}
+	problems = append(problems, machineImageListProblems([][]MachineImage{
+		mappings["machineImages"].Apply(focusImages(imports.MachineImages, focus)),
+		mappings["machineImagesLs"].Apply(focusImages(imports.MachineImagesLs, focus)),
+		mappings["machineImagesProvider"].Apply(focusImages(imports.MachineImagesProvider, focus)),
+		mappings["machineImagesProviderLs"].Apply(focusImages(imports.MachineImagesProviderLs, focus)),
+	}, knownFields)...)
+
+	options := &imports.ComputeMachineImagesOptions
+	switch options.MinVersionsAction {
+	case "", PolicyActionDrop, PolicyActionError:
+	default:
+		add("minVersionsAction: unknown action %q", options.MinVersionsAction)
+	}
+	for _, image := range sortedKeys(options.MinVersions) {
+		if version := options.MinVersions[image]; len(version) == 0 {
+			add("minVersions: empty minimum version of image %s", image)
+		} else if _, err := ParseVersion(version); err != nil {
+			add("minVersions: invalid minimum version of image %s: %v", image, err)
+		}
+	}
+	switch options.MergeStrategy {
+	case "", MergeStrategyOverlay, MergeStrategyPreferLandscape, MergeStrategyPreferLSS, MergeStrategyError:
+	default:
+		add("mergeStrategy: unknown strategy %q", options.MergeStrategy)
+	}
+	if err := options.CRIMergeStrategy.Validate(); err != nil {
+		add("criMergeStrategy: unknown strategy %q", options.CRIMergeStrategy)
+	}
+	if err := options.Sort.Validate(); err != nil {
+		add("sort: %v", err)
+	}
+	if options.LatestPerMinor < 0 {
+		add("latestPerMinor: must not be negative")
+	}
+	if budget := options.Budget; budget != nil {
+		if budget.MaxVersions < 0 || budget.MaxVersionsPerImage < 0 {
+			add("budget: limits must not be negative")
+		}
+		switch budget.Strategy {
+		case "", TrimStrategyNewest, TrimStrategyNewestPerMinor, TrimStrategyKeepSupported:
+		default:
+			add("budget: unknown strategy %q", budget.Strategy)
+		}
+	}
+	if eol := options.EndOfLife; eol != nil {
+		if eol.DefaultGracePeriodDays < 0 {
+			add("endOfLife: defaultGracePeriodDays must not be negative")
+		}
+		for _, image := range sortedKeys(eol.GracePeriodDays) {
+			if eol.GracePeriodDays[image] < 0 {
+				add("endOfLife: grace period of image %s must not be negative", image)
+			}
+		}
+	}
+	aliasNames := map[string]bool{}
+	for i, alias := range options.VersionAliases {
+		if err := alias.Validate(); err != nil {
+			add("versionAliases[%d]: %v", i, err)
+		} else if aliasNames[alias.Name] {
+			add("versionAliases[%d]: duplicate alias %s", i, alias.Name)
+		}
+		aliasNames[alias.Name] = true
+	}
+	for _, image := range sortedKeys(options.Maintenance) {
+		policy := options.Maintenance[image]
+		if policy == nil {
+			add("maintenance: policy of image %s must be set", image)
+		} else if policy.PreviewVersions < 0 || policy.SupportedVersions < 0 {
+			add("maintenance: version counts of image %s must not be negative", image)
+		}
+	}
+	if limits := options.SizeLimits; limits != nil {
+		if limits.WarnBytes < 0 || limits.MaxBytes < 0 {
+			add("sizeLimits: limits must not be negative")
+		}
+		if limits.WarnBytes > 0 && limits.MaxBytes > 0 && limits.WarnBytes > limits.MaxBytes {
+			add("sizeLimits: warnBytes must not exceed maxBytes")
+		}
+	}
+	if sampling := options.ReportLogSampling; sampling != nil && sampling.First < 0 {
+		add("reportLogSampling: first must not be negative")
+	}
+	if remote := options.RemoteOsImages; remote != nil {
+		if err := remote.Validate(); err != nil {
+			add("remoteOsImages: %v", err)
+		}
+		if len(imports.MachineImages) > 0 {
+			add("remoteOsImages: machineImages must be empty if remote os images are configured")
+		}
+	}
+	if webhook := options.IncidentsWebhook; webhook != nil && len(webhook.URL) == 0 {
+		add("incidentsWebhook: url must be set")
+	}
+	if notifications := options.Notifications; notifications != nil {
+		if err := notifications.Validate(); err != nil {
+			add("notifications: %v", err)
+		}
+	}
+	if err := options.Diff.Validate(); err != nil {
+		add("diff: %v", err)
+	}
+	for _, provider := range options.DisableProviders {
+		if len(provider) == 0 {
+			add("disableProviders: empty provider type")
+		}
+	}
+	for _, provider := range sortedKeys(options.ProviderEnabled) {
+		if len(provider) == 0 {
+			add("providerEnabled: empty provider type")
+		}
+	}
+	pluginNames := map[string]bool{}
+	for i, plugin := range options.Plugins {
+		if plugin == nil {
+			add("plugins[%d]: must not be empty", i)
+			continue
+		}
+		if err := plugin.Validate(); err != nil {
+			add("plugins[%d]: %v", i, err)
+		}
+		if pluginNames[plugin.Name] {
+			add("plugins[%d]: duplicate plugin %s", i, plugin.Name)
+		}
+		pluginNames[plugin.Name] = true
+	}
+
+	for i, source := range imports.SelectionFrom {
+		refs := 0
+		if len(source.File) > 0 {
+			refs++
+		}
+		if ref := source.ConfigMapKeyRef; ref != nil {
+			refs++
+			if len(ref.Namespace) == 0 || len(ref.Name) == 0 || len(ref.Key) == 0 {
+				add("selectionFrom[%d]: namespace, name and key of the config map must be provided", i)
+			}
+		}
+		if ref := source.DataObjectRef; ref != nil {
+			refs++
+			if len(ref.Namespace) == 0 || len(ref.Name) == 0 {
+				add("selectionFrom[%d]: namespace and name of the data object must be provided", i)
+			}
+		}
+		if refs != 1 {
+			add("selectionFrom[%d]: exactly one of file, configMapKeyRef and dataObjectRef must be set", i)
+		}
+	}
+	if len(options.Provider) > 0 && !contains(MappingProviders(), options.Provider) {
+		add("provider: unknown provider %q, expected one of %s", options.Provider, strings.Join(MappingProviders(), ", "))
+	}
+	if scope := options.RegionScope; scope != nil {
+		switch scope.Action {
+		case "", PolicyActionDrop, PolicyActionError:
+		default:
+			add("regionScope: unknown action %q", scope.Action)
+		}
+		for _, region := range scope.Regions {
+			if len(region) == 0 {
+				add("regionScope: empty region")
+			}
+		}
+	}
+	if probe := options.ArtifactProbe; probe != nil {
+		switch probe.Action {
+		case "", PolicyActionDrop, PolicyActionError:
+		default:
+			add("artifactProbe: unknown action %q", probe.Action)
+		}
+		for _, image := range sortedKeys(probe.Repositories) {
+			repository := probe.Repositories[image]
+			if _, err := ParseArtifactReference(repository + ":tag"); err != nil {
+				add("artifactProbe: invalid repository %q of image %s", repository, image)
+			}
+		}
+		if probe.Token != nil && len(probe.TokenRegistries) == 0 {
+			add("artifactProbe: token requires tokenRegistries")
+		}
+		if probe.TimeoutSeconds < 0 {
+			add("artifactProbe: negative timeoutSeconds")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
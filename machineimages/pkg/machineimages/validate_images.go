@@ -0,0 +1,214 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/constraint"
+)
+
+// MachineImageListFields are the fields of the imports with the four image lists, in the order of the parameters of
+// ComputeMachineImagesWithOptions.
+var MachineImageListFields = []string{"machineImages", "machineImagesLs", "machineImagesProvider", "machineImagesProviderLs"}
+
+// extraVersionFields are the fields of versions which gardener understands in addition to CoreVersionFields.
+var extraVersionFields = []string{"kubeletVersionConstraint", "inPlaceUpdates"}
+
+// KnownVersionFields returns the fields of versions which gardener, the provider extensions or this package
+// understand: CoreVersionFields, the version fields of DefaultProviderFields, the artifact reference and the version
+// constraint of provider configs and the Garden Linux flavor.
+func KnownVersionFields() []string {
+	fields := append(append([]string{}, CoreVersionFields...), extraVersionFields...)
+	fields = append(fields, DefaultArtifactReferenceField, VersionConstraintField, GardenLinuxFlavorField)
+	for _, provider := range DefaultProviderFields {
+		for _, field := range provider.Version {
+			if !contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// ValidateMachineImages checks the four image lists, in the order of ComputeMachineImagesWithOptions, for images
+// without or with duplicate name and versions without, with duplicate or with unparsable version, see ParseVersion, and
+// with invalid expiration date or cri. Versions are only duplicate if they also have the same architecture and Garden
+// Linux flavor, as provider configs may differ per architecture and flavor, see GardenLinuxFlavor. Provider configs may
+// have a valid version constraint instead of a version, see VersionConstraintField, which must not overlap the other
+// constraints of the image with the same architecture and flavor. It returns a *ValidationError with all problems or
+// nil. The problems start with the field path of the invalid value, e.g. "machineImagesLs[0].versions[2].version".
+// ValidateImports checks the image lists of the imports the same way.
+func ValidateMachineImages(lssOsImages, landscapeOsImages, providerOsImages, providerLandscapeOsImages []MachineImage) error {
+	return validateMachineImageLists([][]MachineImage{lssOsImages, landscapeOsImages, providerOsImages, providerLandscapeOsImages}, nil)
+}
+
+// ValidateMachineImagesStrict is ValidateMachineImages which also rejects the fields of versions which are neither
+// known, see KnownVersionFields, nor one of the extra fields, e.g. fields with typos.
+func ValidateMachineImagesStrict(lssOsImages, landscapeOsImages, providerOsImages, providerLandscapeOsImages []MachineImage, extraFields ...string) error {
+	known := append(KnownVersionFields(), extraFields...)
+	return validateMachineImageLists([][]MachineImage{lssOsImages, landscapeOsImages, providerOsImages, providerLandscapeOsImages}, known)
+}
+
+// validateMachineImageLists validates the lists in the order of MachineImageListFields. Unknown fields are only
+// checked if the known fields are not nil.
+func validateMachineImageLists(lists [][]MachineImage, knownFields []string) error {
+	if problems := machineImageListProblems(lists, knownFields); len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// machineImageListProblems returns the problems of the lists in the order of MachineImageListFields.
+func machineImageListProblems(lists [][]MachineImage, knownFields []string) []string {
+	problems := []string{}
+	for i, images := range lists {
+		problems = append(problems, validateMachineImageList(MachineImageListFields[i], images)...)
+		if knownFields != nil {
+			problems = append(problems, unknownVersionFields(MachineImageListFields[i], images, knownFields)...)
+		}
+	}
+	return problems
+}
+
+func validateMachineImageList(field string, images []MachineImage) []string {
+	problems := []string{}
+	add := func(path, format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	seenImages := map[string]string{}
+	for i, image := range images {
+		imagePath := fmt.Sprintf("%s[%d]", field, i)
+		if len(image.Name) == 0 {
+			add(imagePath+".name", "must not be empty")
+		} else if first, ok := seenImages[image.Name]; ok {
+			add(imagePath+".name", "duplicate image %s, first at %s", image.Name, first)
+		} else {
+			seenImages[image.Name] = imagePath
+		}
+
+		seen := map[string]string{}
+		seenConstraints := map[string][]versionConstraintPath{}
+		for j, version := range image.Versions {
+			versionPath := fmt.Sprintf("%s.versions[%d]", imagePath, j)
+			if problem := architecturesProblem(version); len(problem) > 0 {
+				add(versionPath+".architectures", "%s", problem)
+			}
+
+			if isVersionRange(version) {
+				if problem := validateVersionConstraint(field, version); len(problem) > 0 {
+					add(versionPath+"."+VersionConstraintField, "%s", problem)
+					continue
+				}
+				c := constraint.MustParse(version[VersionConstraintField].(string))
+				key := duplicateVersionKey(image.Name, "", version)
+				for _, first := range seenConstraints[key] {
+					if c.Intersects(first.constraint) {
+						add(versionPath+"."+VersionConstraintField, "overlaps the version constraint at %s", first.path)
+						break
+					}
+				}
+				seenConstraints[key] = append(seenConstraints[key], versionConstraintPath{constraint: c, path: versionPath})
+				continue
+			}
+			value, ok := version["version"]
+			if !ok {
+				add(versionPath+".version", "must be set")
+				continue
+			}
+			v, ok := value.(string)
+			if !ok {
+				add(versionPath+".version", "must be a string, got %T", value)
+				continue
+			}
+			if len(v) == 0 {
+				add(versionPath+".version", "must not be empty")
+				continue
+			}
+			key := duplicateVersionKey(image.Name, v, version)
+			if first, ok := seen[key]; ok {
+				add(versionPath+".version", "duplicate version %s, first at %s", v, first)
+				continue
+			}
+			seen[key] = versionPath
+			if _, err := ParseVersion(v); err != nil {
+				add(versionPath+".version", "%v", err)
+			}
+			if _, err := version.ExpirationDate(); err != nil {
+				add(versionPath+".expirationDate", "%v", err)
+			}
+			if err := validateCRI(version); err != nil {
+				add(versionPath+".cri", "%v", err)
+			}
+		}
+	}
+	return problems
+}
+
+// versionConstraintPath is a parsed version constraint of a provider config and the field path of the config.
+type versionConstraintPath struct {
+	constraint *constraint.Constraint
+	path       string
+}
+
+// duplicateVersionKey returns the key under which versions of an image list are unique. Provider configs of the same
+// version may differ by architecture, Garden Linux versions by flavor.
+func duplicateVersionKey(imageName, v string, version MachineImageVersion) string {
+	key := v + "/" + getArchitecture(version)
+	if flavor, ok := gardenLinuxFlavor(OsImage{Name: imageName, Version: version}); ok {
+		key += "/" + flavor.String()
+	}
+	return key
+}
+
+// architecturesProblem returns the problem of the architectures of a version, which must be a list of distinct
+// architectures, or an empty string. Invalid architectures would otherwise make the version architecture-agnostic.
+func architecturesProblem(version MachineImageVersion) string {
+	value, ok := version["architectures"]
+	if !ok {
+		return ""
+	}
+	var entries []interface{}
+	switch list := value.(type) {
+	case []string:
+		for _, entry := range list {
+			entries = append(entries, entry)
+		}
+	case []interface{}:
+		entries = list
+	default:
+		return fmt.Sprintf("must be a list, got %T", value)
+	}
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		architecture, ok := entry.(string)
+		if !ok || len(architecture) == 0 {
+			return fmt.Sprintf("must only contain architectures, got %v", entry)
+		}
+		if seen[architecture] {
+			return fmt.Sprintf("duplicate architecture %s", architecture)
+		}
+		seen[architecture] = true
+	}
+	return ""
+}
+
+// unknownVersionFields returns a problem for every field of the versions which is not known.
+func unknownVersionFields(field string, images []MachineImage, knownFields []string) []string {
+	problems := []string{}
+	for i, image := range images {
+		for j, version := range image.Versions {
+			for _, key := range sortedKeys(version) {
+				if !contains(knownFields, key) {
+					problems = append(problems, fmt.Sprintf("%s[%d].versions[%d].%s: unknown field", field, i, j, key))
+				}
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("machine image validation", func() {
+
+	It("should accept valid image lists", func() {
+		images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.7.0", "classification": "supported", "architectures": []interface{}{"amd64", "arm64"}},
+			{"version": "934.6"},
+		}}}
+		providerImages := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.7.0", "image": "gl-amd64"},
+			{"version": "934.7.0", "image": "gl-arm64", "architecture": "arm64"},
+		}}}
+
+		Expect(ValidateMachineImages(images, nil, providerImages, nil)).To(Succeed())
+		Expect(ValidateMachineImagesStrict(images, nil, providerImages, nil)).To(Succeed())
+	})
+
+	It("should aggregate the problems of all lists with their field paths", func() {
+		err := ValidateMachineImages(
+			[]MachineImage{{Versions: []MachineImageVersion{{"version": "934.7.0"}}}},
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"classification": "supported"}, {"version": 934}, {"version": ""},
+			}}},
+			[]MachineImage{{Name: "ubuntu", Versions: []MachineImageVersion{{"version": "22.4.0"}, {"version": "22.4.0"}}}},
+			[]MachineImage{{Name: "suse-chost", Versions: []MachineImageVersion{{"version": "latest"}}}},
+		)
+
+		validationErr, ok := err.(*ValidationError)
+		Expect(ok).To(BeTrue())
+		Expect(validationErr.Problems).To(Equal([]string{
+			"machineImages[0].name: must not be empty",
+			"machineImagesLs[0].versions[0].version: must be set",
+			"machineImagesLs[0].versions[1].version: must be a string, got int",
+			"machineImagesLs[0].versions[2].version: must not be empty",
+			"machineImagesProvider[0].versions[1].version: duplicate version 22.4.0, first at machineImagesProvider[0].versions[0]",
+			`machineImagesProviderLs[0].versions[0].version: invalid version "latest": part "latest" is not a number`,
+		}))
+	})
+
+	It("should report the same problems as the validation of the imports", func() {
+		images := []MachineImage{
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "22.4.0", "expirationDate": "soon"}}},
+			{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "20.4.0", "cri": "docker"}}},
+		}
+
+		validationErr, ok := ValidateMachineImages(nil, images, nil, nil).(*ValidationError)
+		Expect(ok).To(BeTrue())
+		Expect(validationErr.Problems).To(Equal([]string{
+			`machineImagesLs[0].versions[0].expirationDate: invalid expirationDate "soon" of version 22.4.0, expected a timestamp like 2006-01-02T15:04:05Z`,
+			"machineImagesLs[1].name: duplicate image ubuntu, first at machineImagesLs[0]",
+			"machineImagesLs[1].versions[0].cri: cri must be a list, got string",
+		}))
+		Expect(ValidateImports(&Imports{MachineImagesLs: images})).To(Equal(validationErr))
+	})
+
+	It("should reject invalid architectures", func() {
+		images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.7.0", "architectures": "amd64"},
+			{"version": "934.6.0", "architectures": []interface{}{"amd64", 64}},
+			{"version": "934.5.0", "architectures": []string{"arm64", "arm64"}},
+			{"version": "934.4.0", "architectures": []interface{}{""}},
+		}}}
+
+		validationErr, ok := ValidateMachineImages(images, nil, nil, nil).(*ValidationError)
+		Expect(ok).To(BeTrue())
+		Expect(validationErr.Problems).To(Equal([]string{
+			"machineImages[0].versions[0].architectures: must be a list, got string",
+			"machineImages[0].versions[1].architectures: must only contain architectures, got 64",
+			"machineImages[0].versions[2].architectures: duplicate architecture arm64",
+			"machineImages[0].versions[3].architectures: must only contain architectures, got ",
+		}))
+	})
+
+	It("should reject unknown fields only in strict mode", func() {
+		images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.7.0", "clasification": "supported", "digest": "sha256:abc"},
+		}}}
+
+		Expect(ValidateMachineImages(images, nil, nil, nil)).To(Succeed())
+		Expect(ValidateMachineImagesStrict(images, nil, nil, nil)).To(MatchError(
+			"invalid imports: machineImages[0].versions[0].clasification: unknown field; " +
+				"machineImages[0].versions[0].digest: unknown field"))
+		Expect(ValidateMachineImagesStrict(images, nil, nil, nil, "clasification", "digest")).To(Succeed())
+	})
+
+	It("should fail computations with invalid image lists before they are processed", func() {
+		images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"classification": "supported"}}}}
+		providerImages := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"image": "gl"}}}}
+
+		_, err := ComputeMachineImages(context.Background(), logr.Discard(), images, nil, providerImages, nil, nil, nil, nil)
+		Expect(err).To(MatchError(ContainSubstring("machineImages[0].versions[0].version: must be set")))
+		Expect(err).To(MatchError(ContainSubstring("machineImagesProvider[0].versions[0].version: must be set")))
+		Expect(ClassOf(err)).To(Equal(ErrorClassValidation))
+	})
+
+	It("should accept the artifact reference field of the artifact probe in strict computations", func() {
+		images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.7.0", "artifact": "ghcr.io/gardenlinux/gardenlinux:934.7.0"},
+		}}}
+		options := &ComputeMachineImagesOptions{StrictVersionFields: true}
+
+		_, err := ComputeMachineImagesWithOptions(context.Background(), logr.Discard(), images, nil, nil, nil, nil, nil, nil, options)
+		Expect(err).To(MatchError(ContainSubstring("machineImages[0].versions[0].artifact: unknown field")))
+
+		options.ArtifactProbe = &ArtifactProbe{ReferenceField: "artifact"}
+		Expect(options.knownVersionFields()).To(ContainElement("artifact"))
+	})
+
+	It("should report unknown fields of the imports in strict mode", func() {
+		imports := &Imports{MachineImages: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "934.7.0", "clasification": "supported"},
+		}}}}
+		Expect(ValidateImports(imports)).To(Succeed())
+
+		imports.StrictVersionFields = true
+		Expect(ValidateImports(imports)).To(MatchError(ContainSubstring(
+			"machineImages[0].versions[0].clasification: unknown field")))
+	})
+})
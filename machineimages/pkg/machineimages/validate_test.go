@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("validate", func() {
+
+	It("should accept valid imports", func() {
+		Expect(ValidateImports(&Imports{
+			MachineImages:  []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{"version": "318.8.0"}}}},
+			IncludeFilters: []OsImagesFilterKind{OsImagesFilterKindSupported},
+		})).To(Succeed())
+	})
+
+	It("should list all problems", func() {
+		err := ValidateImports(&Imports{
+			MachineImages:         []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"version": "1.0.0", "expirationDate": "soon"}}}},
+			MachineImagesProvider: []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{{"cri": "docker"}}}},
+			ComputeMachineImagesOptions: ComputeMachineImagesOptions{
+				Budget:           &VersionBudget{MaxVersions: -1, Strategy: "oldest"},
+				IncidentsWebhook: &IncidentsWebhook{},
+			},
+		})
+		validationErr, ok := err.(*ValidationError)
+		Expect(ok).To(BeTrue())
+		Expect(validationErr.Problems).To(Equal([]string{
+			`machineImages[0].versions[0].expirationDate: invalid expirationDate "soon" of version 1.0.0, expected a timestamp like 2006-01-02T15:04:05Z`,
+			"machineImagesProvider[0].versions[0].version: must be set",
+			"budget: limits must not be negative",
+			`budget: unknown strategy "oldest"`,
+			"incidentsWebhook: url must be set",
+		}))
+	})
+
+	It("should require exactly one reference of selection sources", func() {
+		err := ValidateImports(&Imports{SelectionFrom: []SelectionSource{
+			{},
+			{File: "selection.yaml", DataObjectRef: &DataObjectSelector{Namespace: "garden"}},
+		}})
+		validationErr, ok := err.(*ValidationError)
+		Expect(ok).To(BeTrue())
+		Expect(validationErr.Problems).To(Equal([]string{
+			"selectionFrom[0]: exactly one of file, configMapKeyRef and dataObjectRef must be set",
+			"selectionFrom[1]: namespace and name of the data object must be provided",
+			"selectionFrom[1]: exactly one of file, configMapKeyRef and dataObjectRef must be set",
+		}))
+	})
+
+	It("should accept one provider config per architecture", func() {
+		Expect(ValidateImports(&Imports{
+			MachineImagesProvider: []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "22.4.0", "architecture": "amd64", "image": "a"},
+				{"version": "22.4.0", "architecture": "arm64", "image": "b"},
+			}}},
+		})).To(Succeed())
+
+		err := ValidateImports(&Imports{
+			MachineImagesProvider: []MachineImage{{Name: OsNameUbuntu, Versions: []MachineImageVersion{
+				{"version": "22.4.0", "image": "a"},
+				{"version": "22.4.0", "architecture": DefaultArchitecture, "image": "b"},
+			}}},
+		})
+		Expect(err).To(MatchError("invalid imports: machineImagesProvider[0].versions[1].version: duplicate version 22.4.0, first at machineImagesProvider[0].versions[0]"))
+	})
+})
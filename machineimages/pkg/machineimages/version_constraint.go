@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"fmt"
+
+	"github.com/gardener/landscaper-utils/machineimages/pkg/machineimages/constraint"
+)
+
+// VersionConstraintField is the field of provider configs which apply to all versions satisfying a version
+// constraint, e.g. ">=576.0 <600" for one config per minor line, see the constraint package. Such configs have no
+// version. Configs of the exact version take precedence over the configs of constraints of the same image list.
+const VersionConstraintField = "versionConstraint"
+
+// providerListFields are the image lists whose versions may have a version constraint instead of a version.
+var providerListFields = []string{"machineImagesProvider", "machineImagesProviderLs"}
+
+// isVersionRange returns whether the provider config applies to a version constraint.
+func isVersionRange(config MachineImageVersion) bool {
+	_, ok := config[VersionConstraintField]
+	return ok
+}
+
+// matchesVersionConstraint returns whether the provider config has a version constraint which the version satisfies.
+// Invalid constraints match nothing, they are rejected by the validation.
+func matchesVersionConstraint(config MachineImageVersion, version string) bool {
+	value, ok := config[VersionConstraintField].(string)
+	if !ok {
+		return false
+	}
+	matched, err := constraint.Evaluate(value, version)
+	return err == nil && matched
+}
+
+// withoutVersionConstraint returns a copy of the provider config without version constraint, so that the constraint
+// is not merged into the versions.
+func withoutVersionConstraint(config MachineImageVersion) MachineImageVersion {
+	if !isVersionRange(config) {
+		return config
+	}
+	result := MachineImageVersion{}
+	for key, value := range config {
+		if key != VersionConstraintField {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// validateVersionConstraint returns the problem of a version with version constraint, or an empty string. Versions
+// with version constraint must be provider configs without version.
+func validateVersionConstraint(field string, config MachineImageVersion) string {
+	if !contains(providerListFields, field) {
+		return "version constraints are only supported in provider configs"
+	}
+	if _, ok := config["version"]; ok {
+		return "only one of version and versionConstraint must be set"
+	}
+	value, ok := config[VersionConstraintField].(string)
+	if !ok {
+		return fmt.Sprintf("versionConstraint must be a string, got %T", config[VersionConstraintField])
+	}
+	if _, err := constraint.Parse(value); err != nil {
+		return fmt.Sprintf("invalid versionConstraint: %v", err)
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machineimages
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("version constraints of provider configs", func() {
+
+	compute := func(providerImages, providerLandscapeImages []MachineImage) ([]MachineImage, error) {
+		images := []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "576.2.0"}, {"version": "576.1.0"}, {"version": "318.9.0"},
+		}}}
+		return ComputeMachineImagesWithOptions(context.Background(), logr.Discard(), images, nil, providerImages,
+			providerLandscapeImages, nil, nil, nil, nil)
+	}
+
+	It("should apply the configs of constraints to all satisfying versions and prefer exact versions", func() {
+		result, err := compute([]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{VersionConstraintField: ">=576.0 <600", "image": "minor-576"},
+			{"version": "576.1.0", "image": "exact"},
+		}}}, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "576.2.0", "image": "minor-576"},
+			{"version": "576.1.0", "image": "exact"},
+		}}}))
+	})
+
+	It("should prefer exact versions of both lists, then the landscape configs and match architecture specific configs", func() {
+		result, err := compute(
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{"version": "576.2.0", "image": "default"},
+				{VersionConstraintField: "~576.1", "image": "default-576.1"},
+			}}},
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{VersionConstraintField: "~576.2", "image": "landscape"},
+				{VersionConstraintField: "~576.1", "image": "landscape-576.1"},
+			}}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{"version": "576.2.0", "image": "default"},
+			{"version": "576.1.0", "image": "landscape-576.1"},
+		}}}))
+
+		_, configs := getVersionConfigs(OsNameGardenLinux, "576.1.0", []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{VersionConstraintField: "^576", "architecture": "amd64"},
+			{VersionConstraintField: "^576", "architecture": "arm64"},
+			{VersionConstraintField: "^318", "architecture": "amd64"},
+		}}})
+		Expect(configs).To(Equal([]MachineImageVersion{{"architecture": "amd64"}, {"architecture": "arm64"}}))
+	})
+
+	It("should reject invalid constraints and constraints outside of provider configs", func() {
+		err := ValidateMachineImages(
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{{VersionConstraintField: ">=576.0"}}}},
+			nil,
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{VersionConstraintField: ">=576.0", "version": "576.0.0"},
+				{VersionConstraintField: ">>576"},
+			}}},
+			nil)
+		Expect(err).To(MatchError(ContainSubstring("machineImages[0].versions[0].versionConstraint: version constraints are only supported in provider configs")))
+		Expect(err).To(MatchError(ContainSubstring("machineImagesProvider[0].versions[0].versionConstraint: only one of version and versionConstraint must be set")))
+		Expect(err).To(MatchError(ContainSubstring("machineImagesProvider[0].versions[1].versionConstraint: invalid versionConstraint")))
+
+		imports := &Imports{MachineImagesProvider: []MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+			{VersionConstraintField: ">=576.0 <600"},
+		}}}}
+		Expect(ValidateImports(imports)).To(Succeed())
+	})
+
+	It("should reject overlapping constraints of the same architecture", func() {
+		err := ValidateMachineImages(nil, nil,
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{VersionConstraintField: "^576"},
+				{VersionConstraintField: "^576", "architecture": "arm64"},
+				{VersionConstraintField: "^318"},
+				{VersionConstraintField: "~576.2"},
+			}}},
+			[]MachineImage{{Name: OsNameGardenLinux, Versions: []MachineImageVersion{
+				{VersionConstraintField: "^576"},
+			}}})
+		Expect(err).To(MatchError("invalid imports: machineImagesProvider[0].versions[3].versionConstraint: " +
+			"overlaps the version constraint at machineImagesProvider[0].versions[0]"))
+	})
+})
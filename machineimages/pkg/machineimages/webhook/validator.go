@@ -0,0 +1,133 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package webhook validates MachineImagesConfiguration custom resources as Kubernetes validating admission webhook.
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-logr/logr"
+
+	mi "github.com/gardener/landscaper-utils/machineimages/pkg/machineimages"
+)
+
+const (
+	// ConfigurationAPIVersion is the api version of the MachineImagesConfiguration custom resource.
+	ConfigurationAPIVersion = "machineimages.gardener.cloud/v1alpha1"
+	// ConfigurationKind is the kind of the MachineImagesConfiguration custom resource.
+	ConfigurationKind = "MachineImagesConfiguration"
+
+	admissionReviewAPIVersion = "admission.k8s.io/v1"
+	admissionReviewKind       = "AdmissionReview"
+)
+
+// Configuration is a MachineImagesConfiguration custom resource. Its spec are the imports of the computation.
+type Configuration struct {
+	APIVersion string                 `json:"apiVersion"`
+	Kind       string                 `json:"kind"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Spec       mi.Imports             `json:"spec"`
+}
+
+// AdmissionReview is the request and the response of an admission webhook call.
+type AdmissionReview struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Request    *AdmissionRequest  `json:"request,omitempty"`
+	Response   *AdmissionResponse `json:"response,omitempty"`
+}
+
+// AdmissionRequest contains the object of an admission webhook call.
+type AdmissionRequest struct {
+	UID       string          `json:"uid"`
+	Operation string          `json:"operation,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Namespace string          `json:"namespace,omitempty"`
+	Object    json.RawMessage `json:"object,omitempty"`
+}
+
+// AdmissionResponse decides whether the object of the request is admitted.
+type AdmissionResponse struct {
+	UID     string           `json:"uid"`
+	Allowed bool             `json:"allowed"`
+	Result  *AdmissionResult `json:"status,omitempty"`
+}
+
+// AdmissionResult is the reason of a denied request.
+type AdmissionResult struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+}
+
+// Validator handles admission reviews of MachineImagesConfigurations. It applies mi.ValidateImports to their spec, the
+// same validation as when the imports are read from a file.
+type Validator struct {
+	log logr.Logger
+}
+
+// NewValidator returns the validating webhook handler.
+func NewValidator(log logr.Logger) *Validator {
+	return &Validator{log: log}
+}
+
+// ServeHTTP answers an admission review.
+func (v *Validator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	review := &AdmissionReview{}
+	if err := json.NewDecoder(r.Body).Decode(review); err != nil || review.Request == nil {
+		http.Error(w, "expected an admission review with a request", http.StatusBadRequest)
+		return
+	}
+
+	response := v.Review(review.Request)
+	if !response.Allowed {
+		v.log.Info("Denied configuration", "namespace", review.Request.Namespace, "name", review.Request.Name,
+			"reason", response.Result.Message)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&AdmissionReview{
+		APIVersion: admissionReviewAPIVersion,
+		Kind:       admissionReviewKind,
+		Response:   response,
+	})
+}
+
+// Review decides about an admission request. Deletions are always allowed.
+func (v *Validator) Review(request *AdmissionRequest) *AdmissionResponse {
+	if request.Operation == "DELETE" {
+		return &AdmissionResponse{UID: request.UID, Allowed: true}
+	}
+
+	if err := validateConfiguration(request.Object); err != nil {
+		code := int32(http.StatusBadRequest)
+		validationErr := &mi.ValidationError{}
+		if errors.As(err, &validationErr) {
+			code = http.StatusUnprocessableEntity
+		}
+		return &AdmissionResponse{UID: request.UID, Result: &AdmissionResult{Code: code, Message: err.Error()}}
+	}
+	return &AdmissionResponse{UID: request.UID, Allowed: true}
+}
+
+func validateConfiguration(object json.RawMessage) error {
+	configuration := &Configuration{}
+	if err := json.Unmarshal(object, configuration); err != nil {
+		return fmt.Errorf("unable to decode configuration: %w", err)
+	}
+	if configuration.APIVersion != ConfigurationAPIVersion || configuration.Kind != ConfigurationKind {
+		return fmt.Errorf("expected a %s of %s, got %s of %s", ConfigurationKind, ConfigurationAPIVersion,
+			configuration.Kind, configuration.APIVersion)
+	}
+	return mi.ValidateImports(&configuration.Spec)
+}
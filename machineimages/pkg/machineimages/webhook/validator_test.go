@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("validator", func() {
+
+	var validator *Validator
+
+	BeforeEach(func() {
+		validator = NewValidator(logr.Discard())
+	})
+
+	review := func(object string) *AdmissionResponse {
+		body, err := json.Marshal(&AdmissionReview{
+			APIVersion: admissionReviewAPIVersion,
+			Kind:       admissionReviewKind,
+			Request:    &AdmissionRequest{UID: "uid", Operation: "CREATE", Object: json.RawMessage(object)},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		recorder := httptest.NewRecorder()
+		validator.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader(body)))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		result := &AdmissionReview{}
+		Expect(json.NewDecoder(recorder.Body).Decode(result)).To(Succeed())
+		Expect(result.Kind).To(Equal(admissionReviewKind))
+		Expect(result.Response.UID).To(Equal("uid"))
+		return result.Response
+	}
+
+	It("should allow valid configurations", func() {
+		response := review(`{
+			"apiVersion": "machineimages.gardener.cloud/v1alpha1",
+			"kind": "MachineImagesConfiguration",
+			"spec": {
+				"machineImages": [{"name": "gardenlinux", "versions": [{"version": "318.8.0"}]}],
+				"includeFilters": ["supported"],
+				"budget": {"maxVersions": 10, "strategy": "newestPerMinor"}
+			}
+		}`)
+		Expect(response.Allowed).To(BeTrue())
+	})
+
+	It("should deny invalid filters, duplicate entries and insane policies", func() {
+		response := review(`{
+			"apiVersion": "machineimages.gardener.cloud/v1alpha1",
+			"kind": "MachineImagesConfiguration",
+			"spec": {
+				"machineImagesLs": [
+					{"name": "gardenlinux", "versions": [{"version": "318.8.0"}, {"version": "318.8.0"}]},
+					{"name": "gardenlinux"}
+				],
+				"includeFilters": ["supported", "stable"],
+				"excludeFilters": ["supported"],
+				"minVersionsAction": "warn",
+				"sizeLimits": {"warnBytes": 2000, "maxBytes": 1000}
+			}
+		}`)
+		Expect(response.Allowed).To(BeFalse())
+		Expect(response.Result.Code).To(Equal(int32(http.StatusUnprocessableEntity)))
+		for _, problem := range []string{
+			`includeFilters: unknown filter "stable"`,
+			"excludeFilters: exclude filter list contains element of include list",
+			"machineImagesLs[0].versions[1].version: duplicate version 318.8.0, first at machineImagesLs[0].versions[0]",
+			"machineImagesLs[1].name: duplicate image gardenlinux, first at machineImagesLs[0]",
+			`minVersionsAction: unknown action "warn"`,
+			"sizeLimits: warnBytes must not exceed maxBytes",
+		} {
+			Expect(response.Result.Message).To(ContainSubstring(problem))
+		}
+	})
+
+	It("should deny other kinds", func() {
+		response := review(`{"apiVersion": "v1", "kind": "ConfigMap"}`)
+		Expect(response.Allowed).To(BeFalse())
+		Expect(response.Result.Code).To(Equal(int32(http.StatusBadRequest)))
+	})
+
+	It("should allow deletions", func() {
+		Expect(validator.Review(&AdmissionRequest{UID: "uid", Operation: "DELETE"}).Allowed).To(BeTrue())
+	})
+
+	It("should reject requests without admission review", func() {
+		recorder := httptest.NewRecorder()
+		validator.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/validate", bytes.NewReader([]byte(`{}`))))
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+	})
+})
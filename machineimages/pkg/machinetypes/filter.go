@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machinetypes
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Filter selects machine types by their family or by a pattern of their name. If both are set, both must match.
+type Filter struct {
+	// Family is the family of the machine types, see Family.
+	Family string `json:"family,omitempty" yaml:"family,omitempty"`
+	// Name is a pattern of the names of the machine types in the syntax of path.Match, e.g. "m5.*" or "*-standard-4".
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+}
+
+// Family returns the family of a machine type, which is the part of its name before the first ".", "-" or "_", e.g. m5
+// for m5.large or n2 for n2-standard-4. Names like Standard_D4s_v3 are better selected with name patterns.
+func Family(name string) string {
+	if i := strings.IndexAny(name, ".-_"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// Validate checks that the filter selects something and that its pattern is valid.
+func (f Filter) Validate() error {
+	if len(f.Family) == 0 && len(f.Name) == 0 {
+		return fmt.Errorf("filter must select a family or a name")
+	}
+	if _, err := path.Match(f.Name, ""); err != nil {
+		return fmt.Errorf("invalid name pattern %q: %w", f.Name, err)
+	}
+	return nil
+}
+
+// Matches returns whether the filter selects the machine type.
+func (f Filter) Matches(machineType MachineType) bool {
+	if len(f.Family) > 0 && Family(machineType.Name) != f.Family {
+		return false
+	}
+	if len(f.Name) > 0 {
+		matched, err := path.Match(f.Name, machineType.Name)
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// filterMachineTypes keeps the machine types which match one of the include filters, or all if there are none, and
+// none of the exclude filters.
+func filterMachineTypes(machineTypes []MachineType, includeFilters, excludeFilters []Filter) ([]MachineType, error) {
+	for _, filter := range append(append([]Filter{}, includeFilters...), excludeFilters...) {
+		if err := filter.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	matchesAny := func(machineType MachineType, filters []Filter) bool {
+		for _, filter := range filters {
+			if filter.Matches(machineType) {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := []MachineType{}
+	for _, machineType := range machineTypes {
+		if len(includeFilters) > 0 && !matchesAny(machineType, includeFilters) {
+			continue
+		}
+		if matchesAny(machineType, excludeFilters) {
+			continue
+		}
+		result = append(result, machineType)
+	}
+	return result, nil
+}
@@ -0,0 +1,31 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machinetypes
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("filter", func() {
+
+	It("should derive the family from the name", func() {
+		Expect(Family("m5.large")).To(Equal("m5"))
+		Expect(Family("n2-standard-4")).To(Equal("n2"))
+		Expect(Family("ecs.g6.large")).To(Equal("ecs"))
+		Expect(Family("metal")).To(Equal("metal"))
+	})
+
+	It("should match the family and the name", func() {
+		Expect(Filter{Family: "n2"}.Matches(MachineType{Name: "n2-standard-4"})).To(BeTrue())
+		Expect(Filter{Family: "n2"}.Matches(MachineType{Name: "n2d-standard-4"})).To(BeFalse())
+		Expect(Filter{Name: "Standard_D*_v3"}.Matches(MachineType{Name: "Standard_D4s_v3"})).To(BeTrue())
+		Expect(Filter{Family: "n2", Name: "*-highmem-*"}.Matches(MachineType{Name: "n2-standard-4"})).To(BeFalse())
+	})
+
+	It("should reject invalid patterns", func() {
+		Expect(Filter{Name: "m5.[large"}.Validate()).To(MatchError(ContainSubstring(`invalid name pattern "m5.[large"`)))
+	})
+})
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machinetypes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// ComputeMachineTypesOptions contains optional settings for the computation of machine types.
+type ComputeMachineTypesOptions struct {
+	// IncludeFilters select the machine types of the result. If empty, all machine types are included.
+	IncludeFilters []Filter `json:"includeFilters,omitempty" yaml:"includeFilters,omitempty"`
+	// ExcludeFilters remove machine types from the result.
+	ExcludeFilters []Filter `json:"excludeFilters,omitempty" yaml:"excludeFilters,omitempty"`
+}
+
+// ComputeMachineTypes computes the machine types of a cloud profile like machineimages.ComputeMachineImages computes
+// its machine images. The machine types of the landscape override the ones of the LSS with the same name field by
+// field. Only machine types which the provider offers are part of the result. The provider machine types are the
+// capabilities of the provider and fill the fields which neither the landscape nor the LSS sets, e.g. the cpu and the
+// memory. The result is sorted by family, cpu, gpu, memory and name. The options may be nil.
+func ComputeMachineTypes(
+	ctx context.Context,
+	log logr.Logger,
+	lssMachineTypes []MachineType,
+	landscapeMachineTypes []MachineType,
+	providerMachineTypes []MachineType,
+	options *ComputeMachineTypesOptions,
+) (
+	[]MachineType,
+	error,
+) {
+	log.Info("Computing machine types")
+
+	if options == nil {
+		options = &ComputeMachineTypesOptions{}
+	}
+
+	provider := map[string]MachineType{}
+	for _, machineType := range providerMachineTypes {
+		if _, ok := provider[machineType.Name]; !ok {
+			provider[machineType.Name] = machineType
+		}
+	}
+
+	merged := mergeMachineTypes(lssMachineTypes, landscapeMachineTypes)
+	result := []MachineType{}
+	for _, machineType := range merged {
+		capabilities, ok := provider[machineType.Name]
+		if !ok {
+			log.V(1).Info("Skipping machine type which the provider does not offer", "machineType", machineType.Name)
+			continue
+		}
+		result = append(result, overlay(capabilities, machineType))
+	}
+
+	result, err := filterMachineTypes(result, options.IncludeFilters, options.ExcludeFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sortMachineTypes(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ComputeMachineTypesFromImports computes the machine types from the lists and options of the imports.
+func ComputeMachineTypesFromImports(ctx context.Context, log logr.Logger, imports *Imports) ([]MachineType, error) {
+	return ComputeMachineTypes(
+		ctx,
+		log,
+		imports.MachineTypesLs,
+		imports.MachineTypes,
+		imports.MachineTypesProvider,
+		&imports.ComputeMachineTypesOptions,
+	)
+}
+
+// mergeMachineTypes returns the machine types of the LSS, overridden by the landscape machine types with the same
+// name, followed by the further machine types of the landscape. Later duplicates of a list override earlier ones.
+func mergeMachineTypes(lssMachineTypes, landscapeMachineTypes []MachineType) []MachineType {
+	result := []MachineType{}
+	index := map[string]int{}
+	for _, machineType := range append(append([]MachineType{}, lssMachineTypes...), landscapeMachineTypes...) {
+		if i, ok := index[machineType.Name]; ok {
+			result[i] = overlay(result[i], machineType)
+			continue
+		}
+		index[machineType.Name] = len(result)
+		result = append(result, machineType)
+	}
+	return result
+}
+
+// overlay returns the base machine type with all fields which the override sets.
+func overlay(base, override MachineType) MachineType {
+	result := base
+	if len(override.CPU) > 0 {
+		result.CPU = override.CPU
+	}
+	if len(override.GPU) > 0 {
+		result.GPU = override.GPU
+	}
+	if len(override.Memory) > 0 {
+		result.Memory = override.Memory
+	}
+	if override.Storage != nil {
+		storage := *override.Storage
+		result.Storage = &storage
+	}
+	if override.Architecture != nil {
+		architecture := *override.Architecture
+		result.Architecture = &architecture
+	}
+	if override.Usable != nil {
+		usable := *override.Usable
+		result.Usable = &usable
+	}
+	return result
+}
+
+// sortMachineTypes sorts the machine types by family, cpu, gpu, memory and name. It fails if a quantity is invalid or
+// a machine type has no cpu or memory.
+func sortMachineTypes(machineTypes []MachineType) error {
+	type sortKey struct {
+		family           string
+		cpu, gpu, memory float64
+	}
+	keys := map[string]sortKey{}
+	problems := []string{}
+	for _, machineType := range machineTypes {
+		key := sortKey{family: Family(machineType.Name)}
+		var err error
+		if len(machineType.CPU) == 0 || len(machineType.Memory) == 0 {
+			err = fmt.Errorf("cpu and memory must be provided")
+		}
+		if err == nil {
+			key.cpu, err = parseQuantity(machineType.CPU)
+		}
+		if err == nil {
+			key.gpu, err = parseQuantity(machineType.GPU)
+		}
+		if err == nil {
+			key.memory, err = parseQuantity(machineType.Memory)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("machine type %s: %v", machineType.Name, err))
+		}
+		keys[machineType.Name] = key
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid machine types: %s", strings.Join(problems, "; "))
+	}
+
+	sort.SliceStable(machineTypes, func(i, j int) bool {
+		a, b := keys[machineTypes[i].Name], keys[machineTypes[j].Name]
+		switch {
+		case a.family != b.family:
+			return a.family < b.family
+		case a.cpu != b.cpu:
+			return a.cpu < b.cpu
+		case a.gpu != b.gpu:
+			return a.gpu < b.gpu
+		case a.memory != b.memory:
+			return a.memory < b.memory
+		default:
+			return machineTypes[i].Name < machineTypes[j].Name
+		}
+	})
+	return nil
+}
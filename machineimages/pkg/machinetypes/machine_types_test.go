@@ -0,0 +1,87 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machinetypes
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("machine types", func() {
+
+	usable := func(value bool) *bool { return &value }
+
+	provider := []MachineType{
+		{Name: "m5.xlarge", CPU: "4", Memory: "16Gi"},
+		{Name: "m5.large", CPU: "2", Memory: "8Gi"},
+		{Name: "c5.large", CPU: "2", Memory: "4Gi"},
+		{Name: "p3.2xlarge", CPU: "8", GPU: "1", Memory: "61Gi"},
+	}
+
+	compute := func(lss, landscape []MachineType, options *ComputeMachineTypesOptions) ([]MachineType, error) {
+		return ComputeMachineTypes(context.Background(), logr.Discard(), lss, landscape, provider, options)
+	}
+
+	It("should merge the lists and fill the capabilities of the provider", func() {
+		result, err := compute(
+			[]MachineType{
+				{Name: "m5.xlarge", Storage: &MachineTypeStorage{Class: "standard", StorageSize: "50Gi", Type: "gp3"}},
+				{Name: "m5.large", Usable: usable(true)},
+				{Name: "t2.micro", CPU: "1", Memory: "1Gi"},
+			},
+			[]MachineType{
+				{Name: "m5.large", Usable: usable(false)},
+				{Name: "c5.large"},
+				{Name: "p3.2xlarge", Memory: "64Gi"},
+			},
+			nil,
+		)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineType{
+			{Name: "c5.large", CPU: "2", Memory: "4Gi"},
+			{Name: "m5.large", CPU: "2", Memory: "8Gi", Usable: usable(false)},
+			{Name: "m5.xlarge", CPU: "4", Memory: "16Gi", Storage: &MachineTypeStorage{Class: "standard", StorageSize: "50Gi", Type: "gp3"}},
+			{Name: "p3.2xlarge", CPU: "8", GPU: "1", Memory: "64Gi"},
+		}))
+	})
+
+	It("should filter by family and name pattern", func() {
+		all := []MachineType{{Name: "m5.xlarge"}, {Name: "m5.large"}, {Name: "c5.large"}, {Name: "p3.2xlarge"}}
+		result, err := compute(all, nil, &ComputeMachineTypesOptions{
+			IncludeFilters: []Filter{{Family: "m5"}, {Name: "*.2xlarge"}},
+			ExcludeFilters: []Filter{{Name: "*.xlarge"}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		names := []string{}
+		for _, machineType := range result {
+			names = append(names, machineType.Name)
+		}
+		Expect(names).To(Equal([]string{"m5.large", "p3.2xlarge"}))
+	})
+
+	It("should fail on invalid quantities", func() {
+		_, err := compute([]MachineType{{Name: "m5.large", Memory: "8 GB"}, {Name: "c5.large", CPU: "two"}}, nil, nil)
+		Expect(err).To(MatchError(`invalid machine types: machine type m5.large: invalid quantity "8 GB"; machine type c5.large: invalid quantity "two"`))
+	})
+
+	It("should fail on invalid filters", func() {
+		_, err := compute(nil, nil, &ComputeMachineTypesOptions{IncludeFilters: []Filter{{}}})
+		Expect(err).To(MatchError("filter must select a family or a name"))
+	})
+
+	It("should compute the machine types of the imports", func() {
+		result, err := ComputeMachineTypesFromImports(context.Background(), logr.Discard(), &Imports{
+			MachineTypes:         []MachineType{{Name: "m5.large", Usable: usable(false)}},
+			MachineTypesLs:       []MachineType{{Name: "m5.large"}},
+			MachineTypesProvider: provider,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]MachineType{{Name: "m5.large", CPU: "2", Memory: "8Gi", Usable: usable(false)}}))
+	})
+})
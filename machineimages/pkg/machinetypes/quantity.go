@@ -0,0 +1,43 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machinetypes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// quantitySuffixes are the suffixes of Kubernetes quantities with their factors. Longer suffixes come first, so that
+// Mi is not parsed as M.
+var quantitySuffixes = []struct {
+	suffix string
+	factor float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"Pi", 1 << 50},
+	{"m", 1e-3}, {"k", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}, {"P", 1e15},
+}
+
+// parseQuantity returns the value of a Kubernetes quantity, e.g. 8Gi or 500m. Exponents are not supported. An empty
+// quantity is zero.
+func parseQuantity(quantity string) (float64, error) {
+	if len(quantity) == 0 {
+		return 0, nil
+	}
+
+	number, factor := quantity, 1.0
+	for _, s := range quantitySuffixes {
+		if strings.HasSuffix(quantity, s.suffix) {
+			number, factor = strings.TrimSuffix(quantity, s.suffix), s.factor
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) || strings.ContainsAny(number, "eExX") {
+		return 0, fmt.Errorf("invalid quantity %q", quantity)
+	}
+	return value * factor, nil
+}
@@ -0,0 +1,35 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machinetypes
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("quantity", func() {
+
+	It("should parse kubernetes quantities", func() {
+		for quantity, expected := range map[string]float64{
+			"":     0,
+			"2":    2,
+			"500m": 0.5,
+			"8Gi":  8 << 30,
+			"1.5G": 1.5e9,
+			"64Mi": 64 << 20,
+		} {
+			value, err := parseQuantity(quantity)
+			Expect(err).NotTo(HaveOccurred(), quantity)
+			Expect(value).To(Equal(expected), quantity)
+		}
+	})
+
+	It("should reject invalid quantities", func() {
+		for _, quantity := range []string{"-1", "8 Gi", "Gi", "1e3", "NaN", "0x10"} {
+			_, err := parseQuantity(quantity)
+			Expect(err).To(MatchError(`invalid quantity "`+quantity+`"`), quantity)
+		}
+	})
+})
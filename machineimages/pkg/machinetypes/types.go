@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2021 SAP SE or an SAP affiliate company and Gardener contributors.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package machinetypes
+
+// MachineType is a machine type of a cloud profile. Quantities are given like in Kubernetes, e.g. "2" or "500m" cpus
+// and "8Gi" memory.
+type MachineType struct {
+	Name         string              `json:"name"`
+	CPU          string              `json:"cpu,omitempty"`
+	GPU          string              `json:"gpu,omitempty"`
+	Memory       string              `json:"memory,omitempty"`
+	Storage      *MachineTypeStorage `json:"storage,omitempty"`
+	Architecture *string             `json:"architecture,omitempty"`
+	// Capabilities are the values of the machine capabilities of cloud profiles with capabilities, e.g. the
+	// architecture.
+	Capabilities map[string][]string `json:"capabilities,omitempty"`
+	// Usable is whether shoots may use the machine type. Gardener defaults it to true.
+	Usable *bool `json:"usable,omitempty"`
+}
+
+// MachineTypeStorage is the root disk of a machine type.
+type MachineTypeStorage struct {
+	Class       string `json:"class,omitempty"`
+	StorageSize string `json:"size,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// Imports are the inputs of the computation of machine types.
+type Imports struct {
+	MachineTypes         []MachineType `json:"machineTypes" yaml:"machineTypes"`
+	MachineTypesLs       []MachineType `json:"machineTypesLs" yaml:"machineTypesLs"`
+	MachineTypesProvider []MachineType `json:"machineTypesProvider" yaml:"machineTypesProvider"`
+
+	ComputeMachineTypesOptions `json:",inline" yaml:",inline"`
+}
+
+// Exports are the result of the computation of machine types.
+type Exports struct {
+	ResultMachineTypes []MachineType `json:"resultMachineTypes" yaml:"resultMachineTypes"`
+}